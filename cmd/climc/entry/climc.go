@@ -57,6 +57,9 @@ type BaseOptions struct {
 	OsAccessKey string `default:"$OS_ACCESS_KEY" help:"ak/sk access key, defaults to env[OS_ACCESS_KEY]"`
 	OsSecretKey string `default:"$OS_SECRET_KEY" help:"ak/s secret, defaults to env[OS_SECRET_KEY]"`
 
+	OsApplicationCredentialId     string `default:"$OS_APPLICATION_CREDENTIAL_ID" help:"application credential id, defaults to env[OS_APPLICATION_CREDENTIAL_ID]"`
+	OsApplicationCredentialSecret string `default:"$OS_APPLICATION_CREDENTIAL_SECRET" help:"application credential secret, defaults to env[OS_APPLICATION_CREDENTIAL_SECRET]"`
+
 	OsAuthToken string `default:"$OS_AUTH_TOKEN" help:"token authenticate, defaults to env[OS_AUTH_TOKEN]"`
 
 	OsAuthURL string `default:"$OS_AUTH_URL" help:"Defaults to env[OS_AUTH_URL]"`
@@ -122,8 +125,8 @@ func newClientSession(options *BaseOptions) (*mcclient.ClientSession, error) {
 	if len(options.OsAuthURL) == 0 {
 		return nil, fmt.Errorf("Missing OS_AUTH_URL")
 	}
-	if len(options.OsUsername) == 0 && len(options.OsAccessKey) == 0 && len(options.OsAuthToken) == 0 {
-		return nil, fmt.Errorf("Missing OS_USERNAME or OS_ACCESS_KEY or OS_AUTH_TOKEN")
+	if len(options.OsUsername) == 0 && len(options.OsAccessKey) == 0 && len(options.OsAuthToken) == 0 && len(options.OsApplicationCredentialId) == 0 {
+		return nil, fmt.Errorf("Missing OS_USERNAME or OS_ACCESS_KEY or OS_AUTH_TOKEN or OS_APPLICATION_CREDENTIAL_ID")
 	}
 	if len(options.OsUsername) > 0 && len(options.OsPassword) == 0 {
 		return nil, fmt.Errorf("Missing OS_PASSWORD")
@@ -131,6 +134,9 @@ func newClientSession(options *BaseOptions) (*mcclient.ClientSession, error) {
 	if len(options.OsAccessKey) > 0 && len(options.OsSecretKey) == 0 {
 		return nil, fmt.Errorf("Missing OS_SECRET_KEY")
 	}
+	if len(options.OsApplicationCredentialId) > 0 && len(options.OsApplicationCredentialSecret) == 0 {
+		return nil, fmt.Errorf("Missing OS_APPLICATION_CREDENTIAL_SECRET")
+	}
 
 	logLevel := "info"
 	if options.Debug {
@@ -177,6 +183,9 @@ func newClientSession(options *BaseOptions) (*mcclient.ClientSession, error) {
 			token, err = client.AuthenticateToken(options.OsAuthToken, options.OsProjectName,
 				options.OsProjectDomain,
 				mcclient.AuthSourceCli)
+		} else if len(options.OsApplicationCredentialId) > 0 {
+			token, err = client.AuthenticateAppCredential(options.OsApplicationCredentialId,
+				options.OsApplicationCredentialSecret, mcclient.AuthSourceCli)
 		} else if len(options.OsAccessKey) > 0 {
 			token, err = client.AuthenticateByAccessKey(options.OsAccessKey,
 				options.OsSecretKey, mcclient.AuthSourceCli)
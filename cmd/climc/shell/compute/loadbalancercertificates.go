@@ -83,6 +83,14 @@ func init() {
 		printObject(lbcert)
 		return nil
 	})
+	R(&options.LoadbalancerCertificateRenewOptions{}, "lbcert-renew", "Renew acme lbcert", func(s *mcclient.ClientSession, opts *options.LoadbalancerCertificateRenewOptions) error {
+		lbcert, err := modules.LoadbalancerCertificates.PerformAction(s, opts.ID, "renew", nil)
+		if err != nil {
+			return err
+		}
+		printObject(lbcert)
+		return nil
+	})
 	R(&options.LoadbalancerCertificatePublicOptions{}, "lbcert-public", "Public lbcert", func(s *mcclient.ClientSession, opts *options.LoadbalancerCertificatePublicOptions) error {
 		params := jsonutils.Marshal(opts)
 		lbcert, err := modules.LoadbalancerCertificates.PerformAction(s, opts.ID, "public", params)
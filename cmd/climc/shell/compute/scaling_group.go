@@ -142,4 +142,21 @@ func init() {
 		printObject(ret)
 		return nil
 	})
+
+	type ScalingGroupSetDesireInstanceNumberOptions struct {
+		ID                   string `help:"ScalingGroup ID or Name"`
+		DESIREINSTANCENUMBER int    `help:"Desire instance number" json:"desire_instance_number"`
+	}
+	R(&ScalingGroupSetDesireInstanceNumberOptions{}, "scaling-group-set-desire-instance-number", "Set desire instance number of ScalingGroup",
+		func(s *mcclient.ClientSession, args *ScalingGroupSetDesireInstanceNumberOptions) error {
+			params := jsonutils.NewDict()
+			params.Set("desire_instance_number", jsonutils.NewInt(int64(args.DESIREINSTANCENUMBER)))
+			ret, err := modules.ScalingGroup.PerformAction(s, args.ID, "set-desire-instance-number", params)
+			if err != nil {
+				return err
+			}
+			printObject(ret)
+			return nil
+		},
+	)
 }
@@ -50,6 +50,8 @@ func init() {
 		CONDITION string `help:"condition that assign schedtag to hosts"`
 		Enable    bool   `help:"create the policy with enabled status"`
 		Disable   bool   `help:"create the policy with disabled status"`
+
+		CostWeight *int `help:"weight of cost priority when the condition matches, -1 to use the default of scheduler"`
 	}
 	R(&SchedpoliciesCreateOptions{}, "sched-policy-create", "create a sched policty", func(s *mcclient.ClientSession, args *SchedpoliciesCreateOptions) error {
 		params := jsonutils.NewDict()
@@ -57,6 +59,9 @@ func init() {
 		params.Add(jsonutils.NewString(args.STRATEGY), "strategy")
 		params.Add(jsonutils.NewString(args.CONDITION), "condition")
 		params.Add(jsonutils.NewString(args.SCHEDTAG), "schedtag")
+		if args.CostWeight != nil {
+			params.Add(jsonutils.NewInt(int64(*args.CostWeight)), "cost_weight")
+		}
 
 		if args.Enable {
 			params.Add(jsonutils.JSONTrue, "enabled")
@@ -80,6 +85,8 @@ func init() {
 		Condition string `help:"condition that assign schedtag to hosts"`
 		Enable    bool   `help:"make the sched policy enabled"`
 		Disable   bool   `help:"make the sched policy disabled"`
+
+		CostWeight *int `help:"weight of cost priority when the condition matches, -1 to use the default of scheduler"`
 	}
 	R(&SchedpoliciesUpdateOptions{}, "sched-policy-update", "update a sched policy", func(s *mcclient.ClientSession, args *SchedpoliciesUpdateOptions) error {
 		params := jsonutils.NewDict()
@@ -96,6 +103,9 @@ func init() {
 		if len(args.SchedTag) > 0 {
 			params.Add(jsonutils.NewString(args.SchedTag), "schedtag")
 		}
+		if args.CostWeight != nil {
+			params.Add(jsonutils.NewInt(int64(*args.CostWeight)), "cost_weight")
+		}
 		if args.Enable {
 			params.Add(jsonutils.JSONTrue, "enabled")
 		} else if args.Disable {
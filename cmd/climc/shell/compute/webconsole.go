@@ -17,13 +17,16 @@ package compute
 import (
 	"encoding/base64"
 	"fmt"
+	"io"
 	"net/url"
+	"os"
 
 	"yunion.io/x/jsonutils"
 	"yunion.io/x/pkg/utils"
 
 	webconsole_api "yunion.io/x/onecloud/pkg/apis/webconsole"
 	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/mcclient/modulebase"
 	"yunion.io/x/onecloud/pkg/mcclient/modules/webconsole"
 	o "yunion.io/x/onecloud/pkg/mcclient/options"
 	"yunion.io/x/onecloud/pkg/webconsole/command"
@@ -92,7 +95,11 @@ func init() {
 	})
 
 	R(&o.WebConsoleBaremetalOptions{}, "webconsole-baremetal", "Connect baremetal host webconsole", func(s *mcclient.ClientSession, args *o.WebConsoleBaremetalOptions) error {
-		ret, err := webconsole.WebConsole.DoBaremetalConnect(s, args.ID, nil)
+		params, err := args.Params()
+		if err != nil {
+			return err
+		}
+		ret, err := webconsole.WebConsole.DoBaremetalConnect(s, args.ID, params)
 		if err != nil {
 			return err
 		}
@@ -121,4 +128,58 @@ func init() {
 		handleResult(args.WebConsoleOptions, ret)
 		return nil
 	})
+
+	R(&o.WebConsoleRecordingListOptions{}, "webconsole-recording-list", "List terminal session recordings", func(s *mcclient.ClientSession, args *o.WebConsoleRecordingListOptions) error {
+		params, err := args.Params()
+		if err != nil {
+			return err
+		}
+		ret, err := webconsole.WebConsole.ListRecordings(s, params)
+		if err != nil {
+			return err
+		}
+		output := webconsole_api.SessionRecordingListOutput{}
+		if err := ret.Unmarshal(&output); err != nil {
+			return err
+		}
+		data := make([]jsonutils.JSONObject, len(output.Recordings))
+		for i := range output.Recordings {
+			data[i] = jsonutils.Marshal(output.Recordings[i])
+		}
+		printList(&modulebase.ListResult{
+			Data:   data,
+			Total:  output.Total,
+			Limit:  output.Limit,
+			Offset: output.Offset,
+		}, []string{"Id", "Protocol", "Target", "User", "Project", "Started_At", "Duration", "Storage", "Status"})
+		return nil
+	})
+
+	R(&o.WebConsoleRecordingIdOptions{}, "webconsole-recording-show", "Show details of a terminal session recording", func(s *mcclient.ClientSession, args *o.WebConsoleRecordingIdOptions) error {
+		ret, err := webconsole.WebConsole.GetRecording(s, args.ID)
+		if err != nil {
+			return err
+		}
+		printObject(ret)
+		return nil
+	})
+
+	R(&o.WebConsoleRecordingPlaybackOptions{}, "webconsole-recording-playback", "Download a terminal session recording in asciicast v2 format", func(s *mcclient.ClientSession, args *o.WebConsoleRecordingPlaybackOptions) error {
+		reader, err := webconsole.WebConsole.PlaybackRecording(s, args.ID)
+		if err != nil {
+			return err
+		}
+		defer reader.Close()
+		var w io.Writer = os.Stdout
+		if len(args.Output) > 0 {
+			f, err := os.Create(args.Output)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			w = f
+		}
+		_, err = io.Copy(w, reader)
+		return err
+	})
 }
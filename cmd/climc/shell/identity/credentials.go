@@ -16,10 +16,12 @@ package identity
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"yunion.io/x/jsonutils"
 
+	api "yunion.io/x/onecloud/pkg/apis/identity"
 	"yunion.io/x/onecloud/pkg/mcclient"
 	"yunion.io/x/onecloud/pkg/mcclient/modulebase"
 	modules "yunion.io/x/onecloud/pkg/mcclient/modules/identity"
@@ -28,7 +30,7 @@ import (
 func init() {
 	type CredentialListOptions struct {
 		Scope      string `help:"scope" choices:"project|domain|system"`
-		Type       string `help:"credential type" choices:"totp|recovery|aksk"`
+		Type       string `help:"credential type" choices:"totp|recovery|aksk|app_credential"`
 		User       string `help:"filter by user"`
 		UserDomain string `help:"the domain of user"`
 	}
@@ -237,6 +239,56 @@ func init() {
 		return nil
 	})
 
+	type AppCredentialCreateOptions struct {
+		NAME          string   `help:"name of application credential"`
+		User          string   `help:"User"`
+		UserDomain    string   `help:"domain of user"`
+		Project       string   `help:"Project"`
+		ProjectDomain string   `help:"domain of project"`
+		Role          []string `help:"roles delegated to the application credential, defaults to all roles of the user in the project"`
+		ExpireDays    int      `help:"expire after days, never expire if not set"`
+		AccessRule    []string `help:"access rule in format of service:method:path, e.g. compute:GET:/servers/**"`
+	}
+	R(&AppCredentialCreateOptions{}, "credential-create-app-credential", "Create application credential with restricted roles", func(s *mcclient.ClientSession, args *AppCredentialCreateOptions) error {
+		var uid string
+		var pid string
+		var err error
+		if len(args.User) > 0 {
+			uid, err = modules.UsersV3.FetchId(s, args.User, args.UserDomain)
+			if err != nil {
+				return err
+			}
+		}
+		if len(args.Project) > 0 {
+			pid, err = modules.Projects.FetchId(s, args.Project, args.ProjectDomain)
+			if err != nil {
+				return err
+			}
+		}
+		var expireAt time.Time
+		if args.ExpireDays > 0 {
+			expireAt = time.Now().Add(time.Duration(args.ExpireDays) * 24 * time.Hour)
+		}
+		rules := make([]api.SAppCredentialAccessRule, len(args.AccessRule))
+		for i := range args.AccessRule {
+			parts := strings.SplitN(args.AccessRule[i], ":", 3)
+			if len(parts) != 3 {
+				return fmt.Errorf("invalid access rule %s, should be service:method:path", args.AccessRule[i])
+			}
+			rules[i] = api.SAppCredentialAccessRule{
+				Service: parts[0],
+				Method:  parts[1],
+				Path:    parts[2],
+			}
+		}
+		appCred, err := modules.Credentials.CreateAppCredential(s, uid, pid, args.NAME, args.Role, expireAt, rules)
+		if err != nil {
+			return err
+		}
+		printObject(jsonutils.Marshal(&appCred))
+		return nil
+	})
+
 	type OIDCCredentialOptions struct {
 		User          string `help:"User"`
 		UserDomain    string `help:"domain of user"`
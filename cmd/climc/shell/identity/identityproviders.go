@@ -775,4 +775,18 @@ func init() {
 		printObject(result)
 		return nil
 	})
+
+	type IdpScimOptions struct {
+		ID string `help:"id or name of idp to enable or disable scim provisioning" json:"-"`
+
+		api.PerformIdpScimInput
+	}
+	R(&IdpScimOptions{}, "idp-scim", "Enable/disable SCIM provisioning, a new bearer token is issued when enabled", func(s *mcclient.ClientSession, args *IdpScimOptions) error {
+		result, err := modules.IdentityProviders.PerformAction(s, args.ID, "scim", jsonutils.Marshal(args))
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
 }
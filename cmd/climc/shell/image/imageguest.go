@@ -15,6 +15,10 @@
 package image
 
 import (
+	"os"
+
+	"github.com/cheggaaa/pb/v3"
+
 	"yunion.io/x/jsonutils"
 	"yunion.io/x/pkg/errors"
 
@@ -51,6 +55,54 @@ func init() {
 	},
 	)
 
+	type GuestImageImportOptions struct {
+		NAME         string `help:"Name of guest image"`
+		ImportFormat string `help:"Format of the virtual machine to import" choices:"ova|ovf" default:"ova"`
+		File         string `help:"Local ova file to upload"`
+		CopyFrom     string `help:"Url of ova file or ovf descriptor"`
+		Protected    bool   `help:"if guest image is protected"`
+	}
+
+	R(&GuestImageImportOptions{}, "guest-image-import", "Import guest image from ova or ovf", func(s *mcclient.ClientSession,
+		args *GuestImageImportOptions) error {
+
+		params := jsonutils.NewDict()
+		params.Add(jsonutils.NewString(args.NAME), "name")
+		params.Add(jsonutils.NewString(args.ImportFormat), "import_format")
+		if args.Protected {
+			params.Add(jsonutils.JSONTrue, "protected")
+		}
+		if len(args.CopyFrom) > 0 {
+			params.Add(jsonutils.NewString(args.CopyFrom), "copy_from")
+			ret, err := modules.GuestImages.Create(s, params)
+			if err != nil {
+				return err
+			}
+			printObject(ret)
+			return nil
+		}
+		if len(args.File) == 0 {
+			return errors.Error("either --file or --copy-from is required")
+		}
+		f, err := os.Open(args.File)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		finfo, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		bar := pb.Full.Start64(finfo.Size())
+		ret, err := modules.UploadGuestImage(s, params, bar.NewProxyReader(f), finfo.Size())
+		if err != nil {
+			return err
+		}
+		printObject(ret)
+		return nil
+	},
+	)
+
 	type GuestImageListOptions struct {
 		options.BaseListOptions
 
@@ -16,6 +16,7 @@ package compute
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"yunion.io/x/jsonutils"
@@ -38,7 +39,7 @@ func init() {
 	type ScheduledTaskListOptions struct {
 		options.BaseListOptions
 
-		ScheduledType string `help:"scheduled type" choices:"timing|cycle"`
+		ScheduledType string `help:"scheduled type" choices:"timing|cycle|cron"`
 		ResourceType  string `help:"resource type"`
 		Operation     string `help:"operation"`
 		UtcOffset     int    `help:"utc offset"`
@@ -82,58 +83,135 @@ func init() {
 		CycleEndTime   string `help:"End time for cycle timer, format:'2006-01-02 15:04:05'" json:"end_time"`
 	}
 
-	type ScheduledTaskCreateOptions struct {
-		NAME          string `help:"ScheduledTask Name" json:"name"`
-		ScheduledType string `help:"Scheudled Type" choices:"timing|cycle" json:"scheduled_type"`
+	type CronTimer struct {
+		CronExpr      string `help:"Cron expression for 'cron' type trigger, e.g. '30 8 * * 1-5'"`
+		CronTimeZone  string `help:"Time zone of cron expression, e.g. 'Asia/Shanghai'"`
+		CronStartTime string `help:"Start time for cron timer, format:'2006-01-02 15:04:05'"`
+		CronEndTime   string `help:"End time for cron timer, format:'2006-01-02 15:04:05'"`
+	}
+
+	type ScheduledTaskTimerOptions struct {
+		ScheduledType string `help:"Scheudled Type" choices:"timing|cycle|cron" json:"scheduled_type"`
 
 		Timer
 		CycleTimer
+		CronTimer
 
-		ResourceType string   `help:"resource type"`
-		Operation    string   `help:"operation"`
-		LabelType    string   `help:"label type"`
-		Labels       []string `help:"labels"`
+		ExclusionWindow []string `help:"Window in which the task does not run, format:'2006-01-02 15:04:05~2006-01-02 15:04:05[~reason]'"`
 	}
-	R(&ScheduledTaskCreateOptions{}, "scheduledtask-create", "Create Scheduled Task", func(s *mcclient.ClientSession, args *ScheduledTaskCreateOptions) error {
-		formatStr := "2006-01-02 15:04:05"
-		var exectime, starttime, endtime time.Time
-		var err error
-		if len(args.TimingExecTime) > 0 {
-			exectime, err = time.Parse(formatStr, args.TimingExecTime)
-			if err != nil {
-				return fmt.Errorf("invalid time format for 'exec_time'")
-			}
+
+	formatStr := "2006-01-02 15:04:05"
+	parseTime := func(str, name string) (time.Time, error) {
+		if len(str) == 0 {
+			return time.Time{}, nil
+		}
+		t, err := time.Parse(formatStr, str)
+		if err != nil {
+			return t, fmt.Errorf("invalid time format for '%s'", name)
 		}
-		if len(args.CycleStartTime) > 0 {
-			starttime, err = time.Parse(formatStr, args.CycleStartTime)
+		return t, nil
+	}
+	parseExclusionWindows := func(strs []string) (apis.SExclusionWindows, error) {
+		windows := apis.SExclusionWindows{}
+		for _, str := range strs {
+			parts := strings.SplitN(str, "~", 3)
+			if len(parts) < 2 {
+				return nil, fmt.Errorf("invalid exclusion window %q", str)
+			}
+			var (
+				w   apis.SExclusionWindow
+				err error
+			)
+			w.Start, err = parseTime(parts[0], "exclusion_window")
 			if err != nil {
-				return fmt.Errorf("invalid time format for 'start_time'")
+				return nil, err
 			}
-		}
-		if len(args.CycleEndTime) > 0 {
-			endtime, err = time.Parse(formatStr, args.CycleEndTime)
+			w.End, err = parseTime(parts[1], "exclusion_window")
 			if err != nil {
-				return fmt.Errorf("invalid time format for 'end_time'")
+				return nil, err
+			}
+			if len(parts) == 3 {
+				w.Reason = parts[2]
 			}
+			windows = append(windows, w)
 		}
-		stCreateInput := apis.ScheduledTaskCreateInput{
+		return windows, nil
+	}
+	timerInput := func(args *ScheduledTaskTimerOptions) (apis.ScheduledTaskTimerInput, error) {
+		input := apis.ScheduledTaskTimerInput{
 			ScheduledType: args.ScheduledType,
-			Timer: apis.TimerCreateInput{
-				ExecTime: exectime,
-			},
 			CycleTimer: apis.CycleTimerCreateInput{
 				CycleType: args.CycleCycleType,
 				Minute:    args.CycleMinute,
 				Hour:      args.CycleHour,
 				WeekDays:  args.CycleWeekdays,
 				MonthDays: args.CycleMonthDays,
-				StartTime: starttime,
-				EndTime:   endtime,
 			},
-			ResourceType: args.ResourceType,
-			Operation:    args.Operation,
-			LabelType:    args.LabelType,
-			Labels:       args.Labels,
+			CronTimer: apis.CronTimerCreateInput{
+				Expr:     args.CronExpr,
+				TimeZone: args.CronTimeZone,
+			},
+		}
+		var err error
+		if input.Timer.ExecTime, err = parseTime(args.TimingExecTime, "exec_time"); err != nil {
+			return input, err
+		}
+		if input.CycleTimer.StartTime, err = parseTime(args.CycleStartTime, "start_time"); err != nil {
+			return input, err
+		}
+		if input.CycleTimer.EndTime, err = parseTime(args.CycleEndTime, "end_time"); err != nil {
+			return input, err
+		}
+		if input.CronTimer.StartTime, err = parseTime(args.CronStartTime, "start_time"); err != nil {
+			return input, err
+		}
+		if input.CronTimer.EndTime, err = parseTime(args.CronEndTime, "end_time"); err != nil {
+			return input, err
+		}
+		if input.ExclusionWindows, err = parseExclusionWindows(args.ExclusionWindow); err != nil {
+			return input, err
+		}
+		return input, nil
+	}
+
+	type ScheduledTaskCreateOptions struct {
+		NAME string `help:"ScheduledTask Name" json:"name"`
+
+		ScheduledTaskTimerOptions
+
+		ResourceType string   `help:"resource type" choices:"server|cloudaccount|disk|scalinggroup"`
+		Operation    string   `help:"operation" choices:"start|stop|restart|sync|snapshot|change-config|scale|run-playbook"`
+		LabelType    string   `help:"label type"`
+		Labels       []string `help:"labels"`
+
+		SnapshotRetention    int    `help:"Count of snapshots to keep for operation snapshot"`
+		SnapshotWithMemory   bool   `help:"Save memory of server for operation snapshot"`
+		InstanceType         string `help:"Instance type for operation change-config"`
+		VcpuCount            int    `help:"Cpu count for operation change-config"`
+		VmemSize             string `help:"Memory size for operation change-config, e.g. 4G"`
+		DesireInstanceNumber int    `help:"Desire instance number for operation scale"`
+		Script               string `help:"Devtool script ID or Name for operation run-playbook"`
+	}
+	R(&ScheduledTaskCreateOptions{}, "scheduledtask-create", "Create Scheduled Task", func(s *mcclient.ClientSession, args *ScheduledTaskCreateOptions) error {
+		timer, err := timerInput(&args.ScheduledTaskTimerOptions)
+		if err != nil {
+			return err
+		}
+		stCreateInput := apis.ScheduledTaskCreateInput{
+			ScheduledTaskTimerInput: timer,
+			ResourceType:            args.ResourceType,
+			Operation:               args.Operation,
+			LabelType:               args.LabelType,
+			Labels:                  args.Labels,
+			OperationParams: &apis.ScheduledTaskOperationParams{
+				SnapshotRetention:    args.SnapshotRetention,
+				SnapshotWithMemory:   args.SnapshotWithMemory,
+				InstanceType:         args.InstanceType,
+				VcpuCount:            args.VcpuCount,
+				VmemSize:             args.VmemSize,
+				DesireInstanceNumber: args.DesireInstanceNumber,
+				Script:               args.Script,
+			},
 		}
 		stCreateInput.Name = args.NAME
 		ret, err := modules.ScheduledTask.Create(s, jsonutils.Marshal(stCreateInput))
@@ -144,6 +222,52 @@ func init() {
 		return nil
 	})
 
+	type ScheduledTaskPreviewOptions struct {
+		ScheduledTaskTimerOptions
+
+		Count int    `help:"Count of fire times to preview" default:"10"`
+		From  string `help:"Preview fire times after this time, format:'2006-01-02 15:04:05'"`
+	}
+	R(&ScheduledTaskPreviewOptions{}, "scheduledtask-preview", "Preview the next fire times of Scheduled Task", func(s *mcclient.ClientSession, args *ScheduledTaskPreviewOptions) error {
+		timer, err := timerInput(&args.ScheduledTaskTimerOptions)
+		if err != nil {
+			return err
+		}
+		input := apis.ScheduledTaskPreviewInput{
+			ScheduledTaskTimerInput: timer,
+			Count:                   args.Count,
+		}
+		if input.From, err = parseTime(args.From, "from"); err != nil {
+			return err
+		}
+		ret, err := modules.ScheduledTask.PerformClassAction(s, "preview", jsonutils.Marshal(input))
+		if err != nil {
+			return err
+		}
+		printObject(ret)
+		return nil
+	})
+
+	type ScheduledTaskSetExclusionWindowsOptions struct {
+		ID              string   `help:"ScheduledTask ID or Name"`
+		ExclusionWindow []string `help:"Window in which the task does not run, format:'2006-01-02 15:04:05~2006-01-02 15:04:05[~reason]'"`
+	}
+	R(&ScheduledTaskSetExclusionWindowsOptions{}, "scheduledtask-set-exclusion-windows", "Set exclusion windows of ScheduledTask",
+		func(s *mcclient.ClientSession, args *ScheduledTaskSetExclusionWindowsOptions) error {
+			windows, err := parseExclusionWindows(args.ExclusionWindow)
+			if err != nil {
+				return err
+			}
+			input := apis.ScheduledTaskSetExclusionWindowsInput{ExclusionWindows: windows}
+			ret, err := modules.ScheduledTask.PerformAction(s, args.ID, "set-exclusion-windows", jsonutils.Marshal(input))
+			if err != nil {
+				return err
+			}
+			printObject(ret)
+			return nil
+		},
+	)
+
 	type ScheduledTaskEnableOptions struct {
 		ID string `help:"ScheduledTask ID or Name"`
 	}
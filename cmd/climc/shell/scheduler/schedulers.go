@@ -32,7 +32,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			listFields := []string{"id", "name", "capacity", "count", "score"}
+			listFields := []string{"id", "name", "capacity", "count", "score", "estimated_monthly_cost"}
 			if args.Details {
 				listFields = append(listFields, "capacity_details", "score_details")
 			}
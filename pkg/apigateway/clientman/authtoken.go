@@ -60,6 +60,10 @@ type SAuthToken struct {
 	initTotp   bool
 	isSsoLogin bool
 
+	requireWebauthn bool // 需要webauthn二次认证
+	verifyWebauthn  bool // webauthn验证通过
+	initWebauthn    bool // 已注册webauthn凭证
+
 	retryCount     int    // 重试计数器
 	lockExpireTime uint32 // 锁定时间
 }
@@ -91,6 +95,13 @@ func (t SAuthToken) encodeBytes() []byte {
 	expBytes := make([]byte, 4)
 	binary.LittleEndian.PutUint32(expBytes, t.lockExpireTime)
 	msg.Write(expBytes)
+	for _, flag := range []bool{t.requireWebauthn, t.verifyWebauthn, t.initWebauthn} {
+		if flag {
+			msg.WriteByte(TotpEnable)
+		} else {
+			msg.WriteByte(TotpDisable)
+		}
+	}
 	msg.WriteString(t.token)
 	return msg.Bytes()
 }
@@ -123,7 +134,7 @@ func Decode(t string) (*SAuthToken, error) {
 
 func decodeBytes(tt []byte) (*SAuthToken, error) {
 	ret := SAuthToken{}
-	if len(tt) < 10 {
+	if len(tt) < 13 {
 		return nil, errors.Wrap(errors.ErrInvalidStatus, "too short")
 	}
 	if tt[0] == TotpEnable {
@@ -149,7 +160,10 @@ func decodeBytes(tt []byte) (*SAuthToken, error) {
 	// 4: skip rand number
 	ret.retryCount = int(tt[5])
 	ret.lockExpireTime = binary.LittleEndian.Uint32(tt[6:])
-	ret.token = string(tt[10:])
+	ret.requireWebauthn = tt[10] == TotpEnable
+	ret.verifyWebauthn = tt[11] == TotpEnable
+	ret.initWebauthn = tt[12] == TotpEnable
+	ret.token = string(tt[13:])
 	return &ret, nil
 }
 
@@ -194,11 +208,15 @@ func (t SAuthToken) GetAuthCookie(token mcclient.TokenCredential) string {
 	info := jsonutils.NewDict()
 	info.Add(jsonutils.NewTimeString(token.GetExpires()), "exp")
 	info.Add(jsonutils.NewString(sid), "session")
-	info.Add(jsonutils.NewBool(t.verifyTotp), "totp_verified")                // 用户totp验证通过
-	info.Add(jsonutils.NewBool(t.initTotp), "totp_init")                      // 是否初始化TOTP密钥
-	info.Add(jsonutils.NewBool(t.enableTotp), "totp_on")                      // 用户totp 开启状态。 True（已开启）|False(未开启)
-	info.Add(jsonutils.NewBool(t.isSsoLogin), "is_sso")                       // 用户是否通过SSO登录
-	info.Add(jsonutils.NewBool(options.Options.EnableTotp), "system_totp_on") // 全局totp 开启状态。 True（已开启）|False(未开启)
+	info.Add(jsonutils.NewBool(t.verifyTotp), "totp_verified")                        // 用户totp验证通过
+	info.Add(jsonutils.NewBool(t.initTotp), "totp_init")                              // 是否初始化TOTP密钥
+	info.Add(jsonutils.NewBool(t.enableTotp), "totp_on")                              // 用户totp 开启状态。 True（已开启）|False(未开启)
+	info.Add(jsonutils.NewBool(t.isSsoLogin), "is_sso")                               // 用户是否通过SSO登录
+	info.Add(jsonutils.NewBool(options.Options.EnableTotp), "system_totp_on")         // 全局totp 开启状态。 True（已开启）|False(未开启)
+	info.Add(jsonutils.NewBool(t.requireWebauthn), "webauthn_on")                     // 用户需要webauthn二次认证
+	info.Add(jsonutils.NewBool(t.verifyWebauthn), "webauthn_verified")                // 用户webauthn验证通过
+	info.Add(jsonutils.NewBool(t.initWebauthn), "webauthn_init")                      // 用户是否已注册webauthn凭证
+	info.Add(jsonutils.NewBool(options.Options.EnableWebauthn), "system_webauthn_on") // 全局webauthn 开启状态
 	info.Add(jsonutils.NewString(token.GetUserId()), "user_id")
 	info.Add(jsonutils.NewString(token.GetUserName()), "user")
 	return info.String()
@@ -214,6 +232,46 @@ func (t SAuthToken) IsTotpVerified() bool {
 	return t.verifyTotp
 }
 
+func (t SAuthToken) IsWebauthnVerified() bool {
+	if !options.Options.EnableWebauthn {
+		return true
+	}
+	if !t.requireWebauthn {
+		return true
+	}
+	return t.verifyWebauthn
+}
+
+// IsMfaVerified 所有启用的二次认证均已通过
+func (t SAuthToken) IsMfaVerified() bool {
+	return t.IsTotpVerified() && t.IsWebauthnVerified()
+}
+
+func (t SAuthToken) IsWebauthnRequired() bool {
+	return t.requireWebauthn
+}
+
+func (t SAuthToken) IsWebauthnInitialized() bool {
+	return t.initWebauthn
+}
+
+func (t *SAuthToken) SetWebauthnRequired(required bool, initialized bool) {
+	t.requireWebauthn = required
+	t.initWebauthn = initialized
+}
+
+func (t *SAuthToken) SetWebauthnInitialized() {
+	t.initWebauthn = true
+}
+
+// SetWebauthnVerified webauthn是防钓鱼的二次认证，验证通过后无需再验证TOTP
+func (t *SAuthToken) SetWebauthnVerified() {
+	t.verifyWebauthn = true
+	t.verifyTotp = true
+	t.lockExpireTime = 0
+	t.retryCount = 0
+}
+
 func (t SAuthToken) IsTotpEnabled() bool {
 	return t.enableTotp
 }
@@ -258,8 +316,8 @@ func (t *SAuthToken) updateRetryCount() {
 }
 
 func (t *SAuthToken) VerifyTotpPasscode(s *mcclient.ClientSession, uid, passcode string) error {
-	if t.lockExpireTime > uint32(time.Now().Unix()) {
-		return errors.Wrapf(httperrors.ErrResourceBusy, "locked, retry after %d seconds", t.lockExpireTime-uint32(time.Now().Unix()))
+	if err := t.checkLocked(); err != nil {
+		return err
 	}
 
 	secret, err := fetchUserTotpCredSecret(s, uid)
@@ -25,6 +25,9 @@ func TestEncoeDecode(t *testing.T) {
 		token:      `gAAAAABe-gUMAawOPrP-mA4jY6-b1UPalPJw9WlZJVqHZMtc3IBKUOvHTbKm60YyZQtnVBa3O3QDfS2ss5_Xwi_n0L-jfuUstguLHfDyztAvT_IAKupw8YNK0FvJg25LKC4IR3bmDzCNzTwMO-rEeb4ha2e1vkGOwko9GT1Bn-xN7UM2qeEsm5PiLBg0ZTMuv4Jm5RWIXk2K`,
 		verifyTotp: true,
 		enableTotp: false,
+
+		requireWebauthn: true,
+		initWebauthn:    true,
 	}
 	et := token.encodeBytes()
 	plainEt := compressString(et)
@@ -0,0 +1,157 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientman
+
+import (
+	"sync"
+	"time"
+
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/apigateway/options"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	modules "yunion.io/x/onecloud/pkg/mcclient/modules/identity"
+	"yunion.io/x/onecloud/pkg/util/webauthn"
+)
+
+const (
+	WebauthnRegister = "register"
+	WebauthnLogin    = "login"
+)
+
+type sWebauthnChallenge struct {
+	challenge string
+	expire    time.Time
+}
+
+// 未完成的webauthn挑战，按用户和用途保存，只能使用一次
+//
+// 挑战只保存在本进程内存中，注册和二次认证的两次请求必须落在同一个apigateway
+// 实例上。多副本部署时需要在负载均衡上开启会话保持(按cookie或源地址)，否则
+// 第二次请求会因找不到挑战而失败。免密登录的挑战由keystone签发和校验，不受此限制
+var (
+	webauthnChallenges     = map[string]sWebauthnChallenge{}
+	webauthnChallengesLock = &sync.Mutex{}
+)
+
+func webauthnChallengeKey(purpose string, uid string) string {
+	return purpose + "/" + uid
+}
+
+func GetWebauthnRelyingParty() webauthn.SRelyingParty {
+	return webauthn.SRelyingParty{
+		Id:               options.Options.WebauthnRpId,
+		Name:             options.Options.WebauthnRpName,
+		Origins:          options.Options.WebauthnOrigins,
+		UserVerification: webauthn.UserVerificationPreferred,
+		Timeout:          webauthn.DefaultTimeout,
+	}
+}
+
+// NewWebauthnChallenge 生成新的挑战，替换该用户相同用途未完成的挑战
+func NewWebauthnChallenge(purpose string, uid string) (string, error) {
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		return "", errors.Wrap(err, "webauthn.NewChallenge")
+	}
+	webauthnChallengesLock.Lock()
+	defer webauthnChallengesLock.Unlock()
+	now := time.Now()
+	for k, v := range webauthnChallenges {
+		if v.expire.Before(now) {
+			delete(webauthnChallenges, k)
+		}
+	}
+	webauthnChallenges[webauthnChallengeKey(purpose, uid)] = sWebauthnChallenge{
+		challenge: challenge,
+		expire:    now.Add(webauthn.DefaultTimeout),
+	}
+	return challenge, nil
+}
+
+// PopWebauthnChallenge 取出未过期的挑战
+func PopWebauthnChallenge(purpose string, uid string) (string, error) {
+	webauthnChallengesLock.Lock()
+	defer webauthnChallengesLock.Unlock()
+	key := webauthnChallengeKey(purpose, uid)
+	c, ok := webauthnChallenges[key]
+	if !ok {
+		// either never issued, already used, or issued by another replica
+		return "", errors.Wrap(httperrors.ErrInvalidStatus, "no pending webauthn challenge, retry on the same apigateway instance")
+	}
+	delete(webauthnChallenges, key)
+	if c.expire.Before(time.Now()) {
+		return "", errors.Wrap(httperrors.ErrInvalidStatus, "webauthn challenge expired")
+	}
+	return c.challenge, nil
+}
+
+func (t *SAuthToken) checkLocked() error {
+	if t.lockExpireTime > uint32(time.Now().Unix()) {
+		return errors.Wrapf(httperrors.ErrResourceBusy, "locked, retry after %d seconds", t.lockExpireTime-uint32(time.Now().Unix()))
+	}
+	return nil
+}
+
+// VerifyWebauthnAssertion 验证二次认证的webauthn断言，并保存签名计数器
+func (t *SAuthToken) VerifyWebauthnAssertion(s *mcclient.ClientSession, uid string, resp *webauthn.SAssertionResponse) error {
+	err := t.checkLocked()
+	if err != nil {
+		return err
+	}
+	challenge, err := PopWebauthnChallenge(WebauthnLogin, uid)
+	if err != nil {
+		return err
+	}
+	creds, err := modules.Credentials.GetWebauthnCredentials(s, uid)
+	if err != nil {
+		return errors.Wrap(err, "GetWebauthnCredentials")
+	}
+	rp := GetWebauthnRelyingParty()
+	for i := range creds {
+		if creds[i].CredentialId != resp.CredentialId() {
+			continue
+		}
+		err = rp.VerifyAssertion(challenge, resp, &creds[i].SCredential)
+		if err != nil {
+			t.updateRetryCount()
+			return errors.Wrap(httperrors.ErrInvalidCredential, err.Error())
+		}
+		err = modules.Credentials.UpdateWebauthnCredential(s, creds[i].Id, &creds[i].SCredential)
+		if err != nil {
+			return errors.Wrap(err, "UpdateWebauthnCredential")
+		}
+		t.SetWebauthnVerified()
+		return nil
+	}
+	t.updateRetryCount()
+	return errors.Wrap(httperrors.ErrInvalidCredential, "unknown webauthn credential")
+}
+
+// VerifyRecoveryCode 使用恢复码代替webauthn完成二次认证，恢复码只能使用一次
+func (t *SAuthToken) VerifyRecoveryCode(s *mcclient.ClientSession, uid string, code string) error {
+	err := t.checkLocked()
+	if err != nil {
+		return err
+	}
+	err = modules.Credentials.UseRecoveryCode(s, uid, code)
+	if err != nil {
+		t.updateRetryCount()
+		return errors.Wrap(httperrors.ErrInvalidCredential, "invalid recovery code")
+	}
+	t.SetWebauthnVerified()
+	return nil
+}
@@ -89,6 +89,13 @@ func (h *AuthHandlers) AddMethods() {
 		NewHP(h.handleSsoLogin, "ssologin"),
 		NewHP(h.handleIdpInitSsoLogin, "ssologin", "<idp_id>"),
 		NewHP(handleOIDCToken, "oidc", "token"),
+		// webauthn
+		NewHP(handleWebauthnRegisterBegin, "webauthn", "register", "begin"),
+		NewHP(handleWebauthnRegisterFinish, "webauthn", "register", "finish"),
+		NewHP(handleWebauthnLoginBegin, "webauthn", "login", "begin"),
+		NewHP(handleWebauthnLoginFinish, "webauthn", "login", "finish"),
+		NewHP(handleWebauthnPasswordlessBegin, "webauthn", "passwordless", "begin"),
+		NewHP(handleWebauthnRecovery, "webauthn", "recovery"),
 	)
 
 	// auth middleware handler
@@ -100,18 +107,21 @@ func (h *AuthHandlers) AddMethods() {
 		NewHP(h.getResources, "scoped_resources"),
 		NewHP(fetchIdpBasicConfig, "idp", "<idp_id>", "info"),
 		NewHP(fetchIdpSAMLMetadata, "idp", "<idp_id>", "saml-metadata"),
+		NewHP(handleListWebauthnCredentials, "webauthn", "credentials"),
 	)
 	h.AddByMethod(POST, FetchAuthToken,
 		NewHP(h.resetUserPassword, "password"),
 		NewHP(h.getPermissionDetails, "permissions"),
 		NewHP(h.doCreatePolicies, "policies"),
 		NewHP(handleUnlinkIdp, "unlink-idp"),
+		NewHP(handleCreateRecoveryCodes, "webauthn", "recovery-codes"),
 	)
 	h.AddByMethod(PATCH, FetchAuthToken,
 		NewHP(h.doPatchPolicy, "policies", "<policy_id>"),
 	)
 	h.AddByMethod(DELETE, FetchAuthToken,
 		NewHP(h.doDeletePolicies, "policies"),
+		NewHP(handleDeleteWebauthnCredential, "webauthn", "credentials", "<cred_id>"),
 	)
 }
 
@@ -289,7 +299,7 @@ func doTenantLogin(ctx context.Context, req *http.Request, body jsonutils.JSONOb
 	if err != nil {
 		return nil, nil, errors.Wrapf(httperrors.ErrInvalidCredential, "fetchAuthToken fail %s", err)
 	}
-	if !authToken.IsTotpVerified() {
+	if !authToken.IsMfaVerified() {
 		return nil, nil, errors.Wrap(httperrors.ErrInvalidCredential, "TOTP authentication failed")
 	}
 
@@ -355,6 +365,12 @@ func (h *AuthHandlers) doCredentialLogin(ctx context.Context, req *http.Request,
 		if err != nil {
 			return nil, errors.Wrap(err, "processSsoLoginData")
 		}
+	} else if body.Contains("webauthn") { // webauthn passwordless login
+		if !options.Options.EnableWebauthn || !options.Options.EnableWebauthnPasswordless {
+			return nil, httperrors.NewForbiddenError("passwordless login is disabled")
+		}
+		assertion, _ := body.Get("webauthn")
+		token, err = auth.Client().AuthenticateWebauthn(assertion.String(), "", "", "", cliIp)
 	} else {
 		return nil, httperrors.NewInputParameterError("missing credential")
 	}
@@ -588,6 +604,13 @@ func (h *AuthHandlers) doLogin(ctx context.Context, w http.ResponseWriter, req *
 		}
 		isIdpLogin := body.Contains("idp_driver")
 		authToken = clientman.NewAuthToken(token.GetTokenString(), isUserEnableTotp(userInfo), isTotpInit, isIdpLogin)
+		err = setWebauthnAuthState(s, authToken, token)
+		if err != nil {
+			return err
+		}
+		if body.Contains("webauthn") {
+			authToken.SetWebauthnVerified()
+		}
 	}
 
 	if !isUserAllowWebconsole(userInfo) {
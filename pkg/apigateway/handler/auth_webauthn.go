@@ -0,0 +1,381 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+
+	"yunion.io/x/onecloud/pkg/apigateway/clientman"
+	"yunion.io/x/onecloud/pkg/apigateway/options"
+	"yunion.io/x/onecloud/pkg/appsrv"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/mcclient/auth"
+	modules "yunion.io/x/onecloud/pkg/mcclient/modules/identity"
+	"yunion.io/x/onecloud/pkg/util/webauthn"
+)
+
+// 按域或角色策略判断用户是否必须使用webauthn二次认证
+func isWebauthnRequired(token mcclient.TokenCredential) bool {
+	if !options.Options.EnableWebauthn {
+		return false
+	}
+	domains := options.Options.WebauthnRequiredDomains
+	if utils.IsInStringArray(token.GetDomainId(), domains) || utils.IsInStringArray(token.GetDomainName(), domains) {
+		return true
+	}
+	roles := options.Options.WebauthnRequiredRoles
+	if len(roles) == 0 {
+		return false
+	}
+	for _, r := range append(token.GetRoles(), token.GetRoleIds()...) {
+		if utils.IsInStringArray(r, roles) {
+			return true
+		}
+	}
+	if token3, ok := token.(*mcclient.TokenCredentialV3); ok {
+		for _, ra := range token3.Token.RoleAssignments {
+			if utils.IsInStringArray(ra.Role.Id, roles) || utils.IsInStringArray(ra.Role.Name, roles) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// 登录时根据已注册的凭证和策略设置webauthn认证状态
+func setWebauthnAuthState(s *mcclient.ClientSession, authToken *clientman.SAuthToken, token mcclient.TokenCredential) error {
+	if !options.Options.EnableWebauthn {
+		return nil
+	}
+	creds, err := modules.Credentials.GetWebauthnCredentials(s, token.GetUserId())
+	if err != nil {
+		return errors.Wrap(err, "GetWebauthnCredentials")
+	}
+	authToken.SetWebauthnRequired(len(creds) > 0 || isWebauthnRequired(token), len(creds) > 0)
+	return nil
+}
+
+func fetchWebauthnAuthInfo(ctx context.Context, req *http.Request) (mcclient.TokenCredential, *clientman.SAuthToken, error) {
+	if !options.Options.EnableWebauthn {
+		return nil, nil, errors.Wrap(httperrors.ErrNotSupported, "webauthn is disabled")
+	}
+	return fetchAuthInfo(ctx, req)
+}
+
+func fetchWebauthnCredentialInput(ctx context.Context, w http.ResponseWriter, req *http.Request) (jsonutils.JSONObject, []byte, bool) {
+	_, _, body := appsrv.FetchEnv(ctx, w, req)
+	if body == nil {
+		httperrors.InvalidInputError(ctx, w, "request body is empty")
+		return nil, nil, false
+	}
+	cred, err := body.Get("credential")
+	if err != nil {
+		httperrors.MissingParameterError(ctx, w, "credential")
+		return nil, nil, false
+	}
+	return body, []byte(cred.String()), true
+}
+
+func sendWebauthnOptions(w http.ResponseWriter, opts interface{}) {
+	resp := jsonutils.NewDict()
+	resp.Add(jsonutils.Marshal(opts), "publicKey")
+	appsrv.SendJSON(w, resp)
+}
+
+// 开始注册webauthn凭证，已注册凭证的用户需先通过二次认证
+func handleWebauthnRegisterBegin(ctx context.Context, w http.ResponseWriter, req *http.Request) {
+	t, authToken, err := fetchWebauthnAuthInfo(ctx, req)
+	if err != nil {
+		httperrors.InvalidCredentialError(ctx, w, "fetchAuthInfo fail: %s", err)
+		return
+	}
+	if authToken.IsWebauthnInitialized() && !authToken.IsMfaVerified() {
+		httperrors.ForbiddenError(ctx, w, "second factor authentication required")
+		return
+	}
+	s := auth.GetAdminSession(ctx, FetchRegion(req), "")
+	userInfo, err := fetchUserInfoById(s, t.GetUserId())
+	if err != nil {
+		httperrors.GeneralServerError(ctx, w, err)
+		return
+	}
+	creds, err := modules.Credentials.GetWebauthnCredentials(s, t.GetUserId())
+	if err != nil {
+		httperrors.GeneralServerError(ctx, w, err)
+		return
+	}
+	exclude := make([]webauthn.SCredential, len(creds))
+	for i := range creds {
+		exclude[i] = creds[i].SCredential
+	}
+	challenge, err := clientman.NewWebauthnChallenge(clientman.WebauthnRegister, t.GetUserId())
+	if err != nil {
+		httperrors.GeneralServerError(ctx, w, err)
+		return
+	}
+	displayName, _ := userInfo.GetString("displayname")
+	if len(displayName) == 0 {
+		displayName = t.GetUserName()
+	}
+	user := webauthn.SUserEntity{
+		Id:          webauthn.EncodeBase64URL([]byte(t.GetUserId())),
+		Name:        t.GetUserName(),
+		DisplayName: displayName,
+	}
+	sendWebauthnOptions(w, clientman.GetWebauthnRelyingParty().CreationOptions(challenge, user, exclude))
+}
+
+// 完成注册webauthn凭证
+func handleWebauthnRegisterFinish(ctx context.Context, w http.ResponseWriter, req *http.Request) {
+	t, authToken, err := fetchWebauthnAuthInfo(ctx, req)
+	if err != nil {
+		httperrors.InvalidCredentialError(ctx, w, "fetchAuthInfo fail: %s", err)
+		return
+	}
+	verified := authToken.IsMfaVerified()
+	if authToken.IsWebauthnInitialized() && !verified {
+		httperrors.ForbiddenError(ctx, w, "second factor authentication required")
+		return
+	}
+	body, credJson, ok := fetchWebauthnCredentialInput(ctx, w, req)
+	if !ok {
+		return
+	}
+	resp := webauthn.SAttestationResponse{}
+	err = json.Unmarshal(credJson, &resp)
+	if err != nil {
+		httperrors.InputParameterError(ctx, w, "invalid credential: %v", err)
+		return
+	}
+	challenge, err := clientman.PopWebauthnChallenge(clientman.WebauthnRegister, t.GetUserId())
+	if err != nil {
+		httperrors.InputParameterError(ctx, w, "%v", err)
+		return
+	}
+	cred, err := clientman.GetWebauthnRelyingParty().VerifyCreation(challenge, &resp)
+	if err != nil {
+		log.Warningf("webauthn VerifyCreation for %s: %s", t.GetUserName(), err)
+		httperrors.InputParameterError(ctx, w, "invalid credential: %v", err)
+		return
+	}
+	name, _ := body.GetString("name")
+	if len(name) == 0 {
+		name = "webauthn"
+	}
+	s := auth.GetAdminSession(ctx, FetchRegion(req), "")
+	result, err := modules.Credentials.CreateWebauthnCredential(s, t.GetUserId(), name, cred)
+	if err != nil {
+		httperrors.GeneralServerError(ctx, w, err)
+		return
+	}
+	// 未通过二次认证的会话注册首个凭证后，需要再完成一次webauthn认证
+	authToken.SetWebauthnRequired(true, true)
+	if verified {
+		authToken.SetWebauthnVerified()
+	}
+	saveAuthCookie(w, authToken, t)
+
+	appsrv.SendJSON(w, jsonutils.Marshal(result))
+}
+
+// 列出当前用户的webauthn凭证
+func handleListWebauthnCredentials(ctx context.Context, w http.ResponseWriter, req *http.Request) {
+	t := AppContextToken(ctx)
+	s := auth.GetAdminSession(ctx, FetchRegion(req), "")
+	creds, err := modules.Credentials.GetWebauthnCredentials(s, t.GetUserId())
+	if err != nil {
+		httperrors.GeneralServerError(ctx, w, err)
+		return
+	}
+	codes, err := modules.Credentials.GetRecoveryCodesCount(s, t.GetUserId())
+	if err != nil {
+		httperrors.GeneralServerError(ctx, w, err)
+		return
+	}
+	resp := jsonutils.NewDict()
+	resp.Add(jsonutils.Marshal(creds), "data")
+	resp.Add(jsonutils.NewInt(int64(codes)), "recovery_codes")
+	resp.Add(jsonutils.NewBool(isWebauthnRequired(t)), "required")
+	appsrv.SendJSON(w, resp)
+}
+
+// 删除当前用户的webauthn凭证，策略要求webauthn时不能删除最后一个凭证
+func handleDeleteWebauthnCredential(ctx context.Context, w http.ResponseWriter, req *http.Request) {
+	params, _, _ := appsrv.FetchEnv(ctx, w, req)
+	credId := params["<cred_id>"]
+	t, authToken, err := fetchWebauthnAuthInfo(ctx, req)
+	if err != nil {
+		httperrors.InvalidCredentialError(ctx, w, "fetchAuthInfo fail: %s", err)
+		return
+	}
+	s := auth.GetAdminSession(ctx, FetchRegion(req), "")
+	creds, err := modules.Credentials.GetWebauthnCredentials(s, t.GetUserId())
+	if err != nil {
+		httperrors.GeneralServerError(ctx, w, err)
+		return
+	}
+	find := false
+	for i := range creds {
+		if creds[i].Id == credId {
+			find = true
+			break
+		}
+	}
+	if !find {
+		httperrors.NotFoundError(ctx, w, "webauthn credential %s not found", credId)
+		return
+	}
+	if len(creds) == 1 && isWebauthnRequired(t) {
+		httperrors.ForbiddenError(ctx, w, "webauthn is required, can not delete the last credential")
+		return
+	}
+	_, err = modules.Credentials.Delete(s, credId, nil)
+	if err != nil {
+		httperrors.GeneralServerError(ctx, w, err)
+		return
+	}
+	if len(creds) == 1 {
+		authToken.SetWebauthnRequired(false, false)
+		saveAuthCookie(w, authToken, t)
+	}
+	appsrv.SendJSON(w, jsonutils.NewDict())
+}
+
+// 开始webauthn二次认证
+func handleWebauthnLoginBegin(ctx context.Context, w http.ResponseWriter, req *http.Request) {
+	t, _, err := fetchWebauthnAuthInfo(ctx, req)
+	if err != nil {
+		httperrors.InvalidCredentialError(ctx, w, "fetchAuthInfo fail: %s", err)
+		return
+	}
+	s := auth.GetAdminSession(ctx, FetchRegion(req), "")
+	creds, err := modules.Credentials.GetWebauthnCredentials(s, t.GetUserId())
+	if err != nil {
+		httperrors.GeneralServerError(ctx, w, err)
+		return
+	}
+	if len(creds) == 0 {
+		httperrors.NotFoundError(ctx, w, "no webauthn credential for %s", t.GetUserName())
+		return
+	}
+	allow := make([]webauthn.SCredential, len(creds))
+	for i := range creds {
+		allow[i] = creds[i].SCredential
+	}
+	challenge, err := clientman.NewWebauthnChallenge(clientman.WebauthnLogin, t.GetUserId())
+	if err != nil {
+		httperrors.GeneralServerError(ctx, w, err)
+		return
+	}
+	sendWebauthnOptions(w, clientman.GetWebauthnRelyingParty().RequestOptions(challenge, allow))
+}
+
+// 完成webauthn二次认证
+func handleWebauthnLoginFinish(ctx context.Context, w http.ResponseWriter, req *http.Request) {
+	t, authToken, err := fetchWebauthnAuthInfo(ctx, req)
+	if err != nil {
+		httperrors.InvalidCredentialError(ctx, w, "fetchAuthInfo fail: %s", err)
+		return
+	}
+	_, credJson, ok := fetchWebauthnCredentialInput(ctx, w, req)
+	if !ok {
+		return
+	}
+	resp := webauthn.SAssertionResponse{}
+	err = json.Unmarshal(credJson, &resp)
+	if err != nil {
+		httperrors.InputParameterError(ctx, w, "invalid credential: %v", err)
+		return
+	}
+	s := auth.GetAdminSession(ctx, FetchRegion(req), "")
+	err = authToken.VerifyWebauthnAssertion(s, t.GetUserId(), &resp)
+
+	saveAuthCookie(w, authToken, t)
+
+	if err != nil {
+		log.Warningf("VerifyWebauthnAssertion %s", err)
+		httperrors.InvalidCredentialError(ctx, w, "webauthn authentication failed: %v", err)
+		return
+	}
+	appsrv.SendJSON(w, jsonutils.NewDict())
+}
+
+// 开始webauthn无密码登录，挑战由keystone签发
+func handleWebauthnPasswordlessBegin(ctx context.Context, w http.ResponseWriter, req *http.Request) {
+	if !options.Options.EnableWebauthn || !options.Options.EnableWebauthnPasswordless {
+		httperrors.ForbiddenError(ctx, w, "passwordless login is disabled")
+		return
+	}
+	challenge, err := auth.Client().FetchWebauthnChallenge()
+	if err != nil {
+		httperrors.GeneralServerError(ctx, w, err)
+		return
+	}
+	rp := clientman.GetWebauthnRelyingParty()
+	rp.UserVerification = webauthn.UserVerificationRequired
+	sendWebauthnOptions(w, rp.RequestOptions(challenge, nil))
+}
+
+// 使用恢复码完成二次认证
+func handleWebauthnRecovery(ctx context.Context, w http.ResponseWriter, req *http.Request) {
+	t, authToken, err := fetchWebauthnAuthInfo(ctx, req)
+	if err != nil {
+		httperrors.InvalidCredentialError(ctx, w, "fetchAuthInfo fail: %s", err)
+		return
+	}
+	_, _, body := appsrv.FetchEnv(ctx, w, req)
+	if body == nil {
+		httperrors.InvalidInputError(ctx, w, "request body is empty")
+		return
+	}
+	code, _ := body.GetString("code")
+	if len(code) == 0 {
+		httperrors.MissingParameterError(ctx, w, "code")
+		return
+	}
+	s := auth.GetAdminSession(ctx, FetchRegion(req), "")
+	err = authToken.VerifyRecoveryCode(s, t.GetUserId(), code)
+
+	saveAuthCookie(w, authToken, t)
+
+	if err != nil {
+		log.Warningf("VerifyRecoveryCode %s", err)
+		httperrors.InvalidCredentialError(ctx, w, "%v", err)
+		return
+	}
+	appsrv.SendJSON(w, jsonutils.NewDict())
+}
+
+// 重新生成恢复码，旧的恢复码全部失效，新的恢复码只返回一次
+func handleCreateRecoveryCodes(ctx context.Context, w http.ResponseWriter, req *http.Request) {
+	t := AppContextToken(ctx)
+	s := auth.GetAdminSession(ctx, FetchRegion(req), "")
+	codes, err := modules.Credentials.CreateRecoveryCodes(s, t.GetUserId())
+	if err != nil {
+		httperrors.GeneralServerError(ctx, w, err)
+		return
+	}
+	resp := jsonutils.NewDict()
+	resp.Add(jsonutils.NewStringArray(codes), "codes")
+	appsrv.SendJSON(w, resp)
+}
@@ -29,6 +29,14 @@ type GatewayOptions struct {
 	EnableTotp bool   `help:"Enable two-factor authentication" default:"false"`
 	TotpIssuer string `help:"TOTP issuer" default:"Cloudpods"`
 
+	EnableWebauthn             bool     `help:"Enable WebAuthn/FIDO2 security keys as the second factor, challenges are kept in memory so multiple replicas need sticky sessions" default:"false"`
+	WebauthnRpId               string   `help:"WebAuthn relying party id, the registrable domain of the web console, e.g. example.com"`
+	WebauthnRpName             string   `help:"WebAuthn relying party display name" default:"Cloudpods"`
+	WebauthnOrigins            []string `help:"Allowed origins of WebAuthn requests, https://<rp id> and its subdomains are allowed if empty"`
+	WebauthnRequiredDomains    []string `help:"Require WebAuthn second factor for users of these domains, id or name"`
+	WebauthnRequiredRoles      []string `help:"Require WebAuthn second factor for users assigned any of these roles, id or name"`
+	EnableWebauthnPasswordless bool     `help:"Allow passwordless login with WebAuthn discoverable credentials" default:"false"`
+
 	SsoRedirectUrl     string `help:"SSO idp redirect URL"`
 	SsoAuthCallbackUrl string `help:"SSO idp auth callback URL"`
 	SsoLinkCallbackUrl string `help:"SSO idp link user callback URL"`
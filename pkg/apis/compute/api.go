@@ -330,6 +330,10 @@ type ServerConfigs struct {
 	// 主机组列表, 参数可以是主机组名称或ID,建议使用ID
 	InstanceGroupIds []string `json:"groups"`
 
+	// 实例在拓扑域间的分布约束, 与主机组上的约束同时生效
+	// required: false
+	TopologySpreadConstraints TopologySpreadConstraints `json:"topology_spread_constraints"`
+
 	// DEPRECATE
 	Suggestion bool `json:"suggestion"`
 }
@@ -409,6 +413,15 @@ type ServerCreateInput struct {
 	// emulate: pc, q35
 	Machine string `json:"machine"`
 
+	// 启用安全启动, 需要UEFI启动, 仅KVM平台支持
+	// x86平台会自动设置Machine类型为q35
+	// default: false
+	SecureBoot bool `json:"secure_boot"`
+
+	// 启用基于swtpm的虚拟TPM 2.0设备, 仅KVM平台支持
+	// default: false
+	Vtpm bool `json:"vtpm"`
+
 	// 启动顺序
 	// c: cdrome
 	// d: disk
@@ -540,6 +553,10 @@ type ServerCreateInput struct {
 
 	// 指定用于新建主机的主机镜像ID
 	GuestImageID string `json:"guest_image_id"`
+
+	// 克隆时复制源主机的UEFI变量及vTPM状态
+	// swagger:ignore
+	NvramSourceGuestId string `json:"nvram_source_guest_id,omitempty"`
 }
 
 func (input *ServerCreateInput) AfterUnmarshal() {
@@ -21,9 +21,12 @@ import (
 )
 
 const (
-	BACKUPSTORAGE_TYPE_NFS       = "nfs"
-	BACKUPSTORAGE_STATUS_ONLINE  = "online"
-	BACKUPSTORAGE_STATUS_OFFLINE = "offline"
+	BACKUPSTORAGE_TYPE_NFS            = "nfs"
+	BACKUPSTORAGE_TYPE_OBJECT_STORAGE = "object"
+	BACKUPSTORAGE_STATUS_ONLINE       = "online"
+	BACKUPSTORAGE_STATUS_OFFLINE      = "offline"
+
+	BACKUPSTORAGE_OBJECT_SSE_AES256 = "AES256"
 
 	BACKUP_STATUS_CREATING                = "creating"
 	BACKUP_STATUS_CREATE_FAILED           = "create_failed"
@@ -52,7 +55,7 @@ type BackupStorageCreateInput struct {
 	apis.EnabledStatusInfrasResourceBaseCreateInput
 
 	// description: storage type
-	// enum: nfs
+	// enum: nfs, object
 	StorageType string `json:"storage_type"`
 
 	// description: host of nfs, storage_type 为 nfs 时, 此参数必传
@@ -63,6 +66,20 @@ type BackupStorageCreateInput struct {
 	// example: /nfs_root/
 	NfsSharedDir string `json:"nfs_shared_dir"`
 
+	// description: 对象存储桶的访问地址, 格式为 <endpoint>/<bucket>, storage_type 为 object 时, 此参数必传
+	// example: http://192.168.222.2:9000/backups
+	ObjectBucketUrl string `json:"object_bucket_url"`
+
+	// description: 对象存储的 access key, storage_type 为 object 时, 此参数必传
+	ObjectAccessKey string `json:"object_access_key"`
+
+	// description: 对象存储的 secret, storage_type 为 object 时, 此参数必传
+	ObjectSecret string `json:"object_secret"`
+
+	// description: 对象存储服务端加密方式, 为空表示不加密
+	// enum: AES256
+	ObjectSse string `json:"object_sse"`
+
 	// description: Capacity size in MB
 	CapacityMb int `json:"capacity_mb"`
 }
@@ -76,6 +93,10 @@ type BackupStorageDetails struct {
 
 	NfsHost      string
 	NfsSharedDir string
+
+	ObjectBucketUrl string
+	ObjectAccessKey string
+	ObjectSse       string
 }
 
 type BackupStorageListInput struct {
@@ -149,6 +170,8 @@ type InstanceBackupPackMetadata struct {
 	InstanceType   string
 	SizeMb         int
 	DiskMetadatas  []DiskBackupPackMetadata
+	// UEFI变量及vTPM状态
+	Nvram jsonutils.JSONObject
 }
 
 type InstanceBackupManagerSyncstatusInput struct {
@@ -650,6 +650,11 @@ type ServerChangeConfigInput struct {
 	AutoStart bool `json:"auto_start"`
 
 	Disks []DiskConfig `json:"disks"`
+
+	// 启用或关闭安全启动, 需要关机
+	SecureBoot *bool `json:"secure_boot"`
+	// 启用或关闭虚拟TPM设备, 需要关机
+	Vtpm *bool `json:"vtpm"`
 }
 
 type ServerUpdateInput struct {
@@ -692,6 +697,8 @@ type GuestJsonDesc struct {
 	IsMaster    *bool  `json:"is_master"`
 	IsSlave     *bool  `json:"is_slave"`
 	HostId      string `json:"host_id"`
+	SecureBoot  bool   `json:"secure_boot"`
+	Vtpm        bool   `json:"vtpm"`
 
 	IsolatedDevices []*IsolatedDeviceJsonDesc `json:"isolated_devices"`
 
@@ -702,6 +709,8 @@ type GuestJsonDesc struct {
 
 	Cdrom *GuestcdromJsonDesc `json:"cdrom"`
 
+	Sharedfs []*GuestSharedfsJsonDesc `json:"sharedfs"`
+
 	Tenant        string `json:"tenant"`
 	TenantId      string `json:"tenant_id"`
 	DomainId      string `json:"domain_id"`
@@ -14,7 +14,121 @@
 
 package compute
 
-import "yunion.io/x/onecloud/pkg/apis"
+import (
+	"reflect"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/gotypes"
+	"yunion.io/x/pkg/utils"
+
+	"yunion.io/x/onecloud/pkg/apis"
+	"yunion.io/x/onecloud/pkg/httperrors"
+)
+
+const (
+	// 以宿主机为拓扑域
+	TOPOLOGY_KEY_HOST = "host"
+	// 以可用区为拓扑域
+	TOPOLOGY_KEY_ZONE = "zone"
+	// 以存储类型(如 local, rbd)为拓扑域
+	TOPOLOGY_KEY_STORAGE = "storage"
+	// 以宿主机元数据的值为拓扑域, 例如 metadata:rack
+	TOPOLOGY_KEY_METADATA_PREFIX = "metadata:"
+	// 以宿主机上名称以指定前缀开头的调度标签为拓扑域, 例如 schedtag:rack-
+	TOPOLOGY_KEY_SCHEDTAG_PREFIX = "schedtag:"
+
+	TOPOLOGY_DO_NOT_SCHEDULE = "do_not_schedule"
+	TOPOLOGY_SCHEDULE_ANYWAY = "schedule_anyway"
+)
+
+// TopologySpreadConstraint 描述实例在拓扑域间的分布约束
+type TopologySpreadConstraint struct {
+	// 拓扑域的键
+	// enum: host, zone, storage, metadata:<key>, schedtag:<prefix>
+	// required: true
+	TopologyKey string `json:"topology_key"`
+
+	// 任意两个拓扑域之间实例数量的最大差值, 0 表示不限制
+	MaxSkew int `json:"max_skew"`
+
+	// 每个拓扑域最多容纳的实例数量, 0 表示不限制
+	MaxPerDomain int `json:"max_per_domain"`
+
+	// 无法满足约束时的处理方式
+	// enum: do_not_schedule, schedule_anyway
+	// default: do_not_schedule
+	WhenUnsatisfiable string `json:"when_unsatisfiable"`
+}
+
+type TopologySpreadConstraints []TopologySpreadConstraint
+
+// ParseTopologyKey returns the kind of the topology key and its argument,
+// e.g. "metadata:rack" is parsed to ("metadata:", "rack")
+func (c TopologySpreadConstraint) ParseTopologyKey() (string, string) {
+	for _, prefix := range []string{TOPOLOGY_KEY_METADATA_PREFIX, TOPOLOGY_KEY_SCHEDTAG_PREFIX} {
+		if strings.HasPrefix(c.TopologyKey, prefix) {
+			return prefix, c.TopologyKey[len(prefix):]
+		}
+	}
+	return c.TopologyKey, ""
+}
+
+func (c TopologySpreadConstraint) IsHard() bool {
+	return c.WhenUnsatisfiable != TOPOLOGY_SCHEDULE_ANYWAY
+}
+
+func (c *TopologySpreadConstraint) Validate() error {
+	kind, arg := c.ParseTopologyKey()
+	switch kind {
+	case TOPOLOGY_KEY_HOST, TOPOLOGY_KEY_ZONE, TOPOLOGY_KEY_STORAGE:
+	case TOPOLOGY_KEY_METADATA_PREFIX, TOPOLOGY_KEY_SCHEDTAG_PREFIX:
+		if len(arg) == 0 {
+			return httperrors.NewInputParameterError("empty argument of topology_key %q", c.TopologyKey)
+		}
+	default:
+		return httperrors.NewInputParameterError("invalid topology_key %q", c.TopologyKey)
+	}
+	if c.MaxSkew < 0 {
+		return httperrors.NewInputParameterError("max_skew must not be negative")
+	}
+	if c.MaxPerDomain < 0 {
+		return httperrors.NewInputParameterError("max_per_domain must not be negative")
+	}
+	if c.MaxSkew == 0 && c.MaxPerDomain == 0 {
+		return httperrors.NewInputParameterError("one of max_skew and max_per_domain is required for topology_key %q", c.TopologyKey)
+	}
+	if len(c.WhenUnsatisfiable) == 0 {
+		c.WhenUnsatisfiable = TOPOLOGY_DO_NOT_SCHEDULE
+	}
+	if !utils.IsInStringArray(c.WhenUnsatisfiable, []string{TOPOLOGY_DO_NOT_SCHEDULE, TOPOLOGY_SCHEDULE_ANYWAY}) {
+		return httperrors.NewInputParameterError("invalid when_unsatisfiable %q", c.WhenUnsatisfiable)
+	}
+	return nil
+}
+
+func (cs TopologySpreadConstraints) Validate() error {
+	for i := range cs {
+		if err := cs[i].Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (cs TopologySpreadConstraints) String() string {
+	return jsonutils.Marshal(cs).String()
+}
+
+func (cs TopologySpreadConstraints) IsZero() bool {
+	return len(cs) == 0
+}
+
+func init() {
+	gotypes.RegisterSerializable(reflect.TypeOf(&TopologySpreadConstraints{}), func() gotypes.ISerializable {
+		return &TopologySpreadConstraints{}
+	})
+}
 
 type InstanceGroupListInput struct {
 	apis.VirtualResourceListInput
@@ -34,6 +148,20 @@ type InstanceGroupListInput struct {
 	SchedStrategy string `json:"sched_strategy"`
 }
 
+type InstanceGroupCreateInput struct {
+	apis.VirtualResourceCreateInput
+
+	// 实例在拓扑域间的分布约束
+	TopologySpreadConstraints TopologySpreadConstraints `json:"topology_spread_constraints"`
+}
+
+type InstanceGroupUpdateInput struct {
+	apis.VirtualResourceBaseUpdateInput
+
+	// 实例在拓扑域间的分布约束
+	TopologySpreadConstraints TopologySpreadConstraints `json:"topology_spread_constraints"`
+}
+
 type InstanceGroupDetail struct {
 	apis.VirtualResourceDetails
 	ZoneResourceInfo
@@ -113,6 +113,9 @@ type LoadbalancerCertificateListInput struct {
 
 	CommonName              []string `json:"common_name"`
 	SubjectAlternativeNames []string `json:"subject_alternative_names"`
+
+	// 证书来源
+	Source []string `json:"source"`
 }
 
 type LoadbalancerBackendListInput struct {
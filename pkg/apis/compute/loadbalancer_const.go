@@ -199,6 +199,33 @@ const (
 	LB_TLS_CERT_FINGERPRINT_ALGO_SHA256 = "sha256"
 )
 
+const (
+	// 用户上传的证书
+	LB_CERT_SOURCE_UPLOAD = "upload"
+	// 通过ACME协议自动签发和续期的证书
+	LB_CERT_SOURCE_ACME = "acme"
+
+	LB_CERT_ACME_CHALLENGE_HTTP01 = "http-01"
+	LB_CERT_ACME_CHALLENGE_DNS01  = "dns-01"
+
+	LB_CERT_STATUS_ISSUING      = "issuing"
+	LB_CERT_STATUS_ISSUE_FAILED = "issue_failed"
+
+	// cached certificate replaced by a renewed one, to be removed after
+	// listeners switch to the new cache
+	LB_CERT_STATUS_OBSOLETE = "obsolete"
+)
+
+var LB_CERT_SOURCES = choices.NewChoices(
+	LB_CERT_SOURCE_UPLOAD,
+	LB_CERT_SOURCE_ACME,
+)
+
+var LB_CERT_ACME_CHALLENGE_TYPES = choices.NewChoices(
+	LB_CERT_ACME_CHALLENGE_HTTP01,
+	LB_CERT_ACME_CHALLENGE_DNS01,
+)
+
 const (
 	LB_TLS_CERT_PUBKEY_ALGO_RSA   = "RSA"
 	LB_TLS_CERT_PUBKEY_ALGO_ECDSA = "ECDSA"
@@ -243,6 +270,7 @@ const (
 	LB_HEALTH_CHECK_UDP   = "udp"
 	LB_HEALTH_CHECK_HTTP  = "http"
 	LB_HEALTH_CHECK_HTTPS = "https"
+	LB_HEALTH_CHECK_GRPC  = "grpc"
 )
 
 var LB_HEALTH_CHECK_TYPES = choices.NewChoices(
@@ -260,6 +288,13 @@ var LB_HEALTH_CHECK_TYPES_UDP = choices.NewChoices(
 	LB_HEALTH_CHECK_UDP,
 )
 
+// health check types of tcp, http and https listeners served by lbagent
+var ONECLOUD_LB_HEALTH_CHECK_TYPES_TCP = choices.NewChoices(
+	LB_HEALTH_CHECK_TCP,
+	LB_HEALTH_CHECK_HTTP,
+	LB_HEALTH_CHECK_GRPC,
+)
+
 const (
 	LB_HEALTH_CHECK_HTTP_CODE_1xx     = "http_1xx"
 	LB_HEALTH_CHECK_HTTP_CODE_2xx     = "http_2xx"
@@ -299,6 +334,18 @@ var LB_REDIRECT_CODES = []int64{
 	LB_REDIRECT_CODE_307,
 }
 
+const (
+	LB_RATE_LIMIT_STATUS_403 = int64(403) // Forbidden
+	LB_RATE_LIMIT_STATUS_429 = int64(429) // Too Many Requests
+	LB_RATE_LIMIT_STATUS_503 = int64(503) // Service Unavailable
+)
+
+var LB_RATE_LIMIT_STATUSES = []int64{
+	LB_RATE_LIMIT_STATUS_403,
+	LB_RATE_LIMIT_STATUS_429,
+	LB_RATE_LIMIT_STATUS_503,
+}
+
 const (
 	LB_REDIRECT_SCHEME_IDENTITY = ""
 	LB_REDIRECT_SCHEME_HTTP     = "http"
@@ -45,3 +45,45 @@ type LoadbalancerCertificateFilterListInput struct {
 	// 以证书名称排序
 	OrderByCertificate string `json:"order_by_certificate"`
 }
+
+type LoadbalancerCertificateAcmeInput struct {
+	// 证书来源
+	// enum: upload, acme
+	// default: upload
+	Source string `json:"source"`
+
+	// ACME服务目录地址，默认使用Let's Encrypt，也可以是内部的step-ca等
+	AcmeDirectoryUrl string `json:"acme_directory_url"`
+
+	// ACME账号联系邮箱
+	AcmeEmail string `json:"acme_email"`
+
+	// 域名所有权验证方式，http-01通过负载均衡实例验证，dns-01通过DNS解析验证
+	// enum: http-01, dns-01
+	// default: http-01
+	AcmeChallengeType string `json:"acme_challenge_type"`
+
+	// 证书包含的域名，第一个域名作为证书的CommonName，dns-01验证方式支持泛域名
+	AcmeDomains []string `json:"acme_domains"`
+
+	// dns-01验证方式所用的DNS解析区域名称或ID
+	AcmeDnsZone string `json:"acme_dns_zone"`
+
+	// 到期前多少天自动续期
+	// default: 30
+	AcmeRenewBeforeDays int `json:"acme_renew_before_days"`
+}
+
+type LoadbalancerCertificateCreateInput struct {
+	apis.SharableVirtualResourceCreateInput
+
+	// 证书内容，PEM格式，source为upload时必须指定
+	Certificate string `json:"certificate"`
+	// 证书私钥，PEM格式，source为upload时必须指定
+	PrivateKey string `json:"private_key"`
+
+	LoadbalancerCertificateAcmeInput
+}
+
+type LoadbalancerCertificateRenewInput struct {
+}
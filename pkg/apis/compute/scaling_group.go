@@ -174,3 +174,9 @@ type SGPerformDetachScalingGroupInput struct {
 	// example: true
 	Auto bool `json:"auto"`
 }
+
+type ScalingGroupSetDesireInstanceNumberInput struct {
+	// description: 期望实例数, 需在最小实例数和最大实例数之间
+	// example: 3
+	DesireInstanceNumber int `json:"desire_instance_number"`
+}
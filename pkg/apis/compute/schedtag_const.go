@@ -25,3 +25,9 @@ const (
 )
 
 var STRATEGY_LIST = []string{STRATEGY_REQUIRE, STRATEGY_EXCLUDE, STRATEGY_PREFER, STRATEGY_AVOID}
+
+const (
+	// cost weight of a sched policy not overriding the default of scheduler
+	SCHED_COST_WEIGHT_UNSET = -1
+	SCHED_COST_WEIGHT_MAX   = 10
+)
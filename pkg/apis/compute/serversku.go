@@ -121,6 +121,14 @@ type ServerSkuCreateInput struct {
 
 	// swagger:ignore
 	Provider string
+
+	// 按量付费每小时价格, 用于成本优先调度
+	// minimum: 0
+	PostpaidHourlyPrice float64 `json:"postpaid_hourly_price"`
+
+	// 包年包月每月价格, 用于成本优先调度
+	// minimum: 0
+	PrepaidMonthlyPrice float64 `json:"prepaid_monthly_price"`
 }
 
 type ServerSkuDetails struct {
@@ -185,4 +193,10 @@ type ServerSkuUpdateInput struct {
 	GpuCount *int `json:"gpu_count"`
 
 	GpuMaxCount *int `json:"gpu_max_count"`
+
+	// 按量付费每小时价格, 用于成本优先调度
+	PostpaidHourlyPrice *float64 `json:"postpaid_hourly_price"`
+
+	// 包年包月每月价格, 用于成本优先调度
+	PrepaidMonthlyPrice *float64 `json:"prepaid_monthly_price"`
 }
@@ -0,0 +1,134 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"path"
+	"strings"
+
+	"yunion.io/x/onecloud/pkg/apis"
+	"yunion.io/x/onecloud/pkg/util/choices"
+)
+
+const (
+	SharedFilesystemSourceHostPath = "host_path"
+	SharedFilesystemSourceStorage  = "storage"
+
+	// 共享存储内导出的目录都位于 <storage>/sharedfs/<project_id> 之下
+	SharedFilesystemStorageDir = "sharedfs"
+)
+
+// SharedfsStorageRoot returns the directory of a project inside a shared storage,
+// every storage export of the project is rooted here
+func SharedfsStorageRoot(projectId string) string {
+	return path.Join("/", SharedFilesystemStorageDir, projectId)
+}
+
+// IsSharedfsStorageSubPath tells whether a path relative to the storage mount
+// point stays inside the directory of the project
+func IsSharedfsStorageSubPath(projectId, subPath string) bool {
+	if len(projectId) == 0 || strings.Contains(projectId, "/") || projectId == ".." {
+		return false
+	}
+	root := SharedfsStorageRoot(projectId)
+	p := path.Clean("/" + subPath)
+	return p == root || strings.HasPrefix(p, root+"/")
+}
+
+var SharedFilesystemSources = choices.NewChoices(
+	SharedFilesystemSourceHostPath,
+	SharedFilesystemSourceStorage,
+)
+
+const (
+	SharedFilesystemModeRO = "ro"
+	SharedFilesystemModeRW = "rw"
+)
+
+var SharedFilesystemModes = choices.NewChoices(
+	SharedFilesystemModeRO,
+	SharedFilesystemModeRW,
+)
+
+type SharedFilesystemCreateInput struct {
+	apis.StandaloneAnonResourceCreateInput
+	ServerResourceInput
+
+	// swagger:ignore
+	GuestId string `json:"guest_id"`
+
+	// 虚拟机内挂载使用的标签, 同一台虚拟机内唯一
+	// example: data
+	Tag string `json:"tag"`
+
+	// 共享目录来源
+	// enum: host_path, storage
+	SourceType string `json:"source_type"`
+
+	// 宿主机目录, 仅管理员可以指定, source_type为host_path时必填
+	// example: /opt/share
+	HostPath string `json:"host_path"`
+
+	// NFS/GPFS等共享文件存储(ID或Name), source_type为storage时必填
+	StorageId string `json:"storage_id"`
+
+	// 共享存储内的子目录, 相对于项目目录 <storage>/sharedfs/<project_id>
+	// example: projects/demo
+	SubPath string `json:"sub_path"`
+
+	// 读写模式
+	// enum: ro, rw
+	// default: rw
+	Mode string `json:"mode"`
+
+	// 虚拟机内的挂载点, 为空则不写入fstab
+	// example: /mnt/data
+	Mountpoint string `json:"mountpoint"`
+
+	// 计入项目存储配额的容量, 单位MB
+	SizeMb int `json:"size_mb"`
+}
+
+type SharedFilesystemListInput struct {
+	apis.StandaloneAnonResourceListInput
+	ServerFilterListInput
+
+	// 以共享目录来源过滤
+	SourceType []string `json:"source_type"`
+
+	// 以读写模式过滤
+	Mode []string `json:"mode"`
+
+	// 以共享存储过滤
+	StorageId []string `json:"storage_id"`
+}
+
+type SharedFilesystemDetails struct {
+	apis.StandaloneAnonResourceDetails
+	GuestResourceInfo
+
+	// 共享存储名称
+	Storage string `json:"storage"`
+}
+
+type GuestSharedfsJsonDesc struct {
+	Tag        string `json:"tag"`
+	SourceType string `json:"source_type"`
+	HostPath   string `json:"host_path"`
+	StorageId  string `json:"storage_id"`
+	SubPath    string `json:"sub_path"`
+	Mountpoint string `json:"mountpoint"`
+	Readonly   bool   `json:"readonly"`
+}
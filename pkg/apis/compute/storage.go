@@ -90,6 +90,10 @@ type StorageCreateInput struct {
 	// 网络文件系统共享目录, storage_type 为 nfs 时, 此参数必传
 	// example: /nfs_root/
 	NfsSharedDir string `json:"nfs_shared_dir"`
+
+	// 每GB每月价格, 用于成本优先调度
+	// minimum: 0
+	MonthlyPricePerGb float64 `json:"monthly_price_per_gb"`
 }
 
 type RbdTimeoutInput struct {
@@ -185,4 +189,7 @@ type StorageUpdateInput struct {
 	StorageConf *jsonutils.JSONDict
 
 	UpdateStorageConf bool
+
+	// 每GB每月价格, 用于成本优先调度
+	MonthlyPricePerGb *float64 `json:"monthly_price_per_gb"`
 }
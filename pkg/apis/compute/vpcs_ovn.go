@@ -70,6 +70,20 @@ func VpcInterExtIP2() netutils.IPV4Addr {
 	return vpcInterExtIP2
 }
 
+const (
+	// /30 links between routers of peered vpcs
+	sVpcPeeringLinkCidr = "100.65.64.0/18"
+	VpcPeeringLinkMask  = 30
+)
+
+var (
+	vpcPeeringLinkCidr netutils.IPV4Prefix
+)
+
+func VpcPeeringLinkCidr() netutils.IPV4Prefix {
+	return vpcPeeringLinkCidr
+}
+
 const (
 	sVpcMappedCidr      = "100.64.0.0/17"
 	VpcMappedIPMask     = 17
@@ -134,6 +148,8 @@ func init() {
 	vpcInterExtIP1 = mi(netutils.NewIPV4Addr(sVpcInterExtIP1))
 	vpcInterExtIP2 = mi(netutils.NewIPV4Addr(sVpcInterExtIP2))
 
+	vpcPeeringLinkCidr = mp(netutils.NewIPV4Prefix(sVpcPeeringLinkCidr))
+
 	vpcMappedCidr = mp(netutils.NewIPV4Prefix(sVpcMappedCidr))
 	vpcMappedGatewayIP = mi(netutils.NewIPV4Addr(sVpcMappedGatewayIP))
 
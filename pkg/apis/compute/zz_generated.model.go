@@ -122,15 +122,20 @@ type SBackupDiskConfig struct {
 // SBackupStorage is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SBackupStorage.
 type SBackupStorage struct {
 	apis.SEnabledStatusInfrasResourceBase
-	AccessInfo  *SBackupStorageAccessInfo `json:"access_info"`
-	StorageType string                    `json:"storage_type"`
-	CapacityMb  int                       `json:"capacity_mb"`
+	AccessInfo     *SBackupStorageAccessInfo `json:"access_info"`
+	StorageType    string                    `json:"storage_type"`
+	CapacityMb     int                       `json:"capacity_mb"`
+	UsedCapacityMb int                       `json:"used_capacity_mb"`
 }
 
 // SBackupStorageAccessInfo is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SBackupStorageAccessInfo.
 type SBackupStorageAccessInfo struct {
-	NfsHost      string `json:"nfs_host"`
-	NfsSharedDir string `json:"nfs_shared_dir"`
+	NfsHost         string `json:"nfs_host"`
+	NfsSharedDir    string `json:"nfs_shared_dir"`
+	ObjectBucketUrl string `json:"object_bucket_url"`
+	ObjectAccessKey string `json:"object_access_key"`
+	ObjectSecret    string `json:"object_secret"`
+	ObjectSse       string `json:"object_sse"`
 }
 
 // SBaremetalagent is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SBaremetalagent.
@@ -1053,6 +1058,8 @@ type SGroup struct {
 	// the upper limit number of guests with this group in a host
 	Granularity     int   `json:"granularity"`
 	ForceDispersion *bool `json:"force_dispersion,omitempty"`
+	// 组内实例在拓扑域间的分布约束
+	TopologySpreadConstraints *TopologySpreadConstraints `json:"topology_spread_constraints"`
 }
 
 // SGroupJointsBase is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SGroupJointsBase.
@@ -1643,6 +1650,15 @@ type SLoadbalancerAclResourceBase struct {
 	AclId string `json:"acl_id"`
 }
 
+// SLoadbalancerAcmeHttpChallenge is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SLoadbalancerAcmeHttpChallenge.
+type SLoadbalancerAcmeHttpChallenge struct {
+	Token            string `json:"token"`
+	KeyAuthorization string `json:"key_authorization"`
+}
+
+// SLoadbalancerAcmeHttpChallenges is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SLoadbalancerAcmeHttpChallenges.
+type SLoadbalancerAcmeHttpChallenges []*SLoadbalancerAcmeHttpChallenge
+
 // SLoadbalancerAgent is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SLoadbalancerAgent.
 type SLoadbalancerAgent struct {
 	apis.SStandaloneResourceBase
@@ -1744,6 +1760,22 @@ type SLoadbalancerCertificate struct {
 	apis.SSharableVirtualResourceBase
 	apis.SExternalizedResourceBase
 	apis.SCertificateResourceBase
+	// 证书来源
+	Source string `json:"source"`
+	// ACME服务目录地址
+	AcmeDirectoryUrl string `json:"acme_directory_url"`
+	// ACME账号联系邮箱
+	AcmeEmail string `json:"acme_email"`
+	// 域名所有权验证方式
+	AcmeChallengeType string `json:"acme_challenge_type"`
+	// 证书包含的域名，以逗号分隔
+	AcmeDomains string `json:"acme_domains"`
+	// dns-01验证方式所用的DNS解析区域ID
+	AcmeDnsZoneId string `json:"acme_dns_zone_id"`
+	// 到期前多少天自动续期
+	AcmeRenewBeforeDays int `json:"acme_renew_before_days"`
+	// 等待lbagent响应的http-01验证
+	AcmeHttpChallenges *SLoadbalancerAcmeHttpChallenges `json:"acme_http_challenges"`
 }
 
 // SLoadbalancerCertificateResourceBase is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SLoadbalancerCertificateResourceBase.
@@ -1765,6 +1797,13 @@ type SLoadbalancerClusterResourceBase struct {
 	ClusterId string `json:"cluster_id"`
 }
 
+// SLoadbalancerConnLimiter is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SLoadbalancerConnLimiter.
+type SLoadbalancerConnLimiter struct {
+	MaxConn int `json:"max_conn"`
+	// 监听最大并发连接数
+	MaxConnPerSrc int `json:"max_conn_per_src"`
+}
+
 // SLoadbalancerHTTPListener is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SLoadbalancerHTTPListener.
 type SLoadbalancerHTTPListener struct {
 	StickySession string `json:"sticky_session"`
@@ -1777,6 +1816,8 @@ type SLoadbalancerHTTPListener struct {
 	XForwardedFor              bool `json:"xforwarded_for"`
 	// 获取客户端真实IP
 	Gzip bool `json:"gzip"`
+	// Gzip数据压缩
+	EnableBackendHttp2 bool `json:"enable_backend_http2"`
 }
 
 // SLoadbalancerHTTPRateLimiter is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SLoadbalancerHTTPRateLimiter.
@@ -1784,6 +1825,10 @@ type SLoadbalancerHTTPRateLimiter struct {
 	HTTPRequestRate int `json:"http_request_rate"`
 	// 限定监听接收请示速率
 	HTTPRequestRatePerSrc int `json:"http_request_rate_per_src"`
+	// 源IP监听请求最大速率
+	HTTPRateLimitStatus int `json:"http_rate_limit_status"`
+	// 超出限制时返回的HTTP状态码，默认429
+	HTTPRateLimitBody string `json:"http_rate_limit_body"`
 }
 
 // SLoadbalancerHTTPRedirect is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SLoadbalancerHTTPRedirect.
@@ -1827,6 +1872,8 @@ type SLoadbalancerHealthCheck struct {
 	HealthCheckReq      string `json:"health_check_req"`
 	// UDP监听健康检查的请求串
 	HealthCheckExp string `json:"health_check_exp"`
+	// UDP监听健康检查的响应串
+	HealthCheckGrpcService string `json:"health_check_grpc_service"`
 }
 
 // SLoadbalancerListener is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SLoadbalancerListener.
@@ -1852,6 +1899,7 @@ type SLoadbalancerListener struct {
 	SLoadbalancerAclResourceBase
 	CachedAclId string `json:"cached_acl_id"`
 	SLoadbalancerRateLimiter
+	SLoadbalancerConnLimiter
 	SLoadbalancerTCPListener
 	SLoadbalancerUDPListener
 	SLoadbalancerHTTPListener
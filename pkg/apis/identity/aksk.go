@@ -26,6 +26,9 @@ const (
 	RECOVERY_SECRETS_TYPE = "recovery_secret"
 	OIDC_CREDENTIAL_TYPE  = "oidc"
 	ENCRYPT_KEY_TYPE      = "enc_key"
+	WEBAUTHN_TYPE         = "webauthn"
+	RECOVERY_CODES_TYPE   = "recovery_code"
+	APP_CREDENTIAL_TYPE   = "app_credential"
 )
 
 type SAccessKeySecretBlob struct {
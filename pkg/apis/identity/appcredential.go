@@ -0,0 +1,124 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package identity
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// 应用凭证访问规则，限定应用凭证获得的token能够访问的服务、HTTP方法和路径
+type SAppCredentialAccessRule struct {
+	// 服务类型，例如compute, image，为空或者*表示所有服务
+	Service string `json:"service"`
+	// HTTP方法，例如GET, POST，为空或者*表示所有方法
+	Method string `json:"method"`
+	// 请求路径，支持*匹配一级路径，**匹配其后任意多级路径，为空表示所有路径
+	Path string `json:"path"`
+}
+
+// 应用凭证内容
+type SAppCredentialBlob struct {
+	// 应用凭证的密钥
+	Secret string `json:"secret"`
+	// 应用凭证限定的角色ID列表，必须是用户在凭证所属项目的角色的子集
+	Roles []string `json:"roles"`
+	// 过期时间(unix时间戳)，0表示永不过期
+	Expire int64 `json:"expire"`
+	// 访问规则列表，为空表示不限制
+	AccessRules []SAppCredentialAccessRule `json:"access_rules"`
+}
+
+func (blob SAppCredentialBlob) IsValid() bool {
+	return blob.Expire <= 0 || blob.Expire > time.Now().Unix()
+}
+
+// 通过应用凭证认证获得的token携带的应用凭证信息
+type SAppCredentialInfo struct {
+	// 应用凭证ID
+	Id string `json:"id"`
+	// 应用凭证名称
+	Name string `json:"name"`
+	// 访问规则列表
+	AccessRules []SAppCredentialAccessRule `json:"access_rules"`
+}
+
+func (rule SAppCredentialAccessRule) Validate() bool {
+	switch strings.ToUpper(rule.Method) {
+	case "", "*", http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+	default:
+		return false
+	}
+	if len(rule.Path) > 0 && !strings.HasPrefix(rule.Path, "/") {
+		return false
+	}
+	return true
+}
+
+func (rule SAppCredentialAccessRule) Match(service, method, path string) bool {
+	if len(rule.Service) > 0 && rule.Service != "*" && rule.Service != service {
+		return false
+	}
+	if len(rule.Method) > 0 && rule.Method != "*" && !strings.EqualFold(rule.Method, method) {
+		return false
+	}
+	if len(rule.Path) == 0 {
+		return true
+	}
+	return matchAccessRulePath(splitAccessRulePath(rule.Path), splitAccessRulePath(path))
+}
+
+func splitAccessRulePath(path string) []string {
+	path = strings.Trim(path, "/")
+	if len(path) == 0 {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
+
+func matchAccessRulePath(pattern, segs []string) bool {
+	for i := range pattern {
+		if pattern[i] == "**" {
+			for j := i; j <= len(segs); j++ {
+				if matchAccessRulePath(pattern[i+1:], segs[j:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if i >= len(segs) {
+			return false
+		}
+		if pattern[i] != "*" && pattern[i] != segs[i] {
+			return false
+		}
+	}
+	return len(pattern) == len(segs)
+}
+
+// IsAccessAllowed tells whether a request is allowed by the access rules,
+// empty rules impose no restriction
+func (info SAppCredentialInfo) IsAccessAllowed(service, method, path string) bool {
+	if len(info.AccessRules) == 0 {
+		return true
+	}
+	for i := range info.AccessRules {
+		if info.AccessRules[i].Match(service, method, path) {
+			return true
+		}
+	}
+	return false
+}
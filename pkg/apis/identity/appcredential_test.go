@@ -0,0 +1,134 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package identity
+
+import (
+	"testing"
+)
+
+func TestSAppCredentialAccessRule_Match(t *testing.T) {
+	cases := []struct {
+		name    string
+		rule    SAppCredentialAccessRule
+		service string
+		method  string
+		path    string
+		want    bool
+	}{
+		{
+			name:    "empty rule",
+			rule:    SAppCredentialAccessRule{},
+			service: "compute",
+			method:  "DELETE",
+			path:    "/servers/abc",
+			want:    true,
+		},
+		{
+			name:    "exact",
+			rule:    SAppCredentialAccessRule{Service: "compute", Method: "GET", Path: "/servers"},
+			service: "compute",
+			method:  "get",
+			path:    "/servers/",
+			want:    true,
+		},
+		{
+			name:    "service mismatch",
+			rule:    SAppCredentialAccessRule{Service: "image", Method: "GET", Path: "/servers"},
+			service: "compute",
+			method:  "GET",
+			path:    "/servers",
+			want:    false,
+		},
+		{
+			name:    "method mismatch",
+			rule:    SAppCredentialAccessRule{Service: "compute", Method: "GET", Path: "/servers"},
+			service: "compute",
+			method:  "POST",
+			path:    "/servers",
+			want:    false,
+		},
+		{
+			name:    "single segment wildcard",
+			rule:    SAppCredentialAccessRule{Service: "compute", Method: "*", Path: "/servers/*"},
+			service: "compute",
+			method:  "POST",
+			path:    "/servers/abc",
+			want:    true,
+		},
+		{
+			name:    "single segment wildcard too deep",
+			rule:    SAppCredentialAccessRule{Service: "compute", Path: "/servers/*"},
+			service: "compute",
+			method:  "POST",
+			path:    "/servers/abc/start",
+			want:    false,
+		},
+		{
+			name:    "multi segment wildcard",
+			rule:    SAppCredentialAccessRule{Service: "compute", Path: "/servers/**"},
+			service: "compute",
+			method:  "POST",
+			path:    "/servers/abc/start",
+			want:    true,
+		},
+		{
+			name:    "multi segment wildcard matches none",
+			rule:    SAppCredentialAccessRule{Service: "compute", Path: "/servers/**"},
+			service: "compute",
+			method:  "GET",
+			path:    "/servers",
+			want:    true,
+		},
+		{
+			name:    "multi segment wildcard in middle",
+			rule:    SAppCredentialAccessRule{Path: "/**/start"},
+			service: "compute",
+			method:  "POST",
+			path:    "/servers/abc/start",
+			want:    true,
+		},
+		{
+			name:    "path prefix is not a match",
+			rule:    SAppCredentialAccessRule{Path: "/servers"},
+			service: "compute",
+			method:  "GET",
+			path:    "/servers/abc",
+			want:    false,
+		},
+	}
+	for _, c := range cases {
+		got := c.rule.Match(c.service, c.method, c.path)
+		if got != c.want {
+			t.Errorf("%s: want %v got %v", c.name, c.want, got)
+		}
+	}
+}
+
+func TestSAppCredentialInfo_IsAccessAllowed(t *testing.T) {
+	info := SAppCredentialInfo{}
+	if !info.IsAccessAllowed("compute", "GET", "/servers") {
+		t.Errorf("empty access rules should not restrict")
+	}
+	info.AccessRules = []SAppCredentialAccessRule{
+		{Service: "compute", Method: "GET", Path: "/servers/**"},
+		{Service: "image", Method: "GET", Path: "/images"},
+	}
+	if !info.IsAccessAllowed("image", "GET", "/images") {
+		t.Errorf("image list should be allowed")
+	}
+	if info.IsAccessAllowed("compute", "DELETE", "/servers/abc") {
+		t.Errorf("server delete should be denied")
+	}
+}
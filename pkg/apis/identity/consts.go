@@ -37,6 +37,8 @@ const (
 	AUTH_METHOD_SAML     = "saml"
 	AUTH_METHOD_OIDC     = "oidc"
 	AUTH_METHOD_OAuth2   = "oauth2"
+	AUTH_METHOD_WEBAUTHN = "webauthn"
+	AUTH_METHOD_APP_CRED = "app_credential"
 
 	// AUTH_METHOD_ID_PASSWORD = 1
 	// AUTH_METHOD_ID_TOKEN    = 2
@@ -84,7 +86,7 @@ const (
 )
 
 var (
-	AUTH_METHODS = []string{AUTH_METHOD_PASSWORD, AUTH_METHOD_TOKEN, AUTH_METHOD_AKSK, AUTH_METHOD_CAS}
+	AUTH_METHODS = []string{AUTH_METHOD_PASSWORD, AUTH_METHOD_TOKEN, AUTH_METHOD_AKSK, AUTH_METHOD_CAS, AUTH_METHOD_WEBAUTHN, AUTH_METHOD_APP_CRED}
 
 	PASSWORD_PROTECTED_IDPS = []string{
 		IdentityDriverSQL,
@@ -31,6 +31,9 @@ type CredentialUpdateInput struct {
 
 	// enabled
 	Enabled *bool `json:"enabled"`
+
+	// 凭证内容，仅webauthn和recovery_code类型的凭证允许更新
+	Blob string `json:"blob"`
 }
 
 type CredentialCreateInput struct {
@@ -44,6 +44,9 @@ type IdentityProviderDetails struct {
 	// 该认证源关联的所有域的组数量
 	GroupCount int `json:"group_count,allowempty"`
 
+	// 是否启用了SCIM用户推送
+	ScimEnabled bool `json:"scim_enabled"`
+
 	SIdentityProvider
 }
 
@@ -0,0 +1,204 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package identity
+
+import "yunion.io/x/jsonutils"
+
+const (
+	SCIM_SCHEMA_USER             = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SCIM_SCHEMA_GROUP            = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	SCIM_SCHEMA_LIST_RESPONSE    = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SCIM_SCHEMA_PATCH_OP         = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SCIM_SCHEMA_ERROR            = "urn:ietf:params:scim:api:messages:2.0:Error"
+	SCIM_SCHEMA_SERVICE_PROVIDER = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	SCIM_SCHEMA_RESOURCE_TYPE    = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
+
+	SCIM_RESOURCE_TYPE_USER  = "User"
+	SCIM_RESOURCE_TYPE_GROUP = "Group"
+
+	SCIM_URL_PREFIX           = "/scim/v2"
+	SCIM_CONTENT_TYPE         = "application/scim+json"
+	SCIM_DEFAULT_PAGE_SIZE    = 100
+	SCIM_MAX_PAGE_SIZE        = 1000
+	SCIM_METADATA_EXTERNAL_ID = "__scim_external_id"
+
+	SCIM_ERROR_TYPE_INVALID_FILTER = "invalidFilter"
+	SCIM_ERROR_TYPE_INVALID_PATH   = "invalidPath"
+	SCIM_ERROR_TYPE_INVALID_VALUE  = "invalidValue"
+	SCIM_ERROR_TYPE_INVALID_SYNTAX = "invalidSyntax"
+	SCIM_ERROR_TYPE_NO_TARGET      = "noTarget"
+	SCIM_ERROR_TYPE_UNIQUENESS     = "uniqueness"
+	SCIM_ERROR_TYPE_TOO_MANY       = "tooMany"
+	SCIM_ERROR_TYPE_MUTABILITY     = "mutability"
+
+	SCIM_PATCH_OP_ADD     = "add"
+	SCIM_PATCH_OP_REPLACE = "replace"
+	SCIM_PATCH_OP_REMOVE  = "remove"
+
+	SCIM_AUTHORIZATION_BEARER       = "Bearer"
+	SCIM_AUTHORIZATION_HEADER       = "Authorization"
+	SCIM_TOKEN_BYTES                = 32
+	SCIM_SERVICE_PROVIDER_DOC_URI   = "https://tools.ietf.org/html/rfc7644"
+	SCIM_SERVICE_PROVIDER_AUTH_TYPE = "oauthbearertoken"
+)
+
+type PerformIdpScimInput struct {
+	// 启用或关闭SCIM用户推送，启用时会重新生成访问令牌
+	Enable *bool `json:"enable" help:"enable scim provisioning" negative:"disable"`
+}
+
+type PerformIdpScimOutput struct {
+	// 是否启用了SCIM用户推送
+	Enabled bool `json:"enabled"`
+	// SCIM访问令牌，仅在启用时返回一次，请妥善保存
+	Token string `json:"token"`
+	// SCIM服务的路径
+	Endpoint string `json:"endpoint"`
+}
+
+// SCIM资源元信息
+type ScimMeta struct {
+	ResourceType string `json:"resourceType"`
+	Created      string `json:"created"`
+	LastModified string `json:"lastModified"`
+	Location     string `json:"location"`
+}
+
+// SCIM多值属性，例如emails, phoneNumbers, groups, members
+type ScimMultiValue struct {
+	Value   string `json:"value"`
+	Display string `json:"display"`
+	Type    string `json:"type"`
+	Primary bool   `json:"primary,omitfalse"`
+	Ref     string `json:"$ref"`
+}
+
+type ScimName struct {
+	Formatted  string `json:"formatted"`
+	FamilyName string `json:"familyName"`
+	GivenName  string `json:"givenName"`
+}
+
+// SCIM用户
+type ScimUser struct {
+	Schemas []string `json:"schemas"`
+	// 用户ID
+	Id string `json:"id"`
+	// 认证源侧的外部ID
+	ExternalId string `json:"externalId"`
+	// 用户在认证源中的唯一名称
+	UserName string `json:"userName"`
+
+	Name        *ScimName `json:"name"`
+	DisplayName string    `json:"displayName"`
+	// 是否启用，false时禁用用户
+	Active bool `json:"active,allowfalse"`
+
+	Emails       []ScimMultiValue `json:"emails"`
+	PhoneNumbers []ScimMultiValue `json:"phoneNumbers"`
+	// 用户所在的由该认证源推送的组，只读
+	Groups []ScimMultiValue `json:"groups"`
+
+	Meta ScimMeta `json:"meta"`
+}
+
+// SCIM组
+type ScimGroup struct {
+	Schemas []string `json:"schemas"`
+	// 组ID
+	Id string `json:"id"`
+	// 认证源侧的外部ID
+	ExternalId string `json:"externalId"`
+	// 组名称，在认证源内唯一
+	DisplayName string `json:"displayName"`
+	// 组成员
+	Members []ScimMultiValue `json:"members,allowempty"`
+
+	Meta ScimMeta `json:"meta"`
+}
+
+type ScimListResponse struct {
+	Schemas      []string               `json:"schemas"`
+	TotalResults int                    `json:"totalResults"`
+	StartIndex   int                    `json:"startIndex"`
+	ItemsPerPage int                    `json:"itemsPerPage"`
+	Resources    []jsonutils.JSONObject `json:"Resources,allowempty"`
+}
+
+type ScimPatchOperation struct {
+	// add, replace或remove，不区分大小写
+	Op    string               `json:"op"`
+	Path  string               `json:"path"`
+	Value jsonutils.JSONObject `json:"value"`
+}
+
+type ScimPatchRequest struct {
+	Schemas    []string             `json:"schemas"`
+	Operations []ScimPatchOperation `json:"Operations"`
+}
+
+type ScimError struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType"`
+	Detail   string   `json:"detail"`
+}
+
+type ScimSupported struct {
+	Supported bool `json:"supported,allowfalse"`
+}
+
+type ScimBulkSupported struct {
+	Supported      bool `json:"supported,allowfalse"`
+	MaxOperations  int  `json:"maxOperations"`
+	MaxPayloadSize int  `json:"maxPayloadSize"`
+}
+
+type ScimFilterSupported struct {
+	Supported  bool `json:"supported,allowfalse"`
+	MaxResults int  `json:"maxResults"`
+}
+
+type ScimAuthenticationScheme struct {
+	Type             string `json:"type"`
+	Name             string `json:"name"`
+	Description      string `json:"description"`
+	SpecUri          string `json:"specUri"`
+	DocumentationUri string `json:"documentationUri"`
+	Primary          bool   `json:"primary,omitfalse"`
+}
+
+type ScimServiceProviderConfig struct {
+	Schemas               []string                   `json:"schemas"`
+	DocumentationUri      string                     `json:"documentationUri"`
+	Patch                 ScimSupported              `json:"patch"`
+	Bulk                  ScimBulkSupported          `json:"bulk"`
+	Filter                ScimFilterSupported        `json:"filter"`
+	ChangePassword        ScimSupported              `json:"changePassword"`
+	Sort                  ScimSupported              `json:"sort"`
+	Etag                  ScimSupported              `json:"etag"`
+	AuthenticationSchemes []ScimAuthenticationScheme `json:"authenticationSchemes"`
+	Meta                  ScimMeta                   `json:"meta"`
+}
+
+type ScimResourceType struct {
+	Schemas     []string `json:"schemas"`
+	Id          string   `json:"id"`
+	Name        string   `json:"name"`
+	Endpoint    string   `json:"endpoint"`
+	Description string   `json:"description"`
+	Schema      string   `json:"schema"`
+	Meta        ScimMeta `json:"meta"`
+}
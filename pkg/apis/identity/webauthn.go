@@ -0,0 +1,27 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package identity
+
+// webauthn无密码登录的挑战
+type WebauthnChallengeOutput struct {
+	// base64url编码的挑战，有效期5分钟
+	Challenge string `json:"challenge"`
+}
+
+// 恢复码凭证内容
+type SRecoveryCodesBlob struct {
+	// 未使用的恢复码的sha256摘要(hex)，恢复码使用后即删除
+	Codes []string `json:"codes"`
+}
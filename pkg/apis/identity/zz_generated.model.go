@@ -145,6 +145,8 @@ type SIdentityProvider struct {
 	IsSso *bool `json:"is_sso,omitempty"`
 	// 是否是缺省SSO登录方式
 	IsDefault *bool `json:"is_default,omitempty"`
+	// SCIM推送访问令牌的sha256摘要，为空表示未启用SCIM
+	ScimTokenHash string `json:"scim_token_hash"`
 }
 
 // SIdmapping is an autogenerated struct via yunion.io/x/onecloud/pkg/keystone/models.SIdmapping.
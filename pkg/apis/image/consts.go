@@ -65,6 +65,18 @@ const (
 	IMAGE_DISABLE_USB_KBD     = "disable_usb_kbd"
 	IMAGE_VDI_PROTOCOL        = "vdi_protocol"
 
+	// hints of virtual machine imported from ovf
+	IMAGE_VCPU_COUNT = "vcpu_count"
+	IMAGE_VMEM_SIZE  = "vmem_size"
+	IMAGE_NIC_COUNT  = "nic_count"
+	IMAGE_FIRMWARE   = "firmware"
+
+	// source of disk images of guest image imported from ova/ovf packages
+	IMAGE_IMPORTED_FROM = "imported_from"
+
+	GUEST_IMAGE_IMPORT_FORMAT_OVA = "ova"
+	GUEST_IMAGE_IMPORT_FORMAT_OVF = "ovf"
+
 	IMAGE_STATUS_UPDATING = "updating"
 )
 
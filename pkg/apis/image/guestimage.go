@@ -80,4 +80,12 @@ type GuestImageCreateInput struct {
 
 	// 镜像属性
 	Properties map[string]string `json:"properties"`
+
+	// 导入虚拟机的格式, 磁盘列表及虚拟机配置从OVF描述文件读取
+	// ova: 随请求上传ova文件, 或从copy_from下载
+	// ovf: 从copy_from下载ovf描述文件, 磁盘文件按相对于描述文件的路径下载
+	// enum: ova, ovf
+	ImportFormat string `json:"import_format"`
+	// 导入虚拟机的URL
+	CopyFrom string `json:"copy_from"`
 }
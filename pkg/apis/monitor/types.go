@@ -14,10 +14,32 @@
 
 package monitor
 
+import (
+	"yunion.io/x/onecloud/pkg/apis"
+)
+
 const (
-	DataSourceTypeInfluxdb = "influxdb"
+	DataSourceTypeInfluxdb   = "influxdb"
+	DataSourceTypePrometheus = "prometheus"
+)
+
+var (
+	DataSourceTypes = []string{DataSourceTypeInfluxdb, DataSourceTypePrometheus}
 )
 
+type DataSourceCreateInput struct {
+	apis.StandaloneResourceCreateInput
+
+	// 数据源类型
+	// enum: influxdb, prometheus
+	Type string `json:"type"`
+	// 数据源地址, VictoriaMetrics 等兼容 Prometheus API 的数据源同样使用 prometheus 类型
+	Url      string `json:"url"`
+	User     string `json:"user"`
+	Password string `json:"password"`
+	Database string `json:"database"`
+}
+
 type DataSourceConfig struct {
 	Id     string
 	Name   string
@@ -30,6 +30,11 @@ const (
 	DINGTALK_ROBOT = "dingtalk-robot"
 	WORKWX_ROBOT   = "workwx-robot"
 	WEBHOOK        = "webhook"
+	SLACK          = "slack"
+	MATRIX         = "matrix"
+	SLACK_ROBOT    = "slack-robot"
+	TEAMS_ROBOT    = "teams-robot"
+	MATRIX_ROBOT   = "matrix-robot"
 
 	ROBOT = "robot"
 
@@ -77,6 +82,9 @@ const (
 	ROBOT_TYPE_DINGTALK = "dingtalk"
 	ROBOT_TYPE_WORKWX   = "workwx"
 	ROBOT_TYPE_WEBHOOK  = "webhook"
+	ROBOT_TYPE_SLACK    = "slack"
+	ROBOT_TYPE_TEAMS    = "teams"
+	ROBOT_TYPE_MATRIX   = "matrix"
 
 	ROBOT_STATUS_READY = "ready"
 
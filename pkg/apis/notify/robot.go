@@ -19,10 +19,11 @@ import "yunion.io/x/onecloud/pkg/apis"
 type RobotCreateInput struct {
 	apis.SharableVirtualResourceCreateInput
 	// description: robot type
-	// enum: feishu,dingtalk,workwx,webhook
+	// enum: feishu,dingtalk,workwx,webhook,slack,teams,matrix
 	// example: webhook
 	Type string `json:"type"`
-	// description: address
+	// description: address, it's the room id such as !abc:matrix.org for matrix robot
+	// and the channel id for slack robot sending with bot token of slack config
 	// example: http://helloworld.io/test/webhook
 	Address string `json:"address"`
 	// description: Language preference
@@ -38,7 +39,7 @@ type RobotListInput struct {
 	apis.SharableVirtualResourceListInput
 	apis.EnabledResourceBaseListInput
 	// description: robot type
-	// enum: feishu,dingtalk,workwx,webhook
+	// enum: feishu,dingtalk,workwx,webhook,slack,teams,matrix
 	// example: webhook
 	Type string `json:"type"`
 	// description: Language preference
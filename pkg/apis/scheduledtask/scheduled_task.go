@@ -15,9 +15,14 @@
 package scheduledtask
 
 import (
+	"reflect"
 	"time"
 
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/gotypes"
+
 	"yunion.io/x/onecloud/pkg/apis"
+	"yunion.io/x/onecloud/pkg/httperrors"
 )
 
 type ScheduledTaskDetails struct {
@@ -30,6 +35,10 @@ type ScheduledTaskDetails struct {
 	Timer TimerDetails `json:"timer"`
 	// 周期方式触发
 	CycleTimer CycleTimerDetails `json:"cycle_timer"`
+	// cron表达式方式触发
+	CronTimer CronTimerDetails `json:"cron_timer"`
+	// 不触发的时间窗口
+	ExclusionWindows SExclusionWindows `json:"exclusion_windows"`
 	// 绑定的所有标示
 	Labels       []string      `json:"labels,allowempty"`
 	LabelDetails []LabelDetail `json:"label_details,allowempty"`
@@ -57,6 +66,17 @@ type CycleTimerDetails struct {
 	EndTime time.Time `json:"end_time"`
 }
 
+type CronTimerDetails struct {
+	// description: cron表达式
+	Expr string `json:"expr"`
+	// description: cron表达式的时区
+	TimeZone string `json:"time_zone"`
+	// description: 此周期任务的开始时间
+	StartTime time.Time `json:"start_time"`
+	// description: 此周期任务的截止时间
+	EndTime time.Time `json:"end_time"`
+}
+
 type LabelDetail struct {
 	Label        string    `json:"label"`
 	IsolatedTime time.Time `json:"isolated_time"`
@@ -81,7 +101,7 @@ type ScheduledTaskListInput struct {
 
 	// description: operation
 	// example: stop
-	// enum: start,stop,restart
+	// enum: start,stop,restart,sync,snapshot,change-config,scale,run-playbook
 	Operation string `json:"operation"`
 }
 
@@ -89,21 +109,18 @@ type ScheduledTaskCreateInput struct {
 	apis.VirtualResourceCreateInput
 	apis.EnabledBaseResourceCreateInput
 
-	// description: scheduled type
-	// enum: cycle,timing
-	// example: timing
-	ScheduledType string                `json:"scheduled_type"`
-	Timer         TimerCreateInput      `json:"timer"`
-	CycleTimer    CycleTimerCreateInput `json:"cycle_timer"`
+	ScheduledTaskTimerInput
 
 	// description: resource type
-	// enum: server
+	// enum: server,cloudaccount,disk,scalinggroup
 	// example: server
 	ResourceType string `json:"resource_type"`
 	// description: operation
-	// enum: start,stop,restart
+	// enum: start,stop,restart,sync,snapshot,change-config,scale,run-playbook
 	// example: stop
 	Operation string `json:"operation"`
+	// description: parameters of operation snapshot, change-config, scale and run-playbook
+	OperationParams *ScheduledTaskOperationParams `json:"operation_params"`
 	// description: label type
 	// enum: tag,id
 	// example: id
@@ -113,6 +130,54 @@ type ScheduledTaskCreateInput struct {
 	Labels []string
 }
 
+type ScheduledTaskTimerInput struct {
+	// description: scheduled type
+	// enum: cycle,timing,cron
+	// example: timing
+	ScheduledType string                `json:"scheduled_type"`
+	Timer         TimerCreateInput      `json:"timer"`
+	CycleTimer    CycleTimerCreateInput `json:"cycle_timer"`
+	CronTimer     CronTimerCreateInput  `json:"cron_timer"`
+
+	// description: 不触发的时间窗口, 如节假日或封网期
+	ExclusionWindows SExclusionWindows `json:"exclusion_windows"`
+}
+
+type ScheduledTaskOperationParams struct {
+	// description: 保留的定时快照数量, 超出的最早的快照会被删除, 0为不限制
+	// example: 7
+	SnapshotRetention int `json:"snapshot_retention"`
+	// description: 主机快照是否保存内存
+	SnapshotWithMemory bool `json:"snapshot_with_memory"`
+
+	// description: 调整配置的实例类型, 优先级高于vcpu_count和vmem_size
+	InstanceType string `json:"instance_type"`
+	// description: 调整配置的cpu大小
+	VcpuCount int `json:"vcpu_count"`
+	// description: 调整配置的内存大小, 1024M, 1G
+	VmemSize string `json:"vmem_size"`
+
+	// description: 伸缩组的期望实例数, 伸缩操作必须指定且大于0
+	DesireInstanceNumber int `json:"desire_instance_number"`
+
+	// description: 执行的ansible脚本(devtool script)的ID或名称
+	Script string `json:"script"`
+}
+
+func (params ScheduledTaskOperationParams) String() string {
+	return jsonutils.Marshal(params).String()
+}
+
+func (params ScheduledTaskOperationParams) IsZero() bool {
+	return params == ScheduledTaskOperationParams{}
+}
+
+func init() {
+	gotypes.RegisterSerializable(reflect.TypeOf(&ScheduledTaskOperationParams{}), func() gotypes.ISerializable {
+		return &ScheduledTaskOperationParams{}
+	})
+}
+
 type TimerCreateInput struct {
 
 	// description: 执行时间
@@ -148,6 +213,61 @@ type CycleTimerCreateInput struct {
 	EndTime time.Time `json:"end_time"`
 }
 
+type CronTimerCreateInput struct {
+
+	// description: 5位(分 时 日 月 周)或6位(秒 分 时 日 月 周)的cron表达式, 支持@daily等缩写
+	// example: 30 8 * * 1-5
+	Expr string `json:"expr"`
+
+	// description: cron表达式的时区, 默认为UTC
+	// example: Asia/Shanghai
+	TimeZone string `json:"time_zone"`
+
+	// description: 开始时间
+	StartTime time.Time `json:"start_time"`
+
+	// description: 截止时间, 为空则不截止
+	EndTime time.Time `json:"end_time"`
+}
+
+type SExclusionWindow struct {
+	// description: 开始时间
+	Start time.Time `json:"start"`
+	// description: 结束时间
+	End time.Time `json:"end"`
+	// description: 原因
+	// example: change freeze
+	Reason string `json:"reason"`
+}
+
+type SExclusionWindows []SExclusionWindow
+
+func (windows SExclusionWindows) String() string {
+	return jsonutils.Marshal(windows).String()
+}
+
+func (windows SExclusionWindows) IsZero() bool {
+	return len(windows) == 0
+}
+
+func (windows SExclusionWindows) Validate() error {
+	for i := range windows {
+		if windows[i].Start.IsZero() || windows[i].End.IsZero() {
+			return httperrors.NewInputParameterError("start and end of exclusion window %d should be set", i)
+		}
+		if !windows[i].Start.Before(windows[i].End) {
+			return httperrors.NewInputParameterError("start of exclusion window %d should be earlier than end", i)
+		}
+	}
+	return nil
+}
+
+func init() {
+	gotypes.RegisterSerializable(reflect.TypeOf(&SExclusionWindows{}), func() gotypes.ISerializable {
+		return &SExclusionWindows{}
+	})
+}
+
 type ScheduledTaskResourceInfo struct {
 	// description: 定时任务名称
 	// example: st-nihao
@@ -180,5 +300,26 @@ type ScheduledTaskSetLabelsInput struct {
 	Labels []string `json:"labels"`
 }
 
+type ScheduledTaskSetExclusionWindowsInput struct {
+	// description: 不触发的时间窗口, 为空则清除
+	ExclusionWindows SExclusionWindows `json:"exclusion_windows"`
+}
+
 type ScheduledTaskTriggerInput struct {
 }
+
+type ScheduledTaskPreviewInput struct {
+	ScheduledTaskTimerInput
+
+	// description: 预览的触发次数, 默认为10, 最大为100
+	// example: 10
+	Count int `json:"count"`
+
+	// description: 从此时间之后开始预览, 默认为当前时间
+	From time.Time `json:"from"`
+}
+
+type ScheduledTaskPreviewOutput struct {
+	// description: 接下来的触发时间
+	FireTimes []time.Time `json:"fire_times"`
+}
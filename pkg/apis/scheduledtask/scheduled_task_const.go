@@ -17,17 +17,24 @@ package scheduledtask
 const (
 	ST_TYPE_TIMING = "timing" // 定时
 	ST_TYPE_CYCLE  = "cycle"  // 周期
+	ST_TYPE_CRON   = "cron"   // cron表达式
 
 	ST_STATUS_READY         = "ready"
 	ST_STATUS_CREATE_FAILED = "create_failed"
 
 	ST_RESOURCE_SERVER       = "server"
 	ST_RESOURCE_CLOUDACCOUNT = "cloudaccount"
-
-	ST_RESOURCE_OPERATION_START   = "start"
-	ST_RESOURCE_OPERATION_STOP    = "stop"
-	ST_RESOURCE_OPERATION_RESTART = "restart"
-	ST_RESOURCE_OPERATION_SYNC    = "sync"
+	ST_RESOURCE_DISK         = "disk"
+	ST_RESOURCE_SCALINGGROUP = "scalinggroup"
+
+	ST_RESOURCE_OPERATION_START         = "start"
+	ST_RESOURCE_OPERATION_STOP          = "stop"
+	ST_RESOURCE_OPERATION_RESTART       = "restart"
+	ST_RESOURCE_OPERATION_SYNC          = "sync"
+	ST_RESOURCE_OPERATION_SNAPSHOT      = "snapshot"      // 快照
+	ST_RESOURCE_OPERATION_CHANGE_CONFIG = "change-config" // 调整配置
+	ST_RESOURCE_OPERATION_SCALE         = "scale"         // 调整伸缩组期望实例数
+	ST_RESOURCE_OPERATION_RUN_PLAYBOOK  = "run-playbook"  // 执行ansible脚本
 
 	ST_LABEL_ID  = "id"
 	ST_LABEL_TAG = "tag"
@@ -42,4 +49,5 @@ const (
 	TIMER_TYPE_DAY   = "day"
 	TIMER_TYPE_WEEK  = "week"
 	TIMER_TYPE_MONTH = "month"
+	TIMER_TYPE_CRON  = "cron"
 )
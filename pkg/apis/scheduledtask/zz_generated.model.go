@@ -29,6 +29,8 @@ type SScheduledTask struct {
 	ResourceType string `json:"resource_type"`
 	Operation    string `json:"operation"`
 	LabelType    string `json:"label_type"`
+	// OperationParams is the parameters of operation such as snapshot retention and config to change
+	OperationParams *ScheduledTaskOperationParams `json:"operation_params"`
 }
 
 // SScheduledTaskActivity is an autogenerated struct via yunion.io/x/onecloud/pkg/scheduledtask/models.SScheduledTaskActivity.
@@ -59,5 +61,11 @@ type STimer struct {
 	WeekDays byte `json:"week_days"`
 	// 0-31 0 is unlimited
 	MonthDays uint32 `json:"month_days"`
-	IsExpired bool   `json:"is_expired"`
+	// CronExpr is the cron expression of cron type
+	CronExpr string `json:"cron_expr"`
+	// TimeZone is the time zone in which CronExpr is evaluated
+	TimeZone string `json:"time_zone"`
+	// ExclusionWindows are periods in which the timer should not bell
+	ExclusionWindows *SExclusionWindows `json:"exclusion_windows"`
+	IsExpired        bool               `json:"is_expired"`
 }
@@ -99,6 +99,9 @@ type ScheduleInput struct {
 	HasIsolatedDevice bool
 
 	PendingUsages []jsonutils.JSONObject
+
+	// 成本优先调度权重, 未指定时使用调度器默认值, 可由调度策略设置
+	CostWeight *int `json:"cost_weight"`
 }
 
 func (input ScheduleInput) ToConditionInput() *jsonutils.JSONDict {
@@ -139,6 +142,9 @@ type CandidateResource struct {
 	// used by backup schedule
 	BackupCandidate *CandidateResource `json:"backup_candidate"`
 
+	// 估算的每月费用, 价格未知时为空
+	EstimatedMonthlyCost float64 `json:"estimated_monthly_cost,omitempty"`
+
 	// Error means no candidate found, include reasons
 	Error string `json:"error"`
 }
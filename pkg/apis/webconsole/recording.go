@@ -0,0 +1,100 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webconsole
+
+import (
+	"time"
+
+	"yunion.io/x/onecloud/pkg/apis"
+)
+
+const (
+	RECORDING_STORAGE_LOCAL = "local"
+	RECORDING_STORAGE_S3    = "s3"
+
+	RECORDING_STATUS_RECORDING   = "recording"
+	RECORDING_STATUS_READY       = "ready"
+	RECORDING_STATUS_INTERRUPTED = "interrupted"
+	RECORDING_STATUS_FAILED      = "failed"
+
+	// asciicast v2 format, https://docs.asciinema.org/manual/asciicast/v2/
+	RECORDING_FORMAT_ASCIICAST = "asciicast-v2"
+
+	RECORDING_RESOURCE = "recordings"
+	// 开启录制后, 仅策略允许 perform recordings skip-recording 的用户可以不录制会话
+	RECORDING_ACTION_SKIP = "skip-recording"
+)
+
+type SessionRecordingDetails struct {
+	apis.Meta
+
+	Id string `json:"id"`
+	// 录制对应的会话 id
+	SessionId string `json:"session_id"`
+	// 会话协议
+	Protocol string `json:"protocol"`
+	// 会话目标, 例如 ip 地址或容器名称
+	Target string `json:"target"`
+	Format string `json:"format"`
+
+	UserId          string `json:"user_id"`
+	User            string `json:"user"`
+	ProjectId       string `json:"project_id"`
+	Project         string `json:"project"`
+	ProjectDomainId string `json:"project_domain_id"`
+	ProjectDomain   string `json:"project_domain"`
+
+	// 是否由录制策略强制开启
+	Forced bool `json:"forced"`
+	// 是否录制了用户输入
+	WithInput bool `json:"with_input"`
+
+	StartedAt time.Time `json:"started_at"`
+	EndedAt   time.Time `json:"ended_at"`
+	// 录制时长, 单位秒
+	Duration float64 `json:"duration"`
+	Size     int64   `json:"size"`
+
+	// 存储位置
+	// enum: local, s3
+	Storage  string `json:"storage"`
+	Location string `json:"location"`
+	// enum: recording, ready, interrupted, failed
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+}
+
+type SessionRecordingListInput struct {
+	// 按项目过滤
+	Project string `json:"project"`
+	// 按用户过滤
+	User string `json:"user"`
+	// 按会话 id 过滤
+	SessionId string `json:"session_id"`
+	// 按协议过滤
+	Protocol string `json:"protocol"`
+
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+}
+
+type SessionRecordingListOutput struct {
+	apis.Meta
+
+	Recordings []SessionRecordingDetails `json:"recordings"`
+	Total      int                       `json:"total"`
+	Limit      int                       `json:"limit"`
+	Offset     int                       `json:"offset"`
+}
@@ -42,6 +42,7 @@ import (
 	"yunion.io/x/onecloud/pkg/proxy"
 	"yunion.io/x/onecloud/pkg/util/ctx"
 	"yunion.io/x/onecloud/pkg/util/httputils"
+	"yunion.io/x/onecloud/pkg/util/tracing"
 )
 
 type Application struct {
@@ -225,7 +226,9 @@ func (app *Application) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	} else {
 		counter = &hi.counter5XX
 	}
-	duration := float64(time.Since(start).Nanoseconds()) / 1000000
+	elapsed := time.Since(start)
+	app.observeRequest(hi, r.Method, lrw.status, elapsed)
+	duration := float64(elapsed.Nanoseconds()) / 1000000
 	counter.hit += 1
 	counter.duration += duration
 	skipLog := false
@@ -273,6 +276,29 @@ type appTask struct {
 	segs      []string
 	to        time.Duration
 	cancel    context.CancelFunc
+	span      *tracing.Span
+}
+
+func (t *appTask) startSpan() {
+	route := t.hand.route()
+	t.ctx, t.span = tracing.StartSpan(tracing.Extract(t.ctx, t.r.Header), t.r.Method+" "+route, tracing.SPAN_KIND_SERVER)
+	t.span.SetAttribute("http.method", t.r.Method)
+	t.span.SetAttribute("http.route", route)
+	t.span.SetAttribute("http.target", t.r.URL.Path)
+	t.span.SetAttribute("http.request_id", t.rid)
+	t.span.SetAttribute("service.app", t.app.GetName())
+}
+
+func (t *appTask) endSpan() {
+	status := t.fw.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	t.span.SetAttribute("http.status_code", status)
+	if status >= 500 {
+		t.span.SetStatus(tracing.STATUS_ERROR, http.StatusText(status))
+	}
+	t.span.End()
 }
 
 func (t *appTask) Run() {
@@ -294,6 +320,10 @@ func (t *appTask) Run() {
 				}
 			}()
 			t.ctx = context.WithValue(t.ctx, appctx.APP_CONTEXT_KEY_TRACE, span)
+			if !t.appParams.SkipTrace {
+				t.startSpan()
+				defer t.endSpan()
+			}
 			t.hand.handler(t.ctx, &t.fw, t.r)
 		}()
 	} // otherwise, the task has been timeout
@@ -400,6 +430,7 @@ func (app *Application) addDefaultHandlers() {
 	app.AddDefaultHandler("POST", "/ping", PingHandler, "ping")
 	app.AddDefaultHandler("GET", "/ping", PingHandler, "ping")
 	app.AddDefaultHandler("GET", "/worker_stats", WorkerStatsHandler, "worker_stats")
+	app.AddDefaultHandler("GET", "/metrics", MetricsHandler, "metrics")
 }
 
 func timeoutHandle(h http.Handler) http.HandlerFunc {
@@ -458,6 +489,11 @@ func (app *Application) registerCleanShutdown(s *http.Server, onStop func()) {
 			// Error from closing listeners, or context timeout:
 			log.Errorf("HTTP server Shutdown: %v", err)
 		}
+		func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			tracing.Shutdown(ctx)
+		}()
 		if onStop != nil {
 			func() {
 				defer func() {
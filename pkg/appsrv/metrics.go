@@ -0,0 +1,210 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package appsrv
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"yunion.io/x/log"
+)
+
+const (
+	METRICS_NAMESPACE = "onecloud"
+)
+
+var (
+	requestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: METRICS_NAMESPACE,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "Latency of the http requests handled by appsrv, partitioned by route and status code class.",
+			Buckets:   []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+		},
+		[]string{"app", "method", "route", "code"},
+	)
+)
+
+// the collectors are registered to the default registry, which is also
+// served by the services not built on appsrv, e.g. the scheduler.
+func init() {
+	prometheus.MustRegister(
+		requestDuration,
+		&sWorkerManagerCollector{},
+	)
+}
+
+// RegisterMetricsCollector adds a collector to the registry served by /metrics.
+func RegisterMetricsCollector(c prometheus.Collector) {
+	if err := prometheus.Register(c); err != nil {
+		log.Errorf("register metrics collector: %v", err)
+	}
+}
+
+func MetricsHandler(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{
+		ErrorLog:      metricsErrorLogger{},
+		ErrorHandling: promhttp.ContinueOnError,
+	}).ServeHTTP(w, r)
+}
+
+type metricsErrorLogger struct{}
+
+func (l metricsErrorLogger) Println(v ...interface{}) {
+	log.Errorf("metrics: %s", fmt.Sprint(v...))
+}
+
+func statusCodeClass(status int) string {
+	return fmt.Sprintf("%dxx", status/100)
+}
+
+func (hi *SHandlerInfo) route() string {
+	if len(hi.path) == 0 {
+		return "*"
+	}
+	return "/" + strings.Join(hi.path, "/")
+}
+
+func (app *Application) observeRequest(hi *SHandlerInfo, method string, status int, duration time.Duration) {
+	requestDuration.WithLabelValues(app.name, method, hi.route(), statusCodeClass(status)).Observe(duration.Seconds())
+}
+
+var (
+	workerQueueDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(METRICS_NAMESPACE, "worker", "queue_size"),
+		"Number of tasks waiting in the queue of the worker manager.",
+		[]string{"manager"}, nil,
+	)
+	workerActiveDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(METRICS_NAMESPACE, "worker", "active_workers"),
+		"Number of active workers of the worker manager.",
+		[]string{"manager"}, nil,
+	)
+	workerDetachedDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(METRICS_NAMESPACE, "worker", "detached_workers"),
+		"Number of workers detached from the worker manager.",
+		[]string{"manager"}, nil,
+	)
+	workerMaxDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(METRICS_NAMESPACE, "worker", "max_workers"),
+		"Maximal number of workers of the worker manager.",
+		[]string{"manager"}, nil,
+	)
+)
+
+// sWorkerManagerCollector exports the states of all worker managers, the
+// managers of the same name are summed up.
+type sWorkerManagerCollector struct{}
+
+func (c *sWorkerManagerCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- workerQueueDesc
+	ch <- workerActiveDesc
+	ch <- workerDetachedDesc
+	ch <- workerMaxDesc
+}
+
+func (c *sWorkerManagerCollector) Collect(ch chan<- prometheus.Metric) {
+	workerManagerLock.Lock()
+	managers := make([]*SWorkerManager, len(workerManagers))
+	copy(managers, workerManagers)
+	workerManagerLock.Unlock()
+
+	names := make([]string, 0)
+	states := make(map[string]*SWorkerManagerStates)
+	for _, wm := range managers {
+		state := wm.getState()
+		total, ok := states[state.Name]
+		if !ok {
+			names = append(names, state.Name)
+			states[state.Name] = &state
+			continue
+		}
+		total.QueueCnt += state.QueueCnt
+		total.ActiveWorkerCnt += state.ActiveWorkerCnt
+		total.DetachWorkerCnt += state.DetachWorkerCnt
+		total.MaxWorkerCnt += state.MaxWorkerCnt
+	}
+	for _, name := range names {
+		state := states[name]
+		ch <- prometheus.MustNewConstMetric(workerQueueDesc, prometheus.GaugeValue, float64(state.QueueCnt), name)
+		ch <- prometheus.MustNewConstMetric(workerActiveDesc, prometheus.GaugeValue, float64(state.ActiveWorkerCnt), name)
+		ch <- prometheus.MustNewConstMetric(workerDetachedDesc, prometheus.GaugeValue, float64(state.DetachWorkerCnt), name)
+		ch <- prometheus.MustNewConstMetric(workerMaxDesc, prometheus.GaugeValue, float64(state.MaxWorkerCnt), name)
+	}
+}
+
+type sDBStatsCollector struct {
+	db *sql.DB
+
+	maxOpen           *prometheus.Desc
+	open              *prometheus.Desc
+	inUse             *prometheus.Desc
+	idle              *prometheus.Desc
+	waitCount         *prometheus.Desc
+	waitDuration      *prometheus.Desc
+	maxIdleClosed     *prometheus.Desc
+	maxLifetimeClosed *prometheus.Desc
+}
+
+// NewDBStatsCollector exports the connection pool stats of db, name
+// distinguishes the databases of a service.
+func NewDBStatsCollector(name string, db *sql.DB) prometheus.Collector {
+	labels := prometheus.Labels{"db": name}
+	desc := func(metric, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(METRICS_NAMESPACE, "db", metric), help, nil, labels)
+	}
+	return &sDBStatsCollector{
+		db:                db,
+		maxOpen:           desc("max_open_connections", "Maximum number of open connections to the database."),
+		open:              desc("open_connections", "The number of established connections both in use and idle."),
+		inUse:             desc("in_use_connections", "The number of connections currently in use."),
+		idle:              desc("idle_connections", "The number of idle connections."),
+		waitCount:         desc("wait_count_total", "The total number of connections waited for."),
+		waitDuration:      desc("wait_duration_seconds_total", "The total time blocked waiting for a new connection."),
+		maxIdleClosed:     desc("max_idle_closed_total", "The total number of connections closed due to SetMaxIdleConns."),
+		maxLifetimeClosed: desc("max_lifetime_closed_total", "The total number of connections closed due to SetConnMaxLifetime."),
+	}
+}
+
+func (c *sDBStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.maxOpen
+	ch <- c.open
+	ch <- c.inUse
+	ch <- c.idle
+	ch <- c.waitCount
+	ch <- c.waitDuration
+	ch <- c.maxIdleClosed
+	ch <- c.maxLifetimeClosed
+}
+
+func (c *sDBStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.db.Stats()
+	ch <- prometheus.MustNewConstMetric(c.maxOpen, prometheus.GaugeValue, float64(stats.MaxOpenConnections))
+	ch <- prometheus.MustNewConstMetric(c.open, prometheus.GaugeValue, float64(stats.OpenConnections))
+	ch <- prometheus.MustNewConstMetric(c.inUse, prometheus.GaugeValue, float64(stats.InUse))
+	ch <- prometheus.MustNewConstMetric(c.idle, prometheus.GaugeValue, float64(stats.Idle))
+	ch <- prometheus.MustNewConstMetric(c.waitCount, prometheus.CounterValue, float64(stats.WaitCount))
+	ch <- prometheus.MustNewConstMetric(c.waitDuration, prometheus.CounterValue, stats.WaitDuration.Seconds())
+	ch <- prometheus.MustNewConstMetric(c.maxIdleClosed, prometheus.CounterValue, float64(stats.MaxIdleClosed))
+	ch <- prometheus.MustNewConstMetric(c.maxLifetimeClosed, prometheus.CounterValue, float64(stats.MaxLifetimeClosed))
+}
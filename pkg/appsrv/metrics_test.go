@@ -0,0 +1,90 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package appsrv
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	app := NewApplication("metrics-test", 1, false)
+	app.AddHandler("GET", "/servers/<resid>", func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+		Send(w, "ok")
+	})
+	NewWorkerManager("MetricsTestWorkerManager", 2, 8, false)
+
+	for _, path := range []string{"/servers/abc", "/servers/def", "/not/found"} {
+		app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	w := httptest.NewRecorder()
+	MetricsHandler(context.Background(), w, httptest.NewRequest("GET", "/metrics", nil))
+	body := w.Body.String()
+	for _, want := range []string{
+		`onecloud_http_request_duration_seconds_count{app="metrics-test",code="2xx",method="GET",route="/servers/<resid>"} 2`,
+		`onecloud_http_request_duration_seconds_count{app="metrics-test",code="4xx",method="GET",route="*"} 1`,
+		`onecloud_worker_max_workers{manager="MetricsTestWorkerManager"} 2`,
+		`onecloud_worker_queue_size{manager="MetricsTestWorkerManager"} 0`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics output missing %s", want)
+		}
+	}
+}
+
+type fakeDriver struct{}
+
+func (d fakeDriver) Open(name string) (driver.Conn, error) {
+	return nil, errors.New("fake driver can't connect")
+}
+
+func TestDBStatsCollector(t *testing.T) {
+	sql.Register("appsrv-metrics-test", fakeDriver{})
+	db, err := sql.Open("appsrv-metrics-test", "")
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(3)
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(NewDBStatsCollector("test", db))
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("gather: %v", err)
+	}
+	found := false
+	for _, mf := range mfs {
+		if mf.GetName() != "onecloud_db_max_open_connections" {
+			continue
+		}
+		found = true
+		if v := mf.GetMetric()[0].GetGauge().GetValue(); v != 3 {
+			t.Errorf("max open connections %v, want 3", v)
+		}
+	}
+	if !found {
+		t.Errorf("onecloud_db_max_open_connections not found")
+	}
+}
@@ -18,12 +18,15 @@ import (
 	"net"
 	"os"
 	"strconv"
+	"strings"
 
 	"yunion.io/x/log"
 
 	"yunion.io/x/onecloud/pkg/appsrv"
+	"yunion.io/x/onecloud/pkg/cloudcommon/consts"
 	common_options "yunion.io/x/onecloud/pkg/cloudcommon/options"
 	"yunion.io/x/onecloud/pkg/util/seclib2"
+	"yunion.io/x/onecloud/pkg/util/tracing"
 )
 
 func InitApp(options *common_options.BaseOptions, dbAccess bool) *appsrv.Application {
@@ -32,6 +35,8 @@ func InitApp(options *common_options.BaseOptions, dbAccess bool) *appsrv.Applica
 	app := appsrv.NewApplication(options.ApplicationID, options.RequestWorkerCount, dbAccess)
 	app.CORSAllowHosts(options.CorsHosts)
 
+	initTracing(options)
+
 	// app.SetContext(appsrv.APP_CONTEXT_KEY_CACHE, cache)
 	// if dbConn != nil {
 	//	app.SetContext(appsrv.APP_CONTEXT_KEY_DB, dbConn)
@@ -39,6 +44,30 @@ func InitApp(options *common_options.BaseOptions, dbAccess bool) *appsrv.Applica
 	return app
 }
 
+func initTracing(options *common_options.BaseOptions) {
+	if !options.EnableTracing {
+		return
+	}
+	headers := make(map[string]string)
+	for _, h := range options.OtlpHeaders {
+		pos := strings.IndexByte(h, '=')
+		if pos <= 0 {
+			log.Warningf("invalid otlp header %s, should be key=value", h)
+			continue
+		}
+		headers[strings.TrimSpace(h[:pos])] = strings.TrimSpace(h[pos+1:])
+	}
+	err := tracing.Init(tracing.SOptions{
+		ServiceName: consts.GetServiceType(),
+		Endpoint:    options.OtlpEndpoint,
+		Headers:     headers,
+		SampleRatio: options.TracingSampleRatio,
+	})
+	if err != nil {
+		log.Errorf("init tracing: %v", err)
+	}
+}
+
 func ServeForever(app *appsrv.Application, options *common_options.BaseOptions) {
 	ServeForeverWithCleanup(app, options, nil)
 }
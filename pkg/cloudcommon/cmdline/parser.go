@@ -359,6 +359,41 @@ func ParseBaremetalDiskConfig(desc string) (*compute.BaremetalDiskConfig, error)
 	return bdc, nil
 }
 
+// ParseTopologySpreadConstraint desc format: <topology_key>[,max_skew=<n>][,max_per_domain=<n>][,when_unsatisfiable=<do_not_schedule|schedule_anyway>]
+func ParseTopologySpreadConstraint(desc string) (*compute.TopologySpreadConstraint, error) {
+	if len(desc) == 0 {
+		return nil, ErrorEmptyDesc
+	}
+	parts := strings.Split(desc, ",")
+	c := &compute.TopologySpreadConstraint{
+		TopologyKey: parts[0],
+	}
+	for _, p := range parts[1:] {
+		kv := strings.SplitN(p, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid topology spread option %q", p)
+		}
+		var err error
+		switch kv[0] {
+		case "max_skew":
+			c.MaxSkew, err = strconv.Atoi(kv[1])
+		case "max_per_domain":
+			c.MaxPerDomain, err = strconv.Atoi(kv[1])
+		case "when_unsatisfiable":
+			c.WhenUnsatisfiable = kv[1]
+		default:
+			return nil, fmt.Errorf("unknown topology spread option %q", kv[0])
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q", kv[0], kv[1])
+		}
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
 func ParseRange(rangeStr string) (ret []int64, err error) {
 	rss := regexp.MustCompile(`[\s,]+`).Split(rangeStr, -1)
 	intSet := sets.NewInt64()
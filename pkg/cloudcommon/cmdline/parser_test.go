@@ -72,6 +72,58 @@ func TestParseSchedtagConfig(t *testing.T) {
 	}
 }
 
+func TestParseTopologySpreadConstraint(t *testing.T) {
+	tests := []struct {
+		name    string
+		desc    string
+		want    *compute.TopologySpreadConstraint
+		wantErr bool
+	}{
+		{
+			name:    "empty input",
+			desc:    "",
+			wantErr: true,
+		},
+		{
+			name: "zone skew",
+			desc: "zone,max_skew=1",
+			want: &compute.TopologySpreadConstraint{TopologyKey: "zone", MaxSkew: 1, WhenUnsatisfiable: compute.TOPOLOGY_DO_NOT_SCHEDULE},
+		},
+		{
+			name: "metadata per domain",
+			desc: "metadata:rack,max_per_domain=2,when_unsatisfiable=schedule_anyway",
+			want: &compute.TopologySpreadConstraint{TopologyKey: "metadata:rack", MaxPerDomain: 2, WhenUnsatisfiable: compute.TOPOLOGY_SCHEDULE_ANYWAY},
+		},
+		{
+			name:    "no limit",
+			desc:    "storage",
+			wantErr: true,
+		},
+		{
+			name:    "invalid key",
+			desc:    "rack,max_skew=1",
+			wantErr: true,
+		},
+		{
+			name:    "invalid number",
+			desc:    "host,max_skew=a",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTopologySpreadConstraint(tt.desc)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseTopologySpreadConstraint() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseTopologySpreadConstraint() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestParseRange(t *testing.T) {
 	type args struct {
 		rangeStr string
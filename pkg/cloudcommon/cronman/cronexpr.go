@@ -0,0 +1,406 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cronman
+
+import (
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrInvalidCronExpr = errors.New("invalid cron expression")
+)
+
+const (
+	// the search of next fire time gives up after maxCronYears
+	maxCronYears = 5
+	// starBit marks day-of-month or day-of-week field as "*" or "?"
+	starBit = 1 << 63
+)
+
+type cronBounds struct {
+	min, max uint
+	names    map[string]uint
+}
+
+var (
+	secondBounds = cronBounds{0, 59, nil}
+	minuteBounds = cronBounds{0, 59, nil}
+	hourBounds   = cronBounds{0, 23, nil}
+	domBounds    = cronBounds{1, 31, nil}
+	monthBounds  = cronBounds{1, 12, map[string]uint{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// both 0 and 7 are Sunday
+	dowBounds = cronBounds{0, 7, map[string]uint{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+
+	cronDescriptors = map[string]string{
+		"@yearly":   "0 0 1 1 *",
+		"@annually": "0 0 1 1 *",
+		"@monthly":  "0 0 1 * *",
+		"@weekly":   "0 0 * * 0",
+		"@daily":    "0 0 * * *",
+		"@midnight": "0 0 * * *",
+		"@hourly":   "0 * * * *",
+	}
+)
+
+// SCronSchedule is the schedule of standard cron expression, it implements ICronTimer
+type SCronSchedule struct {
+	expr string
+
+	second, minute, hour, dom, month, dow uint64
+
+	location *time.Location
+}
+
+// ParseCronExpr parses cron expression of 5 fields (minute, hour, day of month, month and day of week)
+// or 6 fields with a leading second field. Descriptors such as @daily are supported as well.
+// The expression is evaluated in loc, unless it is prefixed with CRON_TZ=<zone> or TZ=<zone>.
+func ParseCronExpr(expr string, loc *time.Location) (*SCronSchedule, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	spec := strings.TrimSpace(expr)
+	if strings.HasPrefix(spec, "CRON_TZ=") || strings.HasPrefix(spec, "TZ=") {
+		i := strings.IndexAny(spec, " \t")
+		if i < 0 {
+			return nil, errors.Wrapf(ErrInvalidCronExpr, "%q: missing fields after time zone", expr)
+		}
+		zone := spec[strings.Index(spec, "=")+1 : i]
+		var err error
+		loc, err = time.LoadLocation(zone)
+		if err != nil {
+			return nil, errors.Wrapf(ErrInvalidCronExpr, "%q: unknown time zone %s", expr, zone)
+		}
+		spec = strings.TrimSpace(spec[i:])
+	}
+	if strings.HasPrefix(spec, "@") {
+		desc, ok := cronDescriptors[strings.ToLower(spec)]
+		if !ok {
+			return nil, errors.Wrapf(ErrInvalidCronExpr, "%q: unknown descriptor", expr)
+		}
+		spec = desc
+	}
+	fields := strings.Fields(spec)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, errors.Wrapf(ErrInvalidCronExpr, "%q: expect 5 or 6 fields, got %d", expr, len(fields))
+	}
+	sched := &SCronSchedule{
+		expr:     expr,
+		location: loc,
+	}
+	var err error
+	for i, f := range []struct {
+		name   string
+		bits   *uint64
+		bounds cronBounds
+	}{
+		{"second", &sched.second, secondBounds},
+		{"minute", &sched.minute, minuteBounds},
+		{"hour", &sched.hour, hourBounds},
+		{"day of month", &sched.dom, domBounds},
+		{"month", &sched.month, monthBounds},
+		{"day of week", &sched.dow, dowBounds},
+	} {
+		*f.bits, err = parseCronField(fields[i], f.bounds)
+		if err != nil {
+			return nil, errors.Wrapf(ErrInvalidCronExpr, "%q: %s field %q: %v", expr, f.name, fields[i], err)
+		}
+	}
+	// 7 is an alias of Sunday
+	if sched.dow&(1<<7) != 0 {
+		sched.dow = (sched.dow &^ (1 << 7)) | 1
+	}
+	return sched, nil
+}
+
+func parseCronField(field string, bounds cronBounds) (uint64, error) {
+	var bits uint64
+	for _, expr := range strings.Split(field, ",") {
+		b, err := parseCronRange(expr, bounds)
+		if err != nil {
+			return 0, err
+		}
+		bits |= b
+	}
+	return bits, nil
+}
+
+func parseCronValue(v string, bounds cronBounds) (uint, error) {
+	if n, ok := bounds.names[strings.ToLower(v)]; ok {
+		return n, nil
+	}
+	n, err := strconv.ParseUint(v, 10, 0)
+	if err != nil {
+		return 0, errors.Errorf("invalid value %q", v)
+	}
+	return uint(n), nil
+}
+
+func parseCronRange(expr string, bounds cronBounds) (uint64, error) {
+	var (
+		start, end, step uint = 0, 0, 1
+		extra            uint64
+		err              error
+	)
+	rangeAndStep := strings.Split(expr, "/")
+	if len(rangeAndStep) > 2 {
+		return 0, errors.Errorf("too many slashes in %q", expr)
+	}
+	lowAndHigh := strings.Split(rangeAndStep[0], "-")
+	if len(lowAndHigh) > 2 {
+		return 0, errors.Errorf("too many hyphens in %q", expr)
+	}
+	if lowAndHigh[0] == "*" || lowAndHigh[0] == "?" {
+		if len(lowAndHigh) > 1 {
+			return 0, errors.Errorf("invalid range %q", expr)
+		}
+		start, end = bounds.min, bounds.max
+		if len(rangeAndStep) == 1 {
+			extra = starBit
+		}
+	} else {
+		start, err = parseCronValue(lowAndHigh[0], bounds)
+		if err != nil {
+			return 0, err
+		}
+		end = start
+		if len(lowAndHigh) == 2 {
+			end, err = parseCronValue(lowAndHigh[1], bounds)
+			if err != nil {
+				return 0, err
+			}
+		} else if len(rangeAndStep) == 2 {
+			// a/n means a-max/n
+			end = bounds.max
+		}
+	}
+	if len(rangeAndStep) == 2 {
+		step, err = parseCronValue(rangeAndStep[1], cronBounds{})
+		if err != nil {
+			return 0, err
+		}
+		if step == 0 {
+			return 0, errors.Errorf("step of %q should be positive", expr)
+		}
+	}
+	if start < bounds.min || end > bounds.max {
+		return 0, errors.Errorf("%q is beyond range %d-%d", expr, bounds.min, bounds.max)
+	}
+	if start > end {
+		return 0, errors.Errorf("start of %q is greater than end", expr)
+	}
+	var bits uint64
+	for i := start; i <= end; i += step {
+		bits |= 1 << i
+	}
+	return bits | extra, nil
+}
+
+func (s *SCronSchedule) String() string {
+	return s.expr
+}
+
+func (s *SCronSchedule) Location() *time.Location {
+	return s.location
+}
+
+// FireTimesPerMinute returns how many times the schedule fires in a matched minute
+func (s *SCronSchedule) FireTimesPerMinute() int {
+	return bits.OnesCount64(s.second &^ starBit)
+}
+
+// dayMatches follows the convention of cron, if both day of month and day of week
+// are restricted, the day matches either of them
+func (s *SCronSchedule) dayMatches(t time.Time) bool {
+	domMatch := 1<<uint(t.Day())&s.dom > 0
+	dowMatch := 1<<uint(t.Weekday())&s.dow > 0
+	if s.dom&starBit > 0 || s.dow&starBit > 0 {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// Next returns the first fire time after t, zero time is returned if there is none in 5 years
+func (s *SCronSchedule) Next(t time.Time) time.Time {
+	origLocation := t.Location()
+	loc := s.location
+	t = t.In(loc)
+	// start from the next whole second
+	t = t.Add(time.Second - time.Duration(t.Nanosecond())*time.Nanosecond)
+
+	added := false
+	yearLimit := t.Year() + maxCronYears
+
+WRAP:
+	if t.Year() > yearLimit {
+		return time.Time{}
+	}
+
+	for 1<<uint(t.Month())&s.month == 0 {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc)
+		}
+		t = t.AddDate(0, 1, 0)
+		if t.Month() == time.January {
+			goto WRAP
+		}
+	}
+
+	for !s.dayMatches(t) {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+		}
+		t = t.AddDate(0, 0, 1)
+		// the midnight may be skipped or repeated by daylight saving time
+		if t.Hour() != 0 {
+			if t.Hour() > 12 {
+				t = t.Add(time.Duration(24-t.Hour()) * time.Hour)
+			} else {
+				t = t.Add(time.Duration(-t.Hour()) * time.Hour)
+			}
+		}
+		if t.Day() == 1 {
+			goto WRAP
+		}
+	}
+
+	for 1<<uint(t.Hour())&s.hour == 0 {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, loc)
+		}
+		t = t.Add(time.Hour)
+		if t.Hour() == 0 {
+			goto WRAP
+		}
+	}
+
+	for 1<<uint(t.Minute())&s.minute == 0 {
+		if !added {
+			added = true
+			t = t.Truncate(time.Minute)
+		}
+		t = t.Add(time.Minute)
+		if t.Minute() == 0 {
+			goto WRAP
+		}
+	}
+
+	for 1<<uint(t.Second())&s.second == 0 {
+		if !added {
+			added = true
+			t = t.Truncate(time.Second)
+		}
+		t = t.Add(time.Second)
+		if t.Second() == 0 {
+			goto WRAP
+		}
+	}
+
+	return t.In(origLocation)
+}
+
+// SExclusionWindow is a period, such as holiday or change freeze, in which jobs should not run
+type SExclusionWindow struct {
+	Start time.Time
+	End   time.Time
+}
+
+func (w SExclusionWindow) Contains(t time.Time) bool {
+	return !t.Before(w.Start) && t.Before(w.End)
+}
+
+// SCalendar is a set of exclusion windows
+type SCalendar struct {
+	Windows []SExclusionWindow
+}
+
+// Find returns the exclusion window which contains t, nil if not excluded
+func (c *SCalendar) Find(t time.Time) *SExclusionWindow {
+	if c == nil {
+		return nil
+	}
+	for i := range c.Windows {
+		if c.Windows[i].Contains(t) {
+			return &c.Windows[i]
+		}
+	}
+	return nil
+}
+
+func (c *SCalendar) Excludes(t time.Time) bool {
+	return c.Find(t) != nil
+}
+
+// maxExclusionSkips limits the times of skipping windows in case of overlapped or endless windows
+const maxExclusionSkips = 1000
+
+type sCalendarTimer struct {
+	timer    ICronTimer
+	calendar *SCalendar
+}
+
+// NewCalendarTimer returns the timer skips fire times in the exclusion windows of calendar
+func NewCalendarTimer(timer ICronTimer, calendar *SCalendar) ICronTimer {
+	if calendar == nil || len(calendar.Windows) == 0 {
+		return timer
+	}
+	return &sCalendarTimer{
+		timer:    timer,
+		calendar: calendar,
+	}
+}
+
+func (t *sCalendarTimer) Next(now time.Time) time.Time {
+	next := t.timer.Next(now)
+	for i := 0; i < maxExclusionSkips && !next.IsZero(); i++ {
+		w := t.calendar.Find(next)
+		if w == nil {
+			return next
+		}
+		// the first fire time not earlier than the end of window
+		next = t.timer.Next(w.End.Add(-time.Nanosecond))
+	}
+	return time.Time{}
+}
+
+// NextFireTimes returns at most n fire times of timer after from
+func NextFireTimes(timer ICronTimer, from time.Time, n int) []time.Time {
+	ret := make([]time.Time, 0, n)
+	for len(ret) < n {
+		next := timer.Next(from)
+		if next.IsZero() || !next.After(from) {
+			break
+		}
+		ret = append(ret, next)
+		from = next
+	}
+	return ret
+}
@@ -0,0 +1,169 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cronman
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCronExpr(t *testing.T) {
+	for _, expr := range []string{
+		"* * * * *",
+		"0 30 8 * * 1-5",
+		"*/15 0-6,18-23 1,15 jan-jun mon,fri",
+		"0 0 ? * SUN",
+		"CRON_TZ=Asia/Shanghai 0 8 * * *",
+		"TZ=UTC @daily",
+		"@hourly",
+		"0 0 * * 7",
+	} {
+		if _, err := ParseCronExpr(expr, nil); err != nil {
+			t.Errorf("parse %q: %v", expr, err)
+		}
+	}
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"* * * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"* * * foo *",
+		"@every",
+		"CRON_TZ=Mars/Base 0 8 * * *",
+		"CRON_TZ=UTC",
+	} {
+		if _, err := ParseCronExpr(expr, nil); err == nil {
+			t.Errorf("parse %q: expect error", expr)
+		}
+	}
+}
+
+func TestFireTimesPerMinute(t *testing.T) {
+	for expr, want := range map[string]int{
+		"* * * * *":      1,
+		"*/10 * * * * *": 6,
+		"* * * * * *":    60,
+		"0,30 * * * * *": 2,
+	} {
+		sched, err := ParseCronExpr(expr, nil)
+		if err != nil {
+			t.Fatalf("parse %q: %v", expr, err)
+		}
+		if got := sched.FireTimesPerMinute(); got != want {
+			t.Errorf("%q: want %d got %d", expr, want, got)
+		}
+	}
+}
+
+func TestCronScheduleNext(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Skipf("load location: %v", err)
+	}
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("load location: %v", err)
+	}
+	utc := func(s string) time.Time {
+		tm, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			t.Fatalf("parse time %s: %v", s, err)
+		}
+		return tm
+	}
+	cases := []struct {
+		expr string
+		loc  *time.Location
+		from string
+		want string
+	}{
+		{"* * * * *", nil, "2021-03-01T10:00:00Z", "2021-03-01T10:01:00Z"},
+		{"* * * * *", nil, "2021-03-01T10:00:30.5Z", "2021-03-01T10:01:00Z"},
+		{"*/10 * * * * *", nil, "2021-03-01T10:00:00Z", "2021-03-01T10:00:10Z"},
+		{"30 8 * * 1-5", nil, "2021-03-05T09:00:00Z", "2021-03-08T08:30:00Z"},
+		{"0 0 29 2 *", nil, "2021-03-01T00:00:00Z", "2024-02-29T00:00:00Z"},
+		{"0 0 31 * *", nil, "2021-04-01T00:00:00Z", "2021-05-31T00:00:00Z"},
+		{"@monthly", nil, "2021-12-15T00:00:00Z", "2022-01-01T00:00:00Z"},
+		// day of month and day of week are ORed if both are restricted
+		{"0 0 13 * 5", nil, "2021-03-01T00:00:00Z", "2021-03-05T00:00:00Z"},
+		{"0 0 13 * 5", nil, "2021-03-12T00:00:00Z", "2021-03-13T00:00:00Z"},
+		{"0 0 * * 0", nil, "2021-03-01T00:00:00Z", "2021-03-07T00:00:00Z"},
+		{"0 0 * * 7", nil, "2021-03-01T00:00:00Z", "2021-03-07T00:00:00Z"},
+		{"0 8 * * *", shanghai, "2021-03-01T00:00:00Z", "2021-03-02T00:00:00Z"},
+		{"CRON_TZ=Asia/Shanghai 0 8 * * *", nil, "2021-03-01T00:00:00Z", "2021-03-02T00:00:00Z"},
+		// 02:30 is skipped on the start of daylight saving time
+		{"30 2 * * *", newYork, "2021-03-13T12:00:00Z", "2021-03-15T06:30:00Z"},
+		{"0 0 30 2 *", nil, "2021-03-01T00:00:00Z", "0001-01-01T00:00:00Z"},
+	}
+	for _, c := range cases {
+		sched, err := ParseCronExpr(c.expr, c.loc)
+		if err != nil {
+			t.Fatalf("parse %q: %v", c.expr, err)
+		}
+		got := sched.Next(utc(c.from))
+		if !got.Equal(utc(c.want)) {
+			t.Errorf("%q next of %s: want %s got %s", c.expr, c.from, c.want, got.UTC())
+		}
+	}
+}
+
+func TestCalendarTimer(t *testing.T) {
+	sched, err := ParseCronExpr("0 2 * * *", nil)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	from := time.Date(2021, 12, 23, 0, 0, 0, 0, time.UTC)
+	calendar := &SCalendar{
+		Windows: []SExclusionWindow{
+			{
+				Start: time.Date(2021, 12, 24, 0, 0, 0, 0, time.UTC),
+				End:   time.Date(2021, 12, 26, 0, 0, 0, 0, time.UTC),
+			},
+			{
+				Start: time.Date(2021, 12, 26, 0, 0, 0, 0, time.UTC),
+				End:   time.Date(2021, 12, 27, 2, 0, 0, 0, time.UTC),
+			},
+		},
+	}
+	timer := NewCalendarTimer(sched, calendar)
+	got := NextFireTimes(timer, from, 3)
+	want := []time.Time{
+		time.Date(2021, 12, 23, 2, 0, 0, 0, time.UTC),
+		// the end of window is not excluded
+		time.Date(2021, 12, 27, 2, 0, 0, 0, time.UTC),
+		time.Date(2021, 12, 28, 2, 0, 0, 0, time.UTC),
+	}
+	if len(got) != len(want) {
+		t.Fatalf("want %d fire times, got %v", len(want), got)
+	}
+	for i := range want {
+		if !got[i].Equal(want[i]) {
+			t.Errorf("fire time %d: want %s got %s", i, want[i], got[i])
+		}
+	}
+
+	long := NewCalendarTimer(sched, &SCalendar{
+		Windows: []SExclusionWindow{{Start: from, End: from.AddDate(10, 0, 0)}},
+	})
+	if next, want := long.Next(from), time.Date(2031, 12, 23, 2, 0, 0, 0, time.UTC); !next.Equal(want) {
+		t.Errorf("want %s, got %s", want, next)
+	}
+}
@@ -265,6 +265,43 @@ func (self *SCronJobManager) AddJobEveryFewHour(name string, hour, min, sec int,
 	return nil
 }
 
+// AddJobWithTimer adds job fired by the customized timer
+func (self *SCronJobManager) AddJobWithTimer(name string, timer ICronTimer, jobFunc TCronJobFunction, startRun bool) error {
+	if timer == nil {
+		return errors.New("AddJobWithTimer: timer must not be nil")
+	}
+
+	self.dataLock.Lock()
+	defer self.dataLock.Unlock()
+
+	if !self.IsNameUnique(name) {
+		return ErrCronJobNameConflict
+	}
+
+	job := SCronJob{
+		Name:     name,
+		job:      jobFunc,
+		Timer:    timer,
+		StartRun: startRun,
+	}
+	if !self.running {
+		self.jobs = append(self.jobs, &job)
+	} else {
+		self.addJob(&job)
+	}
+	return nil
+}
+
+// AddJobByCronExpr adds job fired by cron expression evaluated in loc, the fire times
+// falling in the exclusion windows of calendar are skipped
+func (self *SCronJobManager) AddJobByCronExpr(name string, expr string, loc *time.Location, calendar *SCalendar, jobFunc TCronJobFunction, startRun bool) error {
+	sched, err := ParseCronExpr(expr, loc)
+	if err != nil {
+		return errors.Wrap(err, "AddJobByCronExpr")
+	}
+	return self.AddJobWithTimer(name, NewCalendarTimer(sched, calendar), jobFunc, startRun)
+}
+
 func (self *SCronJobManager) addJob(newJob *SCronJob) {
 	now := time.Now()
 	newJob.Next = newJob.Timer.Next(now)
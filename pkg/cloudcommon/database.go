@@ -27,6 +27,7 @@ import (
 	"yunion.io/x/sqlchemy"
 
 	noapi "yunion.io/x/onecloud/pkg/apis/notify"
+	"yunion.io/x/onecloud/pkg/appsrv"
 	"yunion.io/x/onecloud/pkg/cloudcommon/consts"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db/lockman"
@@ -82,6 +83,7 @@ func InitDB(options *common_options.DBOptions) {
 		panic(err)
 	}
 	sqlchemy.SetDBWithNameBackend(dbConn, sqlchemy.DefaultDB, backend)
+	appsrv.RegisterMetricsCollector(appsrv.NewDBStatsCollector("default", dbConn))
 
 	dialect, sqlStr, err = options.GetClickhouseConnStr()
 	if err == nil {
@@ -91,6 +93,7 @@ func InitDB(options *common_options.DBOptions) {
 			panic(err)
 		}
 		sqlchemy.SetDBWithNameBackend(click, db.ClickhouseDB, sqlchemy.ClickhouseBackend)
+		appsrv.RegisterMetricsCollector(appsrv.NewDBStatsCollector("clickhouse", click))
 
 		if options.OpsLogWithClickhouse {
 			consts.OpsLogWithClickhouse = true
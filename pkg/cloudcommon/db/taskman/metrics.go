@@ -0,0 +1,130 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package taskman
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"yunion.io/x/log"
+	"yunion.io/x/sqlchemy"
+
+	"yunion.io/x/onecloud/pkg/appsrv"
+)
+
+const (
+	TASK_STATUS_RUNNING  = "running"
+	TASK_STATUS_COMPLETE = "complete"
+	TASK_STATUS_FAILED   = "failed"
+
+	// only the tasks created in this window are counted, as the tasks table
+	// keeps every task ever run
+	taskMetricsWindow = 24 * time.Hour
+	// task counts are refreshed in background at this interval, so that
+	// scrapes never hit the database
+	taskMetricsInterval = time.Minute
+)
+
+var (
+	taskMetricsOnce sync.Once
+
+	taskCountDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(appsrv.METRICS_NAMESPACE, "taskman", "tasks"),
+		"Number of tasks created in the last 24 hours, partitioned by task name and status.",
+		[]string{"task", "status"}, nil,
+	)
+)
+
+func registerTaskMetrics() {
+	taskMetricsOnce.Do(func() {
+		appsrv.RegisterMetricsCollector(&sTaskCollector{})
+	})
+}
+
+func taskStatus(stage string) string {
+	switch stage {
+	case TASK_STAGE_COMPLETE:
+		return TASK_STATUS_COMPLETE
+	case TASK_STAGE_FAILED:
+		return TASK_STATUS_FAILED
+	default:
+		return TASK_STATUS_RUNNING
+	}
+}
+
+type sTaskCollector struct {
+	// the refresh loop is started on the first scrape, when the database is
+	// surely initialized
+	loopOnce sync.Once
+
+	lock   sync.RWMutex
+	counts map[string]map[string]int
+}
+
+func (c *sTaskCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- taskCountDesc
+}
+
+func (c *sTaskCollector) Collect(ch chan<- prometheus.Metric) {
+	c.loopOnce.Do(func() {
+		go c.refreshLoop()
+	})
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	for name, statusCounts := range c.counts {
+		for status, count := range statusCounts {
+			ch <- prometheus.MustNewConstMetric(taskCountDesc, prometheus.GaugeValue, float64(count), name, status)
+		}
+	}
+}
+
+func (c *sTaskCollector) refreshLoop() {
+	ticker := time.NewTicker(taskMetricsInterval)
+	defer ticker.Stop()
+	for {
+		c.refresh()
+		<-ticker.C
+	}
+}
+
+func (c *sTaskCollector) refresh() {
+	tasks := TaskManager.Query().SubQuery()
+	q := tasks.Query(
+		tasks.Field("task_name"),
+		tasks.Field("stage"),
+		sqlchemy.COUNT("count"),
+	).GE("created_at", time.Now().Add(-taskMetricsWindow)).GroupBy(tasks.Field("task_name"), tasks.Field("stage"))
+	rows := []struct {
+		TaskName string
+		Stage    string
+		Count    int
+	}{}
+	if err := q.All(&rows); err != nil {
+		log.Errorf("refresh task metrics: %v", err)
+		return
+	}
+	counts := map[string]map[string]int{}
+	for _, row := range rows {
+		if _, ok := counts[row.TaskName]; !ok {
+			counts[row.TaskName] = map[string]int{}
+		}
+		counts[row.TaskName][taskStatus(row.Stage)] += row.Count
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.counts = counts
+}
@@ -44,6 +44,7 @@ import (
 	"yunion.io/x/onecloud/pkg/mcclient/auth"
 	"yunion.io/x/onecloud/pkg/util/httputils"
 	"yunion.io/x/onecloud/pkg/util/rbacutils"
+	"yunion.io/x/onecloud/pkg/util/tracing"
 )
 
 const (
@@ -441,6 +442,22 @@ func execITask(taskValue reflect.Value, task *STask, odata jsonutils.JSONObject,
 		stageName = task.Stage
 	}
 
+	// each stage is a span, the following stage continues the trace of
+	// this one through the saved request context
+	ctx, span := tracing.StartSpan(ctx, task.TaskName+"."+stageName, tracing.SPAN_KIND_INTERNAL)
+	defer span.End()
+	span.SetAttribute("task.id", task.Id)
+	span.SetAttribute("task.name", task.TaskName)
+	span.SetAttribute("task.stage", stageName)
+	span.SetAttribute("task.obj_type", task.ObjName)
+	span.SetAttribute("task.obj_id", task.ObjId)
+	if taskFailed {
+		span.SetStatus(tracing.STATUS_ERROR, "stage callback failed")
+	}
+	if sc := span.SpanContext(); sc.IsValid() {
+		ctxData.TraceParent = sc.TraceParent()
+	}
+
 	funcValue := taskValue.MethodByName(stageName)
 
 	if !funcValue.IsValid() || funcValue.IsNil() {
@@ -450,6 +467,8 @@ func execITask(taskValue reflect.Value, task *STask, odata jsonutils.JSONObject,
 		}
 		stageName = utils.Kebab2Camel(stageName, "_")
 		funcValue = taskValue.MethodByName(stageName)
+		span.SetName(task.TaskName + "." + stageName)
+		span.SetAttribute("task.stage", stageName)
 
 		if !funcValue.IsValid() || funcValue.IsNil() {
 			msg := fmt.Sprintf("Stage %s not found", stageName)
@@ -460,6 +479,7 @@ func execITask(taskValue reflect.Value, task *STask, odata jsonutils.JSONObject,
 			} else {
 				log.Errorf(msg)
 			}
+			span.SetStatus(tracing.STATUS_ERROR, msg)
 			task.SetStageFailed(ctx, jsonutils.NewString(msg))
 			task.SaveRequestContext(&ctxData)
 			return
@@ -470,6 +490,7 @@ func execITask(taskValue reflect.Value, task *STask, odata jsonutils.JSONObject,
 	if objManager == nil {
 		msg := fmt.Sprintf("model %s not found??? ...", task.ObjName)
 		log.Errorf(msg)
+		span.SetStatus(tracing.STATUS_ERROR, msg)
 		task.SetStageFailed(ctx, jsonutils.NewString(msg))
 		task.SaveRequestContext(&ctxData)
 		return
@@ -479,6 +500,7 @@ func execITask(taskValue reflect.Value, task *STask, odata jsonutils.JSONObject,
 	if !ok {
 		msg := fmt.Sprintf("model %s is not a resource??? ...", task.ObjName)
 		log.Errorf(msg)
+		span.SetStatus(tracing.STATUS_ERROR, msg)
 		task.SetStageFailed(ctx, jsonutils.NewString(msg))
 		task.SaveRequestContext(&ctxData)
 		return
@@ -495,6 +517,7 @@ func execITask(taskValue reflect.Value, task *STask, odata jsonutils.JSONObject,
 			if err != nil {
 				msg := fmt.Sprintf("fail to find %s object %s", task.ObjName, objId)
 				log.Errorf(msg)
+				span.SetStatus(tracing.STATUS_ERROR, msg)
 				task.SetStageFailed(ctx, jsonutils.NewString(msg))
 				task.SaveRequestContext(&ctxData)
 				return
@@ -512,6 +535,7 @@ func execITask(taskValue reflect.Value, task *STask, odata jsonutils.JSONObject,
 		if err != nil {
 			msg := fmt.Sprintf("fail to find %s object %s", task.ObjName, task.ObjId)
 			log.Errorf(msg)
+			span.SetStatus(tracing.STATUS_ERROR, msg)
 			task.SetStageFailed(ctx, jsonutils.NewString(msg))
 			task.SaveRequestContext(&ctxData)
 			return
@@ -537,6 +561,7 @@ func execITask(taskValue reflect.Value, task *STask, odata jsonutils.JSONObject,
 			// call set stage failed, should not call task.SetStageFailed
 			// func SetStageFailed may be overloading
 			log.Errorf("Task %s PANIC on stage %s: %v \n%s", task.TaskName, stageName, r, debug.Stack())
+			span.SetStatus(tracing.STATUS_ERROR, fmt.Sprintf("panic: %v", r))
 			SetStageFailedFuncValue := taskValue.MethodByName("SetStageFailed")
 			SetStageFailedFuncValue.Call(
 				[]reflect.Value{
@@ -721,7 +746,13 @@ func notifyRemoteTask(ctx context.Context, notifyUrl string, taskid string, body
 	if len(taskid) > 0 {
 		header.Set("X-Task-Id", taskid)
 	}
+	ctx, span := tracing.StartSpan(ctx, "NotifyRemoteTask", tracing.SPAN_KIND_CLIENT)
+	span.SetAttribute("http.url", notifyUrl)
+	span.SetAttribute("task.id", taskid)
+	span.SetAttribute("task.notify_tries", tried)
 	_, body, err := httputils.JSONRequest(client, ctx, "POST", notifyUrl, header, body, true)
+	span.RecordError(err)
+	span.End()
 	if err != nil {
 		log.Errorf("notifyRemoteTask fail %s", err)
 		if tried > MAX_REMOTE_NOTIFY_TRIES {
@@ -112,6 +112,11 @@ type BaseOptions struct {
 	PlatformNames map[string]string `help:"identity name of this platform by language"`
 
 	EnableTlsMigration bool `help:"Enable TLS migration" default:"false"`
+
+	EnableTracing      bool     `help:"Export spans of distributed tracing to an OTLP collector" default:"false"`
+	OtlpEndpoint       string   `help:"OTLP/HTTP endpoint of the tracing collector, e.g. http://otel-collector:4318"`
+	OtlpHeaders        []string `help:"Extra headers sent to the tracing collector, in the form of key=value"`
+	TracingSampleRatio float64  `help:"Ratio of the traces started by this service to be sampled" default:"1.0"`
 }
 
 const (
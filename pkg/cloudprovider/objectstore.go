@@ -277,6 +277,33 @@ type ICloudBucket interface {
 	DeletePolicy(id []string) ([]SBucketPolicyStatement, error)
 
 	ListMultipartUploads() ([]SBucketMultipartUploads, error)
+
+	// S3 compatible XML documents of bucket sub-resources, empty string
+	// when the sub-resource is not configured
+	GetLifecycleConf() (string, error)
+	SetLifecycleConf(conf string) error
+	DeleteLifecycleConf() error
+
+	GetEncryptionConf() (string, error)
+	SetEncryptionConf(conf string) error
+	DeleteEncryptionConf() error
+
+	GetNotificationConf() (string, error)
+	SetNotificationConf(conf string) error
+
+	GetLoggingConf() (string, error)
+	SetLoggingConf(conf string) error
+
+	GetObjectLockConf() (string, error)
+	SetObjectLockConf(conf string) error
+
+	GetVersioningConf() (string, error)
+	SetVersioningConf(conf string) error
+
+	// S3 compatible bucket policy in JSON
+	GetPolicyDocument() (string, error)
+	SetPolicyDocument(doc string) error
+	DeletePolicyDocument() error
 }
 
 type ICloudObject interface {
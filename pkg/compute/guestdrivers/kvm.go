@@ -17,6 +17,7 @@ package guestdrivers
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"strconv"
 	"strings"
 	"time"
@@ -269,8 +270,17 @@ func (self *SKVMGuestDriver) RequestDeployGuestOnHost(ctx context.Context, guest
 	if err != nil {
 		return err
 	}
-	url := fmt.Sprintf("%s/servers/%s/%s", host.ManagerUri, guest.Id, action)
 	header := self.getTaskRequestHeader(task)
+	if action == "create" && guest.HasNvram() {
+		nvram, err := self.fetchDeployNvram(ctx, task.GetUserCred(), guest, host, task.GetParams())
+		if err != nil {
+			return errors.Wrap(err, "fetchDeployNvram")
+		}
+		if nvram != nil {
+			config.Set("nvram", jsonutils.Marshal(nvram))
+		}
+	}
+	url := fmt.Sprintf("%s/servers/%s/%s", host.ManagerUri, guest.Id, action)
 	_, _, err = httputils.JSONRequest(httputils.GetDefaultClient(), ctx, "POST", url, header, config, false)
 	if err != nil {
 		return err
@@ -278,6 +288,37 @@ func (self *SKVMGuestDriver) RequestDeployGuestOnHost(ctx context.Context, guest
 	return nil
 }
 
+// fetchDeployNvram fetches the UEFI variable store and vTPM state of the guest
+// a new one is cloned from, of the instance backup it's recovered from, or of
+// the master guest when deploying its backup
+func (self *SKVMGuestDriver) fetchDeployNvram(ctx context.Context, userCred mcclient.TokenCredential, guest *models.SGuest, host *models.SHost, params *jsonutils.JSONDict) (map[string]string, error) {
+	if ibId, _ := params.GetString("instance_backup_id"); len(ibId) > 0 {
+		ib, err := models.InstanceBackupManager.FetchById(ibId)
+		if err != nil {
+			return nil, errors.Wrapf(err, "fetch instance backup %s", ibId)
+		}
+		return ib.(*models.SInstanceBackup).GetNvram()
+	}
+	srcGuest := guest
+	if srcId, _ := params.GetString("nvram_source_guest_id"); len(srcId) > 0 {
+		srcGuest = models.GuestManager.FetchGuestById(srcId)
+		if srcGuest == nil {
+			return nil, errors.Wrapf(errors.ErrNotFound, "nvram source guest %s", srcId)
+		}
+	} else if len(guest.BackupHostId) == 0 || guest.BackupHostId != host.Id {
+		return nil, nil
+	}
+	nvram, err := srcGuest.ExportNvram(ctx, userCred)
+	if err != nil {
+		if srcGuest.Id == guest.Id && httputils.ErrorCode(err) == http.StatusNotFound {
+			// master guest is not created yet, the backup starts with fresh state as well
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "export nvram")
+	}
+	return nvram, nil
+}
+
 func (self *SKVMGuestDriver) OnGuestDeployTaskDataReceived(ctx context.Context, guest *models.SGuest, task taskman.ITask, data jsonutils.JSONObject) error {
 	guest.SaveDeployInfo(ctx, task.GetUserCred(), data)
 	return nil
@@ -771,6 +812,13 @@ func (self *SKVMGuestDriver) CheckLiveMigrate(ctx context.Context, guest *models
 		if len(devices) > 0 {
 			return httperrors.NewBadRequestError("Cannot live migrate with isolated devices")
 		}
+		sharedfs, err := guest.GetSharedFilesystems()
+		if err != nil {
+			return errors.Wrapf(err, "GetSharedFilesystems")
+		}
+		if len(sharedfs) > 0 {
+			return httperrors.NewBadRequestError("Cannot live migrate with shared filesystems")
+		}
 		if !guest.CheckQemuVersion(guest.GetQemuVersion(userCred), "1.1.2") {
 			return httperrors.NewBadRequestError("Cannot do live migrate, too low qemu version")
 		}
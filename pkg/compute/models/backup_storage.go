@@ -16,11 +16,14 @@ package models
 
 import (
 	"context"
+	"net/url"
 	"reflect"
+	"strings"
 	"time"
 
 	"yunion.io/x/jsonutils"
 	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
 	"yunion.io/x/pkg/gotypes"
 	"yunion.io/x/pkg/utils"
 	"yunion.io/x/sqlchemy"
@@ -40,9 +43,10 @@ type SBackupStorageManager struct {
 type SBackupStorage struct {
 	db.SEnabledStatusInfrasResourceBase
 
-	AccessInfo  *SBackupStorageAccessInfo
-	StorageType string `width:"32" charset:"ascii" nullable:"false" list:"user" create:"domain_required"`
-	CapacityMb  int    `nullable:"false" list:"user" update:"domain" create:"domain_required"`
+	AccessInfo     *SBackupStorageAccessInfo
+	StorageType    string `width:"32" charset:"ascii" nullable:"false" list:"user" create:"domain_required"`
+	CapacityMb     int    `nullable:"false" list:"user" update:"domain" create:"domain_required"`
+	UsedCapacityMb int    `nullable:"false" default:"0" list:"user"`
 }
 
 var BackupStorageManager *SBackupStorageManager
@@ -65,6 +69,12 @@ func init() {
 type SBackupStorageAccessInfo struct {
 	NfsHost      string `json:"nfs_host"`
 	NfsSharedDir string `json:"nfs_shared_dir"`
+
+	ObjectBucketUrl string `json:"object_bucket_url"`
+	ObjectAccessKey string `json:"object_access_key"`
+	// ObjectSecret is encrypted with the id of backup storage
+	ObjectSecret string `json:"object_secret"`
+	ObjectSse    string `json:"object_sse"`
 }
 
 func (ba *SBackupStorageAccessInfo) String() string {
@@ -81,7 +91,7 @@ func (bs *SBackupStorageManager) ValidateCreateData(ctx context.Context, userCre
 	if err != nil {
 		return input, err
 	}
-	if !utils.IsInStringArray(input.StorageType, []string{api.BACKUPSTORAGE_TYPE_NFS}) {
+	if !utils.IsInStringArray(input.StorageType, []string{api.BACKUPSTORAGE_TYPE_NFS, api.BACKUPSTORAGE_TYPE_OBJECT_STORAGE}) {
 		return input, httperrors.NewInputParameterError("Invalid storage type %s", input.StorageType)
 	}
 	switch input.StorageType {
@@ -92,23 +102,91 @@ func (bs *SBackupStorageManager) ValidateCreateData(ctx context.Context, userCre
 		if input.NfsSharedDir == "" {
 			return input, httperrors.NewInputParameterError("nfs_shared_dir is required when storage type is nfs")
 		}
-
+	case api.BACKUPSTORAGE_TYPE_OBJECT_STORAGE:
+		if input.ObjectBucketUrl == "" {
+			return input, httperrors.NewInputParameterError("object_bucket_url is required when storage type is object")
+		}
+		if _, _, err := parseObjectBucketUrl(input.ObjectBucketUrl); err != nil {
+			return input, httperrors.NewInputParameterError("invalid object_bucket_url %s: %s", input.ObjectBucketUrl, err)
+		}
+		if input.ObjectAccessKey == "" {
+			return input, httperrors.NewInputParameterError("object_access_key is required when storage type is object")
+		}
+		if input.ObjectSecret == "" {
+			return input, httperrors.NewInputParameterError("object_secret is required when storage type is object")
+		}
+		if input.ObjectSse != "" && input.ObjectSse != api.BACKUPSTORAGE_OBJECT_SSE_AES256 {
+			return input, httperrors.NewInputParameterError("unsupported object_sse %s", input.ObjectSse)
+		}
 	}
 	return input, nil
 }
 
+// parseObjectBucketUrl split url like http://minio:9000/bucket into endpoint and bucket name
+func parseObjectBucketUrl(bucketUrl string) (string, string, error) {
+	u, err := url.Parse(bucketUrl)
+	if err != nil {
+		return "", "", errors.Wrap(err, "url.Parse")
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", "", errors.Wrapf(httperrors.ErrInputParameter, "unsupported scheme %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return "", "", errors.Wrap(httperrors.ErrInputParameter, "empty endpoint")
+	}
+	bucket := strings.Trim(u.Path, "/")
+	if bucket == "" || strings.Contains(bucket, "/") {
+		return "", "", errors.Wrap(httperrors.ErrInputParameter, "bucket name should be the only path of url")
+	}
+	return u.Scheme + "://" + u.Host, bucket, nil
+}
+
 func (bs *SBackupStorage) CustomizeCreate(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, data jsonutils.JSONObject) error {
 	bs.SetEnabled(true)
-	nfsHost, _ := data.GetString("nfs_host")
-	nfsSharedDir, _ := data.GetString("nfs_shared_dir")
+	input := api.BackupStorageCreateInput{}
+	err := data.Unmarshal(&input)
+	if err != nil {
+		return errors.Wrap(err, "Unmarshal BackupStorageCreateInput")
+	}
 	bs.Status = api.BACKUPSTORAGE_STATUS_ONLINE
 	bs.AccessInfo = &SBackupStorageAccessInfo{
-		NfsHost:      nfsHost,
-		NfsSharedDir: nfsSharedDir,
+		NfsHost:      input.NfsHost,
+		NfsSharedDir: input.NfsSharedDir,
+	}
+	if input.StorageType == api.BACKUPSTORAGE_TYPE_OBJECT_STORAGE {
+		if len(bs.Id) == 0 {
+			bs.Id = db.DefaultUUIDGenerator()
+		}
+		secret, err := utils.EncryptAESBase64(bs.Id, input.ObjectSecret)
+		if err != nil {
+			return errors.Wrap(err, "EncryptAESBase64")
+		}
+		bs.AccessInfo.ObjectBucketUrl = input.ObjectBucketUrl
+		bs.AccessInfo.ObjectAccessKey = input.ObjectAccessKey
+		bs.AccessInfo.ObjectSecret = secret
+		bs.AccessInfo.ObjectSse = input.ObjectSse
 	}
 	return bs.SEnabledStatusInfrasResourceBase.CustomizeCreate(ctx, userCred, ownerId, query, data)
 }
 
+// GetAccessInfo returns the access info which is sent to host agent, with secret decrypted
+func (bs *SBackupStorage) GetAccessInfo() (*jsonutils.JSONDict, error) {
+	if bs.AccessInfo == nil {
+		return jsonutils.NewDict(), nil
+	}
+	info := *bs.AccessInfo
+	if len(info.ObjectSecret) > 0 {
+		secret, err := utils.DescryptAESBase64(bs.Id, info.ObjectSecret)
+		if err != nil {
+			return nil, errors.Wrap(err, "DescryptAESBase64")
+		}
+		info.ObjectSecret = secret
+	}
+	ret := jsonutils.Marshal(info).(*jsonutils.JSONDict)
+	ret.Set("storage_type", jsonutils.NewString(bs.StorageType))
+	return ret, nil
+}
+
 func (bs *SBackupStorage) BackupCount() (int, error) {
 	return DiskBackupManager.Query().Equals("backup_storage_id", bs.GetId()).CountWithError()
 }
@@ -134,8 +212,14 @@ func (bs *SBackupStorage) PostCreate(ctx context.Context, userCred mcclient.Toke
 }
 
 func (bs *SBackupStorage) getMoreDetails(ctx context.Context, out api.BackupStorageDetails) api.BackupStorageDetails {
+	if bs.AccessInfo == nil {
+		return out
+	}
 	out.NfsHost = bs.AccessInfo.NfsHost
 	out.NfsSharedDir = bs.AccessInfo.NfsSharedDir
+	out.ObjectBucketUrl = bs.AccessInfo.ObjectBucketUrl
+	out.ObjectAccessKey = bs.AccessInfo.ObjectAccessKey
+	out.ObjectSse = bs.AccessInfo.ObjectSse
 	return out
 }
 
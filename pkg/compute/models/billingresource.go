@@ -294,6 +294,7 @@ func CheckBillingResourceExpireAt(ctx context.Context, userCred mcclient.TokenCr
 		GuestManager,
 		DBInstanceManager,
 		ElasticcacheManager,
+		LoadbalancerCertificateManager,
 	}
 	for _, advanceDay := range advanceDays {
 		for _, manager := range billingResourceManagers {
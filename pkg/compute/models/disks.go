@@ -713,10 +713,14 @@ func (self *SDisk) getDiskAllocateFromBackupInput(ctx context.Context, backupId
 	if err != nil {
 		return nil, errors.Wrapf(err, "unable to get backupstorage of backup %s", backupId)
 	}
+	accessInfo, err := bs.GetAccessInfo()
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to get access info of backupstorage %s", bs.GetId())
+	}
 	return &api.DiskAllocateFromBackupInput{
 		BackupId:                backupId,
 		BackupStorageId:         bs.GetId(),
-		BackupStorageAccessInfo: jsonutils.Marshal(bs.AccessInfo).(*jsonutils.JSONDict),
+		BackupStorageAccessInfo: accessInfo,
 	}, nil
 }
 
@@ -88,10 +88,48 @@ type SGroup struct {
 	// the upper limit number of guests with this group in a host
 	Granularity     int               `nullable:"false" list:"user" get:"user" create:"optional" update:"user" default:"1"`
 	ForceDispersion tristate.TriState `list:"user" get:"user" create:"optional" update:"user" default:"true"`
+
+	// 组内实例在拓扑域间的分布约束
+	TopologySpreadConstraints *api.TopologySpreadConstraints `nullable:"true" list:"user" get:"user" create:"optional" update:"user"`
 	// 是否启用
 	// Enabled tristate.TriState `default:"true" create:"optional" list:"user" update:"user"`
 }
 
+func (sm *SGroupManager) ValidateCreateData(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	ownerId mcclient.IIdentityProvider,
+	query jsonutils.JSONObject,
+	input api.InstanceGroupCreateInput,
+) (api.InstanceGroupCreateInput, error) {
+	err := input.TopologySpreadConstraints.Validate()
+	if err != nil {
+		return input, err
+	}
+	input.VirtualResourceCreateInput, err = sm.SVirtualResourceBaseManager.ValidateCreateData(ctx, userCred, ownerId, query, input.VirtualResourceCreateInput)
+	if err != nil {
+		return input, errors.Wrap(err, "SVirtualResourceBaseManager.ValidateCreateData")
+	}
+	return input, nil
+}
+
+func (group *SGroup) ValidateUpdateData(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	input api.InstanceGroupUpdateInput,
+) (api.InstanceGroupUpdateInput, error) {
+	err := input.TopologySpreadConstraints.Validate()
+	if err != nil {
+		return input, err
+	}
+	input.VirtualResourceBaseUpdateInput, err = group.SVirtualResourceBase.ValidateUpdateData(ctx, userCred, query, input.VirtualResourceBaseUpdateInput)
+	if err != nil {
+		return input, errors.Wrap(err, "SVirtualResourceBase.ValidateUpdateData")
+	}
+	return input, nil
+}
+
 // 主机组列表
 func (sm *SGroupManager) ListItemFilter(
 	ctx context.Context,
@@ -212,6 +250,50 @@ func (group *SGroup) GetGuestCount() int {
 	return count
 }
 
+func (group *SGroup) GetTopologySpreadConstraints() api.TopologySpreadConstraints {
+	if group.TopologySpreadConstraints == nil {
+		return nil
+	}
+	return *group.TopologySpreadConstraints
+}
+
+// GetGuestCountByStorageType returns the number of the guests in the groups,
+// grouped by the storage type of their system disks.
+func (sm *SGroupManager) GetGuestCountByStorageType(groupIds []string) (map[string]int, error) {
+	ret := make(map[string]int)
+	if len(groupIds) == 0 {
+		return ret, nil
+	}
+	groupguests := GroupguestManager.Query().SubQuery()
+	guestdisks := GuestdiskManager.Query().SubQuery()
+	disks := DiskManager.Query().SubQuery()
+	storages := StorageManager.Query().SubQuery()
+
+	q := groupguests.Query(
+		storages.Field("storage_type"),
+		sqlchemy.COUNT("guest_count", groupguests.Field("guest_id")),
+	)
+	q = q.Join(guestdisks, sqlchemy.Equals(guestdisks.Field("guest_id"), groupguests.Field("guest_id")))
+	q = q.Join(disks, sqlchemy.Equals(disks.Field("id"), guestdisks.Field("disk_id")))
+	q = q.Join(storages, sqlchemy.Equals(storages.Field("id"), disks.Field("storage_id")))
+	q = q.Filter(sqlchemy.In(groupguests.Field("group_id"), groupIds))
+	q = q.Filter(sqlchemy.Equals(guestdisks.Field("index"), 0))
+	q = q.GroupBy(storages.Field("storage_type"))
+
+	results := []struct {
+		StorageType string
+		GuestCount  int
+	}{}
+	err := q.All(&results)
+	if err != nil {
+		return nil, errors.Wrap(err, "query guest count by storage type")
+	}
+	for _, r := range results {
+		ret[r.StorageType] = r.GuestCount
+	}
+	return ret, nil
+}
+
 func (group *SGroup) GetGuests() []SGuest {
 	ggm := GroupguestManager.Query().SubQuery()
 	q := GuestManager.Query()
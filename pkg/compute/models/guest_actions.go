@@ -586,6 +586,9 @@ func (self *SGuest) PerformClone(ctx context.Context, userCred mcclient.TokenCre
 	createInput := self.ToCreateInput(ctx, userCred)
 	createInput.Name = cloneInput.Name
 	createInput.AutoStart = cloneInput.AutoStart
+	if self.HasNvram() {
+		createInput.NvramSourceGuestId = self.Id
+	}
 
 	createInput.EipBw = cloneInput.EipBw
 	createInput.Eip = cloneInput.Eip
@@ -2529,7 +2532,7 @@ func (self *SGuest) PerformChangeConfig(ctx context.Context, userCred mcclient.T
 				addMem = sku.MemorySizeMB - self.VmemSize
 			}
 		}
-	} else {
+	} else if input.VcpuCount > 0 || len(input.VmemSize) > 0 || (input.SecureBoot == nil && input.Vtpm == nil) {
 		if input.VcpuCount != self.VcpuCount {
 			cpuChanged = true
 			addCpu = input.VcpuCount - self.VcpuCount
@@ -2556,6 +2559,29 @@ func (self *SGuest) PerformChangeConfig(ctx context.Context, userCred mcclient.T
 		return nil, httperrors.NewInvalidStatusError("cannot change CPU/Memory spec in status %s", self.Status)
 	}
 
+	if (input.SecureBoot != nil && *input.SecureBoot != self.SecureBoot) || (input.Vtpm != nil && *input.Vtpm != self.Vtpm) {
+		if self.Hypervisor != api.HYPERVISOR_KVM {
+			return nil, httperrors.NewInputParameterError("secure boot and vtpm are only supported by %s", api.HYPERVISOR_KVM)
+		}
+		if self.Status != api.VM_READY {
+			return nil, httperrors.NewInvalidStatusError("cannot change secure boot or vtpm in status %s", self.Status)
+		}
+		if input.SecureBoot != nil && *input.SecureBoot != self.SecureBoot {
+			if *input.SecureBoot {
+				if self.getBios() != "UEFI" {
+					return nil, httperrors.NewInputParameterError("secure boot requires UEFI boot mode")
+				}
+				if self.OsArch != apis.OS_ARCH_AARCH64 && self.getMachine() != api.VM_MACHINE_TYPE_Q35 {
+					return nil, httperrors.NewInputParameterError("secure boot requires %s machine type", api.VM_MACHINE_TYPE_Q35)
+				}
+			}
+			confs.Add(jsonutils.NewBool(*input.SecureBoot), "secure_boot")
+		}
+		if input.Vtpm != nil && *input.Vtpm != self.Vtpm {
+			confs.Add(jsonutils.NewBool(*input.Vtpm), "vtpm")
+		}
+	}
+
 	if addCpu < 0 {
 		addCpu = 0
 	}
@@ -4351,6 +4377,9 @@ func (self *SGuest) PerformSyncFixNics(ctx context.Context,
 }
 
 func (guest *SGuest) PerformChangeOwner(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input apis.PerformChangeProjectOwnerInput) (jsonutils.JSONObject, error) {
+	if err := guest.validateSharedfsChangeOwner(); err != nil {
+		return nil, err
+	}
 	disks, err := guest.GetDisks()
 	if err != nil {
 		return nil, errors.Wrapf(err, "GetDisks")
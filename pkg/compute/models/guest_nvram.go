@@ -0,0 +1,53 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"fmt"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+
+	host_api "yunion.io/x/onecloud/pkg/apis/host"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/httputils"
+)
+
+// HasNvram tells whether the guest keeps UEFI variables or vTPM state on host
+func (self *SGuest) HasNvram() bool {
+	return self.SecureBoot || self.Vtpm
+}
+
+// ExportNvram fetches the UEFI variable store and vTPM state of the guest from
+// its host, the error of host request is returned as is so that callers can
+// check its http code
+func (self *SGuest) ExportNvram(ctx context.Context, userCred mcclient.TokenCredential) (map[string]string, error) {
+	host, err := self.GetHost()
+	if err != nil {
+		return nil, errors.Wrapf(err, "get host of guest %s", self.Id)
+	}
+	url := fmt.Sprintf("%s/servers/%s/nvram-export", host.ManagerUri, self.Id)
+	header := mcclient.GetTokenHeaders(userCred)
+	_, respBody, err := httputils.JSONRequest(httputils.GetDefaultClient(), ctx, "POST", url, header, jsonutils.NewDict(), false)
+	if err != nil {
+		return nil, err
+	}
+	hostresp := &host_api.GuestNvramExportResponse{}
+	if err := respBody.Unmarshal(hostresp); err != nil {
+		return nil, errors.Wrap(err, "unmarshal host response")
+	}
+	return hostresp.Nvram, nil
+}
@@ -141,6 +141,12 @@ type SGuest struct {
 	Vdi     string `width:"36" charset:"ascii" nullable:"true" list:"user" update:"user" create:"optional"`
 	Machine string `width:"36" charset:"ascii" nullable:"true" list:"user" update:"user" create:"optional"`
 	Bios    string `width:"36" charset:"ascii" nullable:"true" list:"user" update:"user" create:"optional"`
+
+	// 是否启用安全启动
+	SecureBoot bool `nullable:"false" default:"false" list:"user" create:"optional"`
+	// 是否启用虚拟TPM设备
+	Vtpm bool `nullable:"false" default:"false" list:"user" create:"optional"`
+
 	// 操作系统类型
 	OsType string `width:"36" charset:"ascii" nullable:"true" list:"user" create:"optional"`
 
@@ -1264,6 +1270,10 @@ func (manager *SGuestManager) validateCreateData(
 		input.InstanceGroupIds = newGroupIds
 	}
 
+	if err := input.TopologySpreadConstraints.Validate(); err != nil {
+		return nil, err
+	}
+
 	// check that all image of disk is the part of guest imgae, if use guest image to create guest
 	err = manager.checkGuestImage(ctx, input)
 	if err != nil {
@@ -1325,6 +1335,14 @@ func (manager *SGuestManager) validateCreateData(
 			imgSupportUEFI = &support
 		}
 
+		if input.SecureBoot {
+			if len(input.Bios) == 0 {
+				input.Bios = "UEFI"
+			} else if input.Bios != "UEFI" {
+				return nil, httperrors.NewInputParameterError("secure boot requires UEFI boot mode")
+			}
+		}
+
 		switch {
 		case imgSupportUEFI == nil:
 		case *imgSupportUEFI:
@@ -1393,6 +1411,22 @@ func (manager *SGuestManager) validateCreateData(
 	}
 
 	hypervisor = input.Hypervisor
+	if input.SecureBoot || input.Vtpm {
+		if hypervisor != api.HYPERVISOR_KVM {
+			return nil, httperrors.NewInputParameterError("secure boot and vtpm are only supported by %s", api.HYPERVISOR_KVM)
+		}
+		if input.SecureBoot && input.OsArch != apis.OS_ARCH_AARCH64 {
+			// secure boot firmware on x86 depends on SMM which requires q35
+			if len(input.Machine) == 0 {
+				input.Machine = api.VM_MACHINE_TYPE_Q35
+			} else if input.Machine != api.VM_MACHINE_TYPE_Q35 {
+				return nil, httperrors.NewInputParameterError("secure boot requires %s machine type", api.VM_MACHINE_TYPE_Q35)
+			}
+		}
+	}
+	// nvram source is only set internally on guest clone
+	input.NvramSourceGuestId = ""
+
 	if hypervisor != api.HYPERVISOR_CONTAINER {
 		// support sku here
 		var sku *SServerSku
@@ -4230,6 +4264,8 @@ func (self *SGuest) GetJsonDescAtHypervisor(ctx context.Context, host *SHost) *a
 		SrcIpCheck:  self.SrcIpCheck.Bool(),
 		SrcMacCheck: self.SrcMacCheck.Bool(),
 		HostId:      host.Id,
+		SecureBoot:  self.SecureBoot,
+		Vtpm:        self.Vtpm,
 
 		EncryptKeyId: self.EncryptKeyId,
 	}
@@ -4274,6 +4310,9 @@ func (self *SGuest) GetJsonDescAtHypervisor(ctx context.Context, host *SHost) *a
 		desc.Cdrom = cdrom.getJsonDesc()
 	}
 
+	// shared filesystems
+	desc.Sharedfs = self.getSharedfsJsonDesc()
+
 	// tenant
 	tc, _ := self.GetTenantCache(ctx)
 	if tc != nil {
@@ -5413,6 +5452,8 @@ func (self *SGuest) ToCreateInput(ctx context.Context, userCred mcclient.TokenCr
 	userInput.Vga = genInput.Vga
 	userInput.Vdi = genInput.Vdi
 	userInput.Bios = genInput.Bios
+	userInput.SecureBoot = genInput.SecureBoot
+	userInput.Vtpm = genInput.Vtpm
 	userInput.Cdrom = genInput.Cdrom
 	userInput.Description = genInput.Description
 	userInput.BootOrder = genInput.BootOrder
@@ -5465,6 +5506,8 @@ func (self *SGuest) toCreateInput() *api.ServerCreateInput {
 	r.Vga = self.Vga
 	r.Vdi = self.Vdi
 	r.Bios = self.Bios
+	r.SecureBoot = self.SecureBoot
+	r.Vtpm = self.Vtpm
 	r.Description = self.Description
 	r.BootOrder = self.BootOrder
 	r.DisableDelete = new(bool)
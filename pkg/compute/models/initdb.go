@@ -43,6 +43,7 @@ func InitDB() error {
 		SecurityGroupCacheManager,
 		NetworkManager,
 		NetworkAddressManager,
+		SharedFilesystemManager,
 		GuestManager,
 		HostManager,
 		LoadbalancerCertificateManager,
@@ -70,6 +70,8 @@ type SInstanceBackup struct {
 	InstanceType string `width:"64" charset:"utf8" nullable:"true" list:"user" create:"optional"`
 	// 主机备份容量和
 	SizeMb int `nullable:"false" list:"user"`
+	// UEFI变量及vTPM状态, 恢复时导入新主机
+	Nvram jsonutils.JSONObject `length:"medium" charset:"ascii" nullable:"true"`
 }
 
 type SInstanceBackupManager struct {
@@ -312,6 +314,30 @@ func (manager *SInstanceBackupManager) CreateInstanceBackup(ctx context.Context,
 	return instanceBackup, nil
 }
 
+// SaveNvram keeps the UEFI variable store and vTPM state of guest with the backup
+func (self *SInstanceBackup) SaveNvram(ctx context.Context, userCred mcclient.TokenCredential, guest *SGuest) error {
+	nvram, err := guest.ExportNvram(ctx, userCred)
+	if err != nil {
+		return errors.Wrapf(err, "export nvram of guest %s", guest.Id)
+	}
+	_, err = db.Update(self, func() error {
+		self.Nvram = jsonutils.Marshal(nvram)
+		return nil
+	})
+	return err
+}
+
+func (self *SInstanceBackup) GetNvram() (map[string]string, error) {
+	if self.Nvram == nil {
+		return nil, nil
+	}
+	nvram := map[string]string{}
+	if err := self.Nvram.Unmarshal(&nvram); err != nil {
+		return nil, errors.Wrapf(err, "unmarshal nvram of instance backup %s", self.Id)
+	}
+	return nvram, nil
+}
+
 func (self *SInstanceBackup) ToInstanceCreateInput(sourceInput *api.ServerCreateInput) (*api.ServerCreateInput, error) {
 
 	createInput := new(api.ServerCreateInput)
@@ -374,6 +400,12 @@ func (self *SInstanceBackup) ToInstanceCreateInput(sourceInput *api.ServerCreate
 	if sourceInput.Bios == "" {
 		sourceInput.Bios = createInput.Bios
 	}
+	if sourceInput.Machine == "" {
+		sourceInput.Machine = createInput.Machine
+	}
+	// the nvram saved with backup is only meaningful with the same firmware and tpm
+	sourceInput.SecureBoot = createInput.SecureBoot
+	sourceInput.Vtpm = createInput.Vtpm
 	if sourceInput.BootOrder == "" {
 		sourceInput.BootOrder = createInput.BootOrder
 	}
@@ -518,6 +550,7 @@ func (self *SInstanceBackup) PackMetadata() (*api.InstanceBackupPackMetadata, er
 		OsType:         self.OsType,
 		InstanceType:   self.InstanceType,
 		SizeMb:         self.SizeMb,
+		Nvram:          self.Nvram,
 	}
 	dbs, err := self.GetBackups()
 	if err != nil {
@@ -579,6 +612,7 @@ func (ib *SInstanceBackup) FillFromPackMetadata(ctx context.Context, userCred mc
 		ib.OsType = metadata.OsType
 		ib.InstanceType = metadata.InstanceType
 		ib.SizeMb = metadata.SizeMb
+		ib.Nvram = metadata.Nvram
 		return nil
 	})
 	if err != nil {
@@ -392,6 +392,8 @@ func (lbcert *SCachedLoadbalancerCertificate) syncRemoveCloudLoadbalancerCertifi
 func (man *SCachedLoadbalancerCertificateManager) getLoadbalancerCertificateByRegion(provider *SCloudprovider, regionId string, localCertificateId string) (SCachedLoadbalancerCertificate, error) {
 	certificates := []SCachedLoadbalancerCertificate{}
 	q := man.Query().Equals("manager_id", provider.Id).Equals("certificate_id", localCertificateId).IsFalse("pending_deleted")
+	// obsolete caches hold content replaced by certificate renewal
+	q = q.NotEquals("status", api.LB_CERT_STATUS_OBSOLETE)
 	regionDriver, err := provider.GetRegionDriver()
 	if err != nil {
 		return SCachedLoadbalancerCertificate{}, errors.Wrap(err, "GetRegionDriver")
@@ -22,10 +22,13 @@ import (
 	"encoding/hex"
 	"encoding/pem"
 	"fmt"
+	"reflect"
+	"time"
 
 	"yunion.io/x/jsonutils"
 	"yunion.io/x/log"
 	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/gotypes"
 	"yunion.io/x/sqlchemy"
 
 	"yunion.io/x/onecloud/pkg/apis"
@@ -50,6 +53,9 @@ type SLoadbalancerCertificateManager struct {
 var LoadbalancerCertificateManager *SLoadbalancerCertificateManager
 
 func init() {
+	gotypes.RegisterSerializable(reflect.TypeOf(&SLoadbalancerAcmeHttpChallenges{}), func() gotypes.ISerializable {
+		return &SLoadbalancerAcmeHttpChallenges{}
+	})
 	LoadbalancerCertificateManager = &SLoadbalancerCertificateManager{
 		SSharableVirtualResourceBaseManager: db.NewSharableVirtualResourceBaseManager(
 			SLoadbalancerCertificate{},
@@ -63,7 +69,6 @@ func init() {
 
 // TODO
 //
-//  - notify users of cert expiration
 //  - ca info: self-signed, public ca
 type SLoadbalancerCertificate struct {
 	db.SSharableVirtualResourceBase
@@ -73,6 +78,32 @@ type SLoadbalancerCertificate struct {
 	// SCloudregionResourceBase
 
 	db.SCertificateResourceBase
+
+	// 证书来源
+	Source string `width:"16" charset:"ascii" nullable:"false" default:"upload" list:"user" create:"optional"`
+
+	// ACME服务目录地址
+	AcmeDirectoryUrl string `width:"256" charset:"ascii" nullable:"true" list:"user" create:"optional"`
+	// ACME账号联系邮箱
+	AcmeEmail string `width:"128" charset:"ascii" nullable:"true" list:"user" create:"optional"`
+	// 域名所有权验证方式
+	AcmeChallengeType string `width:"16" charset:"ascii" nullable:"true" list:"user" create:"optional"`
+	// 证书包含的域名，以逗号分隔
+	AcmeDomains string `width:"1024" charset:"ascii" nullable:"true" list:"user" create:"optional"`
+	// dns-01验证方式所用的DNS解析区域ID
+	AcmeDnsZoneId string `width:"36" charset:"ascii" nullable:"true" list:"user" create:"optional"`
+	// 到期前多少天自动续期
+	AcmeRenewBeforeDays int `nullable:"false" default:"0" list:"user" create:"optional"`
+	// 连续签发失败次数
+	AcmeIssueFailures int `nullable:"false" default:"0" list:"user"`
+	// 签发失败后下次自动重试时间
+	AcmeNextIssueAt time.Time `nullable:"true" list:"user"`
+
+	AcmeAccountKey string `charset:"ascii" nullable:"true"`
+	AcmeAccountUrl string `width:"256" charset:"ascii" nullable:"true"`
+
+	// 等待lbagent响应的http-01验证
+	AcmeHttpChallenges *SLoadbalancerAcmeHttpChallenges `nullable:"true" list:"admin"`
 }
 
 func (lbcert *SLoadbalancerCertificate) GetCachedCerts() ([]SCachedLoadbalancerCertificate, error) {
@@ -115,6 +146,12 @@ func (lbcert *SLoadbalancerCertificate) ValidateUpdateData(ctx context.Context,
 
 func (lbcert *SLoadbalancerCertificate) PostCreate(ctx context.Context, userCred mcclient.TokenCredential, ownerProjId mcclient.IIdentityProvider, query jsonutils.JSONObject, data jsonutils.JSONObject) {
 	lbcert.SSharableVirtualResourceBase.PostCreate(ctx, userCred, ownerProjId, query, data)
+	if lbcert.IsAcme() {
+		if err := lbcert.StartAcmeIssueTask(ctx, userCred, ""); err != nil {
+			lbcert.SetStatus(userCred, api.LB_CERT_STATUS_ISSUE_FAILED, err.Error())
+		}
+		return
+	}
 	lbcert.SetStatus(userCred, api.LB_STATUS_ENABLED, "")
 }
 
@@ -275,6 +312,9 @@ func (man *SLoadbalancerCertificateManager) ListItemFilter(
 	if len(query.SubjectAlternativeNames) > 0 {
 		q = q.In("subject_alternative_names", query.SubjectAlternativeNames)
 	}
+	if len(query.Source) > 0 {
+		q = q.In("source", query.Source)
+	}
 
 	return q, nil
 }
@@ -307,11 +347,22 @@ func (man *SLoadbalancerCertificateManager) QueryDistinctExtraField(q *sqlchemy.
 }
 
 func (man *SLoadbalancerCertificateManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, data *jsonutils.JSONDict) (*jsonutils.JSONDict, error) {
-	v := validators.NewCertKeyValidator("certificate", "private_key")
-	if err := v.Validate(data); err != nil {
-		return nil, err
+	source, _ := data.GetString("source")
+	switch source {
+	case api.LB_CERT_SOURCE_ACME:
+		if err := man.validateAcmeCreateData(ctx, userCred, data); err != nil {
+			return nil, err
+		}
+	case "", api.LB_CERT_SOURCE_UPLOAD:
+		v := validators.NewCertKeyValidator("certificate", "private_key")
+		if err := v.Validate(data); err != nil {
+			return nil, err
+		}
+		data = v.UpdateCertKeyInfo(ctx, data)
+		data.Set("source", jsonutils.NewString(api.LB_CERT_SOURCE_UPLOAD))
+	default:
+		return nil, httperrors.NewInputParameterError("invalid source %q, must be one of %s", source, api.LB_CERT_SOURCES)
 	}
-	data = v.UpdateCertKeyInfo(ctx, data)
 
 	input := apis.SharableVirtualResourceCreateInput{}
 	err := data.Unmarshal(&input)
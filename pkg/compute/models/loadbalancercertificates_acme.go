@@ -0,0 +1,658 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/tristate"
+	"yunion.io/x/pkg/util/regutils"
+	"yunion.io/x/pkg/utils"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/lockman"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/cloudcommon/validators"
+	"yunion.io/x/onecloud/pkg/compute/options"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/acmeutils"
+	"yunion.io/x/onecloud/pkg/util/httputils"
+)
+
+type SLoadbalancerAcmeHttpChallenge struct {
+	Token            string
+	KeyAuthorization string
+}
+
+type SLoadbalancerAcmeHttpChallenges []*SLoadbalancerAcmeHttpChallenge
+
+func (challenges *SLoadbalancerAcmeHttpChallenges) String() string {
+	return jsonutils.Marshal(challenges).String()
+}
+
+func (challenges *SLoadbalancerAcmeHttpChallenges) IsZero() bool {
+	return len([]*SLoadbalancerAcmeHttpChallenge(*challenges)) == 0
+}
+
+func (lbcert *SLoadbalancerCertificate) IsAcme() bool {
+	return lbcert.Source == api.LB_CERT_SOURCE_ACME
+}
+
+func (lbcert *SLoadbalancerCertificate) GetAcmeDomains() []string {
+	if len(lbcert.AcmeDomains) == 0 {
+		return nil
+	}
+	return strings.Split(lbcert.AcmeDomains, ",")
+}
+
+func (man *SLoadbalancerCertificateManager) validateAcmeCreateData(ctx context.Context, userCred mcclient.TokenCredential, data *jsonutils.JSONDict) error {
+	input := api.LoadbalancerCertificateAcmeInput{}
+	if err := data.Unmarshal(&input); err != nil {
+		return httperrors.NewInputParameterError("unmarshal acme input: %v", err)
+	}
+	if data.Contains("certificate") || data.Contains("private_key") {
+		return httperrors.NewInputParameterError("certificate and private_key are issued by acme")
+	}
+
+	if input.AcmeChallengeType == "" {
+		input.AcmeChallengeType = api.LB_CERT_ACME_CHALLENGE_HTTP01
+	}
+	if !api.LB_CERT_ACME_CHALLENGE_TYPES.Has(input.AcmeChallengeType) {
+		return httperrors.NewInputParameterError("invalid acme_challenge_type %q, must be one of %s", input.AcmeChallengeType, api.LB_CERT_ACME_CHALLENGE_TYPES)
+	}
+
+	domains := []string{}
+	for _, domain := range input.AcmeDomains {
+		domain = strings.ToLower(strings.TrimSpace(domain))
+		if domain == "" || utils.IsInStringArray(domain, domains) {
+			continue
+		}
+		name := domain
+		if strings.HasPrefix(domain, "*.") {
+			if input.AcmeChallengeType != api.LB_CERT_ACME_CHALLENGE_DNS01 {
+				return httperrors.NewInputParameterError("wildcard domain %s requires %s challenge", domain, api.LB_CERT_ACME_CHALLENGE_DNS01)
+			}
+			name = domain[2:]
+		}
+		if !regutils.MatchDomainName(name) {
+			return httperrors.NewInputParameterError("invalid domain %s", domain)
+		}
+		domains = append(domains, domain)
+	}
+	if len(domains) == 0 {
+		return httperrors.NewMissingParameterError("acme_domains")
+	}
+	if s := strings.Join(domains, ","); len(s) > 1024 {
+		return httperrors.NewInputParameterError("too many acme_domains")
+	}
+
+	if input.AcmeChallengeType == api.LB_CERT_ACME_CHALLENGE_DNS01 {
+		if input.AcmeDnsZone == "" {
+			return httperrors.NewMissingParameterError("acme_dns_zone")
+		}
+		zoneObj, err := DnsZoneManager.FetchByIdOrName(userCred, input.AcmeDnsZone)
+		if err != nil {
+			if errors.Cause(err) == errors.ErrNotFound || strings.Contains(err.Error(), "no rows") {
+				return httperrors.NewResourceNotFoundError2(DnsZoneManager.Keyword(), input.AcmeDnsZone)
+			}
+			return httperrors.NewGeneralError(err)
+		}
+		zone := zoneObj.(*SDnsZone)
+		for _, domain := range domains {
+			if _, err := acmeDnsRecordName(zone.Name, domain); err != nil {
+				return httperrors.NewInputParameterError("%v", err)
+			}
+		}
+		data.Set("acme_dns_zone_id", jsonutils.NewString(zone.Id))
+	}
+
+	if input.AcmeDirectoryUrl == "" {
+		input.AcmeDirectoryUrl = options.Options.AcmeDirectoryUrl
+	}
+	if !strings.HasPrefix(input.AcmeDirectoryUrl, "https://") && !strings.HasPrefix(input.AcmeDirectoryUrl, "http://") {
+		return httperrors.NewInputParameterError("invalid acme_directory_url %s", input.AcmeDirectoryUrl)
+	}
+	if input.AcmeEmail != "" && !regutils.MatchEmail(input.AcmeEmail) {
+		return httperrors.NewInputParameterError("invalid acme_email %s", input.AcmeEmail)
+	}
+	if input.AcmeRenewBeforeDays <= 0 {
+		input.AcmeRenewBeforeDays = options.Options.AcmeRenewBeforeDays
+	}
+
+	data.Set("source", jsonutils.NewString(api.LB_CERT_SOURCE_ACME))
+	data.Set("acme_directory_url", jsonutils.NewString(input.AcmeDirectoryUrl))
+	data.Set("acme_email", jsonutils.NewString(input.AcmeEmail))
+	data.Set("acme_challenge_type", jsonutils.NewString(input.AcmeChallengeType))
+	data.Set("acme_domains", jsonutils.NewString(strings.Join(domains, ",")))
+	data.Set("acme_renew_before_days", jsonutils.NewInt(int64(input.AcmeRenewBeforeDays)))
+	data.Set("common_name", jsonutils.NewString(domains[0]))
+	data.Set("subject_alternative_names", jsonutils.NewString(strings.Join(domains, " ")))
+	data.Remove("acme_dns_zone")
+	return nil
+}
+
+// acmeDnsRecordName returns name of the dns-01 TXT record of domain relative
+// to the zone
+func acmeDnsRecordName(zoneName, domain string) (string, error) {
+	zoneName = strings.TrimSuffix(strings.ToLower(zoneName), ".")
+	fqdn := acmeutils.DNS01RecordName(domain)
+	if !strings.HasSuffix(fqdn, "."+zoneName) {
+		return "", errors.Wrapf(errors.ErrInvalidStatus, "domain %s is not in dns zone %s", domain, zoneName)
+	}
+	return strings.TrimSuffix(fqdn, "."+zoneName), nil
+}
+
+func (lbcert *SLoadbalancerCertificate) PerformRenew(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.LoadbalancerCertificateRenewInput) (jsonutils.JSONObject, error) {
+	if !lbcert.IsAcme() {
+		return nil, httperrors.NewUnsupportOperationError("only certificates from %s can be renewed", api.LB_CERT_SOURCE_ACME)
+	}
+	if lbcert.Status == api.LB_CERT_STATUS_ISSUING && !lbcert.isAcmeIssueStale(time.Now()) {
+		return nil, httperrors.NewInvalidStatusError("certificate is %s", lbcert.Status)
+	}
+	return nil, lbcert.StartAcmeIssueTask(ctx, userCred, "")
+}
+
+func (lbcert *SLoadbalancerCertificate) StartAcmeIssueTask(ctx context.Context, userCred mcclient.TokenCredential, parentTaskId string) error {
+	lbcert.SetStatus(userCred, api.LB_CERT_STATUS_ISSUING, "")
+	task, err := taskman.TaskManager.NewTask(ctx, "LoadbalancerCertificateAcmeIssueTask", lbcert, userCred, nil, parentTaskId, "", nil)
+	if err != nil {
+		return errors.Wrap(err, "NewTask")
+	}
+	task.ScheduleRun(nil)
+	return nil
+}
+
+func (lbcert *SLoadbalancerCertificate) getAcmeClient(ctx context.Context) (*acmeutils.SAcmeClient, error) {
+	if lbcert.AcmeAccountKey == "" {
+		key, err := acmeutils.GenerateKey()
+		if err != nil {
+			return nil, errors.Wrap(err, "GenerateKey")
+		}
+		keyPem, err := acmeutils.EncodeKey(key)
+		if err != nil {
+			return nil, errors.Wrap(err, "EncodeKey")
+		}
+		_, err = db.Update(lbcert, func() error {
+			lbcert.AcmeAccountKey = keyPem
+			lbcert.AcmeAccountUrl = ""
+			return nil
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "save account key")
+		}
+	}
+	key, err := acmeutils.DecodeKey(lbcert.AcmeAccountKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "DecodeKey")
+	}
+	cli := acmeutils.NewAcmeClient(lbcert.AcmeDirectoryUrl, key, options.Options.AcmeInsecureSkipVerify, time.Minute)
+	if lbcert.AcmeAccountUrl != "" {
+		cli.SetAccountUrl(lbcert.AcmeAccountUrl)
+		return cli, nil
+	}
+	accountUrl, err := cli.Register(ctx, lbcert.AcmeEmail)
+	if err != nil {
+		return nil, errors.Wrap(err, "Register")
+	}
+	_, err = db.Update(lbcert, func() error {
+		lbcert.AcmeAccountUrl = accountUrl
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "save account url")
+	}
+	return cli, nil
+}
+
+type sAcmePendingChallenge struct {
+	domain    string
+	challenge *acmeutils.SChallenge
+	authzUrl  string
+	// http-01 key authorization or dns-01 TXT value
+	value string
+}
+
+// AcmeIssue requests a new certificate from the ACME directory and stores it
+// along with the freshly generated private key
+func (lbcert *SLoadbalancerCertificate) AcmeIssue(ctx context.Context, userCred mcclient.TokenCredential) error {
+	cli, err := lbcert.getAcmeClient(ctx)
+	if err != nil {
+		return errors.Wrap(err, "getAcmeClient")
+	}
+	domains := lbcert.GetAcmeDomains()
+	order, err := cli.NewOrder(ctx, domains)
+	if err != nil {
+		return errors.Wrap(err, "NewOrder")
+	}
+
+	pendings := []sAcmePendingChallenge{}
+	for _, authzUrl := range order.Authorizations {
+		authz, err := cli.GetAuthorization(ctx, authzUrl)
+		if err != nil {
+			return errors.Wrap(err, "GetAuthorization")
+		}
+		if authz.Status == acmeutils.STATUS_VALID {
+			continue
+		}
+		chal := authz.GetChallenge(lbcert.AcmeChallengeType)
+		if chal == nil {
+			return errors.Wrapf(errors.ErrNotSupported, "%s challenge not offered for %s", lbcert.AcmeChallengeType, authz.Identifier.Value)
+		}
+		pending := sAcmePendingChallenge{
+			domain:    authz.Identifier.Value,
+			challenge: chal,
+			authzUrl:  authzUrl,
+		}
+		if authz.Wildcard {
+			pending.domain = "*." + pending.domain
+		}
+		switch lbcert.AcmeChallengeType {
+		case api.LB_CERT_ACME_CHALLENGE_HTTP01:
+			pending.value = cli.KeyAuthorization(chal.Token)
+		case api.LB_CERT_ACME_CHALLENGE_DNS01:
+			pending.value = cli.DNS01Value(chal.Token)
+		}
+		pendings = append(pendings, pending)
+	}
+
+	if len(pendings) > 0 {
+		cleanup, err := lbcert.presentAcmeChallenges(ctx, userCred, pendings)
+		defer cleanup()
+		if err != nil {
+			return errors.Wrap(err, "presentAcmeChallenges")
+		}
+		lbcert.waitAcmeChallenges(ctx, pendings)
+		for i := range pendings {
+			if err := cli.AcceptChallenge(ctx, pendings[i].challenge); err != nil {
+				return errors.Wrapf(err, "AcceptChallenge for %s", pendings[i].domain)
+			}
+		}
+		for i := range pendings {
+			if _, err := cli.WaitAuthorization(ctx, pendings[i].authzUrl); err != nil {
+				return errors.Wrapf(err, "WaitAuthorization for %s", pendings[i].domain)
+			}
+		}
+	}
+
+	certKey, err := acmeutils.GenerateKey()
+	if err != nil {
+		return errors.Wrap(err, "GenerateKey")
+	}
+	csr, err := acmeutils.CreateCertificateRequest(certKey, domains)
+	if err != nil {
+		return errors.Wrap(err, "CreateCertificateRequest")
+	}
+	order, err = cli.FinalizeOrder(ctx, order, csr)
+	if err != nil {
+		return errors.Wrap(err, "FinalizeOrder")
+	}
+	certPem, err := cli.FetchCertificate(ctx, order.Certificate)
+	if err != nil {
+		return errors.Wrap(err, "FetchCertificate")
+	}
+	keyPem, err := acmeutils.EncodeKey(certKey)
+	if err != nil {
+		return errors.Wrap(err, "EncodeKey")
+	}
+	return lbcert.updateCertificateContent(ctx, userCred, certPem, keyPem)
+}
+
+// presentAcmeChallenges publishes http-01 key authorizations for lbagents or
+// dns-01 TXT records.  The returned cleanup func is always non-nil
+func (lbcert *SLoadbalancerCertificate) presentAcmeChallenges(ctx context.Context, userCred mcclient.TokenCredential, pendings []sAcmePendingChallenge) (func(), error) {
+	switch lbcert.AcmeChallengeType {
+	case api.LB_CERT_ACME_CHALLENGE_HTTP01:
+		challenges := SLoadbalancerAcmeHttpChallenges{}
+		for i := range pendings {
+			challenges = append(challenges, &SLoadbalancerAcmeHttpChallenge{
+				Token:            pendings[i].challenge.Token,
+				KeyAuthorization: pendings[i].value,
+			})
+		}
+		cleanup := func() {
+			_, err := db.Update(lbcert, func() error {
+				lbcert.AcmeHttpChallenges = nil
+				return nil
+			})
+			if err != nil {
+				log.Errorf("clean acme http challenges of %s: %v", lbcert.Name, err)
+			}
+		}
+		_, err := db.Update(lbcert, func() error {
+			lbcert.AcmeHttpChallenges = &challenges
+			return nil
+		})
+		return cleanup, err
+	case api.LB_CERT_ACME_CHALLENGE_DNS01:
+		records := []*SDnsRecordSet{}
+		zoneObj, err := DnsZoneManager.FetchById(lbcert.AcmeDnsZoneId)
+		if err != nil {
+			return func() {}, errors.Wrapf(err, "fetch dns zone %s", lbcert.AcmeDnsZoneId)
+		}
+		zone := zoneObj.(*SDnsZone)
+		cleanup := func() {
+			for _, record := range records {
+				if err := record.Delete(ctx, userCred); err != nil {
+					log.Errorf("delete acme dns record %s: %v", record.Name, err)
+				}
+			}
+			if len(records) > 0 {
+				zone.DoSyncRecords(ctx, userCred)
+			}
+		}
+		for i := range pendings {
+			name, err := acmeDnsRecordName(zone.Name, pendings[i].domain)
+			if err != nil {
+				return cleanup, err
+			}
+			record := &SDnsRecordSet{}
+			record.SetModelManager(DnsRecordSetManager, record)
+			record.DnsZoneId = zone.Id
+			record.Name = name
+			record.Description = fmt.Sprintf("acme challenge of loadbalancer certificate %s", lbcert.Name)
+			record.Status = api.DNS_RECORDSET_STATUS_AVAILABLE
+			record.Enabled = tristate.True
+			record.DnsType = "TXT"
+			record.DnsValue = pendings[i].value
+			record.TTL = 60
+			if err := DnsRecordSetManager.TableSpec().Insert(ctx, record); err != nil {
+				return cleanup, errors.Wrapf(err, "insert dns record %s", name)
+			}
+			records = append(records, record)
+		}
+		return cleanup, zone.DoSyncRecords(ctx, userCred)
+	}
+	return func() {}, errors.Wrapf(errors.ErrNotSupported, "challenge type %s", lbcert.AcmeChallengeType)
+}
+
+// waitAcmeChallenges checks that challenges are visible from here before
+// asking the ACME server to validate, as lbagents and dns providers take
+// time to pick them up.  Validation goes on after timeout anyway since the
+// ACME server may see a different network view
+func (lbcert *SLoadbalancerCertificate) waitAcmeChallenges(ctx context.Context, pendings []sAcmePendingChallenge) {
+	deadline := time.Now().Add(time.Duration(options.Options.AcmeChallengeWaitSeconds) * time.Second)
+	httpcli := httputils.GetClient(true, 5*time.Second)
+	ready := make([]bool, len(pendings))
+	for {
+		allReady := true
+		for i := range pendings {
+			if ready[i] {
+				continue
+			}
+			switch lbcert.AcmeChallengeType {
+			case api.LB_CERT_ACME_CHALLENGE_HTTP01:
+				ready[i] = checkAcmeHttpChallenge(ctx, httpcli, pendings[i].domain, pendings[i].challenge.Token, pendings[i].value)
+			case api.LB_CERT_ACME_CHALLENGE_DNS01:
+				ready[i] = checkAcmeDnsChallenge(pendings[i].domain, pendings[i].value)
+			}
+			if !ready[i] {
+				allReady = false
+			}
+		}
+		if allReady {
+			return
+		}
+		if time.Now().After(deadline) {
+			log.Warningf("acme challenges of %s not visible after %ds, validate anyway", lbcert.Name, options.Options.AcmeChallengeWaitSeconds)
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
+
+func checkAcmeHttpChallenge(ctx context.Context, cli *http.Client, domain, token, keyAuth string) bool {
+	url := fmt.Sprintf("http://%s%s%s", domain, acmeutils.HTTP01_PATH_PREFIX, token)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return false
+	}
+	resp, err := cli.Do(req.WithContext(ctx))
+	if err != nil {
+		log.Debugf("check acme http challenge %s: %v", url, err)
+		return false
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	return resp.StatusCode == http.StatusOK && strings.TrimSpace(string(body)) == keyAuth
+}
+
+func checkAcmeDnsChallenge(domain, value string) bool {
+	txts, err := net.LookupTXT(acmeutils.DNS01RecordName(domain))
+	if err != nil {
+		log.Debugf("check acme dns challenge %s: %v", domain, err)
+		return false
+	}
+	return utils.IsInStringArray(value, txts)
+}
+
+func (lbcert *SLoadbalancerCertificate) updateCertificateContent(ctx context.Context, userCred mcclient.TokenCredential, certPem, keyPem string) error {
+	data := jsonutils.NewDict()
+	data.Set("certificate", jsonutils.NewString(certPem))
+	data.Set("private_key", jsonutils.NewString(keyPem))
+	v := validators.NewCertKeyValidator("certificate", "private_key")
+	if err := v.Validate(data); err != nil {
+		return errors.Wrap(err, "validate issued certificate")
+	}
+	data = v.UpdateCertKeyInfo(ctx, data)
+	certBase := db.SCertificateResourceBase{}
+	if err := data.Unmarshal(&certBase); err != nil {
+		return errors.Wrap(err, "Unmarshal")
+	}
+	diff, err := db.Update(lbcert, func() error {
+		lbcert.SCertificateResourceBase = certBase
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "db.Update")
+	}
+	db.OpsLog.LogEvent(lbcert, db.ACT_UPDATE, diff, userCred)
+	return nil
+}
+
+// RolloutCachedCertificates makes cloud listeners pick up renewed content.
+// Existing caches are marked obsolete so that listener sync uploads the new
+// content as a fresh cache.  Obsolete caches are removed by
+// CleanObsoleteCachedCertificates once no listener refers to them.  Listeners
+// of lbagents need nothing as they sync certificates by themselves
+func (lbcert *SLoadbalancerCertificate) RolloutCachedCertificates(ctx context.Context, userCred mcclient.TokenCredential) error {
+	caches, err := lbcert.GetCachedCerts()
+	if err != nil {
+		return errors.Wrap(err, "GetCachedCerts")
+	}
+	for i := range caches {
+		if caches[i].Status == api.LB_CERT_STATUS_OBSOLETE {
+			continue
+		}
+		caches[i].SetStatus(userCred, api.LB_CERT_STATUS_OBSOLETE, "certificate renewed")
+	}
+
+	listeners := []SLoadbalancerListener{}
+	q := LoadbalancerListenerManager.Query().Equals("certificate_id", lbcert.Id).IsFalse("pending_deleted")
+	if err := db.FetchModelObjects(LoadbalancerListenerManager, q, &listeners); err != nil {
+		return errors.Wrap(err, "fetch listeners")
+	}
+	params := jsonutils.NewDict()
+	params.Set("certificate_id", jsonutils.NewString(lbcert.Id))
+	for i := range listeners {
+		lblis := &listeners[i]
+		account := lblis.GetCloudaccount()
+		if account == nil || account.IsOnPremise {
+			continue
+		}
+		if err := lblis.StartLoadBalancerListenerSyncTask(ctx, userCred, params, ""); err != nil {
+			log.Errorf("sync listener %s with renewed certificate %s: %v", lblis.Name, lbcert.Name, err)
+		}
+	}
+	return nil
+}
+
+const (
+	acmeIssueRetryInterval    = time.Hour
+	acmeIssueRetryMaxInterval = 24 * time.Hour
+
+	// acmeIssueTimeout bounds an issue task on top of the challenge wait.
+	// Certificates issuing longer than that are left behind by crashed
+	// region or lost tasks and can be issued again
+	acmeIssueTimeout = time.Hour
+)
+
+func (lbcert *SLoadbalancerCertificate) acmeRenewBeforeDays() int {
+	if lbcert.AcmeRenewBeforeDays > 0 {
+		return lbcert.AcmeRenewBeforeDays
+	}
+	return options.Options.AcmeRenewBeforeDays
+}
+
+// isAcmeIssueStale tells whether the certificate has been issuing for too
+// long to have a live issue task
+func (lbcert *SLoadbalancerCertificate) isAcmeIssueStale(now time.Time) bool {
+	timeout := acmeIssueTimeout + time.Duration(options.Options.AcmeChallengeWaitSeconds)*time.Second
+	return lbcert.Status == api.LB_CERT_STATUS_ISSUING && now.Sub(lbcert.UpdatedAt) > timeout
+}
+
+// needAcmeRenew tells whether the certificate is in its renew window.  Those
+// failed to issue are retried with exponential backoff, those stuck in
+// issuing are retried right away
+func (lbcert *SLoadbalancerCertificate) needAcmeRenew(now time.Time) bool {
+	if lbcert.Status == api.LB_CERT_STATUS_ISSUING {
+		return lbcert.isAcmeIssueStale(now)
+	}
+	if lbcert.Status == api.LB_CERT_STATUS_ISSUE_FAILED {
+		return !now.Before(lbcert.AcmeNextIssueAt)
+	}
+	return !now.AddDate(0, 0, lbcert.acmeRenewBeforeDays()).Before(lbcert.NotAfter)
+}
+
+// isAcmeRenewOverdue tells whether certificate expiring in advanceDay days
+// should have been renewed already, i.e. its renew window has begun
+func (lbcert *SLoadbalancerCertificate) isAcmeRenewOverdue(advanceDay int) bool {
+	return lbcert.acmeRenewBeforeDays() >= advanceDay
+}
+
+func acmeIssueRetryBackoff(failures int) time.Duration {
+	backoff := acmeIssueRetryInterval
+	for i := 1; i < failures && backoff < acmeIssueRetryMaxInterval; i++ {
+		backoff *= 2
+	}
+	if backoff > acmeIssueRetryMaxInterval {
+		backoff = acmeIssueRetryMaxInterval
+	}
+	return backoff
+}
+
+// RecordAcmeIssueResult counts consecutive issue failures and schedules the
+// next automatic retry accordingly
+func (lbcert *SLoadbalancerCertificate) RecordAcmeIssueResult(succ bool) error {
+	_, err := db.Update(lbcert, func() error {
+		if succ {
+			lbcert.AcmeIssueFailures = 0
+			lbcert.AcmeNextIssueAt = time.Time{}
+		} else {
+			lbcert.AcmeIssueFailures += 1
+			lbcert.AcmeNextIssueAt = time.Now().Add(acmeIssueRetryBackoff(lbcert.AcmeIssueFailures))
+		}
+		return nil
+	})
+	return err
+}
+
+// AutoRenewAcmeCertificates starts issue task for ACME certificates to
+// expire in their renew window, or those failed to issue last time and due
+// for retry, or those stuck in issuing
+func (man *SLoadbalancerCertificateManager) AutoRenewAcmeCertificates(ctx context.Context, userCred mcclient.TokenCredential, isStart bool) {
+	lbcerts := []SLoadbalancerCertificate{}
+	q := man.Query().Equals("source", api.LB_CERT_SOURCE_ACME).IsFalse("pending_deleted")
+	if err := db.FetchModelObjects(man, q, &lbcerts); err != nil {
+		log.Errorf("fetch acme loadbalancer certificates: %v", err)
+		return
+	}
+	now := time.Now()
+	for i := range lbcerts {
+		lbcert := &lbcerts[i]
+		if !lbcert.needAcmeRenew(now) {
+			continue
+		}
+		log.Infof("renew acme loadbalancer certificate %s(%s) expiring at %s", lbcert.Name, lbcert.Id, lbcert.NotAfter)
+		if err := lbcert.StartAcmeIssueTask(ctx, userCred, ""); err != nil {
+			log.Errorf("start acme issue task for %s: %v", lbcert.Name, err)
+		}
+	}
+
+	CachedLoadbalancerCertificateManager.CleanObsoleteCachedCertificates(ctx, userCred)
+}
+
+// CleanObsoleteCachedCertificates removes caches replaced by renewed ones once
+// no listener refers to them any more
+func (man *SCachedLoadbalancerCertificateManager) CleanObsoleteCachedCertificates(ctx context.Context, userCred mcclient.TokenCredential) {
+	listeners := LoadbalancerListenerManager.Query("cached_certificate_id").IsFalse("pending_deleted").IsNotEmpty("cached_certificate_id").SubQuery()
+	q := man.Query().Equals("status", api.LB_CERT_STATUS_OBSOLETE).IsFalse("pending_deleted")
+	q = q.Filter(sqlchemy.NotIn(q.Field("id"), listeners))
+	caches := []SCachedLoadbalancerCertificate{}
+	if err := db.FetchModelObjects(man, q, &caches); err != nil {
+		log.Errorf("fetch obsolete cached loadbalancer certificates: %v", err)
+		return
+	}
+	for i := range caches {
+		cache := &caches[i]
+		func() {
+			lockman.LockObject(ctx, cache)
+			defer lockman.ReleaseObject(ctx, cache)
+
+			if err := cache.StartLoadBalancerCertificateDeleteTask(ctx, userCred, jsonutils.NewDict(), ""); err != nil {
+				log.Errorf("delete obsolete cached certificate %s: %v", cache.Name, err)
+			}
+		}()
+	}
+}
+
+func (man *SLoadbalancerCertificateManager) GetExpiredModels(advanceDay int) ([]IBillingModel, error) {
+	upLimit := time.Now().AddDate(0, 0, advanceDay)
+	downLimit := time.Now().AddDate(0, 0, advanceDay-1)
+	q := man.Query().IsFalse("pending_deleted").LE("not_after", upLimit).GE("not_after", downLimit)
+	lbcerts := []SLoadbalancerCertificate{}
+	if err := db.FetchModelObjects(man, q, &lbcerts); err != nil {
+		return nil, errors.Wrapf(err, "unable to list %s", man.KeywordPlural())
+	}
+	ret := make([]IBillingModel, 0, len(lbcerts))
+	for i := range lbcerts {
+		// ACME certificates are renewed in their renew window, alert only
+		// when renewal has not succeeded by then
+		if lbcerts[i].IsAcme() && !lbcerts[i].isAcmeRenewOverdue(advanceDay) {
+			continue
+		}
+		ret = append(ret, &lbcerts[i])
+	}
+	return ret, nil
+}
+
+func (lbcert *SLoadbalancerCertificate) GetExpiredAt() time.Time {
+	return lbcert.NotAfter
+}
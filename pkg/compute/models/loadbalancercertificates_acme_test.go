@@ -0,0 +1,161 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"testing"
+	"time"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/compute/options"
+)
+
+func TestLoadbalancerCertificateIsAcmeRenewOverdue(t *testing.T) {
+	defaultDays := options.Options.AcmeRenewBeforeDays
+	defer func() { options.Options.AcmeRenewBeforeDays = defaultDays }()
+	options.Options.AcmeRenewBeforeDays = 30
+
+	cases := []struct {
+		name            string
+		renewBeforeDays int
+		advanceDay      int
+		want            bool
+	}{
+		{
+			name:            "window not begun",
+			renewBeforeDays: 7,
+			advanceDay:      15,
+			want:            false,
+		},
+		{
+			name:            "window begins today",
+			renewBeforeDays: 15,
+			advanceDay:      15,
+			want:            true,
+		},
+		{
+			name:            "renewal overdue",
+			renewBeforeDays: 30,
+			advanceDay:      7,
+			want:            true,
+		},
+		{
+			name:            "default window overdue",
+			renewBeforeDays: 0,
+			advanceDay:      7,
+			want:            true,
+		},
+		{
+			name:            "default window not begun",
+			renewBeforeDays: 0,
+			advanceDay:      60,
+			want:            false,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			lbcert := &SLoadbalancerCertificate{
+				Source:              api.LB_CERT_SOURCE_ACME,
+				AcmeRenewBeforeDays: c.renewBeforeDays,
+			}
+			if got := lbcert.isAcmeRenewOverdue(c.advanceDay); got != c.want {
+				t.Errorf("isAcmeRenewOverdue(%d) with renew_before_days %d: want %v, got %v", c.advanceDay, c.renewBeforeDays, c.want, got)
+			}
+		})
+	}
+}
+
+func TestLoadbalancerCertificateNeedAcmeRenew(t *testing.T) {
+	now := time.Now()
+	cases := []struct {
+		name    string
+		status  string
+		expire  time.Time
+		next    time.Time
+		updated time.Time
+		want    bool
+	}{
+		{
+			name:   "valid",
+			status: api.LB_STATUS_ENABLED,
+			expire: now.AddDate(0, 0, 60),
+			want:   false,
+		},
+		{
+			name:   "in renew window",
+			status: api.LB_STATUS_ENABLED,
+			expire: now.AddDate(0, 0, 10),
+			want:   true,
+		},
+		{
+			name:   "failed and backing off",
+			status: api.LB_CERT_STATUS_ISSUE_FAILED,
+			expire: now.AddDate(0, 0, 10),
+			next:   now.Add(time.Hour),
+			want:   false,
+		},
+		{
+			name:   "failed and due for retry",
+			status: api.LB_CERT_STATUS_ISSUE_FAILED,
+			expire: now.AddDate(0, 0, 10),
+			next:   now.Add(-time.Minute),
+			want:   true,
+		},
+		{
+			name:    "issuing",
+			status:  api.LB_CERT_STATUS_ISSUING,
+			expire:  now.AddDate(0, 0, 10),
+			updated: now.Add(-time.Minute),
+			want:    false,
+		},
+		{
+			name:    "stuck in issuing",
+			status:  api.LB_CERT_STATUS_ISSUING,
+			expire:  now.AddDate(0, 0, 60),
+			updated: now.Add(-acmeIssueTimeout - 24*time.Hour),
+			want:    true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			lbcert := &SLoadbalancerCertificate{
+				Source:              api.LB_CERT_SOURCE_ACME,
+				AcmeRenewBeforeDays: 30,
+				AcmeNextIssueAt:     c.next,
+			}
+			lbcert.Status = c.status
+			lbcert.NotAfter = c.expire
+			lbcert.UpdatedAt = c.updated
+			if got := lbcert.needAcmeRenew(now); got != c.want {
+				t.Errorf("want %v, got %v", c.want, got)
+			}
+		})
+	}
+}
+
+func TestAcmeIssueRetryBackoff(t *testing.T) {
+	cases := map[int]time.Duration{
+		1:  time.Hour,
+		2:  2 * time.Hour,
+		4:  8 * time.Hour,
+		6:  24 * time.Hour,
+		20: 24 * time.Hour,
+	}
+	for failures, want := range cases {
+		if got := acmeIssueRetryBackoff(failures); got != want {
+			t.Errorf("failures %d: want %s, got %s", failures, want, got)
+		}
+	}
+}
@@ -67,6 +67,14 @@ func init() {
 type SLoadbalancerHTTPRateLimiter struct {
 	HTTPRequestRate       int `nullable:"true" list:"user" create:"optional" update:"user"` // 限定监听接收请示速率
 	HTTPRequestRatePerSrc int `nullable:"true" list:"user" create:"optional" update:"user"` // 源IP监听请求最大速率
+
+	HTTPRateLimitStatus int    `nullable:"true" list:"user" create:"optional" update:"user"`              // 超出限制时返回的HTTP状态码，默认429
+	HTTPRateLimitBody   string `width:"1024" nullable:"true" list:"user" create:"optional" update:"user"` // 超出限制时返回的内容
+}
+
+type SLoadbalancerConnLimiter struct {
+	MaxConn       int `nullable:"true" list:"user" create:"optional" update:"user"` // 监听最大并发连接数
+	MaxConnPerSrc int `nullable:"true" list:"user" create:"optional" update:"user"` // 源IP最大并发连接数
 }
 
 type SLoadbalancerRateLimiter struct {
@@ -88,6 +96,8 @@ type SLoadbalancerHealthCheck struct {
 
 	HealthCheckReq string `list:"user" create:"optional" update:"user"` // UDP监听健康检查的请求串
 	HealthCheckExp string `list:"user" create:"optional" update:"user"` // UDP监听健康检查的响应串
+
+	HealthCheckGrpcService string `width:"128" charset:"ascii" nullable:"true" list:"user" create:"optional" update:"user"` // gRPC健康检查的服务名，为空时检查整个服务端
 }
 
 type SLoadbalancerTCPListener struct{}
@@ -102,6 +112,8 @@ type SLoadbalancerHTTPListener struct {
 
 	XForwardedFor bool `nullable:"true" list:"user" create:"optional" update:"user"` // 获取客户端真实IP
 	Gzip          bool `nullable:"true" list:"user" create:"optional" update:"user"` // Gzip数据压缩
+
+	EnableBackendHttp2 bool `nullable:"true" list:"user" create:"optional" update:"user"` // 后端使用HTTP/2协议，用于gRPC等服务
 }
 
 type SLoadbalancerHTTPRedirect struct {
@@ -153,6 +165,7 @@ type SLoadbalancerListener struct {
 	CachedAclId                  string `width:"36" charset:"ascii" nullable:"true" list:"user" create:"optional" update:"user"`
 
 	SLoadbalancerRateLimiter
+	SLoadbalancerConnLimiter
 
 	SLoadbalancerTCPListener
 	SLoadbalancerUDPListener
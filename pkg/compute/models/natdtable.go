@@ -16,6 +16,7 @@ package models
 
 import (
 	"context"
+	"strings"
 
 	"yunion.io/x/jsonutils"
 	"yunion.io/x/pkg/errors"
@@ -173,6 +174,22 @@ func (man *SNatDEntryManager) ValidateCreateData(ctx context.Context, userCred m
 	if len(eip.AssociateId) > 0 && eip.AssociateId != input.NatgatewayId {
 		return nil, httperrors.NewInputParameterError("eip has been binding to another instance")
 	}
+
+	_nat, err := validators.ValidateModel(userCred, NatGatewayManager, &input.NatgatewayId)
+	if err != nil {
+		return nil, err
+	}
+	nat := _nat.(*SNatGateway)
+	if !nat.IsManaged() {
+		// on-premise dnat rules are ovn load balancer vips of the form
+		// eip:port => ip:port, only tcp and udp are supported
+		input.IpProtocol = strings.ToLower(input.IpProtocol)
+		switch input.IpProtocol {
+		case "tcp", "udp":
+		default:
+			return nil, httperrors.NewInputParameterError("ip_protocol of on-premise nat gateway must be tcp or udp, got %q", input.IpProtocol)
+		}
+	}
 	return input, nil
 }
 
@@ -712,6 +712,16 @@ func (self *SNatGateway) GetVpc() (*SVpc, error) {
 	return vpc.(*SVpc), nil
 }
 
+// IsManaged returns whether the nat gateway belongs to a vpc of a cloud
+// provider.  Nat gateways of on-premise vpcs are rendered by vpcagent
+func (self *SNatGateway) IsManaged() bool {
+	vpc, err := self.GetVpc()
+	if err != nil {
+		return false
+	}
+	return vpc.IsManaged()
+}
+
 func (self *SNatGateway) GetINatGateway(ctx context.Context) (cloudprovider.ICloudNatGateway, error) {
 	vpc, err := self.GetVpc()
 	if err != nil {
@@ -127,6 +127,7 @@ func (guest *SGuest) purge(ctx context.Context, userCred mcclient.TokenCredentia
 	guest.RevokeAllSecgroups(ctx, userCred)
 	guest.LeaveAllGroups(ctx, userCred)
 	guest.DetachAllNetworks(ctx, userCred)
+	guest.DeleteAllSharedfs(ctx, userCred)
 	guest.EjectIso(userCred)
 	guest.DeleteEip(ctx, userCred)
 	guest.purgeInstanceSnapshots(ctx, userCred)
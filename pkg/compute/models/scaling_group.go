@@ -672,6 +672,49 @@ func (sg *SScalingGroup) PerformDisable(ctx context.Context, userCred mcclient.T
 	return nil, nil
 }
 
+type sScalingDesireTrigger struct {
+	user   string
+	number int
+}
+
+func (t sScalingDesireTrigger) TriggerDescription() string {
+	return fmt.Sprintf(`A user "%s" request to set the Desired Instance Number to "%d"`, t.user, t.number)
+}
+
+type sScalingDesireAction struct {
+	number int
+}
+
+func (a sScalingDesireAction) Exec(int) int {
+	return a.number
+}
+
+func (a sScalingDesireAction) CheckCoolTime() bool {
+	return false
+}
+
+// PerformSetDesireInstanceNumber changes the desire instance number of scaling group directly,
+// which is useful for scheduled scaling
+func (sg *SScalingGroup) PerformSetDesireInstanceNumber(ctx context.Context, userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject, input api.ScalingGroupSetDesireInstanceNumberInput) (jsonutils.JSONObject, error) {
+	if sg.Status != api.SG_STATUS_READY {
+		return nil, httperrors.NewInvalidStatusError("Can't set desire instance number of scaling group in status %s", sg.Status)
+	}
+	if sg.Enabled.IsFalse() {
+		return nil, httperrors.NewForbiddenError("scaling group is disabled")
+	}
+	if input.DesireInstanceNumber < sg.MinInstanceNumber || input.DesireInstanceNumber > sg.MaxInstanceNumber {
+		return nil, httperrors.NewInputParameterError("desire_instance_number should between %d and %d",
+			sg.MinInstanceNumber, sg.MaxInstanceNumber)
+	}
+	trigger := sScalingDesireTrigger{user: userCred.GetUserName(), number: input.DesireInstanceNumber}
+	err := sg.Scale(ctx, trigger, sScalingDesireAction{input.DesireInstanceNumber}, 0)
+	if err != nil {
+		return nil, errors.Wrap(err, "Scale")
+	}
+	return nil, nil
+}
+
 func (s *SGuest) PerformDetachScalingGroup(ctx context.Context, userCred mcclient.TokenCredential,
 	query jsonutils.JSONObject, input api.SGPerformDetachScalingGroupInput) (jsonutils.JSONObject, error) {
 	// check ScalingGroup
@@ -62,6 +62,9 @@ type SSchedpolicy struct {
 	Strategy  string `width:"32" charset:"ascii" nullable:"false" list:"user" create:"required" update:"user"`
 
 	Enabled tristate.TriState `default:"true" create:"optional" list:"user" update:"user"`
+
+	// 条件匹配时成本优先调度的权重, -1 表示使用调度器默认值
+	CostWeight int `default:"-1" create:"optional" list:"user" update:"user"`
 }
 
 func validateSchedpolicyInputData(data *jsonutils.JSONDict, create bool) error {
@@ -79,6 +82,16 @@ func validateSchedpolicyInputData(data *jsonutils.JSONDict, create bool) error {
 		return httperrors.NewInputParameterError("invalid strategy %s", strategyStr)
 	}
 
+	if data.Contains("cost_weight") {
+		costWeight, err := data.Int("cost_weight")
+		if err != nil {
+			return httperrors.NewInputParameterError("invalid cost_weight: %v", err)
+		}
+		if costWeight < api.SCHED_COST_WEIGHT_UNSET || costWeight > api.SCHED_COST_WEIGHT_MAX {
+			return httperrors.NewOutOfRangeError("cost_weight should be in range [%d, %d]", api.SCHED_COST_WEIGHT_UNSET, api.SCHED_COST_WEIGHT_MAX)
+		}
+	}
+
 	return nil
 }
 
@@ -299,6 +312,28 @@ func applyNetworkSchedtags(policies []SSchedpolicy, input *api.NetworkConfig) {
 	applyResourceSchedPolicy(policies, input.Schedtags, inputCond, setFunc)
 }
 
+// applyServerCostWeight takes the largest cost weight of the matched host
+// policies, the weight given by the input explicitly is kept
+func applyServerCostWeight(policies []SSchedpolicy, input *schedapi.ScheduleInput) {
+	if input.CostWeight != nil {
+		return
+	}
+	inputCond := GetDynamicConditionInput(GuestManager, input.ToConditionInput())
+	for i := range policies {
+		policy := policies[i]
+		if policy.CostWeight <= api.SCHED_COST_WEIGHT_UNSET {
+			continue
+		}
+		if !matchResourceSchedPolicy(policy, inputCond) {
+			continue
+		}
+		if input.CostWeight == nil || *input.CostWeight < policy.CostWeight {
+			weight := policy.CostWeight
+			input.CostWeight = &weight
+		}
+	}
+}
+
 func ApplySchedPolicies(input *schedapi.ScheduleInput) *schedapi.ScheduleInput {
 	// TODO: refactor this duplicate code
 	hostPolicies := SchedpolicyManager.getHostEnabledPolicies()
@@ -307,6 +342,7 @@ func ApplySchedPolicies(input *schedapi.ScheduleInput) *schedapi.ScheduleInput {
 
 	config := input.ServerConfigs
 
+	applyServerCostWeight(hostPolicies, input)
 	applyServerSchedtags(hostPolicies, input)
 	for _, disk := range config.Disks {
 		applyDiskSchedtags(storagePolicies, disk)
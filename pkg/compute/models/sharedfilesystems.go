@@ -0,0 +1,533 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"database/sql"
+	"path"
+	"regexp"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/util/delayedwork"
+	"yunion.io/x/pkg/utils"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/quotas"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/rbacutils"
+	"yunion.io/x/onecloud/pkg/util/stringutils2"
+)
+
+var (
+	sharedfsTagPattern  = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_-]{0,31}$`)
+	sharedfsPathPattern = regexp.MustCompile("^[^\\s'\"`$\\\\;&|<>*?]*$")
+)
+
+type SSharedFilesystemManager struct {
+	db.SStandaloneAnonResourceBaseManager
+	SGuestResourceBaseManager
+
+	delayedWorkManager *delayedwork.DelayedWorkManager
+}
+
+var SharedFilesystemManager *SSharedFilesystemManager
+
+func init() {
+	SharedFilesystemManager = &SSharedFilesystemManager{
+		SStandaloneAnonResourceBaseManager: db.NewStandaloneAnonResourceBaseManager(
+			SSharedFilesystem{},
+			"sharedfilesystems_tbl",
+			"sharedfilesystem",
+			"sharedfilesystems",
+		),
+	}
+	SharedFilesystemManager.SetVirtualObject(SharedFilesystemManager)
+	SharedFilesystemManager.delayedWorkManager = delayedwork.NewDelayedWorkManager()
+}
+
+// SSharedFilesystem exports a host directory or a subtree of a shared file
+// storage into a KVM guest through virtiofsd
+type SSharedFilesystem struct {
+	db.SStandaloneAnonResourceBase
+	SGuestResourceBase
+
+	// 虚拟机内挂载使用的标签
+	Tag string `width:"32" charset:"ascii" nullable:"false" list:"user" create:"required"`
+	// 共享目录来源
+	SourceType string `width:"16" charset:"ascii" nullable:"false" list:"user" create:"required"`
+	// 宿主机目录
+	HostPath string `width:"256" charset:"utf8" nullable:"true" list:"admin" create:"admin_optional"`
+	// 共享文件存储
+	StorageId string `width:"36" charset:"ascii" nullable:"true" list:"user" create:"optional"`
+	// 共享存储内的子目录
+	SubPath string `width:"256" charset:"utf8" nullable:"true" list:"user" create:"optional"`
+	// 读写模式
+	Mode string `width:"8" charset:"ascii" nullable:"false" default:"rw" list:"user" create:"optional"`
+	// 虚拟机内的挂载点
+	Mountpoint string `width:"256" charset:"utf8" nullable:"true" list:"user" create:"optional"`
+	// 计入存储配额的容量, 单位MB
+	SizeMb int `nullable:"false" default:"0" list:"user" create:"optional"`
+}
+
+func (man *SSharedFilesystemManager) InitializeData() error {
+	go man.delayedWorkManager.Start(context.Background())
+	return nil
+}
+
+func (man *SSharedFilesystemManager) fetchByGuestId(guestId string) ([]SSharedFilesystem, error) {
+	q := man.Query().Equals("guest_id", guestId).Asc("created_at")
+	fss := make([]SSharedFilesystem, 0)
+	if err := db.FetchModelObjects(man, q, &fss); err != nil {
+		return nil, errors.Wrapf(err, "fetch shared filesystems of guest %s", guestId)
+	}
+	return fss, nil
+}
+
+func validateSharedfsPath(name, p string) (string, error) {
+	if !sharedfsPathPattern.MatchString(p) {
+		return "", httperrors.NewInputParameterError("%s %q contains invalid characters", name, p)
+	}
+	return path.Clean(p), nil
+}
+
+// sharedfsStorageSubPath roots the sub path of a storage export under the
+// directory of the project owning the guest, so that a guest can never see
+// disks or other projects' data on the same shared storage
+func sharedfsStorageSubPath(projectId, subPath string) (string, error) {
+	subPath, err := validateSharedfsPath("sub_path", subPath)
+	if err != nil {
+		return "", err
+	}
+	root := api.SharedfsStorageRoot(projectId)
+	if !api.IsSharedfsStorageSubPath(projectId, subPath) {
+		subPath = path.Join(root, path.Clean("/"+subPath))
+	}
+	if !api.IsSharedfsStorageSubPath(projectId, subPath) {
+		return "", httperrors.NewInputParameterError("sub_path %q is outside of %s", subPath, root)
+	}
+	return subPath, nil
+}
+
+func (man *SSharedFilesystemManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, input api.SharedFilesystemCreateInput) (api.SharedFilesystemCreateInput, error) {
+	var err error
+	input.StandaloneAnonResourceCreateInput, err = man.SStandaloneAnonResourceBaseManager.ValidateCreateData(ctx, userCred, ownerId, query, input.StandaloneAnonResourceCreateInput)
+	if err != nil {
+		return input, err
+	}
+
+	if len(input.ServerId) == 0 {
+		return input, httperrors.NewMissingParameterError("server_id")
+	}
+	guest, serverInput, err := ValidateGuestResourceInput(userCred, input.ServerResourceInput)
+	if err != nil {
+		return input, err
+	}
+	input.ServerResourceInput = serverInput
+	if guest.Hypervisor != api.HYPERVISOR_KVM {
+		return input, httperrors.NewNotSupportedError("shared filesystem is not supported by hypervisor %s", guest.Hypervisor)
+	}
+	if guest.Status != api.VM_READY {
+		return input, httperrors.NewInvalidStatusError("cannot attach shared filesystem to server in status %s", guest.Status)
+	}
+	input.GuestId = guest.Id
+
+	if !sharedfsTagPattern.MatchString(input.Tag) {
+		return input, httperrors.NewInputParameterError("invalid tag %q, expect at most 32 letters, digits, '_' or '-'", input.Tag)
+	}
+	cnt, err := man.Query().Equals("guest_id", guest.Id).Equals("tag", input.Tag).CountWithError()
+	if err != nil {
+		return input, httperrors.NewInternalServerError("count shared filesystems: %v", err)
+	}
+	if cnt > 0 {
+		return input, httperrors.NewConflictError("tag %s already used by server %s", input.Tag, guest.Name)
+	}
+
+	if len(input.Mode) == 0 {
+		input.Mode = api.SharedFilesystemModeRW
+	}
+	if !api.SharedFilesystemModes.Has(input.Mode) {
+		return input, httperrors.NewInputParameterError("got unknown mode %q, expect %s", input.Mode, api.SharedFilesystemModes)
+	}
+
+	switch input.SourceType {
+	case api.SharedFilesystemSourceHostPath:
+		if db.IsAdminAllowCreate(userCred, man).Result.IsDeny() {
+			return input, httperrors.NewForbiddenError("only admin can export host path")
+		}
+		if !path.IsAbs(input.HostPath) {
+			return input, httperrors.NewInputParameterError("host_path must be an absolute path")
+		}
+		input.HostPath, err = validateSharedfsPath("host_path", input.HostPath)
+		if err != nil {
+			return input, err
+		}
+		if input.HostPath == "/" {
+			return input, httperrors.NewInputParameterError("cannot export root directory of host")
+		}
+		input.StorageId = ""
+		input.SubPath = ""
+	case api.SharedFilesystemSourceStorage:
+		if len(input.StorageId) == 0 {
+			return input, httperrors.NewMissingParameterError("storage_id")
+		}
+		storageObj, err := StorageManager.FetchByIdOrName(userCred, input.StorageId)
+		if err != nil {
+			if errors.Cause(err) == sql.ErrNoRows {
+				return input, httperrors.NewResourceNotFoundError2(StorageManager.Keyword(), input.StorageId)
+			}
+			return input, httperrors.NewGeneralError(err)
+		}
+		storage := storageObj.(*SStorage)
+		if !utils.IsInStringArray(storage.StorageType, api.SHARED_FILE_STORAGE) {
+			return input, httperrors.NewInputParameterError("storage %s of type %s is not a shared file storage, expect %s",
+				storage.Name, storage.StorageType, api.SHARED_FILE_STORAGE)
+		}
+		host, err := guest.GetHost()
+		if err != nil {
+			return input, httperrors.NewInvalidStatusError("server %s has no host", guest.Name)
+		}
+		if host.GetHoststorageOfId(storage.Id) == nil {
+			return input, httperrors.NewInputParameterError("storage %s is not attached to host %s", storage.Name, host.Name)
+		}
+		input.StorageId = storage.Id
+		input.SubPath, err = sharedfsStorageSubPath(guest.ProjectId, input.SubPath)
+		if err != nil {
+			return input, err
+		}
+		input.HostPath = ""
+	default:
+		return input, httperrors.NewInputParameterError("got unknown source type %q, expect %s",
+			input.SourceType, api.SharedFilesystemSources)
+	}
+
+	if len(input.Mountpoint) > 0 {
+		if !path.IsAbs(input.Mountpoint) {
+			return input, httperrors.NewInputParameterError("mountpoint must be an absolute path")
+		}
+		input.Mountpoint, err = validateSharedfsPath("mountpoint", input.Mountpoint)
+		if err != nil {
+			return input, err
+		}
+		if input.Mountpoint == "/" {
+			return input, httperrors.NewInputParameterError("cannot mount shared filesystem on /")
+		}
+	}
+
+	if input.SizeMb < 0 {
+		return input, httperrors.NewInputParameterError("size_mb must not be negative")
+	}
+	if input.SizeMb > 0 {
+		keys, err := guest.GetQuotaKeys()
+		if err != nil {
+			return input, httperrors.NewInternalServerError("guest.GetQuotaKeys fail %s", err)
+		}
+		pendingUsage := SQuota{Storage: input.SizeMb}
+		pendingUsage.SetKeys(keys)
+		if err := quotas.CheckSetPendingQuota(ctx, userCred, &pendingUsage); err != nil {
+			return input, httperrors.NewOutOfQuotaError("%s", err)
+		}
+	}
+	return input, nil
+}
+
+func (fs *SSharedFilesystem) PostCreate(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, data jsonutils.JSONObject) {
+	fs.SStandaloneAnonResourceBase.PostCreate(ctx, userCred, ownerId, query, data)
+
+	guest, err := fs.GetGuest()
+	if err != nil {
+		log.Errorf("shared filesystem %s GetGuest: %v", fs.Id, err)
+		return
+	}
+	if fs.SizeMb > 0 {
+		keys, err := guest.GetQuotaKeys()
+		if err != nil {
+			log.Errorf("guest.GetQuotaKeys fail %s", err)
+		} else {
+			pendingUsage := SQuota{Storage: fs.SizeMb}
+			pendingUsage.SetKeys(keys)
+			if err := quotas.CancelPendingUsage(ctx, userCred, &pendingUsage, &pendingUsage, true); err != nil {
+				log.Errorf("quotas.CancelPendingUsage fail %s", err)
+			}
+		}
+	}
+	SharedFilesystemManager.submitGuestDeployTask(ctx, userCred, guest)
+}
+
+func (fs *SSharedFilesystem) ValidateDeleteCondition(ctx context.Context, info jsonutils.JSONObject) error {
+	guest, err := fs.GetGuest()
+	if err == nil && guest.Status != api.VM_READY {
+		return httperrors.NewInvalidStatusError("cannot detach shared filesystem from server in status %s", guest.Status)
+	}
+	return fs.SStandaloneAnonResourceBase.ValidateDeleteCondition(ctx, nil)
+}
+
+func (fs *SSharedFilesystem) PostDelete(ctx context.Context, userCred mcclient.TokenCredential) {
+	fs.SStandaloneAnonResourceBase.PostDelete(ctx, userCred)
+
+	guest, err := fs.GetGuest()
+	if err != nil {
+		return
+	}
+	SharedFilesystemManager.submitGuestDeployTask(ctx, userCred, guest)
+}
+
+func (fs *SSharedFilesystem) getJsonDesc() *api.GuestSharedfsJsonDesc {
+	return &api.GuestSharedfsJsonDesc{
+		Tag:        fs.Tag,
+		SourceType: fs.SourceType,
+		HostPath:   fs.HostPath,
+		StorageId:  fs.StorageId,
+		SubPath:    fs.SubPath,
+		Mountpoint: fs.Mountpoint,
+		Readonly:   fs.Mode == api.SharedFilesystemModeRO,
+	}
+}
+
+func (self *SGuest) GetSharedFilesystems() ([]SSharedFilesystem, error) {
+	return SharedFilesystemManager.fetchByGuestId(self.Id)
+}
+
+func (self *SGuest) getSharedfsJsonDesc() []*api.GuestSharedfsJsonDesc {
+	fss, err := self.GetSharedFilesystems()
+	if err != nil {
+		log.Errorf("getSharedfsJsonDesc: %v", err)
+		return nil
+	}
+	var ret []*api.GuestSharedfsJsonDesc
+	for i := range fss {
+		ret = append(ret, fss[i].getJsonDesc())
+	}
+	return ret
+}
+
+// checkSharedfsChangeOwner refuses to change the owner of a guest exporting
+// storage directories, which are rooted under the directory of its project
+// and would become unreachable from the new project
+func checkSharedfsChangeOwner(fss []SSharedFilesystem) error {
+	for i := range fss {
+		if fss[i].SourceType == api.SharedFilesystemSourceStorage {
+			return httperrors.NewUnsupportOperationError("shared filesystem %s exports %s of storage %s, detach it before changing owner",
+				fss[i].Tag, fss[i].SubPath, fss[i].StorageId)
+		}
+	}
+	return nil
+}
+
+func (self *SGuest) validateSharedfsChangeOwner() error {
+	fss, err := self.GetSharedFilesystems()
+	if err != nil {
+		return httperrors.NewGeneralError(err)
+	}
+	return checkSharedfsChangeOwner(fss)
+}
+
+// DeleteAllSharedfs removes the shared filesystems of a guest being deleted
+func (self *SGuest) DeleteAllSharedfs(ctx context.Context, userCred mcclient.TokenCredential) error {
+	fss, err := self.GetSharedFilesystems()
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for i := range fss {
+		if err := db.DeleteModel(ctx, userCred, &fss[i]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.NewAggregate(errs)
+}
+
+func totalSharedfsSize(
+	scope rbacutils.TRbacScope,
+	ownerId mcclient.IIdentityProvider,
+	rangeObjs []db.IStandaloneModel,
+	providers []string,
+	brands []string,
+	cloudEnv string,
+	hypervisors []string,
+) int {
+	fss := SharedFilesystemManager.Query().SubQuery()
+	guests := GuestManager.Query().SubQuery()
+	hosts := HostManager.Query().SubQuery()
+	q := fss.Query(sqlchemy.SUM("total", fss.Field("size_mb")))
+	q = q.Join(guests, sqlchemy.Equals(guests.Field("id"), fss.Field("guest_id")))
+	q = q.Join(hosts, sqlchemy.Equals(hosts.Field("id"), guests.Field("host_id")))
+	q = CloudProviderFilter(q, hosts.Field("manager_id"), providers, brands, cloudEnv)
+	q = RangeObjectsFilter(q, rangeObjs, nil, hosts.Field("zone_id"), hosts.Field("manager_id"), hosts.Field("id"), nil)
+	if len(hypervisors) > 0 {
+		q = q.Filter(sqlchemy.In(guests.Field("hypervisor"), hypervisors))
+	}
+
+	switch scope {
+	case rbacutils.ScopeSystem:
+		// do nothing
+	case rbacutils.ScopeDomain:
+		q = q.Filter(sqlchemy.Equals(guests.Field("domain_id"), ownerId.GetProjectDomainId()))
+	case rbacutils.ScopeProject:
+		q = q.Filter(sqlchemy.Equals(guests.Field("tenant_id"), ownerId.GetProjectId()))
+	}
+
+	size := sql.NullInt64{}
+	if err := q.Row().Scan(&size); err != nil {
+		log.Errorf("totalSharedfsSize error %s: %s", err, q.String())
+		return 0
+	}
+	if size.Valid {
+		return int(size.Int64)
+	}
+	return 0
+}
+
+func (man *SSharedFilesystemManager) ListItemExportKeys(ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	keys stringutils2.SSortedStrings,
+) (*sqlchemy.SQuery, error) {
+	return db.ApplyListItemExportKeys(ctx, q, userCred, keys,
+		&man.SStandaloneAnonResourceBaseManager,
+		&man.SGuestResourceBaseManager,
+	)
+}
+
+func (man *SSharedFilesystemManager) QueryDistinctExtraField(q *sqlchemy.SQuery, field string) (*sqlchemy.SQuery, error) {
+	return db.ApplyQueryDistinctExtraField(q, field,
+		&man.SStandaloneAnonResourceBaseManager,
+		&man.SGuestResourceBaseManager,
+	)
+}
+
+func (man *SSharedFilesystemManager) ListItemFilter(ctx context.Context, q *sqlchemy.SQuery, userCred mcclient.TokenCredential, input api.SharedFilesystemListInput) (*sqlchemy.SQuery, error) {
+	var err error
+
+	q, err = man.SStandaloneAnonResourceBaseManager.ListItemFilter(ctx, q, userCred, input.StandaloneAnonResourceListInput)
+	if err != nil {
+		return nil, err
+	}
+
+	q, err = man.SGuestResourceBaseManager.ListItemFilter(ctx, q, userCred, input.ServerFilterListInput)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(input.SourceType) > 0 {
+		q = q.In("source_type", input.SourceType)
+	}
+	if len(input.Mode) > 0 {
+		q = q.In("mode", input.Mode)
+	}
+	if len(input.StorageId) > 0 {
+		q = q.In("storage_id", input.StorageId)
+	}
+	return q, nil
+}
+
+func (man *SSharedFilesystemManager) OrderByExtraFields(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.SharedFilesystemListInput,
+) (*sqlchemy.SQuery, error) {
+	q, err := man.SStandaloneAnonResourceBaseManager.OrderByExtraFields(ctx, q, userCred, query.StandaloneAnonResourceListInput)
+	if err != nil {
+		return nil, err
+	}
+	return man.SGuestResourceBaseManager.OrderByExtraFields(ctx, q, userCred, query.ServerFilterListInput)
+}
+
+func (man *SSharedFilesystemManager) FetchCustomizeColumns(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	objs []interface{},
+	fields stringutils2.SSortedStrings,
+	isList bool,
+) []api.SharedFilesystemDetails {
+	ret := make([]api.SharedFilesystemDetails, len(objs))
+	stdaCols := man.SStandaloneAnonResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+	for i := range stdaCols {
+		ret[i].StandaloneAnonResourceDetails = stdaCols[i]
+	}
+	guestCols := man.SGuestResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+	for i := range guestCols {
+		ret[i].GuestResourceInfo = guestCols[i]
+	}
+
+	storageIds := make([]string, 0, len(objs))
+	for i := range objs {
+		fs := objs[i].(*SSharedFilesystem)
+		if len(fs.StorageId) > 0 {
+			storageIds = append(storageIds, fs.StorageId)
+		}
+	}
+	if len(storageIds) > 0 {
+		storageNames, err := db.FetchIdNameMap2(StorageManager, storageIds)
+		if err != nil {
+			log.Errorf("FetchIdNameMap2 storages: %v", err)
+			return ret
+		}
+		for i := range objs {
+			fs := objs[i].(*SSharedFilesystem)
+			ret[i].Storage = storageNames[fs.StorageId]
+		}
+	}
+	return ret
+}
+
+func (man *SSharedFilesystemManager) FilterByOwner(q *sqlchemy.SQuery, owner mcclient.IIdentityProvider, scope rbacutils.TRbacScope) *sqlchemy.SQuery {
+	q = db.ApplyFilterByOwner(q, owner, scope,
+		&man.SStandaloneAnonResourceBaseManager,
+	)
+	if owner != nil {
+		var condVar, condVal string
+		switch scope {
+		case rbacutils.ScopeProject:
+			condVar, condVal = "tenant_id", owner.GetProjectId()
+		case rbacutils.ScopeDomain:
+			condVar, condVal = "domain_id", owner.GetProjectDomainId()
+		default:
+			return q
+		}
+		gq := GuestManager.Query("id").Equals(condVar, condVal)
+		q = q.In("guest_id", gq.SubQuery())
+	}
+	return q
+}
+
+// submitGuestDeployTask redeploys the guest so that the fstab inside the
+// guest follows the attached shared filesystems
+func (man *SSharedFilesystemManager) submitGuestDeployTask(ctx context.Context, userCred mcclient.TokenCredential, guest *SGuest) {
+	man.delayedWorkManager.Submit(ctx, delayedwork.DelayedWorkRequest{
+		ID:        guest.Id,
+		SoftDelay: 2 * time.Second,
+		HardDelay: 5 * time.Second,
+		Func: func(ctx context.Context) {
+			guest := GuestManager.FetchGuestById(guest.Id)
+			if guest == nil || guest.Status != api.VM_READY {
+				return
+			}
+			params := jsonutils.NewDict()
+			params.Set("reset_password", jsonutils.JSONFalse)
+			if err := guest.StartGuestDeployTask(ctx, userCred, params, "deploy", ""); err != nil {
+				log.Errorf("guest StartGuestDeployTask: %v", err)
+			}
+		},
+	})
+}
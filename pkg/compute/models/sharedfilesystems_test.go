@@ -0,0 +1,117 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"testing"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+)
+
+func TestSharedfsStorageSubPath(t *testing.T) {
+	projectId := "5d65667d112e47249ae66dbd7bc07030"
+	cases := []struct {
+		name    string
+		subPath string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "empty",
+			subPath: "",
+			want:    "/sharedfs/5d65667d112e47249ae66dbd7bc07030",
+		},
+		{
+			name:    "root",
+			subPath: "/",
+			want:    "/sharedfs/5d65667d112e47249ae66dbd7bc07030",
+		},
+		{
+			name:    "relative",
+			subPath: "projects/demo",
+			want:    "/sharedfs/5d65667d112e47249ae66dbd7bc07030/projects/demo",
+		},
+		{
+			name:    "parent escape",
+			subPath: "../../../",
+			want:    "/sharedfs/5d65667d112e47249ae66dbd7bc07030",
+		},
+		{
+			name:    "parent escape in middle",
+			subPath: "data/../../other/disks",
+			want:    "/sharedfs/5d65667d112e47249ae66dbd7bc07030/other/disks",
+		},
+		{
+			name:    "already rooted",
+			subPath: "/sharedfs/5d65667d112e47249ae66dbd7bc07030/data",
+			want:    "/sharedfs/5d65667d112e47249ae66dbd7bc07030/data",
+		},
+		{
+			name:    "other project",
+			subPath: "/sharedfs/other/data",
+			want:    "/sharedfs/5d65667d112e47249ae66dbd7bc07030/sharedfs/other/data",
+		},
+		{
+			name:    "invalid characters",
+			subPath: "data;rm -rf /",
+			wantErr: true,
+		},
+	}
+	for _, c := range cases {
+		got, err := sharedfsStorageSubPath(projectId, c.subPath)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("%s: expect error, got %s", c.name, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error %s", c.name, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("%s: want %s got %s", c.name, c.want, got)
+		}
+	}
+}
+
+func TestSharedfsStorageSubPathEmptyProject(t *testing.T) {
+	if _, err := sharedfsStorageSubPath("", "data"); err == nil {
+		t.Errorf("expect error for empty project")
+	}
+}
+
+func TestCheckSharedfsChangeOwner(t *testing.T) {
+	hostPath := SSharedFilesystem{Tag: "logs", SourceType: api.SharedFilesystemSourceHostPath, HostPath: "/var/log"}
+	storage := SSharedFilesystem{Tag: "data", SourceType: api.SharedFilesystemSourceStorage, StorageId: "nfs0", SubPath: "/sharedfs/p0/data"}
+	cases := []struct {
+		name    string
+		fss     []SSharedFilesystem
+		wantErr bool
+	}{
+		{"no shared filesystems", nil, false},
+		{"host path", []SSharedFilesystem{hostPath}, false},
+		{"storage", []SSharedFilesystem{storage}, true},
+		{"host path and storage", []SSharedFilesystem{hostPath, storage}, true},
+	}
+	for _, c := range cases {
+		err := checkSharedfsChangeOwner(c.fss)
+		if c.wantErr && err == nil {
+			t.Errorf("%s: expect error", c.name)
+		} else if !c.wantErr && err != nil {
+			t.Errorf("%s: unexpected error %s", c.name, err)
+		}
+	}
+}
@@ -115,6 +115,11 @@ type SServerSku struct {
 	GpuMaxCount   int               `nullable:"true" list:"user" create:"admin_optional" update:"admin"`
 
 	Provider string `width:"64" charset:"ascii" nullable:"true" list:"user" default:"OneCloud" create:"admin_optional"`
+
+	// 按量付费每小时价格
+	PostpaidHourlyPrice float64 `nullable:"true" list:"user" create:"admin_optional" update:"admin"`
+	// 包年包月每月价格
+	PrepaidMonthlyPrice float64 `nullable:"true" list:"user" create:"admin_optional" update:"admin"`
 }
 
 func (manager *SServerSkuManager) FetchUniqValues(ctx context.Context, data jsonutils.JSONObject) jsonutils.JSONObject {
@@ -353,6 +358,10 @@ func (self *SServerSkuManager) ValidateCreateData(ctx context.Context, userCred
 		}
 	}
 
+	if input.PostpaidHourlyPrice < 0 || input.PrepaidMonthlyPrice < 0 {
+		return input, httperrors.NewInputParameterError("price of sku should not be negative")
+	}
+
 	input.EnabledStatusStandaloneResourceCreateInput, err = self.SEnabledStatusStandaloneResourceBaseManager.ValidateCreateData(ctx, userCred, ownerId, query, input.EnabledStatusStandaloneResourceCreateInput)
 	if err != nil {
 		return input, err
@@ -581,6 +590,9 @@ func (self *SServerSku) ValidateUpdateData(ctx context.Context, userCred mcclien
 	if len(input.Name) > 0 {
 		return input, httperrors.NewUnsupportOperationError("Cannot change server sku name")
 	}
+	if (input.PostpaidHourlyPrice != nil && *input.PostpaidHourlyPrice < 0) || (input.PrepaidMonthlyPrice != nil && *input.PrepaidMonthlyPrice < 0) {
+		return input, httperrors.NewInputParameterError("price of sku should not be negative")
+	}
 
 	var err error
 	input.EnabledStatusStandaloneResourceBaseUpdateInput, err = self.SEnabledStatusStandaloneResourceBase.ValidateUpdateData(ctx, userCred, query, input.EnabledStatusStandaloneResourceBaseUpdateInput)
@@ -1132,6 +1144,13 @@ func (self *SServerSku) syncWithCloudSku(ctx context.Context, userCred mcclient.
 		self.CpuArch = extSku.CpuArch
 		self.SysDiskType = extSku.SysDiskType
 		self.DataDiskTypes = extSku.DataDiskTypes
+		// keep the prices set by admin if the sku source has no pricing
+		if extSku.PostpaidHourlyPrice > 0 {
+			self.PostpaidHourlyPrice = extSku.PostpaidHourlyPrice
+		}
+		if extSku.PrepaidMonthlyPrice > 0 {
+			self.PrepaidMonthlyPrice = extSku.PrepaidMonthlyPrice
+		}
 		return nil
 	})
 	return err
@@ -96,6 +96,9 @@ type SStorage struct {
 	// 是否可以用作系统盘存储
 	// example: true
 	IsSysDiskStore tristate.TriState `default:"true" list:"user" create:"optional" update:"domain"`
+
+	// 每GB每月价格, 用于成本优先调度
+	MonthlyPricePerGb float64 `nullable:"true" list:"user" create:"domain_optional" update:"domain"`
 }
 
 func (manager *SStorageManager) GetContextManagers() [][]db.IModelManager {
@@ -111,6 +114,9 @@ func (self *SStorage) ValidateUpdateData(ctx context.Context, userCred mcclient.
 	if err != nil {
 		return input, err
 	}
+	if input.MonthlyPricePerGb != nil && *input.MonthlyPricePerGb < 0 {
+		return input, httperrors.NewInputParameterError("monthly_price_per_gb should not be negative")
+	}
 	input.StorageConf = jsonutils.NewDict()
 	if self.StorageConf != nil {
 		input.StorageConf.Update(jsonutils.Marshal(self.StorageConf))
@@ -126,7 +132,7 @@ func (self *SStorage) ValidateUpdateData(ctx context.Context, userCred mcclient.
 func (self *SStorage) PostUpdate(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) {
 	self.SEnabledStatusInfrasResourceBase.PostUpdate(ctx, userCred, query, data)
 
-	if data.Contains("cmtbound") || data.Contains("capacity") {
+	if data.Contains("cmtbound") || data.Contains("capacity") || data.Contains("monthly_price_per_gb") {
 		hosts, _ := self.GetAttachedHosts()
 		for _, host := range hosts {
 			if err := host.ClearSchedDescCache(); err != nil {
@@ -197,6 +203,9 @@ func (manager *SStorageManager) ValidateCreateData(
 	if len(input.ZoneId) == 0 {
 		return input, httperrors.NewMissingParameterError("zone_id")
 	}
+	if input.MonthlyPricePerGb < 0 {
+		return input, httperrors.NewInputParameterError("monthly_price_per_gb should not be negative")
+	}
 	_, err := validators.ValidateModel(userCred, ZoneManager, &input.ZoneId)
 	if err != nil {
 		return input, err
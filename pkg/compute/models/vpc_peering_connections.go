@@ -26,6 +26,7 @@ import (
 
 	api "yunion.io/x/onecloud/pkg/apis/compute"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/lockman"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
 	"yunion.io/x/onecloud/pkg/cloudprovider"
 	"yunion.io/x/onecloud/pkg/httperrors"
@@ -63,6 +64,9 @@ type SVpcPeeringConnection struct {
 	PeerVpcId        string `width:"36" charset:"ascii" nullable:"true" list:"domain" create:"required" json:"peer_vpc_id"`
 	PeerAccountId    string `width:"36" charset:"ascii" nullable:"true" list:"domain"`
 	Bandwidth        int    `nullable:"false" default:"0" list:"user" create:"optional"`
+
+	// link between the ovn routers of on-premise vpcs
+	LinkCidr string `width:"20" charset:"ascii" nullable:"true" list:"domain"`
 }
 
 func (manager *SVpcPeeringConnectionManager) GetContextManagers() [][]db.IModelManager {
@@ -143,8 +147,21 @@ func (manager *SVpcPeeringConnectionManager) ValidateCreateData(
 	}
 	peerVpc := _peerVpc.(*SVpc)
 
+	if len(vpc.ManagerId) == 0 && len(peerVpc.ManagerId) == 0 {
+		err = manager.validateOnPremiseVpcs(vpc, peerVpc)
+		if err != nil {
+			return input, err
+		}
+		if input.Bandwidth > 0 {
+			return input, httperrors.NewInputParameterError("bandwidth is not supported for on-premise vpc peering")
+		}
+		if input.Enabled == nil && input.Disabled == nil {
+			input.SetEnabled()
+		}
+		return input, nil
+	}
 	if len(vpc.ManagerId) == 0 || len(peerVpc.ManagerId) == 0 {
-		return input, httperrors.NewInputParameterError("Only public cloud support vpcpeering")
+		return input, httperrors.NewInputParameterError("vpc peering between on-premise vpc and public cloud vpc is not supported")
 	}
 
 	// get account,providerFactory
@@ -161,31 +178,12 @@ func (manager *SVpcPeeringConnectionManager) ValidateCreateData(
 
 	// check vpc ip range overlap
 	if !factory.IsSupportVpcPeeringVpcCidrOverlap() {
-		vpcIpv4Ranges := []netutils.IPV4AddrRange{}
-		peervpcIpv4Ranges := []netutils.IPV4AddrRange{}
-		vpcCidrBlocks := strings.Split(vpc.CidrBlock, ",")
-		peervpcCidrBlocks := strings.Split(peerVpc.CidrBlock, ",")
-		for i := range vpcCidrBlocks {
-			vpcIpv4Range, err := netutils.NewIPV4Prefix(vpcCidrBlocks[i])
-			if err != nil {
-				return input, httperrors.NewGeneralError(errors.Wrapf(err, "convert vpc cidr %s to ipv4range error", vpcCidrBlocks[i]))
-			}
-			vpcIpv4Ranges = append(vpcIpv4Ranges, vpcIpv4Range.ToIPRange())
-		}
-
-		for i := range peervpcCidrBlocks {
-			peervpcIpv4Range, err := netutils.NewIPV4Prefix(peervpcCidrBlocks[i])
-			if err != nil {
-				return input, httperrors.NewGeneralError(errors.Wrapf(err, "convert vpc cidr %s to ipv4range error", peervpcCidrBlocks[i]))
-			}
-			peervpcIpv4Ranges = append(peervpcIpv4Ranges, peervpcIpv4Range.ToIPRange())
+		overlap, err := isVpcCidrBlockOverlap(vpc, peerVpc)
+		if err != nil {
+			return input, httperrors.NewGeneralError(err)
 		}
-		for i := range vpcIpv4Ranges {
-			for j := range peervpcIpv4Ranges {
-				if vpcIpv4Ranges[i].IsOverlap(peervpcIpv4Ranges[j]) {
-					return input, httperrors.NewNotSupportedError("ipv4 range overlap")
-				}
-			}
+		if overlap {
+			return input, httperrors.NewNotSupportedError("ipv4 range overlap")
 		}
 	}
 
@@ -218,6 +216,115 @@ func (manager *SVpcPeeringConnectionManager) ValidateCreateData(
 	return input, nil
 }
 
+func getVpcCidrRanges(vpc *SVpc) ([]netutils.IPV4AddrRange, error) {
+	ranges := []netutils.IPV4AddrRange{}
+	for _, cidr := range strings.Split(vpc.CidrBlock, ",") {
+		prefix, err := netutils.NewIPV4Prefix(cidr)
+		if err != nil {
+			return nil, errors.Wrapf(err, "convert vpc cidr %s to ipv4range error", cidr)
+		}
+		ranges = append(ranges, prefix.ToIPRange())
+	}
+	return ranges, nil
+}
+
+func isVpcCidrBlockOverlap(vpc, peerVpc *SVpc) (bool, error) {
+	vpcIpv4Ranges, err := getVpcCidrRanges(vpc)
+	if err != nil {
+		return false, err
+	}
+	peervpcIpv4Ranges, err := getVpcCidrRanges(peerVpc)
+	if err != nil {
+		return false, err
+	}
+	for i := range vpcIpv4Ranges {
+		for j := range peervpcIpv4Ranges {
+			if vpcIpv4Ranges[i].IsOverlap(peervpcIpv4Ranges[j]) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// validateOnPremiseVpcs checks peering of on-premise vpcs, which is realised
+// by vpcagent as a link between the ovn routers of both vpcs
+func (manager *SVpcPeeringConnectionManager) validateOnPremiseVpcs(vpc, peerVpc *SVpc) error {
+	if vpc.Id == peerVpc.Id {
+		return httperrors.NewInputParameterError("vpc can not peer with itself")
+	}
+	if vpc.Id == api.DEFAULT_VPC_ID || peerVpc.Id == api.DEFAULT_VPC_ID {
+		return httperrors.NewInputParameterError("default vpc does not support vpc peering")
+	}
+	if vpc.CloudregionId != peerVpc.CloudregionId {
+		return httperrors.NewNotSupportedError("on-premise vpc peering across regions is not supported")
+	}
+	if len(vpc.CidrBlock) == 0 || len(peerVpc.CidrBlock) == 0 {
+		return httperrors.NewInputParameterError("vpc peering requires cidr_block of both vpcs")
+	}
+	overlap, err := isVpcCidrBlockOverlap(vpc, peerVpc)
+	if err != nil {
+		return httperrors.NewInputParameterError("%v", err)
+	}
+	if overlap {
+		return httperrors.NewNotSupportedError("cidr_block of vpc %s and vpc %s overlap", vpc.Name, peerVpc.Name)
+	}
+	q := manager.Query()
+	q = q.Filter(sqlchemy.OR(
+		sqlchemy.AND(sqlchemy.Equals(q.Field("vpc_id"), vpc.Id), sqlchemy.Equals(q.Field("peer_vpc_id"), peerVpc.Id)),
+		sqlchemy.AND(sqlchemy.Equals(q.Field("vpc_id"), peerVpc.Id), sqlchemy.Equals(q.Field("peer_vpc_id"), vpc.Id)),
+	))
+	cnt, err := q.CountWithError()
+	if err != nil {
+		return httperrors.NewGeneralError(err)
+	}
+	if cnt > 0 {
+		return httperrors.NewNotSupportedError("vpc %s and vpc %s have already connected", vpc.Name, peerVpc.Name)
+	}
+	return nil
+}
+
+// AllocateLinkCidr assigns an unused /30 link to the on-premise vpc peering.
+// The vpc side of the link takes the first address, the peer vpc side the second
+func (self *SVpcPeeringConnection) AllocateLinkCidr(ctx context.Context, userCred mcclient.TokenCredential) error {
+	if len(self.LinkCidr) > 0 {
+		return nil
+	}
+	manager := VpcPeeringConnectionManager
+	lockman.LockClass(ctx, manager, "")
+	defer lockman.ReleaseClass(ctx, manager, "")
+
+	q := manager.Query("link_cidr").IsNotEmpty("link_cidr")
+	rows, err := q.Rows()
+	if err != nil {
+		return errors.Wrap(err, "query link_cidr")
+	}
+	defer rows.Close()
+	used := map[string]bool{}
+	for rows.Next() {
+		var cidr string
+		if err := rows.Scan(&cidr); err != nil {
+			return errors.Wrap(err, "scan link_cidr")
+		}
+		used[cidr] = true
+	}
+
+	linkRange := api.VpcPeeringLinkCidr().ToIPRange()
+	for addr := linkRange.StartIp(); linkRange.Contains(addr); addr += 1 << (32 - api.VpcPeeringLinkMask) {
+		prefix := netutils.IPV4Prefix{Address: addr, MaskLen: api.VpcPeeringLinkMask}
+		cidr := prefix.String()
+		if used[cidr] {
+			continue
+		}
+		_, err := db.Update(self, func() error {
+			self.LinkCidr = cidr
+			return nil
+		})
+		return err
+	}
+	return errors.Wrap(httperrors.ErrOutOfResource, "no vpc peering link available")
+}
+
 func (self *SVpcPeeringConnection) PostCreate(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, data jsonutils.JSONObject) {
 	params := jsonutils.NewDict()
 	task, err := taskman.TaskManager.NewTask(ctx, "VpcPeeringConnectionCreateTask", self, userCred, params, "", "", nil)
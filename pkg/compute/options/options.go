@@ -48,6 +48,11 @@ type ComputeOptions struct {
 
 	LoadbalancerPendingDeleteCheckInterval int `default:"3600" help:"Interval between checks of pending deleted loadbalancer objects, defaults to 1h"`
 
+	AcmeDirectoryUrl         string `default:"https://acme-v02.api.letsencrypt.org/directory" help:"Default ACME directory url for issuing loadbalancer certificates"`
+	AcmeInsecureSkipVerify   bool   `default:"false" help:"Skip TLS verification of ACME directory, e.g. for internal step-ca or pebble"`
+	AcmeRenewBeforeDays      int    `default:"30" help:"Default days before expiration to renew ACME loadbalancer certificates"`
+	AcmeChallengeWaitSeconds int    `default:"120" help:"How long to wait for ACME challenges to propagate to lbagents or dns before validation"`
+
 	ImageCacheStoragePolicy string `default:"least_used" choices:"best_fit|least_used" help:"Policy to choose storage for image cache, best_fit or least_used"`
 	MetricsRetentionDays    int32  `default:"30" help:"Retention days for monitoring metrics in influxdb"`
 
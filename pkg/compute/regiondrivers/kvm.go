@@ -103,11 +103,15 @@ func (self *SKVMRegionDriver) ValidateCreateLoadbalancerData(ctx context.Context
 	if zone == nil {
 		return nil, httperrors.NewInputParameterError("zone info missing")
 	}
+	networkType := api.LB_NETWORK_TYPE_CLASSIC
 	if vpc.Id != api.DEFAULT_VPC_ID {
-		return nil, httperrors.NewInputParameterError("vpc lb is not allowed for now")
-	}
-
-	if clusterV.Model == nil {
+		// vpc loadbalancers are rendered into ovn load balancers by
+		// vpcagent, no lbcluster is involved
+		if clusterV.Model != nil {
+			return nil, httperrors.NewInputParameterError("cluster is not applicable for vpc loadbalancer")
+		}
+		networkType = api.LB_NETWORK_TYPE_VPC
+	} else if clusterV.Model == nil {
 		clusters := models.LoadbalancerClusterManager.FindByZoneId(zone.Id)
 		if len(clusters) == 0 {
 			return nil, httperrors.NewInputParameterError("zone %s(%s) has no lbcluster", zone.Name, zone.Id)
@@ -151,7 +155,7 @@ func (self *SKVMRegionDriver) ValidateCreateLoadbalancerData(ctx context.Context
 	data.Set("cloudregion_id", jsonutils.NewString(region.GetId()))
 	data.Set("zone_id", jsonutils.NewString(zone.GetId()))
 	data.Set("vpc_id", jsonutils.NewString(vpc.GetId()))
-	data.Set("network_type", jsonutils.NewString(api.LB_NETWORK_TYPE_CLASSIC))
+	data.Set("network_type", jsonutils.NewString(networkType))
 	data.Set("address_type", jsonutils.NewString(api.LB_ADDR_TYPE_INTRANET))
 	return data, nil
 }
@@ -204,6 +208,9 @@ func (self *SKVMRegionDriver) ValidateCreateLoadbalancerBackendData(ctx context.
 		basename = guest.Name
 		backend = backendV.Model
 	case api.LB_BACKEND_HOST:
+		if lb != nil && lb.NetworkType == api.LB_NETWORK_TYPE_VPC {
+			return nil, httperrors.NewInputParameterError("host backend is not supported by vpc loadbalancer")
+		}
 		backendV := validators.NewModelIdOrNameValidator("backend", "host", userCred)
 		err := backendV.Validate(data)
 		if err != nil {
@@ -315,6 +322,8 @@ func (self *SKVMRegionDriver) ValidateCreateLoadbalancerListenerRuleData(ctx con
 
 		"http_request_rate":         validators.NewNonNegativeValidator("http_request_rate").Default(0),
 		"http_request_rate_per_src": validators.NewNonNegativeValidator("http_request_rate_per_src").Default(0),
+		"http_rate_limit_status":    validators.NewIntChoicesValidator("http_rate_limit_status", api.LB_RATE_LIMIT_STATUSES).Default(api.LB_RATE_LIMIT_STATUS_429),
+		"http_rate_limit_body":      validators.NewRegexpValidator("http_rate_limit_body", kvmRateLimitBodyRegexp).AllowEmpty(true).Default(""),
 
 		"redirect":        redirectV.Default(api.LB_REDIRECT_OFF),
 		"redirect_code":   redirectCodeV.Default(api.LB_REDIRECT_CODE_302),
@@ -400,6 +409,8 @@ func (self *SKVMRegionDriver) ValidateUpdateLoadbalancerListenerRuleData(ctx con
 
 		"http_request_rate":         validators.NewNonNegativeValidator("http_request_rate"),
 		"http_request_rate_per_src": validators.NewNonNegativeValidator("http_request_rate_per_src"),
+		"http_rate_limit_status":    validators.NewIntChoicesValidator("http_rate_limit_status", api.LB_RATE_LIMIT_STATUSES),
+		"http_rate_limit_body":      validators.NewRegexpValidator("http_rate_limit_body", kvmRateLimitBodyRegexp).AllowEmpty(true),
 
 		"redirect":        redirectV,
 		"redirect_code":   redirectCodeV,
@@ -448,6 +459,39 @@ func (self *SKVMRegionDriver) ValidateUpdateLoadbalancerListenerRuleData(ctx con
 	return data, nil
 }
 
+var kvmGrpcServiceRegexp = regexp.MustCompile(`^[\w.]+$`)
+
+// kvmRateLimitBodyRegexp accepts printable ascii that can be put verbatim in
+// a double-quoted haproxy string: no quote, backslash or env var expansion
+var kvmRateLimitBodyRegexp = regexp.MustCompile(`^[ !#%-\[\]-~]{0,1000}$`)
+
+// checkTypeV adds grpc health check on top of those common to all providers
+func (self *SKVMRegionDriver) checkTypeV(listenerType string) validators.IValidator {
+	switch listenerType {
+	case api.LB_LISTENER_TYPE_HTTP, api.LB_LISTENER_TYPE_HTTPS:
+		return validators.NewStringChoicesValidator("health_check_type", api.ONECLOUD_LB_HEALTH_CHECK_TYPES_TCP).Default(api.LB_HEALTH_CHECK_HTTP)
+	case api.LB_LISTENER_TYPE_TCP:
+		return validators.NewStringChoicesValidator("health_check_type", api.ONECLOUD_LB_HEALTH_CHECK_TYPES_TCP).Default(api.LB_HEALTH_CHECK_TCP)
+	}
+	return models.LoadbalancerListenerManager.CheckTypeV(listenerType)
+}
+
+// validateGrpcHealthCheck makes sure backends of http, https listeners
+// speak http/2 when checked with grpc.  Tcp listeners pass the stream
+// through so it's up to the user
+func (self *SKVMRegionDriver) validateGrpcHealthCheck(listenerType, checkType string, enableBackendHttp2 bool) error {
+	if checkType != api.LB_HEALTH_CHECK_GRPC {
+		return nil
+	}
+	switch listenerType {
+	case api.LB_LISTENER_TYPE_HTTP, api.LB_LISTENER_TYPE_HTTPS:
+		if !enableBackendHttp2 {
+			return httperrors.NewInputParameterError("grpc health check of %s listener requires enable_backend_http2", listenerType)
+		}
+	}
+	return nil
+}
+
 func (self *SKVMRegionDriver) ValidateCreateLoadbalancerListenerData(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, data *jsonutils.JSONDict, lb *models.SLoadbalancer, backendGroup db.IModel) (*jsonutils.JSONDict, error) {
 	var (
 		listenerTypeV = validators.NewStringChoicesValidator("listener_type", api.LB_LISTENER_TYPES)
@@ -462,6 +506,8 @@ func (self *SKVMRegionDriver) ValidateCreateLoadbalancerListenerData(ctx context
 		redirectSchemeV = validators.NewStringChoicesValidator("redirect_scheme", api.LB_REDIRECT_SCHEMES)
 		redirectHostV   = validators.NewHostPortValidator("redirect_host").OptionalPort(true)
 		redirectPathV   = validators.NewURLPathValidator("redirect_path")
+
+		enableBackendHttp2V = validators.NewBoolValidator("enable_backend_http2")
 	)
 	keyV := map[string]validators.IValidator{
 		"status": validators.NewStringChoicesValidator("status", api.LB_STATUS_SPEC).Default(api.LB_STATUS_ENABLED),
@@ -478,6 +524,9 @@ func (self *SKVMRegionDriver) ValidateCreateLoadbalancerListenerData(ctx context
 		"scheduler":   validators.NewStringChoicesValidator("scheduler", api.LB_SCHEDULER_TYPES).Default(api.LB_SCHEDULER_RR),
 		"egress_mbps": validators.NewRangeValidator("egress_mbps", api.LB_MbpsMin, api.LB_MbpsMax).Optional(true),
 
+		"max_conn":         validators.NewNonNegativeValidator("max_conn").Default(0),
+		"max_conn_per_src": validators.NewNonNegativeValidator("max_conn_per_src").Default(0),
+
 		"client_request_timeout":  validators.NewRangeValidator("client_request_timeout", 0, 600).Default(10),
 		"client_idle_timeout":     validators.NewRangeValidator("client_idle_timeout", 0, 600).Default(90),
 		"backend_connect_timeout": validators.NewRangeValidator("backend_connect_timeout", 0, 180).Default(5),
@@ -491,8 +540,12 @@ func (self *SKVMRegionDriver) ValidateCreateLoadbalancerListenerData(ctx context
 		"x_forwarded_for": validators.NewBoolValidator("x_forwarded_for").Default(true),
 		"gzip":            validators.NewBoolValidator("gzip").Default(false),
 
+		"enable_backend_http2": enableBackendHttp2V.Default(false),
+
 		"http_request_rate":         validators.NewNonNegativeValidator("http_request_rate").Default(0),
 		"http_request_rate_per_src": validators.NewNonNegativeValidator("http_request_rate_per_src").Default(0),
+		"http_rate_limit_status":    validators.NewIntChoicesValidator("http_rate_limit_status", api.LB_RATE_LIMIT_STATUSES).Default(api.LB_RATE_LIMIT_STATUS_429),
+		"http_rate_limit_body":      validators.NewRegexpValidator("http_rate_limit_body", kvmRateLimitBodyRegexp).AllowEmpty(true).Default(""),
 
 		"redirect":        redirectV.Default(api.LB_REDIRECT_OFF),
 		"redirect_code":   redirectCodeV.Default(api.LB_REDIRECT_CODE_302),
@@ -507,6 +560,11 @@ func (self *SKVMRegionDriver) ValidateCreateLoadbalancerListenerData(ctx context
 
 	//  listener uniqueness
 	listenerType := listenerTypeV.Value
+	if lb.NetworkType == api.LB_NETWORK_TYPE_VPC {
+		if listenerType != api.LB_LISTENER_TYPE_TCP && listenerType != api.LB_LISTENER_TYPE_UDP {
+			return nil, httperrors.NewInputParameterError("vpc loadbalancer only supports tcp/udp listener, got %s", listenerType)
+		}
+	}
 	err := models.LoadbalancerListenerManager.CheckListenerUniqueness(ctx, lb, listenerType, listenerPortV.Value)
 	if err != nil {
 		return nil, err
@@ -546,11 +604,13 @@ func (self *SKVMRegionDriver) ValidateCreateLoadbalancerListenerData(ctx context
 	}
 
 	// health check default depends on input parameters
-	checkTypeV := models.LoadbalancerListenerManager.CheckTypeV(listenerType)
+	checkTypeV := self.checkTypeV(listenerType)
 	keyVHealth := map[string]validators.IValidator{
 		"health_check":      validators.NewStringChoicesValidator("health_check", api.LB_BOOL_VALUES).Default(api.LB_BOOL_ON),
 		"health_check_type": checkTypeV,
 
+		"health_check_grpc_service": validators.NewRegexpValidator("health_check_grpc_service", kvmGrpcServiceRegexp).AllowEmpty(true).Default(""),
+
 		"health_check_domain":    validators.NewDomainNameValidator("health_check_domain").AllowEmpty(true).Default(""),
 		"health_check_path":      validators.NewURLPathValidator("health_check_path").Default(""),
 		"health_check_http_code": validators.NewStringMultiChoicesValidator("health_check_http_code", api.LB_HEALTH_CHECK_HTTP_CODES).Sep(",").Default(api.LB_HEALTH_CHECK_HTTP_CODE_DEFAULT),
@@ -564,6 +624,10 @@ func (self *SKVMRegionDriver) ValidateCreateLoadbalancerListenerData(ctx context
 	if err := RunValidators(keyVHealth, data, false); err != nil {
 		return nil, err
 	}
+	checkType, _ := data.GetString("health_check_type")
+	if err := self.validateGrpcHealthCheck(listenerType, checkType, enableBackendHttp2V.Value); err != nil {
+		return nil, err
+	}
 
 	// acl check
 	if err := models.LoadbalancerListenerManager.ValidateAcl(aclStatusV, aclTypeV, aclV, data, api.CLOUD_PROVIDER_ONECLOUD); err != nil {
@@ -626,6 +690,9 @@ func (self *SKVMRegionDriver) ValidateUpdateLoadbalancerListenerData(ctx context
 		"scheduler":   validators.NewStringChoicesValidator("scheduler", api.LB_SCHEDULER_TYPES),
 		"egress_mbps": validators.NewRangeValidator("egress_mbps", api.LB_MbpsMin, api.LB_MbpsMax),
 
+		"max_conn":         validators.NewNonNegativeValidator("max_conn"),
+		"max_conn_per_src": validators.NewNonNegativeValidator("max_conn_per_src"),
+
 		"client_request_timeout":  validators.NewRangeValidator("client_request_timeout", 0, 600),
 		"client_idle_timeout":     validators.NewRangeValidator("client_idle_timeout", 0, 600),
 		"backend_connect_timeout": validators.NewRangeValidator("backend_connect_timeout", 0, 180),
@@ -637,7 +704,9 @@ func (self *SKVMRegionDriver) ValidateUpdateLoadbalancerListenerData(ctx context
 		"sticky_session_cookie_timeout": validators.NewNonNegativeValidator("sticky_session_cookie_timeout"),
 
 		"health_check":      validators.NewStringChoicesValidator("health_check", api.LB_BOOL_VALUES),
-		"health_check_type": models.LoadbalancerListenerManager.CheckTypeV(lblis.ListenerType),
+		"health_check_type": self.checkTypeV(lblis.ListenerType),
+
+		"health_check_grpc_service": validators.NewRegexpValidator("health_check_grpc_service", kvmGrpcServiceRegexp).AllowEmpty(true),
 
 		"health_check_domain":    validators.NewDomainNameValidator("health_check_domain").AllowEmpty(true),
 		"health_check_path":      validators.NewURLPathValidator("health_check_path"),
@@ -651,8 +720,12 @@ func (self *SKVMRegionDriver) ValidateUpdateLoadbalancerListenerData(ctx context
 		"x_forwarded_for": validators.NewBoolValidator("x_forwarded_for"),
 		"gzip":            validators.NewBoolValidator("gzip"),
 
+		"enable_backend_http2": validators.NewBoolValidator("enable_backend_http2"),
+
 		"http_request_rate":         validators.NewNonNegativeValidator("http_request_rate"),
 		"http_request_rate_per_src": validators.NewNonNegativeValidator("http_request_rate_per_src"),
+		"http_rate_limit_status":    validators.NewIntChoicesValidator("http_rate_limit_status", api.LB_RATE_LIMIT_STATUSES),
+		"http_rate_limit_body":      validators.NewRegexpValidator("http_rate_limit_body", kvmRateLimitBodyRegexp).AllowEmpty(true),
 
 		"certificate":       certV,
 		"tls_cipher_policy": tlsCipherPolicyV,
@@ -681,6 +754,19 @@ func (self *SKVMRegionDriver) ValidateUpdateLoadbalancerListenerData(ctx context
 			}
 		}
 	}
+	{
+		checkType := lblis.HealthCheckType
+		if v, err := data.GetString("health_check_type"); err == nil {
+			checkType = v
+		}
+		enableBackendHttp2 := lblis.EnableBackendHttp2
+		if data.Contains("enable_backend_http2") {
+			enableBackendHttp2 = jsonutils.QueryBoolean(data, "enable_backend_http2", false)
+		}
+		if err := self.validateGrpcHealthCheck(listenerType, checkType, enableBackendHttp2); err != nil {
+			return nil, err
+		}
+	}
 	// NOTE: it's okay we turn off redirect
 	//
 	//  - scheduler have default value on creation
@@ -1385,7 +1471,11 @@ func (self *SKVMRegionDriver) RequestPackInstanceBackup(ctx context.Context, ib
 	body := jsonutils.NewDict()
 	body.Set("package_name", jsonutils.NewString(packageName))
 	body.Set("backup_storage_id", jsonutils.NewString(backupStorage.GetId()))
-	body.Set("backup_storage_access_info", jsonutils.Marshal(backupStorage.AccessInfo))
+	accessInfo, err := backupStorage.GetAccessInfo()
+	if err != nil {
+		return errors.Wrap(err, "GetAccessInfo")
+	}
+	body.Set("backup_storage_access_info", accessInfo)
 	body.Set("backup_ids", jsonutils.Marshal(backupIds))
 	body.Set("metadata", jsonutils.Marshal(metadata))
 	header := task.GetTaskRequestHeader()
@@ -1411,7 +1501,11 @@ func (self *SKVMRegionDriver) RequestUnpackInstanceBackup(ctx context.Context, i
 	body := jsonutils.NewDict()
 	body.Set("package_name", jsonutils.NewString(packageName))
 	body.Set("backup_storage_id", jsonutils.NewString(backupStorage.GetId()))
-	body.Set("backup_storage_access_info", jsonutils.Marshal(backupStorage.AccessInfo))
+	accessInfo, err := backupStorage.GetAccessInfo()
+	if err != nil {
+		return errors.Wrap(err, "GetAccessInfo")
+	}
+	body.Set("backup_storage_access_info", accessInfo)
 	header := task.GetTaskRequestHeader()
 	_, _, err = httputils.JSONRequest(httputils.GetDefaultClient(), ctx, "POST", url, header, body, false)
 	if err != nil {
@@ -1429,7 +1523,11 @@ func (self *SKVMRegionDriver) RequestSyncBackupStorageStatus(ctx context.Context
 		url := fmt.Sprintf("%s/storages/sync-backup-storage", host.ManagerUri)
 		body := jsonutils.NewDict()
 		body.Set("backup_storage_id", jsonutils.NewString(bs.GetId()))
-		body.Set("backup_storage_access_info", jsonutils.Marshal(bs.AccessInfo))
+		accessInfo, err := bs.GetAccessInfo()
+		if err != nil {
+			return nil, errors.Wrap(err, "GetAccessInfo")
+		}
+		body.Set("backup_storage_access_info", accessInfo)
 		header := task.GetTaskRequestHeader()
 		_, res, err := httputils.JSONRequest(httputils.GetDefaultClient(), ctx, "POST", url, header, body, false)
 		if err != nil {
@@ -1437,6 +1535,16 @@ func (self *SKVMRegionDriver) RequestSyncBackupStorageStatus(ctx context.Context
 		}
 		status, _ := res.GetString("status")
 		reason, _ := res.GetString("reason")
+		if res.Contains("used_capacity_mb") {
+			usedMb, _ := res.Int("used_capacity_mb")
+			_, err = db.Update(bs, func() error {
+				bs.UsedCapacityMb = int(usedMb)
+				return nil
+			})
+			if err != nil {
+				return nil, errors.Wrap(err, "update used_capacity_mb")
+			}
+		}
 		return nil, bs.SetStatus(userCred, status, reason)
 	})
 	return nil
@@ -1498,7 +1606,11 @@ func (self *SKVMRegionDriver) RequestSyncDiskBackupStatus(ctx context.Context, u
 		body := jsonutils.NewDict()
 		body.Set("backup_id", jsonutils.NewString(backup.GetId()))
 		body.Set("backup_storage_id", jsonutils.NewString(backupStroage.GetId()))
-		body.Set("backup_storage_access_info", jsonutils.Marshal(backupStroage.AccessInfo))
+		accessInfo, err := backupStroage.GetAccessInfo()
+		if err != nil {
+			return nil, errors.Wrap(err, "GetAccessInfo")
+		}
+		body.Set("backup_storage_access_info", accessInfo)
 		header := task.GetTaskRequestHeader()
 		_, res, err := httputils.JSONRequest(httputils.GetDefaultClient(), ctx, "POST", url, header, body, false)
 		if err != nil {
@@ -1544,7 +1656,47 @@ func (self *SKVMRegionDriver) RequestSyncSnapshotStatus(ctx context.Context, use
 }
 
 func (self *SKVMRegionDriver) RequestAssociateEipForNAT(ctx context.Context, userCred mcclient.TokenCredential, nat *models.SNatGateway, eip *models.SElasticip, task taskman.ITask) error {
-	return errors.Wrapf(cloudprovider.ErrNotSupported, "RequestAssociateEipForNAT")
+	opts := api.ElasticipAssociateInput{
+		InstanceType: api.EIP_ASSOCIATE_TYPE_NAT_GATEWAY,
+		InstanceId:   nat.Id,
+	}
+	return eip.StartEipAssociateTask(ctx, userCred, jsonutils.Marshal(opts).(*jsonutils.JSONDict), task.GetTaskId())
+}
+
+func (self *SKVMRegionDriver) ValidateCreateNatGateway(ctx context.Context, userCred mcclient.TokenCredential, input api.NatgatewayCreateInput) (api.NatgatewayCreateInput, error) {
+	_vpc, err := models.VpcManager.FetchById(input.VpcId)
+	if err != nil {
+		return input, errors.Wrapf(err, "VpcManager.FetchById(%s)", input.VpcId)
+	}
+	vpc := _vpc.(*models.SVpc)
+	if vpc.Id == api.DEFAULT_VPC_ID {
+		return input, httperrors.NewInputParameterError("nat gateway is not supported in default vpc")
+	}
+	switch vpc.ExternalAccessMode {
+	case api.VPC_EXTERNAL_ACCESS_MODE_EIP, api.VPC_EXTERNAL_ACCESS_MODE_EIP_DISTGW:
+	default:
+		return input, httperrors.NewInputParameterError("vpc %s external access mode %s does not support eip", vpc.Name, vpc.ExternalAccessMode)
+	}
+	if len(input.Duration) > 0 {
+		return input, httperrors.NewInputParameterError("prepaid nat gateway is not supported")
+	}
+	return input, nil
+}
+
+func (self *SKVMRegionDriver) IsSupportedNatAutoRenew() bool {
+	return false
+}
+
+func (self *SKVMRegionDriver) RequestSyncNatGatewayStatus(ctx context.Context, userCred mcclient.TokenCredential, nat *models.SNatGateway, task taskman.ITask) error {
+	taskman.LocalTaskRun(task, func() (jsonutils.JSONObject, error) {
+		// ovn nat rules are programmed by vpcagent, which reports back
+		// the status of the nat gateway and its entries
+		if nat.Status != api.NAT_STAUTS_AVAILABLE {
+			return nil, nat.SetStatus(userCred, api.NAT_STATUS_DEPLOYING, "syncstatus")
+		}
+		return nil, nil
+	})
+	return nil
 }
 
 func (self *SKVMRegionDriver) RequestPreSnapshotPolicyApply(ctx context.Context, userCred mcclient.
@@ -1721,7 +1873,11 @@ func (self *SKVMRegionDriver) RequestDeleteBackup(ctx context.Context, backup *m
 	body := jsonutils.NewDict()
 	body.Set("backup_id", jsonutils.NewString(backup.GetId()))
 	body.Set("backup_storage_id", jsonutils.NewString(backupStroage.GetId()))
-	body.Set("backup_storage_access_info", jsonutils.Marshal(backupStroage.AccessInfo))
+	accessInfo, err := backupStroage.GetAccessInfo()
+	if err != nil {
+		return errors.Wrap(err, "GetAccessInfo")
+	}
+	body.Set("backup_storage_access_info", accessInfo)
 	header := task.GetTaskRequestHeader()
 	_, _, err = httputils.JSONRequest(httputils.GetDefaultClient(), ctx, "POST", url, header, body, false)
 	if err != nil {
@@ -1753,7 +1909,11 @@ func (self *SKVMRegionDriver) RequestCreateBackup(ctx context.Context, backup *m
 	body.Set("snapshot_id", jsonutils.NewString(snapshotId))
 	body.Set("backup_id", jsonutils.NewString(backup.GetId()))
 	body.Set("backup_storage_id", jsonutils.NewString(backupStroage.GetId()))
-	body.Set("backup_storage_access_info", jsonutils.Marshal(backupStroage.AccessInfo))
+	accessInfo, err := backupStroage.GetAccessInfo()
+	if err != nil {
+		return errors.Wrap(err, "GetAccessInfo")
+	}
+	body.Set("backup_storage_access_info", accessInfo)
 	header := task.GetTaskRequestHeader()
 	_, _, err = httputils.JSONRequest(httputils.GetDefaultClient(), ctx, "POST", url, header, body, false)
 	if err != nil {
@@ -1807,6 +1967,8 @@ func (self *SKVMRegionDriver) RequestAssociatEip(ctx context.Context, userCred m
 				return nil, errors.Wrapf(err, "set associated eip for guestnic %s (guest:%s, network:%s)",
 					guestnic.Ifname, guestnic.GuestId, guestnic.NetworkId)
 			}
+		} else if input.InstanceType == api.EIP_ASSOCIATE_TYPE_NAT_GATEWAY {
+			// snat/dnat entries of the nat gateway refer to the eip address
 		} else if input.InstanceType == api.EIP_ASSOCIATE_TYPE_INSTANCE_GROUP {
 			group := obj.(*models.SGroup)
 
@@ -119,6 +119,7 @@ func InitHandlers(app *appsrv.Application) {
 		models.DiskManager,
 		models.NetworkManager,
 		models.NetworkAddressManager,
+		models.SharedFilesystemManager,
 		models.ReservedipManager,
 		models.KeypairManager,
 		models.IsolatedDeviceManager,
@@ -150,6 +150,8 @@ func (self *EipDissociateTask) OnInit(ctx context.Context, obj db.IStandaloneMod
 						errs = append(errs, errors.Wrapf(err, "nic %s", groupnic.IpAddr))
 					}
 				}
+			case api.EIP_ASSOCIATE_TYPE_NAT_GATEWAY:
+				// nat rules refer to the eip address, nothing to clean
 			default:
 				errs = append(errs, errors.Wrapf(httperrors.ErrNotSupported, "not supported type %s", eip.AssociateType))
 			}
@@ -244,8 +244,13 @@ func (self *GuestChangeConfigTask) OnGuestChangeCpuMemSpecComplete(ctx context.C
 		}
 	}
 
-	addCpu := int(vcpuCount - int64(guest.VcpuCount))
-	addMem := int(vmemSize - int64(guest.VmemSize))
+	var addCpu, addMem int
+	if vcpuCount > 0 {
+		addCpu = int(vcpuCount - int64(guest.VcpuCount))
+	}
+	if vmemSize > 0 {
+		addMem = int(vmemSize - int64(guest.VmemSize))
+	}
 
 	_, err := db.Update(guest, func() error {
 		if vcpuCount > 0 {
@@ -257,6 +262,12 @@ func (self *GuestChangeConfigTask) OnGuestChangeCpuMemSpecComplete(ctx context.C
 		if len(instanceType) > 0 {
 			guest.InstanceType = instanceType
 		}
+		if secureBoot, err := self.Params.Bool("secure_boot"); err == nil {
+			guest.SecureBoot = secureBoot
+		}
+		if vtpm, err := self.Params.Bool("vtpm"); err == nil {
+			guest.Vtpm = vtpm
+		}
 		return nil
 	})
 	if err != nil {
@@ -243,6 +243,10 @@ func (self *GuestMigrateTask) OnSrcPrepareComplete(ctx context.Context, guest *m
 	if !jsonutils.QueryBoolean(self.Params, "is_rescue_mode", false) && (guestStatus == api.VM_RUNNING || guestStatus == api.VM_SUSPEND) {
 		body.Set("live_migrate", jsonutils.JSONTrue)
 	}
+	if data != nil && data.Contains("nvram") {
+		nvram, _ := data.Get("nvram")
+		body.Set("nvram", nvram)
+	}
 
 	headers := self.GetTaskRequestHeader()
 
@@ -58,6 +58,12 @@ func (self *InstanceBackupCreateTask) OnInit(ctx context.Context, obj db.IStanda
 	guest := models.GuestManager.FetchGuestById(ib.GuestId)
 	params := jsonutils.NewDict()
 	ib.SetStatus(self.GetUserCred(), compute.INSTANCE_BACKUP_STATUS_SNAPSHOT, "")
+	if guest.HasNvram() {
+		if err := ib.SaveNvram(ctx, self.GetUserCred(), guest); err != nil {
+			self.taskFailed(ctx, ib, guest, jsonutils.NewString(err.Error()), compute.INSTANCE_BACKUP_STATUS_SNAPSHOT_FAILED)
+			return
+		}
+	}
 	if err := ib.GetRegionDriver().RequestCreateInstanceBackup(ctx, guest, ib, self, params); err != nil {
 		self.taskFailed(ctx, ib, guest, jsonutils.NewString(err.Error()), compute.INSTANCE_BACKUP_STATUS_SNAPSHOT_FAILED)
 	}
@@ -0,0 +1,72 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"context"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/cloudcommon/notifyclient"
+	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+)
+
+type LoadbalancerCertificateAcmeIssueTask struct {
+	taskman.STask
+}
+
+func init() {
+	taskman.RegisterTask(LoadbalancerCertificateAcmeIssueTask{})
+}
+
+func (self *LoadbalancerCertificateAcmeIssueTask) taskFail(ctx context.Context, lbcert *models.SLoadbalancerCertificate, reason jsonutils.JSONObject) {
+	lbcert.SetStatus(self.GetUserCred(), api.LB_CERT_STATUS_ISSUE_FAILED, reason.String())
+	if err := lbcert.RecordAcmeIssueResult(false); err != nil {
+		log.Errorf("record acme issue failure of %s: %v", lbcert.Name, err)
+	}
+	db.OpsLog.LogEvent(lbcert, db.ACT_RENEW, reason, self.UserCred)
+	logclient.AddActionLogWithStartable(self, lbcert, logclient.ACT_RENEW, reason, self.UserCred, false)
+	notifyclient.NotifySystemErrorWithCtx(ctx, lbcert.Id, lbcert.Name, api.LB_CERT_STATUS_ISSUE_FAILED, reason.String())
+	self.SetStageFailed(ctx, reason)
+}
+
+func (self *LoadbalancerCertificateAcmeIssueTask) OnInit(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	lbcert := obj.(*models.SLoadbalancerCertificate)
+	self.SetStage("OnAcmeIssueComplete", nil)
+	taskman.LocalTaskRun(self, func() (jsonutils.JSONObject, error) {
+		return nil, lbcert.AcmeIssue(ctx, self.GetUserCred())
+	})
+}
+
+func (self *LoadbalancerCertificateAcmeIssueTask) OnAcmeIssueComplete(ctx context.Context, lbcert *models.SLoadbalancerCertificate, data jsonutils.JSONObject) {
+	lbcert.SetStatus(self.GetUserCred(), api.LB_STATUS_ENABLED, "certificate issued")
+	if err := lbcert.RecordAcmeIssueResult(true); err != nil {
+		log.Errorf("record acme issue success of %s: %v", lbcert.Name, err)
+	}
+	if err := lbcert.RolloutCachedCertificates(ctx, self.GetUserCred()); err != nil {
+		log.Errorf("rollout renewed certificate %s: %v", lbcert.Name, err)
+	}
+	logclient.AddActionLogWithStartable(self, lbcert, logclient.ACT_RENEW, nil, self.UserCred, true)
+	self.SetStageComplete(ctx, nil)
+}
+
+func (self *LoadbalancerCertificateAcmeIssueTask) OnAcmeIssueCompleteFailed(ctx context.Context, lbcert *models.SLoadbalancerCertificate, reason jsonutils.JSONObject) {
+	self.taskFail(ctx, lbcert, reason)
+}
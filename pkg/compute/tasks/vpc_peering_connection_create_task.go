@@ -58,6 +58,18 @@ func (self *VpcPeeringConnectionCreateTask) OnInit(ctx context.Context, obj db.I
 		return
 	}
 
+	if !vpc.IsManaged() {
+		// routers of both vpcs are linked by vpcagent
+		err = peer.AllocateLinkCidr(ctx, self.GetUserCred())
+		if err != nil {
+			self.taskFailed(ctx, peer, errors.Wrapf(err, "AllocateLinkCidr"))
+			return
+		}
+		peer.SetStatus(self.GetUserCred(), api.VPC_PEERING_CONNECTION_STATUS_ACTIVE, "")
+		self.taskComplete(ctx, peer)
+		return
+	}
+
 	iVpc, err := vpc.GetIVpc(ctx)
 	if err != nil {
 		self.taskFailed(ctx, peer, errors.Wrapf(err, "GetIVpc"))
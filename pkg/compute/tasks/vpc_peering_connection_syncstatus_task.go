@@ -51,6 +51,16 @@ func (self *VpcPeeringConnectionSyncstatusTask) OnInit(ctx context.Context, obj
 		return
 	}
 
+	if !svpc.IsManaged() {
+		if len(peer.LinkCidr) > 0 {
+			peer.SetStatus(self.UserCred, api.VPC_PEERING_CONNECTION_STATUS_ACTIVE, "")
+		} else {
+			peer.SetStatus(self.UserCred, api.VPC_PEERING_CONNECTION_STATUS_UNKNOWN, "no link allocated")
+		}
+		self.SetStageComplete(ctx, nil)
+		return
+	}
+
 	extVpc, err := svpc.GetIVpc(ctx)
 	if err != nil {
 		self.taskFail(ctx, peer, errors.Wrap(err, "svpc.GetIVpc()"))
@@ -130,6 +130,9 @@ func DoDeployGuestFs(rootfs fsdriver.IRootFsDriver, guestDesc *deployapi.GuestDe
 			return nil, fmt.Errorf("DeployFstabScripts: %v", err)
 		}
 	}
+	if err = rootfs.DeploySharedfsFstab(partition, guestDesc.Sharedfs); err != nil {
+		return nil, fmt.Errorf("DeploySharedfsFstab: %v", err)
+	}
 
 	if len(deployInfo.Password) > 0 {
 		account, err := rootfs.GetLoginAccount(partition, deployInfo.LoginAccount,
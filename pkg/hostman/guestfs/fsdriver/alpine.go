@@ -0,0 +1,325 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fsdriver
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+
+	"yunion.io/x/onecloud/pkg/apis"
+	"yunion.io/x/onecloud/pkg/cloudcommon/types"
+	deployapi "yunion.io/x/onecloud/pkg/hostman/hostdeployer/apis"
+	"yunion.io/x/onecloud/pkg/util/netutils2"
+	"yunion.io/x/onecloud/pkg/util/procutils"
+	"yunion.io/x/onecloud/pkg/util/seclib2"
+)
+
+// SAlpineRootFs supports Alpine Linux, which configures network by
+// /etc/network/interfaces and starts services by OpenRC. Busybox based
+// images may lack shadow utils, so accounts are edited in files directly.
+type SAlpineRootFs struct {
+	*sLinuxRootFs
+}
+
+func NewAlpineRootFs(part IDiskPartition) IRootFsDriver {
+	return &SAlpineRootFs{sLinuxRootFs: newLinuxRootFs(part)}
+}
+
+func (d *SAlpineRootFs) GetName() string {
+	return "Alpine"
+}
+
+func (d *SAlpineRootFs) String() string {
+	return "AlpineRootFs"
+}
+
+func (d *SAlpineRootFs) RootSignatures() []string {
+	return []string{"/etc/alpine-release", "/bin", "/etc", "/lib", "/usr"}
+}
+
+func (d *SAlpineRootFs) GetReleaseInfo(rootFs IDiskPartition) *deployapi.ReleaseInfo {
+	ver, _ := rootFs.FileGetContents("/etc/alpine-release", false)
+	return deployapi.NewReleaseInfo(d.GetName(), strings.TrimSpace(string(ver)), d.GetArch(rootFs))
+}
+
+// GetArch detects arch by the name of musl dynamic linker, e.g. /lib/ld-musl-x86_64.so.1
+func (d *SAlpineRootFs) GetArch(rootFs IDiskPartition) string {
+	for _, f := range rootFs.ListDir("/lib", false) {
+		if !strings.HasPrefix(f, "ld-musl-") {
+			continue
+		}
+		switch {
+		case strings.Contains(f, apis.OS_ARCH_AARCH64):
+			return apis.OS_ARCH_AARCH64
+		case strings.Contains(f, apis.OS_ARCH_X86_64):
+			return apis.OS_ARCH_X86_64
+		case strings.Contains(f, "arm"):
+			return apis.OS_ARCH_AARCH32
+		case strings.Contains(f, "x86"):
+			return apis.OS_ARCH_X86_32
+		}
+	}
+	return d.sLinuxRootFs.GetArch(rootFs)
+}
+
+func (d *SAlpineRootFs) DeployHostname(rootFs IDiskPartition, hn, domain string) error {
+	return rootFs.FilePutContents("/etc/hostname", hn+"\n", false, false)
+}
+
+func (d *SAlpineRootFs) DeployNetworkingScripts(rootFs IDiskPartition, nics []*types.SServerNic) error {
+	if err := d.sLinuxRootFs.DeployNetworkingScripts(rootFs, nics); err != nil {
+		return err
+	}
+	allNics, _ := convertNicConfigs(nics)
+	mainNic, err := getMainNic(allNics)
+	if err != nil {
+		return err
+	}
+	var mainIp string
+	if mainNic != nil {
+		mainIp = mainNic.Ip
+	}
+
+	var cmds strings.Builder
+	cmds.WriteString("auto lo\n")
+	cmds.WriteString("iface lo inet loopback\n\n")
+	dnss := []string{}
+	domains := []string{}
+	for i := range allNics {
+		nicDesc := allNics[i]
+		cmds.WriteString(fmt.Sprintf("auto %s\n", nicDesc.Name))
+		if nicDesc.TeamingMaster != nil {
+			cmds.WriteString(fmt.Sprintf("iface %s inet manual\n", nicDesc.Name))
+			cmds.WriteString("\n")
+			continue
+		}
+		if nicDesc.Virtual {
+			cmds.WriteString(fmt.Sprintf("iface %s inet static\n", nicDesc.Name))
+			cmds.WriteString(fmt.Sprintf("    address %s\n", netutils2.PSEUDO_VIP))
+			cmds.WriteString("    netmask 255.255.255.255\n")
+		} else if nicDesc.Manual {
+			cmds.WriteString(fmt.Sprintf("iface %s inet static\n", nicDesc.Name))
+			cmds.WriteString(fmt.Sprintf("    address %s\n", nicDesc.Ip))
+			cmds.WriteString(fmt.Sprintf("    netmask %s\n", netutils2.Netlen2Mask(int(nicDesc.Masklen))))
+			if len(nicDesc.Gateway) > 0 && nicDesc.Ip == mainIp {
+				cmds.WriteString(fmt.Sprintf("    gateway %s\n", nicDesc.Gateway))
+			}
+			var routes = make([][]string, 0)
+			netutils2.AddNicRoutes(&routes, nicDesc, mainIp, len(nics), privatePrefixes)
+			for _, r := range routes {
+				cmds.WriteString(fmt.Sprintf("    up ip route add %s via %s dev %s || true\n", r[0], r[1], nicDesc.Name))
+			}
+			dnslist := netutils2.GetNicDns(nicDesc)
+			dnss = append(dnss, dnslist...)
+			if len(dnslist) > 0 && len(nicDesc.Domain) > 0 {
+				domains = append(domains, nicDesc.Domain)
+			}
+		} else {
+			cmds.WriteString(fmt.Sprintf("iface %s inet dhcp\n", nicDesc.Name))
+		}
+		if nicDesc.Mtu > 0 {
+			cmds.WriteString(fmt.Sprintf("    mtu %d\n", nicDesc.Mtu))
+		}
+		if len(nicDesc.TeamingSlaves) > 0 {
+			slaves := make([]string, len(nicDesc.TeamingSlaves))
+			for j := range nicDesc.TeamingSlaves {
+				slaves[j] = nicDesc.TeamingSlaves[j].Name
+			}
+			cmds.WriteString("    use bond\n")
+			cmds.WriteString(fmt.Sprintf("    bond-members %s\n", strings.Join(slaves, " ")))
+			cmds.WriteString("    bond-mode 802.3ad\n")
+			cmds.WriteString("    bond-miimon 100\n")
+		}
+		cmds.WriteString("\n")
+	}
+	if err := rootFs.FilePutContents("/etc/network/interfaces", cmds.String(), false, false); err != nil {
+		return errors.Wrap(err, "write /etc/network/interfaces")
+	}
+	if len(dnss) > 0 {
+		var resolv strings.Builder
+		if len(domains) > 0 {
+			resolv.WriteString(fmt.Sprintf("search %s\n", strings.Join(domains, " ")))
+		}
+		for _, dns := range dnss {
+			resolv.WriteString(fmt.Sprintf("nameserver %s\n", dns))
+		}
+		if err := rootFs.FilePutContents("/etc/resolv.conf", resolv.String(), false, false); err != nil {
+			return errors.Wrap(err, "write /etc/resolv.conf")
+		}
+	}
+	d.enableOpenRCService(rootFs, "networking", "boot")
+	return nil
+}
+
+// enableOpenRCService works like rc-update add, which links the init script into runlevel dir
+func (d *SAlpineRootFs) enableOpenRCService(rootFs IDiskPartition, service, runlevel string) {
+	initScript := path.Join("/etc/init.d", service)
+	runlevelDir := path.Join("/etc/runlevels", runlevel)
+	if !rootFs.Exists(initScript, false) || !rootFs.Exists(runlevelDir, false) {
+		return
+	}
+	if rootFs.Exists(path.Join(runlevelDir, service), false) {
+		return
+	}
+	link := path.Join(rootFs.GetLocalPath(runlevelDir, false), service)
+	if output, err := procutils.NewCommand("ln", "-s", initScript, link).Output(); err != nil {
+		log.Errorf("enable openrc service %s: %s %s", service, err, output)
+	}
+}
+
+func (d *SAlpineRootFs) hasShadowUtils(rootFs IDiskPartition) bool {
+	return rootFs.Exists("/usr/sbin/useradd", false)
+}
+
+// addUser adds account in /etc/passwd, /etc/shadow and /etc/group, returns home dir of user
+func (d *SAlpineRootFs) addUser(rootFs IDiskPartition, user, homeDir string, isSys bool) (string, error) {
+	passwd, err := loadPasswdFile(rootFs, "/etc/passwd")
+	if err != nil {
+		return "", err
+	}
+	if entry := passwd.find(user); entry != nil {
+		if len(entry) > 5 {
+			return entry[5], nil
+		}
+		return "", errors.Errorf("invalid passwd entry of %s", user)
+	}
+	group, err := loadPasswdFile(rootFs, "/etc/group")
+	if err != nil {
+		return "", err
+	}
+	shadow, err := loadPasswdFile(rootFs, "/etc/shadow")
+	if err != nil {
+		return "", err
+	}
+	startId := 1000
+	if isSys {
+		startId = 100
+	}
+	uid := passwd.nextId(2, startId)
+	gid := uid
+	if group.find(user) == nil {
+		gid = group.nextId(2, uid)
+		group.add(user, "x", strconv.Itoa(gid), "")
+	} else {
+		gid, _ = strconv.Atoi(group.find(user)[2])
+	}
+	if len(homeDir) == 0 {
+		homeDir = "/home"
+	}
+	home := path.Join(homeDir, user)
+	passwd.add(user, "x", strconv.Itoa(uid), strconv.Itoa(gid), "", home, "/bin/sh")
+	// * means no password login, but the account is not locked for ssh key login
+	shadow.add(user, "*", "0", "0", "99999", "7", "", "", "")
+
+	if err := passwd.save(rootFs, "/etc/passwd", 0644); err != nil {
+		return "", err
+	}
+	if err := group.save(rootFs, "/etc/group", 0644); err != nil {
+		return "", err
+	}
+	if err := shadow.save(rootFs, "/etc/shadow", 0640); err != nil {
+		return "", err
+	}
+	if !rootFs.Exists(home, false) {
+		if err := rootFs.Mkdir(home, syscall.S_IRWXU|syscall.S_IRGRP|syscall.S_IXGRP, false); err != nil {
+			return "", errors.Wrapf(err, "mkdir %s", home)
+		}
+	}
+	if err := rootFs.Chown(home, uid, gid, false); err != nil {
+		return "", errors.Wrapf(err, "chown %s", home)
+	}
+	return home, nil
+}
+
+// enableUserSudo grants root privilege by sudo or doas, which is the default on alpine
+func (d *SAlpineRootFs) enableUserSudo(rootFs IDiskPartition, user string) error {
+	if err := d.EnableUserSudo(rootFs, user); err != nil {
+		return err
+	}
+	doasDir := "/etc/doas.d"
+	if rootFs.Exists(doasDir, false) {
+		spath := path.Join(doasDir, fmt.Sprintf("%s.conf", user))
+		if err := rootFs.FilePutContents(spath, fmt.Sprintf("permit nopass %s as root\n", user), false, false); err != nil {
+			return errors.Wrapf(err, "write %s", spath)
+		}
+		return rootFs.Chmod(spath, syscall.S_IRUSR|syscall.S_IRGRP, false)
+	}
+	return nil
+}
+
+func (d *SAlpineRootFs) GetLoginAccount(rootFs IDiskPartition, sUser string, defaultRootUser bool, windowsDefaultAdminUser bool) (string, error) {
+	if len(sUser) == 0 || d.hasShadowUtils(rootFs) {
+		return d.sLinuxRootFs.GetLoginAccount(rootFs, sUser, defaultRootUser, windowsDefaultAdminUser)
+	}
+	if _, err := d.addUser(rootFs, sUser, "", false); err != nil {
+		return "", errors.Wrapf(err, "add user %s", sUser)
+	}
+	if err := d.enableUserSudo(rootFs, sUser); err != nil {
+		return "", errors.Wrap(err, "enableUserSudo")
+	}
+	return sUser, nil
+}
+
+func (d *SAlpineRootFs) ChangeUserPasswd(rootFs IDiskPartition, account, gid, publicKey, password string) (string, error) {
+	hash, err := seclib2.GeneratePassword(password)
+	if err != nil {
+		return "", errors.Wrap(err, "GeneratePassword")
+	}
+	shadow, err := loadPasswdFile(rootFs, "/etc/shadow")
+	if err != nil {
+		return "", err
+	}
+	if !shadow.setField(account, 1, hash) {
+		return "", errors.Errorf("user %s not found in /etc/shadow", account)
+	}
+	if err := shadow.save(rootFs, "/etc/shadow", 0640); err != nil {
+		return "", err
+	}
+	if len(publicKey) > 0 {
+		return seclib2.EncryptBase64(publicKey, password)
+	}
+	return utils.EncryptAESBase64(gid, password)
+}
+
+func (d *SAlpineRootFs) DeployYunionroot(rootFs IDiskPartition, pubkeys *deployapi.SSHKeys, isInit, enableCloudInit bool) error {
+	if d.hasShadowUtils(rootFs) {
+		if err := d.sLinuxRootFs.DeployYunionroot(rootFs, pubkeys, isInit, enableCloudInit); err != nil {
+			return err
+		}
+	} else {
+		if !enableCloudInit && isInit {
+			d.DisableCloudinit(rootFs)
+		}
+		home, err := d.addUser(rootFs, YUNIONROOT_USER, cloudrootDirectory, true)
+		if err != nil {
+			return errors.Wrapf(err, "add user %s", YUNIONROOT_USER)
+		}
+		if err := DeployAuthorizedKeys(rootFs, home, pubkeys, true); err != nil {
+			return errors.Wrap(err, "DeployAuthorizedKeys")
+		}
+	}
+	if err := d.enableUserSudo(rootFs, YUNIONROOT_USER); err != nil {
+		return errors.Wrap(err, "enableUserSudo")
+	}
+	d.enableOpenRCService(rootFs, "sshd", "default")
+	return nil
+}
@@ -102,6 +102,10 @@ func (d *sGuestRootFsDriver) DeployFstabScripts(_ IDiskPartition, _ []*deployapi
 	return nil
 }
 
+func (d *sGuestRootFsDriver) DeploySharedfsFstab(_ IDiskPartition, _ []*deployapi.SharedFs) error {
+	return nil
+}
+
 func (d *sGuestRootFsDriver) EnableSerialConsole(rootfs IDiskPartition, sysInfo *jsonutils.JSONDict) error {
 	return nil
 }
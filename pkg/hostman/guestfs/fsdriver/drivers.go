@@ -35,31 +35,38 @@ func GetRootfsDrivers() []newRootFsDriverFunc {
 	return rootfsDrivers
 }
 
-func Init(initPrivatePrefixes []string, cloudrootDir string) error {
-	if len(initPrivatePrefixes) > 0 {
-		privatePrefixes = make([]string, len(initPrivatePrefixes))
-		copy(privatePrefixes, initPrivatePrefixes)
-	}
-
+// newRootfsDrivers returns the root fs drivers in the order of detection
+func newRootfsDrivers() []newRootFsDriverFunc {
 	linuxFsDrivers := []newRootFsDriverFunc{
 		NewFangdeRootFs,
 		NewCentosRootFs, NewFedoraRootFs, NewAnolisRootFs, NewGalaxyKylinRootFs, NewRhelRootFs,
-		NewFangdeDeskRootfs, NewUKylinRootfs,
+		NewFangdeDeskRootfs, NewUKylinRootfs, NewSuseRootFs, NewAlpineRootFs,
 		NewDebianRootFs, NewCirrosRootFs, NewCirrosNewRootFs, NewUbuntuRootFs,
 		NewGentooRootFs, NewArchLinuxRootFs, NewOpenWrtRootFs, NewCoreOsRootFs,
 		NewOpenEulerRootFs,
 	}
-	rootfsDrivers = append(rootfsDrivers, linuxFsDrivers...)
-	rootfsDrivers = append(rootfsDrivers, NewMacOSRootFs)
-	rootfsDrivers = append(rootfsDrivers, NewEsxiRootFs)
-	rootfsDrivers = append(rootfsDrivers, NewWindowsRootFs)
+	drivers := make([]newRootFsDriverFunc, 0)
+	drivers = append(drivers, linuxFsDrivers...)
+	drivers = append(drivers, NewFreeBSDRootFs)
+	drivers = append(drivers, NewMacOSRootFs)
+	drivers = append(drivers, NewEsxiRootFs)
+	drivers = append(drivers, NewWindowsRootFs)
 
 	androidFsDrivers := []newRootFsDriverFunc{
 		NewAndroidRootFs,
 		NewPhoenixOSRootFs,
 	}
+	drivers = append(drivers, androidFsDrivers...)
+	return drivers
+}
+
+func Init(initPrivatePrefixes []string, cloudrootDir string) error {
+	if len(initPrivatePrefixes) > 0 {
+		privatePrefixes = make([]string, len(initPrivatePrefixes))
+		copy(privatePrefixes, initPrivatePrefixes)
+	}
 
-	rootfsDrivers = append(rootfsDrivers, androidFsDrivers...)
+	rootfsDrivers = append(rootfsDrivers, newRootfsDrivers()...)
 
 	cpuArch, err := procutils.NewCommand("uname", "-m").Output()
 	if err != nil {
@@ -0,0 +1,301 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fsdriver
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"yunion.io/x/onecloud/pkg/cloudcommon/types"
+	deployapi "yunion.io/x/onecloud/pkg/hostman/hostdeployer/apis"
+	"yunion.io/x/onecloud/pkg/util/seclib2"
+)
+
+var (
+	suseFiles = map[string]string{
+		"/bin":                              "<dir>",
+		"/boot":                             "<dir>",
+		"/lib":                              "<dir>",
+		"/usr":                              "<dir>",
+		"/etc/hostname":                     "localhost\n",
+		"/etc/os-release":                   "NAME=\"openSUSE Leap\"\nVERSION_ID=\"15.4\"\nID=\"opensuse-leap\"\n",
+		"/etc/sysconfig/network/config":     "NETCONFIG_DNS_STATIC_SERVERS=\"\"\nNETCONFIG_DNS_POLICY=\"auto\"\n",
+		"/etc/sysconfig/network/ifcfg-lo":   "STARTMODE=nfsroot\n",
+		"/etc/sysconfig/network/ifcfg-eth9": "BOOTPROTO='dhcp'\n",
+	}
+	alpineFiles = map[string]string{
+		"/bin":                     "<dir>",
+		"/boot":                    "<dir>",
+		"/usr":                     "<dir>",
+		"/lib/ld-musl-x86_64.so.1": "",
+		"/etc/hostname":            "localhost\n",
+		"/etc/alpine-release":      "3.18.4\n",
+		"/etc/network/interfaces":  "",
+		"/etc/init.d/networking":   "",
+		"/etc/runlevels/boot":      "<dir>",
+		"/etc/passwd":              "root:x:0:0:root:/root:/bin/ash\n",
+		"/etc/shadow":              "root:!::0:::::\n",
+		"/etc/group":               "root:x:0:root\n",
+		"/root":                    "<dir>",
+	}
+	freebsdFiles = map[string]string{
+		"/bin/freebsd-version": "#!/bin/sh\nUSERLAND_VERSION=\"13.2-RELEASE\"\n",
+		"/boot/kernel":         "<dir>",
+		"/lib":                 "<dir>",
+		"/usr/home":            "<dir>",
+		"/etc/rc.conf":         "hostname=\"freebsd\"\nifconfig_vtnet0=\"DHCP\"\nsshd_enable=\"YES\"\n",
+		"/etc/master.passwd":   "root::0:0::0:0:Charlie &:/root:/bin/csh\n",
+		"/etc/group":           "wheel:*:0:root\n",
+		"/etc/rc.d":            "<dir>",
+		"/root":                "<dir>",
+	}
+)
+
+// detectTestRootFs detects root fs of part the same way as
+// guestfs.DetectRootFs, which can't be imported here
+func detectTestRootFs(part IDiskPartition) IRootFsDriver {
+	for _, newDriver := range newRootfsDrivers() {
+		d := newDriver(part)
+		caseInsensitive := d.IsFsCaseInsensitive()
+		match := true
+		for _, sig := range d.RootSignatures() {
+			if !part.Exists(sig, caseInsensitive) {
+				match = false
+				break
+			}
+		}
+		for _, sig := range d.RootExcludeSignatures() {
+			if part.Exists(sig, caseInsensitive) {
+				match = false
+				break
+			}
+		}
+		if match {
+			return d
+		}
+	}
+	return nil
+}
+
+func TestDetectRootFs(t *testing.T) {
+	cases := []struct {
+		files   map[string]string
+		want    string
+		distro  string
+		version string
+	}{
+		{suseFiles, "SuseRootFs", "openSUSE", "15.4"},
+		{alpineFiles, "AlpineRootFs", "Alpine", "3.18.4"},
+		{freebsdFiles, "FreeBSDRootFs", "FreeBSD", "13.2-RELEASE"},
+	}
+	for _, c := range cases {
+		t.Run(c.want, func(t *testing.T) {
+			part := newTestPartition(t, c.files)
+			defer part.cleanup()
+			d := detectTestRootFs(part)
+			if d == nil {
+				t.Fatalf("no driver detected, want %s", c.want)
+			}
+			if d.String() != c.want {
+				t.Fatalf("detected %s, want %s", d.String(), c.want)
+			}
+			info := d.GetReleaseInfo(part)
+			if info.Distro != c.distro || info.Version != c.version {
+				t.Errorf("release info %s %s, want %s %s", info.Distro, info.Version, c.distro, c.version)
+			}
+		})
+	}
+}
+
+func testNics() []*types.SServerNic {
+	return []*types.SServerNic{
+		{
+			Name:    "eth0",
+			Index:   0,
+			Mac:     "00:22:00:00:00:01",
+			Ip:      "192.168.1.10",
+			Masklen: 24,
+			Gateway: "192.168.1.1",
+			Dns:     "114.114.114.114",
+			Domain:  "cloud.local",
+			Manual:  true,
+			Mtu:     1450,
+		},
+		{
+			Name:  "eth1",
+			Index: 1,
+			Mac:   "00:22:00:00:00:02",
+			Ip:    "10.0.0.10",
+		},
+	}
+}
+
+func TestSuseDeploy(t *testing.T) {
+	part := newTestPartition(t, suseFiles)
+	defer part.cleanup()
+	d := NewSuseRootFs(part)
+	if err := d.CleanNetworkScripts(part); err != nil {
+		t.Fatalf("CleanNetworkScripts: %s", err)
+	}
+	if part.Exists("/etc/sysconfig/network/ifcfg-eth9", false) || !part.Exists("/etc/sysconfig/network/ifcfg-lo", false) {
+		t.Errorf("CleanNetworkScripts should remove ifcfg-eth9 and keep ifcfg-lo")
+	}
+	if err := d.DeployHostname(part, "vm1", "cloud.local"); err != nil {
+		t.Fatalf("DeployHostname: %s", err)
+	}
+	if got := part.mustGet(t, "/etc/hostname"); got != "vm1" {
+		t.Errorf("hostname = %q", got)
+	}
+	if err := d.DeployNetworkingScripts(part, testNics()); err != nil {
+		t.Fatalf("DeployNetworkingScripts: %s", err)
+	}
+	eth0 := part.mustGet(t, "/etc/sysconfig/network/ifcfg-eth0")
+	for _, line := range []string{"STARTMODE='auto'", "BOOTPROTO='static'", "IPADDR='192.168.1.10/24'", "MTU='1450'"} {
+		if !strings.Contains(eth0, line) {
+			t.Errorf("ifcfg-eth0 missing %s:\n%s", line, eth0)
+		}
+	}
+	if eth1 := part.mustGet(t, "/etc/sysconfig/network/ifcfg-eth1"); !strings.Contains(eth1, "BOOTPROTO='dhcp'") {
+		t.Errorf("ifcfg-eth1 should be dhcp:\n%s", eth1)
+	}
+	if routes := part.mustGet(t, "/etc/sysconfig/network/routes"); routes != "default 192.168.1.1 - eth0\n" {
+		t.Errorf("routes = %q", routes)
+	}
+	conf := parseShellVars(part.mustGet(t, "/etc/sysconfig/network/config"))
+	if conf["NETCONFIG_DNS_STATIC_SERVERS"] != "114.114.114.114" || conf["NETCONFIG_DNS_STATIC_SEARCHLIST"] != "cloud.local" {
+		t.Errorf("network config = %v", conf)
+	}
+	if conf["NETCONFIG_DNS_POLICY"] != "auto" {
+		t.Errorf("existing network config should be kept: %v", conf)
+	}
+}
+
+func TestAlpineDeploy(t *testing.T) {
+	part := newTestPartition(t, alpineFiles)
+	defer part.cleanup()
+	d := NewAlpineRootFs(part)
+	if err := d.DeployNetworkingScripts(part, testNics()); err != nil {
+		t.Fatalf("DeployNetworkingScripts: %s", err)
+	}
+	ifaces := part.mustGet(t, "/etc/network/interfaces")
+	for _, line := range []string{
+		"iface eth0 inet static",
+		"    address 192.168.1.10",
+		"    netmask 255.255.255.0",
+		"    gateway 192.168.1.1",
+		"    mtu 1450",
+		"iface eth1 inet dhcp",
+	} {
+		if !strings.Contains(ifaces, line+"\n") {
+			t.Errorf("interfaces missing %q:\n%s", line, ifaces)
+		}
+	}
+	if resolv := part.mustGet(t, "/etc/resolv.conf"); resolv != "search cloud.local\nnameserver 114.114.114.114\n" {
+		t.Errorf("resolv.conf = %q", resolv)
+	}
+	// the service link points to a guest path, so check the link itself
+	if _, err := os.Lstat(filepath.Join(part.root, "/etc/runlevels/boot/networking")); err != nil {
+		t.Errorf("networking service is not enabled: %s", err)
+	}
+	if arch := d.GetReleaseInfo(part).Arch; arch != "x86_64" {
+		t.Errorf("arch = %s", arch)
+	}
+
+	account, err := d.GetLoginAccount(part, "alpine", false, false)
+	if err != nil || account != "alpine" {
+		t.Fatalf("GetLoginAccount = %s, %v", account, err)
+	}
+	if _, err := d.ChangeUserPasswd(part, "alpine", "gid", "", "Passw0rd"); err != nil {
+		t.Fatalf("ChangeUserPasswd: %s", err)
+	}
+	shadow := parsePasswdFile(part.mustGet(t, "/etc/shadow"))
+	entry := shadow.find("alpine")
+	if entry == nil {
+		t.Fatalf("user alpine not in shadow")
+	}
+	if err := seclib2.VerifyPassword("Passw0rd", entry[1]); err != nil {
+		t.Errorf("password hash mismatch: %s", err)
+	}
+	passwd := parsePasswdFile(part.mustGet(t, "/etc/passwd"))
+	if entry := passwd.find("alpine"); entry == nil || entry[2] != "1000" || entry[5] != "/home/alpine" {
+		t.Errorf("passwd entry of alpine = %v", entry)
+	}
+	if !part.Exists("/home/alpine", false) {
+		t.Errorf("home of alpine not created")
+	}
+	if err := d.DeployPublicKey(part, "alpine", &deployapi.SSHKeys{PublicKey: "ssh-rsa AAAA test"}); err != nil {
+		t.Fatalf("DeployPublicKey: %s", err)
+	}
+	if keys := part.mustGet(t, "/home/alpine/.ssh/authorized_keys"); !strings.Contains(keys, "ssh-rsa AAAA test") {
+		t.Errorf("authorized_keys = %q", keys)
+	}
+}
+
+func TestFreeBSDDeploy(t *testing.T) {
+	part := newTestPartition(t, freebsdFiles)
+	defer part.cleanup()
+	d := NewFreeBSDRootFs(part)
+	if err := d.DeployHostname(part, "vm1", "cloud.local"); err != nil {
+		t.Fatalf("DeployHostname: %s", err)
+	}
+	nics := testNics()
+	nics[1].Driver = "e1000"
+	if err := d.DeployNetworkingScripts(part, nics); err != nil {
+		t.Fatalf("DeployNetworkingScripts: %s", err)
+	}
+	account, err := d.GetLoginAccount(part, "", true, false)
+	if err != nil || account != "root" {
+		t.Fatalf("GetLoginAccount = %s, %v", account, err)
+	}
+	if _, err := d.ChangeUserPasswd(part, "root", "gid", "", "Passw0rd"); err != nil {
+		t.Fatalf("ChangeUserPasswd: %s", err)
+	}
+	if err := d.DeployYunionroot(part, &deployapi.SSHKeys{PublicKey: "ssh-rsa AAAA test"}, true, false); err != nil {
+		t.Fatalf("DeployYunionroot: %s", err)
+	}
+	if err := d.CommitChanges(part); err != nil {
+		t.Fatalf("CommitChanges: %s", err)
+	}
+
+	rc := parseShellVars(part.mustGet(t, "/etc/rc.conf"))
+	want := map[string]string{
+		"hostname":        "vm1.cloud.local",
+		"ifconfig_vtnet0": "inet 192.168.1.10 netmask 255.255.255.0 mtu 1450",
+		"ifconfig_em0":    "DHCP",
+		"defaultrouter":   "192.168.1.1",
+		"sshd_enable":     "YES",
+		"growfs_enable":   "YES",
+	}
+	for k, v := range want {
+		if rc[k] != v {
+			t.Errorf("rc.conf %s = %q, want %q", k, rc[k], v)
+		}
+	}
+	master := parsePasswdFile(part.mustGet(t, "/etc/master.passwd"))
+	if err := seclib2.VerifyPassword("Passw0rd", master.find("root")[1]); err != nil {
+		t.Errorf("root password hash mismatch: %s", err)
+	}
+	if entry := master.find(YUNIONROOT_USER); entry == nil || len(entry) != 10 {
+		t.Errorf("cloudroot entry = %v", entry)
+	}
+	if group := parsePasswdFile(part.mustGet(t, "/etc/group")); !strings.Contains(group.find("wheel")[3], YUNIONROOT_USER) {
+		t.Errorf("cloudroot should be in wheel group")
+	}
+	if !part.Exists(freebsdDeployRcd, false) || !part.Exists(freebsdFirstboot, false) {
+		t.Errorf("firstboot deploy script is not installed")
+	}
+}
@@ -0,0 +1,492 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fsdriver
+
+import (
+	"encoding/binary"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+
+	"yunion.io/x/onecloud/pkg/apis"
+	"yunion.io/x/onecloud/pkg/cloudcommon/types"
+	deployapi "yunion.io/x/onecloud/pkg/hostman/hostdeployer/apis"
+	"yunion.io/x/onecloud/pkg/util/fileutils2"
+	"yunion.io/x/onecloud/pkg/util/netutils2"
+	"yunion.io/x/onecloud/pkg/util/seclib2"
+)
+
+const (
+	freebsdRcConf        = "/etc/rc.conf"
+	freebsdMasterPasswd  = "/etc/master.passwd"
+	freebsdGroup         = "/etc/group"
+	freebsdFirstboot     = "/firstboot"
+	freebsdDeployRcd     = "/etc/rc.d/onecloud_deploy"
+	freebsdLoaderConf    = "/boot/loader.conf"
+	freebsdSudoersDir    = "/usr/local/etc/sudoers.d"
+	freebsdVersionScript = "/bin/freebsd-version"
+)
+
+// freebsdDeployScript runs once on first boot after deploy, binaries of
+// FreeBSD could not be executed on host, so the password databases are
+// rebuilt inside guest
+const freebsdDeployScript = `#!/bin/sh
+
+# PROVIDE: onecloud_deploy
+# REQUIRE: FILESYSTEMS
+# BEFORE: LOGIN sshd
+# KEYWORD: firstboot
+
+. /etc/rc.subr
+
+name="onecloud_deploy"
+rcvar="onecloud_deploy_enable"
+start_cmd="onecloud_deploy_start"
+stop_cmd=":"
+
+onecloud_deploy_start()
+{
+	/usr/sbin/pwd_mkdb -p /etc/master.passwd
+}
+
+load_rc_config $name
+: ${onecloud_deploy_enable:="YES"}
+run_rc_command "$1"
+`
+
+// SFreeBSDRootFs supports FreeBSD on UFS, whose settings are mainly kept in /etc/rc.conf
+type SFreeBSDRootFs struct {
+	*sGuestRootFsDriver
+
+	rcConf        *string
+	passwdChanged bool
+}
+
+func NewFreeBSDRootFs(part IDiskPartition) IRootFsDriver {
+	return &SFreeBSDRootFs{sGuestRootFsDriver: newGuestRootFsDriver(part)}
+}
+
+func (d *SFreeBSDRootFs) GetName() string {
+	return "FreeBSD"
+}
+
+func (d *SFreeBSDRootFs) String() string {
+	return "FreeBSDRootFs"
+}
+
+func (d *SFreeBSDRootFs) GetOs() string {
+	return "FreeBSD"
+}
+
+func (d *SFreeBSDRootFs) RootSignatures() []string {
+	return []string{freebsdVersionScript, "/boot/kernel", freebsdMasterPasswd, "/etc/rc.conf"}
+}
+
+func (d *SFreeBSDRootFs) GetReleaseInfo(rootFs IDiskPartition) *deployapi.ReleaseInfo {
+	cont, _ := rootFs.FileGetContents(freebsdVersionScript, false)
+	vars := parseShellVars(string(cont))
+	version := vars["USERLAND_VERSION"]
+	return deployapi.NewReleaseInfo(d.GetName(), version, d.getArch(rootFs))
+}
+
+// getArch reads e_machine from ELF header of /bin/sh
+func (d *SFreeBSDRootFs) getArch(rootFs IDiskPartition) string {
+	cont, err := rootFs.FileGetContents("/bin/sh", false)
+	if err != nil || len(cont) < 20 || string(cont[1:4]) != "ELF" {
+		return apis.OS_ARCH_X86_64
+	}
+	switch binary.LittleEndian.Uint16(cont[18:20]) {
+	case 0xb7:
+		return apis.OS_ARCH_AARCH64
+	case 0x28:
+		return apis.OS_ARCH_AARCH32
+	case 0x03:
+		return apis.OS_ARCH_X86_32
+	default:
+		return apis.OS_ARCH_X86_64
+	}
+}
+
+// IsResizeFsPartitionSupport returns false since UFS could not be grown on host,
+// growfs is enabled in guest instead
+func (d *SFreeBSDRootFs) IsResizeFsPartitionSupport() bool {
+	return false
+}
+
+// loadRcConf reads rc.conf once, the modified content is written back in CommitChanges
+func (d *SFreeBSDRootFs) loadRcConf(rootFs IDiskPartition) string {
+	if d.rcConf == nil {
+		cont, _ := rootFs.FileGetContents(freebsdRcConf, false)
+		conf := string(cont)
+		d.rcConf = &conf
+	}
+	return *d.rcConf
+}
+
+func (d *SFreeBSDRootFs) saveRcConf(conf string) {
+	d.rcConf = &conf
+}
+
+func (d *SFreeBSDRootFs) setRcConf(rootFs IDiskPartition, key, val string) {
+	d.saveRcConf(setShellVar(d.loadRcConf(rootFs), key, val))
+}
+
+func (d *SFreeBSDRootFs) DeployHostname(rootFs IDiskPartition, hn, domain string) error {
+	d.setRcConf(rootFs, "hostname", getHostname(hn, domain))
+	return nil
+}
+
+func (d *SFreeBSDRootFs) DeployHosts(rootFs IDiskPartition, hostname, domain string, ips []string) error {
+	var etcHosts = "/etc/hosts"
+	var oldHostFile string
+	if rootFs.Exists(etcHosts, false) {
+		oldhf, err := rootFs.FileGetContents(etcHosts, false)
+		if err != nil {
+			return err
+		}
+		oldHostFile = string(oldhf)
+	}
+	hf := make(fileutils2.HostsFile, 0)
+	hf.Parse(oldHostFile)
+	hf.Add("127.0.0.1", "localhost")
+	for _, ip := range ips {
+		hf.Add(ip, getHostname(hostname, domain), hostname)
+	}
+	return rootFs.FilePutContents(etcHosts, hf.String(), false, false)
+}
+
+// getFreeBSDNicNames names nics by driver and order, e.g. virtio nics are vtnet0, vtnet1
+func getFreeBSDNicNames(nics []*types.SServerNic) map[string]string {
+	sorted := make([]*types.SServerNic, len(nics))
+	copy(sorted, nics)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Index < sorted[j].Index
+	})
+	ret := make(map[string]string)
+	counter := make(map[string]int)
+	for _, nic := range sorted {
+		var prefix string
+		switch nic.Driver {
+		case "e1000":
+			prefix = "em"
+		case "vmxnet3":
+			prefix = "vmx"
+		case "rtl8139":
+			prefix = "re"
+		default:
+			prefix = "vtnet"
+		}
+		ret[nic.Mac] = fmt.Sprintf("%s%d", prefix, counter[prefix])
+		counter[prefix]++
+	}
+	return ret
+}
+
+func (d *SFreeBSDRootFs) DeployNetworkingScripts(rootFs IDiskPartition, nics []*types.SServerNic) error {
+	conf := d.loadRcConf(rootFs)
+	conf = removeShellVarsWithPrefix(conf, "ifconfig_")
+	conf = removeShellVarsWithPrefix(conf, "defaultrouter=")
+	conf = removeShellVarsWithPrefix(conf, "static_routes=")
+	conf = removeShellVarsWithPrefix(conf, "route_")
+	d.saveRcConf(conf)
+
+	allNics := make([]*types.SServerNic, 0)
+	for i := range nics {
+		if len(nics[i].Mac) > 0 {
+			allNics = append(allNics, nics[i])
+		}
+	}
+	nicNames := getFreeBSDNicNames(allNics)
+	mainNic, err := getMainNic(allNics)
+	if err != nil {
+		return err
+	}
+	var mainIp string
+	if mainNic != nil {
+		mainIp = mainNic.Ip
+	}
+	routeNames := []string{}
+	dnss := []string{}
+	domains := []string{}
+	for _, nicDesc := range allNics {
+		name := nicNames[nicDesc.Mac]
+		var ifconfig string
+		if nicDesc.Virtual {
+			ifconfig = fmt.Sprintf("inet %s netmask 255.255.255.255", netutils2.PSEUDO_VIP)
+		} else if nicDesc.Manual {
+			ifconfig = fmt.Sprintf("inet %s netmask %s", nicDesc.Ip, netutils2.Netlen2Mask(int(nicDesc.Masklen)))
+			if len(nicDesc.Gateway) > 0 && nicDesc.Ip == mainIp {
+				d.setRcConf(rootFs, "defaultrouter", nicDesc.Gateway)
+			}
+			var routes = make([][]string, 0)
+			netutils2.AddNicRoutes(&routes, nicDesc, mainIp, len(allNics), privatePrefixes)
+			for i, r := range routes {
+				routeName := fmt.Sprintf("%s_%d", name, i)
+				routeNames = append(routeNames, routeName)
+				d.setRcConf(rootFs, "route_"+routeName, fmt.Sprintf("-net %s %s", r[0], r[1]))
+			}
+			dnslist := netutils2.GetNicDns(nicDesc)
+			dnss = append(dnss, dnslist...)
+			if len(dnslist) > 0 && len(nicDesc.Domain) > 0 {
+				domains = append(domains, nicDesc.Domain)
+			}
+		} else {
+			ifconfig = "DHCP"
+		}
+		if nicDesc.Mtu > 0 {
+			if ifconfig == "DHCP" {
+				ifconfig = fmt.Sprintf("DHCP mtu %d", nicDesc.Mtu)
+			} else {
+				ifconfig = fmt.Sprintf("%s mtu %d", ifconfig, nicDesc.Mtu)
+			}
+		}
+		d.setRcConf(rootFs, "ifconfig_"+name, ifconfig)
+	}
+	if len(routeNames) > 0 {
+		d.setRcConf(rootFs, "static_routes", strings.Join(routeNames, " "))
+	}
+	if len(dnss) > 0 {
+		var resolv strings.Builder
+		if len(domains) > 0 {
+			resolv.WriteString(fmt.Sprintf("search %s\n", strings.Join(domains, " ")))
+		}
+		for _, dns := range dnss {
+			resolv.WriteString(fmt.Sprintf("nameserver %s\n", dns))
+		}
+		if err := rootFs.FilePutContents("/etc/resolv.conf", resolv.String(), false, false); err != nil {
+			return errors.Wrap(err, "write /etc/resolv.conf")
+		}
+	}
+	return nil
+}
+
+func (d *SFreeBSDRootFs) getHomeDir(rootFs IDiskPartition, user string) string {
+	if user == ROOT_USER {
+		return "/root"
+	}
+	home := path.Join("/home", user)
+	if passwd, err := loadPasswdFile(rootFs, freebsdMasterPasswd); err == nil {
+		if entry := passwd.find(user); len(entry) > 8 {
+			home = entry[8]
+		}
+	}
+	// /home is a symlink to usr/home on old releases
+	if strings.HasPrefix(home, "/home/") && rootFs.Exists("/usr/home", false) {
+		home = path.Join("/usr", home)
+	}
+	return home
+}
+
+// addUser adds account in master.passwd and group, returns home dir of user
+func (d *SFreeBSDRootFs) addUser(rootFs IDiskPartition, user, homeDir string, isSys bool) (string, error) {
+	passwd, err := loadPasswdFile(rootFs, freebsdMasterPasswd)
+	if err != nil {
+		return "", err
+	}
+	if passwd.find(user) != nil {
+		return d.getHomeDir(rootFs, user), nil
+	}
+	group, err := loadPasswdFile(rootFs, freebsdGroup)
+	if err != nil {
+		return "", err
+	}
+	startId := 1001
+	if isSys {
+		startId = 200
+	}
+	uid := passwd.nextId(2, startId)
+	gid := uid
+	if entry := group.find(user); entry != nil {
+		gid, _ = strconv.Atoi(entry[2])
+	} else {
+		gid = group.nextId(2, uid)
+		group.add(user, "*", strconv.Itoa(gid), "")
+	}
+	// user in wheel group is allowed to su
+	if wheel := group.find("wheel"); wheel != nil && len(wheel) > 3 {
+		members := []string{}
+		if len(wheel[3]) > 0 {
+			members = strings.Split(wheel[3], ",")
+		}
+		if !utils.IsInStringArray(user, members) {
+			wheel[3] = strings.Join(append(members, user), ",")
+		}
+	}
+	if len(homeDir) == 0 {
+		homeDir = "/home"
+	}
+	home := path.Join(homeDir, user)
+	// name:password:uid:gid:class:change:expire:gecos:home_dir:shell
+	passwd.add(user, "*", strconv.Itoa(uid), strconv.Itoa(gid), "", "0", "0", user, home, "/bin/sh")
+	if err := passwd.save(rootFs, freebsdMasterPasswd, 0600); err != nil {
+		return "", err
+	}
+	if err := group.save(rootFs, freebsdGroup, 0644); err != nil {
+		return "", err
+	}
+	d.passwdChanged = true
+	localHome := d.getHomeDir(rootFs, user)
+	if !rootFs.Exists(localHome, false) {
+		if err := rootFs.Mkdir(localHome, syscall.S_IRWXU|syscall.S_IRGRP|syscall.S_IXGRP, false); err != nil {
+			return "", errors.Wrapf(err, "mkdir %s", localHome)
+		}
+	}
+	if err := rootFs.Chown(localHome, uid, gid, false); err != nil {
+		return "", errors.Wrapf(err, "chown %s", localHome)
+	}
+	return localHome, nil
+}
+
+func (d *SFreeBSDRootFs) enableUserSudo(rootFs IDiskPartition, user string) error {
+	if !rootFs.Exists(freebsdSudoersDir, false) {
+		return nil
+	}
+	spath := path.Join(freebsdSudoersDir, fmt.Sprintf("90-%s-users", user))
+	if err := rootFs.FilePutContents(spath, fmt.Sprintf("%s ALL=(ALL) NOPASSWD:ALL\n", user), false, false); err != nil {
+		return errors.Wrapf(err, "write %s", spath)
+	}
+	return rootFs.Chmod(spath, syscall.S_IRUSR|syscall.S_IRGRP, false)
+}
+
+func (d *SFreeBSDRootFs) GetLoginAccount(rootFs IDiskPartition, sUser string, defaultRootUser bool, windowsDefaultAdminUser bool) (string, error) {
+	if len(sUser) > 0 {
+		if _, err := d.addUser(rootFs, sUser, "", false); err != nil {
+			return "", errors.Wrapf(err, "add user %s", sUser)
+		}
+		if err := d.enableUserSudo(rootFs, sUser); err != nil {
+			return "", errors.Wrap(err, "enableUserSudo")
+		}
+		return sUser, nil
+	}
+	if defaultRootUser {
+		return ROOT_USER, nil
+	}
+	var selUsr string
+	for _, homeDir := range []string{"/usr/home", "/home"} {
+		for _, usr := range rootFs.ListDir(homeDir, false) {
+			if usr == YUNIONROOT_USER {
+				continue
+			}
+			if len(selUsr) == 0 || len(selUsr) > len(usr) {
+				selUsr = usr
+			}
+		}
+		if len(selUsr) > 0 {
+			return selUsr, nil
+		}
+	}
+	return ROOT_USER, nil
+}
+
+func (d *SFreeBSDRootFs) DeployPublicKey(rootFs IDiskPartition, selUsr string, pubkeys *deployapi.SSHKeys) error {
+	return DeployAuthorizedKeys(rootFs, d.getHomeDir(rootFs, selUsr), pubkeys, false)
+}
+
+func (d *SFreeBSDRootFs) ChangeUserPasswd(rootFs IDiskPartition, account, gid, publicKey, password string) (string, error) {
+	hash, err := seclib2.GeneratePassword(password)
+	if err != nil {
+		return "", errors.Wrap(err, "GeneratePassword")
+	}
+	passwd, err := loadPasswdFile(rootFs, freebsdMasterPasswd)
+	if err != nil {
+		return "", err
+	}
+	if !passwd.setField(account, 1, hash) {
+		return "", errors.Errorf("user %s not found in %s", account, freebsdMasterPasswd)
+	}
+	if err := passwd.save(rootFs, freebsdMasterPasswd, 0600); err != nil {
+		return "", err
+	}
+	d.passwdChanged = true
+	if len(publicKey) > 0 {
+		return seclib2.EncryptBase64(publicKey, password)
+	}
+	return utils.EncryptAESBase64(gid, password)
+}
+
+func (d *SFreeBSDRootFs) DeployYunionroot(rootFs IDiskPartition, pubkeys *deployapi.SSHKeys, isInit, enableCloudInit bool) error {
+	home, err := d.addUser(rootFs, YUNIONROOT_USER, cloudrootDirectory, true)
+	if err != nil {
+		return errors.Wrapf(err, "add user %s", YUNIONROOT_USER)
+	}
+	if err := DeployAuthorizedKeys(rootFs, home, pubkeys, true); err != nil {
+		return errors.Wrap(err, "DeployAuthorizedKeys")
+	}
+	return d.enableUserSudo(rootFs, YUNIONROOT_USER)
+}
+
+func (d *SFreeBSDRootFs) EnableSerialConsole(rootFs IDiskPartition, sysInfo *jsonutils.JSONDict) error {
+	return d.setLoaderConsole(rootFs, "comconsole,vidconsole")
+}
+
+func (d *SFreeBSDRootFs) DisableSerialConsole(rootFs IDiskPartition) error {
+	return d.setLoaderConsole(rootFs, "vidconsole")
+}
+
+func (d *SFreeBSDRootFs) setLoaderConsole(rootFs IDiskPartition, console string) error {
+	var cont []byte
+	if rootFs.Exists(freebsdLoaderConf, false) {
+		cont, _ = rootFs.FileGetContents(freebsdLoaderConf, false)
+	}
+	return rootFs.FilePutContents(freebsdLoaderConf, setShellVar(string(cont), "console", console), false, false)
+}
+
+func (d *SFreeBSDRootFs) PrepareFsForTemplate(rootFs IDiskPartition) error {
+	if rootFs.Exists("/etc/ssh", false) {
+		for _, f := range rootFs.ListDir("/etc/ssh", false) {
+			if strings.HasSuffix(f, "_key") || strings.HasSuffix(f, "_key.pub") {
+				rootFs.Remove("/etc/ssh/"+f, false)
+			}
+		}
+	}
+	for _, dir := range []string{"/tmp", "/var/tmp"} {
+		if rootFs.Exists(dir, false) {
+			if err := rootFs.Cleandir(dir, false, false); err != nil {
+				return err
+			}
+		}
+	}
+	if rootFs.Exists("/var/log", false) {
+		if err := rootFs.Zerofiles("/var/log", false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *SFreeBSDRootFs) CommitChanges(rootFs IDiskPartition) error {
+	d.setRcConf(rootFs, "growfs_enable", "YES")
+	d.setRcConf(rootFs, "sshd_enable", "YES")
+	if err := rootFs.FilePutContents(freebsdRcConf, d.loadRcConf(rootFs), false, false); err != nil {
+		return errors.Wrapf(err, "write %s", freebsdRcConf)
+	}
+	if d.passwdChanged {
+		if err := rootFs.FilePutContents(freebsdDeployRcd, freebsdDeployScript, false, false); err != nil {
+			return errors.Wrapf(err, "write %s", freebsdDeployRcd)
+		}
+		if err := rootFs.Chmod(freebsdDeployRcd, 0555, false); err != nil {
+			return errors.Wrapf(err, "chmod %s", freebsdDeployRcd)
+		}
+	}
+	// rc scripts with firstboot keyword, like growfs and onecloud_deploy, run once when /firstboot exists
+	return rootFs.FilePutContents(freebsdFirstboot, "", false, false)
+}
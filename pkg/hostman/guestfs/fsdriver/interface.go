@@ -73,6 +73,7 @@ type IRootFsDriver interface {
 	DeployStandbyNetworkingScripts(part IDiskPartition, nics, nicsStandby []*types.SServerNic) error
 	DeployUdevSubsystemScripts(IDiskPartition) error
 	DeployFstabScripts(IDiskPartition, []*deployapi.Disk) error
+	DeploySharedfsFstab(IDiskPartition, []*deployapi.SharedFs) error
 	GetLoginAccount(IDiskPartition, string, bool, bool) (string, error)
 	DeployPublicKey(IDiskPartition, string, *deployapi.SSHKeys) error
 	ChangeUserPasswd(part IDiskPartition, account, gid, publicKey, password string) (string, error)
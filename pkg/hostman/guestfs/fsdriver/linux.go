@@ -267,6 +267,45 @@ func (l *sLinuxRootFs) DeployFstabScripts(rootFs IDiskPartition, disks []*deploy
 	return rootFs.FilePutContents("/etc/fstab", cf, false, false)
 }
 
+// DeploySharedfsFstab keeps the virtiofs records of /etc/fstab in line with
+// the shared filesystems attached to the guest
+func (l *sLinuxRootFs) DeploySharedfsFstab(rootFs IDiskPartition, sharedfs []*deployapi.SharedFs) error {
+	var fstab *fstabutils.FsTab
+	if rootFs.Exists("/etc/fstab", false) {
+		fstabcont, err := rootFs.FileGetContents("/etc/fstab", false)
+		if err != nil {
+			return err
+		}
+		fstab = fstabutils.FSTabFile(string(fstabcont))
+	}
+	if fstab == nil {
+		if len(sharedfs) == 0 {
+			return nil
+		}
+		_fstab := make(fstabutils.FsTab, 0)
+		fstab = &_fstab
+	}
+	var modeRwxOwner = syscall.S_IRUSR | syscall.S_IWUSR | syscall.S_IXUSR
+	fstab = fstab.RemoveFsType("virtiofs")
+	for _, fs := range sharedfs {
+		if len(fs.Mountpoint) == 0 {
+			continue
+		}
+		opt := "defaults,nofail"
+		if fs.Readonly {
+			opt += ",ro"
+		}
+		fstab.AddFsrec(fmt.Sprintf("%s %s virtiofs %s 0 0", fs.Tag, fs.Mountpoint, opt))
+		if !l.rootFs.Exists(fs.Mountpoint, false) {
+			if err := l.rootFs.Mkdir(fs.Mountpoint, modeRwxOwner, false); err != nil {
+				return err
+			}
+		}
+	}
+	cf := fstab.ToConf()
+	return rootFs.FilePutContents("/etc/fstab", cf, false, false)
+}
+
 func (l *sLinuxRootFs) DeployNetworkingScripts(rootFs IDiskPartition, nics []*types.SServerNic) error {
 	udevPath := "/etc/udev/rules.d/"
 	if rootFs.Exists(udevPath, false) {
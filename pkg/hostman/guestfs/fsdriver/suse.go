@@ -0,0 +1,184 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fsdriver
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"yunion.io/x/log"
+
+	"yunion.io/x/onecloud/pkg/cloudcommon/types"
+	deployapi "yunion.io/x/onecloud/pkg/hostman/hostdeployer/apis"
+	"yunion.io/x/onecloud/pkg/util/netutils2"
+)
+
+const (
+	suseNetworkDir    = "/etc/sysconfig/network"
+	suseNetworkConfig = "/etc/sysconfig/network/config"
+	suseRoutesFile    = "/etc/sysconfig/network/routes"
+)
+
+// SSuseRootFs supports SLES and openSUSE, whose network is configured
+// by wicked with ifcfg files under /etc/sysconfig/network
+type SSuseRootFs struct {
+	*sLinuxRootFs
+}
+
+func NewSuseRootFs(part IDiskPartition) IRootFsDriver {
+	return &SSuseRootFs{sLinuxRootFs: newLinuxRootFs(part)}
+}
+
+func (d *SSuseRootFs) GetName() string {
+	return "SUSE"
+}
+
+func (d *SSuseRootFs) String() string {
+	return "SuseRootFs"
+}
+
+func (d *SSuseRootFs) RootSignatures() []string {
+	sig := d.sLinuxRootFs.RootSignatures()
+	return append([]string{suseNetworkConfig, "/etc/os-release"}, sig...)
+}
+
+func (d *SSuseRootFs) GetReleaseInfo(rootFs IDiskPartition) *deployapi.ReleaseInfo {
+	vars := getOsReleaseVars(rootFs)
+	distro := d.GetName()
+	if strings.HasPrefix(vars["ID"], "opensuse") {
+		distro = "openSUSE"
+	} else if vars["ID"] == "sles" || vars["ID"] == "sled" {
+		distro = "SLES"
+	}
+	return deployapi.NewReleaseInfo(distro, vars["VERSION_ID"], d.GetArch(rootFs))
+}
+
+func (d *SSuseRootFs) DeployHostname(rootFs IDiskPartition, hn, domain string) error {
+	if err := rootFs.FilePutContents("/etc/hostname", hn, false, false); err != nil {
+		return err
+	}
+	// SLES 11 and earlier
+	if rootFs.Exists("/etc/HOSTNAME", false) {
+		return rootFs.FilePutContents("/etc/HOSTNAME", getHostname(hn, domain), false, false)
+	}
+	return nil
+}
+
+func (d *SSuseRootFs) CleanNetworkScripts(rootFs IDiskPartition) error {
+	for _, f := range rootFs.ListDir(suseNetworkDir, false) {
+		if f == "ifcfg-lo" {
+			continue
+		}
+		if strings.HasPrefix(f, "ifcfg-") || strings.HasPrefix(f, "ifroute-") {
+			rootFs.Remove(path.Join(suseNetworkDir, f), false)
+		}
+	}
+	return nil
+}
+
+func (d *SSuseRootFs) DeployNetworkingScripts(rootFs IDiskPartition, nics []*types.SServerNic) error {
+	if err := d.sLinuxRootFs.DeployNetworkingScripts(rootFs, nics); err != nil {
+		return err
+	}
+	allNics, _ := convertNicConfigs(nics)
+	mainNic, err := getMainNic(allNics)
+	if err != nil {
+		return err
+	}
+	var mainIp string
+	if mainNic != nil {
+		mainIp = mainNic.Ip
+	}
+	var (
+		defaultRoutes strings.Builder
+		dnss          = []string{}
+		domains       = []string{}
+	)
+	for i := range allNics {
+		nicDesc := allNics[i]
+		var cmds strings.Builder
+		if nicDesc.TeamingMaster != nil {
+			cmds.WriteString("STARTMODE='hotplug'\n")
+		} else {
+			cmds.WriteString("STARTMODE='auto'\n")
+		}
+		if nicDesc.Mtu > 0 {
+			cmds.WriteString(fmt.Sprintf("MTU='%d'\n", nicDesc.Mtu))
+		}
+		if nicDesc.TeamingMaster != nil {
+			cmds.WriteString("BOOTPROTO='none'\n")
+		} else if nicDesc.Virtual {
+			cmds.WriteString("BOOTPROTO='static'\n")
+			cmds.WriteString(fmt.Sprintf("IPADDR='%s/32'\n", netutils2.PSEUDO_VIP))
+		} else if nicDesc.Manual {
+			cmds.WriteString("BOOTPROTO='static'\n")
+			cmds.WriteString(fmt.Sprintf("IPADDR='%s/%d'\n", nicDesc.Ip, nicDesc.Masklen))
+			if len(nicDesc.Gateway) > 0 && nicDesc.Ip == mainIp {
+				defaultRoutes.WriteString(fmt.Sprintf("default %s - %s\n", nicDesc.Gateway, nicDesc.Name))
+			}
+			var routes = make([][]string, 0)
+			netutils2.AddNicRoutes(&routes, nicDesc, mainIp, len(nics), privatePrefixes)
+			var rtbl strings.Builder
+			for _, r := range routes {
+				rtbl.WriteString(fmt.Sprintf("%s %s - %s\n", r[0], r[1], nicDesc.Name))
+			}
+			if rtbl.Len() > 0 {
+				fn := path.Join(suseNetworkDir, "ifroute-"+nicDesc.Name)
+				if err := rootFs.FilePutContents(fn, rtbl.String(), false, false); err != nil {
+					return err
+				}
+			}
+			dnslist := netutils2.GetNicDns(nicDesc)
+			dnss = append(dnss, dnslist...)
+			if len(dnslist) > 0 && len(nicDesc.Domain) > 0 {
+				domains = append(domains, nicDesc.Domain)
+			}
+		} else {
+			cmds.WriteString("BOOTPROTO='dhcp'\n")
+		}
+		if len(nicDesc.TeamingSlaves) > 0 {
+			cmds.WriteString("BONDING_MASTER='yes'\n")
+			cmds.WriteString("BONDING_MODULE_OPTS='mode=802.3ad miimon=100'\n")
+			for j, slave := range nicDesc.TeamingSlaves {
+				cmds.WriteString(fmt.Sprintf("BONDING_SLAVE_%d='%s'\n", j, slave.Name))
+			}
+		}
+		fn := path.Join(suseNetworkDir, "ifcfg-"+nicDesc.Name)
+		log.Debugf("%s: %s", fn, cmds.String())
+		if err := rootFs.FilePutContents(fn, cmds.String(), false, false); err != nil {
+			return err
+		}
+	}
+	if defaultRoutes.Len() > 0 {
+		if err := rootFs.FilePutContents(suseRoutesFile, defaultRoutes.String(), false, false); err != nil {
+			return err
+		}
+	}
+	if len(dnss) > 0 && rootFs.Exists(suseNetworkConfig, false) {
+		cont, err := rootFs.FileGetContents(suseNetworkConfig, false)
+		if err != nil {
+			return err
+		}
+		conf := setShellVar(string(cont), "NETCONFIG_DNS_STATIC_SERVERS", strings.Join(dnss, " "))
+		if len(domains) > 0 {
+			conf = setShellVar(conf, "NETCONFIG_DNS_STATIC_SEARCHLIST", strings.Join(domains, " "))
+		}
+		if err := rootFs.FilePutContents(suseNetworkConfig, conf, false, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fsdriver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"yunion.io/x/pkg/errors"
+)
+
+// parseShellVars parses files of KEY=VALUE lines, like os-release, rc.conf
+// and sysconfig files, quotes around the value are stripped
+func parseShellVars(content string) map[string]string {
+	ret := make(map[string]string)
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		pos := strings.Index(line, "=")
+		if pos <= 0 {
+			continue
+		}
+		key := strings.TrimSpace(line[:pos])
+		val := strings.TrimSpace(line[pos+1:])
+		if len(val) >= 2 && (val[0] == '"' || val[0] == '\'') && val[len(val)-1] == val[0] {
+			val = val[1 : len(val)-1]
+		}
+		ret[key] = val
+	}
+	return ret
+}
+
+// setShellVar replaces the line of key in content with key="val",
+// the line is appended if key is absent
+func setShellVar(content string, key, val string) string {
+	newLine := fmt.Sprintf("%s=\"%s\"", key, val)
+	lines := make([]string, 0)
+	found := false
+	for _, line := range strings.Split(strings.TrimRight(content, "\n"), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), key+"=") {
+			if found {
+				continue
+			}
+			line = newLine
+			found = true
+		}
+		lines = append(lines, line)
+	}
+	if !found {
+		lines = append(lines, newLine)
+	}
+	return strings.TrimLeft(strings.Join(lines, "\n"), "\n") + "\n"
+}
+
+// removeShellVarsWithPrefix removes the lines of keys starting with prefix
+func removeShellVarsWithPrefix(content string, prefix string) string {
+	lines := strings.Split(content, "\n")
+	ret := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), prefix) {
+			continue
+		}
+		ret = append(ret, line)
+	}
+	return strings.Join(ret, "\n")
+}
+
+func getOsReleaseVars(rootFs IDiskPartition) map[string]string {
+	for _, spath := range []string{"/etc/os-release", "/usr/lib/os-release"} {
+		if rootFs.Exists(spath, false) {
+			cont, err := rootFs.FileGetContents(spath, false)
+			if err == nil {
+				return parseShellVars(string(cont))
+			}
+		}
+	}
+	return map[string]string{}
+}
+
+// sPasswdFile is a colon separated account database, like /etc/passwd,
+// /etc/shadow, /etc/group and master.passwd of BSD
+type sPasswdFile struct {
+	lines [][]string
+}
+
+func parsePasswdFile(content string) *sPasswdFile {
+	pf := &sPasswdFile{}
+	for _, line := range strings.Split(content, "\n") {
+		if len(strings.TrimSpace(line)) == 0 {
+			continue
+		}
+		pf.lines = append(pf.lines, strings.Split(line, ":"))
+	}
+	return pf
+}
+
+func loadPasswdFile(rootFs IDiskPartition, spath string) (*sPasswdFile, error) {
+	cont, err := rootFs.FileGetContents(spath, false)
+	if err != nil {
+		return nil, errors.Wrapf(err, "read %s", spath)
+	}
+	return parsePasswdFile(string(cont)), nil
+}
+
+func (pf *sPasswdFile) find(name string) []string {
+	for i := range pf.lines {
+		if pf.lines[i][0] == name {
+			return pf.lines[i]
+		}
+	}
+	return nil
+}
+
+// setField sets the idx field of the entry of name, returns false if name is not found
+func (pf *sPasswdFile) setField(name string, idx int, val string) bool {
+	entry := pf.find(name)
+	if entry == nil || len(entry) <= idx {
+		return false
+	}
+	entry[idx] = val
+	return true
+}
+
+func (pf *sPasswdFile) add(fields ...string) {
+	pf.lines = append(pf.lines, fields)
+}
+
+// nextId returns the smallest id not less than start which is not used by the idx field
+func (pf *sPasswdFile) nextId(idx int, start int) int {
+	used := make(map[int]bool)
+	for i := range pf.lines {
+		if len(pf.lines[i]) <= idx {
+			continue
+		}
+		id, err := strconv.Atoi(pf.lines[i][idx])
+		if err == nil {
+			used[id] = true
+		}
+	}
+	for used[start] {
+		start++
+	}
+	return start
+}
+
+func (pf *sPasswdFile) String() string {
+	var buf strings.Builder
+	for i := range pf.lines {
+		buf.WriteString(strings.Join(pf.lines[i], ":"))
+		buf.WriteString("\n")
+	}
+	return buf.String()
+}
+
+func (pf *sPasswdFile) save(rootFs IDiskPartition, spath string, mode uint32) error {
+	err := rootFs.FilePutContents(spath, pf.String(), false, false)
+	if err != nil {
+		return errors.Wrapf(err, "write %s", spath)
+	}
+	return rootFs.Chmod(spath, mode, false)
+}
@@ -0,0 +1,238 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fsdriver
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// sTestPartition is a IDiskPartition backed by a local directory,
+// caller should remove the directory by cleanup()
+type sTestPartition struct {
+	root string
+}
+
+func newTestPartition(t *testing.T, files map[string]string) *sTestPartition {
+	root, err := ioutil.TempDir("", "fsdriver")
+	if err != nil {
+		t.Fatalf("TempDir: %s", err)
+	}
+	p := &sTestPartition{root: root}
+	for fn, cont := range files {
+		if cont == "<dir>" {
+			if err := p.Mkdir(fn, 0755, false); err != nil {
+				t.Fatalf("mkdir %s: %s", fn, err)
+			}
+			continue
+		}
+		if err := p.Mkdir(path.Dir(fn), 0755, false); err != nil {
+			t.Fatalf("mkdir %s: %s", path.Dir(fn), err)
+		}
+		if err := p.FilePutContents(fn, cont, false, false); err != nil {
+			t.Fatalf("write %s: %s", fn, err)
+		}
+	}
+	return p
+}
+
+func (p *sTestPartition) cleanup() {
+	os.RemoveAll(p.root)
+}
+
+func (p *sTestPartition) mustGet(t *testing.T, sPath string) string {
+	cont, err := p.FileGetContents(sPath, false)
+	if err != nil {
+		t.Fatalf("read %s: %s", sPath, err)
+	}
+	return string(cont)
+}
+
+func (p *sTestPartition) GetLocalPath(sPath string, caseInsensitive bool) string {
+	return filepath.Join(p.root, sPath)
+}
+
+func (p *sTestPartition) FileGetContents(sPath string, caseInsensitive bool) ([]byte, error) {
+	return ioutil.ReadFile(p.GetLocalPath(sPath, caseInsensitive))
+}
+
+func (p *sTestPartition) FileGetContentsByPath(sPath string) ([]byte, error) {
+	return ioutil.ReadFile(sPath)
+}
+
+func (p *sTestPartition) FilePutContents(sPath, content string, modAppend, caseInsensitive bool) error {
+	flag := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if modAppend {
+		flag = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	}
+	f, err := os.OpenFile(p.GetLocalPath(sPath, caseInsensitive), flag, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.WriteString(content)
+	return err
+}
+
+func (p *sTestPartition) Exists(sPath string, caseInsensitive bool) bool {
+	_, err := os.Stat(p.GetLocalPath(sPath, caseInsensitive))
+	return err == nil
+}
+
+func (p *sTestPartition) Chown(sPath string, uid, gid int, caseInsensitive bool) error {
+	// not root when running test
+	os.Chown(p.GetLocalPath(sPath, caseInsensitive), uid, gid)
+	return nil
+}
+
+func (p *sTestPartition) Chmod(sPath string, mode uint32, caseInsensitive bool) error {
+	return os.Chmod(p.GetLocalPath(sPath, caseInsensitive), os.FileMode(mode))
+}
+
+func (p *sTestPartition) CheckOrAddUser(user, homeDir string, isSys bool) (string, error) {
+	return path.Join(homeDir, user), nil
+}
+
+func (p *sTestPartition) Stat(sPath string, caseInsensitive bool) os.FileInfo {
+	fi, _ := os.Stat(p.GetLocalPath(sPath, caseInsensitive))
+	return fi
+}
+
+func (p *sTestPartition) Symlink(src, dst string, caseInsensitive bool) error {
+	return os.Symlink(src, p.GetLocalPath(dst, caseInsensitive))
+}
+
+func (p *sTestPartition) Passwd(account, password string, caseInsensitive bool) error {
+	return nil
+}
+
+func (p *sTestPartition) Mkdir(sPath string, mode int, caseInsensitive bool) error {
+	return os.MkdirAll(p.GetLocalPath(sPath, caseInsensitive), os.FileMode(mode))
+}
+
+func (p *sTestPartition) ListDir(sPath string, caseInsensitive bool) []string {
+	files, err := ioutil.ReadDir(p.GetLocalPath(sPath, caseInsensitive))
+	if err != nil {
+		return nil
+	}
+	ret := make([]string, 0, len(files))
+	for _, f := range files {
+		ret = append(ret, f.Name())
+	}
+	return ret
+}
+
+func (p *sTestPartition) Remove(sPath string, caseInsensitive bool) {
+	os.Remove(p.GetLocalPath(sPath, caseInsensitive))
+}
+
+func (p *sTestPartition) Cleandir(dir string, keepdir, caseInsensitive bool) error {
+	return nil
+}
+
+func (p *sTestPartition) Zerofiles(dir string, caseInsensitive bool) error {
+	return nil
+}
+
+func (p *sTestPartition) SupportSerialPorts() bool         { return false }
+func (p *sTestPartition) GetPartDev() string               { return "" }
+func (p *sTestPartition) IsMounted() bool                  { return true }
+func (p *sTestPartition) Mount() bool                      { return true }
+func (p *sTestPartition) MountPartReadOnly() bool          { return true }
+func (p *sTestPartition) Umount() error                    { return nil }
+func (p *sTestPartition) GetMountPath() string             { return p.root }
+func (p *sTestPartition) IsReadonly() bool                 { return false }
+func (p *sTestPartition) GetPhysicalPartitionType() string { return "" }
+func (p *sTestPartition) Zerofree()                        {}
+
+func TestParseShellVars(t *testing.T) {
+	cont := `# comment
+NAME="openSUSE Leap"
+VERSION_ID='15.4'
+ID=opensuse-leap
+ hostname = "foo"
+invalid
+`
+	want := map[string]string{
+		"NAME":       "openSUSE Leap",
+		"VERSION_ID": "15.4",
+		"ID":         "opensuse-leap",
+		"hostname":   "foo",
+	}
+	if got := parseShellVars(cont); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseShellVars() = %v, want %v", got, want)
+	}
+}
+
+func TestSetShellVar(t *testing.T) {
+	cases := []struct {
+		name    string
+		content string
+		key     string
+		val     string
+		want    string
+	}{
+		{
+			name:    "append to empty",
+			content: "",
+			key:     "hostname",
+			val:     "foo",
+			want:    "hostname=\"foo\"\n",
+		},
+		{
+			name:    "replace and dedup",
+			content: "hostname=\"old\"\nsshd_enable=\"YES\"\nhostname=\"old2\"\n",
+			key:     "hostname",
+			val:     "foo",
+			want:    "hostname=\"foo\"\nsshd_enable=\"YES\"\n",
+		},
+		{
+			name:    "append",
+			content: "sshd_enable=\"YES\"",
+			key:     "hostname",
+			val:     "foo",
+			want:    "sshd_enable=\"YES\"\nhostname=\"foo\"\n",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := setShellVar(c.content, c.key, c.val); got != c.want {
+				t.Errorf("setShellVar() = %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestPasswdFile(t *testing.T) {
+	pf := parsePasswdFile("root:x:0:0:root:/root:/bin/sh\nuser:x:1000:1000::/home/user:/bin/sh\n")
+	if got := pf.nextId(2, 1000); got != 1001 {
+		t.Errorf("nextId() = %d, want 1001", got)
+	}
+	if !pf.setField("root", 1, "*") {
+		t.Errorf("setField root failed")
+	}
+	if pf.setField("nobody", 1, "*") {
+		t.Errorf("setField should fail for absent user")
+	}
+	pf.add("new", "x", "1001", "1001", "", "/home/new", "/bin/sh")
+	want := "root:*:0:0:root:/root:/bin/sh\nuser:x:1000:1000::/home/user:/bin/sh\nnew:x:1001:1001::/home/new:/bin/sh\n"
+	if got := pf.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
@@ -156,6 +156,13 @@ func (p *SKVMGuestDiskPartition) mount(readonly bool) error {
 		}
 	} else if fsType == "hfsplus" && !readonly {
 		opt = "force,rw"
+	} else if fsType == "ufs" {
+		// FreeBSD uses UFS2, writing requires kernel built with CONFIG_UFS_FS_WRITE
+		if readonly {
+			opt = "ro,ufstype=ufs2"
+		} else {
+			opt = "rw,ufstype=ufs2"
+		}
 	}
 	cmds = append(cmds, fsType)
 	if len(opt) > 0 {
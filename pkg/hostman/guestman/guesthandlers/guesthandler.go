@@ -90,6 +90,7 @@ func AddGuestTaskHandler(prefix string, app *appsrv.Application) {
 			"cpuset-remove":         guestCPUSetRemove,
 			"memory-snapshot":       guestMemorySnapshot,
 			"memory-snapshot-reset": guestMemorySnapshotReset,
+			"nvram-export":          guestNvramExport,
 		} {
 			app.AddHandler("POST",
 				fmt.Sprintf("%s/%s/<sid>/%s", prefix, keyWord, action),
@@ -334,6 +335,13 @@ func guestDestPrepareMigrate(ctx context.Context, userCred mcclient.TokenCredent
 		}
 		params.MigrateCerts = certs
 	}
+	if body.Contains("nvram") {
+		nvram := map[string]string{}
+		if err := body.Unmarshal(&nvram, "nvram"); err != nil {
+			return nil, httperrors.NewInputParameterError("unmarshal nvram to map: %s", err)
+		}
+		params.Nvram = nvram
+	}
 	if isLocal {
 		serverUrl, err := body.GetString("server_url")
 		if err != nil {
@@ -695,6 +703,18 @@ func guestMemorySnapshotReset(ctx context.Context, userCred mcclient.TokenCreden
 	return nil, nil
 }
 
+func guestNvramExport(ctx context.Context, userCred mcclient.TokenCredential, sid string, body jsonutils.JSONObject) (interface{}, error) {
+	guest, ok := guestman.GetGuestManager().GetServer(sid)
+	if !ok {
+		return nil, httperrors.NewNotFoundError("Guest %s not found", sid)
+	}
+	nvram, err := guest.ExportNvram()
+	if err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	return &hostapi.GuestNvramExportResponse{Nvram: nvram}, nil
+}
+
 func guestMemorySnapshotDelete(ctx context.Context, w http.ResponseWriter, r *http.Request) {
 	_, _, body := appsrv.FetchEnv(ctx, w, r)
 	input := new(hostapi.GuestMemorySnapshotDeleteRequest)
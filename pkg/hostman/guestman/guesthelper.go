@@ -46,6 +46,7 @@ type SDestPrepareMigrate struct {
 	SourceQemuCmdline string
 	MigrateCerts      map[string]string
 	EnableTLS         bool
+	Nvram             map[string]string
 	SnapshotsUri      string
 	DisksUri          string
 	// TargetStorageId string
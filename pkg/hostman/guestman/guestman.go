@@ -582,6 +582,15 @@ func (m *SGuestManager) GuestCreate(ctx context.Context, params interface{}) (js
 			if err != nil {
 				return errors.Wrap(err, "save desc")
 			}
+			if deployParams.Body.Contains("nvram") {
+				nvram := map[string]string{}
+				if err := deployParams.Body.Unmarshal(&nvram, "nvram"); err != nil {
+					return errors.Wrap(err, "unmarshal nvram")
+				}
+				if err := guest.ImportNvram(nvram); err != nil {
+					return errors.Wrap(err, "import nvram")
+				}
+			}
 		}
 		m.SaveServer(deployParams.Sid, guest)
 		return nil
@@ -810,6 +819,14 @@ func (m *SGuestManager) SrcPrepareMigrate(ctx context.Context, params interface{
 		}
 		ret.Set("migrate_certs", jsonutils.Marshal(certs))
 	}
+
+	if guest.hasNvram() {
+		nvram, err := guest.ExportNvram()
+		if err != nil {
+			return nil, errors.Wrap(err, "ExportNvram")
+		}
+		ret.Set("nvram", jsonutils.Marshal(nvram))
+	}
 	return ret, nil
 }
 
@@ -847,6 +864,12 @@ func (m *SGuestManager) DestPrepareMigrate(ctx context.Context, params interface
 
 	}
 
+	if len(migParams.Nvram) > 0 {
+		if err := guest.ImportNvram(migParams.Nvram); err != nil {
+			return nil, errors.Wrap(err, "import nvram")
+		}
+	}
+
 	body := jsonutils.NewDict()
 
 	if len(migParams.SrcMemorySnapshots) > 0 {
@@ -1141,6 +1141,9 @@ func (s *SKVMGuestInstance) Delete(ctx context.Context, migrated bool) error {
 	if err := s.delFlatFiles(ctx); err != nil {
 		return errors.Wrap(err, "delFlatFiles")
 	}
+	if err := s.delNvram(); err != nil {
+		return errors.Wrap(err, "delNvram")
+	}
 	if fileutils2.Exists(s.getQemuLogPath()) {
 		procutils.NewRemoteCommandAsFarAsPossible("mv", s.getQemuLogPath(), fmt.Sprintf("/tmp/%s-qemu.log", s.GetId())).Run()
 	}
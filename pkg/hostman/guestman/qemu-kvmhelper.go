@@ -313,6 +313,24 @@ func (s *SKVMGuestInstance) generateStartScript(data *jsonutils.JSONDict) (strin
 	}
 	cmd += diskScripts
 
+	if s.hasNvram() {
+		if err := s.prepareNvram(); err != nil {
+			return "", errors.Wrap(err, "prepareNvram")
+		}
+		if s.isVtpm() {
+			cmd += s.generateSwtpmStartScript()
+		}
+	}
+
+	if sharedfs := s.getSharedfs(); len(sharedfs) > 0 {
+		virtiofsdScripts, sharedfsOpts, err := s.generateVirtiofsdStartScript(sharedfs)
+		if err != nil {
+			return "", errors.Wrap(err, "generateVirtiofsdStartScript")
+		}
+		cmd += virtiofsdScripts
+		input.Sharedfs = sharedfsOpts
+	}
+
 	cmd += fmt.Sprintf("STATE_FILE=`ls -d %s* | head -n 1`\n", s.getStateFilePathRootPrefix())
 	cmd += fmt.Sprintf("PID_FILE=%s\n", input.PidFilePath)
 
@@ -424,10 +442,18 @@ function nic_mtu() {
 		}
 	}
 	if input.BIOS == qemu.BIOS_UEFI {
+		if s.isSecureBoot() {
+			input.SecureBoot = true
+			input.OVMFPath = s.getSecbootCodePath()
+			input.OVMFVarsPath = s.getOvmfVarsPath()
+		}
 		if len(input.OVMFPath) == 0 {
 			input.OVMFPath = options.HostOptions.OvmfPath
 		}
 	}
+	if s.isVtpm() {
+		input.TPMSocketPath = s.getTpmSocketPath()
+	}
 
 	// inject nic and disks
 	if input.OsName == OS_NAME_MACOS {
@@ -649,6 +675,11 @@ func (s *SKVMGuestInstance) generateStopScript(data *jsonutils.JSONDict) string
 	cmd += "  rm -f $PID_FILE\n"
 	cmd += "fi\n"
 
+	if s.isVtpm() {
+		cmd += s.generateSwtpmStopScript()
+	}
+	cmd += s.generateVirtiofsdStopScript()
+
 	cmd += fmt.Sprintf("for d in $(ls -d /dev/hugepages/%s*)\n", uuid)
 	cmd += fmt.Sprintf("do\n")
 	cmd += fmt.Sprintf("  if [ -d $d ]; then\n")
@@ -0,0 +1,218 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guestman
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/hostman/options"
+	"yunion.io/x/onecloud/pkg/hostman/storageman"
+	"yunion.io/x/onecloud/pkg/util/fileutils2"
+	"yunion.io/x/onecloud/pkg/util/procutils"
+)
+
+const (
+	NVRAM_OVMF_VARS_FILE = "OVMF_VARS.fd"
+	NVRAM_TPM_STATE_DIR  = "tpm"
+)
+
+func (s *SKVMGuestInstance) isSecureBoot() bool {
+	return jsonutils.QueryBoolean(s.Desc, "secure_boot", false)
+}
+
+func (s *SKVMGuestInstance) isVtpm() bool {
+	return jsonutils.QueryBoolean(s.Desc, "vtpm", false)
+}
+
+func (s *SKVMGuestInstance) hasNvram() bool {
+	return s.isSecureBoot() || s.isVtpm()
+}
+
+// getNvramDir returns the directory holding the per guest UEFI variable store
+// and vTPM state. It is kept next to the system disk on local storage so that
+// the state shares the lifecycle of the guest disks.
+func (s *SKVMGuestInstance) getNvramDir() string {
+	disks, _ := s.Desc.GetArray("disks")
+	if len(disks) > 0 {
+		storageType, _ := disks[0].GetString("storage_type")
+		storageId, _ := disks[0].GetString("storage_id")
+		if storageType == api.STORAGE_LOCAL {
+			if storage := storageman.GetManager().GetStorage(storageId); storage != nil {
+				return path.Join(storage.GetPath(), "nvram", s.Id)
+			}
+		}
+	}
+	return path.Join(s.HomeDir(), "nvram")
+}
+
+func (s *SKVMGuestInstance) getOvmfVarsPath() string {
+	return path.Join(s.getNvramDir(), NVRAM_OVMF_VARS_FILE)
+}
+
+func (s *SKVMGuestInstance) getTpmStateDir() string {
+	return path.Join(s.getNvramDir(), NVRAM_TPM_STATE_DIR)
+}
+
+func (s *SKVMGuestInstance) getTpmSocketPath() string {
+	return path.Join(s.HomeDir(), "swtpm.sock")
+}
+
+func (s *SKVMGuestInstance) getTpmPidFilePath() string {
+	return path.Join(s.HomeDir(), "swtpm.pid")
+}
+
+// getSecbootCodePath returns the secure boot capable firmware of host arch
+func (s *SKVMGuestInstance) getSecbootCodePath() string {
+	if s.manager.host.IsAarch64() {
+		return options.HostOptions.AavmfSecbootCodePath
+	}
+	return options.HostOptions.OvmfSecbootCodePath
+}
+
+// getSecbootVarsTemplatePath returns the variable store template matching
+// the firmware of getSecbootCodePath
+func (s *SKVMGuestInstance) getSecbootVarsTemplatePath() string {
+	if s.manager.host.IsAarch64() {
+		return options.HostOptions.AavmfVarsTemplatePath
+	}
+	return options.HostOptions.OvmfVarsTemplatePath
+}
+
+func (s *SKVMGuestInstance) prepareNvram() error {
+	if s.isSecureBoot() {
+		varsPath := s.getOvmfVarsPath()
+		if !fileutils2.Exists(varsPath) {
+			if err := s.makeNvramDir(s.getNvramDir()); err != nil {
+				return err
+			}
+			tmpl := s.getSecbootVarsTemplatePath()
+			output, err := procutils.NewCommand("cp", "-f", tmpl, varsPath).Output()
+			if err != nil {
+				return errors.Wrapf(err, "copy %s to %s failed: %s", tmpl, varsPath, output)
+			}
+		}
+	}
+	if s.isVtpm() {
+		if err := s.makeNvramDir(s.getTpmStateDir()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SKVMGuestInstance) makeNvramDir(dir string) error {
+	output, err := procutils.NewCommand("mkdir", "-p", dir).Output()
+	if err != nil {
+		return errors.Wrapf(err, "mkdir %s failed: %s", dir, output)
+	}
+	return nil
+}
+
+func (s *SKVMGuestInstance) generateSwtpmStartScript() string {
+	cmd := fmt.Sprintf("SWTPM_PID_FILE=%s\n", s.getTpmPidFilePath())
+	cmd += "if [ -f $SWTPM_PID_FILE ]; then\n"
+	cmd += "  kill $(cat $SWTPM_PID_FILE) > /dev/null 2>&1\n"
+	cmd += "  rm -f $SWTPM_PID_FILE\n"
+	cmd += "fi\n"
+	cmd += fmt.Sprintf("rm -f %s\n", s.getTpmSocketPath())
+	cmd += fmt.Sprintf("%s socket --tpm2 --tpmstate dir=%s,mode=0600 --ctrl type=unixio,path=%s "+
+		"--pid file=$SWTPM_PID_FILE --log file=%s --terminate --daemon\n",
+		options.HostOptions.SwtpmPath, s.getTpmStateDir(), s.getTpmSocketPath(),
+		path.Join(s.HomeDir(), "swtpm.log"))
+	return cmd
+}
+
+func (s *SKVMGuestInstance) generateSwtpmStopScript() string {
+	cmd := fmt.Sprintf("SWTPM_PID_FILE=%s\n", s.getTpmPidFilePath())
+	cmd += "if [ -f $SWTPM_PID_FILE ]; then\n"
+	cmd += "  kill $(cat $SWTPM_PID_FILE) > /dev/null 2>&1\n"
+	cmd += "  rm -f $SWTPM_PID_FILE\n"
+	cmd += "fi\n"
+	return cmd
+}
+
+// ExportNvram collects the UEFI variable store and vTPM state so that they
+// can be carried to another host on migration, backup or clone.
+func (s *SKVMGuestInstance) ExportNvram() (map[string]string, error) {
+	ret := map[string]string{}
+	dir := s.getNvramDir()
+	if !fileutils2.Exists(dir) {
+		return ret, nil
+	}
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		// skip directories and the lock file held by a running swtpm
+		if !info.Mode().IsRegular() || strings.HasPrefix(info.Name(), ".") {
+			return nil
+		}
+		content, err := ioutil.ReadFile(p)
+		if err != nil {
+			return errors.Wrapf(err, "read %s", p)
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return errors.Wrapf(err, "rel path of %s", p)
+		}
+		ret[rel] = base64.StdEncoding.EncodeToString(content)
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "walk %s", dir)
+	}
+	return ret, nil
+}
+
+func (s *SKVMGuestInstance) ImportNvram(nvram map[string]string) error {
+	dir := s.getNvramDir()
+	for rel, content := range nvram {
+		// keep imported files inside the nvram directory
+		fp := filepath.Join(dir, filepath.Clean("/"+rel))
+		data, err := base64.StdEncoding.DecodeString(content)
+		if err != nil {
+			return errors.Wrapf(err, "decode %s", rel)
+		}
+		if err := os.MkdirAll(filepath.Dir(fp), 0700); err != nil {
+			return errors.Wrapf(err, "mkdir %s", filepath.Dir(fp))
+		}
+		if err := ioutil.WriteFile(fp, data, 0600); err != nil {
+			return errors.Wrapf(err, "write %s", fp)
+		}
+	}
+	return nil
+}
+
+func (s *SKVMGuestInstance) delNvram() error {
+	dir := s.getNvramDir()
+	if !fileutils2.Exists(dir) {
+		return nil
+	}
+	output, err := procutils.NewCommand("rm", "-rf", dir).Output()
+	if err != nil {
+		return errors.Wrapf(err, "rm %s failed: %s", dir, output)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guestman
+
+import (
+	"fmt"
+	"path"
+
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/hostman/guestman/qemu"
+	"yunion.io/x/onecloud/pkg/hostman/options"
+	"yunion.io/x/onecloud/pkg/hostman/storageman"
+)
+
+func (s *SKVMGuestInstance) getSharedfs() []api.GuestSharedfsJsonDesc {
+	sharedfs := make([]api.GuestSharedfsJsonDesc, 0)
+	if s.Desc.Contains("sharedfs") {
+		s.Desc.Unmarshal(&sharedfs, "sharedfs")
+	}
+	return sharedfs
+}
+
+func (s *SKVMGuestInstance) getSharedfsSourcePath(fs api.GuestSharedfsJsonDesc) (string, error) {
+	switch fs.SourceType {
+	case api.SharedFilesystemSourceHostPath:
+		return fs.HostPath, nil
+	case api.SharedFilesystemSourceStorage:
+		storage := storageman.GetManager().GetStorage(fs.StorageId)
+		if storage == nil {
+			return "", errors.Wrapf(errors.ErrNotFound, "storage %s of shared filesystem %s", fs.StorageId, fs.Tag)
+		}
+		projectId, _ := s.Desc.GetString("tenant_id")
+		if !api.IsSharedfsStorageSubPath(projectId, fs.SubPath) {
+			return "", errors.Errorf("sub path %s of shared filesystem %s is outside of %s", fs.SubPath, fs.Tag, api.SharedfsStorageRoot(projectId))
+		}
+		return path.Join(storage.GetPath(), path.Clean("/"+fs.SubPath)), nil
+	default:
+		return "", errors.Errorf("unknown source type %q of shared filesystem %s", fs.SourceType, fs.Tag)
+	}
+}
+
+func (s *SKVMGuestInstance) getVirtiofsdSocketPath(tag string) string {
+	return path.Join(s.HomeDir(), fmt.Sprintf("virtiofsd-%s.sock", tag))
+}
+
+func (s *SKVMGuestInstance) getVirtiofsdPidFilePath(tag string) string {
+	return path.Join(s.HomeDir(), fmt.Sprintf("virtiofsd-%s.pid", tag))
+}
+
+// generateVirtiofsdStartScript starts one virtiofsd per shared filesystem and
+// waits for its vhost-user socket before qemu connects to it
+func (s *SKVMGuestInstance) generateVirtiofsdStartScript(sharedfs []api.GuestSharedfsJsonDesc) (string, []qemu.SharedfsOption, error) {
+	var (
+		cmd  string
+		opts []qemu.SharedfsOption
+	)
+	cmd += s.generateVirtiofsdStopScript()
+	for _, fs := range sharedfs {
+		source, err := s.getSharedfsSourcePath(fs)
+		if err != nil {
+			return "", nil, err
+		}
+		sock := s.getVirtiofsdSocketPath(fs.Tag)
+		args := fmt.Sprintf("--socket-path=%s --shared-dir=%s --cache=auto --sandbox=chroot", sock, source)
+		if fs.Readonly {
+			args += " --readonly"
+		}
+		cmd += fmt.Sprintf("mkdir -p %s\n", source)
+		cmd += fmt.Sprintf("rm -f %s\n", sock)
+		cmd += fmt.Sprintf("nohup %s %s > %s 2>&1 &\n", options.HostOptions.VirtiofsdPath, args,
+			path.Join(s.HomeDir(), fmt.Sprintf("virtiofsd-%s.log", fs.Tag)))
+		cmd += fmt.Sprintf("echo $! > %s\n", s.getVirtiofsdPidFilePath(fs.Tag))
+		cmd += "for i in $(seq 1 20); do\n"
+		cmd += fmt.Sprintf("  [ -S %s ] && break\n", sock)
+		cmd += "  sleep 0.5\n"
+		cmd += "done\n"
+		opts = append(opts, qemu.SharedfsOption{
+			Tag:        fs.Tag,
+			SocketPath: sock,
+		})
+	}
+	return cmd, opts, nil
+}
+
+func (s *SKVMGuestInstance) generateVirtiofsdStopScript() string {
+	cmd := fmt.Sprintf("for f in $(ls %s 2>/dev/null)\n", path.Join(s.HomeDir(), "virtiofsd-*.pid"))
+	cmd += "do\n"
+	cmd += "  kill $(cat $f) > /dev/null 2>&1\n"
+	cmd += "  rm -f $f\n"
+	cmd += "done\n"
+	return cmd
+}
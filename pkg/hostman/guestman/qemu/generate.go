@@ -33,6 +33,11 @@ type Monitor struct {
 	Mode string
 }
 
+type SharedfsOption struct {
+	Tag        string
+	SocketPath string
+}
+
 type GenerateStartOptionsInput struct {
 	QemuVersion Version
 	QemuArch    Arch
@@ -56,6 +61,10 @@ type GenerateStartOptionsInput struct {
 	Machine               string
 	BIOS                  string
 	OVMFPath              string
+	OVMFVarsPath          string
+	SecureBoot            bool
+	TPMSocketPath         string
+	Sharedfs              []SharedfsOption
 	VNCPort               uint
 	VNCPassword           bool
 	IsolatedDevicesParams *isolated_device.QemuParams
@@ -114,6 +123,12 @@ func GenerateStartOptions(
 		opts = append(opts, getMonitorOptions(drvOpt, input.QMPMonitor)...)
 	}
 
+	machineOpt := drvOpt.Machine(input.Machine, accel)
+	if input.SecureBoot && !drvOpt.IsArm() {
+		// x86 secure boot firmware requires system management mode
+		machineOpt += ",smm=on"
+	}
+
 	opts = append(opts,
 		drvOpt.RTC(),
 		drvOpt.Daemonize(),
@@ -121,7 +136,7 @@ func GenerateStartOptions(
 		drvOpt.Nodefconfig(),
 		drvOpt.NoKVMPitReinjection(),
 		drvOpt.Global(),
-		drvOpt.Machine(input.Machine, accel),
+		machineOpt,
 		drvOpt.KeyboardLayoutLanguage("en-us"),
 		drvOpt.SMP(input.Cpu),
 		drvOpt.Name(input.Name),
@@ -132,6 +147,9 @@ func GenerateStartOptions(
 	var memDev string
 	if input.HugepagesEnabled {
 		memDev = drvOpt.MemPath(input.Mem, fmt.Sprintf("/dev/hugepages/%s", input.UUID))
+	} else if len(input.Sharedfs) > 0 {
+		// vhost-user devices require guest memory shared with the backend
+		memDev = drvOpt.MemFd(input.Mem)
 	} else {
 		memDev = drvOpt.MemDev(input.Mem)
 	}
@@ -149,7 +167,24 @@ func GenerateStartOptions(
 		if input.OVMFPath == "" {
 			return "", errors.Errorf("input OVMF path is empty")
 		}
-		opts = append(opts, drvOpt.BIOS(input.OVMFPath))
+		if input.OVMFVarsPath != "" {
+			opts = append(opts, drvOpt.Pflash(input.OVMFPath, input.OVMFVarsPath)...)
+		} else {
+			opts = append(opts, drvOpt.BIOS(input.OVMFPath))
+		}
+		if input.SecureBoot {
+			opts = append(opts, drvOpt.SecureBoot()...)
+		}
+	}
+
+	// vtpm
+	if input.TPMSocketPath != "" {
+		opts = append(opts, drvOpt.TPM(input.TPMSocketPath)...)
+	}
+
+	// virtio-fs shared filesystems
+	for _, fs := range input.Sharedfs {
+		opts = append(opts, drvOpt.VirtioFs(fs.Tag, fs.SocketPath)...)
 	}
 
 	if input.OsName == OS_NAME_MACOS {
@@ -95,8 +95,13 @@ type QemuOptions interface {
 	Memory(sizeMB uint64) string
 	MemPath(sizeMB uint64, p string) string
 	MemDev(sizeMB uint64) string
+	MemFd(sizeMB uint64) string
 	Boot(order string, enableMenu bool) string
 	BIOS(file string) string
+	Pflash(codePath string, varsPath string) []string
+	SecureBoot() []string
+	TPM(socketPath string) []string
+	VirtioFs(tag string, socketPath string) []string
 	Device(devStr string) string
 	Drive(driveStr string) string
 	Spice(port uint, password string) string
@@ -248,6 +253,10 @@ func (o baseOptions) MemDev(sizeMB uint64) string {
 	return fmt.Sprintf("-object memory-backend-ram,id=mem,size=%dM -numa node,memdev=mem", sizeMB)
 }
 
+func (o baseOptions) MemFd(sizeMB uint64) string {
+	return fmt.Sprintf("-object memory-backend-memfd,id=mem,size=%dM,share=on -numa node,memdev=mem", sizeMB)
+}
+
 func (o baseOptions) Boot(order string, enableMenu bool) string {
 	opt := "-boot order=" + order
 	if enableMenu {
@@ -260,6 +269,20 @@ func (o baseOptions) BIOS(file string) string {
 	return "-bios " + file
 }
 
+func (o baseOptions) Pflash(codePath string, varsPath string) []string {
+	return []string{
+		o.Drive(fmt.Sprintf("if=pflash,format=raw,unit=0,readonly=on,file=%s", codePath)),
+		o.Drive(fmt.Sprintf("if=pflash,format=raw,unit=1,file=%s", varsPath)),
+	}
+}
+
+func (o baseOptions) VirtioFs(tag string, socketPath string) []string {
+	return []string{
+		fmt.Sprintf("-chardev socket,id=char-%s,path=%s", tag, socketPath),
+		o.Device(fmt.Sprintf("vhost-user-fs-pci,chardev=char-%s,tag=%s", tag, tag)),
+	}
+}
+
 func (o baseOptions) Device(devStr string) string {
 	return "-device " + devStr
 }
@@ -450,6 +473,19 @@ func (o baseOptions_x86_64) PvpanicDevice() string {
 	return o.Device("pvpanic")
 }
 
+func (o baseOptions_x86_64) SecureBoot() []string {
+	// only let code running in SMM write to the variable store
+	return []string{"-global driver=cfi.pflash01,property=secure,value=on"}
+}
+
+func (o baseOptions_x86_64) TPM(socketPath string) []string {
+	return []string{
+		fmt.Sprintf("-chardev socket,id=chrtpm,path=%s", socketPath),
+		"-tpmdev emulator,id=tpm0,chardev=chrtpm",
+		o.Device("tpm-crb,tpmdev=tpm0"),
+	}
+}
+
 type baseOptions_aarch64 struct {
 	*baseOptions
 }
@@ -526,3 +562,16 @@ func (o baseOptions_aarch64) PvpanicDevice() string {
 	// -device pvpanic: 'pvpanic' is not a valid device model name
 	return ""
 }
+
+func (o baseOptions_aarch64) SecureBoot() []string {
+	// AAVMF protects the variable store without SMM
+	return nil
+}
+
+func (o baseOptions_aarch64) TPM(socketPath string) []string {
+	return []string{
+		fmt.Sprintf("-chardev socket,id=chrtpm,path=%s", socketPath),
+		"-tpmdev emulator,id=tpm0,chardev=chrtpm",
+		o.Device("tpm-tis-device,tpmdev=tpm0"),
+	}
+}
@@ -68,3 +68,34 @@ func Test_baseOptions(t *testing.T) {
 	assert.Equal("-vga std", opt.VGA("std", ""))
 	assert.Equal("-vga x", opt.VGA("std", "-vga x"))
 }
+
+func Test_secureBootAndTPMOptions(t *testing.T) {
+	assert := assert.New(t)
+
+	x86 := newBaseOptions_x86_64()
+	assert.Equal([]string{
+		"-drive if=pflash,format=raw,unit=0,readonly=on,file=/opt/OVMF_CODE.fd",
+		"-drive if=pflash,format=raw,unit=1,file=/opt/sid/OVMF_VARS.fd",
+	}, x86.Pflash("/opt/OVMF_CODE.fd", "/opt/sid/OVMF_VARS.fd"))
+	assert.Equal([]string{"-global driver=cfi.pflash01,property=secure,value=on"}, x86.SecureBoot())
+	assert.Equal([]string{
+		"-chardev socket,id=chrtpm,path=/opt/sid/swtpm.sock",
+		"-tpmdev emulator,id=tpm0,chardev=chrtpm",
+		"-device tpm-crb,tpmdev=tpm0",
+	}, x86.TPM("/opt/sid/swtpm.sock"))
+
+	arm := newBaseOptions_aarch64()
+	assert.Nil(arm.SecureBoot())
+	assert.Equal("-device tpm-tis-device,tpmdev=tpm0", arm.TPM("/opt/sid/swtpm.sock")[2])
+}
+
+func Test_virtioFsOptions(t *testing.T) {
+	assert := assert.New(t)
+
+	x86 := newBaseOptions_x86_64()
+	assert.Equal("-object memory-backend-memfd,id=mem,size=1024M,share=on -numa node,memdev=mem", x86.MemFd(1024))
+	assert.Equal([]string{
+		"-chardev socket,id=char-data,path=/opt/sid/virtiofs-data.sock",
+		"-device vhost-user-fs-pci,chardev=char-data,tag=data",
+	}, x86.VirtioFs("data", "/opt/sid/virtiofs-data.sock"))
+}
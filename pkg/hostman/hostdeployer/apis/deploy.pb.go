@@ -30,17 +30,18 @@ var _ = math.Inf
 const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
 
 type GuestDesc struct {
-	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
-	Uuid                 string   `protobuf:"bytes,2,opt,name=uuid,proto3" json:"uuid,omitempty"`
-	Domain               string   `protobuf:"bytes,3,opt,name=domain,proto3" json:"domain,omitempty"`
-	Nics                 []*Nic   `protobuf:"bytes,4,rep,name=nics,proto3" json:"nics,omitempty"`
-	NicsStandby          []*Nic   `protobuf:"bytes,5,rep,name=nics_standby,json=nicsStandby,proto3" json:"nics_standby,omitempty"`
-	Disks                []*Disk  `protobuf:"bytes,6,rep,name=disks,proto3" json:"disks,omitempty"`
-	Hypervisor           string   `protobuf:"bytes,7,opt,name=Hypervisor,proto3" json:"Hypervisor,omitempty"`
-	Hostname             string   `protobuf:"bytes,8,opt,name=hostname,proto3" json:"hostname,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	Name                 string      `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Uuid                 string      `protobuf:"bytes,2,opt,name=uuid,proto3" json:"uuid,omitempty"`
+	Domain               string      `protobuf:"bytes,3,opt,name=domain,proto3" json:"domain,omitempty"`
+	Nics                 []*Nic      `protobuf:"bytes,4,rep,name=nics,proto3" json:"nics,omitempty"`
+	NicsStandby          []*Nic      `protobuf:"bytes,5,rep,name=nics_standby,json=nicsStandby,proto3" json:"nics_standby,omitempty"`
+	Disks                []*Disk     `protobuf:"bytes,6,rep,name=disks,proto3" json:"disks,omitempty"`
+	Hypervisor           string      `protobuf:"bytes,7,opt,name=Hypervisor,proto3" json:"Hypervisor,omitempty"`
+	Hostname             string      `protobuf:"bytes,8,opt,name=hostname,proto3" json:"hostname,omitempty"`
+	Sharedfs             []*SharedFs `protobuf:"bytes,9,rep,name=sharedfs,proto3" json:"sharedfs,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
+	XXX_unrecognized     []byte      `json:"-"`
+	XXX_sizecache        int32       `json:"-"`
 }
 
 func (m *GuestDesc) Reset()         { *m = GuestDesc{} }
@@ -124,6 +125,13 @@ func (m *GuestDesc) GetHostname() string {
 	return ""
 }
 
+func (m *GuestDesc) GetSharedfs() []*SharedFs {
+	if m != nil {
+		return m.Sharedfs
+	}
+	return nil
+}
+
 type Disk struct {
 	DiskId               string   `protobuf:"bytes,1,opt,name=disk_id,json=diskId,proto3" json:"disk_id,omitempty"`
 	Driver               string   `protobuf:"bytes,2,opt,name=driver,proto3" json:"driver,omitempty"`
@@ -1521,6 +1529,61 @@ func (m *EsxiDisksConnectionInfo) GetDisks() []*EsxiDiskInfo {
 	return nil
 }
 
+type SharedFs struct {
+	Tag                  string   `protobuf:"bytes,1,opt,name=tag,proto3" json:"tag,omitempty"`
+	Mountpoint           string   `protobuf:"bytes,2,opt,name=mountpoint,proto3" json:"mountpoint,omitempty"`
+	Readonly             bool     `protobuf:"varint,3,opt,name=readonly,proto3" json:"readonly,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SharedFs) Reset()         { *m = SharedFs{} }
+func (m *SharedFs) String() string { return proto.CompactTextString(m) }
+func (*SharedFs) ProtoMessage()    {}
+func (*SharedFs) Descriptor() ([]byte, []int) {
+	return fileDescriptor_dd896c9c60108af6, []int{20}
+}
+
+func (m *SharedFs) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SharedFs.Unmarshal(m, b)
+}
+func (m *SharedFs) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SharedFs.Marshal(b, m, deterministic)
+}
+func (m *SharedFs) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SharedFs.Merge(m, src)
+}
+func (m *SharedFs) XXX_Size() int {
+	return xxx_messageInfo_SharedFs.Size(m)
+}
+func (m *SharedFs) XXX_DiscardUnknown() {
+	xxx_messageInfo_SharedFs.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SharedFs proto.InternalMessageInfo
+
+func (m *SharedFs) GetTag() string {
+	if m != nil {
+		return m.Tag
+	}
+	return ""
+}
+
+func (m *SharedFs) GetMountpoint() string {
+	if m != nil {
+		return m.Mountpoint
+	}
+	return ""
+}
+
+func (m *SharedFs) GetReadonly() bool {
+	if m != nil {
+		return m.Readonly
+	}
+	return false
+}
+
 func init() {
 	proto.RegisterType((*GuestDesc)(nil), "apis.GuestDesc")
 	proto.RegisterType((*Disk)(nil), "apis.Disk")
@@ -1542,6 +1605,7 @@ func init() {
 	proto.RegisterType((*EsxiDiskInfo)(nil), "apis.EsxiDiskInfo")
 	proto.RegisterType((*ConnectEsxiDisksParams)(nil), "apis.ConnectEsxiDisksParams")
 	proto.RegisterType((*EsxiDisksConnectionInfo)(nil), "apis.EsxiDisksConnectionInfo")
+	proto.RegisterType((*SharedFs)(nil), "apis.SharedFs")
 }
 
 func init() {
@@ -1549,117 +1613,121 @@ func init() {
 }
 
 var fileDescriptor_dd896c9c60108af6 = []byte{
-	// 1760 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xb4, 0x57, 0xdd, 0x6e, 0x1c, 0x49,
-	0x15, 0xd6, 0xfc, 0x4f, 0x9f, 0xf1, 0x5f, 0x2a, 0x8e, 0xdd, 0x3b, 0xd9, 0xec, 0x5a, 0x83, 0x40,
-	0x56, 0xc8, 0x3a, 0xc2, 0x01, 0x6e, 0x10, 0x12, 0x91, 0xbd, 0xd9, 0xb5, 0xc2, 0x2e, 0x56, 0x3b,
-	0x81, 0x3b, 0x5a, 0xe5, 0xee, 0x9a, 0x99, 0xc2, 0xdd, 0x55, 0xad, 0xae, 0x9a, 0x99, 0x0c, 0xd2,
-	0xbe, 0x11, 0x57, 0x48, 0xdc, 0x72, 0xc9, 0x3b, 0xf0, 0x0a, 0x3c, 0x00, 0xdc, 0xa2, 0x73, 0xaa,
-	0xba, 0xa7, 0xc7, 0x1b, 0xb2, 0xb9, 0xe1, 0xaa, 0xeb, 0xfc, 0x54, 0xd5, 0xf9, 0xfd, 0x4e, 0x35,
-	0x3c, 0x2d, 0xee, 0x66, 0xcf, 0xe7, 0xda, 0xd8, 0x9c, 0x2b, 0xfa, 0xa6, 0xa2, 0xc8, 0xf4, 0x5a,
-	0x94, 0xcf, 0x79, 0x21, 0xcd, 0x73, 0x47, 0x9d, 0x15, 0xa5, 0xb6, 0x9a, 0x75, 0x91, 0x35, 0xf9,
-	0x77, 0x0b, 0x82, 0xaf, 0x16, 0xc2, 0xd8, 0x4b, 0x61, 0x12, 0xc6, 0xa0, 0xab, 0x78, 0x2e, 0xc2,
-	0xd6, 0x49, 0xeb, 0x34, 0x88, 0x68, 0x8d, 0xbc, 0xc5, 0x42, 0xa6, 0x61, 0xdb, 0xf1, 0x70, 0xcd,
-	0x8e, 0xa0, 0x9f, 0xea, 0x9c, 0x4b, 0x15, 0x76, 0x88, 0xeb, 0x29, 0xf6, 0x04, 0xba, 0x4a, 0x26,
-	0x26, 0xec, 0x9e, 0x74, 0x4e, 0x47, 0xe7, 0xc1, 0x19, 0x5e, 0x71, 0xf6, 0xad, 0x4c, 0x22, 0x62,
-	0xb3, 0x67, 0xb0, 0x83, 0xdf, 0xd8, 0x58, 0xae, 0xd2, 0xdb, 0x75, 0xd8, 0xbb, 0xaf, 0x36, 0x42,
-	0xf1, 0x8d, 0x93, 0xb2, 0x13, 0xe8, 0xa5, 0xd2, 0xdc, 0x99, 0xb0, 0x4f, 0x6a, 0xe0, 0xd4, 0x2e,
-	0xa5, 0xb9, 0x8b, 0x9c, 0x80, 0x7d, 0x06, 0xf0, 0xf5, 0xba, 0x10, 0xe5, 0x52, 0x1a, 0x5d, 0x86,
-	0x03, 0x32, 0xa5, 0xc1, 0x61, 0x63, 0x18, 0x62, 0x10, 0xc8, 0xa5, 0x21, 0x49, 0x6b, 0x7a, 0xf2,
-	0xcf, 0x0e, 0x74, 0xf1, 0x2c, 0x76, 0x0c, 0x03, 0x3c, 0x2d, 0x96, 0xa9, 0x77, 0xbb, 0x8f, 0xe4,
-	0x95, 0x73, 0xb2, 0x94, 0x4b, 0x51, 0x7a, 0xd7, 0x3d, 0xc5, 0x9e, 0x00, 0x24, 0x3c, 0x99, 0x8b,
-	0x38, 0xd7, 0xa9, 0xf0, 0x01, 0x08, 0x88, 0xf3, 0x8d, 0x4e, 0x05, 0xfb, 0x04, 0x86, 0x5c, 0x6a,
-	0x27, 0xec, 0x92, 0x70, 0xc0, 0xa5, 0x26, 0x11, 0x83, 0xae, 0x91, 0x7f, 0x16, 0x61, 0xef, 0xa4,
-	0x75, 0xda, 0x89, 0x68, 0xcd, 0x3e, 0x87, 0x91, 0x15, 0x79, 0x91, 0x71, 0x2b, 0xd0, 0x84, 0xbe,
-	0x73, 0xa2, 0x62, 0x5d, 0xa5, 0x78, 0x9d, 0xcc, 0xf9, 0x4c, 0xc4, 0x05, 0xb7, 0x73, 0xef, 0x64,
-	0x40, 0x9c, 0x6b, 0x6e, 0xe7, 0x28, 0x36, 0x56, 0x97, 0xa8, 0x20, 0x53, 0xef, 0x65, 0xe0, 0x39,
-	0x57, 0x29, 0xfb, 0x14, 0x82, 0x5c, 0xce, 0x4a, 0x6e, 0xa5, 0x9a, 0x85, 0xc1, 0x49, 0xeb, 0x74,
-	0x18, 0x6d, 0x18, 0xec, 0x29, 0x3c, 0xb0, 0xbc, 0x9c, 0x09, 0x1b, 0x37, 0xce, 0x00, 0x3a, 0x63,
-	0xdf, 0x09, 0x6e, 0xea, 0x93, 0x18, 0x74, 0xc9, 0x82, 0x91, 0xab, 0x03, 0x5c, 0x63, 0x88, 0xa6,
-	0xba, 0xcc, 0xb9, 0x0d, 0x77, 0x5c, 0x88, 0x1c, 0xc5, 0x0e, 0xa1, 0x27, 0x55, 0x2a, 0xde, 0x85,
-	0xbb, 0x27, 0xad, 0xd3, 0x5e, 0xe4, 0x08, 0xf6, 0x63, 0xd8, 0xcb, 0x45, 0x39, 0x13, 0xb1, 0x51,
-	0xbc, 0x30, 0x73, 0x6d, 0xc3, 0x3d, 0x32, 0x68, 0x97, 0xb8, 0x37, 0x9e, 0xc9, 0xf6, 0xa0, 0x3d,
-	0x35, 0xe1, 0x3e, 0x1d, 0xd8, 0x9e, 0x52, 0x96, 0x73, 0xbd, 0x50, 0xb6, 0xd0, 0x52, 0xd9, 0xf0,
-	0xc0, 0x05, 0x68, 0xc3, 0x61, 0x07, 0xd0, 0x49, 0xc5, 0x32, 0x7c, 0x40, 0x02, 0x5c, 0x4e, 0xfe,
-	0xd5, 0x85, 0xce, 0xb7, 0x32, 0x41, 0x49, 0xce, 0x13, 0x9f, 0x56, 0x5c, 0xe2, 0xd9, 0xb2, 0xf0,
-	0xf9, 0x6c, 0xcb, 0x02, 0x35, 0x94, 0xb0, 0x3e, 0x89, 0xb8, 0x64, 0x8f, 0xa0, 0xaf, 0x84, 0xc5,
-	0x38, 0xb8, 0xe4, 0xf5, 0x94, 0xb0, 0x57, 0x29, 0x0b, 0x61, 0xb0, 0x94, 0xa5, 0x5d, 0xf0, 0x8c,
-	0xb2, 0x37, 0x8c, 0x2a, 0x12, 0x25, 0x33, 0x6e, 0xc5, 0x8a, 0xaf, 0x7d, 0xf2, 0x2a, 0x92, 0x0c,
-	0x53, 0xc6, 0xa7, 0x0c, 0x97, 0x8d, 0xbe, 0x19, 0x6e, 0xf5, 0xcd, 0x11, 0xf4, 0x4b, 0xbd, 0xb0,
-	0xc2, 0x50, 0x8a, 0x82, 0xc8, 0x53, 0xc8, 0x97, 0x53, 0x2a, 0x5f, 0x97, 0x14, 0x4f, 0xe1, 0x9d,
-	0x39, 0x37, 0x77, 0x99, 0x50, 0x94, 0x8e, 0x5e, 0x54, 0x91, 0x8d, 0xa2, 0xdd, 0xd9, 0x2a, 0xda,
-	0x23, 0xe8, 0xdf, 0x96, 0x32, 0x9d, 0x09, 0x4a, 0x49, 0x10, 0x79, 0x0a, 0xab, 0x7f, 0x25, 0x4b,
-	0xca, 0xfb, 0x9e, 0x13, 0x20, 0xe9, 0xd2, 0xbd, 0xcc, 0xb8, 0xa2, 0x3c, 0xf4, 0x22, 0x5a, 0x63,
-	0x31, 0x49, 0x65, 0x45, 0x39, 0xe5, 0x89, 0xf0, 0x89, 0xd8, 0x30, 0x30, 0xb6, 0xb7, 0x2b, 0x4a,
-	0x43, 0x2f, 0x6a, 0xdf, 0xae, 0x36, 0x45, 0xc0, 0x9a, 0x45, 0xf0, 0x39, 0x8c, 0x7c, 0xe4, 0x62,
-	0x59, 0x98, 0xf0, 0xe1, 0x49, 0x07, 0xd3, 0xe9, 0x59, 0x57, 0x85, 0x41, 0x05, 0xf1, 0xce, 0x8a,
-	0x52, 0x89, 0x0c, 0xad, 0x3a, 0x74, 0xf9, 0xae, 0x58, 0x57, 0x29, 0x7b, 0x0c, 0x81, 0x15, 0x3c,
-	0x8f, 0x57, 0xd2, 0xce, 0xc3, 0x47, 0xae, 0xad, 0x91, 0xf1, 0x07, 0xe9, 0x2a, 0x32, 0xe7, 0x0a,
-	0xd3, 0x74, 0x44, 0x69, 0xf2, 0x14, 0x76, 0xa5, 0x92, 0x49, 0x6c, 0xd7, 0x85, 0x08, 0x8f, 0x5d,
-	0x9a, 0x94, 0x4c, 0xde, 0xac, 0x0b, 0x0a, 0x41, 0x26, 0xd5, 0x5d, 0xbc, 0x28, 0xc2, 0xd0, 0xed,
-	0x41, 0xf2, 0x2d, 0x15, 0x47, 0x6e, 0x17, 0xe1, 0x27, 0xd4, 0xad, 0xb8, 0xac, 0xf1, 0x71, 0xbc,
-	0xc1, 0xc7, 0xc9, 0x0a, 0x46, 0xbf, 0xbf, 0xbc, 0x7c, 0x7d, 0xa1, 0xd5, 0x95, 0x9a, 0x6a, 0x54,
-	0x41, 0x8c, 0xa9, 0x20, 0x14, 0xd7, 0xd4, 0x3a, 0xba, 0xb4, 0x54, 0x77, 0xbd, 0x88, 0xd6, 0x04,
-	0xab, 0x46, 0x94, 0xbe, 0xf4, 0x68, 0x8d, 0xc6, 0x17, 0xdc, 0x98, 0x55, 0x55, 0x7b, 0x9e, 0xc2,
-	0x48, 0x2e, 0xf3, 0x52, 0x4c, 0xa9, 0xf4, 0x82, 0xc8, 0x11, 0x93, 0xff, 0xb4, 0x01, 0x2e, 0x09,
-	0xd1, 0xe9, 0xe2, 0x67, 0x00, 0xc5, 0xe2, 0x36, 0x93, 0x49, 0x7c, 0x27, 0xd6, 0x74, 0xfd, 0xe8,
-	0x7c, 0xd7, 0x61, 0xe6, 0xcd, 0xcd, 0xd7, 0xaf, 0xc5, 0xda, 0x44, 0x81, 0x53, 0x78, 0x2d, 0xd6,
-	0xec, 0x0b, 0x18, 0xb8, 0x69, 0x60, 0xc2, 0x36, 0xc1, 0xeb, 0x43, 0x0f, 0xaf, 0xc4, 0xbc, 0xd0,
-	0xca, 0x0a, 0x65, 0xa3, 0x4a, 0x07, 0x91, 0x94, 0x6c, 0xd1, 0x65, 0xea, 0x2d, 0xae, 0x69, 0x8c,
-	0x9f, 0x34, 0xb1, 0x54, 0xd2, 0x92, 0xd9, 0xc3, 0xa8, 0x2f, 0xcd, 0x95, 0x92, 0x16, 0xa1, 0x49,
-	0x28, 0x7e, 0x9b, 0x89, 0xd8, 0xda, 0xb5, 0x6f, 0x9b, 0xc0, 0x71, 0xde, 0xd8, 0x35, 0x82, 0x4f,
-	0x2a, 0xa6, 0x7c, 0x91, 0xd9, 0xb8, 0xd4, 0xda, 0xc6, 0x14, 0x8e, 0x3e, 0x69, 0xed, 0x7b, 0x41,
-	0xa4, 0xb5, 0x7d, 0x8b, 0x91, 0xf9, 0x15, 0x8c, 0x57, 0x52, 0xa5, 0x7a, 0x65, 0xe2, 0x6a, 0x0f,
-	0x4f, 0x73, 0xa9, 0xdc, 0xa6, 0x01, 0x6d, 0x3a, 0xf6, 0x1a, 0x97, 0x4e, 0xe1, 0x25, 0xca, 0x69,
-	0xf3, 0x53, 0x78, 0xe0, 0xed, 0x48, 0x32, 0xbd, 0x48, 0x9d, 0xa9, 0x43, 0x77, 0x91, 0x13, 0x5c,
-	0x20, 0x9f, 0x6c, 0xfe, 0x11, 0xec, 0x66, 0x7a, 0x26, 0x55, 0xcc, 0x93, 0x04, 0x21, 0xc6, 0x37,
-	0xe4, 0x0e, 0x31, 0x5f, 0x3a, 0xde, 0xe4, 0x2f, 0x2d, 0x18, 0xf8, 0x98, 0xa2, 0x93, 0xf7, 0xc2,
-	0x1e, 0x34, 0xe3, 0x4c, 0x4e, 0x66, 0xc2, 0x8a, 0xb8, 0xa1, 0xe5, 0xf0, 0x67, 0xdf, 0x09, 0xae,
-	0x6b, 0xdd, 0x53, 0x38, 0x70, 0x4e, 0x35, 0x54, 0x5d, 0xb0, 0xf7, 0x88, 0xbf, 0xd1, 0x7c, 0x06,
-	0xac, 0x28, 0xf5, 0x9f, 0x44, 0x62, 0x9b, 0xba, 0xae, 0x68, 0x0e, 0xbc, 0xa4, 0xd6, 0x9e, 0xbc,
-	0x85, 0xdd, 0xad, 0xb4, 0xd6, 0x50, 0xde, 0x6a, 0x40, 0x79, 0x08, 0x83, 0xc4, 0x89, 0xbd, 0x79,
-	0x15, 0x89, 0x55, 0xc9, 0x13, 0x2b, 0x75, 0x3d, 0xec, 0x1d, 0x35, 0x19, 0x40, 0xef, 0xcb, 0xbc,
-	0xb0, 0xeb, 0xc9, 0xdf, 0x5a, 0xf0, 0xc8, 0x5d, 0x40, 0x2f, 0x89, 0x57, 0x26, 0x12, 0xa6, 0xd0,
-	0xca, 0x08, 0x42, 0x23, 0x69, 0x6c, 0xa9, 0x1b, 0xa3, 0xd5, 0x96, 0x9a, 0xd0, 0x54, 0x94, 0x06,
-	0xcf, 0xf4, 0x97, 0x79, 0x12, 0x4d, 0xe3, 0x65, 0x32, 0xaf, 0xda, 0x02, 0xd7, 0x58, 0x7c, 0x19,
-	0x57, 0xb3, 0x05, 0x9f, 0x55, 0x13, 0xb5, 0xa6, 0x11, 0x74, 0xb4, 0xf1, 0x7d, 0xd1, 0xd6, 0x06,
-	0x4f, 0xae, 0x32, 0xe7, 0xd1, 0xd8, 0x93, 0xd8, 0xcd, 0x18, 0x24, 0x8f, 0xc6, 0x77, 0x62, 0x3d,
-	0xf9, 0x7b, 0x0b, 0x76, 0x9c, 0xdd, 0xd7, 0xbc, 0xe4, 0xb9, 0x41, 0x64, 0xa1, 0xa7, 0x40, 0x23,
-	0x38, 0x43, 0x64, 0xd0, 0xa0, 0x3d, 0x03, 0x98, 0xa1, 0x7b, 0x71, 0x2a, 0x4c, 0x42, 0x66, 0x8f,
-	0xce, 0xf7, 0x5d, 0xd3, 0xd4, 0x0f, 0xa8, 0x28, 0x98, 0xd5, 0x6f, 0xa9, 0x9f, 0xc1, 0xc8, 0x75,
-	0x4f, 0x2c, 0xd5, 0x54, 0x93, 0x43, 0xa3, 0xf3, 0x83, 0x66, 0x97, 0x61, 0xdb, 0x46, 0x90, 0x6e,
-	0x5a, 0xf8, 0x0c, 0x82, 0x65, 0x9a, 0xde, 0xb9, 0x0d, 0x5d, 0xda, 0xf0, 0xc0, 0x6d, 0x68, 0x20,
-	0x4c, 0x34, 0x44, 0x1d, 0x5c, 0x4d, 0xbe, 0x83, 0xbd, 0x48, 0xe0, 0x2b, 0xe2, 0x95, 0xf9, 0x18,
-	0x0f, 0x3e, 0x03, 0x98, 0x6f, 0x9e, 0x4b, 0x2e, 0xf0, 0x0d, 0xce, 0xf6, 0xf5, 0x9d, 0x1f, 0xbe,
-	0xfe, 0x8f, 0xb0, 0xf7, 0x8a, 0xe6, 0xfd, 0xc7, 0x5d, 0xff, 0x18, 0x82, 0xa9, 0x89, 0xfd, 0x7b,
-	0xc1, 0xdd, 0x3e, 0x9c, 0x1a, 0x77, 0x42, 0xfd, 0xca, 0xec, 0x6c, 0x5e, 0x99, 0x13, 0x0d, 0xa3,
-	0x48, 0x64, 0x82, 0x1b, 0x41, 0xd1, 0xf9, 0xbf, 0x17, 0xd3, 0xe4, 0x1b, 0x60, 0x37, 0x7c, 0x29,
-	0xde, 0xe8, 0xaf, 0x32, 0xae, 0x12, 0xf1, 0x31, 0x4e, 0x8d, 0x61, 0x98, 0xe8, 0xbc, 0x28, 0x85,
-	0x31, 0x74, 0xfb, 0x30, 0xaa, 0xe9, 0x89, 0x80, 0xc3, 0xe6, 0x71, 0x75, 0x57, 0x1c, 0xc3, 0x40,
-	0x1b, 0x17, 0x65, 0xef, 0x89, 0x36, 0xe4, 0xe1, 0xcf, 0x61, 0xa7, 0x74, 0x0e, 0x3b, 0x69, 0xbb,
-	0x99, 0x83, 0x46, 0x28, 0xa2, 0x51, 0xb9, 0x21, 0x26, 0x2f, 0xe0, 0xf0, 0xba, 0xd4, 0xb7, 0xe2,
-	0x0a, 0xdf, 0x84, 0xc8, 0xb9, 0x2e, 0x79, 0xce, 0x3f, 0x6c, 0xf7, 0xe4, 0xaf, 0x6d, 0x08, 0xea,
-	0x0d, 0xec, 0xe9, 0xb6, 0x45, 0xef, 0xbd, 0xb3, 0x32, 0xd2, 0x59, 0x4f, 0x83, 0xb4, 0x5d, 0x59,
-	0x4f, 0x73, 0xf4, 0x27, 0xb0, 0x2f, 0x4d, 0xbc, 0x10, 0x53, 0x19, 0x9b, 0x45, 0x41, 0x03, 0xaf,
-	0xe3, 0xde, 0x77, 0xd2, 0xbc, 0x15, 0x53, 0x79, 0xe3, 0x98, 0x08, 0x73, 0xd2, 0xc4, 0xd9, 0x32,
-	0x8f, 0x0b, 0x5e, 0x5a, 0x49, 0xc8, 0xe2, 0x06, 0xc7, 0x9e, 0x34, 0xbf, 0x5d, 0xe6, 0xd7, 0x15,
-	0x17, 0x9f, 0x02, 0xd2, 0xc4, 0xa5, 0xe0, 0xa9, 0x56, 0x59, 0x35, 0x41, 0x40, 0x9a, 0xc8, 0x73,
-	0xd8, 0x2f, 0xe1, 0xb8, 0x98, 0xaf, 0x8d, 0x4c, 0x78, 0xb6, 0x39, 0xcc, 0xd9, 0xe6, 0xba, 0xff,
-	0x51, 0x25, 0xae, 0x0f, 0x25, 0x53, 0x7f, 0x01, 0xc7, 0x34, 0xb2, 0x8c, 0xe5, 0x59, 0x26, 0xd2,
-	0xe6, 0x5c, 0x70, 0xb3, 0xe4, 0x10, 0x47, 0x98, 0x97, 0xd6, 0xc3, 0x61, 0xf2, 0x53, 0xd8, 0xf9,
-	0xd2, 0xbc, 0x93, 0xf8, 0xdb, 0x40, 0xa1, 0xf8, 0x60, 0x84, 0xbf, 0x83, 0xa3, 0x0b, 0xad, 0x94,
-	0x48, 0x6c, 0xb5, 0xa7, 0xea, 0x92, 0xad, 0x3e, 0x6b, 0xfd, 0x60, 0x9f, 0xb1, 0x17, 0x30, 0xe2,
-	0x49, 0x22, 0x8c, 0xa9, 0xaa, 0x02, 0xe7, 0x35, 0x73, 0x3b, 0x9a, 0xf6, 0x44, 0xe0, 0xd4, 0xa8,
-	0x2a, 0x2e, 0xe0, 0xb8, 0xbe, 0xd7, 0xdb, 0x21, 0xfd, 0x13, 0xe5, 0xb4, 0xfa, 0xb1, 0x6a, 0xfd,
-	0xcf, 0x93, 0x9c, 0xc2, 0xf9, 0x3f, 0x3a, 0x30, 0x72, 0x58, 0xf5, 0x72, 0x86, 0xa3, 0xe0, 0x37,
-	0xd5, 0x24, 0xf1, 0x40, 0xcf, 0x58, 0x13, 0xcf, 0x9c, 0x7b, 0xe3, 0xc7, 0x4d, 0xde, 0xfd, 0x89,
-	0xf0, 0x05, 0x0c, 0x2b, 0xc8, 0x62, 0x87, 0x55, 0x91, 0x35, 0x21, 0x6c, 0x3c, 0xf2, 0xe6, 0xe0,
-	0x68, 0x41, 0xf5, 0x0a, 0x62, 0x2a, 0xf5, 0x6d, 0xc8, 0xd9, 0x56, 0xbf, 0x84, 0x9d, 0x66, 0xc7,
-	0xb1, 0xd0, 0xbf, 0x7f, 0xbe, 0xd7, 0xd4, 0xe3, 0xf1, 0xf7, 0x25, 0xb5, 0x8d, 0xbf, 0x86, 0xbd,
-	0xed, 0x86, 0x62, 0x5e, 0xfb, 0x7d, 0x6d, 0x36, 0xf6, 0x33, 0x60, 0xa3, 0xfc, 0x3b, 0x38, 0xb8,
-	0x9f, 0x78, 0xf6, 0xa9, 0x53, 0x7a, 0x7f, 0x41, 0x8c, 0x9f, 0x6c, 0x67, 0xe0, 0x7e, 0xbe, 0x5e,
-	0xc2, 0xc3, 0x4b, 0x69, 0x92, 0xfb, 0x67, 0x7e, 0x78, 0xd7, 0x56, 0x60, 0x6e, 0xfb, 0xf4, 0xcf,
-	0xff, 0xe2, 0xbf, 0x01, 0x00, 0x00, 0xff, 0xff, 0x05, 0x65, 0xaf, 0xd2, 0x21, 0x10, 0x00, 0x00,
+	// 1811 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xb4, 0x58, 0xcd, 0x6e, 0x1b, 0xc9,
+	0x11, 0x06, 0xff, 0x39, 0x45, 0x99, 0x92, 0xdb, 0xb2, 0x35, 0x4b, 0xaf, 0x77, 0x05, 0x06, 0x09,
+	0x04, 0xc5, 0x2b, 0x23, 0x72, 0x92, 0x4b, 0x10, 0x20, 0x86, 0xb4, 0xde, 0x15, 0x9c, 0xdd, 0x08,
+	0x23, 0x3b, 0xc9, 0x29, 0x83, 0xd6, 0x4c, 0x93, 0xec, 0x68, 0xa6, 0x7b, 0x30, 0xdd, 0xa4, 0xcc,
+	0x00, 0xfb, 0x32, 0x39, 0xe7, 0x14, 0x20, 0xd7, 0x1c, 0xf3, 0x0e, 0x79, 0x85, 0xbc, 0x40, 0xae,
+	0x41, 0x55, 0xf7, 0x0c, 0x87, 0xb2, 0xe3, 0xf5, 0x65, 0x4f, 0xec, 0xfa, 0xaa, 0xba, 0xab, 0xba,
+	0x7e, 0x7b, 0x08, 0xc7, 0xc5, 0xcd, 0xfc, 0xd9, 0x42, 0x1b, 0x9b, 0x73, 0x45, 0xbf, 0xa9, 0x28,
+	0x32, 0xbd, 0x16, 0xe5, 0x33, 0x5e, 0x48, 0xf3, 0xcc, 0x51, 0x27, 0x45, 0xa9, 0xad, 0x66, 0x5d,
+	0x84, 0xa6, 0x7f, 0x6d, 0x43, 0xf0, 0xd5, 0x52, 0x18, 0x7b, 0x2e, 0x4c, 0xc2, 0x18, 0x74, 0x15,
+	0xcf, 0x45, 0xd8, 0x3a, 0x6c, 0x1d, 0x05, 0x11, 0xad, 0x11, 0x5b, 0x2e, 0x65, 0x1a, 0xb6, 0x1d,
+	0x86, 0x6b, 0xf6, 0x08, 0xfa, 0xa9, 0xce, 0xb9, 0x54, 0x61, 0x87, 0x50, 0x4f, 0xb1, 0x27, 0xd0,
+	0x55, 0x32, 0x31, 0x61, 0xf7, 0xb0, 0x73, 0x34, 0x3a, 0x0d, 0x4e, 0x50, 0xc5, 0xc9, 0xb7, 0x32,
+	0x89, 0x08, 0x66, 0x4f, 0x61, 0x07, 0x7f, 0x63, 0x63, 0xb9, 0x4a, 0xaf, 0xd7, 0x61, 0xef, 0xae,
+	0xd8, 0x08, 0xd9, 0x57, 0x8e, 0xcb, 0x0e, 0xa1, 0x97, 0x4a, 0x73, 0x63, 0xc2, 0x3e, 0x89, 0x81,
+	0x13, 0x3b, 0x97, 0xe6, 0x26, 0x72, 0x0c, 0xf6, 0x19, 0xc0, 0xd7, 0xeb, 0x42, 0x94, 0x2b, 0x69,
+	0x74, 0x19, 0x0e, 0xc8, 0x94, 0x06, 0xc2, 0x26, 0x30, 0x44, 0x27, 0xd0, 0x95, 0x86, 0xc4, 0xad,
+	0x69, 0x76, 0x0c, 0x43, 0xb3, 0xe0, 0xa5, 0x48, 0x67, 0x26, 0x0c, 0x48, 0xc1, 0xd8, 0x29, 0xb8,
+	0x22, 0xf4, 0xa5, 0x89, 0x6a, 0xfe, 0xf4, 0xdf, 0x1d, 0xe8, 0xa2, 0x5e, 0x76, 0x00, 0x03, 0xd4,
+	0x1c, 0xcb, 0xd4, 0xbb, 0xa8, 0x8f, 0xe4, 0x85, 0x73, 0x48, 0x29, 0x57, 0xa2, 0xf4, 0x6e, 0xf2,
+	0x14, 0x7b, 0x02, 0x90, 0xf0, 0x64, 0x21, 0xe2, 0x5c, 0xa7, 0xc2, 0x3b, 0x2b, 0x20, 0xe4, 0x1b,
+	0x9d, 0x0a, 0xf6, 0x09, 0x0c, 0xb9, 0xd4, 0x8e, 0xd9, 0x25, 0xe6, 0x80, 0x4b, 0x4d, 0x2c, 0x06,
+	0x5d, 0x23, 0xff, 0x22, 0xc2, 0xde, 0x61, 0xeb, 0xa8, 0x13, 0xd1, 0x9a, 0x7d, 0x0e, 0x23, 0x2b,
+	0xf2, 0x22, 0xe3, 0x56, 0xa0, 0x09, 0x7d, 0x77, 0xe1, 0x0a, 0xba, 0x48, 0x51, 0x9d, 0xcc, 0xf9,
+	0x5c, 0xc4, 0x05, 0xb7, 0x0b, 0xef, 0x90, 0x80, 0x90, 0x4b, 0x6e, 0x17, 0xc8, 0x36, 0x56, 0x97,
+	0x28, 0x20, 0x53, 0xef, 0x91, 0xc0, 0x23, 0x17, 0x29, 0xfb, 0x14, 0x82, 0x5c, 0xce, 0x4b, 0x6e,
+	0xa5, 0x9a, 0x87, 0xc1, 0x61, 0xeb, 0x68, 0x18, 0x6d, 0x00, 0x76, 0x0c, 0xf7, 0x2d, 0x2f, 0xe7,
+	0xc2, 0xc6, 0x8d, 0x33, 0x80, 0xce, 0xd8, 0x75, 0x8c, 0xab, 0xfa, 0x24, 0x06, 0x5d, 0xb2, 0x60,
+	0xe4, 0x72, 0x06, 0xd7, 0xe8, 0xa2, 0x99, 0x2e, 0x73, 0x6e, 0xc3, 0x1d, 0xe7, 0x22, 0x47, 0xb1,
+	0x7d, 0xe8, 0x49, 0x95, 0x8a, 0xb7, 0xe1, 0xbd, 0xc3, 0xd6, 0x51, 0x2f, 0x72, 0x04, 0xfb, 0x31,
+	0x8c, 0x73, 0x51, 0xce, 0x45, 0x6c, 0x14, 0x2f, 0xcc, 0x42, 0xdb, 0x70, 0x4c, 0x06, 0xdd, 0x23,
+	0xf4, 0xca, 0x83, 0x6c, 0x0c, 0xed, 0x99, 0x09, 0x77, 0xe9, 0xc0, 0xf6, 0x8c, 0x32, 0x22, 0xd7,
+	0x4b, 0x65, 0x0b, 0x2d, 0x95, 0x0d, 0xf7, 0x9c, 0x83, 0x36, 0x08, 0xdb, 0x83, 0x4e, 0x2a, 0x56,
+	0xe1, 0x7d, 0x62, 0xe0, 0x72, 0xfa, 0x9f, 0x2e, 0x74, 0xbe, 0x95, 0x09, 0x72, 0x72, 0x9e, 0xf8,
+	0xb0, 0xe2, 0x12, 0xcf, 0x96, 0x85, 0x8f, 0x67, 0x5b, 0x16, 0x28, 0xa1, 0x84, 0xf5, 0x41, 0xc4,
+	0x25, 0x7b, 0x08, 0x7d, 0x25, 0x2c, 0xfa, 0xc1, 0x05, 0xaf, 0xa7, 0x84, 0xbd, 0x48, 0x59, 0x08,
+	0x83, 0x95, 0x2c, 0xed, 0x92, 0x67, 0x14, 0xbd, 0x61, 0x54, 0x91, 0xc8, 0x99, 0x73, 0x2b, 0x6e,
+	0xf9, 0xda, 0x07, 0xaf, 0x22, 0xc9, 0x30, 0x65, 0x7c, 0xc8, 0x70, 0xd9, 0xa8, 0xb1, 0xe1, 0x56,
+	0x8d, 0x3d, 0x82, 0x7e, 0xa9, 0x97, 0x56, 0x18, 0x0a, 0x51, 0x10, 0x79, 0x0a, 0x71, 0x39, 0xa3,
+	0x54, 0x77, 0x41, 0xf1, 0x14, 0xea, 0xcc, 0xb9, 0xb9, 0xc9, 0x84, 0xa2, 0x70, 0xf4, 0xa2, 0x8a,
+	0x6c, 0x24, 0xed, 0xce, 0x56, 0xd2, 0x3e, 0x82, 0xfe, 0x75, 0x29, 0xd3, 0xb9, 0xa0, 0x90, 0x04,
+	0x91, 0xa7, 0x30, 0xfb, 0x6f, 0x65, 0x49, 0x71, 0x1f, 0x3b, 0x06, 0x92, 0x2e, 0xdc, 0xab, 0x8c,
+	0x2b, 0x8a, 0x43, 0x2f, 0xa2, 0x35, 0x26, 0x93, 0x54, 0x56, 0x94, 0x33, 0x9e, 0x08, 0x1f, 0x88,
+	0x0d, 0x80, 0xbe, 0xbd, 0xbe, 0xa5, 0x30, 0xf4, 0xa2, 0xf6, 0xf5, 0xed, 0x26, 0x09, 0x58, 0x33,
+	0x09, 0x3e, 0x87, 0x91, 0xf7, 0x5c, 0x2c, 0x0b, 0x13, 0x3e, 0x38, 0xec, 0x60, 0x38, 0x3d, 0x74,
+	0x51, 0x18, 0x14, 0x10, 0x6f, 0xad, 0x28, 0x95, 0xc8, 0xd0, 0xaa, 0x7d, 0x17, 0xef, 0x0a, 0xba,
+	0x48, 0xd9, 0x63, 0x08, 0xac, 0xe0, 0x79, 0x7c, 0x2b, 0xed, 0x22, 0x7c, 0xe8, 0x5a, 0x00, 0x02,
+	0x7f, 0x90, 0x2e, 0x23, 0x73, 0xae, 0x30, 0x4c, 0x8f, 0x28, 0x4c, 0x9e, 0xc2, 0xaa, 0x54, 0x32,
+	0x89, 0xed, 0xba, 0x10, 0xe1, 0x81, 0x0b, 0x93, 0x92, 0xc9, 0xeb, 0x75, 0x41, 0x2e, 0xc8, 0xa4,
+	0xba, 0x89, 0x97, 0x45, 0x18, 0xba, 0x3d, 0x48, 0xbe, 0xa1, 0xe4, 0xc8, 0xed, 0x32, 0xfc, 0x84,
+	0xaa, 0x15, 0x97, 0x75, 0x2f, 0x9d, 0x6c, 0x7a, 0xe9, 0xf4, 0x16, 0x46, 0xbf, 0x3f, 0x3f, 0x7f,
+	0x75, 0xa6, 0xd5, 0x85, 0x9a, 0x69, 0x14, 0xc1, 0x7e, 0x54, 0xb5, 0x5b, 0x5c, 0x23, 0x56, 0xe8,
+	0xd2, 0x52, 0xde, 0xf5, 0x22, 0x5a, 0x23, 0xb6, 0x34, 0xa2, 0xf4, 0xa9, 0x47, 0x6b, 0x34, 0xbe,
+	0xe0, 0xc6, 0xdc, 0x56, 0xb9, 0xe7, 0x29, 0xf4, 0xe4, 0x2a, 0x2f, 0xc5, 0x8c, 0x52, 0x2f, 0x88,
+	0x1c, 0x31, 0xfd, 0x6f, 0x1b, 0xe0, 0x9c, 0xba, 0x3f, 0x29, 0x7e, 0x0a, 0x50, 0x2c, 0xaf, 0x33,
+	0x99, 0xc4, 0x37, 0x62, 0x4d, 0xea, 0x47, 0xa7, 0xf7, 0x7c, 0xfb, 0xbb, 0xfa, 0xfa, 0x95, 0x58,
+	0x9b, 0x28, 0x70, 0x02, 0xaf, 0xc4, 0x9a, 0x7d, 0x01, 0x03, 0x37, 0x39, 0x4c, 0xd8, 0xa6, 0x4e,
+	0xf9, 0xc0, 0xb7, 0x62, 0x02, 0xcf, 0xb4, 0xb2, 0x42, 0xd9, 0xa8, 0x92, 0xc1, 0xae, 0x4b, 0xb6,
+	0xe8, 0x32, 0xf5, 0x16, 0xd7, 0x34, 0xfa, 0x4f, 0x9a, 0x58, 0x2a, 0x69, 0xc9, 0xec, 0x61, 0xd4,
+	0x97, 0xe6, 0x42, 0x49, 0x8b, 0xad, 0x49, 0x28, 0x7e, 0x9d, 0x89, 0xd8, 0xda, 0xb5, 0x2f, 0x9b,
+	0xc0, 0x21, 0xaf, 0xed, 0x1a, 0x9b, 0x4f, 0x2a, 0x66, 0x7c, 0x99, 0xd9, 0xb8, 0xd4, 0xda, 0xc6,
+	0xe4, 0x8e, 0x3e, 0x49, 0xed, 0x7a, 0x46, 0xa4, 0xb5, 0x7d, 0x83, 0x9e, 0xf9, 0x15, 0x4c, 0x6e,
+	0xa5, 0x4a, 0xf5, 0xad, 0x89, 0xab, 0x3d, 0x3c, 0xcd, 0xa5, 0x72, 0x9b, 0x06, 0xb4, 0xe9, 0xc0,
+	0x4b, 0x9c, 0x3b, 0x81, 0x17, 0xc8, 0xa7, 0xcd, 0xc7, 0x70, 0xdf, 0xdb, 0x91, 0x64, 0x7a, 0x99,
+	0x3a, 0x53, 0x87, 0x4e, 0x91, 0x63, 0x9c, 0x21, 0x4e, 0x36, 0xff, 0x08, 0xee, 0x65, 0x7a, 0x2e,
+	0x55, 0xcc, 0x93, 0x04, 0x5b, 0x8c, 0x2f, 0xc8, 0x1d, 0x02, 0x5f, 0x38, 0x6c, 0xfa, 0xb7, 0x16,
+	0x0c, 0xbc, 0x4f, 0xf1, 0x92, 0x77, 0xdc, 0x1e, 0x34, 0xfd, 0x4c, 0x97, 0xcc, 0x84, 0x15, 0x71,
+	0x43, 0xca, 0xf5, 0x9f, 0x5d, 0xc7, 0xb8, 0xac, 0x65, 0x8f, 0x60, 0xcf, 0x5d, 0xaa, 0x21, 0xea,
+	0x9c, 0x3d, 0x26, 0x7c, 0x23, 0xf9, 0x14, 0x58, 0x51, 0xea, 0x3f, 0x8b, 0xc4, 0x36, 0x65, 0x5d,
+	0xd2, 0xec, 0x79, 0x4e, 0x2d, 0x3d, 0x7d, 0x03, 0xf7, 0xb6, 0xc2, 0x5a, 0xb7, 0xf2, 0x56, 0xa3,
+	0x95, 0x87, 0x30, 0x48, 0x1c, 0xdb, 0x9b, 0x57, 0x91, 0x98, 0x95, 0x3c, 0xb1, 0x52, 0xd7, 0x0f,
+	0x03, 0x47, 0x4d, 0x07, 0xd0, 0xfb, 0x32, 0x2f, 0xec, 0x7a, 0xfa, 0x8f, 0x16, 0x3c, 0x74, 0x0a,
+	0xe8, 0xd5, 0xf1, 0xd2, 0x44, 0xc2, 0x14, 0x5a, 0x19, 0x81, 0x5b, 0x53, 0x69, 0x6c, 0xa9, 0x1b,
+	0xa3, 0xd5, 0x96, 0x9a, 0xba, 0xa9, 0x28, 0x0d, 0x9e, 0xe9, 0x95, 0x79, 0x12, 0x4d, 0xe3, 0x65,
+	0xb2, 0xa8, 0xca, 0x02, 0xd7, 0x98, 0x7c, 0x19, 0x57, 0xf3, 0x25, 0x9f, 0x57, 0x13, 0xb5, 0xa6,
+	0xb1, 0xe9, 0x68, 0xe3, 0xeb, 0xa2, 0xad, 0x0d, 0x9e, 0x5c, 0x45, 0xce, 0x77, 0x63, 0x4f, 0x62,
+	0x35, 0xa3, 0x93, 0x7c, 0x37, 0xbe, 0x11, 0xeb, 0xe9, 0x3f, 0x5b, 0xb0, 0xe3, 0xec, 0xbe, 0xe4,
+	0x25, 0xcf, 0x0d, 0x76, 0x16, 0x7a, 0x0a, 0x34, 0x9c, 0x33, 0x44, 0x80, 0x06, 0xed, 0x09, 0xc0,
+	0x1c, 0xaf, 0x17, 0xa7, 0xc2, 0x24, 0x64, 0xf6, 0xe8, 0x74, 0xd7, 0x15, 0x4d, 0xfd, 0xd8, 0x8a,
+	0x82, 0x79, 0xb5, 0x64, 0x3f, 0x83, 0x91, 0xab, 0x9e, 0x58, 0xaa, 0x99, 0xa6, 0x0b, 0x8d, 0x4e,
+	0xf7, 0x9a, 0x55, 0x86, 0x65, 0x1b, 0x41, 0x5a, 0xaf, 0xd9, 0x09, 0x04, 0xab, 0x34, 0xbd, 0x71,
+	0x1b, 0xba, 0xb4, 0xe1, 0xbe, 0xdb, 0xd0, 0xe8, 0x30, 0xd1, 0x10, 0x65, 0x70, 0x35, 0xfd, 0x0e,
+	0xc6, 0x91, 0xc0, 0x57, 0xc4, 0x4b, 0xf3, 0x31, 0x37, 0xf8, 0x0c, 0x60, 0xb1, 0x79, 0x5a, 0x39,
+	0xc7, 0x37, 0x90, 0x6d, 0xf5, 0x9d, 0xef, 0x57, 0xff, 0x27, 0x18, 0xbf, 0xa4, 0x79, 0xff, 0x71,
+	0xea, 0x1f, 0x43, 0x30, 0x33, 0xb1, 0x7f, 0x2f, 0x38, 0xed, 0xc3, 0x99, 0x71, 0x27, 0xd4, 0x2f,
+	0xd2, 0xce, 0xe6, 0x45, 0x3a, 0xd5, 0x30, 0x8a, 0x44, 0x26, 0xb8, 0x11, 0xe4, 0x9d, 0x1f, 0x3c,
+	0x99, 0xa6, 0xdf, 0x00, 0xbb, 0xe2, 0x2b, 0xf1, 0x5a, 0x7f, 0x95, 0x71, 0x95, 0x88, 0x8f, 0xb9,
+	0xd4, 0x04, 0x86, 0x89, 0xce, 0x8b, 0x52, 0x18, 0x43, 0xda, 0x87, 0x51, 0x4d, 0x4f, 0x05, 0xec,
+	0x37, 0x8f, 0xab, 0xab, 0xe2, 0x00, 0x06, 0xda, 0x38, 0x2f, 0xfb, 0x9b, 0x68, 0x43, 0x37, 0xfc,
+	0x39, 0xec, 0x94, 0xee, 0xc2, 0x8e, 0xdb, 0x6e, 0xc6, 0xa0, 0xe1, 0x8a, 0x68, 0x54, 0x6e, 0x88,
+	0xe9, 0x73, 0xd8, 0xbf, 0x2c, 0xf5, 0xb5, 0xb8, 0xc0, 0x37, 0x21, 0x22, 0x97, 0x25, 0xcf, 0xf9,
+	0x87, 0xed, 0x9e, 0xfe, 0xbd, 0x0d, 0x41, 0xbd, 0x81, 0x1d, 0x6f, 0x5b, 0xf4, 0x5e, 0x9d, 0x95,
+	0x91, 0xce, 0x7a, 0x1a, 0xa4, 0xed, 0xca, 0x7a, 0x9a, 0xa3, 0x3f, 0x81, 0x5d, 0x69, 0xe2, 0xa5,
+	0x98, 0xc9, 0xd8, 0x2c, 0x0b, 0x1a, 0x78, 0x1d, 0xf7, 0xbe, 0x93, 0xe6, 0x8d, 0x98, 0xc9, 0x2b,
+	0x07, 0x62, 0x9b, 0x93, 0x26, 0xce, 0x56, 0x79, 0x5c, 0xf0, 0xd2, 0x4a, 0xea, 0x2c, 0x6e, 0x70,
+	0x8c, 0xa5, 0xf9, 0xed, 0x2a, 0xbf, 0xac, 0x50, 0x7c, 0x0a, 0x48, 0x13, 0x97, 0x82, 0xa7, 0x5a,
+	0x65, 0xd5, 0x04, 0x01, 0x69, 0x22, 0x8f, 0xb0, 0x5f, 0xc2, 0x41, 0xb1, 0x58, 0x1b, 0x99, 0xf0,
+	0x6c, 0x73, 0x98, 0xb3, 0xcd, 0x55, 0xff, 0xc3, 0x8a, 0x5d, 0x1f, 0x4a, 0xa6, 0xfe, 0x02, 0x0e,
+	0x68, 0x64, 0x19, 0xcb, 0xb3, 0x4c, 0xa4, 0xcd, 0xb9, 0xe0, 0x66, 0xc9, 0x3e, 0x8e, 0x30, 0xcf,
+	0xad, 0x87, 0xc3, 0xf4, 0xa7, 0xb0, 0xf3, 0xa5, 0x79, 0x2b, 0xf1, 0xb3, 0x81, 0x5c, 0xf1, 0x41,
+	0x0f, 0x7f, 0x07, 0x8f, 0xce, 0xb4, 0x52, 0x22, 0xb1, 0xd5, 0x9e, 0xaa, 0x4a, 0xb6, 0xea, 0xac,
+	0xf5, 0xbd, 0x75, 0xc6, 0x9e, 0xc3, 0x88, 0x27, 0x89, 0x30, 0xa6, 0xca, 0x0a, 0x9c, 0xd7, 0xcc,
+	0xed, 0x68, 0xda, 0x13, 0x81, 0x13, 0xa3, 0xac, 0x38, 0x83, 0x83, 0x5a, 0xaf, 0xb7, 0x43, 0xba,
+	0x93, 0xd9, 0x51, 0xf5, 0x11, 0xd6, 0xfa, 0xbf, 0x27, 0x39, 0x81, 0xe9, 0x1f, 0x61, 0x58, 0x7d,
+	0x3a, 0x61, 0xff, 0xb4, 0x7c, 0x5e, 0x3d, 0xa6, 0x2d, 0x9f, 0xdf, 0x79, 0x98, 0xb7, 0xdf, 0x79,
+	0x98, 0x4f, 0x60, 0x58, 0xc7, 0xce, 0x65, 0x42, 0x4d, 0x9f, 0xfe, 0xab, 0x03, 0x23, 0xd7, 0x05,
+	0x5f, 0xcc, 0x71, 0xc8, 0xfc, 0xa6, 0x9a, 0x51, 0x7e, 0x84, 0x30, 0xd6, 0xec, 0x94, 0xce, 0x71,
+	0x93, 0xc7, 0x4d, 0xec, 0xee, 0xac, 0xf9, 0x02, 0x86, 0x55, 0x33, 0x64, 0xfb, 0x55, 0xfa, 0x36,
+	0x9b, 0xe3, 0x64, 0xe4, 0x2f, 0x8a, 0x43, 0x0b, 0xc5, 0xab, 0xe6, 0x55, 0x89, 0x6f, 0x37, 0xb3,
+	0x6d, 0xf1, 0x73, 0xd8, 0x69, 0xd6, 0x32, 0x0b, 0xfd, 0xcb, 0xea, 0x9d, 0x76, 0x31, 0x99, 0xbc,
+	0xcb, 0xa9, 0x6d, 0xfc, 0x35, 0x8c, 0xb7, 0x4b, 0x95, 0x79, 0xe9, 0xf7, 0x15, 0xf0, 0xc4, 0x4f,
+	0x97, 0x8d, 0xf0, 0xef, 0x60, 0xef, 0x6e, 0x4a, 0xb1, 0x4f, 0x9d, 0xd0, 0xfb, 0x53, 0x6d, 0xf2,
+	0x64, 0x3b, 0xb6, 0x77, 0x33, 0xe1, 0x05, 0x3c, 0x38, 0x97, 0x26, 0xb9, 0x7b, 0xe6, 0x87, 0x77,
+	0x6d, 0x39, 0xe6, 0xba, 0x4f, 0xff, 0x3c, 0x3c, 0xff, 0xdf, 0x00, 0x30, 0xfc, 0x19, 0x7e, 0xa7,
+	0x10, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
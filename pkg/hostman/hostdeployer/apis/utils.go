@@ -95,6 +95,7 @@ func GuestDescToDeployDesc(guestDesc *jsonutils.JSONDict) (*GuestDesc, error) {
 	jnics, _ := guestDesc.Get("nics")
 	jdisks, _ := guestDesc.Get("disks")
 	jnicsStandby, _ := guestDesc.Get("nics_standby")
+	jsharedfs, _ := guestDesc.Get("sharedfs")
 
 	if jnics != nil {
 		nics := make([]*Nic, 0)
@@ -123,6 +124,15 @@ func GuestDescToDeployDesc(guestDesc *jsonutils.JSONDict) (*GuestDesc, error) {
 		ret.NicsStandby = nicsStandby
 	}
 
+	if jsharedfs != nil {
+		sharedfs := make([]*SharedFs, 0)
+		err := jsharedfs.Unmarshal(&sharedfs)
+		if err != nil {
+			return nil, err
+		}
+		ret.Sharedfs = sharedfs
+	}
+
 	return ret, nil
 }
 
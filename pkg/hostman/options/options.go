@@ -58,9 +58,15 @@ type SHostOptions struct {
 	DnsServer       string `help:"Address of host DNS server"`
 	DnsServerLegacy string `help:"Deprecated Address of host DNS server"`
 
-	ChntpwPath           string `help:"path to chntpw tool" default:"/usr/local/bin/chntpw.static"`
-	OvmfPath             string `help:"Path to OVMF.fd" default:"/opt/cloud/contrib/OVMF.fd"`
-	LinuxDefaultRootUser bool   `help:"Default account for linux system is root"`
+	ChntpwPath            string `help:"path to chntpw tool" default:"/usr/local/bin/chntpw.static"`
+	OvmfPath              string `help:"Path to OVMF.fd" default:"/opt/cloud/contrib/OVMF.fd"`
+	OvmfSecbootCodePath   string `help:"Path to secure boot capable OVMF code image" default:"/opt/cloud/contrib/OVMF_CODE.secboot.fd"`
+	OvmfVarsTemplatePath  string `help:"Path to OVMF variable store template with secure boot keys enrolled" default:"/opt/cloud/contrib/OVMF_VARS.secboot.fd"`
+	AavmfSecbootCodePath  string `help:"Path to secure boot capable AAVMF code image used by aarch64 hosts" default:"/opt/cloud/contrib/AAVMF_CODE.secboot.fd"`
+	AavmfVarsTemplatePath string `help:"Path to AAVMF variable store template with secure boot keys enrolled used by aarch64 hosts" default:"/opt/cloud/contrib/AAVMF_VARS.secboot.fd"`
+	SwtpmPath             string `help:"Path to swtpm binary used by vTPM devices" default:"/usr/bin/swtpm"`
+	VirtiofsdPath         string `help:"Path to virtiofsd binary used by shared filesystems" default:"/usr/libexec/virtiofsd"`
+	LinuxDefaultRootUser  bool   `help:"Default account for linux system is root"`
 
 	BlockIoScheduler string `help:"Block IO scheduler, deadline or cfq" default:"deadline"`
 	EnableKsm        bool   `help:"Enable Kernel Same Page Merging"`
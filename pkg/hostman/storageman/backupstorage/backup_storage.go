@@ -36,9 +36,27 @@ type IBackupStorage interface {
 	IsOnline() (bool, string, error)
 }
 
+// IBackupStorageUsage is implemented by backup storages which could report the used capacity
+type IBackupStorageUsage interface {
+	GetUsedCapacityMb() (int64, error)
+}
+
 var backupStoragePool *sync.Map = &sync.Map{}
 
 func NewBackupStorage(backupStroageId string, backupStorageAccessInfo *jsonutils.JSONDict) (IBackupStorage, error) {
+	storageType, _ := backupStorageAccessInfo.GetString("storage_type")
+	if storageType == "" && backupStorageAccessInfo.Contains("object_bucket_url") {
+		storageType = api.BACKUPSTORAGE_TYPE_OBJECT_STORAGE
+	}
+	switch storageType {
+	case api.BACKUPSTORAGE_TYPE_OBJECT_STORAGE:
+		return newObjectBackupStorage(backupStroageId, backupStorageAccessInfo)
+	default:
+		return newNFSBackupStorage(backupStroageId, backupStorageAccessInfo)
+	}
+}
+
+func newNFSBackupStorage(backupStroageId string, backupStorageAccessInfo *jsonutils.JSONDict) (IBackupStorage, error) {
 	nfsHost, err := backupStorageAccessInfo.GetString("nfs_host")
 	if err != nil {
 		return nil, fmt.Errorf("need nfs_host in backup_storage_access_info")
@@ -50,6 +68,23 @@ func NewBackupStorage(backupStroageId string, backupStorageAccessInfo *jsonutils
 	return NewNFSBackupStorage(backupStroageId, nfsHost, nfsSharedDir), nil
 }
 
+func newObjectBackupStorage(backupStroageId string, backupStorageAccessInfo *jsonutils.JSONDict) (IBackupStorage, error) {
+	bucketUrl, err := backupStorageAccessInfo.GetString("object_bucket_url")
+	if err != nil {
+		return nil, fmt.Errorf("need object_bucket_url in backup_storage_access_info")
+	}
+	accessKey, err := backupStorageAccessInfo.GetString("object_access_key")
+	if err != nil {
+		return nil, fmt.Errorf("need object_access_key in backup_storage_access_info")
+	}
+	secret, err := backupStorageAccessInfo.GetString("object_secret")
+	if err != nil {
+		return nil, fmt.Errorf("need object_secret in backup_storage_access_info")
+	}
+	sse, _ := backupStorageAccessInfo.GetString("object_sse")
+	return NewObjectBackupStorage(backupStroageId, bucketUrl, accessKey, secret, sse)
+}
+
 func GetBackupStorage(backupStroageId string, backupStorageAccessInfo *jsonutils.JSONDict) (IBackupStorage, error) {
 	bs, err := NewBackupStorage(backupStroageId, backupStorageAccessInfo)
 	if err != nil {
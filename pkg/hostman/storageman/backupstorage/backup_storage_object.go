@@ -0,0 +1,578 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backupstorage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/s3cli"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/lockman"
+	"yunion.io/x/onecloud/pkg/hostman/options"
+	"yunion.io/x/onecloud/pkg/util/fileutils2"
+	"yunion.io/x/onecloud/pkg/util/httputils"
+	"yunion.io/x/onecloud/pkg/util/procutils"
+	"yunion.io/x/onecloud/pkg/util/qemuimg"
+)
+
+const (
+	objectBackupPrefix  = "backups/"
+	objectPackagePrefix = "backuppacks/"
+
+	objectPartSize = 64 * 1024 * 1024
+
+	objectSSEHeader = "X-Amz-Server-Side-Encryption"
+)
+
+type SObjectBackupStorage struct {
+	BackupStorageId string
+	Endpoint        string
+	Bucket          string
+	AccessKey       string
+	Secret          string
+	Sse             string
+
+	// Path is the local workspace to hold temporary files
+	Path string
+
+	lock   *sync.Mutex
+	client *s3cli.Client
+}
+
+func NewObjectBackupStorage(backupStorageId, bucketUrl, accessKey, secret, sse string) (*SObjectBackupStorage, error) {
+	u, err := url.Parse(bucketUrl)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parse object_bucket_url %s", bucketUrl)
+	}
+	bucket := strings.Trim(u.Path, "/")
+	if (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 || len(bucket) == 0 || strings.Contains(bucket, "/") {
+		return nil, errors.Errorf("invalid object_bucket_url %s", bucketUrl)
+	}
+	return &SObjectBackupStorage{
+		BackupStorageId: backupStorageId,
+		Endpoint:        u.Scheme + "://" + u.Host,
+		Bucket:          bucket,
+		AccessKey:       accessKey,
+		Secret:          secret,
+		Sse:             sse,
+		Path:            path.Join(BackupStoragePath, backupStorageId),
+		lock:            &sync.Mutex{},
+	}, nil
+}
+
+func (s *SObjectBackupStorage) getClient() (*s3cli.Client, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.client != nil {
+		return s.client, nil
+	}
+	u, err := url.Parse(s.Endpoint)
+	if err != nil {
+		return nil, errors.Wrap(err, "url.Parse endpoint")
+	}
+	cli, err := s3cli.New(u.Host, s.AccessKey, s.Secret, u.Scheme == "https", false)
+	if err != nil {
+		return nil, errors.Wrap(err, "s3cli.New")
+	}
+	cli.SetCustomTransport(httputils.GetTransport(true))
+	s.client = cli
+	return s.client, nil
+}
+
+func (s *SObjectBackupStorage) getTmpDir() string {
+	return path.Join(s.Path, "tmp")
+}
+
+func (s *SObjectBackupStorage) getUploadStateDir() string {
+	return path.Join(s.Path, "uploads")
+}
+
+func (s *SObjectBackupStorage) prepareDir(dir string) error {
+	if fileutils2.Exists(dir) {
+		return nil
+	}
+	output, err := procutils.NewCommand("mkdir", "-p", dir).Output()
+	if err != nil {
+		log.Errorf("mkdir %s failed: %s", dir, output)
+		return errors.Wrapf(err, "mkdir %s failed: %s", dir, output)
+	}
+	return nil
+}
+
+// mkTmpDir creates a temporary directory in local workspace, the caller should remove it after used
+func (s *SObjectBackupStorage) mkTmpDir(name string) (string, error) {
+	tmpDir := s.getTmpDir()
+	err := s.prepareDir(tmpDir)
+	if err != nil {
+		return "", err
+	}
+	return ioutil.TempDir(tmpDir, name)
+}
+
+func removeTmpDir(dir string) {
+	if output, err := procutils.NewCommand("rm", "-rf", dir).Output(); err != nil {
+		log.Errorf("unable to rm %s: %s", dir, output)
+	}
+}
+
+func (s *SObjectBackupStorage) backupKey(backupId string) string {
+	return objectBackupPrefix + backupId
+}
+
+func (s *SObjectBackupStorage) packageKey(packageName string) string {
+	return objectPackagePrefix + packageName + ".tar"
+}
+
+func isUploadNotFound(err error) bool {
+	return s3cli.ToErrorResponse(errors.Cause(err)).Code == "NoSuchUpload"
+}
+
+func isObjectNotFound(err error) bool {
+	code := s3cli.ToErrorResponse(err).Code
+	return code == "NoSuchKey" || code == "NotFound"
+}
+
+func (s *SObjectBackupStorage) isObjectExists(key string) (bool, error) {
+	cli, err := s.getClient()
+	if err != nil {
+		return false, err
+	}
+	_, err = cli.StatObject(s.Bucket, key, s3cli.StatObjectOptions{})
+	if err != nil {
+		if isObjectNotFound(err) {
+			return false, nil
+		}
+		return false, errors.Wrapf(err, "StatObject %s", key)
+	}
+	return true, nil
+}
+
+// sObjectUploadState records the finished parts of a multipart upload,
+// so that an interrupted upload of the same file can be resumed
+type sObjectUploadState struct {
+	UploadId string
+	Size     int64
+	ModTime  int64
+	Parts    []s3cli.CompletePart
+}
+
+func (state *sObjectUploadState) isPartDone(partNumber int) bool {
+	for i := range state.Parts {
+		if state.Parts[i].PartNumber == partNumber {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *SObjectBackupStorage) uploadStatePath(key string) string {
+	return path.Join(s.getUploadStateDir(), strings.ReplaceAll(key, "/", "_")+".json")
+}
+
+func (s *SObjectBackupStorage) loadUploadState(key string) *sObjectUploadState {
+	content, err := ioutil.ReadFile(s.uploadStatePath(key))
+	if err != nil {
+		return nil
+	}
+	obj, err := jsonutils.Parse(content)
+	if err != nil {
+		return nil
+	}
+	state := &sObjectUploadState{}
+	if err := obj.Unmarshal(state); err != nil {
+		return nil
+	}
+	return state
+}
+
+func (s *SObjectBackupStorage) saveUploadState(key string, state *sObjectUploadState) error {
+	err := s.prepareDir(s.getUploadStateDir())
+	if err != nil {
+		return err
+	}
+	return fileutils2.FilePutContents(s.uploadStatePath(key), jsonutils.Marshal(state).String(), false)
+}
+
+func (s *SObjectBackupStorage) removeUploadState(key string) {
+	os.Remove(s.uploadStatePath(key))
+}
+
+// uploadFile uploads local file to object storage with multipart upload.
+// Finished parts are recorded locally, an upload interrupted half way
+// continues from the first unfinished part when retried.
+func (s *SObjectBackupStorage) uploadFile(filename string, key string) error {
+	cli, err := s.getClient()
+	if err != nil {
+		return err
+	}
+	fi, err := os.Stat(filename)
+	if err != nil {
+		return errors.Wrapf(err, "stat %s", filename)
+	}
+	file, err := os.Open(filename)
+	if err != nil {
+		return errors.Wrapf(err, "open %s", filename)
+	}
+	defer file.Close()
+
+	ctx := context.Background()
+	state := s.loadUploadState(key)
+	if state != nil && (state.Size != fi.Size() || state.ModTime != fi.ModTime().Unix()) {
+		// source file changed, the parts uploaded are useless
+		err := cli.AbortMultipartUpload(ctx, s.Bucket, key, state.UploadId)
+		if err != nil {
+			log.Warningf("abort multipart upload %s of %s: %s", state.UploadId, key, err)
+		}
+		s.removeUploadState(key)
+		state = nil
+	}
+	err = s.uploadParts(ctx, cli, file, fi, key, state)
+	if err != nil && state != nil && isUploadNotFound(err) {
+		// the recorded upload was aborted or expired on server side
+		log.Warningf("multipart upload %s of %s not found, start over", state.UploadId, key)
+		s.removeUploadState(key)
+		err = s.uploadParts(ctx, cli, file, fi, key, nil)
+	}
+	return err
+}
+
+// uploadParts continues the multipart upload recorded by state, or starts a
+// new one if state is nil
+func (s *SObjectBackupStorage) uploadParts(ctx context.Context, cli *s3cli.Client, file *os.File, fi os.FileInfo, key string, state *sObjectUploadState) error {
+	if state == nil {
+		opts := s3cli.PutObjectOptions{
+			ContentType: "application/octet-stream",
+		}
+		if len(s.Sse) > 0 {
+			opts.UserMetadata = map[string]string{objectSSEHeader: s.Sse}
+		}
+		result, err := cli.InitiateMultipartUpload(ctx, s.Bucket, key, opts)
+		if err != nil {
+			return errors.Wrapf(err, "InitiateMultipartUpload %s", key)
+		}
+		state = &sObjectUploadState{
+			UploadId: result.UploadID,
+			Size:     fi.Size(),
+			ModTime:  fi.ModTime().Unix(),
+		}
+		err = s.saveUploadState(key, state)
+		if err != nil {
+			return errors.Wrap(err, "saveUploadState")
+		}
+	}
+
+	partCount := int((fi.Size() + objectPartSize - 1) / objectPartSize)
+	if partCount == 0 {
+		// empty file still needs one part to complete the upload
+		partCount = 1
+	}
+	for partNumber := 1; partNumber <= partCount; partNumber++ {
+		if state.isPartDone(partNumber) {
+			continue
+		}
+		offset := int64(partNumber-1) * objectPartSize
+		size := fi.Size() - offset
+		if size > objectPartSize {
+			size = objectPartSize
+		}
+		reader := io.NewSectionReader(file, offset, size)
+		part, err := cli.UploadPart(ctx, s.Bucket, key, state.UploadId, reader, partNumber, "", "", size, nil)
+		if err != nil {
+			return errors.Wrapf(err, "UploadPart %d of %s", partNumber, key)
+		}
+		state.Parts = append(state.Parts, s3cli.CompletePart{PartNumber: partNumber, ETag: part.ETag})
+		err = s.saveUploadState(key, state)
+		if err != nil {
+			return errors.Wrap(err, "saveUploadState")
+		}
+	}
+
+	complete := s3cli.CompleteMultipartUpload{Parts: state.Parts}
+	sort.Slice(complete.Parts, func(i, j int) bool {
+		return complete.Parts[i].PartNumber < complete.Parts[j].PartNumber
+	})
+	_, err := cli.CompleteMultipartUpload(ctx, s.Bucket, key, state.UploadId, complete)
+	if err != nil {
+		return errors.Wrapf(err, "CompleteMultipartUpload %s", key)
+	}
+	s.removeUploadState(key)
+	return nil
+}
+
+// downloadFile downloads object to local file, FGetObject keeps the partial
+// data and continues from where it stopped when retried
+func (s *SObjectBackupStorage) downloadFile(key string, filename string) error {
+	cli, err := s.getClient()
+	if err != nil {
+		return err
+	}
+	err = cli.FGetObject(s.Bucket, key, filename, s3cli.GetObjectOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "FGetObject %s", key)
+	}
+	return nil
+}
+
+func (s *SObjectBackupStorage) removeObject(key string) error {
+	cli, err := s.getClient()
+	if err != nil {
+		return err
+	}
+	err = cli.RemoveObject(s.Bucket, key)
+	if err != nil && !isObjectNotFound(err) {
+		return errors.Wrapf(err, "RemoveObject %s", key)
+	}
+	return nil
+}
+
+func (s *SObjectBackupStorage) CopyBackupFrom(srcFilename string, backupId string) error {
+	return s.uploadFile(srcFilename, s.backupKey(backupId))
+}
+
+func (s *SObjectBackupStorage) CopyBackupTo(targetFilename string, backupId string) error {
+	return s.downloadFile(s.backupKey(backupId), targetFilename)
+}
+
+func (s *SObjectBackupStorage) InstancePack(packageName string, backupIds []string, metadata *api.InstanceBackupPackMetadata) error {
+	lockman.LockRawObject(context.Background(), "package", packageName)
+	defer lockman.ReleaseRawObject(context.Background(), "package", packageName)
+	packageKey := s.packageKey(packageName)
+	exist, err := s.isObjectExists(packageKey)
+	if err != nil {
+		return err
+	}
+	if exist {
+		return errors.Error("A package with the same name already exists")
+	}
+	tmpDir, err := s.mkTmpDir("pack")
+	if err != nil {
+		return errors.Wrap(err, "mkTmpDir")
+	}
+	defer removeTmpDir(tmpDir)
+	packagePath := path.Join(tmpDir, packageName)
+	output, err := procutils.NewCommand("mkdir", "-p", packagePath).Output()
+	if err != nil {
+		log.Errorf("mkdir %s failed: %s", packagePath, output)
+		return errors.Wrapf(err, "mkdir %s failed: %s", packagePath, output)
+	}
+	for i, backupId := range backupIds {
+		packageDiskPath := path.Join(packagePath, fmt.Sprintf("%s_%d", PackageDiskFilename, i))
+		err := s.downloadFile(s.backupKey(backupId), packageDiskPath)
+		if err != nil {
+			return errors.Wrapf(err, "download backup %s", backupId)
+		}
+	}
+	packageMetadataPath := path.Join(packagePath, PackageMetadataFilename)
+	err = ioutil.WriteFile(packageMetadataPath, []byte(jsonutils.Marshal(metadata).PrettyString()), 0644)
+	if err != nil {
+		return errors.Wrapf(err, "unable to write to %s", packageMetadataPath)
+	}
+	// tar
+	packageFilename := path.Join(tmpDir, packageName+".tar")
+	if output, err := procutils.NewCommand("tar", "-cf", packageFilename, "-C", tmpDir, packageName).Output(); err != nil {
+		log.Errorf("unable to 'tar -cf %s -C %s %s': %s", packageFilename, tmpDir, packageName, output)
+		return errors.Wrap(err, "unable to tar")
+	}
+	return s.uploadFile(packageFilename, packageKey)
+}
+
+func (s *SObjectBackupStorage) InstanceUnpack(packageName string) ([]string, *api.InstanceBackupPackMetadata, error) {
+	lockman.LockRawObject(context.Background(), "package", packageName)
+	defer lockman.ReleaseRawObject(context.Background(), "package", packageName)
+	packageKey := s.packageKey(packageName)
+	exist, err := s.isObjectExists(packageKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !exist {
+		return nil, nil, errors.Errorf("package %s does not exists", packageName)
+	}
+	tmpDir, err := s.mkTmpDir("unpack")
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "mkTmpDir")
+	}
+	defer removeTmpDir(tmpDir)
+	packageFilename := path.Join(tmpDir, packageName+".tar")
+	err = s.downloadFile(packageKey, packageFilename)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "download package %s", packageName)
+	}
+	// untar
+	if output, err := procutils.NewCommand("tar", "-xf", packageFilename, "-C", tmpDir, packageName).Output(); err != nil {
+		log.Errorf("unable to 'tar -xf %s -C %s %s': %s", packageFilename, tmpDir, packageName, output)
+		return nil, nil, errors.Wrap(err, "unable to untar")
+	}
+	packagePath := path.Join(tmpDir, packageName)
+	packageMetadataPath := path.Join(packagePath, PackageMetadataFilename)
+	metadataBytes, err := ioutil.ReadFile(packageMetadataPath)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "unable to read metadata file")
+	}
+	metadataJson, err := jsonutils.Parse(metadataBytes)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "unable to parse string to json")
+	}
+	metadata := &api.InstanceBackupPackMetadata{}
+	err = metadataJson.Unmarshal(metadata)
+	if err != nil {
+		return nil, nil, err
+	}
+	backupIds := make([]string, len(metadata.DiskMetadatas))
+	for i := 0; i < len(metadata.DiskMetadatas); i++ {
+		backupId := db.DefaultUUIDGenerator()
+		backupIds[i] = backupId
+		packageDiskPath := path.Join(packagePath, fmt.Sprintf("%s_%d", PackageDiskFilename, i))
+		err := s.uploadFile(packageDiskPath, s.backupKey(backupId))
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "upload disk %d of package %s", i, packageName)
+		}
+	}
+	return backupIds, metadata, nil
+}
+
+func (s *SObjectBackupStorage) ConvertFrom(srcPath string, format qemuimg.TImageFormat, backupId string) (int, error) {
+	tmpDir, err := s.mkTmpDir("convert")
+	if err != nil {
+		return 0, errors.Wrap(err, "mkTmpDir")
+	}
+	defer removeTmpDir(tmpDir)
+	destPath := path.Join(tmpDir, backupId)
+	srcInfo := qemuimg.SConvertInfo{
+		Path:     srcPath,
+		Format:   format,
+		IoLevel:  qemuimg.IONiceNone,
+		Password: "",
+	}
+	destInfo := qemuimg.SConvertInfo{
+		Path:     destPath,
+		Format:   qemuimg.QCOW2,
+		IoLevel:  qemuimg.IONiceNone,
+		Password: "",
+	}
+	err = qemuimg.Convert(srcInfo, destInfo, true, nil)
+	if err != nil {
+		return 0, err
+	}
+	newImage, err := qemuimg.NewQemuImage(destPath)
+	if err != nil {
+		return 0, err
+	}
+	err = s.uploadFile(destPath, s.backupKey(backupId))
+	if err != nil {
+		return 0, err
+	}
+	return newImage.GetActualSizeMB(), nil
+}
+
+func (s *SObjectBackupStorage) ConvertTo(destPath string, format qemuimg.TImageFormat, backupId string) error {
+	tmpDir, err := s.mkTmpDir("convert")
+	if err != nil {
+		return errors.Wrap(err, "mkTmpDir")
+	}
+	defer removeTmpDir(tmpDir)
+	srcPath := path.Join(tmpDir, backupId)
+	err = s.downloadFile(s.backupKey(backupId), srcPath)
+	if err != nil {
+		return err
+	}
+	srcInfo := qemuimg.SConvertInfo{
+		Path:     srcPath,
+		Format:   qemuimg.QCOW2,
+		IoLevel:  qemuimg.IONiceNone,
+		Password: "",
+	}
+	destInfo := qemuimg.SConvertInfo{
+		Path:     destPath,
+		Format:   format,
+		IoLevel:  qemuimg.IONiceNone,
+		Password: "",
+	}
+	var workerOpts []string
+	if options.HostOptions.RestrictQemuImgConvertWorker {
+		workerOpts = nil
+	} else {
+		workerOpts = []string{"-W", "-m", "16"}
+	}
+	return qemuimg.Convert(srcInfo, destInfo, false, workerOpts)
+}
+
+func (s *SObjectBackupStorage) RemoveBackup(backupId string) error {
+	key := s.backupKey(backupId)
+	if state := s.loadUploadState(key); state != nil {
+		cli, err := s.getClient()
+		if err != nil {
+			return err
+		}
+		err = cli.AbortMultipartUpload(context.Background(), s.Bucket, key, state.UploadId)
+		if err != nil {
+			log.Warningf("abort multipart upload %s of %s: %s", state.UploadId, key, err)
+		}
+		s.removeUploadState(key)
+	}
+	return s.removeObject(key)
+}
+
+func (s *SObjectBackupStorage) IsExists(backupId string) (bool, error) {
+	return s.isObjectExists(s.backupKey(backupId))
+}
+
+func (s *SObjectBackupStorage) IsOnline() (bool, string, error) {
+	cli, err := s.getClient()
+	if err != nil {
+		return false, err.Error(), nil
+	}
+	exist, _, err := cli.BucketExists(s.Bucket)
+	if err != nil {
+		return false, err.Error(), nil
+	}
+	if !exist {
+		return false, fmt.Sprintf("bucket %s not exists", s.Bucket), nil
+	}
+	return true, "", nil
+}
+
+// GetUsedCapacityMb sums the size of all backups and packages in bucket
+func (s *SObjectBackupStorage) GetUsedCapacityMb() (int64, error) {
+	cli, err := s.getClient()
+	if err != nil {
+		return 0, err
+	}
+	doneCh := make(chan struct{})
+	defer close(doneCh)
+	var size int64
+	for _, prefix := range []string{objectBackupPrefix, objectPackagePrefix} {
+		for obj := range cli.ListObjects(s.Bucket, prefix, true, doneCh) {
+			if obj.Err != nil {
+				return 0, errors.Wrapf(obj.Err, "ListObjects %s", prefix)
+			}
+			size += obj.Size
+		}
+	}
+	return size / 1024 / 1024, nil
+}
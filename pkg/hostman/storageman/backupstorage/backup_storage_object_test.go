@@ -0,0 +1,281 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backupstorage
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"sync"
+	"testing"
+
+	"yunion.io/x/s3cli"
+)
+
+func TestNewObjectBackupStorage(t *testing.T) {
+	cases := []struct {
+		url      string
+		endpoint string
+		bucket   string
+		wantErr  bool
+	}{
+		{
+			url:      "http://192.168.1.2:9000/backup",
+			endpoint: "http://192.168.1.2:9000",
+			bucket:   "backup",
+		},
+		{
+			url:      "https://s3.example.com/backup/",
+			endpoint: "https://s3.example.com",
+			bucket:   "backup",
+		},
+		{
+			url:     "http://192.168.1.2:9000",
+			wantErr: true,
+		},
+		{
+			url:     "http://192.168.1.2:9000/backup/disks",
+			wantErr: true,
+		},
+		{
+			url:     "192.168.1.2:9000/backup",
+			wantErr: true,
+		},
+		{
+			url:     "ftp://192.168.1.2/backup",
+			wantErr: true,
+		},
+	}
+	for _, c := range cases {
+		s, err := NewObjectBackupStorage("bs0", c.url, "ak", "sk", "")
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("%s: expect error, got endpoint %s bucket %s", c.url, s.Endpoint, s.Bucket)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", c.url, err)
+			continue
+		}
+		if s.Endpoint != c.endpoint || s.Bucket != c.bucket {
+			t.Errorf("%s: want endpoint %s bucket %s, got %s %s", c.url, c.endpoint, c.bucket, s.Endpoint, s.Bucket)
+		}
+	}
+}
+
+const fakeS3Xmlns = "http://s3.amazonaws.com/doc/2006-03-01/"
+
+// fakeS3 serves the multipart upload api of a single bucket
+type fakeS3 struct {
+	lock      sync.Mutex
+	uploads   map[string]bool
+	nextId    int
+	puts      []string
+	completes map[string][]int
+}
+
+func newFakeS3() *fakeS3 {
+	return &fakeS3{
+		uploads:   map[string]bool{},
+		completes: map[string][]int{},
+	}
+}
+
+func (f *fakeS3) writeError(w http.ResponseWriter, status int, code string) {
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "<Error><Code>%s</Code><Message>%s</Message></Error>", code, code)
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	query := r.URL.Query()
+	uploadId := query.Get("uploadId")
+	switch {
+	case r.Method == "GET" && query.Get("location") == "" && len(query["location"]) > 0:
+		fmt.Fprint(w, "<LocationConstraint></LocationConstraint>")
+	case r.Method == "POST" && len(query["uploads"]) > 0:
+		f.nextId++
+		uploadId = fmt.Sprintf("upload%d", f.nextId)
+		f.uploads[uploadId] = true
+		fmt.Fprintf(w, "<InitiateMultipartUploadResult xmlns=\"%s\"><UploadId>%s</UploadId></InitiateMultipartUploadResult>", fakeS3Xmlns, uploadId)
+	case r.Method == "PUT" && uploadId != "":
+		if !f.uploads[uploadId] {
+			f.writeError(w, http.StatusNotFound, "NoSuchUpload")
+			return
+		}
+		ioutil.ReadAll(r.Body)
+		partNumber := query.Get("partNumber")
+		f.puts = append(f.puts, uploadId+"/"+partNumber)
+		w.Header().Set("ETag", `"etag`+partNumber+`"`)
+	case r.Method == "POST" && uploadId != "":
+		if !f.uploads[uploadId] {
+			f.writeError(w, http.StatusNotFound, "NoSuchUpload")
+			return
+		}
+		complete := s3cli.CompleteMultipartUpload{}
+		body, _ := ioutil.ReadAll(r.Body)
+		if err := xml.Unmarshal(body, &complete); err != nil {
+			f.writeError(w, http.StatusBadRequest, "MalformedXML")
+			return
+		}
+		for _, part := range complete.Parts {
+			f.completes[uploadId] = append(f.completes[uploadId], part.PartNumber)
+		}
+		delete(f.uploads, uploadId)
+		fmt.Fprintf(w, `<CompleteMultipartUploadResult xmlns="%s"><Bucket>backup</Bucket><ETag>"etag"</ETag></CompleteMultipartUploadResult>`, fakeS3Xmlns)
+	case r.Method == "DELETE" && uploadId != "":
+		delete(f.uploads, uploadId)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		f.writeError(w, http.StatusNotImplemented, "NotImplemented")
+	}
+}
+
+func newTestObjectBackupStorage(t *testing.T, srv *httptest.Server) (*SObjectBackupStorage, func()) {
+	s, err := NewObjectBackupStorage("bs0", srv.URL+"/backup", "ak", "sk", "")
+	if err != nil {
+		t.Fatalf("NewObjectBackupStorage: %v", err)
+	}
+	dir, err := ioutil.TempDir("", "backupstorage")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	s.Path = dir
+	return s, func() { os.RemoveAll(dir) }
+}
+
+// writeTestFile creates a sparse file of two parts
+func writeTestFile(t *testing.T, dir string) (string, os.FileInfo) {
+	filename := path.Join(dir, "disk")
+	if err := ioutil.WriteFile(filename, nil, 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := os.Truncate(filename, objectPartSize+16); err != nil {
+		t.Fatalf("Truncate: %v", err)
+	}
+	fi, err := os.Stat(filename)
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	return filename, fi
+}
+
+func TestObjectUploadFileResume(t *testing.T) {
+	fake := newFakeS3()
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+	s, cleanup := newTestObjectBackupStorage(t, srv)
+	defer cleanup()
+
+	key := s.backupKey("backup0")
+	filename, fi := writeTestFile(t, s.Path)
+	fake.uploads["upload0"] = true
+	err := s.saveUploadState(key, &sObjectUploadState{
+		UploadId: "upload0",
+		Size:     fi.Size(),
+		ModTime:  fi.ModTime().Unix(),
+		Parts:    []s3cli.CompletePart{{PartNumber: 1, ETag: "etag1"}},
+	})
+	if err != nil {
+		t.Fatalf("saveUploadState: %v", err)
+	}
+
+	if err := s.uploadFile(filename, key); err != nil {
+		t.Fatalf("uploadFile: %v", err)
+	}
+	if len(fake.puts) != 1 || fake.puts[0] != "upload0/2" {
+		t.Errorf("expect only part 2 uploaded, got %v", fake.puts)
+	}
+	if parts := fake.completes["upload0"]; len(parts) != 2 || parts[0] != 1 || parts[1] != 2 {
+		t.Errorf("expect upload0 completed with parts [1 2], got %v", parts)
+	}
+	if state := s.loadUploadState(key); state != nil {
+		t.Errorf("upload state should be removed after completed, got %#v", state)
+	}
+}
+
+func TestObjectUploadFileNoSuchUpload(t *testing.T) {
+	fake := newFakeS3()
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+	s, cleanup := newTestObjectBackupStorage(t, srv)
+	defer cleanup()
+
+	key := s.backupKey("backup0")
+	filename, fi := writeTestFile(t, s.Path)
+	// the recorded upload no longer exists on server
+	err := s.saveUploadState(key, &sObjectUploadState{
+		UploadId: "expired",
+		Size:     fi.Size(),
+		ModTime:  fi.ModTime().Unix(),
+		Parts:    []s3cli.CompletePart{{PartNumber: 1, ETag: "etag1"}},
+	})
+	if err != nil {
+		t.Fatalf("saveUploadState: %v", err)
+	}
+
+	if err := s.uploadFile(filename, key); err != nil {
+		t.Fatalf("uploadFile: %v", err)
+	}
+	if parts := fake.completes["upload1"]; len(parts) != 2 {
+		t.Errorf("expect new upload completed with 2 parts, got %v", fake.completes)
+	}
+	for _, put := range fake.puts {
+		if put != "upload1/1" && put != "upload1/2" {
+			t.Errorf("unexpected part %s", put)
+		}
+	}
+	if state := s.loadUploadState(key); state != nil {
+		t.Errorf("upload state should be removed after completed, got %#v", state)
+	}
+}
+
+func TestObjectUploadFileChanged(t *testing.T) {
+	fake := newFakeS3()
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+	s, cleanup := newTestObjectBackupStorage(t, srv)
+	defer cleanup()
+
+	key := s.backupKey("backup0")
+	filename, fi := writeTestFile(t, s.Path)
+	fake.uploads["upload0"] = true
+	err := s.saveUploadState(key, &sObjectUploadState{
+		UploadId: "upload0",
+		Size:     fi.Size() - 1,
+		ModTime:  fi.ModTime().Unix(),
+		Parts:    []s3cli.CompletePart{{PartNumber: 1, ETag: "etag1"}},
+	})
+	if err != nil {
+		t.Fatalf("saveUploadState: %v", err)
+	}
+
+	if err := s.uploadFile(filename, key); err != nil {
+		t.Fatalf("uploadFile: %v", err)
+	}
+	if fake.uploads["upload0"] {
+		t.Errorf("stale upload0 should be aborted")
+	}
+	if parts := fake.completes["upload1"]; len(parts) != 2 {
+		t.Errorf("expect new upload completed with 2 parts, got %v", fake.completes)
+	}
+}
@@ -276,6 +276,14 @@ func storageSyncBackupStorage(ctx context.Context, w http.ResponseWriter, r *htt
 	}
 	ret.Set("status", jsonutils.NewString(status))
 	ret.Set("reason", jsonutils.NewString(reason))
+	if usage, ok := backupStorage.(backupstorage.IBackupStorageUsage); ok && exist {
+		usedMb, err := usage.GetUsedCapacityMb()
+		if err != nil {
+			log.Errorf("unable to get used capacity of backup storage %s: %s", backupStorageId, err)
+		} else {
+			ret.Set("used_capacity_mb", jsonutils.NewInt(usedMb))
+		}
+	}
 	hostutils.Response(ctx, w, ret)
 }
 
@@ -17,6 +17,7 @@ package models
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"sort"
 	"strings"
 	"time"
@@ -40,6 +41,7 @@ import (
 	"yunion.io/x/onecloud/pkg/httperrors"
 	"yunion.io/x/onecloud/pkg/image/options"
 	"yunion.io/x/onecloud/pkg/mcclient"
+	modules "yunion.io/x/onecloud/pkg/mcclient/modules/image"
 	"yunion.io/x/onecloud/pkg/util/logclient"
 	"yunion.io/x/onecloud/pkg/util/qemuimg"
 	"yunion.io/x/onecloud/pkg/util/rbacutils"
@@ -74,6 +76,20 @@ type SGuestImage struct {
 	Protected tristate.TriState `default:"true" list:"user" get:"user" create:"optional" update:"user"`
 }
 
+func (manager *SGuestImageManager) CustomizeHandlerInfo(info *appsrv.SHandlerInfo) {
+	manager.SSharableVirtualResourceBaseManager.CustomizeHandlerInfo(info)
+
+	switch info.GetName(nil) {
+	case "create":
+		// ova package may be uploaded on create
+		info.SetProcessTimeout(time.Minute * 120).SetWorkerManager(imgStreamingWorkerMan)
+	}
+}
+
+func (manager *SGuestImageManager) FetchCreateHeaderData(ctx context.Context, header http.Header) (jsonutils.JSONObject, error) {
+	return modules.FetchImageMeta(header), nil
+}
+
 func (manager *SGuestImageManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential,
 	ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, input api.GuestImageCreateInput) (api.GuestImageCreateInput, error) {
 	var err error
@@ -86,6 +102,23 @@ func (manager *SGuestImageManager) ValidateCreateData(ctx context.Context, userC
 		return input, errors.Wrap(err, "SEncryptedResourceManager.ValidateCreateData")
 	}
 
+	switch input.ImportFormat {
+	case "":
+	case api.GUEST_IMAGE_IMPORT_FORMAT_OVA, api.GUEST_IMAGE_IMPORT_FORMAT_OVF:
+		if len(input.Images) > 0 {
+			return input, httperrors.NewInputParameterError("images are read from ovf descriptor on import")
+		}
+		if input.ImportFormat == api.GUEST_IMAGE_IMPORT_FORMAT_OVF && len(input.CopyFrom) == 0 {
+			return input, httperrors.NewMissingParameterError("copy_from")
+		}
+		input.DiskFormat = string(qemuimg.QCOW2)
+		// the number of images is unknown until the descriptor is read,
+		// so that pending quota is checked on import
+		return input, nil
+	default:
+		return input, httperrors.NewInputParameterError("unsupported import_format %q", input.ImportFormat)
+	}
+
 	imageNum := len(input.Images)
 	if imageNum == 0 {
 		return input, httperrors.NewMissingParameterError("images")
@@ -118,6 +151,10 @@ func (gi *SGuestImage) PostCreate(ctx context.Context, userCred mcclient.TokenCr
 	ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, data jsonutils.JSONObject) {
 
 	kwargs := data.(*jsonutils.JSONDict)
+	if importFormat, _ := kwargs.GetString("import_format"); len(importFormat) > 0 {
+		gi.postCreateImport(ctx, userCred, importFormat, kwargs)
+		return
+	}
 	// get image number
 	// imageNumber, _ := kwargs.Int("image_number")
 	// deal public params
@@ -182,6 +219,28 @@ func (gi *SGuestImage) PostCreate(ctx context.Context, userCred mcclient.TokenCr
 	gi.SetStatus(userCred, api.IMAGE_STATUS_SAVING, "")
 }
 
+func (gi *SGuestImage) postCreateImport(ctx context.Context, userCred mcclient.TokenCredential, importFormat string, kwargs *jsonutils.JSONDict) {
+	appParams := appsrv.AppContextGetParams(ctx)
+	if importFormat == api.GUEST_IMAGE_IMPORT_FORMAT_OVA && appParams.Request.ContentLength > 0 {
+		gi.SetStatus(userCred, api.IMAGE_STATUS_SAVING, "import upload")
+		err := gi.ImportOva(ctx, userCred, appParams.Request.Body, kwargs)
+		if err != nil {
+			log.Errorf("import ova of guest image %s fail: %s", gi.Id, err)
+		}
+		return
+	}
+	copyFrom, _ := kwargs.GetString("copy_from")
+	if len(copyFrom) == 0 {
+		gi.SetStatus(userCred, api.IMAGE_STATUS_KILLED, "neither upload nor copy_from of ova")
+		return
+	}
+	gi.SetStatus(userCred, api.IMAGE_STATUS_SAVING, "import from url")
+	err := gi.startImportTask(ctx, userCred, kwargs, "")
+	if err != nil {
+		gi.SetStatus(userCred, api.IMAGE_STATUS_KILLED, err.Error())
+	}
+}
+
 func (gi *SGuestImage) ValidateDeleteCondition(ctx context.Context, info jsonutils.JSONObject) error {
 	if gi.Protected.IsTrue() {
 		return httperrors.NewForbiddenError("image is protected")
@@ -0,0 +1,272 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/image"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/quotas"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/image/options"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/httputils"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+	"yunion.io/x/onecloud/pkg/util/ovfutils"
+)
+
+// sOvfImporter creates a sub image of guest image for each disk in ovf descriptor,
+// and saves the disk content into it
+type sOvfImporter struct {
+	guestImage *SGuestImage
+	userCred   mcclient.TokenCredential
+	// params shared by the sub images
+	params *jsonutils.JSONDict
+
+	images []*SImage
+	saved  map[int]bool
+}
+
+func newOvfImporter(gi *SGuestImage, userCred mcclient.TokenCredential, params *jsonutils.JSONDict) *sOvfImporter {
+	params = jsonutils.DeepCopy(params).(*jsonutils.JSONDict)
+	for _, key := range []string{"size", "image_number", "name", "generate_name", "images", "import_format", "copy_from", "properties"} {
+		params.Remove(key)
+	}
+	return &sOvfImporter{
+		guestImage: gi,
+		userCred:   userCred,
+		params:     params,
+		saved:      make(map[int]bool),
+	}
+}
+
+// ovfImageProperties returns the hints of virtual machine read from ovf descriptor
+func ovfImageProperties(info *ovfutils.SOvfInfo) *jsonutils.JSONDict {
+	props := jsonutils.NewDict()
+	if len(info.OsType) > 0 {
+		props.Set(api.IMAGE_OS_TYPE, jsonutils.NewString(info.OsType))
+	}
+	if info.CpuCount > 0 {
+		props.Set(api.IMAGE_VCPU_COUNT, jsonutils.NewInt(int64(info.CpuCount)))
+	}
+	if info.MemoryMB > 0 {
+		props.Set(api.IMAGE_VMEM_SIZE, jsonutils.NewInt(info.MemoryMB))
+	}
+	props.Set(api.IMAGE_NIC_COUNT, jsonutils.NewInt(int64(info.NicCount)))
+	props.Set(api.IMAGE_FIRMWARE, jsonutils.NewString(info.Firmware))
+	if info.Firmware == ovfutils.FIRMWARE_UEFI {
+		props.Set(api.IMAGE_UEFI_SUPPORT, jsonutils.JSONTrue)
+	}
+	return props
+}
+
+func (imp *sOvfImporter) onDescriptor(ctx context.Context, info *ovfutils.SOvfInfo, userProps jsonutils.JSONObject) error {
+	gi := imp.guestImage
+	ownerId := gi.GetOwnerId()
+
+	pendingUsage := SQuota{Image: len(info.Disks)}
+	pendingUsage.SetKeys(imageCreateInput2QuotaKeys("qcow2", ownerId))
+	if err := quotas.CheckSetPendingQuota(ctx, imp.userCred, &pendingUsage); err != nil {
+		return errors.Wrap(err, "CheckSetPendingQuota")
+	}
+	defer quotas.CancelPendingUsage(ctx, imp.userCred, &pendingUsage, &pendingUsage, true)
+
+	for i := range info.Disks {
+		disk := info.Disks[i]
+		params := jsonutils.DeepCopy(imp.params).(*jsonutils.JSONDict)
+		params.Set("is_guest_image", jsonutils.JSONTrue)
+		if len(disk.Format) > 0 {
+			params.Set("disk_format", jsonutils.NewString(disk.Format))
+		}
+		props := jsonutils.NewDict()
+		if disk.Index == 0 {
+			params.Set("generate_name", jsonutils.NewString(fmt.Sprintf("%s-%s", gi.Name, "root")))
+			props.Update(ovfImageProperties(info))
+		} else {
+			params.Set("generate_name", jsonutils.NewString(fmt.Sprintf("%s-%s-%d", gi.Name, "data", disk.Index-1)))
+			params.Set("is_data", jsonutils.JSONTrue)
+		}
+		if userProps != nil {
+			props.Update(userProps)
+		}
+		// mark the disk to be converted, which is skipped for other guest images
+		props.Set(api.IMAGE_IMPORTED_FROM, jsonutils.NewString(api.GUEST_IMAGE_IMPORT_FORMAT_OVF))
+		model, err := db.DoCreate(ImageManager, ctx, imp.userCred, nil, params, ownerId)
+		if err != nil {
+			return errors.Wrapf(err, "create image of disk %s", disk.DiskId)
+		}
+		image := model.(*SImage)
+		imp.images = append(imp.images, image)
+		err = ImagePropertyManager.SaveProperties(ctx, imp.userCred, image.Id, props)
+		if err != nil {
+			image.SetStatus(imp.userCred, api.IMAGE_STATUS_KILLED, "save properties failed")
+			return errors.Wrapf(err, "save properties of image %s", image.Id)
+		}
+		_, err = GuestImageJointManager.CreateGuestImageJoint(ctx, gi.Id, image.Id)
+		if err != nil {
+			image.OnJointFailed(ctx, imp.userCred)
+			return errors.Wrapf(err, "create joint of image %s", image.Id)
+		}
+	}
+	gi.SetStatus(imp.userCred, api.IMAGE_STATUS_SAVING, "import from ovf")
+	return nil
+}
+
+func (imp *sOvfImporter) onDisk(ctx context.Context, disk *ovfutils.SOvfDisk, reader io.Reader) error {
+	if disk.Index >= len(imp.images) {
+		return errors.Wrapf(errors.ErrNotFound, "image of disk %s", disk.DiskId)
+	}
+	image := imp.images[disk.Index]
+	image.SetStatus(imp.userCred, api.IMAGE_STATUS_SAVING, "import from ovf")
+	err := image.SaveImageFromStream(reader, disk.Size, false)
+	if err != nil {
+		image.OnSaveFailed(ctx, imp.userCred, jsonutils.NewString(fmt.Sprintf("import %s fail %s", disk.Href, err)))
+		return err
+	}
+	imp.saved[disk.Index] = true
+	image.OnSaveSuccess(ctx, imp.userCred, "import success")
+	image.StartImagePipeline(ctx, imp.userCred, false)
+	return nil
+}
+
+func (imp *sOvfImporter) onFailed(ctx context.Context, err error) {
+	msg := jsonutils.NewString(fmt.Sprintf("import fail %s", err))
+	for i := range imp.images {
+		if imp.saved[i] || imp.images[i].Status == api.IMAGE_STATUS_KILLED {
+			continue
+		}
+		imp.images[i].saveFailed(imp.userCred, msg)
+	}
+	imp.guestImage.SetStatus(imp.userCred, api.IMAGE_STATUS_KILLED, msg.String())
+	logclient.AddActionLogWithContext(ctx, imp.guestImage, logclient.ACT_IMAGE_SAVE, msg, imp.userCred, false)
+}
+
+// ImportOva imports guest image from the stream of ova package
+func (gi *SGuestImage) ImportOva(ctx context.Context, userCred mcclient.TokenCredential, reader io.Reader, params *jsonutils.JSONDict) error {
+	imp := newOvfImporter(gi, userCred, params)
+	userProps, _ := params.Get("properties")
+	err := ovfutils.ReadOva(reader, func(info *ovfutils.SOvfInfo) error {
+		return imp.onDescriptor(ctx, info, userProps)
+	}, func(disk *ovfutils.SOvfDisk, reader io.Reader) error {
+		return imp.onDisk(ctx, disk, reader)
+	})
+	if err != nil {
+		imp.onFailed(ctx, err)
+		return errors.Wrap(err, "ReadOva")
+	}
+	return nil
+}
+
+// ImportOvf imports guest image from the url of ovf descriptor, the disk files are
+// downloaded from the paths relative to the descriptor
+func (gi *SGuestImage) ImportOvf(ctx context.Context, userCred mcclient.TokenCredential, ovfUrl string, params *jsonutils.JSONDict) error {
+	imp := newOvfImporter(gi, userCred, params)
+	userProps, _ := params.Get("properties")
+	err := func() error {
+		base, err := url.Parse(ovfUrl)
+		if err != nil {
+			return errors.Wrapf(err, "parse url %s", ovfUrl)
+		}
+		resp, err := openImportUrl(ctx, ovfUrl)
+		if err != nil {
+			return err
+		}
+		info, err := ovfutils.ParseStream(resp.Body)
+		httputils.CloseResponse(resp)
+		if err != nil {
+			return errors.Wrapf(err, "parse %s", ovfUrl)
+		}
+		err = imp.onDescriptor(ctx, info, userProps)
+		if err != nil {
+			return err
+		}
+		for i := range info.Disks {
+			disk := &info.Disks[i]
+			ref, err := url.Parse(disk.Href)
+			if err != nil {
+				return errors.Wrapf(err, "parse href %s", disk.Href)
+			}
+			err = func() error {
+				resp, err := openImportUrl(ctx, base.ResolveReference(ref).String())
+				if err != nil {
+					return err
+				}
+				defer httputils.CloseResponse(resp)
+				reader, err := disk.OpenDisk(resp.Body)
+				if err != nil {
+					return err
+				}
+				defer reader.Close()
+				return imp.onDisk(ctx, disk, reader)
+			}()
+			if err != nil {
+				return errors.Wrapf(err, "disk %s", disk.Href)
+			}
+		}
+		return nil
+	}()
+	if err != nil {
+		imp.onFailed(ctx, err)
+		return err
+	}
+	return nil
+}
+
+// ImportFromUrl imports guest image from the url of ova package or ovf descriptor
+func (gi *SGuestImage) ImportFromUrl(ctx context.Context, userCred mcclient.TokenCredential, importFormat string, copyFrom string, params *jsonutils.JSONDict) error {
+	if importFormat == api.GUEST_IMAGE_IMPORT_FORMAT_OVF {
+		return gi.ImportOvf(ctx, userCred, copyFrom, params)
+	}
+	resp, err := openImportUrl(ctx, copyFrom)
+	if err != nil {
+		gi.SetStatus(userCred, api.IMAGE_STATUS_KILLED, err.Error())
+		return err
+	}
+	defer httputils.CloseResponse(resp)
+	return gi.ImportOva(ctx, userCred, resp.Body, params)
+}
+
+func openImportUrl(ctx context.Context, urlStr string) (*http.Response, error) {
+	client := httputils.GetTimeoutClient(0)
+	transport := httputils.GetTransport(true)
+	transport.Proxy = options.Options.HttpTransportProxyFunc()
+	client.Transport = transport
+	resp, err := httputils.Request(client, ctx, httputils.GET, urlStr, http.Header{}, nil, false)
+	if err != nil {
+		return nil, errors.Wrapf(err, "GET %s", urlStr)
+	}
+	if resp.StatusCode/100 != 2 {
+		httputils.CloseResponse(resp)
+		return nil, errors.Errorf("GET %s: status %d", urlStr, resp.StatusCode)
+	}
+	return resp, nil
+}
+
+func (gi *SGuestImage) startImportTask(ctx context.Context, userCred mcclient.TokenCredential, params *jsonutils.JSONDict, parentTaskId string) error {
+	task, err := taskman.TaskManager.NewTask(ctx, "GuestImageImportTask", gi, userCred, params, parentTaskId, "", nil)
+	if err != nil {
+		return err
+	}
+	task.ScheduleRun(nil)
+	return nil
+}
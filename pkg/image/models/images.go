@@ -1854,9 +1854,18 @@ func (image *SImage) doUploadPermanentStorage(ctx context.Context, userCred mccl
 	return nil
 }
 
+func (img *SImage) isImportedFromOvf() bool {
+	prop, err := ImagePropertyManager.GetProperty(img.Id, api.IMAGE_IMPORTED_FROM)
+	if err != nil {
+		return false
+	}
+	return prop.Value == api.GUEST_IMAGE_IMPORT_FORMAT_OVF
+}
+
 func (img *SImage) doConvert(ctx context.Context, userCred mcclient.TokenCredential) error {
-	if img.IsGuestImage.IsTrue() {
-		// for image the part of a guest image, convert is not necessary.
+	if img.IsGuestImage.IsTrue() && !img.isImportedFromOvf() {
+		// for image the part of a guest image, convert is not necessary,
+		// except disks imported from ovf, which are usually vmdk
 		return nil
 	}
 	needConvert := false
@@ -0,0 +1,57 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"context"
+
+	"yunion.io/x/jsonutils"
+
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/image/models"
+)
+
+type GuestImageImportTask struct {
+	taskman.STask
+}
+
+func init() {
+	taskman.RegisterTask(GuestImageImportTask{})
+}
+
+func (self *GuestImageImportTask) OnInit(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	guestImage := obj.(*models.SGuestImage)
+
+	importFormat, _ := self.Params.GetString("import_format")
+	copyFrom, _ := self.Params.GetString("copy_from")
+
+	self.SetStage("OnImportComplete", nil)
+	taskman.LocalTaskRun(self, func() (jsonutils.JSONObject, error) {
+		err := guestImage.ImportFromUrl(ctx, self.UserCred, importFormat, copyFrom, self.Params)
+		if err != nil {
+			return nil, err
+		}
+		return nil, nil
+	})
+}
+
+func (self *GuestImageImportTask) OnImportComplete(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	self.SetStageComplete(ctx, nil)
+}
+
+func (self *GuestImageImportTask) OnImportCompleteFailed(ctx context.Context, obj db.IStandaloneModel, err jsonutils.JSONObject) {
+	self.SetStageFailed(ctx, err)
+}
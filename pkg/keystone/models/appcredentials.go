@@ -0,0 +1,156 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+
+	api "yunion.io/x/onecloud/pkg/apis/identity"
+	"yunion.io/x/onecloud/pkg/httperrors"
+)
+
+// validateAppCredential checks the blob of an application credential and
+// normalizes the delegated roles to role ids, returns the blob to be saved
+func (manager *SCredentialManager) validateAppCredential(userId, projectId, name, blob string) (string, error) {
+	if len(projectId) == 0 || projectId == api.DEFAULT_PROJECT {
+		return "", httperrors.NewInputParameterError("application credential requires a project")
+	}
+	cnt, err := manager.Query().Equals("user_id", userId).Equals("type", api.APP_CREDENTIAL_TYPE).Equals("name", name).CountWithError()
+	if err != nil {
+		return "", httperrors.NewGeneralError(err)
+	}
+	if cnt > 0 {
+		return "", httperrors.NewDuplicateNameError("name", name)
+	}
+
+	appCred := api.SAppCredentialBlob{}
+	err = json.Unmarshal([]byte(blob), &appCred)
+	if err != nil {
+		return "", httperrors.NewInputParameterError("invalid application credential: %s", err)
+	}
+	if len(appCred.Secret) == 0 {
+		return "", httperrors.NewInputParameterError("application credential requires secret")
+	}
+	if appCred.Expire > 0 && appCred.Expire <= time.Now().Unix() {
+		return "", httperrors.NewInputParameterError("application credential expire in the past")
+	}
+	for i := range appCred.AccessRules {
+		if !appCred.AccessRules[i].Validate() {
+			return "", httperrors.NewInputParameterError("invalid access rule %s %s %s", appCred.AccessRules[i].Service, appCred.AccessRules[i].Method, appCred.AccessRules[i].Path)
+		}
+	}
+
+	roles, err := AssignmentManager.FetchUserProjectRoles(userId, projectId)
+	if err != nil {
+		return "", httperrors.NewGeneralError(err)
+	}
+	if len(roles) == 0 {
+		return "", httperrors.NewForbiddenError("user has no role in project %s", projectId)
+	}
+	roleIds := make([]string, 0, len(roles))
+	if len(appCred.Roles) == 0 {
+		// delegate all current roles of the user in the project
+		for i := range roles {
+			roleIds = append(roleIds, roles[i].Id)
+		}
+	} else {
+		for _, r := range appCred.Roles {
+			found := false
+			for i := range roles {
+				if roles[i].Id == r || roles[i].Name == r {
+					if !utils.IsInStringArray(roles[i].Id, roleIds) {
+						roleIds = append(roleIds, roles[i].Id)
+					}
+					found = true
+					break
+				}
+			}
+			if !found {
+				return "", httperrors.NewForbiddenError("user has no role %s in project %s", r, projectId)
+			}
+		}
+	}
+	appCred.Roles = roleIds
+
+	ret, err := json.Marshal(&appCred)
+	if err != nil {
+		return "", httperrors.NewInternalServerError("marshal application credential: %s", err)
+	}
+	return string(ret), nil
+}
+
+func (self *SCredential) GetAppCredential() (*api.SAppCredentialBlob, error) {
+	if self.Type != api.APP_CREDENTIAL_TYPE {
+		return nil, errors.Error("not an application credential")
+	}
+	appCred := api.SAppCredentialBlob{}
+	err := json.Unmarshal(self.getBlob(), &appCred)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unmarshal")
+	}
+	return &appCred, nil
+}
+
+// VerifyAppCredentialSecret compares the secret in constant time
+func (self *SCredential) VerifyAppCredentialSecret(blob *api.SAppCredentialBlob, secret string) bool {
+	return len(secret) > 0 && subtle.ConstantTimeCompare([]byte(blob.Secret), []byte(secret)) == 1
+}
+
+func (manager *SCredentialManager) fetchAppCredential(cred *SCredential) (*SCredential, *api.SAppCredentialBlob, error) {
+	if cred.Type != api.APP_CREDENTIAL_TYPE {
+		return nil, nil, errors.Wrapf(httperrors.ErrNotFound, "application credential %s", cred.Id)
+	}
+	if !cred.Enabled.IsTrue() {
+		return nil, nil, errors.Wrapf(httperrors.ErrInvalidStatus, "application credential %s disabled", cred.Id)
+	}
+	blob, err := cred.GetAppCredential()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "GetAppCredential")
+	}
+	return cred, blob, nil
+}
+
+// FetchAppCredential fetches an enabled application credential by id
+func (manager *SCredentialManager) FetchAppCredential(id string) (*SCredential, *api.SAppCredentialBlob, error) {
+	obj, err := manager.FetchById(id)
+	if err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return nil, nil, errors.Wrapf(httperrors.ErrNotFound, "application credential %s", id)
+		}
+		return nil, nil, errors.Wrap(err, "FetchById")
+	}
+	return manager.fetchAppCredential(obj.(*SCredential))
+}
+
+// FetchAppCredentialByName fetches an enabled application credential of a user by name
+func (manager *SCredentialManager) FetchAppCredentialByName(userId, name string) (*SCredential, *api.SAppCredentialBlob, error) {
+	q := manager.Query().Equals("user_id", userId).Equals("type", api.APP_CREDENTIAL_TYPE).Equals("name", name)
+	cred := SCredential{}
+	cred.SetModelManager(manager, &cred)
+	err := q.First(&cred)
+	if err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return nil, nil, errors.Wrapf(httperrors.ErrNotFound, "application credential %s", name)
+		}
+		return nil, nil, errors.Wrap(err, "Query")
+	}
+	return manager.fetchAppCredential(&cred)
+}
@@ -17,9 +17,12 @@ package models
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"reflect"
 
 	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
 	"yunion.io/x/pkg/errors"
 	"yunion.io/x/pkg/gotypes"
 	"yunion.io/x/pkg/tristate"
@@ -32,6 +35,7 @@ import (
 	"yunion.io/x/onecloud/pkg/mcclient"
 	"yunion.io/x/onecloud/pkg/util/rbacutils"
 	"yunion.io/x/onecloud/pkg/util/stringutils2"
+	"yunion.io/x/onecloud/pkg/util/webauthn"
 )
 
 type SCredentialManager struct {
@@ -119,6 +123,13 @@ func (manager *SCredentialManager) ValidateCreateData(
 	if len(input.Type) == 0 {
 		return input, httperrors.NewInputParameterError("missing input field type")
 	}
+	switch input.Type {
+	case api.ACCESS_SECRET_TYPE, api.OIDC_CREDENTIAL_TYPE, api.APP_CREDENTIAL_TYPE:
+		// credentials carrying rights of the user cannot be issued by a restricted token
+		if len(mcclient.GetAppCredential(userCred).Id) > 0 {
+			return input, httperrors.NewForbiddenError("%s credential cannot be created by token of application credential", input.Type)
+		}
+	}
 	projectId := input.ProjectId
 	userId := ownerId.GetUserId()
 	if len(userId) == 0 {
@@ -147,6 +158,17 @@ func (manager *SCredentialManager) ValidateCreateData(
 	if len(blob) == 0 {
 		return input, httperrors.NewInputParameterError("missing input field blob")
 	}
+	var err error
+	if input.Type == api.APP_CREDENTIAL_TYPE {
+		blob, err = manager.validateAppCredential(userId, projectId, input.Name, blob)
+		if err != nil {
+			return input, err
+		}
+	}
+	err = validateCredentialBlob(input.Type, blob)
+	if err != nil {
+		return input, err
+	}
 	blobEnc, err := keys.CredentialKeyManager.Encrypt([]byte(blob))
 	if err != nil {
 		return input, httperrors.NewInternalServerError("encrypt error %s", err)
@@ -168,6 +190,30 @@ func (self *SCredential) ValidateDeleteCondition(ctx context.Context, info jsonu
 func (self *SCredential) ValidateUpdateData(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.CredentialUpdateInput) (api.CredentialUpdateInput, error) {
 	var err error
 
+	if len(input.Blob) > 0 {
+		switch self.Type {
+		case api.WEBAUTHN_TYPE, api.RECOVERY_CODES_TYPE:
+		default:
+			return input, httperrors.NewForbiddenError("blob of %s credential is immutable", self.Type)
+		}
+		err = validateCredentialBlob(self.Type, input.Blob)
+		if err != nil {
+			return input, err
+		}
+		if self.Type == api.WEBAUTHN_TYPE {
+			cur, err := self.GetWebauthnCredential()
+			if err != nil {
+				return input, httperrors.NewGeneralError(err)
+			}
+			wc := webauthn.SCredential{}
+			json.Unmarshal([]byte(input.Blob), &wc)
+			err = validateWebauthnUpdate(cur, &wc)
+			if err != nil {
+				return input, err
+			}
+		}
+	}
+
 	input.StandaloneResourceBaseUpdateInput, err = self.SStandaloneResourceBase.ValidateUpdateData(ctx, userCred, query, input.StandaloneResourceBaseUpdateInput)
 	if err != nil {
 		return input, errors.Wrap(err, "SStandaloneResourceBase.ValidateUpdateData")
@@ -176,6 +222,78 @@ func (self *SCredential) ValidateUpdateData(ctx context.Context, userCred mcclie
 	return input, nil
 }
 
+// validateWebauthnUpdate only allows the changes made by a successful
+// assertion, i.e. an increasing signature counter and user verified flag
+// being set, so that owners can't rewind the counter to hide cloned
+// authenticators or rebind the credential to another relying party
+func validateWebauthnUpdate(cur, next *webauthn.SCredential) error {
+	// authenticators without a counter always report 0
+	if next.SignCount != 0 || cur.SignCount != 0 {
+		if next.SignCount <= cur.SignCount {
+			return httperrors.NewForbiddenError("sign_count of webauthn credential must increase")
+		}
+	}
+	if cur.UserVerified && !next.UserVerified {
+		return httperrors.NewForbiddenError("user_verified of webauthn credential can't be unset")
+	}
+	a, b := *cur, *next
+	a.SignCount, b.SignCount = 0, 0
+	a.UserVerified, b.UserVerified = false, false
+	if len(a.Transports) == 0 {
+		a.Transports = nil
+	}
+	if len(b.Transports) == 0 {
+		b.Transports = nil
+	}
+	if !reflect.DeepEqual(a, b) {
+		return httperrors.NewForbiddenError("only sign_count of webauthn credential can be updated")
+	}
+	return nil
+}
+
+func (self *SCredential) PostUpdate(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) {
+	self.SStandaloneResourceBase.PostUpdate(ctx, userCred, query, data)
+	blob, _ := data.GetString("blob")
+	if len(blob) > 0 {
+		err := self.setBlob([]byte(blob))
+		if err != nil {
+			log.Errorf("credential %s setBlob fail %s", self.Id, err)
+		}
+	}
+}
+
+func validateCredentialBlob(credType string, blob string) error {
+	switch credType {
+	case api.WEBAUTHN_TYPE:
+		wc := webauthn.SCredential{}
+		err := json.Unmarshal([]byte(blob), &wc)
+		if err != nil {
+			return httperrors.NewInputParameterError("invalid webauthn credential: %s", err)
+		}
+		if len(wc.CredentialId) == 0 || len(wc.RPId) == 0 {
+			return httperrors.NewInputParameterError("webauthn credential requires credential_id and rp_id")
+		}
+		key, err := webauthn.DecodeBase64URL(wc.PublicKey)
+		if err != nil {
+			return httperrors.NewInputParameterError("invalid webauthn public key: %s", err)
+		}
+		pub, _, err := webauthn.ParsePublicKey(key)
+		if err != nil {
+			return httperrors.NewInputParameterError("invalid webauthn public key: %s", err)
+		}
+		if pub.Algorithm != wc.Algorithm {
+			return httperrors.NewInputParameterError("webauthn algorithm %d mismatch public key %d", wc.Algorithm, pub.Algorithm)
+		}
+	case api.RECOVERY_CODES_TYPE:
+		codes := api.SRecoveryCodesBlob{}
+		err := json.Unmarshal([]byte(blob), &codes)
+		if err != nil {
+			return httperrors.NewInputParameterError("invalid recovery codes: %s", err)
+		}
+	}
+	return nil
+}
+
 func (manager *SCredentialManager) FetchCustomizeColumns(
 	ctx context.Context,
 	userCred mcclient.TokenCredential,
@@ -214,6 +332,50 @@ func (self *SCredential) getBlob() []byte {
 	return keys.CredentialKeyManager.Decrypt([]byte(self.EncryptedBlob))
 }
 
+func (self *SCredential) setBlob(blob []byte) error {
+	blobEnc, err := keys.CredentialKeyManager.Encrypt(blob)
+	if err != nil {
+		return errors.Wrap(err, "Encrypt")
+	}
+	_, err = db.Update(self, func() error {
+		self.EncryptedBlob = string(blobEnc)
+		self.KeyHash = keys.CredentialKeyManager.PrimaryKeyHash()
+		return nil
+	})
+	return err
+}
+
+func (self *SCredential) GetWebauthnCredential() (*webauthn.SCredential, error) {
+	if self.Type != api.WEBAUTHN_TYPE {
+		return nil, errors.Error("not a webauthn credential")
+	}
+	wc := webauthn.SCredential{}
+	err := json.Unmarshal(self.getBlob(), &wc)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unmarshal")
+	}
+	return &wc, nil
+}
+
+// SetWebauthnCredential saves the signature counter after a successful assertion
+func (self *SCredential) SetWebauthnCredential(wc *webauthn.SCredential) error {
+	blob, err := json.Marshal(wc)
+	if err != nil {
+		return errors.Wrap(err, "Marshal")
+	}
+	return self.setBlob(blob)
+}
+
+func (manager *SCredentialManager) FetchUserCredentials(userId string, credType string) ([]SCredential, error) {
+	q := manager.Query().Equals("user_id", userId).Equals("type", credType).IsTrue("enabled")
+	creds := make([]SCredential, 0)
+	err := db.FetchModelObjects(manager, q, &creds)
+	if err != nil {
+		return nil, errors.Wrap(err, "FetchModelObjects")
+	}
+	return creds, nil
+}
+
 func (self *SCredential) GetAccessKeySecret() (*api.SAccessKeySecretBlob, error) {
 	if self.Type == api.ACCESS_SECRET_TYPE || self.Type == api.OIDC_CREDENTIAL_TYPE {
 		blobJson, err := jsonutils.Parse(self.getBlob())
@@ -0,0 +1,94 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"testing"
+
+	"yunion.io/x/onecloud/pkg/util/webauthn"
+)
+
+func TestValidateWebauthnUpdate(t *testing.T) {
+	cur := webauthn.SCredential{
+		CredentialId: "Y3JlZA",
+		PublicKey:    "a2V5",
+		Algorithm:    -7,
+		RPId:         "example.com",
+		SignCount:    5,
+		Transports:   []string{"usb"},
+	}
+	cases := []struct {
+		name    string
+		update  func(wc *webauthn.SCredential)
+		wantErr bool
+	}{
+		{
+			name:   "counter increases",
+			update: func(wc *webauthn.SCredential) { wc.SignCount = 6 },
+		},
+		{
+			name: "counter increases with user verified",
+			update: func(wc *webauthn.SCredential) {
+				wc.SignCount = 6
+				wc.UserVerified = true
+			},
+		},
+		{
+			name:    "counter unchanged",
+			update:  func(wc *webauthn.SCredential) {},
+			wantErr: true,
+		},
+		{
+			name:    "counter rewinds",
+			update:  func(wc *webauthn.SCredential) { wc.SignCount = 1 },
+			wantErr: true,
+		},
+		{
+			name: "relying party changes",
+			update: func(wc *webauthn.SCredential) {
+				wc.SignCount = 6
+				wc.RPId = "evil.com"
+			},
+			wantErr: true,
+		},
+		{
+			name: "public key changes",
+			update: func(wc *webauthn.SCredential) {
+				wc.SignCount = 6
+				wc.PublicKey = "b3RoZXI"
+			},
+			wantErr: true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			next := cur
+			next.Transports = append([]string{}, cur.Transports...)
+			c.update(&next)
+			err := validateWebauthnUpdate(&cur, &next)
+			if c.wantErr != (err != nil) {
+				t.Errorf("want error %v, got %v", c.wantErr, err)
+			}
+		})
+	}
+
+	// authenticators without a counter always report 0
+	zero := cur
+	zero.SignCount = 0
+	next := zero
+	if err := validateWebauthnUpdate(&zero, &next); err != nil {
+		t.Errorf("counter of 0 should be allowed: %v", err)
+	}
+}
@@ -107,6 +107,9 @@ type SIdentityProvider struct {
 	IsSso tristate.TriState `list:"domain"`
 	// 是否是缺省SSO登录方式
 	IsDefault tristate.TriState `list:"domain"`
+
+	// SCIM推送访问令牌的sha256摘要，为空表示未启用SCIM
+	ScimTokenHash string `width:"64" charset:"ascii" nullable:"true"`
 }
 
 func (manager *SIdentityProviderManager) initializeAutoCreateUser() error {
@@ -615,6 +618,7 @@ func (self *SIdentityProvider) getMoreDetails(out api.IdentityProviderDetails) a
 	out.ProjectCount, _ = self.GetProjectCount()
 	out.GroupCount, _ = self.GetGroupCount()
 	out.SyncIntervalSeconds = self.getSyncIntervalSeconds()
+	out.ScimEnabled = self.IsScimEnabled()
 	if len(self.TargetDomainId) > 0 {
 		domain, _ := DomainManager.FetchDomainById(self.TargetDomainId)
 		if domain != nil {
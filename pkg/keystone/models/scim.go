@@ -0,0 +1,433 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/tristate"
+
+	api "yunion.io/x/onecloud/pkg/apis/identity"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+)
+
+// SCIM推送的用户属性
+type SScimUser struct {
+	// 用户在认证源中的唯一名称，同时作为id_mapping中的外部ID
+	UserName    string
+	ExternalId  string
+	Displayname string
+	Email       string
+	Mobile      string
+	Enabled     bool
+}
+
+// SCIM推送的组属性
+type SScimGroup struct {
+	// 组名称，同时作为id_mapping中的外部ID
+	DisplayName string
+	ExternalId  string
+	// 组成员的用户ID
+	MemberIds []string
+}
+
+func scimTokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func (idp *SIdentityProvider) IsScimEnabled() bool {
+	return len(idp.ScimTokenHash) > 0
+}
+
+// 启用或关闭SCIM用户推送
+func (idp *SIdentityProvider) AllowPerformScim(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.PerformIdpScimInput) bool {
+	return db.IsAdminAllowPerform(ctx, userCred, idp, "scim")
+}
+
+// 启用或关闭SCIM用户推送
+func (idp *SIdentityProvider) PerformScim(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	input api.PerformIdpScimInput,
+) (jsonutils.JSONObject, error) {
+	if !idp.isSsoIdp() {
+		return nil, errors.Wrapf(httperrors.ErrNotSupported, "scim provisioning is not supported by %s idp", idp.Driver)
+	}
+	output := api.PerformIdpScimOutput{
+		Endpoint: api.SCIM_URL_PREFIX,
+	}
+	hash := ""
+	if input.Enable == nil || *input.Enable {
+		secret := make([]byte, api.SCIM_TOKEN_BYTES)
+		_, err := rand.Read(secret)
+		if err != nil {
+			return nil, errors.Wrap(err, "rand.Read")
+		}
+		output.Token = base64.RawURLEncoding.EncodeToString(secret)
+		output.Enabled = true
+		hash = scimTokenHash(output.Token)
+	}
+	_, err := db.Update(idp, func() error {
+		idp.ScimTokenHash = hash
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "update scim_token_hash")
+	}
+	notes := jsonutils.NewDict()
+	notes.Add(jsonutils.NewBool(output.Enabled), "scim_enabled")
+	db.OpsLog.LogEvent(idp, db.ACT_UPDATE, notes, userCred)
+	logclient.AddSimpleActionLog(idp, logclient.ACT_UPDATE, notes, userCred, true)
+	return jsonutils.Marshal(output), nil
+}
+
+func (manager *SIdentityProviderManager) FetchIdentityProviderByScimToken(token string) (*SIdentityProvider, error) {
+	if len(token) == 0 {
+		return nil, errors.Wrap(httperrors.ErrInvalidCredential, "empty scim token")
+	}
+	idp := &SIdentityProvider{}
+	idp.SetModelManager(manager, idp)
+	err := manager.Query().Equals("scim_token_hash", scimTokenHash(token)).First(idp)
+	if err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return nil, errors.Wrap(httperrors.ErrInvalidCredential, "invalid scim token")
+		}
+		return nil, errors.Wrap(err, "Query")
+	}
+	return idp, nil
+}
+
+// SCIM推送的用户和组所在的域，与SSO登录自动创建用户时使用的域一致
+func (idp *SIdentityProvider) GetScimDomain(ctx context.Context) (*SDomain, error) {
+	return idp.GetSingleDomain(ctx, api.DefaultRemoteDomainId, idp.Name, fmt.Sprintf("%s provider %s", idp.Driver, idp.Name), false)
+}
+
+// 返回该认证源下实体的ID到外部ID的映射
+func (idp *SIdentityProvider) FetchScimIdmaps(entityType string) (map[string]string, error) {
+	q := IdmappingManager.Query().Equals("domain_id", idp.Id).Equals("entity_type", entityType)
+	idmaps := make([]SIdmapping, 0)
+	err := db.FetchModelObjects(IdmappingManager, q, &idmaps)
+	if err != nil && errors.Cause(err) != sql.ErrNoRows {
+		return nil, errors.Wrap(err, "FetchModelObjects")
+	}
+	ret := make(map[string]string, len(idmaps))
+	for i := range idmaps {
+		ret[idmaps[i].PublicId] = idmaps[i].IdpEntityId
+	}
+	return ret, nil
+}
+
+func (idp *SIdentityProvider) fetchScimExternalId(ctx context.Context, entityType string, publicId string) (string, error) {
+	idmaps, err := IdmappingManager.FetchEntities(publicId, entityType)
+	if err != nil {
+		return "", errors.Wrap(err, "FetchEntities")
+	}
+	for i := range idmaps {
+		if idmaps[i].IdpId == idp.Id {
+			return idmaps[i].IdpEntityId, nil
+		}
+	}
+	return "", errors.Wrapf(httperrors.ErrResourceNotFound, "%s %s", entityType, publicId)
+}
+
+// 从SCIM名称查找实体ID
+func (idp *SIdentityProvider) FetchScimPublicId(ctx context.Context, entityType string, extId string) (string, error) {
+	pubId, err := IdmappingManager.FetchByIdpAndEntityId(ctx, idp.Id, extId, entityType)
+	if err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return "", errors.Wrapf(httperrors.ErrResourceNotFound, "%s %s", entityType, extId)
+		}
+		return "", errors.Wrap(err, "FetchByIdpAndEntityId")
+	}
+	return pubId, nil
+}
+
+// rebindScimIdmap moves the id mapping of an entity to a new external id when
+// the entity is renamed by the SCIM client, keeping the public id unchanged
+func (idp *SIdentityProvider) rebindScimIdmap(ctx context.Context, entityType string, publicId string, oldExtId string, newExtId string) error {
+	if oldExtId == newExtId {
+		return nil
+	}
+	pubId, err := IdmappingManager.FetchByIdpAndEntityId(ctx, idp.Id, newExtId, entityType)
+	if err != nil && errors.Cause(err) != sql.ErrNoRows {
+		return errors.Wrap(err, "FetchByIdpAndEntityId")
+	}
+	if err == nil && pubId != publicId {
+		return errors.Wrapf(httperrors.ErrDuplicateResource, "%s %s already exists", entityType, newExtId)
+	}
+	_, err = IdmappingManager.RegisterIdMapWithId(ctx, idp.Id, newExtId, entityType, publicId)
+	if err != nil {
+		return errors.Wrap(err, "RegisterIdMapWithId")
+	}
+	q := filterByIdpAndEntityId(IdmappingManager.Query(), idp.Id, oldExtId, entityType)
+	idmaps := make([]SIdmapping, 0)
+	err = db.FetchModelObjects(IdmappingManager, q, &idmaps)
+	if err != nil && errors.Cause(err) != sql.ErrNoRows {
+		return errors.Wrap(err, "FetchModelObjects")
+	}
+	for i := range idmaps {
+		_, err = db.Update(&idmaps[i], func() error {
+			return idmaps[i].MarkDelete()
+		})
+		if err != nil {
+			return errors.Wrap(err, "MarkDelete")
+		}
+	}
+	return nil
+}
+
+// 查找该认证源推送的用户，返回用户及其SCIM名称
+func (idp *SIdentityProvider) FetchScimUser(ctx context.Context, userId string) (*SUser, string, error) {
+	extId, err := idp.fetchScimExternalId(ctx, api.IdMappingEntityUser, userId)
+	if err != nil {
+		return nil, "", err
+	}
+	usr, err := UserManager.fetchUserById(userId)
+	if err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return nil, "", errors.Wrapf(httperrors.ErrResourceNotFound, "user %s", userId)
+		}
+		return nil, "", errors.Wrap(err, "fetchUserById")
+	}
+	return usr, extId, nil
+}
+
+func (idp *SIdentityProvider) FetchScimUsers() ([]SUser, error) {
+	return idp.getLinkedUsers()
+}
+
+func (idp *SIdentityProvider) ScimCreateUser(ctx context.Context, userCred mcclient.TokenCredential, input SScimUser) (*SUser, error) {
+	pubId, err := IdmappingManager.FetchByIdpAndEntityId(ctx, idp.Id, input.UserName, api.IdMappingEntityUser)
+	if err != nil && errors.Cause(err) != sql.ErrNoRows {
+		return nil, errors.Wrap(err, "FetchByIdpAndEntityId")
+	}
+	if err == nil {
+		usr, _ := UserManager.fetchUserById(pubId)
+		if usr != nil {
+			return nil, errors.Wrapf(httperrors.ErrDuplicateResource, "user %s already exists", input.UserName)
+		}
+	}
+	domain, err := idp.GetScimDomain(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "GetScimDomain")
+	}
+	usr, err := idp.SyncOrCreateUser(ctx, input.UserName, input.UserName, domain.Id, input.Enabled, func(usr *SUser) {
+		usr.Displayname = input.Displayname
+		usr.Email = input.Email
+		usr.Mobile = input.Mobile
+		usr.Enabled = tristate.NewFromBool(input.Enabled)
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "SyncOrCreateUser")
+	}
+	err = usr.SetMetadata(ctx, api.SCIM_METADATA_EXTERNAL_ID, input.ExternalId, userCred)
+	if err != nil {
+		return nil, errors.Wrap(err, "SetMetadata")
+	}
+	db.OpsLog.LogEvent(usr, db.ACT_CREATE, usr.GetShortDesc(ctx), userCred)
+	return usr, nil
+}
+
+func (idp *SIdentityProvider) ScimUpdateUser(ctx context.Context, userCred mcclient.TokenCredential, usr *SUser, userName string, input SScimUser) error {
+	err := idp.rebindScimIdmap(ctx, api.IdMappingEntityUser, usr.Id, userName, input.UserName)
+	if err != nil {
+		return errors.Wrap(err, "rebindScimIdmap")
+	}
+	name := usr.Name
+	if userName != input.UserName {
+		name, err = db.GenerateAlterName(usr, input.UserName)
+		if err != nil {
+			return errors.Wrapf(err, "db.GenerateAlterName %s", input.UserName)
+		}
+	}
+	diff, err := db.Update(usr, func() error {
+		usr.Name = name
+		usr.Displayname = input.Displayname
+		usr.Email = input.Email
+		usr.Mobile = input.Mobile
+		usr.Enabled = tristate.NewFromBool(input.Enabled)
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "Update")
+	}
+	db.OpsLog.LogEvent(usr, db.ACT_UPDATE, diff, userCred)
+	err = usr.SetMetadata(ctx, api.SCIM_METADATA_EXTERNAL_ID, input.ExternalId, userCred)
+	if err != nil {
+		return errors.Wrap(err, "SetMetadata")
+	}
+	return nil
+}
+
+// 删除SCIM推送的用户，用户无法删除时(例如仍拥有资源)禁用用户并解除与认证源的关联
+func (idp *SIdentityProvider) ScimDeleteUser(ctx context.Context, userCred mcclient.TokenCredential, usr *SUser) error {
+	err := usr.ValidateDeleteCondition(ctx, nil)
+	if err == nil {
+		return usr.Delete(ctx, userCred)
+	}
+	log.Warningf("scim: user %s(%s) cannot be deleted, disable it instead: %s", usr.Name, usr.Id, err)
+	diff, err := db.Update(usr, func() error {
+		usr.Enabled = tristate.False
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "Update")
+	}
+	db.OpsLog.LogEvent(usr, db.ACT_UPDATE, diff, userCred)
+	for _, grpId := range UsergroupManager.getUserGroupIds(usr.Id) {
+		grp := GroupManager.fetchGroupById(grpId)
+		if grp != nil && grp.LinkedWithIdp(idp.Id) {
+			err = UsergroupManager.remove(ctx, userCred, usr, grp)
+			if err != nil {
+				return errors.Wrapf(err, "remove from group %s", grp.Name)
+			}
+		}
+	}
+	return usr.UnlinkIdp(idp.Id)
+}
+
+// 返回用户所在的由该认证源推送的组
+func (idp *SIdentityProvider) FetchScimUserGroupIds(userId string, groupIdmaps map[string]string) []string {
+	ret := make([]string, 0)
+	for _, grpId := range UsergroupManager.getUserGroupIds(userId) {
+		if _, ok := groupIdmaps[grpId]; ok {
+			ret = append(ret, grpId)
+		}
+	}
+	return ret
+}
+
+// 查找该认证源推送的组，返回组及其SCIM名称
+func (idp *SIdentityProvider) FetchScimGroup(ctx context.Context, groupId string) (*SGroup, string, error) {
+	extId, err := idp.fetchScimExternalId(ctx, api.IdMappingEntityGroup, groupId)
+	if err != nil {
+		return nil, "", err
+	}
+	grp := GroupManager.fetchGroupById(groupId)
+	if grp == nil {
+		return nil, "", errors.Wrapf(httperrors.ErrResourceNotFound, "group %s", groupId)
+	}
+	return grp, extId, nil
+}
+
+func (idp *SIdentityProvider) FetchScimGroups() ([]SGroup, error) {
+	return idp.getLinkedGroups()
+}
+
+func (idp *SIdentityProvider) FetchScimGroupMemberIds(groupId string) []string {
+	return UsergroupManager.getGroupUserIds(groupId)
+}
+
+func (idp *SIdentityProvider) ScimCreateGroup(ctx context.Context, userCred mcclient.TokenCredential, input SScimGroup) (*SGroup, error) {
+	pubId, err := IdmappingManager.FetchByIdpAndEntityId(ctx, idp.Id, input.DisplayName, api.IdMappingEntityGroup)
+	if err != nil && errors.Cause(err) != sql.ErrNoRows {
+		return nil, errors.Wrap(err, "FetchByIdpAndEntityId")
+	}
+	if err == nil && GroupManager.fetchGroupById(pubId) != nil {
+		return nil, errors.Wrapf(httperrors.ErrDuplicateResource, "group %s already exists", input.DisplayName)
+	}
+	err = idp.validateScimGroupMembers(input.MemberIds)
+	if err != nil {
+		return nil, err
+	}
+	domain, err := idp.GetScimDomain(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "GetScimDomain")
+	}
+	grp, err := GroupManager.RegisterExternalGroup(ctx, idp.Id, domain.Id, input.DisplayName, input.DisplayName)
+	if err != nil {
+		return nil, errors.Wrap(err, "RegisterExternalGroup")
+	}
+	err = grp.SetMetadata(ctx, api.SCIM_METADATA_EXTERNAL_ID, input.ExternalId, userCred)
+	if err != nil {
+		return nil, errors.Wrap(err, "SetMetadata")
+	}
+	db.OpsLog.LogEvent(grp, db.ACT_CREATE, grp.GetShortDesc(ctx), userCred)
+	UsergroupManager.SyncGroupUsers(ctx, userCred, grp.Id, input.MemberIds)
+	return grp, nil
+}
+
+func (idp *SIdentityProvider) ScimUpdateGroup(ctx context.Context, userCred mcclient.TokenCredential, grp *SGroup, displayName string, input SScimGroup) error {
+	err := idp.validateScimGroupMembers(input.MemberIds)
+	if err != nil {
+		return err
+	}
+	err = idp.rebindScimIdmap(ctx, api.IdMappingEntityGroup, grp.Id, displayName, input.DisplayName)
+	if err != nil {
+		return errors.Wrap(err, "rebindScimIdmap")
+	}
+	if displayName != input.DisplayName {
+		// group names are unique in domain, the display name keeps the
+		// one pushed by the provider
+		name, err := db.GenerateAlterName(grp, input.DisplayName)
+		if err != nil {
+			return errors.Wrapf(err, "db.GenerateAlterName %s", input.DisplayName)
+		}
+		diff, err := db.Update(grp, func() error {
+			grp.Name = name
+			grp.Displayname = input.DisplayName
+			return nil
+		})
+		if err != nil {
+			return errors.Wrap(err, "Update")
+		}
+		db.OpsLog.LogEvent(grp, db.ACT_UPDATE, diff, userCred)
+	}
+	err = grp.SetMetadata(ctx, api.SCIM_METADATA_EXTERNAL_ID, input.ExternalId, userCred)
+	if err != nil {
+		return errors.Wrap(err, "SetMetadata")
+	}
+	UsergroupManager.SyncGroupUsers(ctx, userCred, grp.Id, input.MemberIds)
+	return nil
+}
+
+func (idp *SIdentityProvider) ScimDeleteGroup(ctx context.Context, userCred mcclient.TokenCredential, grp *SGroup) error {
+	err := grp.UnlinkIdp(idp.Id)
+	if err != nil {
+		return errors.Wrap(err, "UnlinkIdp")
+	}
+	return grp.Delete(ctx, userCred)
+}
+
+// 组成员只能是该认证源推送的用户
+func (idp *SIdentityProvider) validateScimGroupMembers(userIds []string) error {
+	if len(userIds) == 0 {
+		return nil
+	}
+	userIdmaps, err := idp.FetchScimIdmaps(api.IdMappingEntityUser)
+	if err != nil {
+		return errors.Wrap(err, "FetchScimIdmaps")
+	}
+	for _, uid := range userIds {
+		if _, ok := userIdmaps[uid]; !ok {
+			return errors.Wrapf(httperrors.ErrInputParameter, "member %s is not a user of identity provider %s", uid, idp.Name)
+		}
+	}
+	return nil
+}
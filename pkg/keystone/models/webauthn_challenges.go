@@ -0,0 +1,105 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/sqlchemy"
+
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+)
+
+// +onecloud:swagger-gen-ignore
+type SWebauthnChallengeManager struct {
+	db.SResourceBaseManager
+}
+
+var WebauthnChallengeManager *SWebauthnChallengeManager
+
+func init() {
+	WebauthnChallengeManager = &SWebauthnChallengeManager{
+		SResourceBaseManager: db.NewResourceBaseManager(
+			SWebauthnChallenge{},
+			"webauthn_challenge_tbl",
+			"webauthn_challenge",
+			"webauthn_challenges",
+		),
+	}
+	WebauthnChallengeManager.SetVirtualObject(WebauthnChallengeManager)
+}
+
+// SWebauthnChallenge records a used webauthn challenge until it expires, so
+// that an assertion replayed to any keystone replica is rejected
+type SWebauthnChallenge struct {
+	db.SResourceBase
+
+	// sha256 of the challenge
+	Id string `width:"64" charset:"ascii" nullable:"false" primary:"true"`
+
+	ExpiredAt time.Time `nullable:"false" index:"true"`
+}
+
+func webauthnChallengeId(challenge string) string {
+	sum := sha256.Sum256([]byte(challenge))
+	return hex.EncodeToString(sum[:])
+}
+
+func (manager *SWebauthnChallengeManager) IsUsed(challenge string) (bool, error) {
+	cnt, err := manager.RawQuery().Equals("id", webauthnChallengeId(challenge)).CountWithError()
+	if err != nil {
+		return false, errors.Wrap(err, "CountWithError")
+	}
+	return cnt > 0, nil
+}
+
+// Consume marks the challenge used.  The primary key makes sure only one
+// of concurrent consumers succeeds, the others get errors.ErrDuplicateId
+func (manager *SWebauthnChallengeManager) Consume(ctx context.Context, challenge string, ttl time.Duration) error {
+	now := time.Now()
+	manager.purgeExpired(now)
+	record := &SWebauthnChallenge{
+		Id:        webauthnChallengeId(challenge),
+		ExpiredAt: now.Add(ttl),
+	}
+	record.SetModelManager(manager, record)
+	err := manager.TableSpec().Insert(ctx, record)
+	if err != nil {
+		used, _ := manager.IsUsed(challenge)
+		if used {
+			return errors.ErrDuplicateId
+		}
+		return errors.Wrap(err, "Insert")
+	}
+	return nil
+}
+
+func (manager *SWebauthnChallengeManager) purgeExpired(now time.Time) {
+	_, err := sqlchemy.GetDB().Exec(
+		fmt.Sprintf(
+			"delete from %s where expired_at < ?",
+			manager.TableSpec().Name(),
+		), now,
+	)
+	if err != nil {
+		log.Errorf("purge expired webauthn challenges: %v", err)
+	}
+}
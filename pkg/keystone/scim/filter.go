@@ -0,0 +1,522 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scim
+
+import (
+	"strings"
+	"unicode"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/identity"
+)
+
+// IFilter is a parsed SCIM filter expression, see RFC 7644 section 3.4.2.2
+type IFilter interface {
+	Match(obj jsonutils.JSONObject) bool
+}
+
+const (
+	tokenEOF = iota
+	tokenAttr
+	tokenString
+	tokenNumber
+	tokenKeyword
+	tokenLParen
+	tokenRParen
+	tokenLBracket
+	tokenRBracket
+)
+
+type sToken struct {
+	kind  int
+	value string
+}
+
+type sLexer struct {
+	input  []rune
+	pos    int
+	tokens []sToken
+}
+
+func isAttrRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-' || r == '.' || r == ':' || r == '$'
+}
+
+func tokenize(input string) ([]sToken, error) {
+	lex := &sLexer{input: []rune(input)}
+	for {
+		for lex.pos < len(lex.input) && unicode.IsSpace(lex.input[lex.pos]) {
+			lex.pos++
+		}
+		if lex.pos >= len(lex.input) {
+			lex.tokens = append(lex.tokens, sToken{kind: tokenEOF})
+			return lex.tokens, nil
+		}
+		r := lex.input[lex.pos]
+		switch {
+		case r == '(':
+			lex.tokens = append(lex.tokens, sToken{kind: tokenLParen, value: "("})
+			lex.pos++
+		case r == ')':
+			lex.tokens = append(lex.tokens, sToken{kind: tokenRParen, value: ")"})
+			lex.pos++
+		case r == '[':
+			lex.tokens = append(lex.tokens, sToken{kind: tokenLBracket, value: "["})
+			lex.pos++
+		case r == ']':
+			lex.tokens = append(lex.tokens, sToken{kind: tokenRBracket, value: "]"})
+			lex.pos++
+		case r == '"':
+			str, err := lex.readString()
+			if err != nil {
+				return nil, err
+			}
+			lex.tokens = append(lex.tokens, sToken{kind: tokenString, value: str})
+		case r == '-' || unicode.IsDigit(r):
+			start := lex.pos
+			lex.pos++
+			for lex.pos < len(lex.input) && (unicode.IsDigit(lex.input[lex.pos]) || strings.ContainsRune(".eE+-", lex.input[lex.pos])) {
+				lex.pos++
+			}
+			lex.tokens = append(lex.tokens, sToken{kind: tokenNumber, value: string(lex.input[start:lex.pos])})
+		case isAttrRune(r):
+			start := lex.pos
+			for lex.pos < len(lex.input) && isAttrRune(lex.input[lex.pos]) {
+				lex.pos++
+			}
+			word := string(lex.input[start:lex.pos])
+			switch strings.ToLower(word) {
+			case "and", "or", "not", "eq", "ne", "co", "sw", "ew", "pr", "gt", "ge", "lt", "le", "true", "false", "null":
+				lex.tokens = append(lex.tokens, sToken{kind: tokenKeyword, value: strings.ToLower(word)})
+			default:
+				lex.tokens = append(lex.tokens, sToken{kind: tokenAttr, value: word})
+			}
+		default:
+			return nil, errors.Wrapf(ErrInvalidFilter, "unexpected character %q at %d", r, lex.pos)
+		}
+	}
+}
+
+func (lex *sLexer) readString() (string, error) {
+	start := lex.pos
+	lex.pos++
+	for lex.pos < len(lex.input) {
+		switch lex.input[lex.pos] {
+		case '\\':
+			lex.pos += 2
+		case '"':
+			lex.pos++
+			str, err := jsonutils.ParseString(string(lex.input[start:lex.pos]))
+			if err != nil {
+				return "", errors.Wrapf(ErrInvalidFilter, "invalid string %s", string(lex.input[start:lex.pos]))
+			}
+			val, _ := str.GetString()
+			return val, nil
+		default:
+			lex.pos++
+		}
+	}
+	return "", errors.Wrapf(ErrInvalidFilter, "unterminated string at %d", start)
+}
+
+type sParser struct {
+	tokens []sToken
+	pos    int
+}
+
+func (p *sParser) peek() sToken {
+	return p.tokens[p.pos]
+}
+
+func (p *sParser) next() sToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *sParser) isKeyword(kw string) bool {
+	tok := p.peek()
+	return tok.kind == tokenKeyword && tok.value == kw
+}
+
+func (p *sParser) expect(kind int, desc string) error {
+	tok := p.next()
+	if tok.kind != kind {
+		return errors.Wrapf(ErrInvalidFilter, "expect %s, got %q", desc, tok.value)
+	}
+	return nil
+}
+
+// ParseFilter parses a SCIM filter expression
+func ParseFilter(filter string) (IFilter, error) {
+	tokens, err := tokenize(filter)
+	if err != nil {
+		return nil, err
+	}
+	p := &sParser{tokens: tokens}
+	f, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokenEOF {
+		return nil, errors.Wrapf(ErrInvalidFilter, "unexpected %q", p.peek().value)
+	}
+	return f, nil
+}
+
+func (p *sParser) parseOr() (IFilter, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &sLogicFilter{and: false, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *sParser) parseAnd() (IFilter, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("and") {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &sLogicFilter{and: true, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *sParser) parseUnary() (IFilter, error) {
+	if p.isKeyword("not") {
+		p.next()
+		if p.peek().kind != tokenLParen {
+			return nil, errors.Wrap(ErrInvalidFilter, "expect ( after not")
+		}
+		f, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &sNotFilter{filter: f}, nil
+	}
+	if p.peek().kind == tokenLParen {
+		p.next()
+		f, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		err = p.expect(tokenRParen, ")")
+		if err != nil {
+			return nil, err
+		}
+		return f, nil
+	}
+	tok := p.next()
+	if tok.kind != tokenAttr {
+		return nil, errors.Wrapf(ErrInvalidFilter, "expect attribute, got %q", tok.value)
+	}
+	path := parseAttrPath(tok.value)
+	if p.peek().kind == tokenLBracket {
+		p.next()
+		f, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		err = p.expect(tokenRBracket, "]")
+		if err != nil {
+			return nil, err
+		}
+		return &sValuePathFilter{path: path, filter: f}, nil
+	}
+	op := p.next()
+	if op.kind != tokenKeyword {
+		return nil, errors.Wrapf(ErrInvalidFilter, "expect operator after %s, got %q", tok.value, op.value)
+	}
+	switch op.value {
+	case "pr":
+		return &sAttrFilter{path: path, op: op.value}, nil
+	case "eq", "ne", "co", "sw", "ew", "gt", "ge", "lt", "le":
+		val := p.next()
+		var value jsonutils.JSONObject
+		switch {
+		case val.kind == tokenString:
+			value = jsonutils.NewString(val.value)
+		case val.kind == tokenNumber:
+			num, err := jsonutils.ParseString(val.value)
+			if err != nil {
+				return nil, errors.Wrapf(ErrInvalidFilter, "invalid number %s", val.value)
+			}
+			value = num
+		case val.kind == tokenKeyword && val.value == "true":
+			value = jsonutils.JSONTrue
+		case val.kind == tokenKeyword && val.value == "false":
+			value = jsonutils.JSONFalse
+		case val.kind == tokenKeyword && val.value == "null":
+			value = jsonutils.JSONNull
+		default:
+			return nil, errors.Wrapf(ErrInvalidFilter, "invalid comparison value %q", val.value)
+		}
+		return &sAttrFilter{path: path, op: op.value, value: value}, nil
+	default:
+		return nil, errors.Wrapf(ErrInvalidFilter, "unexpected operator %s", op.value)
+	}
+}
+
+// parseAttrPath splits an attribute path into names, stripping the core
+// schema URN prefix and keeping an extension schema URN as the first name
+func parseAttrPath(path string) []string {
+	if strings.HasPrefix(strings.ToLower(path), "urn:") {
+		lowerPath := strings.ToLower(path)
+		for _, schema := range []string{api.SCIM_SCHEMA_USER, api.SCIM_SCHEMA_GROUP} {
+			if strings.HasPrefix(lowerPath, strings.ToLower(schema)+":") {
+				return strings.Split(path[len(schema)+1:], ".")
+			}
+		}
+		pos := strings.LastIndex(path, ":")
+		if pos > 0 {
+			return append([]string{path[:pos]}, strings.Split(path[pos+1:], ".")...)
+		}
+		return []string{path}
+	}
+	return strings.Split(path, ".")
+}
+
+// getAttr returns the value of an attribute, matching the name case-insensitively
+func getAttr(obj jsonutils.JSONObject, name string) (string, jsonutils.JSONObject) {
+	dict, ok := obj.(*jsonutils.JSONDict)
+	if !ok {
+		return "", nil
+	}
+	if dict.Contains(name) {
+		val, _ := dict.Get(name)
+		return name, val
+	}
+	attrs, _ := dict.GetMap()
+	for k, v := range attrs {
+		if strings.EqualFold(k, name) {
+			return k, v
+		}
+	}
+	return "", nil
+}
+
+// resolveAttrPath returns all the values of an attribute path, flattening
+// multi-valued attributes
+func resolveAttrPath(obj jsonutils.JSONObject, path []string) []jsonutils.JSONObject {
+	values := []jsonutils.JSONObject{obj}
+	for _, name := range path {
+		next := make([]jsonutils.JSONObject, 0)
+		for _, val := range values {
+			_, attr := getAttr(val, name)
+			if attr == nil {
+				continue
+			}
+			if arr, ok := attr.(*jsonutils.JSONArray); ok {
+				items, _ := arr.GetArray()
+				next = append(next, items...)
+			} else {
+				next = append(next, attr)
+			}
+		}
+		values = next
+	}
+	return values
+}
+
+type sLogicFilter struct {
+	and   bool
+	left  IFilter
+	right IFilter
+}
+
+func (f *sLogicFilter) Match(obj jsonutils.JSONObject) bool {
+	if f.and {
+		return f.left.Match(obj) && f.right.Match(obj)
+	}
+	return f.left.Match(obj) || f.right.Match(obj)
+}
+
+type sNotFilter struct {
+	filter IFilter
+}
+
+func (f *sNotFilter) Match(obj jsonutils.JSONObject) bool {
+	return !f.filter.Match(obj)
+}
+
+type sValuePathFilter struct {
+	path   []string
+	filter IFilter
+}
+
+func (f *sValuePathFilter) Match(obj jsonutils.JSONObject) bool {
+	for _, val := range resolveAttrPath(obj, f.path) {
+		if f.filter.Match(val) {
+			return true
+		}
+	}
+	return false
+}
+
+type sAttrFilter struct {
+	path  []string
+	op    string
+	value jsonutils.JSONObject
+}
+
+func (f *sAttrFilter) Match(obj jsonutils.JSONObject) bool {
+	if f.op == "ne" {
+		return !(&sAttrFilter{path: f.path, op: "eq", value: f.value}).Match(obj)
+	}
+	values := resolveAttrPath(obj, f.path)
+	if f.op == "pr" {
+		for _, val := range values {
+			if isPresent(val) {
+				return true
+			}
+		}
+		return false
+	}
+	if f.value == jsonutils.JSONNull {
+		return len(values) == 0
+	}
+	for _, val := range values {
+		if _, sub := getAttr(val, "value"); sub != nil {
+			// a complex multi-valued attribute compares with its "value" sub-attribute
+			val = sub
+		}
+		if compareValue(val, f.op, f.value) {
+			return true
+		}
+	}
+	return false
+}
+
+func isPresent(val jsonutils.JSONObject) bool {
+	switch v := val.(type) {
+	case *jsonutils.JSONString:
+		return len(v.Value()) > 0
+	case *jsonutils.JSONArray:
+		return v.Length() > 0
+	case *jsonutils.JSONDict:
+		return v.Length() > 0
+	default:
+		return val != nil && val != jsonutils.JSONNull
+	}
+}
+
+func compareValue(attr jsonutils.JSONObject, op string, value jsonutils.JSONObject) bool {
+	switch v := value.(type) {
+	case *jsonutils.JSONBool:
+		attrBool, err := attr.Bool()
+		if err != nil {
+			return false
+		}
+		return op == "eq" && attrBool == v.Value()
+	case *jsonutils.JSONInt, *jsonutils.JSONFloat:
+		attrNum, err := attr.Float()
+		if err != nil {
+			return false
+		}
+		num, _ := value.Float()
+		return compareOrdered(op, attrNum-num)
+	}
+	attrStr, err := attr.GetString()
+	if err != nil {
+		return false
+	}
+	str, _ := value.GetString()
+	attrStr = strings.ToLower(attrStr)
+	str = strings.ToLower(str)
+	switch op {
+	case "eq":
+		return attrStr == str
+	case "co":
+		return strings.Contains(attrStr, str)
+	case "sw":
+		return strings.HasPrefix(attrStr, str)
+	case "ew":
+		return strings.HasSuffix(attrStr, str)
+	default:
+		return compareOrdered(op, float64(strings.Compare(attrStr, str)))
+	}
+}
+
+func compareOrdered(op string, diff float64) bool {
+	switch op {
+	case "eq":
+		return diff == 0
+	case "gt":
+		return diff > 0
+	case "ge":
+		return diff >= 0
+	case "lt":
+		return diff < 0
+	case "le":
+		return diff <= 0
+	default:
+		return false
+	}
+}
+
+// equalityValue returns the value of a filter in the form of `attr eq "value"`,
+// which is used to look up a resource directly instead of scanning all of them
+func equalityValue(f IFilter, attr string) (string, bool) {
+	af, ok := f.(*sAttrFilter)
+	if !ok || af.op != "eq" || len(af.path) != 1 || !strings.EqualFold(af.path[0], attr) {
+		return "", false
+	}
+	str, err := af.value.GetString()
+	if err != nil {
+		return "", false
+	}
+	return str, true
+}
+
+// equalities collects the attribute values required by the eq comparisons of
+// a filter joined by and, e.g. `type eq "work"`, used to create the missing
+// element of a multi-valued attribute in a PATCH operation
+func equalities(f IFilter) map[string]jsonutils.JSONObject {
+	ret := make(map[string]jsonutils.JSONObject)
+	switch v := f.(type) {
+	case *sAttrFilter:
+		if v.op == "eq" && len(v.path) == 1 {
+			ret[v.path[0]] = v.value
+		}
+	case *sLogicFilter:
+		if v.and {
+			for k, val := range equalities(v.left) {
+				ret[k] = val
+			}
+			for k, val := range equalities(v.right) {
+				ret[k] = val
+			}
+		}
+	}
+	return ret
+}
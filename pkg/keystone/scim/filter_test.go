@@ -0,0 +1,121 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scim
+
+import (
+	"testing"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+)
+
+func TestParseFilter(t *testing.T) {
+	user, _ := jsonutils.ParseString(`{
+		"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"],
+		"id": "2819c223",
+		"userName": "Bjensen@example.com",
+		"displayName": "Babs Jensen",
+		"active": true,
+		"emails": [
+			{"value": "bjensen@example.com", "type": "work", "primary": true},
+			{"value": "babs@jensen.org", "type": "home"}
+		],
+		"meta": {"resourceType": "User", "lastModified": "2011-05-13T04:42:34Z"},
+		"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": {"employeeNumber": "701984"}
+	}`)
+	cases := []struct {
+		filter string
+		want   bool
+	}{
+		{`userName eq "bjensen@example.com"`, true},
+		{`USERNAME Eq "BJENSEN@EXAMPLE.COM"`, true},
+		{`userName ne "bjensen@example.com"`, false},
+		{`displayName co "jen"`, true},
+		{`displayName sw "Babs"`, true},
+		{`displayName ew "Jensen"`, true},
+		{`title pr`, false},
+		{`displayName pr`, true},
+		{`title eq null`, true},
+		{`active eq true`, true},
+		{`active eq false`, false},
+		{`emails co "jensen.org"`, true},
+		{`emails.type eq "home"`, true},
+		{`emails[type eq "work" and value co "@example.com"]`, true},
+		{`emails[type eq "home" and value co "@example.com"]`, false},
+		{`meta.lastModified gt "2011-05-13T04:42:34Z"`, false},
+		{`meta.lastModified ge "2011-05-13T04:42:34Z"`, true},
+		{`userName eq "nobody" or displayName eq "Babs Jensen"`, true},
+		{`userName eq "nobody" or displayName eq "Babs Jensen" and active eq false`, false},
+		{`(userName eq "nobody" or displayName eq "Babs Jensen") and active eq true`, true},
+		{`not (userName eq "nobody")`, true},
+		{`urn:ietf:params:scim:schemas:core:2.0:User:userName sw "bjensen"`, true},
+		{`urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber eq "701984"`, true},
+		{`userName eq "a \"quoted\" name"`, false},
+	}
+	for _, c := range cases {
+		f, err := ParseFilter(c.filter)
+		if err != nil {
+			t.Errorf("ParseFilter(%s) error %s", c.filter, err)
+			continue
+		}
+		if got := f.Match(user); got != c.want {
+			t.Errorf("filter %s want %v got %v", c.filter, c.want, got)
+		}
+	}
+}
+
+func TestParseFilterError(t *testing.T) {
+	for _, filter := range []string{
+		`userName`,
+		`userName eq`,
+		`userName eq "unterminated`,
+		`userName xx "a"`,
+		`(userName eq "a"`,
+		`emails[type eq "work"`,
+		`not userName eq "a"`,
+		`userName eq "a" and`,
+		`userName eq "a" #`,
+	} {
+		_, err := ParseFilter(filter)
+		if errors.Cause(err) != ErrInvalidFilter {
+			t.Errorf("ParseFilter(%s) want ErrInvalidFilter got %v", filter, err)
+		}
+	}
+}
+
+func TestEqualityValue(t *testing.T) {
+	cases := []struct {
+		filter string
+		attr   string
+		value  string
+		ok     bool
+	}{
+		{`userName eq "alice"`, "userName", "alice", true},
+		{`username eq "alice"`, "userName", "alice", true},
+		{`userName sw "alice"`, "userName", "", false},
+		{`userName eq "alice" and active eq true`, "userName", "", false},
+		{`displayName eq "alice"`, "userName", "", false},
+	}
+	for _, c := range cases {
+		f, err := ParseFilter(c.filter)
+		if err != nil {
+			t.Fatalf("ParseFilter(%s) error %s", c.filter, err)
+		}
+		value, ok := equalityValue(f, c.attr)
+		if value != c.value || ok != c.ok {
+			t.Errorf("equalityValue(%s, %s) want %s %v got %s %v", c.filter, c.attr, c.value, c.ok, value, ok)
+		}
+	}
+}
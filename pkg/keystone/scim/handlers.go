@@ -0,0 +1,650 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scim
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/identity"
+	"yunion.io/x/onecloud/pkg/appctx"
+	"yunion.io/x/onecloud/pkg/appsrv"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/lockman"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/keystone/models"
+)
+
+type scimHandler func(ctx context.Context, w http.ResponseWriter, r *http.Request, idp *models.SIdentityProvider)
+
+func AddHandler(app *appsrv.Application) {
+	prefix := api.SCIM_URL_PREFIX
+	app.AddHandler2("GET", prefix+"/ServiceProviderConfig", authenticateScim(getServiceProviderConfig), nil, "scim_service_provider_config", nil)
+	app.AddHandler2("GET", prefix+"/ResourceTypes", authenticateScim(getResourceTypes), nil, "scim_resource_types", nil)
+
+	app.AddHandler2("GET", prefix+"/Users", authenticateScim(listUsers), nil, "scim_list_users", nil)
+	app.AddHandler2("POST", prefix+"/Users", authenticateScim(createUser), nil, "scim_create_user", nil)
+	app.AddHandler2("GET", prefix+"/Users/<id>", authenticateScim(getUser), nil, "scim_get_user", nil)
+	app.AddHandler2("PUT", prefix+"/Users/<id>", authenticateScim(replaceUser), nil, "scim_replace_user", nil)
+	app.AddHandler2("PATCH", prefix+"/Users/<id>", authenticateScim(patchUser), nil, "scim_patch_user", nil)
+	app.AddHandler2("DELETE", prefix+"/Users/<id>", authenticateScim(deleteUser), nil, "scim_delete_user", nil)
+
+	app.AddHandler2("GET", prefix+"/Groups", authenticateScim(listGroups), nil, "scim_list_groups", nil)
+	app.AddHandler2("POST", prefix+"/Groups", authenticateScim(createGroup), nil, "scim_create_group", nil)
+	app.AddHandler2("GET", prefix+"/Groups/<id>", authenticateScim(getGroup), nil, "scim_get_group", nil)
+	app.AddHandler2("PUT", prefix+"/Groups/<id>", authenticateScim(replaceGroup), nil, "scim_replace_group", nil)
+	app.AddHandler2("PATCH", prefix+"/Groups/<id>", authenticateScim(patchGroup), nil, "scim_patch_group", nil)
+	app.AddHandler2("DELETE", prefix+"/Groups/<id>", authenticateScim(deleteGroup), nil, "scim_delete_group", nil)
+}
+
+// authenticateScim authenticates the bearer token issued by the scim action
+// of an identity provider, requests of the same identity provider are
+// serialized when they modify users or groups
+func authenticateScim(f scimHandler) func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+		auth := strings.SplitN(strings.TrimSpace(r.Header.Get(api.SCIM_AUTHORIZATION_HEADER)), " ", 2)
+		if len(auth) != 2 || !strings.EqualFold(auth[0], api.SCIM_AUTHORIZATION_BEARER) {
+			sendError(ctx, w, errors.Wrap(httperrors.ErrUnauthorized, "missing bearer token"))
+			return
+		}
+		idp, err := models.IdentityProviderManager.FetchIdentityProviderByScimToken(strings.TrimSpace(auth[1]))
+		if err != nil {
+			sendError(ctx, w, err)
+			return
+		}
+		if !idp.GetEnabled() {
+			sendError(ctx, w, errors.Wrapf(httperrors.ErrForbidden, "identity provider %s is disabled", idp.Name))
+			return
+		}
+		if r.Method != "GET" {
+			lockman.LockObject(ctx, idp)
+			defer lockman.ReleaseObject(ctx, idp)
+		}
+		f(ctx, w, r, idp)
+	}
+}
+
+func sendScim(w http.ResponseWriter, status int, obj interface{}) {
+	output := []byte(jsonutils.Marshal(obj).String())
+	w.Header().Set("Content-Type", api.SCIM_CONTENT_TYPE)
+	w.Header().Set("Content-Length", strconv.Itoa(len(output)))
+	w.WriteHeader(status)
+	w.Write(output)
+}
+
+func sendError(ctx context.Context, w http.ResponseWriter, err error) {
+	status, scimType := 0, ""
+	switch errors.Cause(err) {
+	case ErrInvalidFilter:
+		status, scimType = http.StatusBadRequest, api.SCIM_ERROR_TYPE_INVALID_FILTER
+	case ErrInvalidPath:
+		status, scimType = http.StatusBadRequest, api.SCIM_ERROR_TYPE_INVALID_PATH
+	case ErrInvalidValue, httperrors.ErrInputParameter:
+		status, scimType = http.StatusBadRequest, api.SCIM_ERROR_TYPE_INVALID_VALUE
+	case ErrInvalidSyntax:
+		status, scimType = http.StatusBadRequest, api.SCIM_ERROR_TYPE_INVALID_SYNTAX
+	case ErrNoTarget:
+		status, scimType = http.StatusBadRequest, api.SCIM_ERROR_TYPE_NO_TARGET
+	case httperrors.ErrDuplicateResource:
+		status, scimType = http.StatusConflict, api.SCIM_ERROR_TYPE_UNIQUENESS
+	default:
+		status = httperrors.NewGeneralError(err).Code
+	}
+	if status >= http.StatusInternalServerError {
+		log.Errorf("scim request error: %s", err)
+	}
+	sendScim(w, status, api.ScimError{
+		Schemas:  []string{api.SCIM_SCHEMA_ERROR},
+		Status:   strconv.Itoa(status),
+		ScimType: scimType,
+		Detail:   err.Error(),
+	})
+}
+
+func fetchBody(r *http.Request) (*jsonutils.JSONDict, error) {
+	body, err := appsrv.FetchJSON(r)
+	if err != nil {
+		return nil, errors.Wrap(ErrInvalidSyntax, err.Error())
+	}
+	dict, ok := body.(*jsonutils.JSONDict)
+	if !ok {
+		return nil, errors.Wrap(ErrInvalidSyntax, "request body must be a json object")
+	}
+	return dict, nil
+}
+
+func fetchPatchOperations(r *http.Request) ([]api.ScimPatchOperation, error) {
+	body, err := fetchBody(r)
+	if err != nil {
+		return nil, err
+	}
+	ops := make([]api.ScimPatchOperation, 0)
+	_, opsObj := getAttr(body, "Operations")
+	if opsObj == nil {
+		return nil, errors.Wrap(ErrInvalidSyntax, "missing Operations")
+	}
+	err = opsObj.Unmarshal(&ops)
+	if err != nil {
+		return nil, errors.Wrap(ErrInvalidSyntax, err.Error())
+	}
+	return ops, nil
+}
+
+func fetchFilter(r *http.Request) (IFilter, error) {
+	filterStr := strings.TrimSpace(r.URL.Query().Get("filter"))
+	if len(filterStr) == 0 {
+		return nil, nil
+	}
+	return ParseFilter(filterStr)
+}
+
+func isExcluded(r *http.Request, attr string) bool {
+	for _, excluded := range strings.Split(r.URL.Query().Get("excludedAttributes"), ",") {
+		if strings.EqualFold(strings.TrimSpace(excluded), attr) {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchPage returns the 0-based offset and the page size from the 1-based
+// startIndex and count query parameters
+func fetchPage(r *http.Request) (int, int) {
+	query := r.URL.Query()
+	start, err := strconv.Atoi(query.Get("startIndex"))
+	if err != nil || start < 1 {
+		start = 1
+	}
+	count, err := strconv.Atoi(query.Get("count"))
+	if err != nil {
+		count = api.SCIM_DEFAULT_PAGE_SIZE
+	}
+	if count < 0 {
+		count = 0
+	} else if count > api.SCIM_MAX_PAGE_SIZE {
+		count = api.SCIM_MAX_PAGE_SIZE
+	}
+	return start - 1, count
+}
+
+func pageRange(total, offset, count int) (int, int) {
+	if offset > total {
+		offset = total
+	}
+	end := offset + count
+	if end > total {
+		end = total
+	}
+	return offset, end
+}
+
+func sendList(w http.ResponseWriter, total int, offset int, resources []jsonutils.JSONObject) {
+	sendScim(w, http.StatusOK, api.ScimListResponse{
+		Schemas:      []string{api.SCIM_SCHEMA_LIST_RESPONSE},
+		TotalResults: total,
+		StartIndex:   offset + 1,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	})
+}
+
+func getServiceProviderConfig(ctx context.Context, w http.ResponseWriter, r *http.Request, idp *models.SIdentityProvider) {
+	sendScim(w, http.StatusOK, api.ScimServiceProviderConfig{
+		Schemas:          []string{api.SCIM_SCHEMA_SERVICE_PROVIDER},
+		DocumentationUri: api.SCIM_SERVICE_PROVIDER_DOC_URI,
+		Patch:            api.ScimSupported{Supported: true},
+		Filter:           api.ScimFilterSupported{Supported: true, MaxResults: api.SCIM_MAX_PAGE_SIZE},
+		AuthenticationSchemes: []api.ScimAuthenticationScheme{
+			{
+				Type:        api.SCIM_SERVICE_PROVIDER_AUTH_TYPE,
+				Name:        "OAuth Bearer Token",
+				Description: "Authentication with the token issued by the scim action of the identity provider",
+				Primary:     true,
+			},
+		},
+		Meta: api.ScimMeta{
+			ResourceType: "ServiceProviderConfig",
+			Location:     api.SCIM_URL_PREFIX + "/ServiceProviderConfig",
+		},
+	})
+}
+
+func getResourceTypes(ctx context.Context, w http.ResponseWriter, r *http.Request, idp *models.SIdentityProvider) {
+	resources := []jsonutils.JSONObject{
+		jsonutils.Marshal(api.ScimResourceType{
+			Schemas:  []string{api.SCIM_SCHEMA_RESOURCE_TYPE},
+			Id:       api.SCIM_RESOURCE_TYPE_USER,
+			Name:     api.SCIM_RESOURCE_TYPE_USER,
+			Endpoint: "/Users",
+			Schema:   api.SCIM_SCHEMA_USER,
+			Meta: api.ScimMeta{
+				ResourceType: "ResourceType",
+				Location:     api.SCIM_URL_PREFIX + "/ResourceTypes/" + api.SCIM_RESOURCE_TYPE_USER,
+			},
+		}),
+		jsonutils.Marshal(api.ScimResourceType{
+			Schemas:  []string{api.SCIM_SCHEMA_RESOURCE_TYPE},
+			Id:       api.SCIM_RESOURCE_TYPE_GROUP,
+			Name:     api.SCIM_RESOURCE_TYPE_GROUP,
+			Endpoint: "/Groups",
+			Schema:   api.SCIM_SCHEMA_GROUP,
+			Meta: api.ScimMeta{
+				ResourceType: "ResourceType",
+				Location:     api.SCIM_URL_PREFIX + "/ResourceTypes/" + api.SCIM_RESOURCE_TYPE_GROUP,
+			},
+		}),
+	}
+	sendList(w, len(resources), 0, resources)
+}
+
+// fetchCandidateUsers returns the users to be matched with the filter, a
+// filter of `userName eq` or `id eq` is resolved with the id mapping directly
+func fetchCandidateUsers(ctx context.Context, idp *models.SIdentityProvider, filter IFilter) ([]models.SUser, error) {
+	uid := ""
+	if filter != nil {
+		if userName, ok := equalityValue(filter, "userName"); ok {
+			pubId, err := idp.FetchScimPublicId(ctx, api.IdMappingEntityUser, userName)
+			if err != nil {
+				if errors.Cause(err) == httperrors.ErrResourceNotFound {
+					return nil, nil
+				}
+				return nil, err
+			}
+			uid = pubId
+		} else if id, ok := equalityValue(filter, "id"); ok {
+			uid = id
+		}
+	}
+	if len(uid) > 0 {
+		usr, _, err := idp.FetchScimUser(ctx, uid)
+		if err != nil {
+			if errors.Cause(err) == httperrors.ErrResourceNotFound {
+				return nil, nil
+			}
+			return nil, err
+		}
+		return []models.SUser{*usr}, nil
+	}
+	users, err := idp.FetchScimUsers()
+	if err != nil {
+		return nil, errors.Wrap(err, "FetchScimUsers")
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].Id < users[j].Id
+	})
+	return users, nil
+}
+
+func listUsers(ctx context.Context, w http.ResponseWriter, r *http.Request, idp *models.SIdentityProvider) {
+	filter, err := fetchFilter(r)
+	if err != nil {
+		sendError(ctx, w, err)
+		return
+	}
+	users, err := fetchCandidateUsers(ctx, idp, filter)
+	if err != nil {
+		sendError(ctx, w, err)
+		return
+	}
+	userIdmaps, err := idp.FetchScimIdmaps(api.IdMappingEntityUser)
+	if err != nil {
+		sendError(ctx, w, err)
+		return
+	}
+	groupIdmaps, err := idp.FetchScimIdmaps(api.IdMappingEntityGroup)
+	if err != nil {
+		sendError(ctx, w, err)
+		return
+	}
+	toScim := func(usr *models.SUser) *jsonutils.JSONDict {
+		return userToScim(ctx, usr, userIdmaps[usr.Id], idp.FetchScimUserGroupIds(usr.Id, groupIdmaps), groupIdmaps)
+	}
+	offset, count := fetchPage(r)
+	resources := make([]jsonutils.JSONObject, 0)
+	if filter == nil {
+		start, end := pageRange(len(users), offset, count)
+		for i := start; i < end; i++ {
+			resources = append(resources, toScim(&users[i]))
+		}
+		sendList(w, len(users), offset, resources)
+		return
+	}
+	for i := range users {
+		doc := toScim(&users[i])
+		if filter.Match(doc) {
+			resources = append(resources, doc)
+		}
+	}
+	start, end := pageRange(len(resources), offset, count)
+	sendList(w, len(resources), offset, resources[start:end])
+}
+
+func sendUser(ctx context.Context, w http.ResponseWriter, status int, idp *models.SIdentityProvider, usr *models.SUser, userName string) {
+	groupIdmaps, err := idp.FetchScimIdmaps(api.IdMappingEntityGroup)
+	if err != nil {
+		sendError(ctx, w, err)
+		return
+	}
+	doc := userToScim(ctx, usr, userName, idp.FetchScimUserGroupIds(usr.Id, groupIdmaps), groupIdmaps)
+	if status == http.StatusCreated {
+		w.Header().Set("Location", scimLocation("Users", usr.Id))
+	}
+	sendScim(w, status, doc)
+}
+
+func getUser(ctx context.Context, w http.ResponseWriter, r *http.Request, idp *models.SIdentityProvider) {
+	usr, userName, err := idp.FetchScimUser(ctx, appctx.AppContextParams(ctx)["<id>"])
+	if err != nil {
+		sendError(ctx, w, err)
+		return
+	}
+	sendUser(ctx, w, http.StatusOK, idp, usr, userName)
+}
+
+func createUser(ctx context.Context, w http.ResponseWriter, r *http.Request, idp *models.SIdentityProvider) {
+	body, err := fetchBody(r)
+	if err != nil {
+		sendError(ctx, w, err)
+		return
+	}
+	input, err := scimUserFromDoc(body)
+	if err != nil {
+		sendError(ctx, w, err)
+		return
+	}
+	usr, err := idp.ScimCreateUser(ctx, models.GetDefaultAdminCred(), input)
+	if err != nil {
+		sendError(ctx, w, err)
+		return
+	}
+	sendUser(ctx, w, http.StatusCreated, idp, usr, input.UserName)
+}
+
+func replaceUser(ctx context.Context, w http.ResponseWriter, r *http.Request, idp *models.SIdentityProvider) {
+	usr, userName, err := idp.FetchScimUser(ctx, appctx.AppContextParams(ctx)["<id>"])
+	if err != nil {
+		sendError(ctx, w, err)
+		return
+	}
+	body, err := fetchBody(r)
+	if err != nil {
+		sendError(ctx, w, err)
+		return
+	}
+	updateUser(ctx, w, idp, usr, userName, body)
+}
+
+func patchUser(ctx context.Context, w http.ResponseWriter, r *http.Request, idp *models.SIdentityProvider) {
+	usr, userName, err := idp.FetchScimUser(ctx, appctx.AppContextParams(ctx)["<id>"])
+	if err != nil {
+		sendError(ctx, w, err)
+		return
+	}
+	ops, err := fetchPatchOperations(r)
+	if err != nil {
+		sendError(ctx, w, err)
+		return
+	}
+	doc := userToScim(ctx, usr, userName, nil, nil)
+	err = ApplyPatch(doc, ops)
+	if err != nil {
+		sendError(ctx, w, err)
+		return
+	}
+	updateUser(ctx, w, idp, usr, userName, doc)
+}
+
+func updateUser(ctx context.Context, w http.ResponseWriter, idp *models.SIdentityProvider, usr *models.SUser, userName string, doc *jsonutils.JSONDict) {
+	input, err := scimUserFromDoc(doc)
+	if err != nil {
+		sendError(ctx, w, err)
+		return
+	}
+	err = idp.ScimUpdateUser(ctx, models.GetDefaultAdminCred(), usr, userName, input)
+	if err != nil {
+		sendError(ctx, w, err)
+		return
+	}
+	sendUser(ctx, w, http.StatusOK, idp, usr, input.UserName)
+}
+
+func deleteUser(ctx context.Context, w http.ResponseWriter, r *http.Request, idp *models.SIdentityProvider) {
+	usr, _, err := idp.FetchScimUser(ctx, appctx.AppContextParams(ctx)["<id>"])
+	if err != nil {
+		sendError(ctx, w, err)
+		return
+	}
+	err = idp.ScimDeleteUser(ctx, models.GetDefaultAdminCred(), usr)
+	if err != nil {
+		sendError(ctx, w, err)
+		return
+	}
+	appsrv.SendNoContent(w)
+}
+
+// fetchCandidateGroups returns the groups to be matched with the filter, a
+// filter of `displayName eq` or `id eq` is resolved with the id mapping directly
+func fetchCandidateGroups(ctx context.Context, idp *models.SIdentityProvider, filter IFilter) ([]models.SGroup, error) {
+	gid := ""
+	if filter != nil {
+		if displayName, ok := equalityValue(filter, "displayName"); ok {
+			pubId, err := idp.FetchScimPublicId(ctx, api.IdMappingEntityGroup, displayName)
+			if err != nil {
+				if errors.Cause(err) == httperrors.ErrResourceNotFound {
+					return nil, nil
+				}
+				return nil, err
+			}
+			gid = pubId
+		} else if id, ok := equalityValue(filter, "id"); ok {
+			gid = id
+		}
+	}
+	if len(gid) > 0 {
+		grp, _, err := idp.FetchScimGroup(ctx, gid)
+		if err != nil {
+			if errors.Cause(err) == httperrors.ErrResourceNotFound {
+				return nil, nil
+			}
+			return nil, err
+		}
+		return []models.SGroup{*grp}, nil
+	}
+	groups, err := idp.FetchScimGroups()
+	if err != nil {
+		return nil, errors.Wrap(err, "FetchScimGroups")
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].Id < groups[j].Id
+	})
+	return groups, nil
+}
+
+func listGroups(ctx context.Context, w http.ResponseWriter, r *http.Request, idp *models.SIdentityProvider) {
+	filter, err := fetchFilter(r)
+	if err != nil {
+		sendError(ctx, w, err)
+		return
+	}
+	groups, err := fetchCandidateGroups(ctx, idp, filter)
+	if err != nil {
+		sendError(ctx, w, err)
+		return
+	}
+	userIdmaps, err := idp.FetchScimIdmaps(api.IdMappingEntityUser)
+	if err != nil {
+		sendError(ctx, w, err)
+		return
+	}
+	groupIdmaps, err := idp.FetchScimIdmaps(api.IdMappingEntityGroup)
+	if err != nil {
+		sendError(ctx, w, err)
+		return
+	}
+	withMembers := !isExcluded(r, "members")
+	toScim := func(grp *models.SGroup, withMembers bool) *jsonutils.JSONDict {
+		var memberIds []string
+		if withMembers {
+			memberIds = idp.FetchScimGroupMemberIds(grp.Id)
+		}
+		doc := groupToScim(ctx, grp, groupIdmaps[grp.Id], memberIds, userIdmaps)
+		if !withMembers {
+			doc.Remove("members")
+		}
+		return doc
+	}
+	offset, count := fetchPage(r)
+	resources := make([]jsonutils.JSONObject, 0)
+	if filter == nil {
+		start, end := pageRange(len(groups), offset, count)
+		for i := start; i < end; i++ {
+			resources = append(resources, toScim(&groups[i], withMembers))
+		}
+		sendList(w, len(groups), offset, resources)
+		return
+	}
+	for i := range groups {
+		// members are always needed to evaluate a filter such as members[value eq "id"]
+		doc := toScim(&groups[i], true)
+		if filter.Match(doc) {
+			if !withMembers {
+				doc.Remove("members")
+			}
+			resources = append(resources, doc)
+		}
+	}
+	start, end := pageRange(len(resources), offset, count)
+	sendList(w, len(resources), offset, resources[start:end])
+}
+
+func sendGroup(ctx context.Context, w http.ResponseWriter, status int, idp *models.SIdentityProvider, grp *models.SGroup, displayName string) {
+	userIdmaps, err := idp.FetchScimIdmaps(api.IdMappingEntityUser)
+	if err != nil {
+		sendError(ctx, w, err)
+		return
+	}
+	doc := groupToScim(ctx, grp, displayName, idp.FetchScimGroupMemberIds(grp.Id), userIdmaps)
+	if status == http.StatusCreated {
+		w.Header().Set("Location", scimLocation("Groups", grp.Id))
+	}
+	sendScim(w, status, doc)
+}
+
+func getGroup(ctx context.Context, w http.ResponseWriter, r *http.Request, idp *models.SIdentityProvider) {
+	grp, displayName, err := idp.FetchScimGroup(ctx, appctx.AppContextParams(ctx)["<id>"])
+	if err != nil {
+		sendError(ctx, w, err)
+		return
+	}
+	if isExcluded(r, "members") {
+		doc := groupToScim(ctx, grp, displayName, nil, nil)
+		doc.Remove("members")
+		sendScim(w, http.StatusOK, doc)
+		return
+	}
+	sendGroup(ctx, w, http.StatusOK, idp, grp, displayName)
+}
+
+func createGroup(ctx context.Context, w http.ResponseWriter, r *http.Request, idp *models.SIdentityProvider) {
+	body, err := fetchBody(r)
+	if err != nil {
+		sendError(ctx, w, err)
+		return
+	}
+	input, err := scimGroupFromDoc(body)
+	if err != nil {
+		sendError(ctx, w, err)
+		return
+	}
+	grp, err := idp.ScimCreateGroup(ctx, models.GetDefaultAdminCred(), input)
+	if err != nil {
+		sendError(ctx, w, err)
+		return
+	}
+	sendGroup(ctx, w, http.StatusCreated, idp, grp, input.DisplayName)
+}
+
+func replaceGroup(ctx context.Context, w http.ResponseWriter, r *http.Request, idp *models.SIdentityProvider) {
+	grp, displayName, err := idp.FetchScimGroup(ctx, appctx.AppContextParams(ctx)["<id>"])
+	if err != nil {
+		sendError(ctx, w, err)
+		return
+	}
+	body, err := fetchBody(r)
+	if err != nil {
+		sendError(ctx, w, err)
+		return
+	}
+	updateGroup(ctx, w, idp, grp, displayName, body)
+}
+
+func patchGroup(ctx context.Context, w http.ResponseWriter, r *http.Request, idp *models.SIdentityProvider) {
+	grp, displayName, err := idp.FetchScimGroup(ctx, appctx.AppContextParams(ctx)["<id>"])
+	if err != nil {
+		sendError(ctx, w, err)
+		return
+	}
+	ops, err := fetchPatchOperations(r)
+	if err != nil {
+		sendError(ctx, w, err)
+		return
+	}
+	userIdmaps, err := idp.FetchScimIdmaps(api.IdMappingEntityUser)
+	if err != nil {
+		sendError(ctx, w, err)
+		return
+	}
+	doc := groupToScim(ctx, grp, displayName, idp.FetchScimGroupMemberIds(grp.Id), userIdmaps)
+	err = ApplyPatch(doc, ops)
+	if err != nil {
+		sendError(ctx, w, err)
+		return
+	}
+	updateGroup(ctx, w, idp, grp, displayName, doc)
+}
+
+func updateGroup(ctx context.Context, w http.ResponseWriter, idp *models.SIdentityProvider, grp *models.SGroup, displayName string, doc *jsonutils.JSONDict) {
+	input, err := scimGroupFromDoc(doc)
+	if err != nil {
+		sendError(ctx, w, err)
+		return
+	}
+	err = idp.ScimUpdateGroup(ctx, models.GetDefaultAdminCred(), grp, displayName, input)
+	if err != nil {
+		sendError(ctx, w, err)
+		return
+	}
+	sendGroup(ctx, w, http.StatusOK, idp, grp, input.DisplayName)
+}
+
+func deleteGroup(ctx context.Context, w http.ResponseWriter, r *http.Request, idp *models.SIdentityProvider) {
+	grp, _, err := idp.FetchScimGroup(ctx, appctx.AppContextParams(ctx)["<id>"])
+	if err != nil {
+		sendError(ctx, w, err)
+		return
+	}
+	err = idp.ScimDeleteGroup(ctx, models.GetDefaultAdminCred(), grp)
+	if err != nil {
+		sendError(ctx, w, err)
+		return
+	}
+	appsrv.SendNoContent(w)
+}
@@ -0,0 +1,285 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scim
+
+import (
+	"strings"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/identity"
+)
+
+// sPatchPath is a parsed PATCH path, e.g. members[value eq "id"] or
+// emails[type eq "work"].value, see RFC 7644 section 3.5.2
+type sPatchPath struct {
+	attr    []string
+	filter  IFilter
+	subAttr string
+}
+
+func parsePatchPath(path string) (*sPatchPath, error) {
+	tokens, err := tokenize(path)
+	if err != nil {
+		return nil, errors.Wrap(ErrInvalidPath, err.Error())
+	}
+	p := &sParser{tokens: tokens}
+	tok := p.next()
+	if tok.kind != tokenAttr {
+		return nil, errors.Wrapf(ErrInvalidPath, "invalid path %s", path)
+	}
+	ret := &sPatchPath{attr: parseAttrPath(tok.value)}
+	if p.peek().kind == tokenLBracket {
+		p.next()
+		ret.filter, err = p.parseOr()
+		if err != nil {
+			return nil, errors.Wrap(ErrInvalidPath, err.Error())
+		}
+		err = p.expect(tokenRBracket, "]")
+		if err != nil {
+			return nil, errors.Wrap(ErrInvalidPath, err.Error())
+		}
+		if p.peek().kind == tokenAttr {
+			sub := p.next().value
+			if !strings.HasPrefix(sub, ".") || len(sub) == 1 {
+				return nil, errors.Wrapf(ErrInvalidPath, "invalid sub-attribute %s", sub)
+			}
+			ret.subAttr = sub[1:]
+		}
+	}
+	if p.peek().kind != tokenEOF {
+		return nil, errors.Wrapf(ErrInvalidPath, "invalid path %s", path)
+	}
+	return ret, nil
+}
+
+// ApplyPatch applies PATCH operations to the SCIM representation of a resource
+func ApplyPatch(doc *jsonutils.JSONDict, ops []api.ScimPatchOperation) error {
+	for i := range ops {
+		err := applyPatchOperation(doc, ops[i])
+		if err != nil {
+			return errors.Wrapf(err, "operation %d", i)
+		}
+	}
+	return nil
+}
+
+func applyPatchOperation(doc *jsonutils.JSONDict, op api.ScimPatchOperation) error {
+	opName := strings.ToLower(op.Op)
+	switch opName {
+	case api.SCIM_PATCH_OP_ADD, api.SCIM_PATCH_OP_REPLACE, api.SCIM_PATCH_OP_REMOVE:
+	default:
+		return errors.Wrapf(ErrInvalidSyntax, "invalid op %q", op.Op)
+	}
+	if len(op.Path) > 0 {
+		path, err := parsePatchPath(op.Path)
+		if err != nil {
+			return err
+		}
+		if opName != api.SCIM_PATCH_OP_REMOVE && op.Value == nil {
+			return errors.Wrapf(ErrInvalidValue, "missing value for %s", op.Path)
+		}
+		return applyPatchPath(doc, opName, path, op.Value)
+	}
+	if opName == api.SCIM_PATCH_OP_REMOVE {
+		return errors.Wrap(ErrNoTarget, "remove requires a path")
+	}
+	values, ok := op.Value.(*jsonutils.JSONDict)
+	if !ok {
+		return errors.Wrap(ErrInvalidValue, "value of an operation without path must be an object")
+	}
+	attrs, _ := values.GetMap()
+	for k, v := range attrs {
+		path, err := parsePatchPath(k)
+		if err != nil {
+			path = &sPatchPath{attr: []string{k}}
+		}
+		err = applyPatchPath(doc, opName, path, v)
+		if err != nil {
+			return errors.Wrap(err, k)
+		}
+	}
+	return nil
+}
+
+func applyPatchPath(doc *jsonutils.JSONDict, op string, path *sPatchPath, value jsonutils.JSONObject) error {
+	parent := doc
+	for _, name := range path.attr[:len(path.attr)-1] {
+		key, val := getAttr(parent, name)
+		child, ok := val.(*jsonutils.JSONDict)
+		if !ok {
+			if op == api.SCIM_PATCH_OP_REMOVE {
+				return nil
+			}
+			if len(key) == 0 {
+				key = name
+			}
+			child = jsonutils.NewDict()
+			parent.Set(key, child)
+		}
+		parent = child
+	}
+	name := path.attr[len(path.attr)-1]
+	key, current := getAttr(parent, name)
+	if len(key) == 0 {
+		key = name
+	}
+
+	if path.filter == nil {
+		switch op {
+		case api.SCIM_PATCH_OP_REMOVE:
+			arr, isArray := current.(*jsonutils.JSONArray)
+			if isArray && value != nil {
+				// remove the given values from a multi-valued attribute
+				removes := make(map[string]bool)
+				for _, v := range toArray(value) {
+					removes[itemKey(v)] = true
+				}
+				items, _ := arr.GetArray()
+				remains := make([]jsonutils.JSONObject, 0, len(items))
+				for _, item := range items {
+					if !removes[itemKey(item)] {
+						remains = append(remains, item)
+					}
+				}
+				parent.Set(key, jsonutils.NewArray(remains...))
+			} else {
+				parent.Remove(key)
+			}
+		case api.SCIM_PATCH_OP_ADD:
+			if arr, ok := current.(*jsonutils.JSONArray); ok {
+				items, _ := arr.GetArray()
+				exists := make(map[string]bool)
+				for _, item := range items {
+					exists[itemKey(item)] = true
+				}
+				for _, v := range toArray(value) {
+					if !exists[itemKey(v)] {
+						exists[itemKey(v)] = true
+						items = append(items, v)
+					}
+				}
+				parent.Set(key, jsonutils.NewArray(items...))
+			} else if !mergeDict(current, value) {
+				parent.Set(key, value)
+			}
+		case api.SCIM_PATCH_OP_REPLACE:
+			if !mergeDict(current, value) {
+				parent.Set(key, value)
+			}
+		}
+		return nil
+	}
+
+	items := make([]jsonutils.JSONObject, 0)
+	if arr, ok := current.(*jsonutils.JSONArray); ok {
+		items, _ = arr.GetArray()
+	}
+	matched := false
+	result := make([]jsonutils.JSONObject, 0, len(items))
+	for _, item := range items {
+		if !path.filter.Match(item) {
+			result = append(result, item)
+			continue
+		}
+		matched = true
+		if op == api.SCIM_PATCH_OP_REMOVE {
+			if len(path.subAttr) > 0 {
+				if dict, ok := item.(*jsonutils.JSONDict); ok {
+					subKey, _ := getAttr(dict, path.subAttr)
+					dict.Remove(subKey)
+				}
+				result = append(result, item)
+			}
+			continue
+		}
+		setItem(item, path.subAttr, value)
+		result = append(result, item)
+	}
+	if !matched {
+		if op == api.SCIM_PATCH_OP_REMOVE {
+			return nil
+		}
+		item := jsonutils.NewDict()
+		for k, v := range equalities(path.filter) {
+			item.Set(k, v)
+		}
+		if item.Length() == 0 {
+			return errors.Wrapf(ErrNoTarget, "no value of %s matches the filter", name)
+		}
+		setItem(item, path.subAttr, value)
+		result = append(result, item)
+	}
+	parent.Set(key, jsonutils.NewArray(result...))
+	return nil
+}
+
+func toArray(value jsonutils.JSONObject) []jsonutils.JSONObject {
+	if arr, ok := value.(*jsonutils.JSONArray); ok {
+		items, _ := arr.GetArray()
+		return items
+	}
+	return []jsonutils.JSONObject{value}
+}
+
+// itemKey identifies an element of a multi-valued attribute by its "value"
+// sub-attribute, falling back to the whole element
+func itemKey(item jsonutils.JSONObject) string {
+	if _, val := getAttr(item, "value"); val != nil {
+		str, _ := val.GetString()
+		return strings.ToLower(str)
+	}
+	if str, err := item.GetString(); err == nil {
+		return strings.ToLower(str)
+	}
+	return item.String()
+}
+
+func mergeDict(current jsonutils.JSONObject, value jsonutils.JSONObject) bool {
+	dict, ok := current.(*jsonutils.JSONDict)
+	if !ok {
+		return false
+	}
+	values, ok := value.(*jsonutils.JSONDict)
+	if !ok {
+		return false
+	}
+	attrs, _ := values.GetMap()
+	for k, v := range attrs {
+		key, _ := getAttr(dict, k)
+		if len(key) == 0 {
+			key = k
+		}
+		dict.Set(key, v)
+	}
+	return true
+}
+
+func setItem(item jsonutils.JSONObject, subAttr string, value jsonutils.JSONObject) {
+	dict, ok := item.(*jsonutils.JSONDict)
+	if !ok {
+		return
+	}
+	if len(subAttr) > 0 {
+		key, _ := getAttr(dict, subAttr)
+		if len(key) == 0 {
+			key = subAttr
+		}
+		dict.Set(key, value)
+		return
+	}
+	mergeDict(dict, value)
+}
@@ -0,0 +1,218 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scim
+
+import (
+	"reflect"
+	"testing"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/identity"
+)
+
+func parsePatch(t *testing.T, body string) []api.ScimPatchOperation {
+	obj, err := jsonutils.ParseString(body)
+	if err != nil {
+		t.Fatalf("parse %s: %s", body, err)
+	}
+	_, opsObj := getAttr(obj, "Operations")
+	ops := make([]api.ScimPatchOperation, 0)
+	err = opsObj.Unmarshal(&ops)
+	if err != nil {
+		t.Fatalf("unmarshal operations: %s", err)
+	}
+	return ops
+}
+
+func newUserDoc(t *testing.T) *jsonutils.JSONDict {
+	obj, err := jsonutils.ParseString(`{
+		"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"],
+		"id": "u1",
+		"userName": "alice",
+		"displayName": "Alice",
+		"active": true,
+		"emails": [{"value": "alice@example.com", "type": "work", "primary": true}]
+	}`)
+	if err != nil {
+		t.Fatalf("parse user: %s", err)
+	}
+	return obj.(*jsonutils.JSONDict)
+}
+
+func TestApplyPatchUser(t *testing.T) {
+	cases := []struct {
+		name  string
+		patch string
+		check func(doc *jsonutils.JSONDict) error
+	}{
+		{
+			name:  "replace without path",
+			patch: `{"Operations": [{"op": "replace", "value": {"active": false}}]}`,
+			check: func(doc *jsonutils.JSONDict) error {
+				input, err := scimUserFromDoc(doc)
+				if err != nil {
+					return err
+				}
+				if input.Enabled {
+					return errors.Error("user should be disabled")
+				}
+				return nil
+			},
+		},
+		{
+			name:  "replace active with a string value",
+			patch: `{"Operations": [{"op": "Replace", "path": "active", "value": "False"}]}`,
+			check: func(doc *jsonutils.JSONDict) error {
+				input, _ := scimUserFromDoc(doc)
+				if input.Enabled {
+					return errors.Error("user should be disabled")
+				}
+				return nil
+			},
+		},
+		{
+			name:  "replace email by filter",
+			patch: `{"Operations": [{"op": "replace", "path": "emails[type eq \"work\"].value", "value": "alice@corp.example.com"}]}`,
+			check: func(doc *jsonutils.JSONDict) error {
+				input, _ := scimUserFromDoc(doc)
+				if input.Email != "alice@corp.example.com" {
+					return errors.Errorf("unexpected email %s", input.Email)
+				}
+				return nil
+			},
+		},
+		{
+			name:  "add a missing phone number by filter",
+			patch: `{"Operations": [{"op": "add", "path": "phoneNumbers[type eq \"mobile\"].value", "value": "13800000000"}]}`,
+			check: func(doc *jsonutils.JSONDict) error {
+				input, _ := scimUserFromDoc(doc)
+				if input.Mobile != "13800000000" {
+					return errors.Errorf("unexpected mobile %s", input.Mobile)
+				}
+				return nil
+			},
+		},
+		{
+			name:  "rename with a core schema path and set name",
+			patch: `{"Operations": [{"op": "replace", "path": "urn:ietf:params:scim:schemas:core:2.0:User:userName", "value": "alice2"}, {"op": "remove", "path": "displayName"}, {"op": "add", "path": "name.givenName", "value": "Alice"}, {"op": "add", "path": "name.familyName", "value": "Liddell"}]}`,
+			check: func(doc *jsonutils.JSONDict) error {
+				input, _ := scimUserFromDoc(doc)
+				if input.UserName != "alice2" || input.Displayname != "Alice Liddell" {
+					return errors.Errorf("unexpected user %#v", input)
+				}
+				return nil
+			},
+		},
+		{
+			name:  "remove email",
+			patch: `{"Operations": [{"op": "remove", "path": "emails[type eq \"work\"]"}]}`,
+			check: func(doc *jsonutils.JSONDict) error {
+				input, _ := scimUserFromDoc(doc)
+				if input.Email != "" {
+					return errors.Errorf("unexpected email %s", input.Email)
+				}
+				return nil
+			},
+		},
+	}
+	for _, c := range cases {
+		doc := newUserDoc(t)
+		err := ApplyPatch(doc, parsePatch(t, c.patch))
+		if err != nil {
+			t.Errorf("%s: ApplyPatch error %s", c.name, err)
+			continue
+		}
+		err = c.check(doc)
+		if err != nil {
+			t.Errorf("%s: %s", c.name, err)
+		}
+	}
+}
+
+func TestApplyPatchGroupMembers(t *testing.T) {
+	obj, _ := jsonutils.ParseString(`{"displayName": "devops", "members": [{"value": "u1"}, {"value": "u2"}]}`)
+	doc := obj.(*jsonutils.JSONDict)
+	patch := `{"Operations": [
+		{"op": "add", "path": "members", "value": [{"value": "u2"}, {"value": "u3"}]},
+		{"op": "remove", "path": "members[value eq \"u1\"]"},
+		{"op": "Remove", "path": "members", "value": [{"value": "u2"}]},
+		{"op": "remove", "path": "members[value eq \"u9\"]"}
+	]}`
+	err := ApplyPatch(doc, parsePatch(t, patch))
+	if err != nil {
+		t.Fatalf("ApplyPatch error %s", err)
+	}
+	input, err := scimGroupFromDoc(doc)
+	if err != nil {
+		t.Fatalf("scimGroupFromDoc error %s", err)
+	}
+	if !reflect.DeepEqual(input.MemberIds, []string{"u3"}) {
+		t.Errorf("unexpected members %v", input.MemberIds)
+	}
+
+	err = ApplyPatch(doc, parsePatch(t, `{"Operations": [{"op": "replace", "path": "members", "value": []}, {"op": "replace", "value": {"displayName": "ops"}}]}`))
+	if err != nil {
+		t.Fatalf("ApplyPatch error %s", err)
+	}
+	input, _ = scimGroupFromDoc(doc)
+	if input.DisplayName != "ops" || len(input.MemberIds) != 0 {
+		t.Errorf("unexpected group %#v", input)
+	}
+}
+
+func TestApplyPatchError(t *testing.T) {
+	cases := []struct {
+		patch string
+		err   error
+	}{
+		{`{"Operations": [{"op": "move", "path": "active", "value": true}]}`, ErrInvalidSyntax},
+		{`{"Operations": [{"op": "remove"}]}`, ErrNoTarget},
+		{`{"Operations": [{"op": "replace", "value": "x"}]}`, ErrInvalidValue},
+		{`{"Operations": [{"op": "replace", "path": "emails[type eq"}]}`, ErrInvalidPath},
+		{`{"Operations": [{"op": "replace", "path": "emails[type ne \"home\"].value", "value": "x"}]}`, nil},
+		{`{"Operations": [{"op": "replace", "path": "emails[type pr].display", "value": "x"}]}`, nil},
+		{`{"Operations": [{"op": "replace", "path": "phoneNumbers[type pr].value", "value": "x"}]}`, ErrNoTarget},
+	}
+	for _, c := range cases {
+		doc := newUserDoc(t)
+		err := ApplyPatch(doc, parsePatch(t, c.patch))
+		if errors.Cause(err) != c.err {
+			t.Errorf("%s: want %v got %v", c.patch, c.err, err)
+		}
+	}
+}
+
+func TestScimUserFromDoc(t *testing.T) {
+	obj, _ := jsonutils.ParseString(`{
+		"userName": " bob ",
+		"externalId": "00u1",
+		"name": {"formatted": "Bob Smith"},
+		"emails": [{"value": "bob@home.example.com"}, {"value": "bob@example.com", "primary": "true"}],
+		"phoneNumbers": [{"value": "555-555-5555", "type": "work"}]
+	}`)
+	input, err := scimUserFromDoc(obj)
+	if err != nil {
+		t.Fatalf("scimUserFromDoc error %s", err)
+	}
+	if input.UserName != "bob" || input.ExternalId != "00u1" || input.Displayname != "Bob Smith" || input.Email != "bob@example.com" || input.Mobile != "555-555-5555" || !input.Enabled {
+		t.Errorf("unexpected user %#v", input)
+	}
+	_, err = scimUserFromDoc(jsonutils.NewDict())
+	if errors.Cause(err) != ErrInvalidValue {
+		t.Errorf("missing userName want ErrInvalidValue got %v", err)
+	}
+}
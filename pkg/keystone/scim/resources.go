@@ -0,0 +1,192 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scim
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/identity"
+	"yunion.io/x/onecloud/pkg/keystone/models"
+)
+
+func scimTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func scimLocation(resource string, id string) string {
+	return fmt.Sprintf("%s/%s/%s", api.SCIM_URL_PREFIX, resource, id)
+}
+
+func userToScim(ctx context.Context, usr *models.SUser, userName string, groupIds []string, groupIdmaps map[string]string) *jsonutils.JSONDict {
+	user := api.ScimUser{
+		Schemas:     []string{api.SCIM_SCHEMA_USER},
+		Id:          usr.Id,
+		ExternalId:  usr.GetMetadata(ctx, api.SCIM_METADATA_EXTERNAL_ID, nil),
+		UserName:    userName,
+		DisplayName: usr.Displayname,
+		Active:      usr.Enabled.IsTrue(),
+		Meta: api.ScimMeta{
+			ResourceType: api.SCIM_RESOURCE_TYPE_USER,
+			Created:      scimTime(usr.CreatedAt),
+			LastModified: scimTime(usr.UpdatedAt),
+			Location:     scimLocation("Users", usr.Id),
+		},
+	}
+	if len(usr.Displayname) > 0 {
+		user.Name = &api.ScimName{Formatted: usr.Displayname}
+	}
+	if len(usr.Email) > 0 {
+		user.Emails = []api.ScimMultiValue{{Value: usr.Email, Type: "work", Primary: true}}
+	}
+	if len(usr.Mobile) > 0 {
+		user.PhoneNumbers = []api.ScimMultiValue{{Value: usr.Mobile, Type: "mobile", Primary: true}}
+	}
+	for _, gid := range groupIds {
+		user.Groups = append(user.Groups, api.ScimMultiValue{
+			Value:   gid,
+			Display: groupIdmaps[gid],
+			Ref:     scimLocation("Groups", gid),
+		})
+	}
+	return jsonutils.Marshal(user).(*jsonutils.JSONDict)
+}
+
+func groupToScim(ctx context.Context, grp *models.SGroup, displayName string, memberIds []string, userIdmaps map[string]string) *jsonutils.JSONDict {
+	group := api.ScimGroup{
+		Schemas:     []string{api.SCIM_SCHEMA_GROUP},
+		Id:          grp.Id,
+		ExternalId:  grp.GetMetadata(ctx, api.SCIM_METADATA_EXTERNAL_ID, nil),
+		DisplayName: displayName,
+		Members:     []api.ScimMultiValue{},
+		Meta: api.ScimMeta{
+			ResourceType: api.SCIM_RESOURCE_TYPE_GROUP,
+			Created:      scimTime(grp.CreatedAt),
+			LastModified: scimTime(grp.UpdatedAt),
+			Location:     scimLocation("Groups", grp.Id),
+		},
+	}
+	for _, uid := range memberIds {
+		userName, ok := userIdmaps[uid]
+		if !ok {
+			continue
+		}
+		group.Members = append(group.Members, api.ScimMultiValue{
+			Value:   uid,
+			Display: userName,
+			Ref:     scimLocation("Users", uid),
+		})
+	}
+	return jsonutils.Marshal(group).(*jsonutils.JSONDict)
+}
+
+func getString(obj jsonutils.JSONObject, path ...string) string {
+	for _, name := range path {
+		_, obj = getAttr(obj, name)
+		if obj == nil {
+			return ""
+		}
+	}
+	str, _ := obj.GetString()
+	return strings.TrimSpace(str)
+}
+
+// getPrimaryValue returns the primary or else the first value of a
+// multi-valued attribute such as emails
+func getPrimaryValue(obj jsonutils.JSONObject, name string) string {
+	_, attr := getAttr(obj, name)
+	if attr == nil {
+		return ""
+	}
+	items := toArray(attr)
+	for _, item := range items {
+		if _, primary := getAttr(item, "primary"); primary != nil && parseBool(primary, false) {
+			return getString(item, "value")
+		}
+	}
+	if len(items) > 0 {
+		return getString(items[0], "value")
+	}
+	return ""
+}
+
+// parseBool accepts both boolean and string values, some clients send
+// "True" or "False" as the value of a PATCH operation
+func parseBool(obj jsonutils.JSONObject, def bool) bool {
+	if obj == nil || obj == jsonutils.JSONNull {
+		return def
+	}
+	if val, err := obj.Bool(); err == nil {
+		return val
+	}
+	str, _ := obj.GetString()
+	val, err := strconv.ParseBool(strings.TrimSpace(str))
+	if err != nil {
+		return def
+	}
+	return val
+}
+
+func scimUserFromDoc(doc jsonutils.JSONObject) (models.SScimUser, error) {
+	input := models.SScimUser{
+		UserName:    getString(doc, "userName"),
+		ExternalId:  getString(doc, "externalId"),
+		Displayname: getString(doc, "displayName"),
+		Email:       getPrimaryValue(doc, "emails"),
+		Mobile:      getPrimaryValue(doc, "phoneNumbers"),
+	}
+	if len(input.UserName) == 0 {
+		return input, errors.Wrap(ErrInvalidValue, "userName is required")
+	}
+	if len(input.Displayname) == 0 {
+		input.Displayname = getString(doc, "name", "formatted")
+	}
+	if len(input.Displayname) == 0 {
+		input.Displayname = strings.TrimSpace(getString(doc, "name", "givenName") + " " + getString(doc, "name", "familyName"))
+	}
+	_, active := getAttr(doc, "active")
+	input.Enabled = parseBool(active, true)
+	return input, nil
+}
+
+func scimGroupFromDoc(doc jsonutils.JSONObject) (models.SScimGroup, error) {
+	input := models.SScimGroup{
+		DisplayName: getString(doc, "displayName"),
+		ExternalId:  getString(doc, "externalId"),
+		MemberIds:   make([]string, 0),
+	}
+	if len(input.DisplayName) == 0 {
+		return input, errors.Wrap(ErrInvalidValue, "displayName is required")
+	}
+	if _, members := getAttr(doc, "members"); members != nil && members != jsonutils.JSONNull {
+		for _, member := range toArray(members) {
+			uid := getString(member, "value")
+			if len(uid) == 0 {
+				return input, errors.Wrap(ErrInvalidValue, "member value is required")
+			}
+			input.MemberIds = append(input.MemberIds, uid)
+		}
+	}
+	return input, nil
+}
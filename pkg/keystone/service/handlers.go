@@ -22,6 +22,7 @@ import (
 	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
 	"yunion.io/x/onecloud/pkg/keystone/cronjobs"
 	"yunion.io/x/onecloud/pkg/keystone/models"
+	"yunion.io/x/onecloud/pkg/keystone/scim"
 	"yunion.io/x/onecloud/pkg/keystone/tokens"
 	"yunion.io/x/onecloud/pkg/keystone/usages"
 )
@@ -43,6 +44,7 @@ func InitHandlers(app *appsrv.Application) {
 	taskman.AddTaskHandler(API_VERSION, app)
 
 	tokens.AddHandler(app)
+	scim.AddHandler(app)
 
 	for _, manager := range []db.IModelManager{
 		taskman.TaskManager,
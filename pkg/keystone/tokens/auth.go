@@ -49,6 +49,10 @@ func authUserByToken(ctx context.Context, tokenStr string) (*api.SUserExtended,
 	if err != nil {
 		return nil, errors.Wrap(err, "token.ParseFernetToken")
 	}
+	if len(token.AppCredentialId) > 0 {
+		// prevent a restricted token from being exchanged for an unrestricted one
+		return nil, ErrAppCredentialRescope
+	}
 	return models.UserManager.FetchUserExtended(token.UserId, "", "", "")
 }
 
@@ -376,6 +380,39 @@ func authUserByAccessKeyV3(ctx context.Context, input mcclient.SAuthenticationIn
 	return usrExt, credential.ProjectId, aksk, nil
 }
 
+func authUserByAppCredentialV3(ctx context.Context, input mcclient.SAuthenticationInputV3) (*api.SUserExtended, *models.SCredential, *api.SAppCredentialBlob, error) {
+	ident := input.Auth.Identity.AppCredential
+	var credential *models.SCredential
+	var blob *api.SAppCredentialBlob
+	var err error
+	if len(ident.Id) > 0 {
+		credential, blob, err = models.CredentialManager.FetchAppCredential(ident.Id)
+	} else if len(ident.Name) > 0 {
+		var usr *api.SUserExtended
+		usr, err = models.UserManager.FetchUserExtended(ident.User.Id, ident.User.Name, ident.User.Domain.Id, ident.User.Domain.Name)
+		if err != nil {
+			return nil, nil, nil, errors.Wrap(err, "UserManager.FetchUserExtended")
+		}
+		credential, blob, err = models.CredentialManager.FetchAppCredentialByName(usr.Id, ident.Name)
+	} else {
+		return nil, nil, nil, ErrEmptyAuth
+	}
+	if err != nil {
+		return nil, nil, nil, errors.Wrapf(ErrInvalidAppCredential, "%s", err)
+	}
+	if !credential.VerifyAppCredentialSecret(blob, ident.Secret) {
+		return nil, nil, nil, errors.Wrap(ErrInvalidAppCredential, "secret mismatch")
+	}
+	if !blob.IsValid() {
+		return nil, nil, nil, ErrExpiredAppCredential
+	}
+	usrExt, err := models.UserManager.FetchUserExtended(credential.UserId, "", "", "")
+	if err != nil {
+		return nil, nil, nil, errors.Wrap(err, "UserManager.FetchUserExtended")
+	}
+	return usrExt, credential, blob, nil
+}
+
 // +onecloud:swagger-gen-route-method=POST
 // +onecloud:swagger-gen-route-path=/v3/auth/tokens
 // +onecloud:swagger-gen-route-tag=authentication
@@ -387,6 +424,8 @@ func authUserByAccessKeyV3(ctx context.Context, input mcclient.SAuthenticationIn
 // keystone v3认证API
 func AuthenticateV3(ctx context.Context, input mcclient.SAuthenticationInputV3) (*mcclient.TokenCredentialV3, error) {
 	var akskInfo api.SAccessKeySecretInfo
+	var appCredential *models.SCredential
+	var appCredentialBlob *api.SAppCredentialBlob
 	var user *api.SUserExtended
 	var err error
 	if len(input.Auth.Identity.Methods) != 1 {
@@ -430,6 +469,22 @@ func AuthenticateV3(ctx context.Context, input mcclient.SAuthenticationInputV3)
 		if err != nil {
 			return nil, errors.Wrap(err, "authUserByOAuth2")
 		}
+	case api.AUTH_METHOD_WEBAUTHN:
+		// passwordless auth by a WebAuthn/FIDO2 discoverable credential
+		user, err = authUserByWebauthn(ctx, input)
+		if err != nil {
+			return nil, errors.Wrap(err, "authUserByWebauthn")
+		}
+	case api.AUTH_METHOD_APP_CRED:
+		// auth by application credential, the token is scoped to the project of the credential
+		user, appCredential, appCredentialBlob, err = authUserByAppCredentialV3(ctx, input)
+		if err != nil {
+			return nil, errors.Wrap(err, "authUserByAppCredentialV3")
+		}
+		input.Auth.Scope.Project.Id = appCredential.ProjectId
+		input.Auth.Scope.Project.Name = ""
+		input.Auth.Scope.Domain.Id = ""
+		input.Auth.Scope.Domain.Name = ""
 	default:
 		// auth by other methods, e.g. password , etc...
 		user, err = authUserByIdentityV3(ctx, input)
@@ -458,6 +513,12 @@ func AuthenticateV3(ctx context.Context, input mcclient.SAuthenticationInputV3)
 	now := time.Now().UTC()
 	token.ExpiresAt = now.Add(time.Duration(options.Options.TokenExpirationSeconds) * time.Second)
 	token.Context = input.Auth.Context
+	if appCredential != nil {
+		token.AppCredentialId = appCredential.Id
+		if appCredentialBlob.Expire > 0 && appCredentialBlob.Expire < token.ExpiresAt.Unix() {
+			token.ExpiresAt = time.Unix(appCredentialBlob.Expire, 0).UTC()
+		}
+	}
 
 	if len(input.Auth.Scope.Project.Id) == 0 && len(input.Auth.Scope.Project.Name) == 0 && len(input.Auth.Scope.Domain.Id) == 0 && len(input.Auth.Scope.Domain.Name) == 0 {
 		// unscoped auth
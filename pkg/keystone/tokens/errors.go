@@ -30,4 +30,12 @@ const (
 	ErrUserNotInProject   = errors.Error("user not in project")
 	ErrInvalidAccessKeyId = errors.Error("invalid access key id")
 	ErrExpiredAccessKey   = errors.Error("expired access key")
+
+	ErrInvalidAppCredential = errors.Error("invalid application credential")
+	ErrExpiredAppCredential = errors.Error("expired application credential")
+	ErrAppCredentialRescope = errors.Error("token issued by application credential cannot be used to authenticate")
+	ErrAppCredentialV2      = errors.Error("token issued by application credential is not supported by v2 api")
+
+	ErrInvalidWebauthnChallenge   = errors.Error("invalid webauthn challenge")
+	ErrWebauthnCredentialNotFound = errors.Error("webauthn credential not found")
 )
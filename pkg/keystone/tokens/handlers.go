@@ -36,6 +36,7 @@ import (
 func AddHandler(app *appsrv.Application) {
 	app.AddHandler2("POST", "/v2.0/tokens", authenticateTokensV2, nil, "auth_tokens_v2", nil)
 	app.AddHandler2("POST", "/v3/auth/tokens", authenticateTokensV3, nil, "auth_tokens_v3", nil)
+	app.AddHandler2("POST", "/v3/auth/webauthn/challenge", webauthnChallenge, nil, "webauthn_challenge", nil)
 	app.AddHandler2("GET", "/v2.0/tokens/<token>", authenticateToken(verifyTokensV2), nil, "verify_tokens_v2", nil)
 	app.AddHandler2("GET", "/v3/auth/tokens", authenticateToken(verifyTokensV3), nil, "verify_tokens_v3", nil)
 	app.AddHandler2("GET", "/v3/auth/policies", authenticateToken(fetchTokenPolicies), nil, "fetch_token_policies", nil)
@@ -130,11 +131,20 @@ func verifyTokensV2(ctx context.Context, w http.ResponseWriter, r *http.Request)
 		httperrors.GeneralServerError(ctx, w, err)
 		return
 	}
+	if len(token.AppCredentialId) > 0 {
+		httperrors.InvalidCredentialError(ctx, w, "%s", ErrAppCredentialV2)
+		return
+	}
 	user, err := models.UserManager.FetchUserExtended(token.UserId, "", "", "")
 	if err != nil {
 		httperrors.InvalidCredentialError(ctx, w, "invalid user")
 		return
 	}
+	err = verifyUserEnabled(user)
+	if err != nil {
+		httperrors.InvalidCredentialError(ctx, w, "%s", err)
+		return
+	}
 	project, err := models.ProjectManager.FetchProject(token.ProjectId, "", "", "")
 	if err != nil {
 		httperrors.InvalidCredentialError(ctx, w, "invalid project")
@@ -184,6 +194,11 @@ func verifyTokensV3(ctx context.Context, w http.ResponseWriter, r *http.Request)
 		httperrors.InvalidCredentialError(ctx, w, "invalid user")
 		return
 	}
+	err = verifyUserEnabled(user)
+	if err != nil {
+		httperrors.InvalidCredentialError(ctx, w, "%s", err)
+		return
+	}
 	var projExt *models.SProjectExtended
 	var domain *models.SDomain
 	if len(token.ProjectId) > 0 {
@@ -215,6 +230,18 @@ func verifyTokensV3(ctx context.Context, w http.ResponseWriter, r *http.Request)
 	appsrv.SendJSON(w, jsonutils.Marshal(v3token))
 }
 
+// verifyUserEnabled rejects the tokens of a user which is disabled or deleted
+// after the token was issued, e.g. deprovisioned by the identity provider
+func verifyUserEnabled(user *api.SUserExtended) error {
+	if !user.Enabled {
+		return ErrUserDisabled
+	}
+	if !user.DomainEnabled {
+		return ErrDomainDisabled
+	}
+	return nil
+}
+
 func verifyCommon(ctx context.Context, w http.ResponseWriter, tokenStr string) (*SAuthToken, error) {
 	adminToken := policy.FetchUserCredential(ctx)
 	if adminToken == nil || len(tokenStr) == 0 {
@@ -39,6 +39,8 @@ const (
 	SProjectScopedPayloadWithContextVersion = TScopedPayloadVersion(5)
 	SDomainScopedPayloadWithContextVersion  = TScopedPayloadVersion(4)
 	SUnscopedPayloadWithContextVersion      = TScopedPayloadVersion(3)
+
+	SProjectScopedPayloadWithAppCredentialVersion = TScopedPayloadVersion(6)
 )
 
 type ITokenPayload interface {
@@ -171,6 +173,25 @@ func (p *SProjectScopedPayloadWithContext) Encode() ([]byte, error) {
 	return msgpackEncoder(p)
 }
 
+// token issued by an application credential, always project scoped
+type SProjectScopedPayloadWithAppCredential struct {
+	SProjectScopedPayloadWithContext
+	AppCredentialId SUuidPayload
+}
+
+func (p *SProjectScopedPayloadWithAppCredential) Unmarshal(tk []byte) error {
+	return msgpackDecoder(p, tk, SProjectScopedPayloadWithAppCredentialVersion)
+}
+
+func (p *SProjectScopedPayloadWithAppCredential) Decode(token *SAuthToken) {
+	p.SProjectScopedPayloadWithContext.Decode(token)
+	token.AppCredentialId = p.AppCredentialId.getUuid()
+}
+
+func (p *SProjectScopedPayloadWithAppCredential) Encode() ([]byte, error) {
+	return msgpackEncoder(p)
+}
+
 type SDomainScopedPayload struct {
 	Version   TScopedPayloadVersion
 	UserId    SUuidPayload
@@ -23,6 +23,7 @@ import (
 	"yunion.io/x/pkg/utils"
 
 	api "yunion.io/x/onecloud/pkg/apis/identity"
+	"yunion.io/x/onecloud/pkg/httperrors"
 	"yunion.io/x/onecloud/pkg/keystone/keys"
 	"yunion.io/x/onecloud/pkg/keystone/models"
 	"yunion.io/x/onecloud/pkg/keystone/options"
@@ -58,10 +59,14 @@ type SAuthToken struct {
 	AuditIds  []string
 
 	Context mcclient.SAuthContext
+
+	// id of the application credential the token is issued by
+	AppCredentialId string
 }
 
 func (t *SAuthToken) Decode(tk []byte) error {
 	for _, payload := range []ITokenPayload{
+		&SProjectScopedPayloadWithAppCredential{},
 		&SProjectScopedPayloadWithContext{},
 		&SDomainScopedPayloadWithContext{},
 		&SUnscopedPayloadWithContext{},
@@ -101,6 +106,19 @@ func (t *SAuthToken) getProjectScopedPayloadWithContext() ITokenPayload {
 	return &p
 }
 
+func (t *SAuthToken) getProjectScopedPayloadWithAppCredential() ITokenPayload {
+	p := SProjectScopedPayloadWithAppCredential{}
+	p.Version = SProjectScopedPayloadWithAppCredentialVersion
+	p.UserId.parse(t.UserId)
+	p.ProjectId.parse(t.ProjectId)
+	p.Method = authMethodStr2Id(t.Method)
+	p.ExpiresAt = float64(t.ExpiresAt.Unix())
+	p.AuditIds = auditStrings2Bytes(t.AuditIds)
+	p.Context = authContext2Payload(t.Context)
+	p.AppCredentialId.parse(t.AppCredentialId)
+	return &p
+}
+
 func (t *SAuthToken) getDomainScopedPayload() ITokenPayload {
 	p := SDomainScopedPayload{}
 	p.Version = SDomainScopedPayloadVersion
@@ -146,6 +164,9 @@ func (t *SAuthToken) getUnscopedPayloadWithContext() ITokenPayload {
 }
 
 func (t *SAuthToken) getPayload() ITokenPayload {
+	if len(t.AppCredentialId) > 0 {
+		return t.getProjectScopedPayloadWithAppCredential()
+	}
 	if len(t.ProjectId) > 0 {
 		return t.getProjectScopedPayloadWithContext()
 	}
@@ -191,6 +212,10 @@ func (t *SAuthToken) GetSimpleUserCred(token string) (mcclient.TokenCredential,
 	if err != nil {
 		return nil, errors.Wrap(err, "UserManager.FetchUserExtended")
 	}
+	err = verifyUserEnabled(userExt)
+	if err != nil {
+		return nil, httperrors.NewInvalidCredentialError("%s", err)
+	}
 	ret := mcclient.SSimpleToken{
 		Token:    token,
 		UserId:   t.UserId,
@@ -200,7 +225,6 @@ func (t *SAuthToken) GetSimpleUserCred(token string) (mcclient.TokenCredential,
 		Expires:  t.ExpiresAt,
 		Context:  t.Context,
 	}
-	var roles []models.SRole
 	if len(t.ProjectId) > 0 {
 		proj, err := models.ProjectManager.FetchProjectById(t.ProjectId)
 		if err != nil {
@@ -210,7 +234,6 @@ func (t *SAuthToken) GetSimpleUserCred(token string) (mcclient.TokenCredential,
 		ret.Project = proj.Name
 		ret.ProjectDomainId = proj.DomainId
 		ret.ProjectDomain = proj.GetDomain().Name
-		roles, err = models.AssignmentManager.FetchUserProjectRoles(t.UserId, t.ProjectId)
 	} else if len(t.DomainId) > 0 {
 		domain, err := models.DomainManager.FetchDomainById(t.DomainId)
 		if err != nil {
@@ -218,7 +241,19 @@ func (t *SAuthToken) GetSimpleUserCred(token string) (mcclient.TokenCredential,
 		}
 		ret.ProjectDomainId = t.DomainId
 		ret.ProjectDomain = domain.Name
-		roles, err = models.AssignmentManager.FetchUserProjectRoles(t.UserId, t.DomainId)
+	}
+	roles, err := t.getRoles()
+	if err != nil {
+		if len(t.AppCredentialId) > 0 {
+			return nil, httperrors.NewInvalidCredentialError("%s", err)
+		}
+		return nil, errors.Wrap(err, "getRoles")
+	}
+	if len(t.AppCredentialId) > 0 {
+		ret.AppCredential, _, err = t.fetchAppCredential()
+		if err != nil {
+			return nil, httperrors.NewInvalidCredentialError("%s", err)
+		}
 	}
 	roleStrs := make([]string, len(roles))
 	roleIdStrs := make([]string, len(roles))
@@ -238,10 +273,53 @@ func (t *SAuthToken) getRoles() ([]models.SRole, error) {
 	} else if len(t.DomainId) > 0 {
 		roleProjectId = t.DomainId
 	}
-	if len(roleProjectId) > 0 {
-		return models.AssignmentManager.FetchUserProjectRoles(t.UserId, roleProjectId)
+	if len(roleProjectId) == 0 {
+		return nil, nil
 	}
-	return nil, nil
+	roles, err := models.AssignmentManager.FetchUserProjectRoles(t.UserId, roleProjectId)
+	if err != nil {
+		return nil, errors.Wrap(err, "FetchUserProjectRoles")
+	}
+	if len(t.AppCredentialId) > 0 {
+		// token issued by application credential only has the delegated roles
+		// which the user still owns
+		_, roleIds, err := t.fetchAppCredential()
+		if err != nil {
+			return nil, errors.Wrap(err, "fetchAppCredential")
+		}
+		appRoles := make([]models.SRole, 0, len(roles))
+		for i := range roles {
+			if utils.IsInStringArray(roles[i].Id, roleIds) {
+				appRoles = append(appRoles, roles[i])
+			}
+		}
+		if len(appRoles) == 0 {
+			return nil, ErrUserNotInProject
+		}
+		roles = appRoles
+	}
+	return roles, nil
+}
+
+// fetchAppCredential validates the application credential of the token on
+// every use, so that removing, disabling or expiring the credential revokes
+// the tokens issued by it
+func (t *SAuthToken) fetchAppCredential() (api.SAppCredentialInfo, []string, error) {
+	info := api.SAppCredentialInfo{}
+	cred, blob, err := models.CredentialManager.FetchAppCredential(t.AppCredentialId)
+	if err != nil {
+		return info, nil, errors.Wrapf(ErrInvalidAppCredential, "%s", err)
+	}
+	if cred.UserId != t.UserId || cred.ProjectId != t.ProjectId {
+		return info, nil, errors.Wrap(ErrInvalidAppCredential, "owner mismatch")
+	}
+	if !blob.IsValid() {
+		return info, nil, ErrExpiredAppCredential
+	}
+	info.Id = cred.Id
+	info.Name = cred.Name
+	info.AccessRules = blob.AccessRules
+	return info, blob.Roles, nil
 }
 
 func (t *SAuthToken) getTokenV3(
@@ -253,6 +331,13 @@ func (t *SAuthToken) getTokenV3(
 ) (*mcclient.TokenCredentialV3, error) {
 	token := mcclient.TokenCredentialV3{}
 	token.Token.AccessKey = akskInfo
+	if len(t.AppCredentialId) > 0 {
+		appCred, _, err := t.fetchAppCredential()
+		if err != nil {
+			return nil, errors.Wrap(err, "fetchAppCredential")
+		}
+		token.Token.AppCredential = appCred
+	}
 	token.Token.ExpiresAt = t.ExpiresAt
 	token.Token.IssuedAt = t.ExpiresAt.Add(-time.Duration(options.Options.TokenExpirationSeconds) * time.Second)
 	token.Token.AuditIds = t.AuditIds
@@ -360,6 +445,10 @@ func (t *SAuthToken) getTokenV2(
 	user *api.SUserExtended,
 	project *models.SProjectExtended,
 ) (*mcclient.TokenCredentialV2, error) {
+	if len(t.AppCredentialId) > 0 {
+		// v2 token carries no access rules of application credential
+		return nil, ErrAppCredentialV2
+	}
 	token := mcclient.TokenCredentialV2{}
 	token.User.Name = user.Name
 	token.User.Id = user.Id
@@ -15,11 +15,14 @@
 package tokens
 
 import (
+	"context"
 	"testing"
 	"time"
 
 	"github.com/golang-plus/uuid"
 
+	"yunion.io/x/pkg/errors"
+
 	api "yunion.io/x/onecloud/pkg/apis/identity"
 	"yunion.io/x/onecloud/pkg/util/fernetool"
 )
@@ -66,3 +69,59 @@ func TestSAuthToken_Encode(t *testing.T) {
 		}
 	}
 }
+
+func TestSAuthToken_EncodeAppCredential(t *testing.T) {
+	token := SAuthToken{}
+	token.UserId = newUuid()
+	token.Method = api.AUTH_METHOD_APP_CRED
+	token.ProjectId = newUuid()
+	token.ExpiresAt = time.Now()
+	token.AuditIds = []string{newUuid()}
+	token.AppCredentialId = newUuid()
+
+	tk, err := token.Encode()
+	if err != nil {
+		t.Fatalf("SAuthToken encode fail %s", err)
+	}
+	token2 := SAuthToken{}
+	err = token2.Decode(tk)
+	if err != nil {
+		t.Fatalf("SAuthToken decode fail %s", err)
+	}
+	if token2.AppCredentialId != token.AppCredentialId {
+		t.Fatalf("recovery app credential id fail %s != %s", token.AppCredentialId, token2.AppCredentialId)
+	}
+	if token2.Method != token.Method || token2.ProjectId != token.ProjectId {
+		t.Fatalf("recovery token fail %#v != %#v", token, token2)
+	}
+
+	// tokens without application credential keep the previous payload
+	token.AppCredentialId = ""
+	tk, err = token.Encode()
+	if err != nil {
+		t.Fatalf("SAuthToken encode fail %s", err)
+	}
+	token2 = SAuthToken{}
+	err = token2.Decode(tk)
+	if err != nil {
+		t.Fatalf("SAuthToken decode fail %s", err)
+	}
+	if len(token2.AppCredentialId) > 0 {
+		t.Fatalf("unexpected app credential id %s", token2.AppCredentialId)
+	}
+}
+
+func TestSAuthToken_GetTokenV2AppCredential(t *testing.T) {
+	token := SAuthToken{}
+	token.UserId = newUuid()
+	token.Method = api.AUTH_METHOD_APP_CRED
+	token.ProjectId = newUuid()
+	token.ExpiresAt = time.Now().Add(time.Hour)
+	token.AppCredentialId = newUuid()
+
+	// v2 token cannot carry access rules, so it must not be issued
+	v2token, err := token.getTokenV2(context.Background(), &api.SUserExtended{}, nil)
+	if errors.Cause(err) != ErrAppCredentialV2 {
+		t.Fatalf("want %s, got %v %v", ErrAppCredentialV2, v2token, err)
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tokens
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/identity"
+	"yunion.io/x/onecloud/pkg/appsrv"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/keystone/keys"
+	"yunion.io/x/onecloud/pkg/keystone/models"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/webauthn"
+)
+
+const webauthnChallengeTTL = 5 * time.Minute
+
+// newWebauthnChallenge issues a stateless fernet token as challenge, the ones
+// already used are recorded in database until they expire to reject replayed
+// assertions on any replica
+func newWebauthnChallenge() (string, error) {
+	nonce := make([]byte, 16)
+	_, err := rand.Read(nonce)
+	if err != nil {
+		return "", errors.Wrap(err, "rand.Read")
+	}
+	tok, err := keys.TokenKeysManager.Encrypt(nonce)
+	if err != nil {
+		return "", errors.Wrap(err, "Encrypt")
+	}
+	return webauthn.EncodeBase64URL(tok), nil
+}
+
+func verifyWebauthnChallenge(challenge string) error {
+	tok, err := webauthn.DecodeBase64URL(challenge)
+	if err != nil {
+		return ErrInvalidWebauthnChallenge
+	}
+	if keys.TokenKeysManager.VerifyAndDecrypt(tok, webauthnChallengeTTL) == nil {
+		return ErrInvalidWebauthnChallenge
+	}
+	used, err := models.WebauthnChallengeManager.IsUsed(challenge)
+	if err != nil {
+		return errors.Wrap(err, "IsUsed")
+	}
+	if used {
+		return ErrInvalidWebauthnChallenge
+	}
+	return nil
+}
+
+func consumeWebauthnChallenge(ctx context.Context, challenge string) error {
+	err := models.WebauthnChallengeManager.Consume(ctx, challenge, webauthnChallengeTTL)
+	if err != nil {
+		if errors.Cause(err) == errors.ErrDuplicateId {
+			return ErrInvalidWebauthnChallenge
+		}
+		return errors.Wrap(err, "Consume")
+	}
+	return nil
+}
+
+func authUserByWebauthn(ctx context.Context, input mcclient.SAuthenticationInputV3) (*api.SUserExtended, error) {
+	resp := webauthn.SAssertionResponse{}
+	err := json.Unmarshal([]byte(input.Auth.Identity.WebauthnAuth.Assertion), &resp)
+	if err != nil {
+		return nil, errors.Wrap(httperrors.ErrInputParameter, "invalid assertion")
+	}
+	clientData, _, err := webauthn.ParseClientData(resp.Response.ClientDataJSON)
+	if err != nil {
+		return nil, errors.Wrap(err, "ParseClientData")
+	}
+	err = verifyWebauthnChallenge(clientData.Challenge)
+	if err != nil {
+		return nil, err
+	}
+	// passwordless login relies on discoverable credentials which return
+	// the user handle, i.e. the user id
+	userId, err := resp.UserHandle()
+	if err != nil || len(userId) == 0 {
+		return nil, errors.Wrap(ErrWebauthnCredentialNotFound, "missing user handle")
+	}
+	creds, err := models.CredentialManager.FetchUserCredentials(userId, api.WEBAUTHN_TYPE)
+	if err != nil {
+		return nil, errors.Wrap(err, "FetchUserCredentials")
+	}
+	for i := range creds {
+		wc, err := creds[i].GetWebauthnCredential()
+		if err != nil {
+			return nil, errors.Wrap(err, "GetWebauthnCredential")
+		}
+		if wc.CredentialId != resp.CredentialId() {
+			continue
+		}
+		rp := webauthn.SRelyingParty{
+			Id:               wc.RPId,
+			UserVerification: webauthn.UserVerificationRequired,
+		}
+		err = rp.VerifyAssertion(clientData.Challenge, &resp, wc)
+		if err != nil {
+			return nil, errors.Wrap(err, "VerifyAssertion")
+		}
+		err = consumeWebauthnChallenge(ctx, clientData.Challenge)
+		if err != nil {
+			return nil, err
+		}
+		err = creds[i].SetWebauthnCredential(wc)
+		if err != nil {
+			return nil, errors.Wrap(err, "SetWebauthnCredential")
+		}
+		return models.UserManager.FetchUserExtended(userId, "", "", "")
+	}
+	return nil, ErrWebauthnCredentialNotFound
+}
+
+func webauthnChallenge(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	challenge, err := newWebauthnChallenge()
+	if err != nil {
+		httperrors.GeneralServerError(ctx, w, err)
+		return
+	}
+	appsrv.SendJSON(w, jsonutils.Marshal(api.WebauthnChallengeOutput{Challenge: challenge}))
+}
@@ -141,6 +141,11 @@ func (b *LoadbalancerCorpus) GenGobetweenConfigs(dir string, opts *GenGobetweenC
 			pize := func(s string) *string {
 				return &s
 			}
+			var maxConnections *int
+			if listener.MaxConn > 0 {
+				maxConn := listener.MaxConn
+				maxConnections = &maxConn
+			}
 
 			opts.Config.Servers[listener.Id] = gobetween.Server{
 				Bind:     fmt.Sprintf("%s:%d", lb.Address, listener.ListenerPort),
@@ -155,6 +160,7 @@ func (b *LoadbalancerCorpus) GenGobetweenConfigs(dir string, opts *GenGobetweenC
 				Healthcheck: serverHealthcheck,
 				Access:      serverAccess,
 				ConnectionOptions: gobetween.ConnectionOptions{
+					MaxConnections:           maxConnections,
 					ClientIdleTimeout:        pize(fmt.Sprintf("%ds", listener.ClientIdleTimeout)),
 					BackendIdleTimeout:       pize(fmt.Sprintf("%ds", listener.BackendIdleTimeout)),
 					BackendConnectionTimeout: pize(fmt.Sprintf("%ds", listener.BackendConnectTimeout)),
@@ -21,6 +21,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"text/template"
 
@@ -29,6 +30,7 @@ import (
 	computeapi "yunion.io/x/onecloud/pkg/apis/compute"
 	agentutils "yunion.io/x/onecloud/pkg/lbagent/utils"
 	"yunion.io/x/onecloud/pkg/mcclient/models"
+	"yunion.io/x/onecloud/pkg/util/acmeutils"
 )
 
 var haproxyConfigErrNop = errors.New("nop haproxy config snippet")
@@ -75,6 +77,10 @@ func (b *LoadbalancerCorpus) GenHaproxyConfigs(dir string, opts *AgentParams) (*
 			}
 		}
 		for _, lbcert := range b.LoadbalancerCertificates {
+			if !lbcert.isComplete() {
+				// acme certificate not issued yet
+				continue
+			}
 			d := []byte(lbcert.Certificate)
 			if len(d) > 0 && d[len(d)-1] != '\n' {
 				d = append(d, '\n')
@@ -187,7 +193,9 @@ func (b *LoadbalancerCorpus) genHaproxyConfigCommon(lb *Loadbalancer, listener *
 				}
 			}
 			if listener.EnableHttp2 {
-				bind += fmt.Sprintf(" alpn h2,http/1.1")
+				bind += " alpn h2,http/1.1"
+			} else {
+				bind += " alpn http/1.1"
 			}
 		}
 		data["bind"] = bind
@@ -249,7 +257,8 @@ func (b *LoadbalancerCorpus) genHaproxyConfigBackend(data map[string]interface{}
 	var mode string
 	var balanceAlgorithm string
 	var httpCheck, httpCheckExpect string
-	var checkEnable, httpCheckEnable bool
+	var grpcCheck []string
+	var checkEnable, httpCheckEnable, grpcCheckEnable bool
 	var err error
 	{ // mode
 		switch listener.ListenerType {
@@ -276,6 +285,13 @@ func (b *LoadbalancerCorpus) genHaproxyConfigBackend(data map[string]interface{}
 					listener.HealthCheckURI, listener.HealthCheckDomain)
 				httpCheckExpect = agentutils.HaproxyConfigHttpCheckExpect(
 					listener.HealthCheckHttpCode)
+			} else if listener.HealthCheckType == "grpc" {
+				grpcCheckEnable = true
+				grpcCheck, err = agentutils.HaproxyConfigGrpcCheck(
+					listener.HealthCheckGrpcService, listener.HealthCheckDomain)
+				if err != nil {
+					return fmt.Errorf("listener %s(%s): grpc check: %v", listener.Name, listener.Id, err)
+				}
 			}
 		}
 	}
@@ -337,6 +353,15 @@ func (b *LoadbalancerCorpus) genHaproxyConfigBackend(data map[string]interface{}
 				serverLine += " ssl"
 				serverLine += " verify none"
 				serverLine += " check-ssl"
+				if mode == "http" && listener.EnableBackendHttp2 {
+					serverLine += " alpn h2"
+				}
+				if grpcCheckEnable {
+					serverLine += " check-alpn h2"
+				}
+			} else if mode == "http" && listener.EnableBackendHttp2 {
+				// h2c with prior knowledge
+				serverLine += " proto h2"
 			}
 			serverLines = append(serverLines, serverLine)
 		}
@@ -363,12 +388,20 @@ func (b *LoadbalancerCorpus) genHaproxyConfigBackend(data map[string]interface{}
 		data["httpCheck"] = httpCheck
 		data["httpCheckExpect"] = httpCheckExpect
 	}
+	if grpcCheckEnable {
+		data["grpcCheck"] = grpcCheck
+	}
 	return nil
 }
 
-func (b *LoadbalancerCorpus) genHaproxyConfigHttpRate(data map[string]interface{}, requestRate, requestRatePerSrc int) error {
-	periodSecond := 10
-	id := data["id"].(string)
+func (b *LoadbalancerCorpus) genHaproxyConfigHttpRate(data map[string]interface{}, rateLimiter *models.LoadbalancerHTTPRateLimiter) error {
+	var (
+		periodSecond      = 10
+		id                = data["id"].(string)
+		requestRate       = rateLimiter.HTTPRequestRate
+		requestRatePerSrc = rateLimiter.HTTPRequestRatePerSrc
+		deny              = agentutils.HaproxyConfigHttpDeny(rateLimiter.HTTPRateLimitStatus, rateLimiter.HTTPRateLimitBody)
+	)
 	dummyBackends := []map[string]string{}
 	rateRules := []string{}
 
@@ -381,8 +414,8 @@ func (b *LoadbalancerCorpus) genHaproxyConfigHttpRate(data map[string]interface{
 			"stick_table": fmt.Sprintf("stick-table type ip size 1m expire 1m store http_req_rate(%ds)", periodSecond),
 		})
 		rateRules = append(rateRules,
-			fmt.Sprintf("http-request deny deny_status 429 if { src_http_req_rate(%s) gt %d }",
-				idPerSrc, requestRatePerSrc*periodSecond),
+			fmt.Sprintf("%s if { src_http_req_rate(%s) gt %d }",
+				deny, idPerSrc, requestRatePerSrc*periodSecond),
 			fmt.Sprintf("http-request track-sc0 src table %s",
 				idPerSrc))
 	}
@@ -393,8 +426,8 @@ func (b *LoadbalancerCorpus) genHaproxyConfigHttpRate(data map[string]interface{
 			"stick_table": fmt.Sprintf("stick-table type integer size 1 expire 1m store http_req_rate(%ds)", periodSecond),
 		})
 		rateRules = append(rateRules,
-			fmt.Sprintf("http-request deny deny_status 429 if { int(1),table_http_req_rate(%s) gt %d }",
-				idTotal, requestRate*periodSecond),
+			fmt.Sprintf("%s if { int(1),table_http_req_rate(%s) gt %d }",
+				deny, idTotal, requestRate*periodSecond),
 			fmt.Sprintf("http-request track-sc1 int(1) table %s",
 				idTotal))
 	}
@@ -403,6 +436,37 @@ func (b *LoadbalancerCorpus) genHaproxyConfigHttpRate(data map[string]interface{
 	return nil
 }
 
+// genHaproxyConfigConnLimit caps concurrent connections of the listener as a
+// whole and those from each client ip.  Per-src connections are tracked with
+// sc2 in the frontend, leaving sc0, sc1 for request rate tracking in backends
+func (b *LoadbalancerCorpus) genHaproxyConfigConnLimit(data map[string]interface{}, listener *LoadbalancerListener) {
+	id := data["id"].(string)
+	dummyBackends := []map[string]string{}
+	connRules := []string{}
+
+	if listener.MaxConn > 0 {
+		data["maxconn"] = listener.MaxConn
+	}
+	if listener.MaxConnPerSrc > 0 {
+		idConnPerSrc := id + "_connpersrc"
+		dummyBackends = append(dummyBackends, map[string]string{
+			"id":          idConnPerSrc,
+			"stick_table": "stick-table type ip size 1m expire 1m store conn_cur",
+		})
+		data["conn_track"] = fmt.Sprintf("tcp-request connection track-sc2 src table %s", idConnPerSrc)
+		cond := fmt.Sprintf("if { sc2_conn_cur gt %d }", listener.MaxConnPerSrc)
+		switch listener.ListenerType {
+		case "tcp":
+			connRules = append(connRules, "tcp-request connection reject "+cond)
+		case "http", "https":
+			deny := agentutils.HaproxyConfigHttpDeny(listener.HTTPRateLimitStatus, listener.HTTPRateLimitBody)
+			connRules = append(connRules, deny+" "+cond)
+		}
+	}
+	data["conn_rules"] = connRules
+	data["dummy_backends"] = dummyBackends
+}
+
 func (b *LoadbalancerCorpus) haproxyRedirectLine(r *models.LoadbalancerHTTPRedirect, listenerType string) string {
 	var (
 		code   = r.RedirectCode
@@ -423,12 +487,42 @@ func (b *LoadbalancerCorpus) haproxyRedirectLine(r *models.LoadbalancerHTTPRedir
 	return line
 }
 
+func (lbcert *LoadbalancerCertificate) isComplete() bool {
+	return lbcert.Certificate != "" && lbcert.PrivateKey != ""
+}
+
+// haproxyAcmeChallengeLines answers ACME http-01 validation requests of
+// certificates being issued on all http listeners, since domain names may be
+// resolved to any of them
+func (b *LoadbalancerCorpus) haproxyAcmeChallengeLines() []string {
+	lines := []string{}
+	for _, lbcert := range b.LoadbalancerCertificates {
+		if lbcert.AcmeHttpChallenges == nil {
+			continue
+		}
+		for _, challenge := range *lbcert.AcmeHttpChallenges {
+			if challenge.Token == "" || challenge.KeyAuthorization == "" {
+				continue
+			}
+			line := fmt.Sprintf("http-request return status 200 content-type text/plain string %q if { path %s%s }",
+				challenge.KeyAuthorization, acmeutils.HTTP01_PATH_PREFIX, challenge.Token)
+			lines = append(lines, line)
+		}
+	}
+	sort.Strings(lines)
+	return lines
+}
+
 func (b *LoadbalancerCorpus) genHaproxyConfigHttp(buf *bytes.Buffer, listener *LoadbalancerListener, opts *AgentParams) error {
 	var (
 		lb = listener.loadbalancer
 	)
 
+	if listener.ListenerType == "https" && listener.certificate != nil && !listener.certificate.isComplete() {
+		return haproxyConfigErrNop
+	}
 	data := b.genHaproxyConfigCommon(lb, listener, opts)
+	b.genHaproxyConfigConnLimit(data, listener)
 	{
 		// NOTE add X-Real-IP if needed
 		//
@@ -437,6 +531,11 @@ func (b *LoadbalancerCorpus) genHaproxyConfigHttp(buf *bytes.Buffer, listener *L
 		data["xforwardedfor"] = listener.XForwardedFor
 		data["gzip"] = listener.Gzip
 	}
+	acmeChallenges := []string{}
+	if listener.ListenerType == "http" {
+		acmeChallenges = b.haproxyAcmeChallengeLines()
+		data["acme_challenges"] = acmeChallenges
+	}
 
 	var (
 		rules     = listener.rules.OrderedEnabledList()
@@ -501,7 +600,7 @@ func (b *LoadbalancerCorpus) genHaproxyConfigHttp(buf *bytes.Buffer, listener *L
 			if err := b.genHaproxyConfigBackend(backendData, lb, listener, backendGroup); err != nil {
 				return err
 			}
-			if err := b.genHaproxyConfigHttpRate(backendData, rule.HTTPRequestRate, rule.HTTPRequestRatePerSrc); err != nil {
+			if err := b.genHaproxyConfigHttpRate(backendData, &rule.LoadbalancerHTTPRateLimiter); err != nil {
 				return err
 			}
 			backends = append(backends, backendData)
@@ -518,7 +617,7 @@ func (b *LoadbalancerCorpus) genHaproxyConfigHttp(buf *bytes.Buffer, listener *L
 			if err := b.genHaproxyConfigBackend(backendData, lb, listener, backendGroup); err != nil {
 				return err
 			}
-			if err := b.genHaproxyConfigHttpRate(backendData, listener.HTTPRequestRate, listener.HTTPRequestRatePerSrc); err != nil {
+			if err := b.genHaproxyConfigHttpRate(backendData, &listener.LoadbalancerHTTPRateLimiter); err != nil {
 				return err
 			}
 			backends = append(backends, backendData)
@@ -526,7 +625,7 @@ func (b *LoadbalancerCorpus) genHaproxyConfigHttp(buf *bytes.Buffer, listener *L
 		}
 		data["backends"] = backends
 	}
-	if len(ruleLines) == 0 && len(backends) == 0 {
+	if len(ruleLines) == 0 && len(backends) == 0 && len(acmeChallenges) == 0 {
 		// nothing to serve
 		return haproxyConfigErrNop
 	}
@@ -537,6 +636,7 @@ func (b *LoadbalancerCorpus) genHaproxyConfigHttp(buf *bytes.Buffer, listener *L
 func (b *LoadbalancerCorpus) genHaproxyConfigTcp(buf *bytes.Buffer, listener *LoadbalancerListener, opts *AgentParams) error {
 	lb := listener.loadbalancer
 	data := b.genHaproxyConfigCommon(lb, listener, opts)
+	b.genHaproxyConfigConnLimit(data, listener)
 	if listener.BackendGroupId != "" {
 		backendGroup := lb.backendGroups[listener.BackendGroupId]
 		backendData := map[string]interface{}{
@@ -559,12 +659,19 @@ func (b *LoadbalancerCorpus) genHaproxyConfigTcp(buf *bytes.Buffer, listener *Lo
 var haproxyConfigTmpl = template.Must(template.New("").Parse(`
 {{ define "tcpListen" -}}
 # {{ .listener_type }} listener: {{ .comment }}
+{{- range .dummy_backends }}
+backend {{ .id }}
+	{{ println .stick_table }}
+{{- end }}
 listen {{ .id }}
 	bind {{ .bind }}
 	mode tcp
 	{{- println }}
+	{{- if .maxconn }}	maxconn {{ println .maxconn }} {{- end}}
 	{{- if .log }}	{{ println "option tcplog" }} {{- end }}
 	{{- if .acl }}	{{ println .acl }} {{- end}}
+	{{- if .conn_track }}	{{ println .conn_track }} {{- end}}
+	{{- range .conn_rules }}	{{ println . }} {{- end }}
 	{{- if .client_idle_timeout }}	timeout client {{ println .client_idle_timeout }} {{- end}}
 	default_backend {{ .backend.id }}
 {{ template "backend" .backend }}
@@ -580,8 +687,12 @@ frontend {{ .id }}
 	bind {{ .bind }}
 	mode http
 	{{- println }}
+	{{- if .maxconn }}	maxconn {{ println .maxconn }} {{- end}}
 	{{- if .log }}	{{ println "option httplog clf" }} {{- end }}
+	{{- if .conn_track }}	{{ println .conn_track }} {{- end}}
+	{{- range .acme_challenges }}	{{ println . }} {{- end }}
 	{{- if .acl }}	{{ println .acl }} {{- end}}
+	{{- range .conn_rules }}	{{ println . }} {{- end }}
 	{{- if .client_request_timeout }}	timeout http-request {{ println .client_request_timeout }} {{- end}}
 	{{- if .client_idle_timeout }}	timeout http-keep-alive {{ println .client_idle_timeout }} {{- end}}
 	{{- if .xforwardedfor }}	{{ println "option forwardfor" }} {{- end}}
@@ -610,6 +721,7 @@ backend {{ .id }}
 	{{- if .stickyCookie }}	{{ println .stickyCookie }} {{- end }}
 	{{- if .httpCheck }}	{{ println .httpCheck }} {{- end }}
 	{{- if .httpCheckExpect }}	{{ println .httpCheckExpect }} {{- end }}
+	{{- range .grpcCheck }}	{{ println . }} {{- end }}
 	{{- range .servers }}	{{ println . }} {{- end }}
 {{- end }}
 `))
@@ -15,9 +15,15 @@
 package utils
 
 import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
 	"fmt"
 	"strings"
 
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+
 	"yunion.io/x/onecloud/pkg/apis/compute"
 )
 
@@ -86,6 +92,19 @@ func HaproxyConfigHttpCheckExpect(s string) string {
 	return s
 }
 
+// HaproxyConfigHttpDeny returns the http-request deny action answering with
+// the specified status code, plus a plain text body if provided
+func HaproxyConfigHttpDeny(status int, body string) string {
+	if status <= 0 {
+		status = int(compute.LB_RATE_LIMIT_STATUS_429)
+	}
+	s := fmt.Sprintf("http-request deny deny_status %d", status)
+	if body != "" {
+		s += fmt.Sprintf(" content-type text/plain string %q", body)
+	}
+	return s
+}
+
 func HaproxySendProxy(s string) (r string, err error) {
 	switch s {
 	case compute.LB_SENDPROXY_OFF, "":
@@ -102,3 +121,90 @@ func HaproxySendProxy(s string) (r string, err error) {
 	}
 	return
 }
+
+const (
+	grpcHealthCheckPath = "/grpc.health.v1.Health/Check"
+)
+
+// grpcHealthServing is the length-prefixed grpc message of
+// grpc.health.v1.HealthCheckResponse{status: SERVING}
+var grpcHealthServing = []byte{0x00, 0x00, 0x00, 0x00, 0x02, 0x08, 0x01}
+
+// GrpcHealthCheckRequest returns bytes of a complete HTTP/2 connection
+// preface followed by a grpc.health.v1.Health/Check call with prior
+// knowledge, in a single stream
+func GrpcHealthCheckRequest(service, authority string) ([]byte, error) {
+	if authority == "" {
+		authority = "localhost"
+	}
+	var (
+		buf     = &bytes.Buffer{}
+		hdrBuf  = &bytes.Buffer{}
+		framer  = http2.NewFramer(buf, nil)
+		encoder = hpack.NewEncoder(hdrBuf)
+		headers = [][2]string{
+			{":method", "POST"},
+			{":scheme", "http"},
+			{":path", grpcHealthCheckPath},
+			{":authority", authority},
+			{"content-type", "application/grpc"},
+			{"te", "trailers"},
+		}
+	)
+	buf.WriteString(http2.ClientPreface)
+	if err := framer.WriteSettings(); err != nil {
+		return nil, err
+	}
+	for _, hdr := range headers {
+		if err := encoder.WriteField(hpack.HeaderField{Name: hdr[0], Value: hdr[1]}); err != nil {
+			return nil, err
+		}
+	}
+	if err := framer.WriteHeaders(http2.HeadersFrameParam{
+		StreamID:      1,
+		BlockFragment: hdrBuf.Bytes(),
+		EndHeaders:    true,
+	}); err != nil {
+		return nil, err
+	}
+
+	// HealthCheckRequest{service: service}
+	msg := []byte{}
+	if service != "" {
+		msg = append(msg, 0x0a)
+		msg = appendVarint(msg, uint64(len(service)))
+		msg = append(msg, service...)
+	}
+	data := make([]byte, 5, 5+len(msg))
+	binary.BigEndian.PutUint32(data[1:], uint32(len(msg)))
+	data = append(data, msg...)
+	if err := framer.WriteData(1, true, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func appendVarint(b []byte, v uint64) []byte {
+	for v >= 0x80 {
+		b = append(b, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(b, byte(v))
+}
+
+// HaproxyConfigGrpcCheck returns backend lines checking servers with
+// grpc.health.v1.Health/Check.  Haproxy has no native support for it, so
+// tcp-check is used to send the prebuilt HTTP/2 request and look for
+// SERVING status in the response
+func HaproxyConfigGrpcCheck(service, authority string) ([]string, error) {
+	req, err := GrpcHealthCheckRequest(service, authority)
+	if err != nil {
+		return nil, err
+	}
+	lines := []string{
+		"option tcp-check",
+		fmt.Sprintf("tcp-check send-binary %s", hex.EncodeToString(req)),
+		fmt.Sprintf("tcp-check expect binary %s", hex.EncodeToString(grpcHealthServing)),
+	}
+	return lines, nil
+}
@@ -0,0 +1,177 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+// fakeGrpcHealthHandler mimics grpc.health.v1.Health/Check of a grpc server
+func fakeGrpcHealthHandler(t *testing.T, serving map[string]bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != grpcHealthCheckPath {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/grpc" {
+			t.Errorf("unexpected content-type %q", ct)
+		}
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("read body: %v", err)
+			return
+		}
+		if len(body) < 5 || int(binary.BigEndian.Uint32(body[1:5])) != len(body)-5 {
+			t.Errorf("bad grpc message %x", body)
+			return
+		}
+		service := ""
+		if msg := body[5:]; len(msg) > 0 {
+			if msg[0] != 0x0a || int(msg[1]) != len(msg)-2 {
+				t.Errorf("bad HealthCheckRequest %x", msg)
+				return
+			}
+			service = string(msg[2:])
+		}
+		status := byte(2) // NOT_SERVING
+		if serving[service] {
+			status = 1
+		}
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Trailer", "Grpc-Status")
+		w.Write([]byte{0x00, 0x00, 0x00, 0x00, 0x02, 0x08, status})
+		w.Header().Set("Grpc-Status", "0")
+	}
+}
+
+func TestGrpcHealthCheckRequest(t *testing.T) {
+	serving := map[string]bool{
+		"":              true,
+		"hello.Greeter": true,
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		srv := &http2.Server{}
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go srv.ServeConn(conn, &http2.ServeConnOpts{
+				Handler: fakeGrpcHealthHandler(t, serving),
+			})
+		}
+	}()
+
+	cases := []struct {
+		service string
+		want    bool
+	}{
+		{"", true},
+		{"hello.Greeter", true},
+		{"hello.Sleeper", false},
+	}
+	for _, c := range cases {
+		req, err := GrpcHealthCheckRequest(c.service, "")
+		if err != nil {
+			t.Fatalf("service %q: build request: %v", c.service, err)
+		}
+		conn, err := net.Dial("tcp", ln.Addr().String())
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		if _, err := conn.Write(req); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		// like tcp-check expect, read until the pattern shows up or timeout
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		resp := []byte{}
+		buf := make([]byte, 4096)
+		got := false
+		for !got {
+			n, err := conn.Read(buf)
+			resp = append(resp, buf[:n]...)
+			got = bytes.Contains(resp, grpcHealthServing)
+			if err != nil {
+				break
+			}
+		}
+		conn.Close()
+		if got != c.want {
+			t.Errorf("service %q: want serving %v, got %v, response %x", c.service, c.want, got, resp)
+		}
+	}
+}
+
+func TestHaproxyConfigGrpcCheck(t *testing.T) {
+	lines, err := HaproxyConfigGrpcCheck("hello.Greeter", "grpc.example.com")
+	if err != nil {
+		t.Fatalf("HaproxyConfigGrpcCheck: %v", err)
+	}
+	want := []string{
+		"option tcp-check",
+		"tcp-check send-binary 505249202a20485454502f322e300d0a0d0a534d0d0a0d0a",
+		"tcp-check expect binary 00000000020801",
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("want %d lines, got %d: %v", len(want), len(lines), lines)
+	}
+	for i := range want {
+		if !strings.HasPrefix(lines[i], want[i]) {
+			t.Errorf("line %d: want prefix %q, got %q", i, want[i], lines[i])
+		}
+	}
+}
+
+func TestHaproxyConfigHttpDeny(t *testing.T) {
+	cases := []struct {
+		status int
+		body   string
+		want   string
+	}{
+		{
+			want: "http-request deny deny_status 429",
+		},
+		{
+			status: 503,
+			want:   "http-request deny deny_status 503",
+		},
+		{
+			status: 429,
+			body:   "slow down please",
+			want:   `http-request deny deny_status 429 content-type text/plain string "slow down please"`,
+		},
+	}
+	for _, c := range cases {
+		got := HaproxyConfigHttpDeny(c.status, c.body)
+		if got != c.want {
+			t.Errorf("status %d body %q: want %q, got %q", c.status, c.body, c.want, got)
+		}
+	}
+}
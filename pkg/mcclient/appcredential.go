@@ -0,0 +1,52 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mcclient
+
+import (
+	api "yunion.io/x/onecloud/pkg/apis/identity"
+	"yunion.io/x/onecloud/pkg/httperrors"
+)
+
+// AuthenticateAppCredential authenticates with an application credential,
+// the token is always scoped to the project of the credential
+func (this *Client) AuthenticateAppCredential(credId, secret string, source string) (TokenCredential, error) {
+	aCtx := SAuthContext{
+		Source: source,
+	}
+	return this.authenticateAppCredentialWithContext(credId, "", "", "", secret, aCtx)
+}
+
+// AuthenticateAppCredentialByName authenticates with the name of an application credential of a user
+func (this *Client) AuthenticateAppCredentialByName(credName, uname, domainName, secret string, source string) (TokenCredential, error) {
+	aCtx := SAuthContext{
+		Source: source,
+	}
+	return this.authenticateAppCredentialWithContext("", credName, uname, domainName, secret, aCtx)
+}
+
+func (this *Client) authenticateAppCredentialWithContext(credId, credName, uname, domainName, secret string, aCtx SAuthContext) (TokenCredential, error) {
+	if this.AuthVersion() != "v3" {
+		return nil, httperrors.ErrNotSupported
+	}
+	input := SAuthenticationInputV3{}
+	input.Auth.Identity.Methods = []string{api.AUTH_METHOD_APP_CRED}
+	input.Auth.Identity.AppCredential.Id = credId
+	input.Auth.Identity.AppCredential.Name = credName
+	input.Auth.Identity.AppCredential.Secret = secret
+	input.Auth.Identity.AppCredential.User.Name = uname
+	input.Auth.Identity.AppCredential.User.Domain.Name = domainName
+	input.Auth.Context = aCtx
+	return this._authV3Input(input)
+}
@@ -23,6 +23,7 @@ import (
 	api "yunion.io/x/onecloud/pkg/apis/identity"
 	"yunion.io/x/onecloud/pkg/appctx"
 	"yunion.io/x/onecloud/pkg/appsrv"
+	"yunion.io/x/onecloud/pkg/cloudcommon/consts"
 	"yunion.io/x/onecloud/pkg/httperrors"
 	"yunion.io/x/onecloud/pkg/mcclient"
 	"yunion.io/x/onecloud/pkg/util/rbacutils"
@@ -72,6 +73,13 @@ func AuthenticateWithDelayDecision(f appsrv.FilterHandler, delayDecision bool) a
 				token = &GuestToken
 			}
 		}
+		// token issued by an application credential is limited by its access rules
+		appCred := mcclient.GetAppCredential(token)
+		if len(appCred.Id) > 0 && !appCred.IsAccessAllowed(consts.GetServiceType(), r.Method, r.URL.Path) {
+			log.Errorf("%s %s %s denied by access rules of application credential %s", consts.GetServiceType(), r.Method, r.URL.Path, appCred.Id)
+			httperrors.ForbiddenError(ctx, w, "access denied by application credential %s", appCred.Name)
+			return
+		}
 		ctx = context.WithValue(ctx, appctx.APP_CONTEXT_KEY_AUTH_TOKEN, token)
 
 		if taskId := r.Header.Get(mcclient.TASK_ID); taskId != "" {
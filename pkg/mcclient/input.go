@@ -83,6 +83,8 @@ type SAuthenticationIdentity struct {
 	// | saml     | 作为SAML 2.0 SP通过IDP认证                                            |
 	// | oidc     | 作为OpenID Connect/OAuth2 Client认证                                 |
 	// | oauth2   | OAuth2认证                                                          |
+	// | webauthn | 通过WebAuthn/FIDO2安全密钥无密码认证                                   |
+	// | app_credential | 应用凭证认证，获得的token只有凭证限定的角色和访问规则                  |
 	//
 	Methods []string `json:"methods,omitempty"`
 	// 当认证方式为password时，通过该字段提供密码认证信息
@@ -128,6 +130,34 @@ type SAuthenticationIdentity struct {
 	OAuth2 struct {
 		Code string `json:"code,omitempty"`
 	}
+	// 当认证方式为webauthn时，通过该字段提供浏览器navigator.credentials.get()返回的断言
+	WebauthnAuth struct {
+		// PublicKeyCredential序列化后的JSON
+		Assertion string `json:"assertion,omitempty"`
+	} `json:"webauthn_auth,omitempty"`
+	// 当认证方式为app_credential时，通过该字段提供应用凭证信息
+	AppCredential struct {
+		// 应用凭证ID，ID和Name只需要指定其中一个
+		Id string `json:"id,omitempty"`
+		// 应用凭证名称，指定Name时，需要指定凭证所属用户
+		Name string `json:"name,omitempty"`
+		// 应用凭证密钥
+		Secret string `json:"secret,omitempty"`
+		// 应用凭证所属用户
+		User struct {
+			// 用户ID
+			Id string `json:"id,omitempty"`
+			// 用户名称
+			Name string `json:"name,omitempty"`
+			// 用户所属域的信息
+			Domain struct {
+				// 域ID
+				Id string `json:"id,omitempty"`
+				// 域名称
+				Name string `json:"name,omitempty"`
+			} `json:"domain,omitempty"`
+		} `json:"user,omitempty"`
+	} `json:"application_credential,omitempty"`
 }
 
 type SAuthenticationInputV3 struct {
@@ -35,6 +35,7 @@ import (
 	"yunion.io/x/onecloud/pkg/util/httputils"
 	"yunion.io/x/onecloud/pkg/util/rbacutils"
 	"yunion.io/x/onecloud/pkg/util/seclib2"
+	"yunion.io/x/onecloud/pkg/util/tracing"
 )
 
 var listenerWorker *appsrv.SWorkerManager
@@ -152,12 +153,50 @@ func joinUrl(baseUrl, path string) string {
 	return fmt.Sprintf("%s%s", baseUrl, path)
 }
 
+// startRequestSpan starts a client span for the request, the trace context
+// of the span is propagated by httputils through the returned context
+func startRequestSpan(ctx context.Context, method httputils.THttpMethod, reqUrl string) (context.Context, *tracing.Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, span := tracing.StartSpan(ctx, string(method), tracing.SPAN_KIND_CLIENT)
+	span.SetAttribute("http.method", string(method))
+	span.SetAttribute("http.url", reqUrl)
+	return ctx, span
+}
+
+func endRequestSpan(span *tracing.Span, status int, err error) {
+	if status > 0 {
+		span.SetAttribute("http.status_code", status)
+	}
+	span.RecordError(err)
+	span.End()
+}
+
 func (this *Client) rawRequest(ctx context.Context, endpoint string, token string, method httputils.THttpMethod, url string, header http.Header, body io.Reader) (*http.Response, error) {
-	return httputils.Request(this.httpconn, ctx, method, joinUrl(endpoint, url), getDefaultHeader(header, token), body, this.debug)
+	reqUrl := joinUrl(endpoint, url)
+	ctx, span := startRequestSpan(ctx, method, reqUrl)
+	resp, err := httputils.Request(this.httpconn, ctx, method, reqUrl, getDefaultHeader(header, token), body, this.debug)
+	status := 0
+	if resp != nil {
+		status = resp.StatusCode
+	}
+	endRequestSpan(span, status, err)
+	return resp, err
 }
 
 func (this *Client) jsonRequest(ctx context.Context, endpoint string, token string, method httputils.THttpMethod, url string, header http.Header, body jsonutils.JSONObject) (http.Header, jsonutils.JSONObject, error) {
-	return httputils.JSONRequest(this.httpconn, ctx, method, joinUrl(endpoint, url), getDefaultHeader(header, token), body, this.debug)
+	reqUrl := joinUrl(endpoint, url)
+	ctx, span := startRequestSpan(ctx, method, reqUrl)
+	hdr, ret, err := httputils.JSONRequest(this.httpconn, ctx, method, reqUrl, getDefaultHeader(header, token), body, this.debug)
+	status := 0
+	if err == nil {
+		status = http.StatusOK
+	} else if jce, ok := err.(*httputils.JSONClientError); ok {
+		status = jce.Code
+	}
+	endRequestSpan(span, status, err)
+	return hdr, ret, err
 }
 
 func (this *Client) _authV3(domainName, uname, passwd, projectId, projectName, projectDomain, token string, aCtx SAuthContext) (TokenCredential, error) {
@@ -49,6 +49,8 @@ type LoadbalancerHTTPListener struct {
 
 	XForwardedFor bool
 	Gzip          bool
+
+	EnableBackendHttp2 bool
 }
 
 // CACertificate string
@@ -61,6 +63,14 @@ type LoadbalancerHTTPSListener struct {
 type LoadbalancerHTTPRateLimiter struct {
 	HTTPRequestRate       int
 	HTTPRequestRatePerSrc int
+
+	HTTPRateLimitStatus int
+	HTTPRateLimitBody   string
+}
+
+type LoadbalancerConnLimiter struct {
+	MaxConn       int
+	MaxConnPerSrc int
 }
 
 type LoadbalancerHTTPRedirect struct {
@@ -112,11 +122,14 @@ type LoadbalancerListener struct {
 	HealthCheckReq string
 	HealthCheckExp string
 
+	HealthCheckGrpcService string
+
 	LoadbalancerTCPListener
 	LoadbalancerUDPListener
 	LoadbalancerHTTPListener
 	LoadbalancerHTTPSListener
 
+	LoadbalancerConnLimiter
 	LoadbalancerHTTPRateLimiter
 	LoadbalancerHTTPRedirect
 }
@@ -179,6 +192,11 @@ type LoadbalancerAcl struct {
 	CloudregionId string
 }
 
+type LoadbalancerAcmeHttpChallenge struct {
+	Token            string
+	KeyAuthorization string
+}
+type LoadbalancerAcmeHttpChallenges []*LoadbalancerAcmeHttpChallenge
 type LoadbalancerCertificate struct {
 	VirtualResource
 	ManagedResource
@@ -187,6 +205,9 @@ type LoadbalancerCertificate struct {
 	Certificate string
 	PrivateKey  string
 
+	Source             string
+	AcmeHttpChallenges *LoadbalancerAcmeHttpChallenges
+
 	CloudregionId           string
 	PublicKeyAlgorithm      string
 	PublicKeyBitLen         int
@@ -42,11 +42,13 @@ type Server struct {
 	HostId       string
 	BackupHostId string
 
-	Vga     string
-	Vdi     string
-	Machine string
-	Bios    string
-	OsType  string
+	Vga        string
+	Vdi        string
+	Machine    string
+	Bios       string
+	SecureBoot bool
+	Vtpm       bool
+	OsType     string
 
 	FlavorId string
 
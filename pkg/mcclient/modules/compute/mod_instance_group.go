@@ -26,7 +26,7 @@ var (
 func init() {
 	InstanceGroups = modules.NewComputeManager("instancegroup", "instancegroups",
 		[]string{"ID", "Name", "Service_Type", "Parent_Id", "Zone_Id", "Sched_Strategy", "Domain_Id", "Project_Id",
-			"Granularity", "Is_Froced_Sep", "ips", "eip"},
+			"Granularity", "Is_Froced_Sep", "Topology_Spread_Constraints", "ips", "eip"},
 		[]string{})
 
 	modules.RegisterCompute(&InstanceGroups)
@@ -28,6 +28,7 @@ func init() {
 		[]string{
 			"ID", "Name", "Description", "Condition", "Schedtag",
 			"Resource_Type", "Schedtag_Id", "Strategy", "Enabled",
+			"Cost_Weight",
 		},
 		[]string{})
 
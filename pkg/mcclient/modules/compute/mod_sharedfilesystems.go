@@ -0,0 +1,45 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"yunion.io/x/onecloud/pkg/mcclient/modulebase"
+	"yunion.io/x/onecloud/pkg/mcclient/modules"
+)
+
+var (
+	SharedFilesystems modulebase.ResourceManager
+)
+
+func init() {
+	SharedFilesystems = modules.NewComputeManager("sharedfilesystem", "sharedfilesystems",
+		[]string{
+			"ID",
+			"guest_id",
+			"guest",
+			"tag",
+			"source_type",
+			"host_path",
+			"storage_id",
+			"storage",
+			"sub_path",
+			"mode",
+			"mountpoint",
+			"size_mb",
+		},
+		[]string{})
+
+	modules.RegisterCompute(&SharedFilesystems)
+}
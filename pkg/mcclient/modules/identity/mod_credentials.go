@@ -15,7 +15,13 @@
 package identity
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
 	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"net/url"
 	"strings"
@@ -31,6 +37,7 @@ import (
 	"yunion.io/x/onecloud/pkg/mcclient/modulebase"
 	"yunion.io/x/onecloud/pkg/mcclient/modules"
 	"yunion.io/x/onecloud/pkg/util/seclib2"
+	"yunion.io/x/onecloud/pkg/util/webauthn"
 )
 
 type SCredentialManager struct {
@@ -45,6 +52,11 @@ const (
 	RECOVERY_SECRETS_TYPE = api.RECOVERY_SECRETS_TYPE
 	OIDC_CREDENTIAL_TYPE  = api.OIDC_CREDENTIAL_TYPE
 	ENCRYPT_KEY_TYPE      = api.ENCRYPT_KEY_TYPE
+	WEBAUTHN_TYPE         = api.WEBAUTHN_TYPE
+	RECOVERY_CODES_TYPE   = api.RECOVERY_CODES_TYPE
+	APP_CREDENTIAL_TYPE   = api.APP_CREDENTIAL_TYPE
+
+	RECOVERY_CODES_COUNT = 10
 )
 
 type STotpSecret struct {
@@ -76,6 +88,13 @@ type SOpenIDConnectCredential struct {
 	api.SAccessKeySecretBlob
 }
 
+type SWebauthnCredential struct {
+	Id        string    `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	webauthn.SCredential
+}
+
 type SEncryptKeySecret struct {
 	KeyId     string             `json:"-"`
 	KeyName   string             `json:"-"`
@@ -170,6 +189,14 @@ func (manager *SCredentialManager) FetchEncryptionKeys(s *mcclient.ClientSession
 	return manager.fetchCredentials(s, ENCRYPT_KEY_TYPE, uid, "")
 }
 
+func (manager *SCredentialManager) FetchWebauthnCredentials(s *mcclient.ClientSession, uid string) ([]jsonutils.JSONObject, error) {
+	return manager.fetchCredentials(s, WEBAUTHN_TYPE, uid, "")
+}
+
+func (manager *SCredentialManager) FetchRecoveryCodes(s *mcclient.ClientSession, uid string) ([]jsonutils.JSONObject, error) {
+	return manager.fetchCredentials(s, RECOVERY_CODES_TYPE, uid, "")
+}
+
 func (manager *SCredentialManager) GetTotpSecret(s *mcclient.ClientSession, uid string) (string, error) {
 	secrets, err := manager.FetchTotpSecrets(s, uid)
 	if err != nil {
@@ -220,6 +247,42 @@ func (manager *SCredentialManager) GetRecoverySecrets(s *mcclient.ClientSession,
 	return latestQ.Questions, nil
 }
 
+func DecodeWebauthnCredential(secret jsonutils.JSONObject) (SWebauthnCredential, error) {
+	curr := SWebauthnCredential{}
+	blobStr, err := secret.GetString("blob")
+	if err != nil {
+		return curr, errors.Wrap(err, "secret.GetString blob")
+	}
+	err = json.Unmarshal([]byte(blobStr), &curr.SCredential)
+	if err != nil {
+		return curr, errors.Wrap(err, "json.Unmarshal")
+	}
+	curr.Id, _ = secret.GetString("id")
+	curr.Name, _ = secret.GetString("name")
+	curr.CreatedAt, _ = secret.GetTime("created_at")
+	return curr, nil
+}
+
+// GetWebauthnCredentials returns the enabled webauthn credentials of user
+func (manager *SCredentialManager) GetWebauthnCredentials(s *mcclient.ClientSession, uid string) ([]SWebauthnCredential, error) {
+	secrets, err := manager.FetchWebauthnCredentials(s, uid)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]SWebauthnCredential, 0, len(secrets))
+	for i := range secrets {
+		if !jsonutils.QueryBoolean(secrets[i], "enabled", false) {
+			continue
+		}
+		cred, err := DecodeWebauthnCredential(secrets[i])
+		if err != nil {
+			return nil, errors.Wrap(err, "DecodeWebauthnCredential")
+		}
+		ret = append(ret, cred)
+	}
+	return ret, nil
+}
+
 func DecodeAccessKeySecret(secret jsonutils.JSONObject) (SAccessKeySecret, error) {
 	curr := SAccessKeySecret{}
 	blobStr, err := secret.GetString("blob")
@@ -414,6 +477,51 @@ func (manager *SCredentialManager) CreateAccessKeySecret(s *mcclient.ClientSessi
 	return aksk, nil
 }
 
+type SAppCredential struct {
+	Id        string    `json:"id"`
+	Name      string    `json:"name"`
+	ProjectId string    `json:"project_id"`
+	TimeStamp time.Time `json:"created_at"`
+	api.SAppCredentialBlob
+}
+
+// CreateAppCredential creates an application credential restricted to roles of
+// the user in a project, empty roles delegate all current roles of the user
+func (manager *SCredentialManager) CreateAppCredential(s *mcclient.ClientSession, uid string, pid string, name string, roles []string, expireAt time.Time, rules []api.SAppCredentialAccessRule) (SAppCredential, error) {
+	appCred := SAppCredential{}
+	appCred.Secret = base64.URLEncoding.EncodeToString([]byte(seclib.RandomPassword(32)))
+	appCred.Roles = roles
+	if !expireAt.IsZero() {
+		appCred.Expire = expireAt.Unix()
+	}
+	appCred.AccessRules = rules
+	blobJson := jsonutils.Marshal(&appCred.SAppCredentialBlob)
+	params := jsonutils.NewDict()
+	if len(pid) > 0 {
+		params.Add(jsonutils.NewString(pid), "project_id")
+	}
+	params.Add(jsonutils.NewString(APP_CREDENTIAL_TYPE), "type")
+	if len(uid) > 0 {
+		params.Add(jsonutils.NewString(uid), "user_id")
+	}
+	params.Add(jsonutils.NewString(blobJson.String()), "blob")
+	params.Add(jsonutils.NewString(name), "name")
+	result, err := manager.Create(s, params)
+	if err != nil {
+		return appCred, err
+	}
+	appCred.Id, _ = result.GetString("id")
+	appCred.Name, _ = result.GetString("name")
+	appCred.ProjectId, _ = result.GetString("project_id")
+	appCred.TimeStamp, _ = result.GetTime("created_at")
+	// roles are normalized to ids by keystone
+	blob, _ := result.GetString("blob")
+	if len(blob) > 0 {
+		json.Unmarshal([]byte(blob), &appCred.SAppCredentialBlob)
+	}
+	return appCred, nil
+}
+
 func (manager *SCredentialManager) DoCreateOidcSecret(s *mcclient.ClientSession, params jsonutils.JSONObject) (jsonutils.JSONObject, error) {
 	redirectUri, _ := params.GetString("redirect_uri")
 
@@ -512,6 +620,134 @@ func (manager *SCredentialManager) SaveRecoverySecrets(s *mcclient.ClientSession
 	return nil
 }
 
+func (manager *SCredentialManager) CreateWebauthnCredential(s *mcclient.ClientSession, uid string, name string, cred *webauthn.SCredential) (SWebauthnCredential, error) {
+	ret := SWebauthnCredential{SCredential: *cred}
+	blob, err := json.Marshal(cred)
+	if err != nil {
+		return ret, errors.Wrap(err, "json.Marshal")
+	}
+	params := jsonutils.NewDict()
+	params.Add(jsonutils.NewString(DEFAULT_PROJECT), "project_id")
+	params.Add(jsonutils.NewString(WEBAUTHN_TYPE), "type")
+	params.Add(jsonutils.NewString(uid), "user_id")
+	params.Add(jsonutils.NewString(string(blob)), "blob")
+	params.Add(jsonutils.NewString(name), "generate_name")
+	result, err := manager.Create(s, params)
+	if err != nil {
+		return ret, errors.Wrap(err, "Create")
+	}
+	ret.Id, _ = result.GetString("id")
+	ret.Name, _ = result.GetString("name")
+	ret.CreatedAt, _ = result.GetTime("created_at")
+	return ret, nil
+}
+
+// UpdateWebauthnCredential saves the signature counter of the credential
+func (manager *SCredentialManager) UpdateWebauthnCredential(s *mcclient.ClientSession, id string, cred *webauthn.SCredential) error {
+	blob, err := json.Marshal(cred)
+	if err != nil {
+		return errors.Wrap(err, "json.Marshal")
+	}
+	params := jsonutils.NewDict()
+	params.Add(jsonutils.NewString(string(blob)), "blob")
+	_, err = manager.Update(s, id, params)
+	return err
+}
+
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.Replace(code, "-", "", -1)
+	code = strings.Replace(code, " ", "", -1)
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+func newRecoveryCode() (string, error) {
+	b := make([]byte, 10)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	code := strings.ToLower(base32.StdEncoding.EncodeToString(b))
+	return code[:8] + "-" + code[8:], nil
+}
+
+// CreateRecoveryCodes replaces the recovery codes of user, only the hashes
+// of the codes are kept and the plain codes are returned to show them once
+func (manager *SCredentialManager) CreateRecoveryCodes(s *mcclient.ClientSession, uid string) ([]string, error) {
+	err := manager.RemoveRecoveryCodes(s, uid)
+	if err != nil {
+		return nil, errors.Wrap(err, "RemoveRecoveryCodes")
+	}
+	codes := make([]string, RECOVERY_CODES_COUNT)
+	blob := api.SRecoveryCodesBlob{}
+	for i := range codes {
+		codes[i], err = newRecoveryCode()
+		if err != nil {
+			return nil, errors.Wrap(err, "newRecoveryCode")
+		}
+		blob.Codes = append(blob.Codes, hashRecoveryCode(codes[i]))
+	}
+	params := jsonutils.NewDict()
+	params.Add(jsonutils.NewString(DEFAULT_PROJECT), "project_id")
+	params.Add(jsonutils.NewString(RECOVERY_CODES_TYPE), "type")
+	params.Add(jsonutils.NewString(uid), "user_id")
+	params.Add(jsonutils.NewString(jsonutils.Marshal(&blob).String()), "blob")
+	_, err = manager.Create(s, params)
+	if err != nil {
+		return nil, errors.Wrap(err, "Create")
+	}
+	return codes, nil
+}
+
+// GetRecoveryCodesCount returns the number of unused recovery codes
+func (manager *SCredentialManager) GetRecoveryCodesCount(s *mcclient.ClientSession, uid string) (int, error) {
+	secrets, err := manager.FetchRecoveryCodes(s, uid)
+	if err != nil {
+		return 0, err
+	}
+	cnt := 0
+	for i := range secrets {
+		blobStr, _ := secrets[i].GetString("blob")
+		blob := api.SRecoveryCodesBlob{}
+		if json.Unmarshal([]byte(blobStr), &blob) == nil {
+			cnt += len(blob.Codes)
+		}
+	}
+	return cnt, nil
+}
+
+// UseRecoveryCode consumes a recovery code of user
+func (manager *SCredentialManager) UseRecoveryCode(s *mcclient.ClientSession, uid string, code string) error {
+	secrets, err := manager.FetchRecoveryCodes(s, uid)
+	if err != nil {
+		return err
+	}
+	hash := hashRecoveryCode(code)
+	for i := range secrets {
+		blobStr, _ := secrets[i].GetString("blob")
+		blob := api.SRecoveryCodesBlob{}
+		if json.Unmarshal([]byte(blobStr), &blob) != nil {
+			continue
+		}
+		for j := range blob.Codes {
+			if subtle.ConstantTimeCompare([]byte(blob.Codes[j]), []byte(hash)) != 1 {
+				continue
+			}
+			blob.Codes = append(blob.Codes[:j], blob.Codes[j+1:]...)
+			if blob.Codes == nil {
+				blob.Codes = []string{}
+			}
+			sid, _ := secrets[i].GetString("id")
+			params := jsonutils.NewDict()
+			params.Add(jsonutils.NewString(jsonutils.Marshal(&blob).String()), "blob")
+			_, err = manager.Update(s, sid, params)
+			return err
+		}
+	}
+	return httperrors.NewForbiddenError("invalid recovery code")
+}
+
 func (manager *SCredentialManager) DoCreateEncryptKey(s *mcclient.ClientSession, params jsonutils.JSONObject) (jsonutils.JSONObject, error) {
 	name, _ := params.GetString("name")
 	alg, _ := params.GetString("alg")
@@ -579,6 +815,14 @@ func (manager *SCredentialManager) RemoveRecoverySecrets(s *mcclient.ClientSessi
 	return manager.removeCredentials(s, RECOVERY_SECRETS_TYPE, uid, "")
 }
 
+func (manager *SCredentialManager) RemoveWebauthnCredentials(s *mcclient.ClientSession, uid string) error {
+	return manager.removeCredentials(s, WEBAUTHN_TYPE, uid, "")
+}
+
+func (manager *SCredentialManager) RemoveRecoveryCodes(s *mcclient.ClientSession, uid string) error {
+	return manager.removeCredentials(s, RECOVERY_CODES_TYPE, uid, "")
+}
+
 func (manager *SCredentialManager) RemoveOIDCSecrets(s *mcclient.ClientSession, uid string, pid string) error {
 	return manager.removeCredentials(s, OIDC_CREDENTIAL_TYPE, uid, pid)
 }
@@ -15,8 +15,15 @@
 package image
 
 import (
+	"fmt"
+	"io"
+
+	"yunion.io/x/jsonutils"
+
+	"yunion.io/x/onecloud/pkg/mcclient"
 	"yunion.io/x/onecloud/pkg/mcclient/modulebase"
 	"yunion.io/x/onecloud/pkg/mcclient/modules"
+	"yunion.io/x/onecloud/pkg/util/httputils"
 )
 
 var GuestImages modulebase.ResourceManager
@@ -27,3 +34,22 @@ func init() {
 		[]string{})
 	modules.Register(&GuestImages)
 }
+
+// UploadGuestImage creates guest image from the uploaded ova package
+func UploadGuestImage(s *mcclient.ClientSession, params jsonutils.JSONObject, body io.Reader, size int64) (jsonutils.JSONObject, error) {
+	headers, err := setImageMeta(params)
+	if err != nil {
+		return nil, err
+	}
+	headers.Set("Content-Type", "application/octet-stream")
+	if size > 0 {
+		headers.Set("Content-Length", fmt.Sprintf("%d", size))
+	}
+	path := fmt.Sprintf("/%s", GuestImages.URLPath())
+	resp, err := modulebase.RawRequest(GuestImages, s, httputils.POST, path, headers, body)
+	_, json, err := s.ParseJSONResponse("", resp, err)
+	if err != nil {
+		return nil, err
+	}
+	return json.Get("guestimage")
+}
@@ -16,6 +16,7 @@ package webconsole
 
 import (
 	"fmt"
+	"io"
 
 	"yunion.io/x/jsonutils"
 	"yunion.io/x/pkg/errors"
@@ -26,6 +27,7 @@ import (
 	"yunion.io/x/onecloud/pkg/mcclient/auth"
 	"yunion.io/x/onecloud/pkg/mcclient/modulebase"
 	"yunion.io/x/onecloud/pkg/mcclient/modules/k8s"
+	"yunion.io/x/onecloud/pkg/util/httputils"
 )
 
 var (
@@ -147,3 +149,32 @@ func (m WebConsoleManager) DoSshConnect(s *mcclient.ClientSession, id string, pa
 func (m WebConsoleManager) DoServerConnect(s *mcclient.ClientSession, id string, params jsonutils.JSONObject) (jsonutils.JSONObject, error) {
 	return m.DoConnect(s, "server", id, "", params)
 }
+
+func (m WebConsoleManager) ListRecordings(s *mcclient.ClientSession, params jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	url := "/webconsole/recordings"
+	if params != nil {
+		if qs := params.QueryString(); len(qs) > 0 {
+			url = fmt.Sprintf("%s?%s", url, qs)
+		}
+	}
+	return modulebase.Get(m.ResourceManager, s, url, "webconsole")
+}
+
+func (m WebConsoleManager) GetRecording(s *mcclient.ClientSession, id string) (jsonutils.JSONObject, error) {
+	return modulebase.Get(m.ResourceManager, s, fmt.Sprintf("/webconsole/recordings/%s", id), "webconsole")
+}
+
+func (m WebConsoleManager) PlaybackRecording(s *mcclient.ClientSession, id string) (io.ReadCloser, error) {
+	resp, err := modulebase.RawRequest(m.ResourceManager, s, httputils.GET, fmt.Sprintf("/webconsole/recordings/%s/playback", id), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		_, body, err := httputils.ParseJSONResponse("", resp, err, false)
+		if err != nil {
+			return nil, err
+		}
+		return nil, errors.Errorf("playback recording %s: %s", id, body)
+	}
+	return resp.Body, nil
+}
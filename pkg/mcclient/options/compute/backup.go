@@ -111,10 +111,14 @@ func (opts *BackupStorageIdOptions) Params() (jsonutils.JSONObject, error) {
 
 type BackupStorageCreateOptions struct {
 	options.BaseCreateOptions
-	StorageType  string `help:"storage type" choices:"nfs"`
-	NfsHost      string `help:"nfs host, required when storage_type is nfs"`
-	NfsSharedDir string `help:"nfs shared dir, required when storage_type is nfs" `
-	CapacityMb   int    `help:"capacity, unit mb"`
+	StorageType     string `help:"storage type" choices:"nfs|object"`
+	NfsHost         string `help:"nfs host, required when storage_type is nfs"`
+	NfsSharedDir    string `help:"nfs shared dir, required when storage_type is nfs" `
+	ObjectBucketUrl string `help:"bucket url like http://minio:9000/bucket, required when storage_type is object"`
+	ObjectAccessKey string `help:"access key of object storage, required when storage_type is object"`
+	ObjectSecret    string `help:"secret of object storage, required when storage_type is object"`
+	ObjectSse       string `help:"server side encryption of object storage" choices:"AES256"`
+	CapacityMb      int    `help:"capacity, unit mb"`
 }
 
 func (opts *BackupStorageCreateOptions) Params() (jsonutils.JSONObject, error) {
@@ -19,6 +19,7 @@ import (
 	"yunion.io/x/pkg/errors"
 
 	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/cmdline"
 	"yunion.io/x/onecloud/pkg/mcclient/options"
 )
 
@@ -48,6 +49,20 @@ type InstanceGroupCreateOptions struct {
 	SchedStrategy   string `help:"scheduler strategy"`
 	Granularity     string `help:"the upper limit number of guests with this group in a host"`
 	ForceDispersion bool   `help:"force to make guest dispersion"`
+
+	TopologySpread []string `help:"Topology spread constraint, e.g. 'zone,max_skew=1', 'metadata:rack,max_per_domain=2'" json:"-"`
+}
+
+func parseTopologySpreadConstraints(descs []string) (api.TopologySpreadConstraints, error) {
+	ret := make(api.TopologySpreadConstraints, 0, len(descs))
+	for _, desc := range descs {
+		c, err := cmdline.ParseTopologySpreadConstraint(desc)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parse topology spread %q", desc)
+		}
+		ret = append(ret, *c)
+	}
+	return ret, nil
 }
 
 func (opts *InstanceGroupCreateOptions) Params() (jsonutils.JSONObject, error) {
@@ -55,6 +70,13 @@ func (opts *InstanceGroupCreateOptions) Params() (jsonutils.JSONObject, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, "StructToParams")
 	}
+	if len(opts.TopologySpread) > 0 {
+		constraints, err := parseTopologySpreadConstraints(opts.TopologySpread)
+		if err != nil {
+			return nil, err
+		}
+		params.Set("topology_spread_constraints", jsonutils.Marshal(constraints))
+	}
 	return params, nil
 }
 
@@ -64,6 +86,8 @@ type InstanceGroupUpdateOptions struct {
 	Name            string `help:"New name to change"`
 	Granularity     string `help:"the upper limit number of guests with this group in a host"`
 	ForceDispersion string `help:"force to make guest dispersion" choices:"yes|no" json:"-"`
+
+	TopologySpread []string `help:"Topology spread constraint, e.g. 'zone,max_skew=1', 'metadata:rack,max_per_domain=2'" json:"-"`
 }
 
 func (opts *InstanceGroupUpdateOptions) Params() (jsonutils.JSONObject, error) {
@@ -76,6 +100,13 @@ func (opts *InstanceGroupUpdateOptions) Params() (jsonutils.JSONObject, error) {
 	} else {
 		params.Set("force_dispersion", jsonutils.JSONFalse)
 	}
+	if len(opts.TopologySpread) > 0 {
+		constraints, err := parseTopologySpreadConstraints(opts.TopologySpread)
+		if err != nil {
+			return nil, err
+		}
+		params.Set("topology_spread_constraints", jsonutils.Marshal(constraints))
+	}
 	return params, nil
 }
 
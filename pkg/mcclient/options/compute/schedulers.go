@@ -27,6 +27,8 @@ type SchedulerTestBaseOptions struct {
 	Sku   string `help:"Server SKU instance type"`
 	Log   bool   `help:"Record to schedule history"`
 	Cdrom string `help:"ISO image ID" metavar:"IMAGE_ID"`
+
+	CostWeight *int `help:"Weight of the cost priority, default to the one of scheduler"`
 }
 
 func (o SchedulerTestBaseOptions) data(s *mcclient.ClientSession) (*scheduler.ServerConfig, error) {
@@ -61,6 +63,18 @@ func (o SchedulerTestBaseOptions) options() *scheduler.ScheduleBaseConfig {
 	return opt
 }
 
+func (o SchedulerTestBaseOptions) input(s *mcclient.ClientSession) (*scheduler.ScheduleInput, error) {
+	data, err := o.data(s)
+	if err != nil {
+		return nil, err
+	}
+	input := new(scheduler.ScheduleInput)
+	input.ServerConfig = *data
+	input.ScheduleBaseConfig = *o.options()
+	input.CostWeight = o.CostWeight
+	return input, nil
+}
+
 type SchedulerTestOptions struct {
 	SchedulerTestBaseOptions
 	SuggestionLimit int64 `help:"Number of schedule candidate informations" default:"50"`
@@ -69,14 +83,10 @@ type SchedulerTestOptions struct {
 }
 
 func (o *SchedulerTestOptions) Params(s *mcclient.ClientSession) (*scheduler.ScheduleInput, error) {
-	data, err := o.data(s)
+	input, err := o.input(s)
 	if err != nil {
 		return nil, err
 	}
-	opts := o.options()
-	input := new(scheduler.ScheduleInput)
-	input.ServerConfig = *data
-	input.ScheduleBaseConfig = *opts
 	input.SuggestionLimit = o.SuggestionLimit
 	input.SuggestionAll = o.SuggestionAll
 	input.Details = o.Details
@@ -89,13 +99,5 @@ type SchedulerForecastOptions struct {
 }
 
 func (o SchedulerForecastOptions) Params(s *mcclient.ClientSession) (*scheduler.ScheduleInput, error) {
-	data, err := o.data(s)
-	if err != nil {
-		return nil, err
-	}
-	opts := o.options()
-	input := new(scheduler.ScheduleInput)
-	input.ServerConfig = *data
-	input.ScheduleBaseConfig = *opts
-	return input, nil
+	return o.input(s)
 }
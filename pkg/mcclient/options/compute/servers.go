@@ -253,6 +253,8 @@ type ServerConfigs struct {
 	Project        string   `help:"'Owner project ID or Name" json:"tenant"`
 	User           string   `help:"Owner user ID or Name"`
 	Count          int      `help:"Create multiple simultaneously" default:"1"`
+
+	TopologySpread []string `help:"Topology spread constraint, e.g. 'zone,max_skew=1', 'metadata:rack,max_per_domain=2', 'storage,max_skew=1,when_unsatisfiable=schedule_anyway'"`
 }
 
 func (o ServerConfigs) Data() (*computeapi.ServerConfigs, error) {
@@ -330,6 +332,13 @@ func (o ServerConfigs) Data() (*computeapi.ServerConfigs, error) {
 		}
 		data.Schedtags = append(data.Schedtags, schedtag)
 	}
+	for _, desc := range o.TopologySpread {
+		c, err := cmdline.ParseTopologySpreadConstraint(desc)
+		if err != nil {
+			return nil, err
+		}
+		data.TopologySpreadConstraints = append(data.TopologySpreadConstraints, *c)
+	}
 	return data, nil
 }
 
@@ -387,6 +396,8 @@ type ServerCreateOptionalOptions struct {
 	Vdi              string   `help:"VDI protocool" choices:"vnc|spice"`
 	Bios             string   `help:"BIOS" choices:"BIOS|UEFI"`
 	Machine          string   `help:"Machine type" choices:"pc|q35"`
+	SecureBoot       bool     `help:"Enable UEFI secure boot, KVM only"`
+	Vtpm             bool     `help:"Attach a swtpm backed vTPM 2.0 device, KVM only"`
 	Desc             string   `help:"Description" metavar:"<DESCRIPTION>" json:"description"`
 	Boot             string   `help:"Boot device" metavar:"<BOOT_DEVICE>" choices:"disk|cdrom" json:"-"`
 	EnableCloudInit  bool     `help:"Enable cloud-init service"`
@@ -493,6 +504,8 @@ func (opts *ServerCreateOptionalOptions) OptionalParams() (*computeapi.ServerCre
 		Vdi:                opts.Vdi,
 		Bios:               opts.Bios,
 		Machine:            opts.Machine,
+		SecureBoot:         opts.SecureBoot,
+		Vtpm:               opts.Vtpm,
 		ShutdownBehavior:   opts.ShutdownBehavior,
 		AutoStart:          opts.AutoStart,
 		Duration:           opts.Duration,
@@ -848,6 +861,9 @@ type ServerChangeConfigOptions struct {
 	Disk      []string `help:"Data disk description, from the 1st data disk to the last one, empty string if no change for this data disk"`
 
 	InstanceType string `help:"Instance Type, e.g. S2.SMALL2 for qcloud"`
+
+	SecureBoot *bool `help:"Enable or disable UEFI secure boot, server must be stopped" negative:"no_secure_boot"`
+	Vtpm       *bool `help:"Attach or detach the vTPM device, server must be stopped" negative:"no_vtpm"`
 }
 
 func (o *ServerChangeConfigOptions) Params() (jsonutils.JSONObject, error) {
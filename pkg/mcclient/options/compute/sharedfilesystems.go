@@ -0,0 +1,59 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"yunion.io/x/jsonutils"
+
+	"yunion.io/x/onecloud/pkg/mcclient/options"
+)
+
+type SharedFilesystemListOptions struct {
+	options.BaseListOptions
+
+	Server     string   `help:"filter by server name or id" json:"server_id"`
+	SourceType []string `help:"filter by source type" choices:"host_path|storage"`
+	Mode       []string `help:"filter by mode" choices:"ro|rw"`
+	StorageId  []string `help:"filter by storage id"`
+}
+
+func (opts *SharedFilesystemListOptions) Params() (jsonutils.JSONObject, error) {
+	return options.ListStructToParams(opts)
+}
+
+type SharedFilesystemCreateOptions struct {
+	SERVER string `help:"server name or id" json:"server_id"`
+	TAG    string `help:"mount tag used inside the guest" json:"tag"`
+
+	HostPath   string `help:"export a host directory, admin only" json:"host_path"`
+	Storage    string `help:"export a subtree of a nfs/gpfs shared storage" json:"storage_id"`
+	SubPath    string `help:"sub directory inside the shared storage" json:"sub_path"`
+	Mode       string `help:"access mode" choices:"ro|rw" default:"rw" json:"mode"`
+	Mountpoint string `help:"mount point written to the guest fstab" json:"mountpoint"`
+	SizeMb     int    `help:"size in MB counted against storage quota" json:"size_mb,omitzero"`
+}
+
+func (opts *SharedFilesystemCreateOptions) Params() (jsonutils.JSONObject, error) {
+	params, err := options.StructToParams(opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(opts.HostPath) > 0 {
+		params.Set("source_type", jsonutils.NewString("host_path"))
+	} else if len(opts.Storage) > 0 {
+		params.Set("source_type", jsonutils.NewString("storage"))
+	}
+	return params, nil
+}
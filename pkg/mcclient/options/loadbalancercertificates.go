@@ -52,8 +52,16 @@ type LoadbalancerCertificateCreateOptions struct {
 
 	NAME string
 
-	Cert string `required:"true" json:"-" help:"path to certificate file"`
-	Pkey string `required:"true" json:"-" help:"path to private key file"`
+	Cert string `json:"-" help:"path to certificate file"`
+	Pkey string `json:"-" help:"path to private key file"`
+
+	Source              string   `choices:"upload|acme" help:"certificate source, default upload"`
+	AcmeDirectoryUrl    string   `help:"acme directory url, default to the one configured in region"`
+	AcmeEmail           string   `help:"contact email of acme account"`
+	AcmeChallengeType   string   `choices:"http-01|dns-01" help:"acme challenge type, default http-01"`
+	AcmeDomains         []string `help:"domain names of acme certificate"`
+	AcmeDnsZone         string   `help:"dns zone serving dns-01 challenge records"`
+	AcmeRenewBeforeDays int      `help:"renew acme certificate days before expiration"`
 }
 
 func (opts *LoadbalancerCertificateCreateOptions) Params() (*jsonutils.JSONDict, error) {
@@ -69,6 +77,9 @@ func (opts *LoadbalancerCertificateCreateOptions) Params() (*jsonutils.JSONDict,
 
 	params.Update(sp)
 
+	if opts.Source == "acme" {
+		return params, nil
+	}
 	paramsCertKey, err := loadbalancerCertificateLoadFiles(opts.Cert, opts.Pkey, false)
 	if err != nil {
 		return nil, err
@@ -92,7 +103,8 @@ type LoadbalancerCertificateListOptions struct {
 	PublicKeyBitLen    *int
 	SignatureAlgorithm string
 	Cloudregion        string
-	Usable             *bool `help:"List certificates are usable"`
+	Usable             *bool    `help:"List certificates are usable"`
+	Source             []string `help:"List certificates of source" choices:"upload|acme"`
 }
 
 type LoadbalancerCertificateUpdateOptions struct {
@@ -121,3 +133,7 @@ type LoadbalancerCertificatePublicOptions struct {
 type LoadbalancerCertificatePrivateOptions struct {
 	ID string `json:"-"`
 }
+
+type LoadbalancerCertificateRenewOptions struct {
+	ID string `json:"-"`
+}
@@ -23,6 +23,8 @@ type LoadbalancerListenerRuleCreateOptions struct {
 
 	HTTPRequestRate       *int
 	HTTPRequestRatePerSrc *int
+	HTTPRateLimitStatus   *int    `choices:"403|429|503" help:"http status when request rate limit is hit, default 429"`
+	HTTPRateLimitBody     *string `json:",allowempty" help:"response body when request rate limit is hit"`
 
 	Redirect       *string `choices:"off|raw"`
 	RedirectCode   *int    `choices:"301|302|307"`
@@ -54,6 +56,8 @@ type LoadbalancerListenerRuleUpdateOptions struct {
 
 	HTTPRequestRate       *int
 	HTTPRequestRatePerSrc *int
+	HTTPRateLimitStatus   *int    `choices:"403|429|503" help:"http status when request rate limit is hit, default 429"`
+	HTTPRateLimitBody     *string `json:",allowempty" help:"response body when request rate limit is hit"`
 
 	Redirect       *string `choices:"off|raw"`
 	RedirectCode   *int    `choices:"301|302|307"`
@@ -39,7 +39,7 @@ type LoadbalancerListenerCreateOptions struct {
 	EgressMbps int
 
 	HealthCheck     string `choices:"on|off"`
-	HealthCheckType string `choices:"tcp|http"`
+	HealthCheckType string `choices:"tcp|http|grpc"`
 
 	HealthCheckDomain   string
 	HealthCheckURI      string
@@ -53,6 +53,8 @@ type LoadbalancerListenerCreateOptions struct {
 	HealthCheckReq string
 	HealthCheckExp string
 
+	HealthCheckGrpcService string `help:"service name of grpc health check, empty for the whole server"`
+
 	StickySession              string
 	StickySessionType          string
 	StickySessionCookie        string
@@ -61,12 +63,19 @@ type LoadbalancerListenerCreateOptions struct {
 	XForwardedFor string `choices:"true|false"`
 	Gzip          string `choices:"true|false"`
 
+	EnableBackendHttp2 string `choices:"true|false" help:"talk to backends with http/2, e.g. grpc services"`
+
 	Certificate     string
 	TLSCipherPolicy string
 	EnableHttp2     string `choices:"true|false"`
 
+	MaxConn       *int `help:"max concurrent connections of the listener"`
+	MaxConnPerSrc *int `help:"max concurrent connections of each client ip"`
+
 	HTTPRequestRate       *int
 	HTTPRequestRatePerSrc *int
+	HTTPRateLimitStatus   *int    `choices:"403|429|503" help:"http status when request rate limit is hit, default 429"`
+	HTTPRateLimitBody     *string `json:",allowempty" help:"response body when request rate limit is hit"`
 
 	Redirect       *string `choices:"off|raw"`
 	RedirectCode   *int    `choices:"301|302|307"`
@@ -97,7 +106,7 @@ type LoadbalancerListenerListOptions struct {
 	Acl       string
 
 	HealthCheck     string `choices:"on|off"`
-	HealthCheckType string `choices:"tcp|http"`
+	HealthCheckType string `choices:"tcp|http|grpc"`
 
 	HealthCheckDomain   string
 	HealthCheckURI      string
@@ -153,7 +162,7 @@ type LoadbalancerListenerUpdateOptions struct {
 	Acl       string
 
 	HealthCheck     string `choices:"on|off"`
-	HealthCheckType string `choices:"tcp|http"`
+	HealthCheckType string `choices:"tcp|http|grpc"`
 
 	HealthCheckDomain   string
 	HealthCheckURI      string
@@ -167,6 +176,8 @@ type LoadbalancerListenerUpdateOptions struct {
 	HealthCheckReq string
 	HealthCheckExp string
 
+	HealthCheckGrpcService string `help:"service name of grpc health check, empty for the whole server"`
+
 	StickySession              string
 	StickySessionType          string
 	StickySessionCookie        string
@@ -175,12 +186,19 @@ type LoadbalancerListenerUpdateOptions struct {
 	XForwardedFor string `choices:"true|false"`
 	Gzip          string `choices:"true|false"`
 
+	EnableBackendHttp2 string `choices:"true|false" help:"talk to backends with http/2, e.g. grpc services"`
+
 	Certificate     string
 	TLSCipherPolicy string
 	EnableHttp2     string `choices:"true|false"`
 
+	MaxConn       *int `help:"max concurrent connections of the listener"`
+	MaxConnPerSrc *int `help:"max concurrent connections of each client ip"`
+
 	HTTPRequestRate       *int
 	HTTPRequestRatePerSrc *int
+	HTTPRateLimitStatus   *int    `choices:"403|429|503" help:"http status when request rate limit is hit, default 429"`
+	HTTPRateLimitBody     *string `json:",allowempty" help:"response body when request rate limit is hit"`
 
 	Redirect       *string `choices:"off|raw"`
 	RedirectCode   *int    `choices:"301|302|307"`
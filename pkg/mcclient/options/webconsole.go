@@ -24,8 +24,13 @@ type WebConsoleOptions struct {
 	WebconsoleUrl string `help:"Frontend webconsole url" short-token:"w" default:"$WEBCONSOLE_URL"`
 }
 
+type WebConsoleRecordOptions struct {
+	Record bool `help:"Record the terminal session in asciicast v2 format"`
+}
+
 type PodBaseOptions struct {
 	WebConsoleOptions
+	WebConsoleRecordOptions
 	NAME      string `help:"Name of k8s pod to connect"`
 	Namespace string `help:"Namespace of this pod"`
 	Container string `help:"Container in this pod"`
@@ -62,7 +67,8 @@ func (opt *PodLogOptoins) Params() (*jsonutils.JSONDict, error) {
 
 type WebConsoleBaremetalOptions struct {
 	WebConsoleOptions
-	ID string `help:"Baremetal host id or name"`
+	WebConsoleRecordOptions
+	ID string `help:"Baremetal host id or name" json:"-"`
 }
 
 func (opt *WebConsoleBaremetalOptions) Params() (*jsonutils.JSONDict, error) {
@@ -71,6 +77,7 @@ func (opt *WebConsoleBaremetalOptions) Params() (*jsonutils.JSONDict, error) {
 
 type WebConsoleSshOptions struct {
 	WebConsoleOptions
+	WebConsoleRecordOptions
 	IP   string `help:"IP to connect" json:"-"`
 	Port int    `help:"Remote server port"`
 }
@@ -89,3 +96,25 @@ type WebConsoleServerOptions struct {
 	WebConsoleOptions
 	ID string `help:"Server id or name"`
 }
+
+type WebConsoleRecordingListOptions struct {
+	Project   string `help:"Filter by project id or name"`
+	User      string `help:"Filter by user id or name"`
+	SessionId string `help:"Filter by session id"`
+	Protocol  string `help:"Filter by protocol"`
+	Limit     int    `help:"Max items to show" default:"20"`
+	Offset    int    `help:"Offset of the first item"`
+}
+
+func (opt *WebConsoleRecordingListOptions) Params() (*jsonutils.JSONDict, error) {
+	return StructToParams(opt)
+}
+
+type WebConsoleRecordingIdOptions struct {
+	ID string `help:"Recording id"`
+}
+
+type WebConsoleRecordingPlaybackOptions struct {
+	WebConsoleRecordingIdOptions
+	Output string `help:"File to save the asciicast recording, print to stdout if not set" short-token:"o"`
+}
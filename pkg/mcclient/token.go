@@ -20,6 +20,7 @@ import (
 	"yunion.io/x/jsonutils"
 	"yunion.io/x/pkg/gotypes"
 
+	api "yunion.io/x/onecloud/pkg/apis/identity"
 	"yunion.io/x/onecloud/pkg/util/rbacutils"
 )
 
@@ -97,3 +98,16 @@ type TokenCredential interface {
 	GetLoginSource() string
 	GetLoginIp() string
 }
+
+// IAppCredentialToken is implemented by tokens which carry the application credential they are issued by
+type IAppCredentialToken interface {
+	GetAppCredential() api.SAppCredentialInfo
+}
+
+// GetAppCredential returns the application credential a token is issued by, Id is empty otherwise
+func GetAppCredential(token TokenCredential) api.SAppCredentialInfo {
+	if acToken, ok := token.(IAppCredentialToken); ok {
+		return acToken.GetAppCredential()
+	}
+	return api.SAppCredentialInfo{}
+}
@@ -140,6 +140,9 @@ type KeystoneTokenV3 struct {
 
 	// 如果时AK/SK认证，返回用户的AccessKey/Secret信息，用于客户端后续的AK/SK认证，避免频繁访问keystone进行AK/SK认证
 	AccessKey api.SAccessKeySecretInfo `json:"access_key"`
+
+	// 如果是应用凭证认证，返回应用凭证的信息，服务根据其中的访问规则限制token的访问范围
+	AppCredential api.SAppCredentialInfo `json:"app_credential"`
 }
 
 type TokenCredentialV3 struct {
@@ -295,6 +298,10 @@ func (this *TokenCredentialV3) GetLoginIp() string {
 	return this.Token.Context.Ip
 }
 
+func (this *TokenCredentialV3) GetAppCredential() api.SAppCredentialInfo {
+	return this.Token.AppCredential
+}
+
 func (catalog KeystoneServiceCatalogV3) GetInternalServices(region string) []string {
 	services := make([]string, 0)
 	for i := 0; i < len(catalog); i++ {
@@ -44,6 +44,8 @@ type SSimpleToken struct {
 	Expires time.Time
 
 	Context SAuthContext
+
+	AppCredential api.SAppCredentialInfo
 }
 
 func (self *SSimpleToken) GetTokenString() string {
@@ -190,6 +192,10 @@ func (this *SSimpleToken) GetLoginIp() string {
 	return this.Context.Ip
 }
 
+func (this *SSimpleToken) GetAppCredential() api.SAppCredentialInfo {
+	return this.AppCredential
+}
+
 func SimplifyToken(token TokenCredential) TokenCredential {
 	simToken, ok := token.(*SSimpleToken)
 	if ok {
@@ -213,6 +219,7 @@ func SimplifyToken(token TokenCredential) TokenCredential {
 			Source: token.GetLoginSource(),
 			Ip:     token.GetLoginIp(),
 		},
+		AppCredential: GetAppCredential(token),
 	}
 }
 
@@ -38,6 +38,7 @@ import (
 	"yunion.io/x/onecloud/pkg/cloudcommon/db"
 	"yunion.io/x/onecloud/pkg/hostman/hostinfo/hostconsts"
 	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
 	"yunion.io/x/onecloud/pkg/mcclient/auth"
 	merrors "yunion.io/x/onecloud/pkg/monitor/errors"
 	"yunion.io/x/onecloud/pkg/monitor/options"
@@ -148,11 +149,11 @@ func (man *SDataSourceManager) GetDefaultSource() (*SDataSource, error) {
 type SDataSource struct {
 	db.SStandaloneResourceBase
 
-	Type      string            `nullable:"false" list:"user"`
-	Url       string            `nullable:"false" list:"user"`
-	User      string            `width:"64" charset:"utf8" nullable:"true"`
-	Password  string            `width:"64" charset:"utf8" nullable:"true"`
-	Database  string            `width:"64" charset:"utf8" nullable:"true"`
+	Type      string            `nullable:"false" list:"user" create:"admin_required"`
+	Url       string            `nullable:"false" list:"user" create:"admin_required"`
+	User      string            `width:"64" charset:"utf8" nullable:"true" create:"admin_optional"`
+	Password  string            `width:"64" charset:"utf8" nullable:"true" create:"admin_optional"`
+	Database  string            `width:"64" charset:"utf8" nullable:"true" create:"admin_optional"`
 	IsDefault tristate.TriState `default:"false" create:"optional"`
 	/*
 		TimeInterval string
@@ -162,6 +163,24 @@ type SDataSource struct {
 	*/
 }
 
+func (man *SDataSourceManager) ValidateCreateData(
+	ctx context.Context, userCred mcclient.TokenCredential,
+	ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject,
+	input monitor.DataSourceCreateInput) (monitor.DataSourceCreateInput, error) {
+	if !utils.IsInStringArray(input.Type, monitor.DataSourceTypes) {
+		return input, httperrors.NewInputParameterError("unsupported datasource type %q", input.Type)
+	}
+	if len(input.Url) == 0 {
+		return input, httperrors.NewMissingParameterError("url")
+	}
+	var err error
+	input.StandaloneResourceCreateInput, err = man.SStandaloneResourceBaseManager.ValidateCreateData(ctx, userCred, ownerId, query, input.StandaloneResourceCreateInput)
+	if err != nil {
+		return input, err
+	}
+	return input, nil
+}
+
 func (m *SDataSourceManager) GetSource(id string) (*SDataSource, error) {
 	ret, err := m.FetchById(id)
 	if err != nil {
@@ -40,6 +40,7 @@ import (
 	"yunion.io/x/onecloud/pkg/monitor/subscriptionmodel"
 	_ "yunion.io/x/onecloud/pkg/monitor/tasks"
 	_ "yunion.io/x/onecloud/pkg/monitor/tsdb/driver/influxdb"
+	_ "yunion.io/x/onecloud/pkg/monitor/tsdb/driver/prometheus"
 	"yunion.io/x/onecloud/pkg/monitor/worker"
 )
 
@@ -0,0 +1,70 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import (
+	"time"
+
+	api "yunion.io/x/onecloud/pkg/apis/monitor"
+)
+
+type Query struct {
+	Measurement string
+	Tags        []api.MetricQueryTag
+	GroupBy     []string
+	Selects     []*Select
+	Alias       string
+	// TimeGroup is the param of group by time part, e.g. 5m or $interval
+	TimeGroup string
+	Interval  time.Duration
+}
+
+// Select is one column of influxdb style select, it's rendered
+// as a standalone PromQL expression.
+type Select struct {
+	Field string
+	// Aggregator is the influxdb aggregate function name, e.g. mean, max
+	Aggregator string
+	AggParams  []string
+	// RangeFunc is the PromQL range function applied before aggregating,
+	// e.g. rate for derivative
+	RangeFunc string
+	Math      string
+	Alias     string
+}
+
+// Expr is the rendered PromQL of one select
+type Expr struct {
+	Column string
+	PromQL string
+}
+
+type Response struct {
+	Status    string `json:"status"`
+	Data      Data   `json:"data"`
+	ErrorType string `json:"errorType,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+type Data struct {
+	ResultType string   `json:"resultType"`
+	Result     []Sample `json:"result"`
+}
+
+// Sample is one series of matrix result, each value is [<unix_time>, "<value>"]
+type Sample struct {
+	Metric map[string]string `json:"metric"`
+	Values [][]interface{}   `json:"values,omitempty"`
+}
@@ -0,0 +1,94 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	api "yunion.io/x/onecloud/pkg/apis/monitor"
+	"yunion.io/x/onecloud/pkg/monitor/tsdb"
+)
+
+func TestPrometheusExecutor(t *testing.T) {
+	Convey("Prometheus executor", t, func() {
+		var queries []string
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/api/v1/query_range" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			r.ParseForm()
+			queries = append(queries, r.Form.Get("query"))
+			if r.Form.Get("step") != "60" {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"status":"error","errorType":"bad_data","error":"invalid step"}`)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"status":"success","data":{"resultType":"matrix","result":[{"metric":{"host_id":"h1"},"values":[[1600000000,"10"],[1600000060,"20"]]}]}}`)
+		}))
+		defer ts.Close()
+
+		ds := &tsdb.DataSource{
+			Id:   "prometheus-test",
+			Type: api.DataSourceTypePrometheus,
+			Url:  ts.URL,
+		}
+		newRequest := func(interval string) *tsdb.TsdbQuery {
+			return &tsdb.TsdbQuery{
+				TimeRange: tsdb.NewTimeRange("1h", "now"),
+				Queries: []*tsdb.Query{
+					{
+						RefId: "A",
+						MetricQuery: api.MetricQuery{
+							Measurement: "cpu",
+							Selects: []api.MetricQuerySelect{
+								{{Type: "field", Params: []string{"usage_active"}}, {Type: "mean"}},
+							},
+							GroupBy: []api.MetricQueryPart{
+								{Type: "time", Params: []string{interval}},
+								{Type: "tag", Params: []string{"host_id"}},
+							},
+						},
+					},
+				},
+			}
+		}
+
+		Convey("can query through registered endpoint", func() {
+			resp, err := tsdb.HandleRequest(context.Background(), ds, newRequest("1m"))
+			So(err, ShouldBeNil)
+			So(queries, ShouldResemble, []string{`avg by (host_id) (avg_over_time(cpu_usage_active{}[60s]))`})
+			ret := resp.Results["A"]
+			So(ret, ShouldNotBeNil)
+			So(ret.Meta.RawQuery, ShouldEqual, queries[0])
+			So(len(ret.Series), ShouldEqual, 1)
+			So(ret.Series[0].Tags["host_id"], ShouldEqual, "h1")
+			So(ret.Series[0].Points[1].Value(), ShouldEqual, float64(20))
+		})
+
+		Convey("returns prometheus error", func() {
+			_, err := tsdb.HandleRequest(context.Background(), ds, newRequest("5m"))
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "invalid step")
+		})
+	})
+}
@@ -0,0 +1,202 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/monitor/tsdb"
+)
+
+type aggregator struct {
+	// Outer is the aggregation operator across series
+	Outer string
+	// OverTime is the range function aggregating points of one series
+	OverTime string
+	// Quantile means the first param of the function is a quantile
+	Quantile bool
+}
+
+var (
+	aggregators = map[string]aggregator{
+		"mean":       {Outer: "avg", OverTime: "avg_over_time"},
+		"sum":        {Outer: "sum", OverTime: "sum_over_time"},
+		"max":        {Outer: "max", OverTime: "max_over_time"},
+		"min":        {Outer: "min", OverTime: "min_over_time"},
+		"count":      {Outer: "sum", OverTime: "count_over_time"},
+		"last":       {Outer: "max", OverTime: "last_over_time"},
+		"stddev":     {Outer: "stddev", OverTime: "stddev_over_time"},
+		"median":     {Outer: "quantile", OverTime: "quantile_over_time", Quantile: true},
+		"percentile": {Outer: "quantile", OverTime: "quantile_over_time", Quantile: true},
+	}
+
+	invalidNameChars      = regexp.MustCompile(`[^a-zA-Z0-9_:]`)
+	regexpOperatorPattern = regexp.MustCompile(`^\/.*\/$`)
+)
+
+// MetricName converts influxdb measurement and field to prometheus metric name,
+// it's the same convention used by telegraf prometheus output: <measurement>_<field>
+func MetricName(measurement, field string) string {
+	return sanitizeName(fmt.Sprintf("%s_%s", measurement, field))
+}
+
+func sanitizeName(name string) string {
+	return invalidNameChars.ReplaceAllString(name, "_")
+}
+
+// Build renders PromQL expressions of every select and the step of range query
+func (query *Query) Build(queryCtx *tsdb.TsdbQuery) ([]*Expr, time.Duration, error) {
+	step := query.getStep(queryCtx)
+	matchers, err := query.renderMatchers()
+	if err != nil {
+		return nil, 0, err
+	}
+	exprs := make([]*Expr, 0, len(query.Selects))
+	for _, sel := range query.Selects {
+		promql, err := query.renderSelect(sel, matchers, step)
+		if err != nil {
+			return nil, 0, err
+		}
+		exprs = append(exprs, &Expr{
+			Column: sel.columnName(),
+			PromQL: promql,
+		})
+	}
+	return exprs, step, nil
+}
+
+func (query *Query) getStep(queryCtx *tsdb.TsdbQuery) time.Duration {
+	switch query.TimeGroup {
+	case "", "auto", "$interval", "$__interval":
+	default:
+		if d, err := time.ParseDuration(query.TimeGroup); err == nil && d > 0 {
+			return d
+		}
+	}
+	calculator := tsdb.NewIntervalCalculator(&tsdb.IntervalOptions{})
+	interval := calculator.Calculate(queryCtx.TimeRange, query.Interval)
+	return interval.Value
+}
+
+func (query *Query) renderMatchers() (string, error) {
+	var res []string
+	for _, tag := range query.Tags {
+		if strings.ToUpper(tag.Condition) == "OR" {
+			return "", errors.Wrapf(ErrUnsupportedQueryPart, "OR condition of tag %s", tag.Key)
+		}
+		op := tag.Operator
+		val := tag.Value
+		if op == "" {
+			if regexpOperatorPattern.MatchString(val) {
+				op = "=~"
+			} else {
+				op = "="
+			}
+		}
+		switch op {
+		case "=", "!=":
+		case "=~", "!~":
+			val = strings.TrimSuffix(strings.TrimPrefix(val, "/"), "/")
+		case "<>":
+			op = "!="
+		default:
+			return "", errors.Wrapf(ErrUnsupportedQueryPart, "tag operator %s", op)
+		}
+		res = append(res, fmt.Sprintf("%s%s%s", sanitizeName(tag.Key), op, strconv.Quote(val)))
+	}
+	return strings.Join(res, ","), nil
+}
+
+func (query *Query) renderSelect(sel *Select, matchers string, step time.Duration) (string, error) {
+	expr := fmt.Sprintf("%s{%s}", MetricName(query.Measurement, sel.Field), matchers)
+	rangeExpr := fmt.Sprintf("%s[%s]", expr, formatDuration(step))
+
+	if sel.RangeFunc != "" {
+		expr = fmt.Sprintf("%s(%s)", sel.RangeFunc, rangeExpr)
+	}
+
+	if sel.Aggregator != "" {
+		agg := aggregators[sel.Aggregator]
+		param := ""
+		if agg.Quantile {
+			q, err := sel.quantile()
+			if err != nil {
+				return "", err
+			}
+			param = q + ", "
+		}
+		inner := expr
+		if sel.RangeFunc == "" {
+			inner = fmt.Sprintf("%s(%s%s)", agg.OverTime, param, rangeExpr)
+		}
+		by := ""
+		if len(query.GroupBy) > 0 {
+			labels := make([]string, len(query.GroupBy))
+			for i := range query.GroupBy {
+				labels[i] = sanitizeName(query.GroupBy[i])
+			}
+			by = fmt.Sprintf(" by (%s)", strings.Join(labels, ", "))
+		}
+		expr = fmt.Sprintf("%s%s (%s%s)", agg.Outer, by, param, inner)
+	}
+
+	if sel.Math != "" {
+		expr = fmt.Sprintf("(%s) %s", expr, sel.Math)
+	}
+	return expr, nil
+}
+
+func (sel *Select) quantile() (string, error) {
+	if sel.Aggregator == "median" {
+		return "0.5", nil
+	}
+	if len(sel.AggParams) == 0 {
+		return "", errors.Wrap(ErrUnsupportedQueryPart, "percentile without nth")
+	}
+	nth, err := strconv.ParseFloat(sel.AggParams[0], 64)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid percentile %s", sel.AggParams[0])
+	}
+	return strconv.FormatFloat(nth/100, 'f', -1, 64), nil
+}
+
+func (sel *Select) columnName() string {
+	if sel.Alias != "" {
+		return sel.Alias
+	}
+	if sel.Aggregator != "" {
+		return sel.Aggregator
+	}
+	return sel.Field
+}
+
+// formatDuration renders duration as PromQL duration, which doesn't accept
+// the fractional or compound format of time.Duration.String
+func formatDuration(d time.Duration) string {
+	if d%time.Second != 0 || d < time.Second {
+		ms := d.Milliseconds()
+		if ms < 1 {
+			ms = 1
+		}
+		return fmt.Sprintf("%dms", ms)
+	}
+	return fmt.Sprintf("%ds", int64(d/time.Second))
+}
@@ -0,0 +1,142 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import (
+	"time"
+
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/monitor"
+	"yunion.io/x/onecloud/pkg/monitor/tsdb"
+)
+
+const (
+	ErrUnsupportedQueryPart = errors.Error("Unsupported query part for prometheus")
+)
+
+type PrometheusQueryParser struct{}
+
+func (qp *PrometheusQueryParser) Parse(model *tsdb.Query, dsInfo *tsdb.DataSource) (*Query, error) {
+	if model.Measurement == "" {
+		return nil, errors.Error("measurement is empty")
+	}
+
+	groupBy, timeGroup, err := qp.parseGroupBy(model.GroupBy)
+	if err != nil {
+		return nil, err
+	}
+
+	selects, err := qp.parseSelects(model.Selects)
+	if err != nil {
+		return nil, err
+	}
+
+	parsedInterval, err := tsdb.GetIntervalFrom(dsInfo, model, time.Millisecond*1)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Query{
+		Measurement: model.Measurement,
+		Tags:        model.Tags,
+		GroupBy:     groupBy,
+		Selects:     selects,
+		Alias:       model.Alias,
+		TimeGroup:   timeGroup,
+		Interval:    parsedInterval,
+	}, nil
+}
+
+func (qp *PrometheusQueryParser) parseGroupBy(parts []api.MetricQueryPart) ([]string, string, error) {
+	var (
+		labels    []string
+		timeGroup string
+	)
+	for _, part := range parts {
+		switch part.Type {
+		case "tag":
+			if len(part.Params) == 0 {
+				return nil, "", errors.Wrap(ErrUnsupportedQueryPart, "tag without params")
+			}
+			if part.Params[0] == "*" {
+				return nil, "", errors.Wrap(ErrUnsupportedQueryPart, "group by tag *")
+			}
+			labels = append(labels, part.Params[0])
+		case "time":
+			if len(part.Params) > 0 {
+				timeGroup = part.Params[0]
+			}
+		case "fill":
+			// prometheus never fills missing points, fill(none) is the default behavior
+		default:
+			return nil, "", errors.Wrapf(ErrUnsupportedQueryPart, "group by %s", part.Type)
+		}
+	}
+	return labels, timeGroup, nil
+}
+
+func (qp *PrometheusQueryParser) parseSelects(selects []api.MetricQuerySelect) ([]*Select, error) {
+	var result []*Select
+	for _, sel := range selects {
+		s, err := qp.parseSelect(sel)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, s)
+	}
+	if len(result) == 0 {
+		return nil, errors.Error("query contains no selects")
+	}
+	return result, nil
+}
+
+func (qp *PrometheusQueryParser) parseSelect(parts api.MetricQuerySelect) (*Select, error) {
+	s := &Select{}
+	for _, part := range parts {
+		switch part.Type {
+		case "field":
+			if len(part.Params) == 0 || part.Params[0] == "*" {
+				return nil, errors.Wrap(ErrUnsupportedQueryPart, "field must be specified")
+			}
+			s.Field = part.Params[0]
+		case "derivative", "non_negative_derivative":
+			s.RangeFunc = "rate"
+		case "difference", "non_negative_difference":
+			s.RangeFunc = "increase"
+		case "math":
+			if len(part.Params) > 0 {
+				s.Math = part.Params[0]
+			}
+		case "alias":
+			if len(part.Params) > 0 {
+				s.Alias = part.Params[0]
+			}
+		default:
+			if _, ok := aggregators[part.Type]; !ok {
+				return nil, errors.Wrapf(ErrUnsupportedQueryPart, "select %s", part.Type)
+			}
+			if s.Aggregator != "" {
+				return nil, errors.Wrapf(ErrUnsupportedQueryPart, "nested aggregator %s(%s)", part.Type, s.Aggregator)
+			}
+			s.Aggregator = part.Type
+			s.AggParams = part.Params
+		}
+	}
+	if s.Field == "" {
+		return nil, errors.Wrap(ErrUnsupportedQueryPart, "select without field")
+	}
+	return s, nil
+}
@@ -0,0 +1,128 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	api "yunion.io/x/onecloud/pkg/apis/monitor"
+	"yunion.io/x/onecloud/pkg/monitor/tsdb"
+)
+
+func TestPrometheusQueryBuilder(t *testing.T) {
+	Convey("Prometheus query builder", t, func() {
+		parser := &PrometheusQueryParser{}
+		queryContext := &tsdb.TsdbQuery{
+			TimeRange: tsdb.NewTimeRange("1h", "now"),
+		}
+
+		newQuery := func(model api.MetricQuery) *tsdb.Query {
+			return &tsdb.Query{RefId: "A", MetricQuery: model}
+		}
+
+		Convey("can build aggregate query grouped by tag", func() {
+			q, err := parser.Parse(newQuery(api.MetricQuery{
+				Measurement: "cpu",
+				Tags: []api.MetricQueryTag{
+					{Key: "host", Operator: "=", Value: "server1"},
+					{Key: "res_type", Value: "/guest|host/"},
+				},
+				Selects: []api.MetricQuerySelect{
+					{{Type: "field", Params: []string{"usage_active"}}, {Type: "mean"}},
+				},
+				GroupBy: []api.MetricQueryPart{
+					{Type: "time", Params: []string{"5m"}},
+					{Type: "tag", Params: []string{"host_id"}},
+					{Type: "fill", Params: []string{"none"}},
+				},
+			}), nil)
+			So(err, ShouldBeNil)
+
+			exprs, step, err := q.Build(queryContext)
+			So(err, ShouldBeNil)
+			So(step.String(), ShouldEqual, "5m0s")
+			So(len(exprs), ShouldEqual, 1)
+			So(exprs[0].Column, ShouldEqual, "mean")
+			So(exprs[0].PromQL, ShouldEqual, `avg by (host_id) (avg_over_time(cpu_usage_active{host="server1",res_type=~"guest|host"}[300s]))`)
+		})
+
+		Convey("can build multiple selects with rate, percentile, math and alias", func() {
+			q, err := parser.Parse(newQuery(api.MetricQuery{
+				Measurement: "net",
+				Selects: []api.MetricQuerySelect{
+					{{Type: "field", Params: []string{"bytes_recv"}}, {Type: "mean"}, {Type: "non_negative_derivative", Params: []string{"1s"}}, {Type: "math", Params: []string{"* 8"}}, {Type: "alias", Params: []string{"bps"}}},
+					{{Type: "field", Params: []string{"drop_in"}}, {Type: "percentile", Params: []string{"95"}}},
+					{{Type: "field", Params: []string{"err_in"}}},
+				},
+				GroupBy: []api.MetricQueryPart{
+					{Type: "time", Params: []string{"1m"}},
+				},
+			}), nil)
+			So(err, ShouldBeNil)
+
+			exprs, _, err := q.Build(queryContext)
+			So(err, ShouldBeNil)
+			So(len(exprs), ShouldEqual, 3)
+			So(exprs[0].Column, ShouldEqual, "bps")
+			So(exprs[0].PromQL, ShouldEqual, `(avg (rate(net_bytes_recv{}[60s]))) * 8`)
+			So(exprs[1].PromQL, ShouldEqual, `quantile (0.95, quantile_over_time(0.95, net_drop_in{}[60s]))`)
+			So(exprs[2].Column, ShouldEqual, "err_in")
+			So(exprs[2].PromQL, ShouldEqual, `net_err_in{}`)
+		})
+
+		Convey("auto interval is calculated from time range", func() {
+			q, err := parser.Parse(newQuery(api.MetricQuery{
+				Measurement: "mem",
+				Selects: []api.MetricQuerySelect{
+					{{Type: "field", Params: []string{"used_percent"}}, {Type: "max"}},
+				},
+				GroupBy: []api.MetricQueryPart{
+					{Type: "time", Params: []string{"$interval"}},
+				},
+			}), nil)
+			So(err, ShouldBeNil)
+
+			_, step, err := q.Build(queryContext)
+			So(err, ShouldBeNil)
+			So(step.Seconds(), ShouldBeGreaterThan, 0)
+		})
+
+		Convey("unsupported parts are rejected", func() {
+			_, err := parser.Parse(newQuery(api.MetricQuery{
+				Measurement: "cpu",
+				Selects: []api.MetricQuerySelect{
+					{{Type: "field", Params: []string{"usage_active"}}, {Type: "holt_winters", Params: []string{"10", "2"}}},
+				},
+			}), nil)
+			So(err, ShouldNotBeNil)
+
+			q, err := parser.Parse(newQuery(api.MetricQuery{
+				Measurement: "cpu",
+				Tags: []api.MetricQueryTag{
+					{Key: "host", Operator: "=", Value: "a"},
+					{Key: "host", Operator: "=", Value: "b", Condition: "OR"},
+				},
+				Selects: []api.MetricQuerySelect{
+					{{Type: "field", Params: []string{"usage_active"}}},
+				},
+			}), nil)
+			So(err, ShouldBeNil)
+			_, _, err = q.Build(queryContext)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,192 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"yunion.io/x/onecloud/pkg/monitor/tsdb"
+)
+
+const (
+	labelMetricName = "__name__"
+)
+
+type ResponseParser struct{}
+
+var (
+	legendFormat = regexp.MustCompile(`\[\[(\w+)(\.\w+)*\]\]*|\$\s*(\w+?)*`)
+)
+
+type mergedSeries struct {
+	tags   map[string]string
+	points map[float64][]*float64
+}
+
+// Parse merges the matrix results of every select into series keyed by labels,
+// each point carries one value per select followed by the timestamp in ms,
+// which is the same layout as influxdb driver returns.
+func (rp *ResponseParser) Parse(responses []*Response, query *Query, exprs []*Expr) *tsdb.QueryResult {
+	queryRes := tsdb.NewQueryResult()
+
+	columns := make([]string, 0, len(exprs)+1)
+	for _, expr := range exprs {
+		columns = append(columns, expr.Column)
+	}
+	columns = append(columns, "time")
+
+	series := make(map[string]*mergedSeries)
+	keys := make([]string, 0)
+	for idx, resp := range responses {
+		for _, sample := range resp.Data.Result {
+			tags := make(map[string]string)
+			for k, v := range sample.Metric {
+				if k == labelMetricName {
+					continue
+				}
+				tags[k] = v
+			}
+			key := seriesKey(tags)
+			ms, ok := series[key]
+			if !ok {
+				ms = &mergedSeries{
+					tags:   tags,
+					points: make(map[float64][]*float64),
+				}
+				series[key] = ms
+				keys = append(keys, key)
+			}
+			for _, pair := range sample.Values {
+				ts, val, err := rp.parseValuePair(pair)
+				if err != nil {
+					continue
+				}
+				vals, ok := ms.points[ts]
+				if !ok {
+					vals = make([]*float64, len(exprs))
+					ms.points[ts] = vals
+				}
+				vals[idx] = val
+			}
+		}
+	}
+
+	for _, key := range keys {
+		ms := series[key]
+		timestamps := make([]float64, 0, len(ms.points))
+		for ts := range ms.points {
+			timestamps = append(timestamps, ts)
+		}
+		sort.Float64s(timestamps)
+		points := make(tsdb.TimeSeriesPoints, 0, len(timestamps))
+		for _, ts := range timestamps {
+			point := make(tsdb.TimePoint, 0, len(exprs)+1)
+			for _, val := range ms.points[ts] {
+				point = append(point, val)
+			}
+			point = append(point, ts)
+			points = append(points, point)
+		}
+		queryRes.Series = append(queryRes.Series, &tsdb.TimeSeries{
+			Name:    rp.formatSerieName(ms.tags, strings.Join(columns[:len(columns)-1], "-"), query),
+			Columns: columns,
+			Points:  points,
+			Tags:    ms.tags,
+		})
+	}
+
+	return queryRes
+}
+
+func seriesKey(tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("%s=%q", k, tags[k]))
+	}
+	return strings.Join(parts, ",")
+}
+
+// parseValuePair parses [<unix_time>, "<value>"] to timestamp in ms and value,
+// NaN and Inf values are treated as null
+func (rp *ResponseParser) parseValuePair(pair []interface{}) (float64, *float64, error) {
+	if len(pair) != 2 {
+		return 0, nil, fmt.Errorf("invalid value pair %v", pair)
+	}
+	var ts float64
+	switch v := pair[0].(type) {
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return 0, nil, err
+		}
+		ts = f
+	case float64:
+		ts = v
+	default:
+		return 0, nil, fmt.Errorf("invalid timestamp %v", pair[0])
+	}
+	ts = math.Round(ts * 1000)
+
+	str, ok := pair[1].(string)
+	if !ok {
+		return ts, nil, nil
+	}
+	val, err := strconv.ParseFloat(str, 64)
+	if err != nil || math.IsNaN(val) || math.IsInf(val, 0) {
+		return ts, nil, nil
+	}
+	return ts, &val, nil
+}
+
+func (rp *ResponseParser) formatSerieName(tags map[string]string, column string, query *Query) string {
+	if query.Alias == "" {
+		return fmt.Sprintf("%s.%s", query.Measurement, column)
+	}
+
+	result := legendFormat.ReplaceAllFunc([]byte(query.Alias), func(in []byte) []byte {
+		aliasFormat := string(in)
+		aliasFormat = strings.Replace(aliasFormat, "[[", "", 1)
+		aliasFormat = strings.Replace(aliasFormat, "]]", "", 1)
+		aliasFormat = strings.Replace(aliasFormat, "$", "", 1)
+
+		if aliasFormat == "m" || aliasFormat == "measurement" {
+			return []byte(query.Measurement)
+		}
+		if aliasFormat == "col" {
+			return []byte(column)
+		}
+		if !strings.HasPrefix(aliasFormat, "tag_") {
+			return in
+		}
+		tagKey := strings.Replace(aliasFormat, "tag_", "", 1)
+		if tagValue, exist := tags[tagKey]; exist {
+			return []byte(tagValue)
+		}
+		return in
+	})
+
+	return string(result)
+}
@@ -0,0 +1,94 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPrometheusResponseParser(t *testing.T) {
+	Convey("Prometheus response parser", t, func() {
+		parser := &ResponseParser{}
+		query := &Query{Measurement: "cpu"}
+		exprs := []*Expr{{Column: "mean"}, {Column: "max"}}
+
+		responses := []*Response{
+			{
+				Status: "success",
+				Data: Data{
+					ResultType: "matrix",
+					Result: []Sample{
+						{
+							Metric: map[string]string{"__name__": "cpu_usage_active", "host": "server1"},
+							Values: [][]interface{}{
+								{json.Number("1600000000"), "1.5"},
+								{json.Number("1600000060"), "NaN"},
+							},
+						},
+					},
+				},
+			},
+			{
+				Status: "success",
+				Data: Data{
+					ResultType: "matrix",
+					Result: []Sample{
+						{
+							Metric: map[string]string{"host": "server1"},
+							Values: [][]interface{}{
+								{json.Number("1600000000"), "3"},
+								{json.Number("1600000060"), "4"},
+							},
+						},
+						{
+							Metric: map[string]string{"host": "server2"},
+							Values: [][]interface{}{
+								{json.Number("1600000060.5"), "7"},
+							},
+						},
+					},
+				},
+			},
+		}
+
+		result := parser.Parse(responses, query, exprs)
+
+		Convey("merges series of every select by labels", func() {
+			So(len(result.Series), ShouldEqual, 2)
+			So(result.Series[0].Name, ShouldEqual, "cpu.mean-max")
+			So(result.Series[0].Tags, ShouldResemble, map[string]string{"host": "server1"})
+			So(result.Series[0].Columns, ShouldResemble, []string{"mean", "max", "time"})
+		})
+
+		Convey("can parse points with timestamp in ms", func() {
+			points := result.Series[0].Points
+			So(len(points), ShouldEqual, 2)
+			So(points[0].Values(), ShouldResemble, []float64{1.5, 3})
+			So(points[0].Timestamp(), ShouldEqual, float64(1600000000000))
+			So(points[1].IsValid(), ShouldBeFalse)
+			So(result.Series[1].Points[0].Timestamp(), ShouldEqual, float64(1600000060500))
+			So(result.Series[1].Points[0].IsValid(), ShouldBeFalse)
+		})
+
+		Convey("can format serie names with alias", func() {
+			query.Alias = "$m $tag_host $col"
+			result := parser.Parse(responses, query, exprs)
+			So(result.Series[1].Name, ShouldEqual, "cpu server2 mean-max")
+		})
+	})
+}
@@ -121,3 +121,71 @@ func (b *SBaseBucket) DeletePolicy(id []string) ([]cloudprovider.SBucketPolicySt
 func (b *SBaseBucket) ListMultipartUploads() ([]cloudprovider.SBucketMultipartUploads, error) {
 	return nil, cloudprovider.ErrNotImplemented
 }
+
+func (b *SBaseBucket) GetLifecycleConf() (string, error) {
+	return "", cloudprovider.ErrNotImplemented
+}
+
+func (b *SBaseBucket) SetLifecycleConf(conf string) error {
+	return cloudprovider.ErrNotImplemented
+}
+
+func (b *SBaseBucket) DeleteLifecycleConf() error {
+	return cloudprovider.ErrNotImplemented
+}
+
+func (b *SBaseBucket) GetEncryptionConf() (string, error) {
+	return "", cloudprovider.ErrNotImplemented
+}
+
+func (b *SBaseBucket) SetEncryptionConf(conf string) error {
+	return cloudprovider.ErrNotImplemented
+}
+
+func (b *SBaseBucket) DeleteEncryptionConf() error {
+	return cloudprovider.ErrNotImplemented
+}
+
+func (b *SBaseBucket) GetNotificationConf() (string, error) {
+	return "", cloudprovider.ErrNotImplemented
+}
+
+func (b *SBaseBucket) SetNotificationConf(conf string) error {
+	return cloudprovider.ErrNotImplemented
+}
+
+func (b *SBaseBucket) GetLoggingConf() (string, error) {
+	return "", cloudprovider.ErrNotImplemented
+}
+
+func (b *SBaseBucket) SetLoggingConf(conf string) error {
+	return cloudprovider.ErrNotImplemented
+}
+
+func (b *SBaseBucket) GetObjectLockConf() (string, error) {
+	return "", cloudprovider.ErrNotImplemented
+}
+
+func (b *SBaseBucket) SetObjectLockConf(conf string) error {
+	return cloudprovider.ErrNotImplemented
+}
+
+func (b *SBaseBucket) GetVersioningConf() (string, error) {
+	return "", cloudprovider.ErrNotImplemented
+}
+
+func (b *SBaseBucket) SetVersioningConf(conf string) error {
+	return cloudprovider.ErrNotImplemented
+}
+
+func (b *SBaseBucket) GetPolicyDocument() (string, error) {
+	return "", cloudprovider.ErrNotImplemented
+}
+
+func (b *SBaseBucket) SetPolicyDocument(doc string) error {
+	return cloudprovider.ErrNotImplemented
+}
+
+func (b *SBaseBucket) DeletePolicyDocument() error {
+	return cloudprovider.ErrNotImplemented
+}
@@ -15,10 +15,14 @@
 package objectstore
 
 import (
+	"bytes"
 	"context"
+	"encoding/xml"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 
@@ -363,3 +367,159 @@ func (bucket *SBucket) CopyPart(ctx context.Context, key string, uploadId string
 	}
 	return result.ETag, nil
 }
+
+func (bucket *SBucket) GetLifecycleConf() (string, error) {
+	conf, err := bucket.client.S3Client().GetBucketLifecycle(bucket.Name)
+	if err != nil {
+		return "", errors.Wrap(err, "GetBucketLifecycle")
+	}
+	return conf, nil
+}
+
+func (bucket *SBucket) SetLifecycleConf(conf string) error {
+	err := bucket.client.S3Client().SetBucketLifecycle(bucket.Name, conf)
+	if err != nil {
+		return errors.Wrap(err, "SetBucketLifecycle")
+	}
+	return nil
+}
+
+func (bucket *SBucket) DeleteLifecycleConf() error {
+	return bucket.SetLifecycleConf("")
+}
+
+func (bucket *SBucket) GetNotificationConf() (string, error) {
+	notification, err := bucket.client.S3Client().GetBucketNotification(bucket.Name)
+	if err != nil {
+		return "", errors.Wrap(err, "GetBucketNotification")
+	}
+	if len(notification.LambdaConfigs) == 0 && len(notification.TopicConfigs) == 0 && len(notification.QueueConfigs) == 0 {
+		return "", nil
+	}
+	conf, err := xml.Marshal(notification)
+	if err != nil {
+		return "", errors.Wrap(err, "xml.Marshal")
+	}
+	return string(conf), nil
+}
+
+func (bucket *SBucket) SetNotificationConf(conf string) error {
+	notification := s3cli.BucketNotification{}
+	if len(conf) > 0 {
+		err := xml.Unmarshal([]byte(conf), &notification)
+		if err != nil {
+			return errors.Wrap(err, "xml.Unmarshal")
+		}
+	}
+	err := bucket.client.S3Client().SetBucketNotification(bucket.Name, notification)
+	if err != nil {
+		return errors.Wrap(err, "SetBucketNotification")
+	}
+	return nil
+}
+
+func (bucket *SBucket) GetLoggingConf() (string, error) {
+	logging, err := bucket.client.S3Client().GetBucketLogging(bucket.Name)
+	if err != nil {
+		return "", errors.Wrap(err, "GetBucketLogging")
+	}
+	if len(logging.LoggingEnabled.TargetBucket) == 0 {
+		return "", nil
+	}
+	conf, err := xml.Marshal(logging)
+	if err != nil {
+		return "", errors.Wrap(err, "xml.Marshal")
+	}
+	return string(conf), nil
+}
+
+func (bucket *SBucket) SetLoggingConf(conf string) error {
+	logging := s3cli.BucketLoggingStatus{}
+	if len(conf) > 0 {
+		err := xml.Unmarshal([]byte(conf), &logging)
+		if err != nil {
+			return errors.Wrap(err, "xml.Unmarshal")
+		}
+	}
+	err := bucket.client.S3Client().SetBucketLogging(bucket.Name, logging)
+	if err != nil {
+		return errors.Wrap(err, "SetBucketLogging")
+	}
+	return nil
+}
+
+func (bucket *SBucket) GetPolicyDocument() (string, error) {
+	doc, err := bucket.client.S3Client().GetBucketPolicy(bucket.Name)
+	if err != nil {
+		return "", errors.Wrap(err, "GetBucketPolicy")
+	}
+	return doc, nil
+}
+
+func (bucket *SBucket) SetPolicyDocument(doc string) error {
+	err := bucket.client.S3Client().SetBucketPolicy(bucket.Name, doc)
+	if err != nil {
+		return errors.Wrap(err, "SetBucketPolicy")
+	}
+	return nil
+}
+
+func (bucket *SBucket) DeletePolicyDocument() error {
+	return bucket.SetPolicyDocument("")
+}
+
+// requestSubResource requests sub-resource of the bucket not covered by
+// s3cli, the request is authorized by presigning
+func (bucket *SBucket) requestSubResource(method string, subResource string, body []byte) ([]byte, error) {
+	params := url.Values{}
+	params.Set(subResource, "")
+	u, err := bucket.client.S3Client().Presign(method, bucket.Name, "", 5*time.Minute, params)
+	if err != nil {
+		return nil, errors.Wrap(err, "Presign")
+	}
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, errors.Wrap(err, "http.NewRequest")
+	}
+	resp, err := bucket.client.GetHttpClient().Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s %s", method, subResource)
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "ioutil.ReadAll")
+	}
+	if resp.StatusCode >= 300 {
+		errResp := s3cli.ErrorResponse{}
+		xml.Unmarshal(respBody, &errResp)
+		errResp.StatusCode = resp.StatusCode
+		return nil, errResp
+	}
+	return respBody, nil
+}
+
+func (bucket *SBucket) GetVersioningConf() (string, error) {
+	body, err := bucket.requestSubResource("GET", "versioning", nil)
+	if err != nil {
+		return "", errors.Wrap(err, "GetBucketVersioning")
+	}
+	conf := s3cli.VersioningConfiguration{}
+	err = xml.Unmarshal(body, &conf)
+	if err != nil {
+		return "", errors.Wrap(err, "xml.Unmarshal")
+	}
+	if len(conf.Status) == 0 {
+		// never versioned
+		return "", nil
+	}
+	return string(body), nil
+}
+
+func (bucket *SBucket) SetVersioningConf(conf string) error {
+	_, err := bucket.requestSubResource("PUT", "versioning", []byte(conf))
+	if err != nil {
+		return errors.Wrap(err, "PutBucketVersioning")
+	}
+	return nil
+}
@@ -15,6 +15,8 @@
 package objectstore
 
 import (
+	"net/http"
+
 	"yunion.io/x/jsonutils"
 	"yunion.io/x/s3cli"
 
@@ -29,6 +31,7 @@ type IBucketProvider interface {
 	GetEndpoint() string
 
 	S3Client() *s3cli.Client
+	GetHttpClient() *http.Client
 
 	About() jsonutils.JSONObject
 	GetVersion() string
@@ -15,6 +15,7 @@
 package objectstore
 
 import (
+	"net/http"
 	"net/url"
 	"os"
 	"strings"
@@ -95,6 +96,8 @@ type SObjectStoreClient struct {
 	iBuckets []cloudprovider.ICloudBucket
 
 	client *s3cli.Client
+
+	httpClient *http.Client
 }
 
 func NewObjectStoreClient(cfg *ObjectStoreClientConfig) (*SObjectStoreClient, error) {
@@ -129,6 +132,7 @@ func NewObjectStoreClientAndFetch(cfg *ObjectStoreClientConfig, doFetch bool) (*
 	cli.SetCustomTransport(tr)
 
 	client.client = cli
+	client.httpClient = &http.Client{Transport: tr}
 	client.SetVirtualObject(&client)
 
 	if client.debug {
@@ -207,6 +211,12 @@ func (cli *SObjectStoreClient) S3Client() *s3cli.Client {
 	return cli.client
 }
 
+// GetHttpClient returns client sharing the transport of S3Client, for
+// requests not covered by s3cli
+func (cli *SObjectStoreClient) GetHttpClient() *http.Client {
+	return cli.httpClient
+}
+
 func (cli *SObjectStoreClient) GetClientRC() map[string]string {
 	return map[string]string{
 		"S3_ACCESS_KEY": cli.accessKey,
@@ -31,6 +31,7 @@ type INotifyService interface {
 	UpdateConfig(ctx context.Context, service string, config SConfig) error
 	Send(ctx context.Context, contactType string, args apis.SendParams) error
 	ContactByMobile(ctx context.Context, mobile, serviceName, domainId string) (string, error)
+	ContactByEmail(ctx context.Context, email, serviceName, domainId string) (string, error)
 	BatchSend(ctx context.Context, contactType string, args apis.BatchSendParams) ([]*apis.FailedRecord, error)
 	SendRobotMessage(ctx context.Context, rType string, receivers []*apis.SReceiver, title string, message string) ([]*apis.FailedRecord, error)
 	AddConfig(ctx context.Context, service string, config SConfig) error
@@ -81,5 +82,6 @@ type SConfig struct {
 
 var (
 	ErrNoSuchMobile     = errors.Error("no such mobile")
+	ErrNoSuchEmail      = errors.Error("no such email")
 	ErrIncompleteConfig = errors.Error("incomplete config")
 )
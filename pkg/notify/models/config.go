@@ -80,7 +80,7 @@ func (cm *SConfigManager) ValidateCreateData(ctx context.Context, userCred mccli
 			return input, err
 		}
 	}
-	if !utils.IsInStringArray(input.Type, []string{api.EMAIL, api.MOBILE, api.DINGTALK, api.FEISHU, api.WEBCONSOLE, api.WORKWX}) {
+	if !utils.IsInStringArray(input.Type, []string{api.EMAIL, api.MOBILE, api.DINGTALK, api.FEISHU, api.WEBCONSOLE, api.WORKWX, api.SLACK, api.MATRIX}) {
 		return input, httperrors.NewInputParameterError("unkown type %q", input.Type)
 	}
 	if !utils.IsInStringArray(input.Attribution, []string{api.CONFIG_ATTRIBUTION_SYSTEM, api.CONFIG_ATTRIBUTION_DOMAIN}) {
@@ -366,7 +366,7 @@ func (cm *SConfigManager) PerformValidate(ctx context.Context, userCred mcclient
 		output api.ConfigValidateOutput
 		err    error
 	)
-	if !utils.IsInStringArray(input.Type, []string{api.EMAIL, api.MOBILE, api.DINGTALK, api.FEISHU, api.WEBCONSOLE, api.WORKWX, api.FEISHU_ROBOT, api.DINGTALK_ROBOT, api.WORKWX_ROBOT}) {
+	if !utils.IsInStringArray(input.Type, []string{api.EMAIL, api.MOBILE, api.DINGTALK, api.FEISHU, api.WEBCONSOLE, api.WORKWX, api.FEISHU_ROBOT, api.DINGTALK_ROBOT, api.WORKWX_ROBOT, api.SLACK, api.MATRIX}) {
 		return output, httperrors.NewInputParameterError("unkown type %q", input.Type)
 	}
 	if input.Content == nil {
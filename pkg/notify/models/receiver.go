@@ -57,6 +57,11 @@ var (
 		api.DINGTALK,
 		api.FEISHU,
 		api.WORKWX,
+		api.SLACK,
+	}
+	// EmailContactTypes are the sub contact types looked up by email instead of mobile
+	EmailContactTypes = []string{
+		api.SLACK,
 	}
 	RobotContactTypes = []string{
 		api.FEISHU_ROBOT,
@@ -349,12 +354,19 @@ func (r *SReceiver) setEnabledContactType(contactType string, enabled bool) {
 				ReceiverID: r.Id,
 				Enabled:    tristate.NewFromBool(enabled),
 			}
-			subContact.ParentContactType = api.MOBILE
+			subContact.ParentContactType = parentContactType(contactType)
 			r.subContactCache[contactType] = subContact
 		}
 	}
 }
 
+func parentContactType(contactType string) string {
+	if utils.IsInStringArray(contactType, EmailContactTypes) {
+		return api.EMAIL
+	}
+	return api.MOBILE
+}
+
 func (r *SReceiver) SetEnabledContactTypes(contactTypes []string) error {
 	if err := r.PullCache(false); err != nil {
 		return err
@@ -383,7 +395,7 @@ func (r *SReceiver) MarkContactTypeVerified(contactType string) error {
 			ReceiverID: r.Id,
 			Verified:   tristate.True,
 		}
-		subContact.ParentContactType = api.MOBILE
+		subContact.ParentContactType = parentContactType(contactType)
 		subContact.VerifiedNote = ""
 		r.subContactCache[contactType] = subContact
 	}
@@ -404,7 +416,7 @@ func (r *SReceiver) MarkContactTypeUnVerified(contactType string, note string) e
 			VerifiedNote: note,
 			Verified:     tristate.False,
 		}
-		subContact.ParentContactType = api.MOBILE
+		subContact.ParentContactType = parentContactType(contactType)
 		r.subContactCache[contactType] = subContact
 	}
 	return nil
@@ -425,7 +437,7 @@ func (r *SReceiver) setVerifiedContactType(contactType string, enabled bool) {
 				ReceiverID: r.Id,
 				Verified:   tristate.NewFromBool(enabled),
 			}
-			subContact.ParentContactType = api.MOBILE
+			subContact.ParentContactType = parentContactType(contactType)
 			r.subContactCache[contactType] = subContact
 		}
 	}
@@ -1046,10 +1058,10 @@ func (r *SReceiver) PerformTriggerVerify(ctx context.Context, userCred mcclient.
 	if len(input.ContactType) == 0 {
 		return nil, httperrors.NewMissingParameterError("contact_type")
 	}
-	if !utils.IsInStringArray(input.ContactType, []string{api.EMAIL, api.MOBILE, api.DINGTALK, api.FEISHU, api.WORKWX}) {
+	if !utils.IsInStringArray(input.ContactType, []string{api.EMAIL, api.MOBILE, api.DINGTALK, api.FEISHU, api.WORKWX, api.SLACK}) {
 		return nil, httperrors.NewInputParameterError("not support such contact type %q", input.ContactType)
 	}
-	if utils.IsInStringArray(input.ContactType, []string{api.DINGTALK, api.FEISHU, api.WORKWX}) {
+	if utils.IsInStringArray(input.ContactType, []string{api.DINGTALK, api.FEISHU, api.WORKWX, api.SLACK}) {
 		r.SetStatus(userCred, api.RECEIVER_STATUS_PULLING, "")
 		params := jsonutils.NewDict()
 		params.Set("contact_types", jsonutils.NewArray(jsonutils.NewString(input.ContactType)))
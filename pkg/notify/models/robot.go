@@ -205,7 +205,7 @@ func (rm *SRobotManager) ValidateCreateData(ctx context.Context, userCred mcclie
 		return input, errors.Wrap(err, "SSharableVirtualResourceBaseManager.ValidateCreateData")
 	}
 	// check type
-	if !utils.IsInStringArray(input.Type, []string{api.ROBOT_TYPE_FEISHU, api.ROBOT_TYPE_WORKWX, api.ROBOT_TYPE_DINGTALK, api.ROBOT_TYPE_WEBHOOK}) {
+	if !utils.IsInStringArray(input.Type, []string{api.ROBOT_TYPE_FEISHU, api.ROBOT_TYPE_WORKWX, api.ROBOT_TYPE_DINGTALK, api.ROBOT_TYPE_WEBHOOK, api.ROBOT_TYPE_SLACK, api.ROBOT_TYPE_TEAMS, api.ROBOT_TYPE_MATRIX}) {
 		return input, httperrors.NewInputParameterError("unkown type %q", input.Type)
 	}
 	// check lang
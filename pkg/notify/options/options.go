@@ -33,6 +33,10 @@ type NotifyOption struct {
 
 	VerifyExpireInterval int `help:"expire interval of verify message; minutes" default:"2"`
 	VerifyValidInterval  int `help:"valid interval of verify message; miniutes" default:"20"`
+
+	SenderRateLimit          float64 `help:"max messages per second sent to one slack, teams or matrix channel" default:"1"`
+	SenderRateBurst          int     `help:"burst of messages sent to one slack, teams or matrix channel" default:"5"`
+	SenderRateMaxWaitSeconds int     `help:"max seconds to wait for the rate limit of channel before the message is left to resend" default:"30"`
 }
 
 var Options NotifyOption
@@ -218,6 +218,11 @@ func (self *SRpcService) ContactByMobile(ctx context.Context, mobile, serviceNam
 	return "", err
 }
 
+// ContactByEmail isn't supported by rpc send services, which only look up contacts by mobile.
+func (self *SRpcService) ContactByEmail(ctx context.Context, email, serviceName string, domainId string) (string, error) {
+	return "", errors.Wrapf(errors.ErrNotImplemented, "contact of %s by email", serviceName)
+}
+
 // Wrap function to execute function call rpc server
 func (self *SRpcService) execute(ctx context.Context, f func(client *apis.SendNotificationClient) (interface{}, error),
 	serviceName string) (interface{}, error) {
@@ -0,0 +1,104 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sender
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/util/stringutils"
+
+	api "yunion.io/x/onecloud/pkg/apis/notify"
+	"yunion.io/x/onecloud/pkg/notify"
+	"yunion.io/x/onecloud/pkg/util/httputils"
+)
+
+const (
+	MATRIX_CONFIG_HOMESERVER   = "homeserver"
+	MATRIX_CONFIG_ACCESS_TOKEN = "access_token"
+)
+
+func init() {
+	Register(&SMatrixSender{})
+}
+
+// SMatrixSender sends m.room.message event to the room with access token of matrix config
+type SMatrixSender struct{}
+
+type matrixMessage struct {
+	MsgType       string `json:"msgtype"`
+	Body          string `json:"body"`
+	Format        string `json:"format"`
+	FormattedBody string `json:"formatted_body"`
+}
+
+func (s *SMatrixSender) GetContactType() string {
+	return api.MATRIX_ROBOT
+}
+
+func (s *SMatrixSender) GetConfigType() string {
+	return api.MATRIX
+}
+
+func (s *SMatrixSender) message(msg *SMessage) *matrixMessage {
+	return &matrixMessage{
+		MsgType: "m.text",
+		Body:    fmt.Sprintf("%s\n%s", msg.Title, msg.Content),
+		Format:  "org.matrix.custom.html",
+		FormattedBody: fmt.Sprintf(`<h4><font color="%s">%s</font></h4><p>%s</p>`,
+			priorityColor(msg.Priority), htmlContent(msg.Title), htmlContent(msg.Content)),
+	}
+}
+
+func (s *SMatrixSender) clientUrl(config map[string]string, path string) (string, http.Header, error) {
+	homeserver, token := config[MATRIX_CONFIG_HOMESERVER], config[MATRIX_CONFIG_ACCESS_TOKEN]
+	if len(homeserver) == 0 || len(token) == 0 {
+		return "", nil, errors.Wrapf(notify.ErrIncompleteConfig, "need %s and %s of matrix config", MATRIX_CONFIG_HOMESERVER, MATRIX_CONFIG_ACCESS_TOKEN)
+	}
+	header := http.Header{}
+	header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	return fmt.Sprintf("%s/_matrix/client/r0/%s", strings.TrimSuffix(homeserver, "/"), path), header, nil
+}
+
+func (s *SMatrixSender) Send(ctx context.Context, config map[string]string, contact string, msg *SMessage) error {
+	// matrix requires a transaction id unique to the access token, no message
+	// id reaches native senders so a resent notification is a new message
+	txnId := fmt.Sprintf("onecloud.%s", stringutils.UUID4())
+	urlStr, header, err := s.clientUrl(config, fmt.Sprintf("rooms/%s/send/m.room.message/%s", url.PathEscape(contact), txnId))
+	if err != nil {
+		return err
+	}
+	err = doRequest(ctx, httputils.PUT, urlStr, header, s.message(msg), nil)
+	if err != nil {
+		return errors.Wrapf(err, "send to matrix room %s", contact)
+	}
+	return nil
+}
+
+func (s *SMatrixSender) ValidateConfig(ctx context.Context, config map[string]string) (bool, string, error) {
+	urlStr, header, err := s.clientUrl(config, "account/whoami")
+	if err != nil {
+		return false, err.Error(), nil
+	}
+	err = doRequest(ctx, httputils.GET, urlStr, header, nil, nil)
+	if err != nil {
+		return false, err.Error(), nil
+	}
+	return true, "", nil
+}
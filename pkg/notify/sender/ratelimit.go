@@ -0,0 +1,125 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sender
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"yunion.io/x/pkg/errors"
+)
+
+// limiters idle for this long are evicted, unless they are still refilling
+const channelLimiterIdleTimeout = 10 * time.Minute
+
+// sChannelLimiter limits the sending rate of each channel, a channel is one contact of one contact type
+type sChannelLimiter struct {
+	limit   rate.Limit
+	burst   int
+	maxWait time.Duration
+	// a limiter idle for longer than idleTimeout is full and the same as a new one
+	idleTimeout time.Duration
+
+	lock      sync.Mutex
+	limiters  map[string]*sChannel
+	lastEvict time.Time
+}
+
+type sChannel struct {
+	limiter  *rate.Limiter
+	lastUsed time.Time
+}
+
+func newChannelLimiter(perSecond float64, burst int, maxWait time.Duration) *sChannelLimiter {
+	limit := rate.Inf
+	if perSecond > 0 {
+		limit = rate.Limit(perSecond)
+	}
+	if burst <= 0 {
+		burst = 1
+	}
+	idleTimeout := channelLimiterIdleTimeout
+	if limit != rate.Inf {
+		if refill := time.Duration(float64(burst) / perSecond * float64(time.Second)); refill > idleTimeout {
+			idleTimeout = refill
+		}
+	}
+	return &sChannelLimiter{
+		limit:       limit,
+		burst:       burst,
+		maxWait:     maxWait,
+		idleTimeout: idleTimeout,
+		limiters:    make(map[string]*sChannel),
+		lastEvict:   time.Now(),
+	}
+}
+
+func (l *sChannelLimiter) get(contactType, contact string) *rate.Limiter {
+	key := fmt.Sprintf("%s/%s", contactType, contact)
+	now := time.Now()
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.evict(now)
+	channel, ok := l.limiters[key]
+	if !ok {
+		channel = &sChannel{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.limiters[key] = channel
+	}
+	channel.lastUsed = now
+	return channel.limiter
+}
+
+// evict removes the idle limiters, it runs at most once per idleTimeout
+func (l *sChannelLimiter) evict(now time.Time) {
+	if now.Sub(l.lastEvict) < l.idleTimeout {
+		return
+	}
+	for key, channel := range l.limiters {
+		if now.Sub(channel.lastUsed) >= l.idleTimeout {
+			delete(l.limiters, key)
+		}
+	}
+	l.lastEvict = now
+}
+
+// Wait blocks until the channel is allowed to send or ctx is done, the message
+// is rejected if it has to wait longer than maxWait, and it will be resent later.
+func (l *sChannelLimiter) Wait(ctx context.Context, contactType, contact string) error {
+	r := l.get(contactType, contact).Reserve()
+	if !r.OK() {
+		return errors.Wrapf(ErrRateLimited, "channel %s/%s", contactType, contact)
+	}
+	delay := r.Delay()
+	if delay > l.maxWait {
+		r.Cancel()
+		return errors.Wrapf(ErrRateLimited, "channel %s/%s needs to wait %s", contactType, contact, delay)
+	}
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		r.Cancel()
+		return errors.Wrapf(ctx.Err(), "wait for channel %s/%s", contactType, contact)
+	}
+}
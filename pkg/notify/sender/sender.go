@@ -0,0 +1,161 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sender
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/notify"
+	"yunion.io/x/onecloud/pkg/util/httputils"
+)
+
+const (
+	ErrRateLimited = errors.Error("rate limited")
+)
+
+// SMessage is the message filled with event templates
+type SMessage struct {
+	Title    string
+	Content  string
+	Priority string
+}
+
+// ISender sends messages of one contact type natively in notify service instead of through rpc send services
+type ISender interface {
+	GetContactType() string
+	// GetConfigType returns the type of notify config the sender requires,
+	// empty means the contact itself carries everything needed, such as a webhook url
+	GetConfigType() string
+	Send(ctx context.Context, config map[string]string, contact string, msg *SMessage) error
+	ValidateConfig(ctx context.Context, config map[string]string) (bool, string, error)
+}
+
+// IContactResolver resolves contact by email for contact types of receivers
+type IContactResolver interface {
+	ContactByEmail(ctx context.Context, config map[string]string, email string) (string, error)
+}
+
+var senders = make(map[string]ISender)
+
+func Register(s ISender) {
+	senders[s.GetContactType()] = s
+}
+
+func GetSender(contactType string) (ISender, bool) {
+	s, ok := senders[contactType]
+	return s, ok
+}
+
+var robotContactTypes = map[string]string{
+	api.ROBOT_TYPE_SLACK:  api.SLACK_ROBOT,
+	api.ROBOT_TYPE_TEAMS:  api.TEAMS_ROBOT,
+	api.ROBOT_TYPE_MATRIX: api.MATRIX_ROBOT,
+}
+
+// RobotContactType returns the native contact type of robot type
+func RobotContactType(rType string) (string, bool) {
+	ct, ok := robotContactTypes[rType]
+	return ct, ok
+}
+
+// isWebhookContact tells whether the contact is a webhook url rather than an id
+func isWebhookContact(contact string) bool {
+	return strings.HasPrefix(contact, "https://") || strings.HasPrefix(contact, "http://")
+}
+
+func priorityColor(priority string) string {
+	switch priority {
+	case api.NOTIFICATION_PRIORITY_CRITICAL:
+		return "#d32f2f"
+	case api.NOTIFICATION_PRIORITY_IMPORTANT:
+		return "#f57c00"
+	default:
+		return "#1976d2"
+	}
+}
+
+// htmlContent renders the plain text template result as html paragraphs
+func htmlContent(content string) string {
+	lines := strings.Split(strings.TrimSpace(content), "\n")
+	for i := range lines {
+		lines[i] = html.EscapeString(lines[i])
+	}
+	return strings.Join(lines, "<br/>")
+}
+
+type httpStatusError struct {
+	StatusCode int
+	RetryAfter string
+	Body       string
+}
+
+func (e *httpStatusError) Error() string {
+	if e.StatusCode == 429 {
+		return fmt.Sprintf("%s: status 429, retry after %q", ErrRateLimited, e.RetryAfter)
+	}
+	return fmt.Sprintf("status %d: %s", e.StatusCode, e.Body)
+}
+
+var httpClient = httputils.GetTimeoutClient(30 * time.Second)
+
+// doRequest sends body as json and decodes the json response into output if it isn't nil
+func doRequest(ctx context.Context, method httputils.THttpMethod, urlStr string, header http.Header, body interface{}, output interface{}) error {
+	if header == nil {
+		header = http.Header{}
+	}
+	var reader *bytes.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return errors.Wrap(err, "json.Marshal")
+		}
+		reader = bytes.NewReader(data)
+		header.Set("Content-Type", "application/json")
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+	resp, err := httputils.Request(httpClient, ctx, method, urlStr, header, reader, false)
+	if err != nil {
+		return err
+	}
+	defer httputils.CloseResponse(resp)
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrap(err, "read response")
+	}
+	if resp.StatusCode/100 != 2 {
+		return &httpStatusError{
+			StatusCode: resp.StatusCode,
+			RetryAfter: resp.Header.Get("Retry-After"),
+			Body:       string(data),
+		}
+	}
+	if output != nil && len(data) > 0 {
+		if err := json.Unmarshal(data, output); err != nil {
+			return errors.Wrapf(err, "unmarshal response %q", string(data))
+		}
+	}
+	return nil
+}
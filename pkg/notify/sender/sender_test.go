@@ -0,0 +1,259 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sender
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/notify"
+	notifyv2 "yunion.io/x/onecloud/pkg/notify"
+	"yunion.io/x/onecloud/pkg/notify/rpc/apis"
+)
+
+type sRequest struct {
+	Method string
+	Path   string
+	Auth   string
+	Body   map[string]interface{}
+}
+
+func newTestServer(response string, reqs *[]sRequest) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := sRequest{
+			Method: r.Method,
+			Path:   r.URL.Path,
+			Auth:   r.Header.Get("Authorization"),
+		}
+		data, _ := ioutil.ReadAll(r.Body)
+		if len(data) > 0 {
+			json.Unmarshal(data, &req.Body)
+		}
+		*reqs = append(*reqs, req)
+		w.Write([]byte(response))
+	}))
+}
+
+type sFakeConfigStore struct {
+	configs map[string][]notifyv2.SConfig
+}
+
+func (s *sFakeConfigStore) GetConfigs(service string) ([]notifyv2.SConfig, error) {
+	return s.configs[service], nil
+}
+
+func (s *sFakeConfigStore) SetConfig(service string, config notifyv2.SConfig) error {
+	return nil
+}
+
+type sFakeRpcService struct {
+	notifyv2.INotifyService
+
+	sent []string
+}
+
+func (s *sFakeRpcService) BatchSend(ctx context.Context, contactType string, args apis.BatchSendParams) ([]*apis.FailedRecord, error) {
+	s.sent = append(s.sent, contactType)
+	return nil, nil
+}
+
+func TestSlackSender(t *testing.T) {
+	ctx := context.Background()
+	msg := &SMessage{Title: "alert", Content: "cpu > 90%", Priority: api.NOTIFICATION_PRIORITY_CRITICAL}
+	s, _ := GetSender(api.SLACK)
+
+	Convey("Test slack sender", t, func() {
+		reqs := []sRequest{}
+		Convey("Send through webhook", func() {
+			srv := newTestServer("ok", &reqs)
+			defer srv.Close()
+			err := s.Send(ctx, nil, srv.URL+"/services/T/B/X", msg)
+			So(err, ShouldBeNil)
+			So(reqs, ShouldHaveLength, 1)
+			So(reqs[0].Path, ShouldEqual, "/services/T/B/X")
+			So(reqs[0].Auth, ShouldEqual, "")
+			So(reqs[0].Body["text"], ShouldEqual, "alert")
+			attachment := reqs[0].Body["attachments"].([]interface{})[0].(map[string]interface{})
+			So(attachment["color"], ShouldEqual, "#d32f2f")
+		})
+
+		Convey("Send with bot token", func() {
+			srv := newTestServer(`{"ok":true}`, &reqs)
+			defer srv.Close()
+			slackApiUrl = srv.URL
+			err := s.Send(ctx, map[string]string{SLACK_CONFIG_TOKEN: "xoxb"}, "C123", msg)
+			So(err, ShouldBeNil)
+			So(reqs[0].Path, ShouldEqual, "/chat.postMessage")
+			So(reqs[0].Auth, ShouldEqual, "Bearer xoxb")
+			So(reqs[0].Body["channel"], ShouldEqual, "C123")
+
+			err = s.Send(ctx, nil, "C123", msg)
+			So(errors.Cause(err), ShouldEqual, notifyv2.ErrIncompleteConfig)
+		})
+
+		Convey("Slack api error", func() {
+			srv := newTestServer(`{"ok":false,"error":"users_not_found"}`, &reqs)
+			defer srv.Close()
+			slackApiUrl = srv.URL
+			_, err := s.(IContactResolver).ContactByEmail(ctx, map[string]string{SLACK_CONFIG_TOKEN: "xoxb"}, "a@b.com")
+			So(errors.Cause(err), ShouldEqual, notifyv2.ErrNoSuchEmail)
+		})
+	})
+}
+
+func TestTeamsAndMatrixSender(t *testing.T) {
+	ctx := context.Background()
+	msg := &SMessage{Title: "alert", Content: "line1\nline2 <b>"}
+
+	Convey("Test teams and matrix sender", t, func() {
+		reqs := []sRequest{}
+		srv := newTestServer("{}", &reqs)
+		defer srv.Close()
+
+		Convey("Teams message card", func() {
+			s, _ := GetSender(api.TEAMS_ROBOT)
+			err := s.Send(ctx, nil, srv.URL+"/webhook", msg)
+			So(err, ShouldBeNil)
+			So(reqs[0].Body["@type"], ShouldEqual, "MessageCard")
+			So(reqs[0].Body["themeColor"], ShouldEqual, "1976d2")
+			So(reqs[0].Body["text"], ShouldEqual, "line1\n\nline2 <b>")
+
+			err = s.Send(ctx, nil, "not-a-url", msg)
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("Matrix room message", func() {
+			s, _ := GetSender(api.MATRIX_ROBOT)
+			config := map[string]string{
+				MATRIX_CONFIG_HOMESERVER:   srv.URL + "/",
+				MATRIX_CONFIG_ACCESS_TOKEN: "syt",
+			}
+			err := s.Send(ctx, config, "!room:example.org", msg)
+			So(err, ShouldBeNil)
+			So(reqs[0].Method, ShouldEqual, "PUT")
+			So(strings.HasPrefix(reqs[0].Path, "/_matrix/client/r0/rooms/!room:example.org/send/m.room.message/"), ShouldBeTrue)
+			So(reqs[0].Auth, ShouldEqual, "Bearer syt")
+			So(reqs[0].Body["formatted_body"], ShouldContainSubstring, "line1<br/>line2 &lt;b&gt;")
+
+			// every send is a new transaction
+			err = s.Send(ctx, config, "!room:example.org", msg)
+			So(err, ShouldBeNil)
+			So(reqs[1].Path, ShouldNotEqual, reqs[0].Path)
+
+			err = s.Send(ctx, map[string]string{}, "!room:example.org", msg)
+			So(errors.Cause(err), ShouldEqual, notifyv2.ErrIncompleteConfig)
+		})
+	})
+}
+
+func TestChannelLimiter(t *testing.T) {
+	Convey("Test channel limiter", t, func() {
+		ctx := context.Background()
+		l := newChannelLimiter(1, 2, 10*time.Millisecond)
+		So(l.Wait(ctx, api.SLACK, "C1"), ShouldBeNil)
+		So(l.Wait(ctx, api.SLACK, "C1"), ShouldBeNil)
+		So(errors.Cause(l.Wait(ctx, api.SLACK, "C1")), ShouldEqual, ErrRateLimited)
+		// channels are limited separately
+		So(l.Wait(ctx, api.SLACK, "C2"), ShouldBeNil)
+
+		Convey("Waiting is stopped by context", func() {
+			l := newChannelLimiter(1, 1, time.Hour)
+			So(l.Wait(ctx, api.SLACK, "C1"), ShouldBeNil)
+			cctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+			defer cancel()
+			start := time.Now()
+			err := l.Wait(cctx, api.SLACK, "C1")
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, context.DeadlineExceeded.Error())
+			So(time.Since(start), ShouldBeLessThan, 500*time.Millisecond)
+		})
+
+		Convey("Idle channels are evicted", func() {
+			l.idleTimeout = 10 * time.Millisecond
+			time.Sleep(20 * time.Millisecond)
+			So(l.Wait(ctx, api.SLACK, "C3"), ShouldBeNil)
+			So(l.limiters, ShouldHaveLength, 1)
+			So(l.limiters, ShouldContainKey, api.SLACK+"/C3")
+		})
+	})
+}
+
+func TestNativeSendService(t *testing.T) {
+	ctx := context.Background()
+
+	Convey("Test native send service", t, func() {
+		reqs := []sRequest{}
+		srv := newTestServer(`{"ok":true}`, &reqs)
+		defer srv.Close()
+		slackApiUrl = srv.URL
+
+		rpc := &sFakeRpcService{}
+		store := &sFakeConfigStore{
+			configs: map[string][]notifyv2.SConfig{
+				api.SLACK: {
+					{Config: map[string]string{SLACK_CONFIG_TOKEN: "system"}},
+					{Config: map[string]string{SLACK_CONFIG_TOKEN: "domain"}, DomainId: "d1"},
+				},
+			},
+		}
+		service := NewNativeSendService(rpc, store, SNativeSendOptions{MaxWait: time.Second})
+
+		Convey("Non native contact types are delegated", func() {
+			_, err := service.BatchSend(ctx, api.EMAIL, apis.BatchSendParams{})
+			So(err, ShouldBeNil)
+			So(rpc.sent, ShouldResemble, []string{api.EMAIL})
+			So(reqs, ShouldBeEmpty)
+		})
+
+		Convey("Domain config is preferred", func() {
+			failed, err := service.BatchSend(ctx, api.SLACK, apis.BatchSendParams{
+				Receivers: []*apis.SReceiver{
+					{Contact: "U1", DomainId: "d1"},
+					{Contact: "U2", DomainId: "d2"},
+				},
+				Title: "alert",
+			})
+			So(err, ShouldBeNil)
+			So(failed, ShouldBeEmpty)
+			So(reqs, ShouldHaveLength, 2)
+			So(reqs[0].Auth, ShouldEqual, "Bearer domain")
+			So(reqs[1].Auth, ShouldEqual, "Bearer system")
+		})
+
+		Convey("Robot webhooks need no config", func() {
+			store.configs = nil
+			failed, err := service.SendRobotMessage(ctx, api.ROBOT_TYPE_SLACK, []*apis.SReceiver{
+				{Contact: srv.URL + "/services/T/B/X"},
+				{Contact: "C123"},
+			}, "alert", "cpu > 90%")
+			So(err, ShouldBeNil)
+			So(reqs, ShouldHaveLength, 1)
+			So(reqs[0].Path, ShouldEqual, "/services/T/B/X")
+			// channel ids still need the bot token
+			So(failed, ShouldHaveLength, 1)
+			So(failed[0].Receiver.Contact, ShouldEqual, "C123")
+		})
+	})
+}
@@ -0,0 +1,206 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sender
+
+import (
+	"context"
+	"time"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	notifyv2 "yunion.io/x/onecloud/pkg/notify"
+	"yunion.io/x/onecloud/pkg/notify/rpc/apis"
+)
+
+// SNativeSendService sends messages of native contact types such as slack, teams and matrix by itself,
+// and passes the others to the rpc send services.
+type SNativeSendService struct {
+	notifyv2.INotifyService
+
+	configStore notifyv2.IServiceConfigStore
+	limiter     *sChannelLimiter
+}
+
+type SNativeSendOptions struct {
+	// RateLimit is the max messages per second sent to one channel
+	RateLimit float64
+	RateBurst int
+	// MaxWait is the max time to wait for rate limit before giving up
+	MaxWait time.Duration
+}
+
+func NewNativeSendService(rpcService notifyv2.INotifyService, configStore notifyv2.IServiceConfigStore, opts SNativeSendOptions) *SNativeSendService {
+	return &SNativeSendService{
+		INotifyService: rpcService,
+		configStore:    configStore,
+		limiter:        newChannelLimiter(opts.RateLimit, opts.RateBurst, opts.MaxWait),
+	}
+}
+
+// getConfig returns config of domain and falls back to the system config
+func (self *SNativeSendService) getConfig(s ISender, domainId string) (map[string]string, error) {
+	configType := s.GetConfigType()
+	if len(configType) == 0 {
+		return nil, nil
+	}
+	configs, err := self.configStore.GetConfigs(configType)
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetConfigs of %s", configType)
+	}
+	var system map[string]string
+	for i := range configs {
+		if len(domainId) > 0 && configs[i].DomainId == domainId {
+			return configs[i].Config, nil
+		}
+		if len(configs[i].DomainId) == 0 {
+			system = configs[i].Config
+		}
+	}
+	if system == nil {
+		return nil, errors.Wrapf(notifyv2.ErrIncompleteConfig, "no %s config for domain %q", configType, domainId)
+	}
+	return system, nil
+}
+
+func (self *SNativeSendService) send(ctx context.Context, s ISender, receiver *apis.SReceiver, msg *SMessage) error {
+	var config map[string]string
+	// webhook urls carry everything needed and don't require any config
+	if !isWebhookContact(receiver.Contact) {
+		var err error
+		config, err = self.getConfig(s, receiver.DomainId)
+		if err != nil {
+			return err
+		}
+	}
+	err := self.limiter.Wait(ctx, s.GetContactType(), receiver.Contact)
+	if err != nil {
+		return err
+	}
+	return s.Send(ctx, config, receiver.Contact, msg)
+}
+
+func (self *SNativeSendService) batchSend(ctx context.Context, s ISender, receivers []*apis.SReceiver, msg *SMessage) []*apis.FailedRecord {
+	ret := make([]*apis.FailedRecord, 0)
+	for _, receiver := range receivers {
+		if receiver == nil {
+			continue
+		}
+		err := self.send(ctx, s, receiver, msg)
+		if err != nil {
+			log.Errorf("send %s message to %s: %v", s.GetContactType(), receiver.Contact, err)
+			ret = append(ret, &apis.FailedRecord{
+				Receiver: receiver,
+				Reason:   err.Error(),
+			})
+		}
+	}
+	return ret
+}
+
+func (self *SNativeSendService) Send(ctx context.Context, contactType string, args apis.SendParams) error {
+	s, ok := GetSender(contactType)
+	if !ok {
+		return self.INotifyService.Send(ctx, contactType, args)
+	}
+	return self.send(ctx, s, args.Receiver, &SMessage{
+		Title:    args.Title,
+		Content:  args.Message,
+		Priority: args.Priority,
+	})
+}
+
+func (self *SNativeSendService) BatchSend(ctx context.Context, contactType string, args apis.BatchSendParams) ([]*apis.FailedRecord, error) {
+	s, ok := GetSender(contactType)
+	if !ok {
+		return self.INotifyService.BatchSend(ctx, contactType, args)
+	}
+	return self.batchSend(ctx, s, args.Receivers, &SMessage{
+		Title:    args.Title,
+		Content:  args.Message,
+		Priority: args.Priority,
+	}), nil
+}
+
+func (self *SNativeSendService) SendRobotMessage(ctx context.Context, rType string, receivers []*apis.SReceiver, title string, message string) ([]*apis.FailedRecord, error) {
+	contactType, ok := RobotContactType(rType)
+	if !ok {
+		return self.INotifyService.SendRobotMessage(ctx, rType, receivers, title, message)
+	}
+	s, ok := GetSender(contactType)
+	if !ok {
+		return nil, errors.Wrapf(errors.ErrNotSupported, "robot type %s", rType)
+	}
+	return self.batchSend(ctx, s, receivers, &SMessage{
+		Title:   title,
+		Content: message,
+	}), nil
+}
+
+func (self *SNativeSendService) ValidateConfig(ctx context.Context, cType string, configs map[string]string) (bool, string, error) {
+	for _, s := range senders {
+		if s.GetConfigType() == cType {
+			return s.ValidateConfig(ctx, configs)
+		}
+	}
+	return self.INotifyService.ValidateConfig(ctx, cType, configs)
+}
+
+// configs of native senders are read from config store on sending, so there is nothing to sync
+func (self *SNativeSendService) isNativeConfig(cType string) bool {
+	for _, s := range senders {
+		if s.GetConfigType() == cType {
+			return true
+		}
+	}
+	return false
+}
+
+func (self *SNativeSendService) AddConfig(ctx context.Context, service string, config notifyv2.SConfig) error {
+	if self.isNativeConfig(service) {
+		return nil
+	}
+	return self.INotifyService.AddConfig(ctx, service, config)
+}
+
+func (self *SNativeSendService) UpdateConfig(ctx context.Context, service string, config notifyv2.SConfig) error {
+	if self.isNativeConfig(service) {
+		return nil
+	}
+	return self.INotifyService.UpdateConfig(ctx, service, config)
+}
+
+func (self *SNativeSendService) DeleteConfig(ctx context.Context, service, domainId string) error {
+	if self.isNativeConfig(service) {
+		return nil
+	}
+	return self.INotifyService.DeleteConfig(ctx, service, domainId)
+}
+
+func (self *SNativeSendService) ContactByEmail(ctx context.Context, email, serviceName, domainId string) (string, error) {
+	s, ok := GetSender(serviceName)
+	if !ok {
+		return self.INotifyService.ContactByEmail(ctx, email, serviceName, domainId)
+	}
+	resolver, ok := s.(IContactResolver)
+	if !ok {
+		return "", errors.Wrapf(errors.ErrNotImplemented, "contact of %s by email", serviceName)
+	}
+	config, err := self.getConfig(s, domainId)
+	if err != nil {
+		return "", err
+	}
+	return resolver.ContactByEmail(ctx, config, email)
+}
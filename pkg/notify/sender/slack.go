@@ -0,0 +1,182 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sender
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/notify"
+	"yunion.io/x/onecloud/pkg/notify"
+	"yunion.io/x/onecloud/pkg/util/httputils"
+)
+
+const (
+	SLACK_CONFIG_TOKEN = "token"
+
+	slackHeaderMaxLen = 150
+)
+
+var slackApiUrl = "https://slack.com/api"
+
+func init() {
+	Register(&SSlackSender{contactType: api.SLACK})
+	Register(&SSlackSender{contactType: api.SLACK_ROBOT})
+}
+
+// SSlackSender sends messages through incoming webhook if the contact is a url,
+// otherwise the contact is treated as the channel or member id and sent with the bot token
+type SSlackSender struct {
+	contactType string
+}
+
+type slackApiResponse struct {
+	Ok    bool   `json:"ok"`
+	Error string `json:"error"`
+	User  struct {
+		Id string `json:"id"`
+	} `json:"user"`
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type slackBlock struct {
+	Type     string       `json:"type"`
+	Text     *slackText   `json:"text,omitempty"`
+	Elements []*slackText `json:"elements,omitempty"`
+}
+
+type slackAttachment struct {
+	Color  string        `json:"color"`
+	Blocks []*slackBlock `json:"blocks"`
+}
+
+type slackMessage struct {
+	Channel     string             `json:"channel,omitempty"`
+	Text        string             `json:"text"`
+	Blocks      []*slackBlock      `json:"blocks,omitempty"`
+	Attachments []*slackAttachment `json:"attachments,omitempty"`
+}
+
+func (s *SSlackSender) GetContactType() string {
+	return s.contactType
+}
+
+func (s *SSlackSender) GetConfigType() string {
+	return api.SLACK
+}
+
+func slackEscape(text string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+}
+
+func (s *SSlackSender) message(msg *SMessage) *slackMessage {
+	title := msg.Title
+	if len([]rune(title)) > slackHeaderMaxLen {
+		title = string([]rune(title)[:slackHeaderMaxLen-3]) + "..."
+	}
+	return &slackMessage{
+		Text: msg.Title,
+		Blocks: []*slackBlock{
+			{
+				Type: "header",
+				Text: &slackText{Type: "plain_text", Text: title},
+			},
+		},
+		Attachments: []*slackAttachment{
+			{
+				Color: priorityColor(msg.Priority),
+				Blocks: []*slackBlock{
+					{
+						Type: "section",
+						Text: &slackText{Type: "mrkdwn", Text: slackEscape(msg.Content)},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (s *SSlackSender) Send(ctx context.Context, config map[string]string, contact string, msg *SMessage) error {
+	body := s.message(msg)
+	if isWebhookContact(contact) {
+		// incoming webhook responds plain text "ok"
+		err := doRequest(ctx, httputils.POST, contact, nil, body, nil)
+		if err != nil {
+			return errors.Wrap(err, "send to slack webhook")
+		}
+		return nil
+	}
+	token := config[SLACK_CONFIG_TOKEN]
+	if len(token) == 0 {
+		return errors.Wrapf(notify.ErrIncompleteConfig, "missing %s of slack config", SLACK_CONFIG_TOKEN)
+	}
+	body.Channel = contact
+	return s.callApi(ctx, token, httputils.POST, "chat.postMessage", body, nil)
+}
+
+func (s *SSlackSender) callApi(ctx context.Context, token string, method httputils.THttpMethod, apiName string, body interface{}, output *slackApiResponse) error {
+	header := http.Header{}
+	header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	resp := &slackApiResponse{}
+	err := doRequest(ctx, method, fmt.Sprintf("%s/%s", slackApiUrl, apiName), header, body, resp)
+	if err != nil {
+		return errors.Wrapf(err, "call slack api %s", apiName)
+	}
+	if !resp.Ok {
+		return errors.Errorf("slack api %s: %s", apiName, resp.Error)
+	}
+	if output != nil {
+		*output = *resp
+	}
+	return nil
+}
+
+func (s *SSlackSender) ValidateConfig(ctx context.Context, config map[string]string) (bool, string, error) {
+	token := config[SLACK_CONFIG_TOKEN]
+	if len(token) == 0 {
+		return false, fmt.Sprintf("missing %s", SLACK_CONFIG_TOKEN), nil
+	}
+	err := s.callApi(ctx, token, httputils.POST, "auth.test", nil, nil)
+	if err != nil {
+		return false, err.Error(), nil
+	}
+	return true, "", nil
+}
+
+// ContactByEmail looks up the slack member id of email
+func (s *SSlackSender) ContactByEmail(ctx context.Context, config map[string]string, email string) (string, error) {
+	token := config[SLACK_CONFIG_TOKEN]
+	if len(token) == 0 {
+		return "", errors.Wrapf(notify.ErrIncompleteConfig, "missing %s of slack config", SLACK_CONFIG_TOKEN)
+	}
+	resp := slackApiResponse{}
+	err := s.callApi(ctx, token, httputils.GET, "users.lookupByEmail?email="+url.QueryEscape(email), nil, &resp)
+	if err != nil {
+		if strings.Contains(err.Error(), "users_not_found") {
+			return "", errors.Wrap(notify.ErrNoSuchEmail, email)
+		}
+		return "", err
+	}
+	return resp.User.Id, nil
+}
@@ -0,0 +1,76 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sender
+
+import (
+	"context"
+	"strings"
+
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/notify"
+	"yunion.io/x/onecloud/pkg/util/httputils"
+)
+
+func init() {
+	Register(&STeamsSender{})
+}
+
+// STeamsSender sends MessageCard to the incoming webhook of Microsoft Teams connector
+type STeamsSender struct{}
+
+type teamsMessageCard struct {
+	Type       string `json:"@type"`
+	Context    string `json:"@context"`
+	Summary    string `json:"summary"`
+	ThemeColor string `json:"themeColor"`
+	Title      string `json:"title"`
+	Text       string `json:"text"`
+}
+
+func (s *STeamsSender) GetContactType() string {
+	return api.TEAMS_ROBOT
+}
+
+func (s *STeamsSender) GetConfigType() string {
+	return ""
+}
+
+func (s *STeamsSender) message(msg *SMessage) *teamsMessageCard {
+	return &teamsMessageCard{
+		Type:       "MessageCard",
+		Context:    "http://schema.org/extensions",
+		Summary:    msg.Title,
+		ThemeColor: strings.TrimPrefix(priorityColor(msg.Priority), "#"),
+		Title:      msg.Title,
+		// text of message card is markdown, a single newline is ignored
+		Text: strings.ReplaceAll(strings.TrimSpace(msg.Content), "\n", "\n\n"),
+	}
+}
+
+func (s *STeamsSender) Send(ctx context.Context, config map[string]string, contact string, msg *SMessage) error {
+	if !strings.HasPrefix(contact, "https://") && !strings.HasPrefix(contact, "http://") {
+		return errors.Errorf("invalid teams connector url %q", contact)
+	}
+	err := doRequest(ctx, httputils.POST, contact, nil, s.message(msg), nil)
+	if err != nil {
+		return errors.Wrap(err, "send to teams connector")
+	}
+	return nil
+}
+
+func (s *STeamsSender) ValidateConfig(ctx context.Context, config map[string]string) (bool, string, error) {
+	return true, "", nil
+}
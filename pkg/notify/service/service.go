@@ -31,6 +31,7 @@ import (
 	"yunion.io/x/onecloud/pkg/notify/options"
 	_ "yunion.io/x/onecloud/pkg/notify/policy"
 	"yunion.io/x/onecloud/pkg/notify/rpc"
+	"yunion.io/x/onecloud/pkg/notify/sender"
 	_ "yunion.io/x/onecloud/pkg/notify/tasks"
 )
 
@@ -66,7 +67,15 @@ func StartService() {
 	}
 
 	// init notify service
-	models.NotifyService = rpc.NewSRpcService(opts.SocketFileDir, models.ConfigManager, models.TemplateManager)
+	models.NotifyService = sender.NewNativeSendService(
+		rpc.NewSRpcService(opts.SocketFileDir, models.ConfigManager, models.TemplateManager),
+		models.ConfigManager,
+		sender.SNativeSendOptions{
+			RateLimit: opts.SenderRateLimit,
+			RateBurst: opts.SenderRateBurst,
+			MaxWait:   time.Duration(opts.SenderRateMaxWaitSeconds) * time.Second,
+		},
+	)
 	models.NotifyService.InitAll()
 	defer models.NotifyService.StopAll()
 
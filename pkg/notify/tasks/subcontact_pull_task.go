@@ -36,6 +36,7 @@ var PullContactType = []string{
 	apis.DINGTALK,
 	apis.FEISHU,
 	apis.WORKWX,
+	apis.SLACK,
 }
 
 type SubcontactPullTask struct {
@@ -57,7 +58,7 @@ func (self *SubcontactPullTask) OnInit(ctx context.Context, obj db.IStandaloneMo
 	failedReasons := make([]string, 0)
 	// pull contacts
 	receiver := obj.(*models.SReceiver)
-	if len(receiver.Mobile) == 0 {
+	if len(receiver.Mobile) == 0 && len(receiver.Email) == 0 {
 		self.SetStageComplete(ctx, nil)
 		return
 	}
@@ -72,12 +73,27 @@ func (self *SubcontactPullTask) OnInit(ctx context.Context, obj db.IStandaloneMo
 		if !utils.IsInStringArray(cType, PullContactType) {
 			continue
 		}
-		userid, err := models.NotifyService.ContactByMobile(ctx, receiver.Mobile, cType, receiver.GetDomainId())
+		var userid string
+		var err error
+		if utils.IsInStringArray(cType, models.EmailContactTypes) {
+			if len(receiver.Email) == 0 {
+				continue
+			}
+			userid, err = models.NotifyService.ContactByEmail(ctx, receiver.Email, cType, receiver.GetDomainId())
+		} else {
+			if len(receiver.Mobile) == 0 {
+				continue
+			}
+			userid, err = models.NotifyService.ContactByMobile(ctx, receiver.Mobile, cType, receiver.GetDomainId())
+		}
 		if err != nil {
 			var reason string
 			if errors.Cause(err) == notify.ErrNoSuchMobile {
 				receiver.MarkContactTypeUnVerified(cType, notify.ErrNoSuchMobile.Error())
 				reason = fmt.Sprintf("%q: no such mobile %s", cType, receiver.Mobile)
+			} else if errors.Cause(err) == notify.ErrNoSuchEmail {
+				receiver.MarkContactTypeUnVerified(cType, notify.ErrNoSuchEmail.Error())
+				reason = fmt.Sprintf("%q: no such email %s", cType, receiver.Email)
 			} else if errors.Cause(err) == notify.ErrIncompleteConfig {
 				receiver.MarkContactTypeUnVerified(cType, notify.ErrIncompleteConfig.Error())
 				reason = fmt.Sprintf("%q: %v", cType, err)
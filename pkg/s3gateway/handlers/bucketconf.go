@@ -0,0 +1,553 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/s3cli"
+
+	"yunion.io/x/onecloud/pkg/appsrv"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/s3gateway/models"
+)
+
+type CORSRule struct {
+	ID             string   `xml:"ID,omitempty"`
+	AllowedMethods []string `xml:"AllowedMethod"`
+	AllowedOrigins []string `xml:"AllowedOrigin"`
+	AllowedHeaders []string `xml:"AllowedHeader,omitempty"`
+	MaxAgeSeconds  int      `xml:"MaxAgeSeconds,omitempty"`
+	ExposeHeaders  []string `xml:"ExposeHeader,omitempty"`
+}
+
+type CORSConfiguration struct {
+	XMLName   xml.Name   `xml:"CORSConfiguration"`
+	CORSRules []CORSRule `xml:"CORSRule"`
+}
+
+type WebsiteIndexDocument struct {
+	Suffix string `xml:"Suffix"`
+}
+
+type WebsiteErrorDocument struct {
+	Key string `xml:"Key"`
+}
+
+type WebsiteRoutingRuleCondition struct {
+	HttpErrorCodeReturnedEquals string `xml:"HttpErrorCodeReturnedEquals,omitempty"`
+	KeyPrefixEquals             string `xml:"KeyPrefixEquals,omitempty"`
+}
+
+type WebsiteRoutingRuleRedirect struct {
+	Protocol             string `xml:"Protocol,omitempty"`
+	ReplaceKeyWith       string `xml:"ReplaceKeyWith,omitempty"`
+	ReplaceKeyPrefixWith string `xml:"ReplaceKeyPrefixWith,omitempty"`
+}
+
+type WebsiteRoutingRule struct {
+	Condition *WebsiteRoutingRuleCondition `xml:"Condition,omitempty"`
+	Redirect  WebsiteRoutingRuleRedirect   `xml:"Redirect"`
+}
+
+type WebsiteConfiguration struct {
+	XMLName       xml.Name              `xml:"WebsiteConfiguration"`
+	IndexDocument *WebsiteIndexDocument `xml:"IndexDocument,omitempty"`
+	ErrorDocument *WebsiteErrorDocument `xml:"ErrorDocument,omitempty"`
+	RoutingRules  []WebsiteRoutingRule  `xml:"RoutingRules>RoutingRule,omitempty"`
+}
+
+// BucketTagging is the S3 tagging document, where tags are wrapped by
+// TagSet>Tag elements, unlike s3cli.Tagging
+type BucketTagging struct {
+	XMLName xml.Name    `xml:"Tagging"`
+	Tags    []s3cli.Tag `xml:"TagSet>Tag"`
+}
+
+// sRawDocument is a sub-resource document passed through as is between
+// clients and the bucket provider
+type sRawDocument struct {
+	ContentType string
+	Body        []byte
+}
+
+func newRawXmlDocument(conf string) *sRawDocument {
+	return &sRawDocument{
+		ContentType: "application/xml;charset=utf-8",
+		Body:        []byte(conf),
+	}
+}
+
+func newRawJsonDocument(doc string) *sRawDocument {
+	return &sRawDocument{
+		ContentType: "application/json",
+		Body:        []byte(doc),
+	}
+}
+
+func sendBucketResponse(w http.ResponseWriter, hdr http.Header, resp interface{}) {
+	doc, ok := resp.(*sRawDocument)
+	if !ok {
+		appsrv.SendXml(w, hdr, resp)
+		return
+	}
+	for k, v := range hdr {
+		if k != "Content-Type" && k != "Content-Length" && len(v) > 0 {
+			w.Header().Set(k, v[0])
+		}
+	}
+	w.Header().Set("Content-Type", doc.ContentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(doc.Body)))
+	w.Write(doc.Body)
+}
+
+// fetchIBucket is a variable so that tests can serve fake buckets
+var fetchIBucket = fetchIBucketByName
+
+func fetchIBucketByName(ctx context.Context, userCred mcclient.TokenCredential, bucketName string) (cloudprovider.ICloudBucket, error) {
+	bucket, err := models.BucketManager.GetByName(ctx, userCred, bucketName)
+	if err != nil {
+		return nil, errors.Wrap(err, "models.BucketManager.GetByName")
+	}
+	iBucket, err := bucket.GetIBucket(ctx, userCred)
+	if err != nil {
+		return nil, errors.Wrap(err, "bucket.GetIBucket")
+	}
+	return iBucket, nil
+}
+
+// bucketConfError tells clients that the provider of the bucket does not
+// support the sub-resource, instead of an internal error
+func bucketConfError(ctx context.Context, err error, op string) error {
+	switch errors.Cause(err) {
+	case cloudprovider.ErrNotImplemented, cloudprovider.ErrNotSupported:
+		return NotImplemented(ctx, fmt.Sprintf("%s is not supported by the bucket provider", op))
+	}
+	return errors.Wrap(err, op)
+}
+
+// fetchXmlConf reads the request body and checks that it is a well-formed
+// xml document with the expected root element
+func fetchXmlConf(ctx context.Context, r *http.Request, root string) (string, error) {
+	body, err := appsrv.Fetch(r)
+	if err != nil {
+		return "", errors.Wrap(httperrors.ErrBadRequest, "Fetch")
+	}
+	doc := struct {
+		XMLName xml.Name
+	}{}
+	err = xml.Unmarshal(body, &doc)
+	if err != nil {
+		return "", MalformedXML(ctx, err.Error())
+	}
+	if doc.XMLName.Local != root {
+		return "", MalformedXML(ctx, fmt.Sprintf("expect %s, got %s", root, doc.XMLName.Local))
+	}
+	return string(body), nil
+}
+
+func fetchXmlConfTo(ctx context.Context, r *http.Request, target interface{}) error {
+	err := appsrv.FetchXml(r, target)
+	if err != nil {
+		return MalformedXML(ctx, err.Error())
+	}
+	return nil
+}
+
+func bucketCORS(ctx context.Context, userCred mcclient.TokenCredential, bucketName string) (*CORSConfiguration, error) {
+	iBucket, err := fetchIBucket(ctx, userCred, bucketName)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := iBucket.GetCORSRules()
+	if err != nil {
+		return nil, bucketConfError(ctx, err, "GetCORSRules")
+	}
+	if len(rules) == 0 {
+		return nil, NoSuchConfiguration(ctx, "NoSuchCORSConfiguration", "The CORS configuration does not exist")
+	}
+	result := &CORSConfiguration{}
+	for i := range rules {
+		result.CORSRules = append(result.CORSRules, CORSRule{
+			ID:             rules[i].Id,
+			AllowedMethods: rules[i].AllowedMethods,
+			AllowedOrigins: rules[i].AllowedOrigins,
+			AllowedHeaders: rules[i].AllowedHeaders,
+			MaxAgeSeconds:  rules[i].MaxAgeSeconds,
+			ExposeHeaders:  rules[i].ExposeHeaders,
+		})
+	}
+	return result, nil
+}
+
+func putBucketCORS(ctx context.Context, userCred mcclient.TokenCredential, bucketName string, r *http.Request) error {
+	conf := CORSConfiguration{}
+	err := fetchXmlConfTo(ctx, r, &conf)
+	if err != nil {
+		return err
+	}
+	if len(conf.CORSRules) == 0 {
+		return MalformedXML(ctx, "no CORSRule")
+	}
+	rules := make([]cloudprovider.SBucketCORSRule, len(conf.CORSRules))
+	for i, rule := range conf.CORSRules {
+		if len(rule.AllowedMethods) == 0 || len(rule.AllowedOrigins) == 0 {
+			return MalformedXML(ctx, "CORSRule must have AllowedMethod and AllowedOrigin")
+		}
+		rules[i] = cloudprovider.SBucketCORSRule{
+			Id:             rule.ID,
+			AllowedMethods: rule.AllowedMethods,
+			AllowedOrigins: rule.AllowedOrigins,
+			AllowedHeaders: rule.AllowedHeaders,
+			MaxAgeSeconds:  rule.MaxAgeSeconds,
+			ExposeHeaders:  rule.ExposeHeaders,
+		}
+	}
+	iBucket, err := fetchIBucket(ctx, userCred, bucketName)
+	if err != nil {
+		return err
+	}
+	// PUT replaces the whole configuration, unlike cloudprovider.SetBucketCORS
+	err = iBucket.SetCORS(rules)
+	if err != nil {
+		return bucketConfError(ctx, err, "SetCORS")
+	}
+	return nil
+}
+
+func deleteBucketCORS(ctx context.Context, userCred mcclient.TokenCredential, bucketName string) error {
+	iBucket, err := fetchIBucket(ctx, userCred, bucketName)
+	if err != nil {
+		return err
+	}
+	err = iBucket.DeleteCORS()
+	if err != nil {
+		return bucketConfError(ctx, err, "DeleteCORS")
+	}
+	return nil
+}
+
+func bucketWebsite(ctx context.Context, userCred mcclient.TokenCredential, bucketName string) (*WebsiteConfiguration, error) {
+	iBucket, err := fetchIBucket(ctx, userCred, bucketName)
+	if err != nil {
+		return nil, err
+	}
+	conf, err := iBucket.GetWebsiteConf()
+	if err != nil {
+		return nil, bucketConfError(ctx, err, "GetWebsiteConf")
+	}
+	if len(conf.Index) == 0 {
+		return nil, NoSuchConfiguration(ctx, "NoSuchWebsiteConfiguration", "The specified bucket does not have a website configuration")
+	}
+	result := &WebsiteConfiguration{
+		IndexDocument: &WebsiteIndexDocument{Suffix: conf.Index},
+	}
+	if len(conf.ErrorDocument) > 0 {
+		result.ErrorDocument = &WebsiteErrorDocument{Key: conf.ErrorDocument}
+	}
+	for _, rule := range conf.Rules {
+		routingRule := WebsiteRoutingRule{
+			Redirect: WebsiteRoutingRuleRedirect{
+				Protocol:             rule.RedirectProtocol,
+				ReplaceKeyWith:       rule.RedirectReplaceKey,
+				ReplaceKeyPrefixWith: rule.RedirectReplaceKeyPrefix,
+			},
+		}
+		if len(rule.ConditionErrorCode) > 0 || len(rule.ConditionPrefix) > 0 {
+			routingRule.Condition = &WebsiteRoutingRuleCondition{
+				HttpErrorCodeReturnedEquals: rule.ConditionErrorCode,
+				KeyPrefixEquals:             rule.ConditionPrefix,
+			}
+		}
+		result.RoutingRules = append(result.RoutingRules, routingRule)
+	}
+	return result, nil
+}
+
+func putBucketWebsite(ctx context.Context, userCred mcclient.TokenCredential, bucketName string, r *http.Request) error {
+	conf := WebsiteConfiguration{}
+	err := fetchXmlConfTo(ctx, r, &conf)
+	if err != nil {
+		return err
+	}
+	if conf.IndexDocument == nil || len(conf.IndexDocument.Suffix) == 0 {
+		return MalformedXML(ctx, "missing IndexDocument")
+	}
+	websiteConf := cloudprovider.SBucketWebsiteConf{
+		Index: conf.IndexDocument.Suffix,
+	}
+	if conf.ErrorDocument != nil {
+		websiteConf.ErrorDocument = conf.ErrorDocument.Key
+	}
+	for _, rule := range conf.RoutingRules {
+		websiteRule := cloudprovider.SBucketWebsiteRoutingRule{
+			RedirectProtocol:         rule.Redirect.Protocol,
+			RedirectReplaceKey:       rule.Redirect.ReplaceKeyWith,
+			RedirectReplaceKeyPrefix: rule.Redirect.ReplaceKeyPrefixWith,
+		}
+		if rule.Condition != nil {
+			websiteRule.ConditionErrorCode = rule.Condition.HttpErrorCodeReturnedEquals
+			websiteRule.ConditionPrefix = rule.Condition.KeyPrefixEquals
+		}
+		websiteConf.Rules = append(websiteConf.Rules, websiteRule)
+	}
+	iBucket, err := fetchIBucket(ctx, userCred, bucketName)
+	if err != nil {
+		return err
+	}
+	err = iBucket.SetWebsite(websiteConf)
+	if err != nil {
+		return bucketConfError(ctx, err, "SetWebsite")
+	}
+	return nil
+}
+
+func deleteBucketWebsite(ctx context.Context, userCred mcclient.TokenCredential, bucketName string) error {
+	iBucket, err := fetchIBucket(ctx, userCred, bucketName)
+	if err != nil {
+		return err
+	}
+	err = iBucket.DeleteWebSiteConf()
+	if err != nil {
+		return bucketConfError(ctx, err, "DeleteWebSiteConf")
+	}
+	return nil
+}
+
+func bucketTagging(ctx context.Context, userCred mcclient.TokenCredential, bucketName string) (*BucketTagging, error) {
+	iBucket, err := fetchIBucket(ctx, userCred, bucketName)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := iBucket.GetTags()
+	if err != nil {
+		return nil, bucketConfError(ctx, err, "GetTags")
+	}
+	if len(tags) == 0 {
+		return nil, NoSuchConfiguration(ctx, "NoSuchTagSet", "The TagSet does not exist")
+	}
+	result := &BucketTagging{}
+	for k, v := range tags {
+		result.Tags = append(result.Tags, s3cli.Tag{Key: k, Value: v})
+	}
+	return result, nil
+}
+
+func putBucketTagging(ctx context.Context, userCred mcclient.TokenCredential, bucketName string, r *http.Request) error {
+	conf := BucketTagging{}
+	err := fetchXmlConfTo(ctx, r, &conf)
+	if err != nil {
+		return err
+	}
+	tags := map[string]string{}
+	for _, tag := range conf.Tags {
+		if len(tag.Key) == 0 {
+			return MalformedXML(ctx, "empty tag key")
+		}
+		if _, ok := tags[tag.Key]; ok {
+			return MalformedXML(ctx, fmt.Sprintf("duplicate tag key %s", tag.Key))
+		}
+		tags[tag.Key] = tag.Value
+	}
+	iBucket, err := fetchIBucket(ctx, userCred, bucketName)
+	if err != nil {
+		return err
+	}
+	err = iBucket.SetTags(tags, true)
+	if err != nil {
+		return bucketConfError(ctx, err, "SetTags")
+	}
+	return nil
+}
+
+func deleteBucketTagging(ctx context.Context, userCred mcclient.TokenCredential, bucketName string) error {
+	iBucket, err := fetchIBucket(ctx, userCred, bucketName)
+	if err != nil {
+		return err
+	}
+	err = iBucket.SetTags(map[string]string{}, true)
+	if err != nil {
+		return bucketConfError(ctx, err, "SetTags")
+	}
+	return nil
+}
+
+func bucketPolicy(ctx context.Context, userCred mcclient.TokenCredential, bucketName string) (*sRawDocument, error) {
+	iBucket, err := fetchIBucket(ctx, userCred, bucketName)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := iBucket.GetPolicyDocument()
+	if err != nil {
+		return nil, bucketConfError(ctx, err, "GetPolicyDocument")
+	}
+	if len(doc) == 0 {
+		return nil, NoSuchConfiguration(ctx, "NoSuchBucketPolicy", "The bucket policy does not exist")
+	}
+	return newRawJsonDocument(doc), nil
+}
+
+func putBucketPolicy(ctx context.Context, userCred mcclient.TokenCredential, bucketName string, r *http.Request) error {
+	body, err := appsrv.Fetch(r)
+	if err != nil {
+		return errors.Wrap(httperrors.ErrBadRequest, "Fetch")
+	}
+	if !json.Valid(body) {
+		return MalformedPolicy(ctx, "policy is not a valid json document")
+	}
+	iBucket, err := fetchIBucket(ctx, userCred, bucketName)
+	if err != nil {
+		return err
+	}
+	err = iBucket.SetPolicyDocument(string(body))
+	if err != nil {
+		return bucketConfError(ctx, err, "SetPolicyDocument")
+	}
+	return nil
+}
+
+func deleteBucketPolicy(ctx context.Context, userCred mcclient.TokenCredential, bucketName string) error {
+	iBucket, err := fetchIBucket(ctx, userCred, bucketName)
+	if err != nil {
+		return err
+	}
+	err = iBucket.DeletePolicyDocument()
+	if err != nil {
+		return bucketConfError(ctx, err, "DeletePolicyDocument")
+	}
+	return nil
+}
+
+// sBucketXmlConf binds a sub-resource to the ICloudBucket methods handling
+// its xml document
+type sBucketXmlConf struct {
+	// root element of the document
+	Root string
+	// error code of GET when not configured, empty document is returned
+	// instead if not set
+	NotFoundCode string
+
+	Get    func(iBucket cloudprovider.ICloudBucket) (string, error)
+	Set    func(iBucket cloudprovider.ICloudBucket, conf string) error
+	Delete func(iBucket cloudprovider.ICloudBucket) error
+}
+
+var (
+	bucketLifecycleConf = sBucketXmlConf{
+		Root:         "LifecycleConfiguration",
+		NotFoundCode: "NoSuchLifecycleConfiguration",
+		Get:          cloudprovider.ICloudBucket.GetLifecycleConf,
+		Set:          cloudprovider.ICloudBucket.SetLifecycleConf,
+		Delete:       cloudprovider.ICloudBucket.DeleteLifecycleConf,
+	}
+	bucketEncryptionConf = sBucketXmlConf{
+		Root:         "ServerSideEncryptionConfiguration",
+		NotFoundCode: "ServerSideEncryptionConfigurationNotFoundError",
+		Get:          cloudprovider.ICloudBucket.GetEncryptionConf,
+		Set:          cloudprovider.ICloudBucket.SetEncryptionConf,
+		Delete:       cloudprovider.ICloudBucket.DeleteEncryptionConf,
+	}
+	bucketNotificationConf = sBucketXmlConf{
+		Root: "NotificationConfiguration",
+		Get:  cloudprovider.ICloudBucket.GetNotificationConf,
+		Set:  cloudprovider.ICloudBucket.SetNotificationConf,
+	}
+	bucketLoggingConf = sBucketXmlConf{
+		Root: "BucketLoggingStatus",
+		Get:  cloudprovider.ICloudBucket.GetLoggingConf,
+		Set:  cloudprovider.ICloudBucket.SetLoggingConf,
+	}
+	bucketObjectLockConf = sBucketXmlConf{
+		Root:         "ObjectLockConfiguration",
+		NotFoundCode: "ObjectLockConfigurationNotFoundError",
+		Get:          cloudprovider.ICloudBucket.GetObjectLockConf,
+		Set:          cloudprovider.ICloudBucket.SetObjectLockConf,
+	}
+	bucketVersioningConf = sBucketXmlConf{
+		Root: "VersioningConfiguration",
+		Get:  cloudprovider.ICloudBucket.GetVersioningConf,
+		Set:  cloudprovider.ICloudBucket.SetVersioningConf,
+	}
+)
+
+func (c sBucketXmlConf) get(ctx context.Context, userCred mcclient.TokenCredential, bucketName string) (*sRawDocument, error) {
+	iBucket, err := fetchIBucket(ctx, userCred, bucketName)
+	if err != nil {
+		return nil, err
+	}
+	conf, err := c.Get(iBucket)
+	if err != nil {
+		return nil, bucketConfError(ctx, err, "Get"+c.Root)
+	}
+	if len(conf) == 0 {
+		if len(c.NotFoundCode) > 0 {
+			return nil, NoSuchConfiguration(ctx, c.NotFoundCode, fmt.Sprintf("The %s does not exist", c.Root))
+		}
+		conf = fmt.Sprintf("<%s></%s>", c.Root, c.Root)
+	}
+	return newRawXmlDocument(conf), nil
+}
+
+func (c sBucketXmlConf) put(ctx context.Context, userCred mcclient.TokenCredential, bucketName string, r *http.Request) error {
+	conf, err := fetchXmlConf(ctx, r, c.Root)
+	if err != nil {
+		return err
+	}
+	iBucket, err := fetchIBucket(ctx, userCred, bucketName)
+	if err != nil {
+		return err
+	}
+	err = c.Set(iBucket, conf)
+	if err != nil {
+		return bucketConfError(ctx, err, "Set"+c.Root)
+	}
+	return nil
+}
+
+func (c sBucketXmlConf) delete(ctx context.Context, userCred mcclient.TokenCredential, bucketName string) error {
+	if c.Delete == nil {
+		return NotImplemented(ctx, fmt.Sprintf("%s cannot be deleted", c.Root))
+	}
+	iBucket, err := fetchIBucket(ctx, userCred, bucketName)
+	if err != nil {
+		return err
+	}
+	err = c.Delete(iBucket)
+	if err != nil {
+		return bucketConfError(ctx, err, "Delete"+c.Root)
+	}
+	return nil
+}
+
+// bucketVersioning reports buckets of providers not supporting versioning
+// as never versioned, which is what S3 tools expect
+func bucketVersioning(ctx context.Context, userCred mcclient.TokenCredential, bucketName string) (interface{}, error) {
+	doc, err := bucketVersioningConf.get(ctx, userCred, bucketName)
+	if err != nil {
+		if e, ok := err.(s3cli.ErrorResponse); ok && e.StatusCode == http.StatusNotImplemented {
+			return &s3cli.VersioningConfiguration{}, nil
+		}
+		return nil, err
+	}
+	return doc, nil
+}
@@ -0,0 +1,204 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"yunion.io/x/s3cli"
+
+	"yunion.io/x/onecloud/pkg/cloudprovider"
+	"yunion.io/x/onecloud/pkg/mcclient"
+)
+
+// fakeBucket implements the bucket configurations in memory, other methods
+// of cloudprovider.ICloudBucket are not expected to be called
+type fakeBucket struct {
+	cloudprovider.ICloudBucket
+
+	tags    map[string]string
+	tagsErr error
+
+	corsRules []cloudprovider.SBucketCORSRule
+	corsErr   error
+
+	lifecycle    string
+	notification string
+
+	versioning    string
+	versioningErr error
+}
+
+func (b *fakeBucket) GetTags() (map[string]string, error) {
+	return b.tags, b.tagsErr
+}
+
+func (b *fakeBucket) SetTags(tags map[string]string, replace bool) error {
+	if b.tagsErr != nil {
+		return b.tagsErr
+	}
+	b.tags = tags
+	return nil
+}
+
+func (b *fakeBucket) GetCORSRules() ([]cloudprovider.SBucketCORSRule, error) {
+	return b.corsRules, b.corsErr
+}
+
+func (b *fakeBucket) SetCORS(rules []cloudprovider.SBucketCORSRule) error {
+	if b.corsErr != nil {
+		return b.corsErr
+	}
+	b.corsRules = rules
+	return nil
+}
+
+func (b *fakeBucket) GetLifecycleConf() (string, error) {
+	return b.lifecycle, nil
+}
+
+func (b *fakeBucket) GetNotificationConf() (string, error) {
+	return b.notification, nil
+}
+
+func (b *fakeBucket) GetVersioningConf() (string, error) {
+	return b.versioning, b.versioningErr
+}
+
+func useFakeBucket(b *fakeBucket) func() {
+	orig := fetchIBucket
+	fetchIBucket = func(ctx context.Context, userCred mcclient.TokenCredential, bucketName string) (cloudprovider.ICloudBucket, error) {
+		return b, nil
+	}
+	return func() { fetchIBucket = orig }
+}
+
+// respond sends the result of a sub-resource handler as the gateway does
+func respond(ctx context.Context, resp interface{}, err error) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	if err != nil {
+		SendGeneralError(ctx, w, err)
+	} else {
+		sendBucketResponse(w, nil, resp)
+	}
+	return w
+}
+
+func putRequest(body string) *http.Request {
+	return httptest.NewRequest("PUT", "/bucket", strings.NewReader(body))
+}
+
+func checkErrorResponse(t *testing.T, w *httptest.ResponseRecorder, status int, code string) {
+	t.Helper()
+	if w.Code != status {
+		t.Errorf("want status %d, got %d", status, w.Code)
+	}
+	resp := s3cli.ErrorResponse{}
+	if err := xml.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal error response %s: %v", w.Body.String(), err)
+	}
+	if resp.Code != code {
+		t.Errorf("want code %s, got %s", code, resp.Code)
+	}
+}
+
+func TestBucketTagging(t *testing.T) {
+	ctx := context.Background()
+	bucket := &fakeBucket{tags: map[string]string{"env": "prod"}}
+	defer useFakeBucket(bucket)()
+
+	resp, err := bucketTagging(ctx, nil, "bucket")
+	w := respond(ctx, resp, err)
+	if w.Code != http.StatusOK {
+		t.Fatalf("want status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	want := "<Tagging><TagSet><Tag><Key>env</Key><Value>prod</Value></Tag></TagSet></Tagging>"
+	if !strings.HasSuffix(w.Body.String(), want) {
+		t.Errorf("want %s, got %s", want, w.Body.String())
+	}
+
+	body := `<Tagging xmlns="http://s3.amazonaws.com/doc/2006-03-01/"><TagSet><Tag><Key>env</Key><Value>test</Value></Tag><Tag><Key>team</Key><Value>infra</Value></Tag></TagSet></Tagging>`
+	err = putBucketTagging(ctx, nil, "bucket", putRequest(body))
+	if err != nil {
+		t.Fatalf("putBucketTagging: %v", err)
+	}
+	if len(bucket.tags) != 2 || bucket.tags["env"] != "test" || bucket.tags["team"] != "infra" {
+		t.Errorf("unexpected tags %v", bucket.tags)
+	}
+
+	body = `<Tagging><TagSet><Tag><Key>env</Key><Value>a</Value></Tag><Tag><Key>env</Key><Value>b</Value></Tag></TagSet></Tagging>`
+	err = putBucketTagging(ctx, nil, "bucket", putRequest(body))
+	checkErrorResponse(t, respond(ctx, nil, err), http.StatusBadRequest, "MalformedXML")
+}
+
+func TestBucketConfNotConfigured(t *testing.T) {
+	ctx := context.Background()
+	defer useFakeBucket(&fakeBucket{})()
+
+	resp, err := bucketTagging(ctx, nil, "bucket")
+	checkErrorResponse(t, respond(ctx, resp, err), http.StatusNotFound, "NoSuchTagSet")
+
+	cors, err := bucketCORS(ctx, nil, "bucket")
+	checkErrorResponse(t, respond(ctx, cors, err), http.StatusNotFound, "NoSuchCORSConfiguration")
+
+	doc, err := bucketLifecycleConf.get(ctx, nil, "bucket")
+	checkErrorResponse(t, respond(ctx, doc, err), http.StatusNotFound, "NoSuchLifecycleConfiguration")
+
+	// sub-resources without error code answer an empty document
+	doc, err = bucketNotificationConf.get(ctx, nil, "bucket")
+	w := respond(ctx, doc, err)
+	if w.Code != http.StatusOK || w.Body.String() != "<NotificationConfiguration></NotificationConfiguration>" {
+		t.Errorf("want empty notification configuration, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestBucketConfNotImplemented(t *testing.T) {
+	ctx := context.Background()
+	bucket := &fakeBucket{
+		tagsErr:       cloudprovider.ErrNotSupported,
+		corsErr:       cloudprovider.ErrNotImplemented,
+		versioningErr: cloudprovider.ErrNotImplemented,
+	}
+	defer useFakeBucket(bucket)()
+
+	cors, err := bucketCORS(ctx, nil, "bucket")
+	checkErrorResponse(t, respond(ctx, cors, err), http.StatusNotImplemented, "NotImplemented")
+
+	body := `<CORSConfiguration><CORSRule><AllowedMethod>GET</AllowedMethod><AllowedOrigin>*</AllowedOrigin></CORSRule></CORSConfiguration>`
+	err = putBucketCORS(ctx, nil, "bucket", putRequest(body))
+	checkErrorResponse(t, respond(ctx, nil, err), http.StatusNotImplemented, "NotImplemented")
+
+	err = deleteBucketTagging(ctx, nil, "bucket")
+	checkErrorResponse(t, respond(ctx, nil, err), http.StatusNotImplemented, "NotImplemented")
+
+	// buckets not supporting versioning are reported as never versioned
+	resp, err := bucketVersioning(ctx, nil, "bucket")
+	w := respond(ctx, resp, err)
+	if w.Code != http.StatusOK {
+		t.Fatalf("want status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	conf := s3cli.VersioningConfiguration{}
+	if err := xml.Unmarshal(w.Body.Bytes(), &conf); err != nil {
+		t.Fatalf("unmarshal %s: %v", w.Body.String(), err)
+	}
+	if conf.Status != "" {
+		t.Errorf("want empty versioning status, got %s", conf.Status)
+	}
+}
@@ -66,7 +66,21 @@ func NotSupported(ctx context.Context, msg string) s3cli.ErrorResponse {
 }
 
 func NotImplemented(ctx context.Context, msg string) s3cli.ErrorResponse {
-	return generalError(ctx, 406, "Not Implemented", msg)
+	return generalError(ctx, 501, "NotImplemented", msg)
+}
+
+func MalformedXML(ctx context.Context, msg string) s3cli.ErrorResponse {
+	return generalError(ctx, 400, "MalformedXML", msg)
+}
+
+func MalformedPolicy(ctx context.Context, msg string) s3cli.ErrorResponse {
+	return generalError(ctx, 400, "MalformedPolicy", msg)
+}
+
+// NoSuchConfiguration answers GET requests of bucket sub-resources not
+// configured yet, with errCode specific to each sub-resource
+func NoSuchConfiguration(ctx context.Context, errCode string, msg string) s3cli.ErrorResponse {
+	return generalError(ctx, 404, errCode, msg)
 }
 
 func InvalidStatus(ctx context.Context, msg string) s3cli.ErrorResponse {
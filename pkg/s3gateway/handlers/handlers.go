@@ -58,6 +58,8 @@ func s3HandlerTimeoutInfo(info *appsrv.SHandlerInfo, r *http.Request) time.Durat
 			return 2 * time.Hour
 		} else if r.Method == http.MethodPut && (len(r.URL.RawQuery) == 0 || strings.Contains(r.URL.RawQuery, "partNumber=")) {
 			return 2 * time.Hour
+		} else if r.Method == http.MethodPost && strings.Contains(r.URL.RawQuery, "select") {
+			return 2 * time.Hour
 		}
 	}
 	return time.Duration(0)
@@ -160,42 +162,52 @@ func readBucket(ctx context.Context, userCred mcclient.TokenCredential, bucketNa
 	} else if query.Contains("analytics") {
 
 	} else if query.Contains("cors") {
-
+		resp, err := bucketCORS(ctx, userCred, bucketName)
+		return resp, nil, err
 	} else if query.Contains("encryption") {
-
+		resp, err := bucketEncryptionConf.get(ctx, userCred, bucketName)
+		return resp, nil, err
 	} else if query.Contains("inventory") {
 
 	} else if query.Contains("lifecycle") {
-
+		resp, err := bucketLifecycleConf.get(ctx, userCred, bucketName)
+		return resp, nil, err
 	} else if query.Contains("location") {
 		result := s3cli.LocationConstraint(bucket.Location)
 		return &result, nil, nil
 	} else if query.Contains("publicAccessBlock") {
 
 	} else if query.Contains("logging") {
-
+		resp, err := bucketLoggingConf.get(ctx, userCred, bucketName)
+		return resp, nil, err
 	} else if query.Contains("metrics") {
 
 	} else if query.Contains("notification") {
-
+		resp, err := bucketNotificationConf.get(ctx, userCred, bucketName)
+		return resp, nil, err
 	} else if query.Contains("object-lock") {
-
+		resp, err := bucketObjectLockConf.get(ctx, userCred, bucketName)
+		return resp, nil, err
 	} else if query.Contains("policyStatus") {
 
 	} else if query.Contains("versions") {
 
 	} else if query.Contains("policy") {
-
+		resp, err := bucketPolicy(ctx, userCred, bucketName)
+		return resp, nil, err
 	} else if query.Contains("replication") {
 
 	} else if query.Contains("requestPayment") {
 
 	} else if query.Contains("tagging") {
-
+		resp, err := bucketTagging(ctx, userCred, bucketName)
+		return resp, nil, err
 	} else if query.Contains("versioning") {
-		return &s3cli.VersioningConfiguration{}, nil, nil
+		resp, err := bucketVersioning(ctx, userCred, bucketName)
+		return resp, nil, err
 	} else if query.Contains("website") {
-
+		resp, err := bucketWebsite(ctx, userCred, bucketName)
+		return resp, nil, err
 	} else if query.Contains("uploads") {
 		input := s3cli.ListMultipartUploadsInput{}
 		err := query.Unmarshal(&input)
@@ -329,7 +341,7 @@ func readHandler(ctx context.Context, w http.ResponseWriter, r *http.Request) {
 			SendGeneralError(ctx, w, err)
 			return
 		}
-		appsrv.SendXml(w, respHdr, resp)
+		sendBucketResponse(w, respHdr, resp)
 	} else {
 		// object get
 		if len(r.URL.RawQuery) == 0 {
@@ -372,9 +384,6 @@ func postObject(ctx context.Context, userCred mcclient.TokenCredential, bucket s
 			return nil, nil, errors.Wrap(httperrors.ErrBadRequest, "FetchXml")
 		}
 		return completeMultipartUpload(ctx, userCred, r.Header, bucket, key, uploadId, &request)
-	} else if query.Contains("select") {
-		// select object
-		return selectObject(ctx, userCred, r.Header, bucket, key)
 	} else {
 		// upload object by form POST
 	}
@@ -397,6 +406,14 @@ func postHandler(ctx context.Context, w http.ResponseWriter, r *http.Request) {
 			SendError(ctx, w, BadRequest(ctx, err.Error()))
 			return
 		}
+		if query.Contains("select") {
+			// select object content, the result is streamed
+			err := selectObject(ctx, userCred, o.Bucket, o.Key, r, w)
+			if err != nil {
+				SendGeneralError(ctx, w, err)
+			}
+			return
+		}
 		resp, respHdr, err := postObject(ctx, userCred, o.Bucket, o.Key, query, r)
 		if err != nil {
 			SendGeneralError(ctx, w, err)
@@ -416,35 +433,35 @@ func putBucket(ctx context.Context, userCred mcclient.TokenCredential, bucket st
 	} else if query.Contains("analytics") {
 
 	} else if query.Contains("cors") {
-
+		return nil, nil, putBucketCORS(ctx, userCred, bucket, r)
 	} else if query.Contains("encryption") {
-
+		return nil, nil, bucketEncryptionConf.put(ctx, userCred, bucket, r)
 	} else if query.Contains("inventory") {
 
 	} else if query.Contains("lifecycle") {
-
+		return nil, nil, bucketLifecycleConf.put(ctx, userCred, bucket, r)
 	} else if query.Contains("publicAccessBlock") {
 
 	} else if query.Contains("logging") {
-
+		return nil, nil, bucketLoggingConf.put(ctx, userCred, bucket, r)
 	} else if query.Contains("metrics") {
 
 	} else if query.Contains("notification") {
-
+		return nil, nil, bucketNotificationConf.put(ctx, userCred, bucket, r)
 	} else if query.Contains("object-lock") {
-
+		return nil, nil, bucketObjectLockConf.put(ctx, userCred, bucket, r)
 	} else if query.Contains("policy") {
-
+		return nil, nil, putBucketPolicy(ctx, userCred, bucket, r)
 	} else if query.Contains("replication") {
 
 	} else if query.Contains("requestPayment") {
 
 	} else if query.Contains("tagging") {
-
+		return nil, nil, putBucketTagging(ctx, userCred, bucket, r)
 	} else if query.Contains("versioning") {
-
+		return nil, nil, bucketVersioningConf.put(ctx, userCred, bucket, r)
 	} else if query.Contains("website") {
-
+		return nil, nil, putBucketWebsite(ctx, userCred, bucket, r)
 	} else {
 		// create bucket
 		return nil, nil, NotSupported(ctx, "Not supported")
@@ -521,25 +538,25 @@ func deleteBucket(ctx context.Context, userCred mcclient.TokenCredential, bucket
 	if query.Contains("analytics") {
 
 	} else if query.Contains("cors") {
-
+		return nil, deleteBucketCORS(ctx, userCred, bucket)
 	} else if query.Contains("encryption") {
-
+		return nil, bucketEncryptionConf.delete(ctx, userCred, bucket)
 	} else if query.Contains("inventory") {
 
 	} else if query.Contains("lifecycle") {
-
+		return nil, bucketLifecycleConf.delete(ctx, userCred, bucket)
 	} else if query.Contains("publicAccessBlock") {
 
 	} else if query.Contains("metrics") {
 
 	} else if query.Contains("policy") {
-
+		return nil, deleteBucketPolicy(ctx, userCred, bucket)
 	} else if query.Contains("replication") {
 
 	} else if query.Contains("tagging") {
-
+		return nil, deleteBucketTagging(ctx, userCred, bucket)
 	} else if query.Contains("website") {
-
+		return nil, deleteBucketWebsite(ctx, userCred, bucket)
 	} else {
 		// delete bucket
 		err := removeBucket(ctx, userCred, bucket)
@@ -18,11 +18,52 @@ import (
 	"context"
 	"net/http"
 
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
 	"yunion.io/x/s3cli"
 
+	"yunion.io/x/onecloud/pkg/appsrv"
+	"yunion.io/x/onecloud/pkg/cloudprovider"
 	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/s3gateway/s3select"
 )
 
-func selectObject(ctx context.Context, userCred mcclient.TokenCredential, hdr http.Header, bucket string, key string) (*s3cli.InitiateMultipartUploadResult, http.Header, error) {
-	return nil, nil, NotImplemented(ctx, "")
+// selectObject evaluates SelectObjectContent on the gateway over the object
+// content, so that it works for buckets of any provider.  The response is
+// an event stream written directly to w, errors returned before that are
+// sent as normal error responses
+func selectObject(ctx context.Context, userCred mcclient.TokenCredential, bucketName string, key string, r *http.Request, w http.ResponseWriter) error {
+	request := s3cli.SelectObjectOptions{}
+	err := appsrv.FetchXml(r, &request)
+	if err != nil {
+		return MalformedXML(ctx, err.Error())
+	}
+	sel, err := s3select.NewSelect(&request)
+	if err != nil {
+		if se, ok := err.(*s3select.SSelectError); ok {
+			return generalError(ctx, http.StatusBadRequest, se.Code, se.Message)
+		}
+		return errors.Wrap(err, "s3select.NewSelect")
+	}
+	iBucket, err := fetchIBucket(ctx, userCred, bucketName)
+	if err != nil {
+		return err
+	}
+	_, err = cloudprovider.GetIObject(iBucket, key)
+	if err != nil {
+		return errors.Wrap(err, "cloudprovider.GetIObject")
+	}
+	stream, err := iBucket.GetObject(ctx, key, nil)
+	if err != nil {
+		return errors.Wrap(err, "iBucket.GetObject")
+	}
+	defer stream.Close()
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.WriteHeader(http.StatusOK)
+	err = sel.Run(ctx, stream, w)
+	if err != nil {
+		log.Errorf("select object %s/%s fail %s", bucketName, key, err)
+	}
+	return nil
 }
@@ -0,0 +1,93 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3select
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+)
+
+const (
+	eventHeaderValueString = 7
+
+	// total length, headers length and prelude crc
+	eventPreludeLength = 12
+	eventCrcLength     = 4
+)
+
+type sEventHeader struct {
+	name  string
+	value string
+}
+
+// writeEventMessage encodes a message of the AWS event stream format,
+// which is
+//
+//	| total len | headers len | prelude crc | headers | payload | message crc |
+func writeEventMessage(w io.Writer, headers []sEventHeader, payload []byte) error {
+	hbuf := bytes.Buffer{}
+	for _, h := range headers {
+		hbuf.WriteByte(byte(len(h.name)))
+		hbuf.WriteString(h.name)
+		hbuf.WriteByte(eventHeaderValueString)
+		binary.Write(&hbuf, binary.BigEndian, uint16(len(h.value)))
+		hbuf.WriteString(h.value)
+	}
+	total := eventPreludeLength + hbuf.Len() + len(payload) + eventCrcLength
+
+	msg := bytes.NewBuffer(make([]byte, 0, total))
+	binary.Write(msg, binary.BigEndian, uint32(total))
+	binary.Write(msg, binary.BigEndian, uint32(hbuf.Len()))
+	binary.Write(msg, binary.BigEndian, crc32.ChecksumIEEE(msg.Bytes()))
+	msg.Write(hbuf.Bytes())
+	msg.Write(payload)
+	binary.Write(msg, binary.BigEndian, crc32.ChecksumIEEE(msg.Bytes()))
+
+	_, err := w.Write(msg.Bytes())
+	return err
+}
+
+func writeRecordsEvent(w io.Writer, payload []byte) error {
+	return writeEventMessage(w, []sEventHeader{
+		{":event-type", "Records"},
+		{":content-type", "application/octet-stream"},
+		{":message-type", "event"},
+	}, payload)
+}
+
+func writeXmlEvent(w io.Writer, event string, payload []byte) error {
+	return writeEventMessage(w, []sEventHeader{
+		{":event-type", event},
+		{":content-type", "text/xml"},
+		{":message-type", "event"},
+	}, payload)
+}
+
+func writeEndEvent(w io.Writer) error {
+	return writeEventMessage(w, []sEventHeader{
+		{":event-type", "End"},
+		{":message-type", "event"},
+	}, nil)
+}
+
+func writeErrorEvent(w io.Writer, code, message string) error {
+	return writeEventMessage(w, []sEventHeader{
+		{":error-code", code},
+		{":error-message", message},
+		{":message-type", "error"},
+	}, nil)
+}
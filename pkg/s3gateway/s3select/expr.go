@@ -0,0 +1,490 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3select
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+type sPathElem struct {
+	name    string
+	quoted  bool
+	index   int
+	isIndex bool
+}
+
+// iRecord is one row of the input, either a csv line or a json value
+type iRecord interface {
+	// get resolves column names, positional _N columns and json paths
+	get(path []sPathElem) SValue
+	// fields returns all top level columns in order, used by SELECT *
+	fields() ([]string, []SValue)
+}
+
+type iExpr interface {
+	eval(r iRecord) (SValue, error)
+}
+
+type sLiteral struct {
+	value SValue
+}
+
+func (e *sLiteral) eval(r iRecord) (SValue, error) {
+	return e.value, nil
+}
+
+type sColumnRef struct {
+	path []sPathElem
+}
+
+func (e *sColumnRef) eval(r iRecord) (SValue, error) {
+	if r == nil {
+		return nullValue, newEvaluatorError("column reference outside of aggregate function")
+	}
+	return r.get(e.path), nil
+}
+
+// name is the key of the column in json output, elements of arrays have
+// no name
+func (e *sColumnRef) name() string {
+	last := e.path[len(e.path)-1]
+	if last.isIndex {
+		return ""
+	}
+	return last.name
+}
+
+type sUnary struct {
+	op string
+	x  iExpr
+}
+
+func (e *sUnary) eval(r iRecord) (SValue, error) {
+	v, err := e.x.eval(r)
+	if err != nil {
+		return nullValue, err
+	}
+	switch e.op {
+	case "NOT":
+		if v.IsNull() {
+			return nullValue, nil
+		}
+		if v.Kind != KindBool {
+			return nullValue, newEvaluatorError("NOT applied to non boolean value %q", v.String())
+		}
+		return boolValue(!v.b), nil
+	case "-":
+		return arithmetic("-", intValue(0), v)
+	}
+	return v, nil
+}
+
+type sBinary struct {
+	op   string
+	l, r iExpr
+}
+
+func (e *sBinary) eval(r iRecord) (SValue, error) {
+	lv, err := e.l.eval(r)
+	if err != nil {
+		return nullValue, err
+	}
+	switch e.op {
+	case "AND", "OR":
+		return e.evalLogical(r, lv)
+	}
+	rv, err := e.r.eval(r)
+	if err != nil {
+		return nullValue, err
+	}
+	switch e.op {
+	case "+", "-", "*", "/", "%":
+		return arithmetic(e.op, lv, rv)
+	case "||":
+		if lv.IsNull() || rv.IsNull() {
+			return nullValue, nil
+		}
+		return stringValue(lv.String() + rv.String()), nil
+	}
+	cmp, ok := compareValues(lv, rv)
+	if !ok {
+		return nullValue, nil
+	}
+	switch e.op {
+	case "=":
+		return boolValue(cmp == 0), nil
+	case "!=", "<>":
+		return boolValue(cmp != 0), nil
+	case "<":
+		return boolValue(cmp < 0), nil
+	case "<=":
+		return boolValue(cmp <= 0), nil
+	case ">":
+		return boolValue(cmp > 0), nil
+	case ">=":
+		return boolValue(cmp >= 0), nil
+	}
+	return nullValue, newEvaluatorError("unknown operator %s", e.op)
+}
+
+// evalLogical implements the three valued AND and OR, the right side is
+// skipped when the left side decides the result
+func (e *sBinary) evalLogical(r iRecord, lv SValue) (SValue, error) {
+	short := e.op == "OR"
+	if lv.Kind == KindBool && lv.b == short {
+		return lv, nil
+	}
+	rv, err := e.r.eval(r)
+	if err != nil {
+		return nullValue, err
+	}
+	for _, v := range []SValue{lv, rv} {
+		if !v.IsNull() && v.Kind != KindBool {
+			return nullValue, newEvaluatorError("%s applied to non boolean value %q", e.op, v.String())
+		}
+	}
+	if rv.Kind == KindBool && rv.b == short {
+		return rv, nil
+	}
+	if lv.IsNull() || rv.IsNull() {
+		return nullValue, nil
+	}
+	return boolValue(!short), nil
+}
+
+type sLike struct {
+	x       iExpr
+	pattern iExpr
+	escape  iExpr
+	not     bool
+}
+
+func (e *sLike) eval(r iRecord) (SValue, error) {
+	v, err := e.x.eval(r)
+	if err != nil {
+		return nullValue, err
+	}
+	p, err := e.pattern.eval(r)
+	if err != nil {
+		return nullValue, err
+	}
+	escape := rune(0)
+	if e.escape != nil {
+		ev, err := e.escape.eval(r)
+		if err != nil {
+			return nullValue, err
+		}
+		if utf8.RuneCountInString(ev.String()) != 1 {
+			return nullValue, newEvaluatorError("ESCAPE must be a single character")
+		}
+		escape, _ = utf8.DecodeRuneInString(ev.String())
+	}
+	if v.IsNull() || p.IsNull() {
+		return nullValue, nil
+	}
+	matched := likeMatch([]rune(v.String()), []rune(p.String()), escape)
+	return boolValue(matched != e.not), nil
+}
+
+// likeMatch matches s against a LIKE pattern where % matches any sequence
+// and _ matches a single character
+func likeMatch(s, p []rune, escape rune) bool {
+	for len(p) > 0 {
+		c := p[0]
+		switch {
+		case escape != 0 && c == escape && len(p) > 1:
+			if len(s) == 0 || s[0] != p[1] {
+				return false
+			}
+			s, p = s[1:], p[2:]
+		case c == '%':
+			for len(p) > 0 && p[0] == '%' {
+				p = p[1:]
+			}
+			if len(p) == 0 {
+				return true
+			}
+			for i := 0; i <= len(s); i++ {
+				if likeMatch(s[i:], p, escape) {
+					return true
+				}
+			}
+			return false
+		case c == '_':
+			if len(s) == 0 {
+				return false
+			}
+			s, p = s[1:], p[1:]
+		default:
+			if len(s) == 0 || s[0] != c {
+				return false
+			}
+			s, p = s[1:], p[1:]
+		}
+	}
+	return len(s) == 0
+}
+
+type sIn struct {
+	x    iExpr
+	list []iExpr
+	not  bool
+}
+
+func (e *sIn) eval(r iRecord) (SValue, error) {
+	v, err := e.x.eval(r)
+	if err != nil {
+		return nullValue, err
+	}
+	if v.IsNull() {
+		return nullValue, nil
+	}
+	for _, item := range e.list {
+		iv, err := item.eval(r)
+		if err != nil {
+			return nullValue, err
+		}
+		if cmp, ok := compareValues(v, iv); ok && cmp == 0 {
+			return boolValue(!e.not), nil
+		}
+	}
+	return boolValue(e.not), nil
+}
+
+type sBetween struct {
+	x, lo, hi iExpr
+	not       bool
+}
+
+func (e *sBetween) eval(r iRecord) (SValue, error) {
+	vals := make([]SValue, 3)
+	for i, x := range []iExpr{e.x, e.lo, e.hi} {
+		v, err := x.eval(r)
+		if err != nil {
+			return nullValue, err
+		}
+		vals[i] = v
+	}
+	lo, ok1 := compareValues(vals[0], vals[1])
+	hi, ok2 := compareValues(vals[0], vals[2])
+	if !ok1 || !ok2 {
+		return nullValue, nil
+	}
+	return boolValue((lo >= 0 && hi <= 0) != e.not), nil
+}
+
+type sIsNull struct {
+	x       iExpr
+	missing bool
+	not     bool
+}
+
+func (e *sIsNull) eval(r iRecord) (SValue, error) {
+	v, err := e.x.eval(r)
+	if err != nil {
+		return nullValue, err
+	}
+	is := v.IsNull()
+	if e.missing {
+		is = v.Kind == KindMissing
+	}
+	return boolValue(is != e.not), nil
+}
+
+type sCast struct {
+	x   iExpr
+	typ string
+}
+
+func (e *sCast) eval(r iRecord) (SValue, error) {
+	v, err := e.x.eval(r)
+	if err != nil {
+		return nullValue, err
+	}
+	return castValue(v, e.typ)
+}
+
+type sFunc struct {
+	name string
+	args []iExpr
+}
+
+var scalarFuncArgs = map[string][2]int{
+	"LOWER":            {1, 1},
+	"UPPER":            {1, 1},
+	"TRIM":             {1, 1},
+	"CHAR_LENGTH":      {1, 1},
+	"CHARACTER_LENGTH": {1, 1},
+	"SUBSTRING":        {2, 3},
+	"COALESCE":         {1, -1},
+	"NULLIF":           {2, 2},
+}
+
+func (e *sFunc) eval(r iRecord) (SValue, error) {
+	args := make([]SValue, len(e.args))
+	for i, a := range e.args {
+		v, err := a.eval(r)
+		if err != nil {
+			return nullValue, err
+		}
+		args[i] = v
+	}
+	switch e.name {
+	case "COALESCE":
+		for _, v := range args {
+			if !v.IsNull() {
+				return v, nil
+			}
+		}
+		return nullValue, nil
+	case "NULLIF":
+		if cmp, ok := compareValues(args[0], args[1]); ok && cmp == 0 {
+			return nullValue, nil
+		}
+		return args[0], nil
+	}
+	if args[0].IsNull() {
+		return nullValue, nil
+	}
+	s := args[0].String()
+	switch e.name {
+	case "LOWER":
+		return stringValue(strings.ToLower(s)), nil
+	case "UPPER":
+		return stringValue(strings.ToUpper(s)), nil
+	case "TRIM":
+		return stringValue(strings.TrimSpace(s)), nil
+	case "CHAR_LENGTH", "CHARACTER_LENGTH":
+		return intValue(int64(utf8.RuneCountInString(s))), nil
+	case "SUBSTRING":
+		return substring(s, args[1:])
+	}
+	return nullValue, newEvaluatorError("unknown function %s", e.name)
+}
+
+// substring follows the SQL semantic, start is 1 based and may be out of
+// the range of the string
+func substring(s string, args []SValue) (SValue, error) {
+	rs := []rune(s)
+	nums := make([]int64, len(args))
+	for i, a := range args {
+		v, err := castValue(a, "INT")
+		if err != nil {
+			return nullValue, err
+		}
+		if v.IsNull() {
+			return nullValue, nil
+		}
+		nums[i] = v.i
+	}
+	start := nums[0] - 1
+	end := int64(len(rs))
+	if len(nums) > 1 {
+		if nums[1] < 0 {
+			return nullValue, newEvaluatorError("negative SUBSTRING length")
+		}
+		end = start + nums[1]
+	}
+	if start < 0 {
+		start = 0
+	}
+	if end > int64(len(rs)) {
+		end = int64(len(rs))
+	}
+	if start >= end {
+		return stringValue(""), nil
+	}
+	return stringValue(string(rs[start:end])), nil
+}
+
+// sAggregate accumulates the rows passed to update, eval returns the
+// aggregated result so far
+type sAggregate struct {
+	name string
+	// nil for COUNT(*)
+	arg iExpr
+
+	count int64
+	sum   SValue
+	best  SValue
+}
+
+func newAggregate(name string, arg iExpr) *sAggregate {
+	return &sAggregate{
+		name: name,
+		arg:  arg,
+		sum:  intValue(0),
+		best: nullValue,
+	}
+}
+
+func (e *sAggregate) update(r iRecord) error {
+	if e.arg == nil {
+		e.count++
+		return nil
+	}
+	v, err := e.arg.eval(r)
+	if err != nil {
+		return err
+	}
+	if v.IsNull() {
+		return nil
+	}
+	switch e.name {
+	case "SUM", "AVG":
+		n, ok := v.toNumber()
+		if !ok {
+			return newEvaluatorError("%s of non numeric value %q", e.name, v.String())
+		}
+		sum, err := arithmetic("+", e.sum, n)
+		if err != nil {
+			return err
+		}
+		e.sum = sum
+	case "MIN", "MAX":
+		if e.best.IsNull() {
+			e.best = v
+			break
+		}
+		cmp, ok := compareValues(v, e.best)
+		if !ok {
+			return newEvaluatorError("%s of incomparable values %q and %q", e.name, v.String(), e.best.String())
+		}
+		if (e.name == "MIN" && cmp < 0) || (e.name == "MAX" && cmp > 0) {
+			e.best = v
+		}
+	}
+	e.count++
+	return nil
+}
+
+func (e *sAggregate) eval(r iRecord) (SValue, error) {
+	switch e.name {
+	case "COUNT":
+		return intValue(e.count), nil
+	case "SUM":
+		if e.count == 0 {
+			return nullValue, nil
+		}
+		return e.sum, nil
+	case "AVG":
+		if e.count == 0 {
+			return nullValue, nil
+		}
+		return floatValue(e.sum.float() / float64(e.count)), nil
+	}
+	return e.best, nil
+}
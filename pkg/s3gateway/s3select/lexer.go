@@ -0,0 +1,136 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3select
+
+import (
+	"strings"
+	"unicode"
+)
+
+type tTokenKind int
+
+const (
+	tokEOF tTokenKind = iota
+	tokIdent
+	tokQuotedIdent
+	tokString
+	tokNumber
+	tokOp
+)
+
+type sToken struct {
+	kind tTokenKind
+	text string
+	pos  int
+}
+
+// keyword reports whether the token is the given keyword, keywords are
+// case insensitive and never quoted
+func (t sToken) keyword(kw string) bool {
+	return t.kind == tokIdent && strings.EqualFold(t.text, kw)
+}
+
+func (t sToken) op(op string) bool {
+	return t.kind == tokOp && t.text == op
+}
+
+var twoCharOps = []string{"<=", ">=", "<>", "!=", "||"}
+
+func tokenize(sql string) ([]sToken, error) {
+	tokens := []sToken{}
+	rs := []rune(sql)
+	i := 0
+	for i < len(rs) {
+		c := rs[i]
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '\'' || c == '"':
+			start := i
+			i++
+			buf := strings.Builder{}
+			closed := false
+			for i < len(rs) {
+				if rs[i] == c {
+					if i+1 < len(rs) && rs[i+1] == c {
+						buf.WriteRune(c)
+						i += 2
+						continue
+					}
+					i++
+					closed = true
+					break
+				}
+				buf.WriteRune(rs[i])
+				i++
+			}
+			if !closed {
+				return nil, newParseError(start, "unterminated quoted string")
+			}
+			kind := tokString
+			if c == '"' {
+				kind = tokQuotedIdent
+			}
+			tokens = append(tokens, sToken{kind: kind, text: buf.String(), pos: start})
+		case unicode.IsDigit(c) || (c == '.' && i+1 < len(rs) && unicode.IsDigit(rs[i+1])):
+			start := i
+			for i < len(rs) && (unicode.IsDigit(rs[i]) || rs[i] == '.') {
+				i++
+			}
+			if i < len(rs) && (rs[i] == 'e' || rs[i] == 'E') {
+				j := i + 1
+				if j < len(rs) && (rs[j] == '+' || rs[j] == '-') {
+					j++
+				}
+				if j < len(rs) && unicode.IsDigit(rs[j]) {
+					i = j
+					for i < len(rs) && unicode.IsDigit(rs[i]) {
+						i++
+					}
+				}
+			}
+			tokens = append(tokens, sToken{kind: tokNumber, text: string(rs[start:i]), pos: start})
+		case unicode.IsLetter(c) || c == '_':
+			start := i
+			for i < len(rs) && (unicode.IsLetter(rs[i]) || unicode.IsDigit(rs[i]) || rs[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, sToken{kind: tokIdent, text: string(rs[start:i]), pos: start})
+		default:
+			if i+1 < len(rs) {
+				two := string(rs[i : i+2])
+				matched := false
+				for _, op := range twoCharOps {
+					if two == op {
+						matched = true
+						break
+					}
+				}
+				if matched {
+					tokens = append(tokens, sToken{kind: tokOp, text: two, pos: i})
+					i += 2
+					continue
+				}
+			}
+			if !strings.ContainsRune("()[],.*+-/%=<>", c) {
+				return nil, newParseError(i, "unexpected character %q", string(c))
+			}
+			tokens = append(tokens, sToken{kind: tokOp, text: string(c), pos: i})
+			i++
+		}
+	}
+	tokens = append(tokens, sToken{kind: tokEOF, pos: len(rs)})
+	return tokens, nil
+}
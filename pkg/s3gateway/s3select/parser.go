@@ -0,0 +1,606 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3select
+
+import (
+	"strconv"
+	"strings"
+)
+
+type sProjection struct {
+	expr  iExpr
+	alias string
+}
+
+// SQuery is a parsed S3 Select statement:
+//
+//	SELECT <projections> FROM S3Object[[*][.path]] [[AS] alias] [WHERE <cond>] [LIMIT <n>]
+type SQuery struct {
+	projections []sProjection
+	// SELECT *
+	star bool
+
+	// FROM S3Object[*], the elements of json arrays are records
+	fromStar bool
+	fromPath []sPathElem
+	alias    string
+
+	where iExpr
+	// negative for no limit
+	limit int64
+
+	aggregates []*sAggregate
+}
+
+func (q *SQuery) isAggregate() bool {
+	return len(q.aggregates) > 0
+}
+
+type sParser struct {
+	tokens []sToken
+	pos    int
+
+	columns []*sColumnRef
+	// columns referenced outside of aggregate functions
+	plainColumns int
+	aggregates   []*sAggregate
+	inAggregate  bool
+}
+
+func ParseQuery(sql string) (*SQuery, error) {
+	tokens, err := tokenize(sql)
+	if err != nil {
+		return nil, err
+	}
+	p := &sParser{tokens: tokens}
+	return p.parseQuery()
+}
+
+func (p *sParser) peek() sToken {
+	return p.tokens[p.pos]
+}
+
+func (p *sParser) next() sToken {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *sParser) acceptKeyword(kw string) bool {
+	if p.peek().keyword(kw) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *sParser) acceptOp(op string) bool {
+	if p.peek().op(op) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *sParser) expectKeyword(kw string) error {
+	if !p.acceptKeyword(kw) {
+		return p.unexpected(kw)
+	}
+	return nil
+}
+
+func (p *sParser) expectOp(op string) error {
+	if !p.acceptOp(op) {
+		return p.unexpected(op)
+	}
+	return nil
+}
+
+func (p *sParser) unexpected(expect string) error {
+	t := p.peek()
+	if t.kind == tokEOF {
+		return newParseError(t.pos, "expect %s, got end of expression", expect)
+	}
+	return newParseError(t.pos, "expect %s, got %q", expect, t.text)
+}
+
+var reservedWords = map[string]bool{
+	"SELECT": true, "FROM": true, "WHERE": true, "LIMIT": true, "AS": true,
+	"AND": true, "OR": true, "NOT": true, "LIKE": true, "ESCAPE": true,
+	"IN": true, "BETWEEN": true, "IS": true, "NULL": true, "MISSING": true,
+	"TRUE": true, "FALSE": true, "CAST": true,
+}
+
+func (p *sParser) parseQuery() (*SQuery, error) {
+	q := &SQuery{limit: -1}
+	if err := p.expectKeyword("SELECT"); err != nil {
+		return nil, err
+	}
+	if p.acceptOp("*") {
+		q.star = true
+	} else {
+		for {
+			proj, err := p.parseProjection()
+			if err != nil {
+				return nil, err
+			}
+			q.projections = append(q.projections, proj)
+			if !p.acceptOp(",") {
+				break
+			}
+		}
+	}
+	if len(p.aggregates) > 0 && p.plainColumns > 0 {
+		return nil, newParseError(0, "aggregate and non aggregate projections can not be mixed")
+	}
+	q.aggregates = p.aggregates
+
+	if err := p.parseFrom(q); err != nil {
+		return nil, err
+	}
+
+	if p.acceptKeyword("WHERE") {
+		aggs := len(p.aggregates)
+		where, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if len(p.aggregates) > aggs {
+			return nil, newParseError(0, "aggregate functions are not allowed in WHERE clause")
+		}
+		q.where = where
+	}
+	if p.acceptKeyword("LIMIT") {
+		t := p.next()
+		limit, err := strconv.ParseInt(t.text, 10, 64)
+		if t.kind != tokNumber || err != nil || limit < 0 {
+			return nil, newParseError(t.pos, "invalid LIMIT %q", t.text)
+		}
+		q.limit = limit
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, newParseError(t.pos, "unexpected %q", t.text)
+	}
+
+	if q.alias != "" {
+		for _, col := range p.columns {
+			if len(col.path) > 1 && !col.path[0].isIndex && strings.EqualFold(col.path[0].name, q.alias) {
+				col.path = col.path[1:]
+			}
+		}
+	}
+	return q, nil
+}
+
+func (p *sParser) parseProjection() (sProjection, error) {
+	expr, err := p.parseExpr()
+	if err != nil {
+		return sProjection{}, err
+	}
+	proj := sProjection{expr: expr}
+	if p.acceptKeyword("AS") {
+		t := p.next()
+		if (t.kind != tokIdent && t.kind != tokQuotedIdent) || (t.kind == tokIdent && reservedWords[strings.ToUpper(t.text)]) {
+			return sProjection{}, newParseError(t.pos, "invalid alias %q", t.text)
+		}
+		proj.alias = t.text
+	}
+	return proj, nil
+}
+
+func (p *sParser) parseFrom(q *SQuery) error {
+	if err := p.expectKeyword("FROM"); err != nil {
+		return err
+	}
+	if err := p.expectKeyword("S3Object"); err != nil {
+		return err
+	}
+	// top level documents are always iterated, so a [*] directly after
+	// S3Object only matters when no path follows
+	q.fromStar = p.acceptWildcard()
+	if p.peek().op(".") {
+		path, err := p.parsePathTail(nil)
+		if err != nil {
+			return err
+		}
+		q.fromPath = path
+		q.fromStar = p.acceptWildcard()
+	}
+
+	t := p.peek()
+	if p.acceptKeyword("AS") {
+		t = p.peek()
+		if t.kind != tokIdent && t.kind != tokQuotedIdent {
+			return p.unexpected("alias")
+		}
+	}
+	if t.kind == tokQuotedIdent || (t.kind == tokIdent && !reservedWords[strings.ToUpper(t.text)]) {
+		p.pos++
+		q.alias = t.text
+	}
+	return nil
+}
+
+// acceptWildcard accepts [*]
+func (p *sParser) acceptWildcard() bool {
+	if p.peek().op("[") && p.tokens[p.pos+1].op("*") && p.tokens[p.pos+2].op("]") {
+		p.pos += 3
+		return true
+	}
+	return false
+}
+
+// parsePathTail parses the .name and [index] parts following a column
+func (p *sParser) parsePathTail(path []sPathElem) ([]sPathElem, error) {
+	for {
+		if p.acceptOp(".") {
+			t := p.next()
+			if t.kind != tokIdent && t.kind != tokQuotedIdent {
+				return nil, newParseError(t.pos, "expect name after '.', got %q", t.text)
+			}
+			path = append(path, sPathElem{name: t.text, quoted: t.kind == tokQuotedIdent})
+		} else if p.peek().op("[") && !p.tokens[p.pos+1].op("*") {
+			p.pos++
+			t := p.next()
+			idx, err := strconv.Atoi(t.text)
+			if t.kind != tokNumber || err != nil || idx < 0 {
+				return nil, newParseError(t.pos, "invalid array index %q", t.text)
+			}
+			if err := p.expectOp("]"); err != nil {
+				return nil, err
+			}
+			path = append(path, sPathElem{index: idx, isIndex: true})
+		} else {
+			return path, nil
+		}
+	}
+}
+
+func (p *sParser) parseExpr() (iExpr, error) {
+	return p.parseOr()
+}
+
+func (p *sParser) parseOr() (iExpr, error) {
+	l, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptKeyword("OR") {
+		r, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l = &sBinary{op: "OR", l: l, r: r}
+	}
+	return l, nil
+}
+
+func (p *sParser) parseAnd() (iExpr, error) {
+	l, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptKeyword("AND") {
+		r, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		l = &sBinary{op: "AND", l: l, r: r}
+	}
+	return l, nil
+}
+
+func (p *sParser) parseNot() (iExpr, error) {
+	if p.acceptKeyword("NOT") {
+		x, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &sUnary{op: "NOT", x: x}, nil
+	}
+	return p.parseComparison()
+}
+
+var comparisonOps = []string{"=", "!=", "<>", "<", "<=", ">", ">="}
+
+func (p *sParser) parseComparison() (iExpr, error) {
+	l, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	t := p.peek()
+	if t.kind == tokOp {
+		for _, op := range comparisonOps {
+			if t.text == op {
+				p.pos++
+				r, err := p.parseAdditive()
+				if err != nil {
+					return nil, err
+				}
+				return &sBinary{op: op, l: l, r: r}, nil
+			}
+		}
+		return l, nil
+	}
+	if p.acceptKeyword("IS") {
+		not := p.acceptKeyword("NOT")
+		if p.acceptKeyword("NULL") {
+			return &sIsNull{x: l, not: not}, nil
+		}
+		if p.acceptKeyword("MISSING") {
+			return &sIsNull{x: l, not: not, missing: true}, nil
+		}
+		return nil, p.unexpected("NULL or MISSING")
+	}
+	not := p.acceptKeyword("NOT")
+	switch {
+	case p.acceptKeyword("LIKE"):
+		pattern, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		like := &sLike{x: l, pattern: pattern, not: not}
+		if p.acceptKeyword("ESCAPE") {
+			like.escape, err = p.parseAdditive()
+			if err != nil {
+				return nil, err
+			}
+		}
+		return like, nil
+	case p.acceptKeyword("BETWEEN"):
+		lo, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectKeyword("AND"); err != nil {
+			return nil, err
+		}
+		hi, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		return &sBetween{x: l, lo: lo, hi: hi, not: not}, nil
+	case p.acceptKeyword("IN"):
+		if err := p.expectOp("("); err != nil {
+			return nil, err
+		}
+		list, err := p.parseExprList()
+		if err != nil {
+			return nil, err
+		}
+		return &sIn{x: l, list: list, not: not}, nil
+	}
+	if not {
+		return nil, p.unexpected("LIKE, BETWEEN or IN")
+	}
+	return l, nil
+}
+
+// parseExprList parses comma separated expressions up to the closing ')'
+func (p *sParser) parseExprList() ([]iExpr, error) {
+	list := []iExpr{}
+	if p.acceptOp(")") {
+		return list, nil
+	}
+	for {
+		x, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, x)
+		if p.acceptOp(")") {
+			return list, nil
+		}
+		if err := p.expectOp(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *sParser) parseAdditive() (iExpr, error) {
+	l, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if !t.op("+") && !t.op("-") && !t.op("||") {
+			return l, nil
+		}
+		p.pos++
+		r, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		l = &sBinary{op: t.text, l: l, r: r}
+	}
+}
+
+func (p *sParser) parseMultiplicative() (iExpr, error) {
+	l, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if !t.op("*") && !t.op("/") && !t.op("%") {
+			return l, nil
+		}
+		p.pos++
+		r, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l = &sBinary{op: t.text, l: l, r: r}
+	}
+}
+
+func (p *sParser) parseUnary() (iExpr, error) {
+	if p.acceptOp("-") {
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if lit, ok := x.(*sLiteral); ok && lit.value.isNumber() {
+			return &sLiteral{value: negate(lit.value)}, nil
+		}
+		return &sUnary{op: "-", x: x}, nil
+	}
+	p.acceptOp("+")
+	return p.parsePrimary()
+}
+
+func negate(v SValue) SValue {
+	if v.Kind == KindInt {
+		return intValue(-v.i)
+	}
+	return floatValue(-v.f)
+}
+
+func (p *sParser) parsePrimary() (iExpr, error) {
+	t := p.next()
+	switch t.kind {
+	case tokNumber:
+		if i, err := strconv.ParseInt(t.text, 10, 64); err == nil {
+			return &sLiteral{value: intValue(i)}, nil
+		}
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, newParseError(t.pos, "invalid number %q", t.text)
+		}
+		return &sLiteral{value: floatValue(f)}, nil
+	case tokString:
+		return &sLiteral{value: stringValue(t.text)}, nil
+	case tokQuotedIdent:
+		return p.parseColumn(t)
+	case tokOp:
+		if t.text == "(" {
+			x, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectOp(")"); err != nil {
+				return nil, err
+			}
+			return x, nil
+		}
+	case tokIdent:
+		upper := strings.ToUpper(t.text)
+		switch upper {
+		case "NULL":
+			return &sLiteral{value: nullValue}, nil
+		case "MISSING":
+			return &sLiteral{value: missingValue}, nil
+		case "TRUE", "FALSE":
+			return &sLiteral{value: boolValue(upper == "TRUE")}, nil
+		case "CAST":
+			return p.parseCast()
+		}
+		if reservedWords[upper] {
+			break
+		}
+		if p.peek().op("(") {
+			p.pos++
+			return p.parseCall(t, upper)
+		}
+		return p.parseColumn(t)
+	}
+	p.pos--
+	return nil, p.unexpected("expression")
+}
+
+func (p *sParser) parseColumn(t sToken) (iExpr, error) {
+	path, err := p.parsePathTail([]sPathElem{{name: t.text, quoted: t.kind == tokQuotedIdent}})
+	if err != nil {
+		return nil, err
+	}
+	col := &sColumnRef{path: path}
+	p.columns = append(p.columns, col)
+	if !p.inAggregate {
+		p.plainColumns++
+	}
+	return col, nil
+}
+
+var castTypes = map[string]bool{
+	"INT": true, "INTEGER": true, "FLOAT": true, "DECIMAL": true, "NUMERIC": true,
+	"REAL": true, "DOUBLE": true, "STRING": true, "VARCHAR": true, "CHAR": true,
+	"BOOL": true, "BOOLEAN": true,
+}
+
+func (p *sParser) parseCast() (iExpr, error) {
+	if err := p.expectOp("("); err != nil {
+		return nil, err
+	}
+	x, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expectKeyword("AS"); err != nil {
+		return nil, err
+	}
+	t := p.next()
+	typ := strings.ToUpper(t.text)
+	if t.kind != tokIdent || !castTypes[typ] {
+		return nil, newParseError(t.pos, "unsupported cast type %q", t.text)
+	}
+	if err := p.expectOp(")"); err != nil {
+		return nil, err
+	}
+	return &sCast{x: x, typ: typ}, nil
+}
+
+func (p *sParser) parseCall(t sToken, name string) (iExpr, error) {
+	switch name {
+	case "COUNT", "SUM", "AVG", "MIN", "MAX":
+		if p.inAggregate {
+			return nil, newParseError(t.pos, "nested aggregate function %s", name)
+		}
+		var arg iExpr
+		if name == "COUNT" && p.acceptOp("*") {
+			if err := p.expectOp(")"); err != nil {
+				return nil, err
+			}
+		} else {
+			p.inAggregate = true
+			x, err := p.parseExpr()
+			p.inAggregate = false
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectOp(")"); err != nil {
+				return nil, err
+			}
+			arg = x
+		}
+		agg := newAggregate(name, arg)
+		p.aggregates = append(p.aggregates, agg)
+		return agg, nil
+	}
+	nargs, ok := scalarFuncArgs[name]
+	if !ok {
+		return nil, newParseError(t.pos, "unsupported function %s", t.text)
+	}
+	args, err := p.parseExprList()
+	if err != nil {
+		return nil, err
+	}
+	if len(args) < nargs[0] || (nargs[1] >= 0 && len(args) > nargs[1]) {
+		return nil, newParseError(t.pos, "wrong number of arguments to %s", name)
+	}
+	return &sFunc{name: name, args: args}, nil
+}
@@ -0,0 +1,430 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3select
+
+import (
+	"bufio"
+	"compress/bzip2"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"yunion.io/x/s3cli"
+)
+
+type iRecordReader interface {
+	// Read returns io.EOF when there are no more records
+	Read() (iRecord, error)
+}
+
+type sCountingReader struct {
+	r     io.Reader
+	count int64
+}
+
+func (c *sCountingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.count += int64(n)
+	return n, err
+}
+
+func checkCompression(compression s3cli.SelectCompressionType) error {
+	switch strings.ToUpper(string(compression)) {
+	case "", string(s3cli.SelectCompressionNONE), s3cli.SelectCompressionGZIP, s3cli.SelectCompressionBZIP:
+		return nil
+	}
+	return newRequestError("InvalidCompressionFormat", "unsupported compression type %s", compression)
+}
+
+func decompressReader(r io.Reader, compression s3cli.SelectCompressionType) (io.Reader, error) {
+	switch strings.ToUpper(string(compression)) {
+	case s3cli.SelectCompressionGZIP:
+		gr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, newRequestError("InvalidCompressionFormat", "gzip: %v", err)
+		}
+		return gr, nil
+	case s3cli.SelectCompressionBZIP:
+		return bzip2.NewReader(r), nil
+	}
+	return r, checkCompression(compression)
+}
+
+// sByteReplaceReader translates a custom record delimiter into '\n' which
+// is the only one understood by encoding/csv
+type sByteReplaceReader struct {
+	r    io.Reader
+	from byte
+}
+
+func (b *sByteReplaceReader) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	for i := 0; i < n; i++ {
+		if p[i] == b.from {
+			p[i] = '\n'
+		}
+	}
+	return n, err
+}
+
+func singleRune(name, s string, def rune) (rune, error) {
+	if s == "" {
+		return def, nil
+	}
+	if utf8.RuneCountInString(s) != 1 {
+		return 0, newRequestError("InvalidRequestParameter", "%s must be a single character", name)
+	}
+	r, _ := utf8.DecodeRuneInString(s)
+	return r, nil
+}
+
+type sCsvRecord struct {
+	names  []string
+	values []string
+}
+
+func (r *sCsvRecord) get(path []sPathElem) SValue {
+	if len(path) != 1 || path[0].isIndex {
+		return missingValue
+	}
+	name := path[0].name
+	if !path[0].quoted && strings.HasPrefix(name, "_") {
+		if idx, err := strconv.Atoi(name[1:]); err == nil {
+			if idx >= 1 && idx <= len(r.values) {
+				return stringValue(r.values[idx-1])
+			}
+			return missingValue
+		}
+	}
+	for i, n := range r.names {
+		if i >= len(r.values) {
+			break
+		}
+		if (path[0].quoted && n == name) || (!path[0].quoted && strings.EqualFold(n, name)) {
+			return stringValue(r.values[i])
+		}
+	}
+	return missingValue
+}
+
+func (r *sCsvRecord) fields() ([]string, []SValue) {
+	names := make([]string, len(r.values))
+	values := make([]SValue, len(r.values))
+	for i, v := range r.values {
+		if i < len(r.names) {
+			names[i] = r.names[i]
+		} else {
+			names[i] = fmt.Sprintf("_%d", i+1)
+		}
+		values[i] = stringValue(v)
+	}
+	return names, values
+}
+
+// sCsvInput is the validated csv input serialization
+type sCsvInput struct {
+	recordDelimiter string
+	comma           rune
+	comment         rune
+	header          s3cli.CSVFileHeaderInfo
+}
+
+func parseCsvInput(opts *s3cli.CSVInputOptions) (*sCsvInput, error) {
+	switch opts.RecordDelimiter {
+	case "", "\n", "\r\n":
+	default:
+		if len(opts.RecordDelimiter) != 1 {
+			return nil, newRequestError("InvalidRequestParameter", "unsupported RecordDelimiter %q", opts.RecordDelimiter)
+		}
+	}
+	comma, err := singleRune("FieldDelimiter", opts.FieldDelimiter, ',')
+	if err != nil {
+		return nil, err
+	}
+	for name, s := range map[string]string{"QuoteCharacter": opts.QuoteCharacter, "QuoteEscapeCharacter": opts.QuoteEscapeCharacter} {
+		if s != "" && s != `"` {
+			return nil, newRequestError("InvalidRequestParameter", "unsupported %s %q", name, s)
+		}
+	}
+	comment, err := singleRune("Comments", opts.Comments, 0)
+	if err != nil {
+		return nil, err
+	}
+	header := s3cli.CSVFileHeaderInfo(strings.ToUpper(string(opts.FileHeaderInfo)))
+	switch header {
+	case "":
+		header = s3cli.CSVFileHeaderInfoNone
+	case s3cli.CSVFileHeaderInfoNone, s3cli.CSVFileHeaderInfoIgnore, s3cli.CSVFileHeaderInfoUse:
+	default:
+		return nil, newRequestError("InvalidFileHeaderInfo", "invalid FileHeaderInfo %s", opts.FileHeaderInfo)
+	}
+	return &sCsvInput{
+		recordDelimiter: opts.RecordDelimiter,
+		comma:           comma,
+		comment:         comment,
+		header:          header,
+	}, nil
+}
+
+func (c *sCsvInput) newReader(r io.Reader) *sCsvReader {
+	if len(c.recordDelimiter) == 1 && c.recordDelimiter != "\n" {
+		r = &sByteReplaceReader{r: r, from: c.recordDelimiter[0]}
+	}
+	cr := csv.NewReader(bufio.NewReader(r))
+	cr.Comma = c.comma
+	cr.Comment = c.comment
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	return &sCsvReader{reader: cr, header: c.header}
+}
+
+type sCsvReader struct {
+	reader *csv.Reader
+	header s3cli.CSVFileHeaderInfo
+	names  []string
+
+	headerDone bool
+}
+
+func (c *sCsvReader) Read() (iRecord, error) {
+	if !c.headerDone {
+		c.headerDone = true
+		if c.header != s3cli.CSVFileHeaderInfoNone {
+			names, err := c.reader.Read()
+			if err != nil {
+				return nil, c.error(err)
+			}
+			if c.header == s3cli.CSVFileHeaderInfoUse {
+				c.names = names
+			}
+		}
+	}
+	values, err := c.reader.Read()
+	if err != nil {
+		return nil, c.error(err)
+	}
+	return &sCsvRecord{names: c.names, values: values}, nil
+}
+
+func (c *sCsvReader) error(err error) error {
+	if err == io.EOF {
+		return err
+	}
+	if _, ok := err.(*csv.ParseError); ok {
+		return &SSelectError{Code: "CSVParsingError", Message: err.Error()}
+	}
+	return err
+}
+
+// sJsonObject keeps the order of the keys of a json object, so that
+// records are returned the way they are stored
+type sJsonObject struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func (o *sJsonObject) MarshalJSON() ([]byte, error) {
+	buf := strings.Builder{}
+	buf.WriteByte('{')
+	for i, k := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		kb, _ := json.Marshal(k)
+		buf.Write(kb)
+		buf.WriteByte(':')
+		vb, err := json.Marshal(o.values[k])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(vb)
+	}
+	buf.WriteByte('}')
+	return []byte(buf.String()), nil
+}
+
+func (o *sJsonObject) get(name string, quoted bool) (interface{}, bool) {
+	if v, ok := o.values[name]; ok {
+		return v, true
+	}
+	if quoted {
+		return nil, false
+	}
+	for _, k := range o.keys {
+		if strings.EqualFold(k, name) {
+			return o.values[k], true
+		}
+	}
+	return nil, false
+}
+
+// decodeJsonValue decodes the next value with ordered objects
+func decodeJsonValue(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return tok, nil
+	}
+	switch delim {
+	case '{':
+		obj := &sJsonObject{values: map[string]interface{}{}}
+		for dec.More() {
+			kt, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			key, _ := kt.(string)
+			val, err := decodeJsonValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			if _, dup := obj.values[key]; !dup {
+				obj.keys = append(obj.keys, key)
+			}
+			obj.values[key] = val
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return obj, nil
+	case '[':
+		arr := []interface{}{}
+		for dec.More() {
+			val, err := decodeJsonValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, val)
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return arr, nil
+	}
+	return nil, fmt.Errorf("unexpected json delimiter %s", delim)
+}
+
+// jsonPath walks path from v, ok is false when any part is missing
+func jsonPath(v interface{}, path []sPathElem) (interface{}, bool) {
+	for _, elem := range path {
+		if elem.isIndex {
+			arr, ok := v.([]interface{})
+			if !ok || elem.index >= len(arr) {
+				return nil, false
+			}
+			v = arr[elem.index]
+		} else {
+			obj, ok := v.(*sJsonObject)
+			if !ok {
+				return nil, false
+			}
+			v, ok = obj.get(elem.name, elem.quoted)
+			if !ok {
+				return nil, false
+			}
+		}
+	}
+	return v, true
+}
+
+type sJsonRecord struct {
+	value interface{}
+}
+
+func (r *sJsonRecord) get(path []sPathElem) SValue {
+	v, ok := jsonPath(r.value, path)
+	if !ok {
+		return missingValue
+	}
+	return jsonValue(v)
+}
+
+func (r *sJsonRecord) fields() ([]string, []SValue) {
+	obj, ok := r.value.(*sJsonObject)
+	if !ok {
+		return []string{"_1"}, []SValue{jsonValue(r.value)}
+	}
+	values := make([]SValue, len(obj.keys))
+	for i, k := range obj.keys {
+		values[i] = jsonValue(obj.values[k])
+	}
+	return obj.keys, values
+}
+
+type sJsonReader struct {
+	dec *json.Decoder
+
+	fromStar bool
+	fromPath []sPathElem
+
+	pending []interface{}
+}
+
+func checkJsonInput(opts *s3cli.JSONInputOptions) error {
+	switch strings.ToUpper(string(opts.Type)) {
+	case string(s3cli.JSONDocumentType), s3cli.JSONLinesType:
+		return nil
+	}
+	return newRequestError("InvalidJsonType", "invalid JSON Type %s", opts.Type)
+}
+
+// newJsonReader reads both DOCUMENT and LINES input, a stream of json
+// values does not need to be split by lines to be decoded
+func newJsonReader(r io.Reader, q *SQuery) *sJsonReader {
+	dec := json.NewDecoder(bufio.NewReader(r))
+	dec.UseNumber()
+	return &sJsonReader{
+		dec:      dec,
+		fromStar: q.fromStar,
+		fromPath: q.fromPath,
+	}
+}
+
+// Read returns the documents of the input one by one, FROM S3Object[*]
+// flattens json arrays so that each element is a record
+func (j *sJsonReader) Read() (iRecord, error) {
+	for len(j.pending) == 0 {
+		doc, err := decodeJsonValue(j.dec)
+		if err != nil {
+			if err == io.EOF {
+				return nil, err
+			}
+			return nil, &SSelectError{Code: "JSONParsingError", Message: err.Error()}
+		}
+		if len(j.fromPath) > 0 {
+			var ok bool
+			doc, ok = jsonPath(doc, j.fromPath)
+			if !ok {
+				continue
+			}
+		}
+		if arr, ok := doc.([]interface{}); ok && j.fromStar {
+			j.pending = arr
+		} else {
+			j.pending = []interface{}{doc}
+		}
+	}
+	doc := j.pending[0]
+	j.pending = j.pending[1:]
+	return &sJsonRecord{value: doc}, nil
+}
@@ -0,0 +1,303 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3select
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"yunion.io/x/s3cli"
+)
+
+const (
+	// records are sent in events of about this size
+	recordsEventSize = 128 * 1024
+)
+
+// SSelectError is an error reported to the client with the S3 error code
+type SSelectError struct {
+	Code    string
+	Message string
+}
+
+func (e *SSelectError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+func newRequestError(code string, msg string, args ...interface{}) error {
+	return &SSelectError{Code: code, Message: fmt.Sprintf(msg, args...)}
+}
+
+func newParseError(pos int, msg string, args ...interface{}) error {
+	return &SSelectError{
+		Code:    "ParseUnexpectedToken",
+		Message: fmt.Sprintf("%s at position %d", fmt.Sprintf(msg, args...), pos),
+	}
+}
+
+func newEvaluatorError(msg string, args ...interface{}) error {
+	return newRequestError("EvaluatorInvalidArguments", msg, args...)
+}
+
+type sStats struct {
+	XMLName        xml.Name
+	BytesScanned   int64
+	BytesProcessed int64
+	BytesReturned  int64
+}
+
+type iFlusher interface {
+	Flush()
+}
+
+// SSelect is a validated SelectObjectContent request
+type SSelect struct {
+	query       *SQuery
+	compression s3cli.SelectCompressionType
+	csvInput    *sCsvInput
+	jsonInput   *s3cli.JSONInputOptions
+	writer      iRecordWriter
+	progress    bool
+}
+
+func NewSelect(opts *s3cli.SelectObjectOptions) (*SSelect, error) {
+	if opts.ExpressionType != "" && !strings.EqualFold(string(opts.ExpressionType), string(s3cli.QueryExpressionTypeSQL)) {
+		return nil, newRequestError("InvalidExpressionType", "unsupported ExpressionType %s", opts.ExpressionType)
+	}
+	if strings.TrimSpace(opts.Expression) == "" {
+		return nil, newRequestError("MissingRequiredParameter", "empty Expression")
+	}
+	query, err := ParseQuery(opts.Expression)
+	if err != nil {
+		return nil, err
+	}
+	s := &SSelect{
+		query:       query,
+		compression: opts.InputSerialization.CompressionType,
+		progress:    opts.RequestProgress.Enabled,
+	}
+	if err := checkCompression(s.compression); err != nil {
+		return nil, err
+	}
+
+	input := opts.InputSerialization
+	switch {
+	case input.Parquet != nil:
+		return nil, newRequestError("UnsupportedSerialization", "Parquet input is not supported")
+	case input.CSV != nil && input.JSON != nil:
+		return nil, newRequestError("InvalidDataSource", "multiple input serializations")
+	case input.CSV != nil:
+		if len(query.fromPath) > 0 {
+			return nil, newRequestError("InvalidDataSource", "json path is not allowed for CSV input")
+		}
+		s.csvInput, err = parseCsvInput(input.CSV)
+		if err != nil {
+			return nil, err
+		}
+	case input.JSON != nil:
+		if err := checkJsonInput(input.JSON); err != nil {
+			return nil, err
+		}
+		s.jsonInput = input.JSON
+	default:
+		return nil, newRequestError("MissingRequiredParameter", "missing InputSerialization")
+	}
+
+	output := opts.OutputSerialization
+	switch {
+	case output.CSV != nil && output.JSON != nil:
+		return nil, newRequestError("InvalidRequestParameter", "multiple output serializations")
+	case output.CSV != nil:
+		s.writer, err = newCsvWriter(output.CSV)
+		if err != nil {
+			return nil, err
+		}
+	case output.JSON != nil:
+		s.writer = newJsonWriter(output.JSON)
+	default:
+		return nil, newRequestError("MissingRequiredParameter", "missing OutputSerialization")
+	}
+	return s, nil
+}
+
+// projectionNames are the keys of json output
+func (s *SSelect) projectionNames() []string {
+	names := make([]string, len(s.query.projections))
+	for i, proj := range s.query.projections {
+		if proj.alias != "" {
+			names[i] = proj.alias
+		} else if col, ok := proj.expr.(*sColumnRef); ok && col.name() != "" {
+			names[i] = col.name()
+		} else {
+			names[i] = fmt.Sprintf("_%d", i+1)
+		}
+	}
+	return names
+}
+
+func (s *SSelect) project(r iRecord, names []string) ([]string, []SValue, error) {
+	if s.query.star {
+		names, values := r.fields()
+		return names, values, nil
+	}
+	values := make([]SValue, len(s.query.projections))
+	for i, proj := range s.query.projections {
+		v, err := proj.expr.eval(r)
+		if err != nil {
+			return nil, nil, err
+		}
+		values[i] = v
+	}
+	return names, values, nil
+}
+
+// Run evaluates the query over the object content read from input and
+// writes the result in event stream format to w.  Once started the
+// response can not be turned into an error response, errors are sent as
+// error events and returned for logging
+func (s *SSelect) Run(ctx context.Context, input io.Reader, w io.Writer) error {
+	scanned := &sCountingReader{r: input}
+	processed := &sCountingReader{}
+	buf := &bytes.Buffer{}
+	var returned int64
+
+	stats := func(name string) []byte {
+		st := sStats{
+			XMLName:        xml.Name{Local: name},
+			BytesScanned:   scanned.count,
+			BytesProcessed: processed.count,
+			BytesReturned:  returned,
+		}
+		b, _ := xml.Marshal(st)
+		return append([]byte(xml.Header), b...)
+	}
+	flush := func() error {
+		if buf.Len() > 0 {
+			returned += int64(buf.Len())
+			if err := writeRecordsEvent(w, buf.Bytes()); err != nil {
+				return err
+			}
+			buf.Reset()
+			if s.progress {
+				if err := writeXmlEvent(w, "Progress", stats("Progress")); err != nil {
+					return err
+				}
+			}
+		}
+		if f, ok := w.(iFlusher); ok {
+			f.Flush()
+		}
+		return nil
+	}
+
+	err := s.run(ctx, scanned, processed, buf, flush)
+	if err == nil {
+		err = flush()
+		if err == nil {
+			err = writeXmlEvent(w, "Stats", stats("Stats"))
+		}
+		if err == nil {
+			err = writeEndEvent(w)
+		}
+		if err == nil {
+			flush()
+		}
+		return err
+	}
+
+	code, msg := "InternalError", err.Error()
+	if se, ok := err.(*SSelectError); ok {
+		code, msg = se.Code, se.Message
+	}
+	// flush the records evaluated before the error, then report it
+	if ferr := flush(); ferr == nil {
+		writeErrorEvent(w, code, msg)
+		flush()
+	}
+	return err
+}
+
+func (s *SSelect) run(ctx context.Context, scanned, processed *sCountingReader, buf *bytes.Buffer, flush func() error) error {
+	dr, err := decompressReader(scanned, s.compression)
+	if err != nil {
+		return err
+	}
+	processed.r = dr
+
+	var reader iRecordReader
+	if s.csvInput != nil {
+		reader = s.csvInput.newReader(processed)
+	} else {
+		reader = newJsonReader(processed, s.query)
+	}
+
+	q := s.query
+	names := s.projectionNames()
+	var count int64
+	for q.isAggregate() || q.limit < 0 || count < q.limit {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		r, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		if q.where != nil {
+			v, err := q.where.eval(r)
+			if err != nil {
+				return err
+			}
+			if v.Kind != KindBool || !v.b {
+				continue
+			}
+		}
+		if q.isAggregate() {
+			for _, agg := range q.aggregates {
+				if err := agg.update(r); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		count++
+		keys, values, err := s.project(r, names)
+		if err != nil {
+			return err
+		}
+		if err := s.writer.write(buf, keys, values); err != nil {
+			return err
+		}
+		if buf.Len() >= recordsEventSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	// the single row of aggregate queries is still subject to LIMIT 0
+	if q.isAggregate() && q.limit != 0 {
+		_, values, err := s.project(nil, names)
+		if err != nil {
+			return err
+		}
+		return s.writer.write(buf, names, values)
+	}
+	return nil
+}
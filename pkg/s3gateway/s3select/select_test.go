@@ -0,0 +1,296 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3select
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"encoding/xml"
+	"hash/crc32"
+	"io"
+	"strings"
+	"testing"
+
+	"yunion.io/x/s3cli"
+)
+
+type sTestEvent struct {
+	headers map[string]string
+	payload []byte
+}
+
+func decodeEvents(t *testing.T, data []byte) []sTestEvent {
+	events := []sTestEvent{}
+	for len(data) > 0 {
+		if len(data) < eventPreludeLength+eventCrcLength {
+			t.Fatalf("short message %x", data)
+		}
+		total := binary.BigEndian.Uint32(data[0:4])
+		hlen := binary.BigEndian.Uint32(data[4:8])
+		if crc32.ChecksumIEEE(data[0:8]) != binary.BigEndian.Uint32(data[8:12]) {
+			t.Fatalf("bad prelude crc")
+		}
+		msg := data[:total]
+		if crc32.ChecksumIEEE(msg[:total-4]) != binary.BigEndian.Uint32(msg[total-4:]) {
+			t.Fatalf("bad message crc")
+		}
+		hdrs := msg[12 : 12+hlen]
+		ev := sTestEvent{headers: map[string]string{}, payload: msg[12+hlen : total-4]}
+		for len(hdrs) > 0 {
+			nlen := int(hdrs[0])
+			name := string(hdrs[1 : 1+nlen])
+			if hdrs[1+nlen] != eventHeaderValueString {
+				t.Fatalf("unexpected header type %d", hdrs[1+nlen])
+			}
+			vlen := int(binary.BigEndian.Uint16(hdrs[2+nlen:]))
+			ev.headers[name] = string(hdrs[4+nlen : 4+nlen+vlen])
+			hdrs = hdrs[4+nlen+vlen:]
+		}
+		events = append(events, ev)
+		data = data[total:]
+	}
+	return events
+}
+
+// runSelect returns the concatenated records, the stats and the error
+// code of the stream
+func runSelect(t *testing.T, opts *s3cli.SelectObjectOptions, input []byte) (string, *sStats, string) {
+	s, err := NewSelect(opts)
+	if err != nil {
+		t.Fatalf("NewSelect %q: %v", opts.Expression, err)
+	}
+	out := &bytes.Buffer{}
+	s.Run(context.Background(), bytes.NewReader(input), out)
+	records := strings.Builder{}
+	var stats *sStats
+	errCode := ""
+	ended := false
+	for _, ev := range decodeEvents(t, out.Bytes()) {
+		if ev.headers[":message-type"] == "error" {
+			errCode = ev.headers[":error-code"]
+			continue
+		}
+		switch ev.headers[":event-type"] {
+		case "Records":
+			records.Write(ev.payload)
+		case "Stats":
+			stats = &sStats{}
+			if err := xml.Unmarshal(ev.payload, stats); err != nil {
+				t.Fatalf("unmarshal stats %s: %v", ev.payload, err)
+			}
+		case "End":
+			ended = true
+		}
+	}
+	if errCode == "" && (!ended || stats == nil) {
+		t.Errorf("%q: stream without stats or end", opts.Expression)
+	}
+	return records.String(), stats, errCode
+}
+
+const testCsv = `name,age,city
+alice,30,"Beijing, China"
+bob,25,Shanghai
+carol,41,Beijing
+dave,,Shenzhen
+`
+
+const testJsonLines = `{"name":"alice","age":30,"tags":["a","b"],"addr":{"city":"Beijing"}}
+{"name":"bob","age":25.5,"tags":[],"addr":{"city":"Shanghai"}}
+{"name":"carol","addr":{"city":"Beijing"}}
+`
+
+func csvOptions(expr string, header s3cli.CSVFileHeaderInfo) *s3cli.SelectObjectOptions {
+	opts := &s3cli.SelectObjectOptions{Expression: expr, ExpressionType: s3cli.QueryExpressionTypeSQL}
+	opts.InputSerialization.CSV = &s3cli.CSVInputOptions{FileHeaderInfo: header}
+	opts.OutputSerialization.CSV = &s3cli.CSVOutputOptions{}
+	return opts
+}
+
+func jsonOptions(expr string) *s3cli.SelectObjectOptions {
+	opts := &s3cli.SelectObjectOptions{Expression: expr}
+	opts.InputSerialization.JSON = &s3cli.JSONInputOptions{Type: s3cli.JSONLinesType}
+	opts.OutputSerialization.JSON = &s3cli.JSONOutputOptions{}
+	return opts
+}
+
+func TestSelectCsv(t *testing.T) {
+	cases := []struct {
+		expr   string
+		header s3cli.CSVFileHeaderInfo
+		want   string
+	}{
+		{
+			expr:   "SELECT * FROM S3Object LIMIT 2",
+			header: s3cli.CSVFileHeaderInfoNone,
+			want:   "name,age,city\nalice,30,\"Beijing, China\"\n",
+		},
+		{
+			expr:   "select s._1 from s3object s where s._3 = 'Beijing'",
+			header: s3cli.CSVFileHeaderInfoIgnore,
+			want:   "carol\n",
+		},
+		{
+			expr:   "SELECT name, age + 1 FROM S3Object WHERE age > 26 AND city LIKE 'Beijing%'",
+			header: s3cli.CSVFileHeaderInfoUse,
+			want:   "alice,31\ncarol,42\n",
+		},
+		{
+			expr:   `SELECT UPPER(s."name") FROM S3Object s WHERE s.age IS NULL OR s.age = ''`,
+			header: s3cli.CSVFileHeaderInfoUse,
+			want:   "DAVE\n",
+		},
+		{
+			expr:   "SELECT COUNT(*), SUM(CAST(age AS INT)), MIN(age), MAX(name), AVG(age) FROM S3Object WHERE age <> ''",
+			header: s3cli.CSVFileHeaderInfoUse,
+			want:   "3,96,25,carol,32\n",
+		},
+		{
+			expr:   "SELECT name FROM S3Object WHERE age BETWEEN 25 AND 30 AND name NOT IN ('bob')",
+			header: s3cli.CSVFileHeaderInfoUse,
+			want:   "alice\n",
+		},
+		{
+			expr:   "SELECT COUNT(*) FROM S3Object WHERE city = 'Nowhere'",
+			header: s3cli.CSVFileHeaderInfoUse,
+			want:   "0\n",
+		},
+	}
+	for _, c := range cases {
+		got, stats, errCode := runSelect(t, csvOptions(c.expr, c.header), []byte(testCsv))
+		if errCode != "" {
+			t.Errorf("%q: error %s", c.expr, errCode)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("%q: got %q, want %q", c.expr, got, c.want)
+		}
+		if stats.BytesScanned != int64(len(testCsv)) || stats.BytesReturned != int64(len(got)) {
+			t.Errorf("%q: unexpected stats %#v", c.expr, stats)
+		}
+	}
+}
+
+func TestSelectJson(t *testing.T) {
+	cases := []struct {
+		expr string
+		want string
+	}{
+		{
+			expr: "SELECT s.name, s.addr.city AS c FROM S3Object s WHERE s.age >= 25",
+			want: `{"name":"alice","c":"Beijing"}` + "\n" + `{"name":"bob","c":"Shanghai"}` + "\n",
+		},
+		{
+			expr: "SELECT * FROM S3Object[*] s WHERE s.age IS MISSING",
+			want: `{"name":"carol","addr":{"city":"Beijing"}}` + "\n",
+		},
+		{
+			expr: "SELECT s.tags[1], s.age * 2 FROM S3Object s LIMIT 2",
+			want: `{"_1":"b","_2":60}` + "\n" + `{"_2":51}` + "\n",
+		},
+		{
+			expr: "SELECT COUNT(s.age) AS n, SUM(s.age) AS total FROM S3Object s",
+			want: `{"n":2,"total":55.5}` + "\n",
+		},
+	}
+	for _, c := range cases {
+		got, _, errCode := runSelect(t, jsonOptions(c.expr), []byte(testJsonLines))
+		if errCode != "" {
+			t.Errorf("%q: error %s", c.expr, errCode)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("%q: got %q, want %q", c.expr, got, c.want)
+		}
+	}
+}
+
+func TestSelectJsonDocument(t *testing.T) {
+	doc := `{"items": [{"id": 1, "v": "x"}, {"id": 2, "v": "y,z"}]}`
+	opts := jsonOptions("SELECT i.v FROM S3Object[*].items[*] i WHERE i.id > 1")
+	opts.InputSerialization.JSON.Type = s3cli.JSONDocumentType
+	opts.OutputSerialization.JSON = nil
+	opts.OutputSerialization.CSV = &s3cli.CSVOutputOptions{QuoteFields: s3cli.CSVQuoteFieldsAsNeeded}
+	got, _, _ := runSelect(t, opts, []byte(doc))
+	if want := "\"y,z\"\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestSelectGzip(t *testing.T) {
+	buf := &bytes.Buffer{}
+	gw := gzip.NewWriter(buf)
+	io.WriteString(gw, testCsv)
+	gw.Close()
+
+	opts := csvOptions("SELECT COUNT(*) FROM S3Object", s3cli.CSVFileHeaderInfoUse)
+	opts.InputSerialization.CompressionType = s3cli.SelectCompressionGZIP
+	got, stats, _ := runSelect(t, opts, buf.Bytes())
+	if got != "4\n" {
+		t.Errorf("got %q", got)
+	}
+	if stats.BytesScanned != int64(buf.Len()) || stats.BytesProcessed != int64(len(testCsv)) {
+		t.Errorf("unexpected stats %#v", stats)
+	}
+}
+
+func TestSelectErrors(t *testing.T) {
+	for _, expr := range []string{
+		"SELECT FROM S3Object",
+		"SELECT name, COUNT(*) FROM S3Object",
+		"SELECT * FROM S3Object WHERE SUM(age) > 1",
+		"SELECT * FROM S3Object LIMIT x",
+		"SELECT * FROM S3Object WHERE name = 'a",
+		"DELETE FROM S3Object",
+	} {
+		_, err := NewSelect(csvOptions(expr, s3cli.CSVFileHeaderInfoUse))
+		if _, ok := err.(*SSelectError); !ok {
+			t.Errorf("%q: expect SSelectError, got %v", expr, err)
+		}
+	}
+
+	opts := csvOptions("SELECT * FROM S3Object", s3cli.CSVFileHeaderInfoUse)
+	opts.InputSerialization.CompressionType = "ZSTD"
+	if _, err := NewSelect(opts); err == nil {
+		t.Errorf("unsupported compression accepted")
+	}
+
+	_, _, errCode := runSelect(t, csvOptions("SELECT CAST(name AS INT) FROM S3Object", s3cli.CSVFileHeaderInfoUse), []byte(testCsv))
+	if errCode != "CastFailed" {
+		t.Errorf("expect CastFailed, got %q", errCode)
+	}
+}
+
+func TestLikeMatch(t *testing.T) {
+	cases := []struct {
+		s, p  string
+		match bool
+	}{
+		{"abc", "a%", true},
+		{"abc", "%c", true},
+		{"abc", "a_c", true},
+		{"abc", "a_", false},
+		{"a%c", `a\%c`, true},
+		{"abc", `a\%c`, false},
+		{"", "%", true},
+	}
+	for _, c := range cases {
+		if got := likeMatch([]rune(c.s), []rune(c.p), '\\'); got != c.match {
+			t.Errorf("%q LIKE %q: got %v", c.s, c.p, got)
+		}
+	}
+}
@@ -0,0 +1,304 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3select
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+type TValueKind int
+
+const (
+	KindMissing TValueKind = iota
+	KindNull
+	KindBool
+	KindInt
+	KindFloat
+	KindString
+	// json objects and arrays, kept as decoded
+	KindObject
+)
+
+// SValue is a dynamically typed value of the query, from either the input
+// records or the expression
+type SValue struct {
+	Kind TValueKind
+
+	b   bool
+	i   int64
+	f   float64
+	s   string
+	obj interface{}
+}
+
+var (
+	missingValue = SValue{Kind: KindMissing}
+	nullValue    = SValue{Kind: KindNull}
+)
+
+func boolValue(b bool) SValue {
+	return SValue{Kind: KindBool, b: b}
+}
+
+func intValue(i int64) SValue {
+	return SValue{Kind: KindInt, i: i}
+}
+
+func floatValue(f float64) SValue {
+	return SValue{Kind: KindFloat, f: f}
+}
+
+func stringValue(s string) SValue {
+	return SValue{Kind: KindString, s: s}
+}
+
+// jsonValue converts values decoded with json.Decoder.UseNumber
+func jsonValue(v interface{}) SValue {
+	switch vv := v.(type) {
+	case nil:
+		return nullValue
+	case bool:
+		return boolValue(vv)
+	case string:
+		return stringValue(vv)
+	case json.Number:
+		if i, err := vv.Int64(); err == nil {
+			return intValue(i)
+		}
+		f, _ := vv.Float64()
+		return floatValue(f)
+	default:
+		return SValue{Kind: KindObject, obj: v}
+	}
+}
+
+func (v SValue) IsNull() bool {
+	return v.Kind == KindMissing || v.Kind == KindNull
+}
+
+func (v SValue) isNumber() bool {
+	return v.Kind == KindInt || v.Kind == KindFloat
+}
+
+// toNumber converts the value to int or float.  Strings, such as all
+// fields of csv records, are parsed
+func (v SValue) toNumber() (SValue, bool) {
+	switch v.Kind {
+	case KindInt, KindFloat:
+		return v, true
+	case KindString:
+		s := strings.TrimSpace(v.s)
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return intValue(i), true
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return floatValue(f), true
+		}
+	}
+	return v, false
+}
+
+func (v SValue) float() float64 {
+	if v.Kind == KindInt {
+		return float64(v.i)
+	}
+	return v.f
+}
+
+func (v SValue) String() string {
+	switch v.Kind {
+	case KindBool:
+		return strconv.FormatBool(v.b)
+	case KindInt:
+		return strconv.FormatInt(v.i, 10)
+	case KindFloat:
+		return strconv.FormatFloat(v.f, 'f', -1, 64)
+	case KindString:
+		return v.s
+	case KindObject:
+		b, _ := json.Marshal(v.obj)
+		return string(b)
+	}
+	return ""
+}
+
+// MarshalJSON implements json.Marshaler for json output of records
+func (v SValue) MarshalJSON() ([]byte, error) {
+	switch v.Kind {
+	case KindBool:
+		return json.Marshal(v.b)
+	case KindInt:
+		return json.Marshal(v.i)
+	case KindFloat:
+		if math.IsInf(v.f, 0) || math.IsNaN(v.f) {
+			return json.Marshal(v.String())
+		}
+		return json.Marshal(v.f)
+	case KindString:
+		return json.Marshal(v.s)
+	case KindObject:
+		return json.Marshal(v.obj)
+	}
+	return []byte("null"), nil
+}
+
+// compareValues returns the order of a and b, ok is false when they are not
+// comparable, e.g. when one of them is null
+func compareValues(a, b SValue) (int, bool) {
+	if a.IsNull() || b.IsNull() {
+		return 0, false
+	}
+	if a.isNumber() || b.isNumber() {
+		na, oka := a.toNumber()
+		nb, okb := b.toNumber()
+		if !oka || !okb {
+			return 0, false
+		}
+		if na.Kind == KindInt && nb.Kind == KindInt {
+			return compareInt(na.i, nb.i), true
+		}
+		fa, fb := na.float(), nb.float()
+		switch {
+		case fa < fb:
+			return -1, true
+		case fa > fb:
+			return 1, true
+		}
+		return 0, true
+	}
+	if a.Kind == KindBool && b.Kind == KindBool {
+		if a.b == b.b {
+			return 0, true
+		} else if !a.b {
+			return -1, true
+		}
+		return 1, true
+	}
+	if a.Kind == KindString && b.Kind == KindString {
+		return strings.Compare(a.s, b.s), true
+	}
+	return 0, false
+}
+
+func compareInt(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func arithmetic(op string, a, b SValue) (SValue, error) {
+	if a.IsNull() || b.IsNull() {
+		return nullValue, nil
+	}
+	na, oka := a.toNumber()
+	nb, okb := b.toNumber()
+	if !oka || !okb {
+		return nullValue, newEvaluatorError("cannot apply %s to %q and %q", op, a.String(), b.String())
+	}
+	if na.Kind == KindInt && nb.Kind == KindInt {
+		x, y := na.i, nb.i
+		switch op {
+		case "+":
+			return intValue(x + y), nil
+		case "-":
+			return intValue(x - y), nil
+		case "*":
+			return intValue(x * y), nil
+		case "/", "%":
+			if y == 0 {
+				return nullValue, newEvaluatorError("division by zero")
+			}
+			if op == "/" {
+				return intValue(x / y), nil
+			}
+			return intValue(x % y), nil
+		}
+	}
+	x, y := na.float(), nb.float()
+	switch op {
+	case "+":
+		return floatValue(x + y), nil
+	case "-":
+		return floatValue(x - y), nil
+	case "*":
+		return floatValue(x * y), nil
+	case "/":
+		if y == 0 {
+			return nullValue, newEvaluatorError("division by zero")
+		}
+		return floatValue(x / y), nil
+	case "%":
+		if y == 0 {
+			return nullValue, newEvaluatorError("division by zero")
+		}
+		return floatValue(math.Mod(x, y)), nil
+	}
+	return nullValue, newEvaluatorError("unknown operator %s", op)
+}
+
+func castValue(v SValue, typ string) (SValue, error) {
+	if v.IsNull() {
+		return nullValue, nil
+	}
+	switch typ {
+	case "INT", "INTEGER":
+		n, ok := v.toNumber()
+		if !ok {
+			return nullValue, newCastError(v, typ)
+		}
+		if n.Kind == KindFloat {
+			return intValue(int64(n.f)), nil
+		}
+		return n, nil
+	case "FLOAT", "DECIMAL", "NUMERIC", "REAL", "DOUBLE":
+		n, ok := v.toNumber()
+		if !ok {
+			return nullValue, newCastError(v, typ)
+		}
+		return floatValue(n.float()), nil
+	case "STRING", "VARCHAR", "CHAR":
+		return stringValue(v.String()), nil
+	case "BOOL", "BOOLEAN":
+		switch v.Kind {
+		case KindBool:
+			return v, nil
+		case KindInt:
+			return boolValue(v.i != 0), nil
+		case KindString:
+			b, err := strconv.ParseBool(strings.TrimSpace(v.s))
+			if err != nil {
+				return nullValue, newCastError(v, typ)
+			}
+			return boolValue(b), nil
+		}
+		return nullValue, newCastError(v, typ)
+	}
+	return nullValue, newEvaluatorError("unsupported cast type %s", typ)
+}
+
+func newCastError(v SValue, typ string) error {
+	return &SSelectError{
+		Code:    "CastFailed",
+		Message: fmt.Sprintf("cannot cast %q to %s", v.String(), typ),
+	}
+}
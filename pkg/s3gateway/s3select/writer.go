@@ -0,0 +1,125 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3select
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"yunion.io/x/s3cli"
+)
+
+type iRecordWriter interface {
+	write(buf *bytes.Buffer, names []string, values []SValue) error
+}
+
+type sCsvWriter struct {
+	fieldDelimiter  string
+	recordDelimiter string
+	quote           string
+	escape          string
+	always          bool
+}
+
+func newCsvWriter(opts *s3cli.CSVOutputOptions) (*sCsvWriter, error) {
+	w := &sCsvWriter{
+		fieldDelimiter:  opts.FieldDelimiter,
+		recordDelimiter: opts.RecordDelimiter,
+		quote:           opts.QuoteCharacter,
+		escape:          opts.QuoteEscapeCharacter,
+	}
+	if w.fieldDelimiter == "" {
+		w.fieldDelimiter = ","
+	}
+	if w.recordDelimiter == "" {
+		w.recordDelimiter = "\n"
+	}
+	if w.quote == "" {
+		w.quote = `"`
+	}
+	if w.escape == "" {
+		w.escape = w.quote
+	}
+	switch strings.ToLower(string(opts.QuoteFields)) {
+	case "", "asneeded":
+	case "always":
+		w.always = true
+	default:
+		return nil, newRequestError("InvalidQuoteFields", "invalid QuoteFields %s", opts.QuoteFields)
+	}
+	return w, nil
+}
+
+func (w *sCsvWriter) write(buf *bytes.Buffer, names []string, values []SValue) error {
+	for i, v := range values {
+		if i > 0 {
+			buf.WriteString(w.fieldDelimiter)
+		}
+		s := v.String()
+		if w.always || w.needQuote(s) {
+			buf.WriteString(w.quote)
+			buf.WriteString(strings.Replace(s, w.quote, w.escape+w.quote, -1))
+			buf.WriteString(w.quote)
+		} else {
+			buf.WriteString(s)
+		}
+	}
+	buf.WriteString(w.recordDelimiter)
+	return nil
+}
+
+func (w *sCsvWriter) needQuote(s string) bool {
+	return strings.Contains(s, w.fieldDelimiter) || strings.Contains(s, w.recordDelimiter) ||
+		strings.Contains(s, w.quote) || strings.ContainsAny(s, "\r\n")
+}
+
+type sJsonWriter struct {
+	recordDelimiter string
+}
+
+func newJsonWriter(opts *s3cli.JSONOutputOptions) *sJsonWriter {
+	w := &sJsonWriter{recordDelimiter: opts.RecordDelimiter}
+	if w.recordDelimiter == "" {
+		w.recordDelimiter = "\n"
+	}
+	return w
+}
+
+// write outputs a json object, missing values are left out
+func (w *sJsonWriter) write(buf *bytes.Buffer, names []string, values []SValue) error {
+	buf.WriteByte('{')
+	first := true
+	for i, v := range values {
+		if v.Kind == KindMissing {
+			continue
+		}
+		if !first {
+			buf.WriteByte(',')
+		}
+		first = false
+		kb, _ := json.Marshal(names[i])
+		buf.Write(kb)
+		buf.WriteByte(':')
+		vb, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		buf.Write(vb)
+	}
+	buf.WriteByte('}')
+	buf.WriteString(w.recordDelimiter)
+	return nil
+}
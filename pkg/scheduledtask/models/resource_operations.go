@@ -0,0 +1,305 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+
+	comapi "yunion.io/x/onecloud/pkg/apis/compute"
+	devapi "yunion.io/x/onecloud/pkg/apis/devtool"
+	api "yunion.io/x/onecloud/pkg/apis/scheduledtask"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/mcclient/auth"
+	"yunion.io/x/onecloud/pkg/mcclient/modulebase"
+	"yunion.io/x/onecloud/pkg/mcclient/modules/compute"
+	"yunion.io/x/onecloud/pkg/mcclient/modules/devtool"
+	"yunion.io/x/onecloud/pkg/util/httputils"
+)
+
+var (
+	ServerSnapshot     ResourceOperation
+	ServerChangeConfig ResourceOperation
+	ServerRunPlaybook  ResourceOperation
+	DiskSnapshot       ResourceOperation
+	ScalingGroupScale  ResourceOperation
+)
+
+func init() {
+	ServerSnapshot = ResourceOperation{
+		Resource:  ResourceServer,
+		Operation: api.ST_RESOURCE_OPERATION_SNAPSHOT,
+		Timeout:   30 * time.Minute,
+		Apply:     applyServerSnapshot,
+	}
+	ServerChangeConfig = ResourceOperation{
+		Resource:      ResourceServer,
+		Operation:     api.ST_RESOURCE_OPERATION_CHANGE_CONFIG,
+		StatusSuccess: []string{comapi.VM_RUNNING, comapi.VM_READY},
+		Fail: []ResourceOperationFail{
+			{comapi.VM_CHANGE_FLAVOR_FAIL, db.ACT_CHANGE_FLAVOR_FAIL},
+		},
+		Timeout: 10 * time.Minute,
+		Apply:   applyServerChangeConfig,
+	}
+	ServerRunPlaybook = ResourceOperation{
+		Resource:  ResourceServer,
+		Operation: api.ST_RESOURCE_OPERATION_RUN_PLAYBOOK,
+		Timeout:   30 * time.Minute,
+		Apply:     applyServerRunPlaybook,
+	}
+	DiskSnapshot = ResourceOperation{
+		Resource:  ResourceDisk,
+		Operation: api.ST_RESOURCE_OPERATION_SNAPSHOT,
+		Timeout:   30 * time.Minute,
+		Apply:     applyDiskSnapshot,
+	}
+	ScalingGroupScale = ResourceOperation{
+		Resource:  ResourceScalingGroup,
+		Operation: api.ST_RESOURCE_OPERATION_SCALE,
+		Apply:     applyScalingGroupScale,
+	}
+}
+
+func validateOperationParams(ctx context.Context, userCred mcclient.TokenCredential, operation string, params *api.ScheduledTaskOperationParams) (*api.ScheduledTaskOperationParams, error) {
+	if params == nil {
+		params = &api.ScheduledTaskOperationParams{}
+	}
+	switch operation {
+	case api.ST_RESOURCE_OPERATION_SNAPSHOT:
+		if params.SnapshotRetention < 0 {
+			return nil, httperrors.NewInputParameterError("snapshot_retention should not be negative")
+		}
+	case api.ST_RESOURCE_OPERATION_CHANGE_CONFIG:
+		if params.VcpuCount < 0 {
+			return nil, httperrors.NewInputParameterError("vcpu_count should not be negative")
+		}
+		if len(params.InstanceType) == 0 && params.VcpuCount == 0 && len(params.VmemSize) == 0 {
+			return nil, httperrors.NewMissingParameterError("instance_type, vcpu_count or vmem_size")
+		}
+	case api.ST_RESOURCE_OPERATION_SCALE:
+		if params.DesireInstanceNumber < 0 {
+			return nil, httperrors.NewInputParameterError("desire_instance_number should not be negative")
+		}
+		// zero can't be told from absence, which would scale in to nothing
+		if params.DesireInstanceNumber == 0 {
+			return nil, httperrors.NewMissingParameterError("desire_instance_number")
+		}
+	case api.ST_RESOURCE_OPERATION_RUN_PLAYBOOK:
+		if len(params.Script) == 0 {
+			return nil, httperrors.NewMissingParameterError("script")
+		}
+		session := auth.GetSession(ctx, userCred, "", "")
+		script, err := devtool.DevToolScripts.Get(session, params.Script, nil)
+		if err != nil {
+			return nil, httperrors.NewResourceNotFoundError2("script", params.Script)
+		}
+		params.Script, _ = script.GetString("id")
+	}
+	if params.IsZero() {
+		return nil, nil
+	}
+	return params, nil
+}
+
+// errReason returns the details of client error which is more readable
+func errReason(err error) string {
+	if clientErr, ok := err.(*httputils.JSONClientError); ok {
+		return clientErr.Details
+	}
+	return err.Error()
+}
+
+func shortId(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
+
+// snapshotPrefix returns the name prefix of snapshots created by scheduled task for resource id,
+// which is used to find the expired snapshots even if the scheduled task is renamed
+func (r SAction) snapshotPrefix(id string) string {
+	return fmt.Sprintf("st-%s-%s-", shortId(r.taskId), shortId(id))
+}
+
+func applyServerSnapshot(r SAction, id string) (bool, string) {
+	prefix := r.snapshotPrefix(id)
+	name := prefix + time.Now().UTC().Format("20060102150405")
+	params := jsonutils.NewDict()
+	params.Set("name", jsonutils.NewString(name))
+	params.Set("with_memory", jsonutils.NewBool(r.params.SnapshotWithMemory))
+	_, err := compute.Servers.PerformAction(r.session, id, "instance-snapshot", params)
+	if err != nil {
+		return false, errReason(err)
+	}
+	return r.waitSnapshot(compute.InstanceSnapshots, "guest_id", id, name, prefix,
+		comapi.INSTANCE_SNAPSHOT_READY, comapi.INSTANCE_SNAPSHOT_FAILED)
+}
+
+func applyDiskSnapshot(r SAction, id string) (bool, string) {
+	prefix := r.snapshotPrefix(id)
+	name := prefix + time.Now().UTC().Format("20060102150405")
+	params := jsonutils.NewDict()
+	params.Set("name", jsonutils.NewString(name))
+	params.Set("disk_id", jsonutils.NewString(id))
+	_, err := compute.Snapshots.Create(r.session, params)
+	if err != nil {
+		return false, errReason(err)
+	}
+	return r.waitSnapshot(compute.Snapshots, "disk_id", id, name, prefix,
+		comapi.SNAPSHOT_READY, comapi.SNAPSHOT_FAILED)
+}
+
+// listSnapshots lists the snapshots of resource id created by this scheduled task, latest first
+func (r SAction) listSnapshots(manager modulebase.ResourceManager, field, id, name, prefix string) ([]jsonutils.JSONObject, error) {
+	params := jsonutils.NewDict()
+	params.Set("scope", jsonutils.NewString("system"))
+	params.Set("details", jsonutils.JSONFalse)
+	params.Set("limit", jsonutils.NewInt(0))
+	params.Set("order_by", jsonutils.NewString("created_at"))
+	params.Set("order", jsonutils.NewString("desc"))
+	filters := []string{fmt.Sprintf("%s.equals(%s)", field, id)}
+	if len(name) > 0 {
+		filters = append(filters, fmt.Sprintf("name.equals(%s)", name))
+	} else {
+		filters = append(filters, fmt.Sprintf("name.startswith(%s)", prefix))
+	}
+	params.Set("filter", jsonutils.NewStringArray(filters))
+	ret, err := manager.List(r.session, params)
+	if err != nil {
+		return nil, err
+	}
+	return ret.Data, nil
+}
+
+func (r SAction) waitSnapshot(manager modulebase.ResourceManager, field, id, name, prefix, ready, failed string) (bool, string) {
+	// creating instance snapshot responds nothing, so the snapshot is found by its unique name
+	snapshots, err := r.listSnapshots(manager, field, id, name, "")
+	if err != nil {
+		return false, errReason(err)
+	}
+	if len(snapshots) == 0 {
+		return false, fmt.Sprintf("snapshot %s not found", name)
+	}
+	snapshotId, _ := snapshots[0].GetString("id")
+	success, reason := r.waitStatus(manager, snapshotId, []string{ready}, []ResourceOperationFail{{Status: failed}})
+	if !success {
+		return false, reason
+	}
+	r.cleanSnapshots(manager, field, id, prefix)
+	return true, ""
+}
+
+// cleanSnapshots deletes the earliest snapshots exceeding the retention
+func (r SAction) cleanSnapshots(manager modulebase.ResourceManager, field, id, prefix string) {
+	if r.params.SnapshotRetention <= 0 {
+		return
+	}
+	snapshots, err := r.listSnapshots(manager, field, id, "", prefix)
+	if err != nil {
+		log.Errorf("list snapshots of %s %s error: %v", field, id, err)
+		return
+	}
+	for i := r.params.SnapshotRetention; i < len(snapshots); i++ {
+		snapshotId, _ := snapshots[i].GetString("id")
+		_, err := manager.Delete(r.session, snapshotId, nil)
+		if err != nil {
+			log.Errorf("delete expired snapshot %s of %s %s error: %v", snapshotId, field, id, err)
+		}
+	}
+}
+
+func applyServerChangeConfig(r SAction, id string) (bool, string) {
+	server, err := compute.Servers.GetSpecific(r.session, id, "status", nil)
+	if err != nil {
+		return false, errReason(err)
+	}
+	status, _ := server.GetString("status")
+	params := jsonutils.NewDict()
+	if len(r.params.InstanceType) > 0 {
+		params.Set("instance_type", jsonutils.NewString(r.params.InstanceType))
+	}
+	if r.params.VcpuCount > 0 {
+		params.Set("vcpu_count", jsonutils.NewInt(int64(r.params.VcpuCount)))
+	}
+	if len(r.params.VmemSize) > 0 {
+		params.Set("vmem_size", jsonutils.NewString(r.params.VmemSize))
+	}
+	// keep the power state of server
+	params.Set("auto_start", jsonutils.NewBool(status == comapi.VM_RUNNING))
+	_, err = compute.Servers.PerformAction(r.session, id, "change-config", params)
+	if err != nil {
+		return false, errReason(err)
+	}
+	return r.waitStatus(compute.Servers.ResourceManager, id, r.operation.StatusSuccess, r.operation.Fail)
+}
+
+func applyScalingGroupScale(r SAction, id string) (bool, string) {
+	input := comapi.ScalingGroupSetDesireInstanceNumberInput{
+		DesireInstanceNumber: r.params.DesireInstanceNumber,
+	}
+	_, err := compute.ScalingGroup.PerformAction(r.session, id, "set-desire-instance-number", jsonutils.Marshal(input))
+	if err != nil {
+		return false, errReason(err)
+	}
+	return true, ""
+}
+
+func applyServerRunPlaybook(r SAction, id string) (bool, string) {
+	input := devapi.ScriptApplyInput{
+		ServerID: id,
+	}
+	ret, err := devtool.DevToolScripts.PerformAction(r.session, r.params.Script, "apply", jsonutils.Marshal(input))
+	if err != nil {
+		return false, errReason(err)
+	}
+	output := devapi.ScriptApplyOutput{}
+	err = ret.Unmarshal(&output)
+	if err != nil || len(output.ScriptApplyId) == 0 {
+		return false, fmt.Sprintf("invalid response of applying script: %s", ret)
+	}
+	params := jsonutils.NewDict()
+	params.Set("scope", jsonutils.NewString("system"))
+	params.Set("script_apply_id", jsonutils.NewString(output.ScriptApplyId))
+	params.Set("order_by", jsonutils.NewString("start_time"))
+	params.Set("order", jsonutils.NewString("desc"))
+	params.Set("limit", jsonutils.NewInt(1))
+	return r.wait(func() (bool, bool, string) {
+		records, err := devtool.DevToolScriptApplyRecords.List(r.session, params)
+		if err != nil {
+			log.Errorf("list apply records of script apply %s error: %v", output.ScriptApplyId, err)
+			return false, false, ""
+		}
+		if len(records.Data) == 0 {
+			return false, false, ""
+		}
+		status, _ := records.Data[0].GetString("status")
+		switch status {
+		case devapi.SCRIPT_APPLY_RECORD_SUCCEED:
+			return true, true, ""
+		case devapi.SCRIPT_APPLY_RECORD_FAILED:
+			reason, _ := records.Data[0].GetString("reason")
+			return true, false, reason
+		}
+		return false, false, ""
+	})
+}
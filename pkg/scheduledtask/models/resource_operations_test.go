@@ -0,0 +1,225 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	api "yunion.io/x/onecloud/pkg/apis/scheduledtask"
+)
+
+func TestResourceOperationMap(t *testing.T) {
+	cases := []struct {
+		resource  Resource
+		operation string
+		want      bool
+	}{
+		{ResourceServer, api.ST_RESOURCE_OPERATION_SNAPSHOT, true},
+		{ResourceServer, api.ST_RESOURCE_OPERATION_CHANGE_CONFIG, true},
+		{ResourceServer, api.ST_RESOURCE_OPERATION_RUN_PLAYBOOK, true},
+		{ResourceDisk, api.ST_RESOURCE_OPERATION_SNAPSHOT, true},
+		{ResourceScalingGroup, api.ST_RESOURCE_OPERATION_SCALE, true},
+		{ResourceDisk, api.ST_RESOURCE_OPERATION_START, false},
+		{ResourceScalingGroup, api.ST_RESOURCE_OPERATION_SNAPSHOT, false},
+		{ResourceCloudAccount, api.ST_RESOURCE_OPERATION_SCALE, false},
+	}
+	for _, c := range cases {
+		st := &SScheduledTask{ResourceType: string(c.resource), Operation: c.operation}
+		oper := st.ResourceOperation()
+		if got := oper.Apply != nil; got != c.want {
+			t.Errorf("%s.%s: want supported %v, got %v", c.resource, c.operation, c.want, got)
+			continue
+		}
+		if !c.want {
+			continue
+		}
+		if oper.Resource != c.resource || oper.Operation != c.operation {
+			t.Errorf("%s.%s: got operation %s.%s", c.resource, c.operation, oper.Resource, oper.Operation)
+		}
+		if _, ok := Modules[oper.Resource]; !ok {
+			t.Errorf("%s.%s: resource is not registered", c.resource, c.operation)
+		}
+	}
+}
+
+func TestValidateOperationParams(t *testing.T) {
+	ctx := context.Background()
+	cases := []struct {
+		name      string
+		operation string
+		params    *api.ScheduledTaskOperationParams
+		wantErr   bool
+		wantNil   bool
+	}{
+		{
+			name:      "snapshot without params",
+			operation: api.ST_RESOURCE_OPERATION_SNAPSHOT,
+			wantNil:   true,
+		},
+		{
+			name:      "snapshot with retention",
+			operation: api.ST_RESOURCE_OPERATION_SNAPSHOT,
+			params:    &api.ScheduledTaskOperationParams{SnapshotRetention: 7},
+		},
+		{
+			name:      "snapshot with negative retention",
+			operation: api.ST_RESOURCE_OPERATION_SNAPSHOT,
+			params:    &api.ScheduledTaskOperationParams{SnapshotRetention: -1},
+			wantErr:   true,
+		},
+		{
+			name:      "change config without config",
+			operation: api.ST_RESOURCE_OPERATION_CHANGE_CONFIG,
+			params:    &api.ScheduledTaskOperationParams{SnapshotRetention: 7},
+			wantErr:   true,
+		},
+		{
+			name:      "change config with negative vcpu count",
+			operation: api.ST_RESOURCE_OPERATION_CHANGE_CONFIG,
+			params:    &api.ScheduledTaskOperationParams{VcpuCount: -2, VmemSize: "2G"},
+			wantErr:   true,
+		},
+		{
+			name:      "change config with instance type",
+			operation: api.ST_RESOURCE_OPERATION_CHANGE_CONFIG,
+			params:    &api.ScheduledTaskOperationParams{InstanceType: "ecs.g6.large"},
+		},
+		{
+			name:      "change config with vcpu count and vmem size",
+			operation: api.ST_RESOURCE_OPERATION_CHANGE_CONFIG,
+			params:    &api.ScheduledTaskOperationParams{VcpuCount: 4, VmemSize: "8G"},
+		},
+		{
+			name:      "scale with negative desire instance number",
+			operation: api.ST_RESOURCE_OPERATION_SCALE,
+			params:    &api.ScheduledTaskOperationParams{DesireInstanceNumber: -1},
+			wantErr:   true,
+		},
+		{
+			name:      "scale without desire instance number",
+			operation: api.ST_RESOURCE_OPERATION_SCALE,
+			params:    &api.ScheduledTaskOperationParams{},
+			wantErr:   true,
+		},
+		{
+			name:      "scale without params",
+			operation: api.ST_RESOURCE_OPERATION_SCALE,
+			wantErr:   true,
+		},
+		{
+			name:      "scale out",
+			operation: api.ST_RESOURCE_OPERATION_SCALE,
+			params:    &api.ScheduledTaskOperationParams{DesireInstanceNumber: 3},
+		},
+		{
+			name:      "run playbook without script",
+			operation: api.ST_RESOURCE_OPERATION_RUN_PLAYBOOK,
+			wantErr:   true,
+		},
+		{
+			name:      "start ignores params",
+			operation: api.ST_RESOURCE_OPERATION_START,
+			params:    &api.ScheduledTaskOperationParams{},
+			wantNil:   true,
+		},
+	}
+	for _, c := range cases {
+		var input *api.ScheduledTaskOperationParams
+		if c.params != nil {
+			p := *c.params
+			input = &p
+		}
+		params, err := validateOperationParams(ctx, nil, c.operation, input)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("%s: want error, got nil", c.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if c.wantNil {
+			if params != nil {
+				t.Errorf("%s: want nil params, got %s", c.name, params)
+			}
+			continue
+		}
+		if params == nil || *params != *c.params {
+			t.Errorf("%s: want params %s, got %v", c.name, c.params, params)
+		}
+	}
+}
+
+func TestSnapshotPrefix(t *testing.T) {
+	r := Action.ScheduledTask("0123456789abcdef", nil)
+	if got, want := r.snapshotPrefix("fedcba9876543210"), "st-01234567-fedcba98-"; got != want {
+		t.Errorf("want snapshot prefix %s, got %s", want, got)
+	}
+	if got, want := r.snapshotPrefix("disk1"), "st-01234567-disk1-"; got != want {
+		t.Errorf("want snapshot prefix %s, got %s", want, got)
+	}
+}
+
+func TestActionApply(t *testing.T) {
+	params := &api.ScheduledTaskOperationParams{DesireInstanceNumber: 3}
+	var applied SAction
+	var appliedId string
+	oper := ResourceOperation{
+		Resource:  ResourceScalingGroup,
+		Operation: api.ST_RESOURCE_OPERATION_SCALE,
+		Apply: func(r SAction, id string) (bool, string) {
+			applied, appliedId = r, id
+			return false, "failed"
+		},
+	}
+	r := Action.ResourceOperation(oper).ScheduledTask("task1", params)
+	// the params are copied so later changes of the task don't affect the action
+	params.DesireInstanceNumber = 5
+
+	success, reason := r.Apply("sg1")
+	if success || reason != "failed" {
+		t.Errorf("want the result of Apply of operation, got %v %q", success, reason)
+	}
+	if appliedId != "sg1" || applied.taskId != "task1" || applied.params.DesireInstanceNumber != 3 {
+		t.Errorf("unexpected action applied: %s %s %d", appliedId, applied.taskId, applied.params.DesireInstanceNumber)
+	}
+}
+
+func TestActionWaitTimeout(t *testing.T) {
+	oper := ResourceOperation{
+		Resource:  ResourceServer,
+		Operation: api.ST_RESOURCE_OPERATION_SNAPSHOT,
+		Timeout:   10 * time.Millisecond,
+	}
+	// the timeout of operation overrides the one of action
+	r := Action.Timeout(time.Hour).ResourceOperation(oper)
+	success, reason := r.wait(func() (bool, bool, string) {
+		return false, false, ""
+	})
+	if success || reason != "timeout" {
+		t.Errorf("want timeout, got %v %q", success, reason)
+	}
+
+	success, reason = r.wait(func() (bool, bool, string) {
+		return true, false, "snapshot failed"
+	})
+	if success || reason != "snapshot failed" {
+		t.Errorf("want snapshot failed, got %v %q", success, reason)
+	}
+}
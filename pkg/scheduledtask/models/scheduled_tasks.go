@@ -40,7 +40,6 @@ import (
 	"yunion.io/x/onecloud/pkg/mcclient/modules/compute"
 	"yunion.io/x/onecloud/pkg/mcclient/options"
 	sop "yunion.io/x/onecloud/pkg/scheduledtask/options"
-	"yunion.io/x/onecloud/pkg/util/httputils"
 	"yunion.io/x/onecloud/pkg/util/logclient"
 	"yunion.io/x/onecloud/pkg/util/stringutils2"
 )
@@ -77,6 +76,9 @@ type SScheduledTask struct {
 	ResourceType string `width:"32" charset:"ascii" create:"required" list:"user" get:"user"`
 	Operation    string `width:"32" charset:"ascii" create:"required" list:"user" get:"user"`
 	LabelType    string `width:"4" charset:"ascii" create:"required" list:"user" get:"user"`
+
+	// OperationParams is the parameters of operation such as snapshot retention and config to change
+	OperationParams *api.ScheduledTaskOperationParams `charset:"utf8" create:"optional" list:"user" get:"user"`
 }
 
 func (stm *SScheduledTaskManager) ListItemFilter(ctx context.Context, q *sqlchemy.SQuery, userCred mcclient.TokenCredential, input api.ScheduledTaskListInput) (*sqlchemy.SQuery, error) {
@@ -140,6 +142,11 @@ func (st *SScheduledTask) getMoreDetails(ctx context.Context, userCred mcclient.
 		out.Timer = st.STimer.TimerDetails()
 	case api.ST_TYPE_CYCLE:
 		out.CycleTimer = st.STimer.CycleTimerDetails()
+	case api.ST_TYPE_CRON:
+		out.CronTimer = st.STimer.CronTimerDetails()
+	}
+	if st.ExclusionWindows != nil {
+		out.ExclusionWindows = *st.ExclusionWindows
 	}
 	out.TimerDesc = st.Description(ctx, zone)
 	// fill label
@@ -163,30 +170,122 @@ func (stm *SScheduledTaskManager) ValidateCreateData(ctx context.Context, userCr
 	if err != nil {
 		return input, err
 	}
-	if !utils.IsInStringArray(input.ScheduledType, []string{api.ST_TYPE_TIMING, api.ST_TYPE_CYCLE}) {
-		return input, httperrors.NewInputParameterError("unkown scheduled type '%s'", input.ScheduledType)
-	}
-	if !utils.IsInStringArray(input.ResourceType, []string{api.ST_RESOURCE_SERVER, api.ST_RESOURCE_CLOUDACCOUNT}) {
+	if _, ok := Modules[Resource(input.ResourceType)]; !ok {
 		return input, httperrors.NewInputParameterError("unkown resource type '%s'", input.ResourceType)
 	}
-	if !utils.IsInStringArray(input.Operation, []string{api.ST_RESOURCE_OPERATION_RESTART, api.ST_RESOURCE_OPERATION_STOP, api.ST_RESOURCE_OPERATION_START, api.ST_RESOURCE_OPERATION_SYNC}) {
-		return input, httperrors.NewInputParameterError("unkown resource operation '%s'", input.Operation)
+	if _, ok := ResourceOperationMap[fmt.Sprintf("%s.%s", input.ResourceType, input.Operation)]; !ok {
+		return input, httperrors.NewInputParameterError("unkown resource operation '%s' of '%s'", input.Operation, input.ResourceType)
+	}
+	input.OperationParams, err = validateOperationParams(ctx, userCred, input.Operation, input.OperationParams)
+	if err != nil {
+		return input, err
 	}
 	if !utils.IsInStringArray(input.LabelType, []string{api.ST_LABEL_ID, api.ST_LABEL_TAG}) {
 		return input, httperrors.NewInputParameterError("unkown label type '%s'", input.LabelType)
 	}
-	// check timer or cycletimer
-	if input.ScheduledType == api.ST_TYPE_TIMING {
+	input.ScheduledTaskTimerInput, err = validateTimerInput(input.ScheduledTaskTimerInput)
+	if err != nil {
+		return input, err
+	}
+	return input, nil
+}
+
+func validateTimerInput(input api.ScheduledTaskTimerInput) (api.ScheduledTaskTimerInput, error) {
+	var err error
+	// check timer, cycletimer or crontimer
+	switch input.ScheduledType {
+	case api.ST_TYPE_TIMING:
 		input.Timer, err = checkTimerCreateInput(input.Timer)
-	} else {
+	case api.ST_TYPE_CYCLE:
 		input.CycleTimer, err = checkCycleTimerCreateInput(input.CycleTimer)
+	case api.ST_TYPE_CRON:
+		input.CronTimer, err = checkCronTimerCreateInput(input.CronTimer)
+	default:
+		return input, httperrors.NewInputParameterError("unkown scheduled type '%s'", input.ScheduledType)
 	}
 	if err != nil {
 		return input, httperrors.NewInputParameterError("%v", err)
 	}
+	err = input.ExclusionWindows.Validate()
+	if err != nil {
+		return input, err
+	}
 	return input, nil
 }
 
+func newTimer(input api.ScheduledTaskTimerInput) STimer {
+	var timer STimer
+	switch input.ScheduledType {
+	case api.ST_TYPE_TIMING:
+		timer = STimer{
+			Type:      api.TIMER_TYPE_ONCE,
+			StartTime: input.Timer.ExecTime,
+			EndTime:   input.Timer.ExecTime,
+			NextTime:  input.Timer.ExecTime,
+		}
+	case api.ST_TYPE_CYCLE:
+		timer = STimer{
+			Type:      input.CycleTimer.CycleType,
+			Minute:    input.CycleTimer.Minute,
+			Hour:      input.CycleTimer.Hour,
+			StartTime: input.CycleTimer.StartTime,
+			EndTime:   input.CycleTimer.EndTime,
+			NextTime:  time.Time{},
+		}
+		timer.SetWeekDays(input.CycleTimer.WeekDays)
+		timer.SetMonthDays(input.CycleTimer.MonthDays)
+	case api.ST_TYPE_CRON:
+		timer = STimer{
+			Type:      api.TIMER_TYPE_CRON,
+			CronExpr:  input.CronTimer.Expr,
+			TimeZone:  input.CronTimer.TimeZone,
+			StartTime: input.CronTimer.StartTime,
+			EndTime:   input.CronTimer.EndTime,
+			NextTime:  time.Time{},
+		}
+	}
+	if len(input.ExclusionWindows) > 0 {
+		windows := input.ExclusionWindows
+		timer.ExclusionWindows = &windows
+	}
+	return timer
+}
+
+// PerformPreview returns the next fire times of the timer so that it can be validated before creating
+func (stm *SScheduledTaskManager) PerformPreview(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.ScheduledTaskPreviewInput) (jsonutils.JSONObject, error) {
+	var err error
+	input.ScheduledTaskTimerInput, err = validateTimerInput(input.ScheduledTaskTimerInput)
+	if err != nil {
+		return nil, err
+	}
+	if input.Count <= 0 {
+		input.Count = 10
+	}
+	if input.Count > 100 {
+		return nil, httperrors.NewOutOfRangeError("count should not be greater than 100")
+	}
+	timer := newTimer(input.ScheduledTaskTimerInput)
+	from := input.From
+	if from.IsZero() {
+		from = time.Now()
+	}
+	if from.Before(timer.StartTime) {
+		from = timer.StartTime
+	}
+	out := api.ScheduledTaskPreviewOutput{
+		FireTimes: []time.Time{},
+	}
+	for len(out.FireTimes) < input.Count {
+		next := timer.NextFireTime(from)
+		if next.IsZero() || (timer.hasEndTime() && next.After(timer.EndTime)) {
+			break
+		}
+		out.FireTimes = append(out.FireTimes, next)
+		from = next.Add(time.Nanosecond)
+	}
+	return jsonutils.Marshal(out), nil
+}
+
 func (st *SScheduledTask) PerformEnable(ctx context.Context, userCred mcclient.TokenCredential,
 	query jsonutils.JSONObject, input apis.PerformEnableInput) (jsonutils.JSONObject, error) {
 	err := db.EnabledPerformEnable(st, ctx, userCred, true)
@@ -228,26 +327,7 @@ func (st *SScheduledTask) PostCreate(ctx context.Context, userCred mcclient.Toke
 		createFailed(err.Error())
 		return
 	}
-	switch st.ScheduledType {
-	case api.ST_TYPE_TIMING:
-		st.STimer = STimer{
-			Type:      api.TIMER_TYPE_ONCE,
-			StartTime: input.Timer.ExecTime,
-			EndTime:   input.Timer.ExecTime,
-			NextTime:  input.Timer.ExecTime,
-		}
-	case api.ST_TYPE_CYCLE:
-		st.STimer = STimer{
-			Type:      input.CycleTimer.CycleType,
-			Minute:    input.CycleTimer.Minute,
-			Hour:      input.CycleTimer.Hour,
-			StartTime: input.CycleTimer.StartTime,
-			EndTime:   input.CycleTimer.EndTime,
-			NextTime:  time.Time{},
-		}
-		st.SetWeekDays(input.CycleTimer.WeekDays)
-		st.SetMonthDays(input.CycleTimer.MonthDays)
-	}
+	st.STimer = newTimer(input.ScheduledTaskTimerInput)
 	st.Update(time.Time{})
 	st.Status = api.ST_STATUS_READY
 	st.Enabled = tristate.True
@@ -342,6 +422,32 @@ func (st *SScheduledTask) PerformSetLabels(ctx context.Context, userCred mcclien
 	return nil, nil
 }
 
+func (st *SScheduledTask) PerformSetExclusionWindows(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.ScheduledTaskSetExclusionWindowsInput) (jsonutils.JSONObject, error) {
+	err := input.ExclusionWindows.Validate()
+	if err != nil {
+		return nil, err
+	}
+	diff, err := db.Update(st, func() error {
+		if len(input.ExclusionWindows) > 0 {
+			st.ExclusionWindows = &input.ExclusionWindows
+		} else {
+			st.ExclusionWindows = nil
+		}
+		// recalculate the next fire time skipping the new windows,
+		// the exec time of timing task is checked on execution
+		if st.Type != api.TIMER_TYPE_ONCE && !st.IsExpired {
+			st.NextTime = time.Time{}
+			st.Update(time.Time{})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "db.Update")
+	}
+	db.OpsLog.LogEvent(st, db.ACT_UPDATE, diff, userCred)
+	return nil, nil
+}
+
 func (st *SScheduledTask) PerformTrigger(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.ScheduledTaskTriggerInput) (jsonutils.JSONObject, error) {
 	go func() {
 		log.Infof("start to execute scheduled task '%s'", st.Id)
@@ -375,7 +481,7 @@ func (st *SScheduledTask) CustomizeDelete(ctx context.Context, userCred mcclient
 
 func (st *SScheduledTask) Action(ctx context.Context, userCred mcclient.TokenCredential) SAction {
 	session := auth.GetSession(ctx, userCred, "", "")
-	return Action.ResourceOperation(st.ResourceOperation()).Session(session)
+	return Action.ResourceOperation(st.ResourceOperation()).Session(session).ScheduledTask(st.Id, st.OperationParams)
 }
 
 func (st *SScheduledTask) ExecuteNotify(ctx context.Context, userCred mcclient.TokenCredential, name string) {
@@ -495,6 +601,14 @@ func (st *SScheduledTask) Execute(ctx context.Context, userCred mcclient.TokenCr
 }
 
 func (st *SScheduledTask) NewActivity(ctx context.Context, reject bool) (*SScheduledTaskActivity, error) {
+	reason := ""
+	if reject {
+		reason = "This Scheduled Task is being executed now"
+	}
+	return st.newActivity(ctx, reject, reason)
+}
+
+func (st *SScheduledTask) newActivity(ctx context.Context, reject bool, reason string) (*SScheduledTaskActivity, error) {
 	now := time.Now()
 	sa := &SScheduledTaskActivity{
 		StartTime: now,
@@ -504,7 +618,7 @@ func (st *SScheduledTask) NewActivity(ctx context.Context, reject bool) (*SSched
 	if reject {
 		sa.Status = api.ST_ACTIVITY_STATUS_REJECT
 		sa.EndTime = now
-		sa.Reason = "This Scheduled Task is being executed now"
+		sa.Reason = reason
 	}
 	err := ScheduledTaskActivityManager.TableSpec().Insert(ctx, sa)
 	if err != nil {
@@ -574,9 +688,21 @@ func (stm *SScheduledTaskManager) Timer(ctx context.Context, userCred mcclient.T
 					return
 				}
 			}
-			err := st.Execute(ctx, userCred)
-			if err != nil {
-				log.Errorf("unable to execute scheduled task '%s'", st.Id)
+			if w := st.ExclusionWindow(st.NextTime); w != nil {
+				// the exclusion windows may be changed after NextTime was calculated
+				reason := fmt.Sprintf("%s is in exclusion window from %s to %s", st.NextTime, w.Start, w.End)
+				if len(w.Reason) > 0 {
+					reason = fmt.Sprintf("%s: %s", reason, w.Reason)
+				}
+				_, err := st.newActivity(ctx, true, reason)
+				if err != nil {
+					log.Errorf("unable to record rejected activity of scheduled task '%s': %v", st.Id, err)
+				}
+			} else {
+				err := st.Execute(ctx, userCred)
+				if err != nil {
+					log.Errorf("unable to execute scheduled task '%s'", st.Id)
+				}
 			}
 			st.Update(timeScope.End)
 			err = stm.TableSpec().InsertOrUpdate(ctx, &st)
@@ -594,6 +720,8 @@ func (stm *SScheduledTaskManager) Timer(ctx context.Context, userCred mcclient.T
 func init() {
 	Register(ResourceServer, compute.Servers.ResourceManager)
 	Register(ResourceCloudAccount, compute.Cloudaccounts)
+	Register(ResourceDisk, compute.Disks)
+	Register(ResourceScalingGroup, compute.ScalingGroup)
 }
 
 // Modules describe the correspondence between Resource and modulebase.ResourceManager,
@@ -611,6 +739,8 @@ type Resource string
 const (
 	ResourceServer       Resource = api.ST_RESOURCE_SERVER
 	ResourceCloudAccount Resource = api.ST_RESOURCE_CLOUDACCOUNT
+	ResourceDisk         Resource = api.ST_RESOURCE_DISK
+	ResourceScalingGroup Resource = api.ST_RESOURCE_SCALINGGROUP
 )
 
 // ResourceOperation describe the operation for onecloud resource like create, update, delete and so on.
@@ -620,6 +750,10 @@ type ResourceOperation struct {
 	StatusSuccess []string
 	Fail          []ResourceOperationFail
 	Params        *jsonutils.JSONDict
+	// Timeout overrides the timeout of SAction if it's not zero
+	Timeout time.Duration
+	// Apply is used instead of performing the action named Operation on the resource if it's not nil
+	Apply func(r SAction, id string) (success bool, failReason string)
 }
 
 type ResourceOperationFail struct {
@@ -667,6 +801,12 @@ func init() {
 		fmt.Sprintf("%s.%s", ResourceServer, api.ST_RESOURCE_OPERATION_STOP):       ServerStop,
 		fmt.Sprintf("%s.%s", ResourceServer, api.ST_RESOURCE_OPERATION_RESTART):    ServerRestart,
 		fmt.Sprintf("%s.%s", ResourceCloudAccount, api.ST_RESOURCE_OPERATION_SYNC): CloudAccountSync,
+
+		fmt.Sprintf("%s.%s", ResourceServer, api.ST_RESOURCE_OPERATION_SNAPSHOT):      ServerSnapshot,
+		fmt.Sprintf("%s.%s", ResourceServer, api.ST_RESOURCE_OPERATION_CHANGE_CONFIG): ServerChangeConfig,
+		fmt.Sprintf("%s.%s", ResourceServer, api.ST_RESOURCE_OPERATION_RUN_PLAYBOOK):  ServerRunPlaybook,
+		fmt.Sprintf("%s.%s", ResourceDisk, api.ST_RESOURCE_OPERATION_SNAPSHOT):        DiskSnapshot,
+		fmt.Sprintf("%s.%s", ResourceScalingGroup, api.ST_RESOURCE_OPERATION_SCALE):   ScalingGroupScale,
 	}
 }
 
@@ -689,6 +829,9 @@ type SAction struct {
 	operation ResourceOperation
 	session   *mcclient.ClientSession
 	timeout   time.Duration
+
+	taskId string
+	params api.ScheduledTaskOperationParams
 }
 
 func (r SAction) ResourceOperation(oper ResourceOperation) SAction {
@@ -706,6 +849,15 @@ func (r SAction) Timeout(time time.Duration) SAction {
 	return r
 }
 
+// ScheduledTask sets the scheduled task which the action belongs to and the operation parameters of it
+func (r SAction) ScheduledTask(taskId string, params *api.ScheduledTaskOperationParams) SAction {
+	r.taskId = taskId
+	if params != nil {
+		r.params = *params
+	}
+	return r
+}
+
 type WrapperListOptions struct {
 	options.BaseListOptions
 }
@@ -733,7 +885,9 @@ func (r SAction) List(opts *WrapperListOptions) (map[string]string, error) {
 }
 
 func (r SAction) Apply(id string) (success bool, failReason string) {
-	success = true
+	if r.operation.Apply != nil {
+		return r.operation.Apply(r, id)
+	}
 	resourceManager, ok := Modules[r.operation.Resource]
 	if !ok {
 		return false, fmt.Sprintf("no such resource '%s' in Modules", r.operation.Resource)
@@ -750,57 +904,75 @@ func (r SAction) Apply(id string) (success bool, failReason string) {
 	}
 	err := requestFunc(r.session, id, r.operation.Params)
 	if err != nil {
-		clientErr, _ := err.(*httputils.JSONClientError)
-		return false, clientErr.Details
+		return false, errReason(err)
 	}
 	if len(r.operation.StatusSuccess) == 0 {
 		return true, ""
 	}
-	// wait for status
-	timer := time.NewTimer(r.timeout)
+	return r.waitStatus(resourceManager, id, r.operation.StatusSuccess, r.operation.Fail)
+}
+
+// waitStatus waits for the resource to be in one of status success, the reason of failure
+// is fetched from the opslog of resource if the status is one of fails
+func (r SAction) waitStatus(resourceManager modulebase.ResourceManager, id string, success []string, fails []ResourceOperationFail) (bool, string) {
+	return r.wait(func() (bool, bool, string) {
+		ret, e := resourceManager.GetSpecific(r.session, id, "status", nil)
+		if e != nil {
+			log.Errorf("fail to exec resouce(%s.%s).GetStatus: %s", r.operation.Resource, id, e.Error())
+			return false, false, ""
+		}
+		status, _ := ret.GetString("status")
+		if utils.IsInStringArray(status, success) {
+			return true, true, ""
+		}
+		for _, fail := range fails {
+			if status != fail.Status {
+				continue
+			}
+			if len(fail.LogEvent) == 0 {
+				return true, false, fmt.Sprintf("status of %s is %s", id, status)
+			}
+			params := jsonutils.NewDict()
+			params.Add(jsonutils.NewString(id), "obj_id")
+			params.Add(jsonutils.NewStringArray([]string{fail.LogEvent}), "action")
+			params.Add(jsonutils.NewInt(1), "limit")
+			events, err := compute.Logs.List(r.session, params)
+			if err != nil {
+				log.Errorf("Logs.List failed: %s", err.Error())
+				return false, false, ""
+			}
+			if len(events.Data) == 0 {
+				log.Errorf("These is no opslog about action '%s' for %s.%s", fail.LogEvent, r.operation.Resource, id)
+				return false, false, ""
+			}
+			reason, _ := events.Data[0].GetString("notes")
+			return true, false, reason
+		}
+		return false, false, ""
+	})
+}
+
+// wait calls check every 10 seconds until it's done or timeout
+func (r SAction) wait(check func() (done bool, success bool, failReason string)) (bool, string) {
+	timeout := r.timeout
+	if r.operation.Timeout > 0 {
+		timeout = r.operation.Timeout
+	}
+	timer := time.NewTimer(timeout)
 	ticker := time.NewTicker(10 * time.Second)
 	defer func() {
 		ticker.Stop()
 		timer.Stop()
 	}()
 	for {
+		done, success, reason := check()
+		if done {
+			return success, reason
+		}
 		select {
-		default:
-			ret, e := resourceManager.GetSpecific(r.session, id, "status", nil)
-			if e != nil {
-				log.Errorf("fail to exec resouce(%s.%s).GetStatus: %s", r.operation.Resource, id, e.Error())
-				<-ticker.C
-				continue
-			}
-			status, _ := ret.GetString("status")
-			if utils.IsInStringArray(status, r.operation.StatusSuccess) {
-				return
-			}
-			for _, fail := range r.operation.Fail {
-				if status != fail.Status {
-					continue
-				}
-				params := jsonutils.NewDict()
-				params.Add(jsonutils.NewString(id), "obj_id")
-				params.Add(jsonutils.NewStringArray([]string{fail.LogEvent}), "action")
-				params.Add(jsonutils.NewInt(1), "limit")
-				events, err := compute.Logs.List(r.session, params)
-				if err != nil {
-					log.Errorf("Logs.List failed: %s", err.Error())
-					<-ticker.C
-					continue
-				}
-				if len(events.Data) == 0 {
-					log.Errorf("These is no opslog about action '%s' for %s.%s: %s", fail.LogEvent, r.operation.Resource, id, err.Error())
-					<-ticker.C
-					continue
-				}
-				reason, _ := events.Data[0].GetString("notes")
-				return false, reason
-			}
-			<-ticker.C
+		case <-ticker.C:
 		case <-timer.C:
-			log.Errorf("timeout(%s) to exec resource(%s.%s).%s", r.timeout.String(), r.operation.Resource, id, r.operation.Operation)
+			log.Errorf("timeout(%s) to exec resource(%s).%s", timeout.String(), r.operation.Resource, r.operation.Operation)
 			return false, "timeout"
 		}
 	}
@@ -23,8 +23,10 @@ import (
 	"time"
 
 	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
 
 	api "yunion.io/x/onecloud/pkg/apis/scheduledtask"
+	"yunion.io/x/onecloud/pkg/cloudcommon/cronman"
 	"yunion.io/x/onecloud/pkg/i18n"
 	"yunion.io/x/onecloud/pkg/util/bitmap"
 )
@@ -41,6 +43,13 @@ type STimer struct {
 	// 0-31 0 is unlimited
 	MonthDays uint32 `nullable:"false"`
 
+	// CronExpr is the cron expression of cron type
+	CronExpr string `width:"128" charset:"ascii"`
+	// TimeZone is the time zone in which CronExpr is evaluated
+	TimeZone string `width:"64" charset:"ascii"`
+	// ExclusionWindows are periods in which the timer should not bell
+	ExclusionWindows *api.SExclusionWindows `charset:"utf8"`
+
 	// StartTime represent the start time of this timer
 	StartTime time.Time
 	// EndTime represent deadline of this timer
@@ -55,7 +64,7 @@ func (st *STimer) Update(now time.Time) {
 	if now.IsZero() {
 		now = time.Now()
 	}
-	if !now.Before(st.EndTime) {
+	if st.hasEndTime() && !now.Before(st.EndTime) {
 		st.IsExpired = true
 		return
 	}
@@ -66,6 +75,87 @@ func (st *STimer) Update(now time.Time) {
 		return
 	}
 
+	newNextTime := st.NextFireTime(now)
+	log.Debugf("The final NextTime: %s", newNextTime)
+	if newNextTime.IsZero() {
+		st.IsExpired = true
+		return
+	}
+	st.NextTime = newNextTime
+	if st.hasEndTime() && st.NextTime.After(st.EndTime) {
+		st.IsExpired = true
+	}
+}
+
+// hasEndTime reports whether the timer has deadline, the end time of cron timer is optional
+func (st *STimer) hasEndTime() bool {
+	return st.Type != api.TIMER_TYPE_CRON || !st.EndTime.IsZero()
+}
+
+// NextFireTime returns the first fire time not earlier than now which is not in the exclusion windows
+func (st *STimer) NextFireTime(now time.Time) time.Time {
+	return cronman.NewCalendarTimer(st, st.Calendar()).Next(now)
+}
+
+// Calendar returns the exclusion windows of timer
+func (st *STimer) Calendar() *cronman.SCalendar {
+	if st.ExclusionWindows == nil || len(*st.ExclusionWindows) == 0 {
+		return nil
+	}
+	calendar := &cronman.SCalendar{}
+	for _, w := range *st.ExclusionWindows {
+		calendar.Windows = append(calendar.Windows, cronman.SExclusionWindow{Start: w.Start, End: w.End})
+	}
+	return calendar
+}
+
+// ExclusionWindow returns the exclusion window contains t, nil if t is not excluded
+func (st *STimer) ExclusionWindow(t time.Time) *api.SExclusionWindow {
+	if st.ExclusionWindows == nil {
+		return nil
+	}
+	for i, w := range *st.ExclusionWindows {
+		if !t.Before(w.Start) && t.Before(w.End) {
+			return &(*st.ExclusionWindows)[i]
+		}
+	}
+	return nil
+}
+
+func (st *STimer) location() (*time.Location, error) {
+	if len(st.TimeZone) == 0 {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(st.TimeZone)
+}
+
+func (st *STimer) cronSchedule() (*cronman.SCronSchedule, error) {
+	loc, err := st.location()
+	if err != nil {
+		return nil, errors.Wrapf(err, "load time zone %s", st.TimeZone)
+	}
+	return cronman.ParseCronExpr(st.CronExpr, loc)
+}
+
+// Next returns the first fire time not earlier than now regardless of the exclusion windows,
+// STimer is a cronman.ICronTimer
+func (st *STimer) Next(now time.Time) time.Time {
+	switch st.Type {
+	case api.TIMER_TYPE_ONCE:
+		if now.After(st.StartTime) {
+			return time.Time{}
+		}
+		return st.StartTime
+	case api.TIMER_TYPE_CRON:
+		sched, err := st.cronSchedule()
+		if err != nil {
+			log.Errorf("invalid cron timer %q: %v", st.CronExpr, err)
+			return time.Time{}
+		}
+		// SCronSchedule.Next returns the time after now
+		return sched.Next(now.Add(-time.Nanosecond))
+	}
+
 	newNextTime := time.Date(now.Year(), now.Month(), now.Day(), st.Hour, st.Minute, 0, 0, time.UTC).In(now.Location())
 	if now.After(newNextTime) {
 		newNextTime = newNextTime.AddDate(0, 0, 1)
@@ -102,11 +192,7 @@ func (st *STimer) Update(now time.Time) {
 		// day
 
 	}
-	log.Debugf("The final NextTime: %s", newNextTime)
-	st.NextTime = newNextTime
-	if st.NextTime.After(st.EndTime) {
-		st.IsExpired = true
-	}
+	return newNextTime
 }
 
 // MonthDaySum calculate the number of month's days
@@ -155,6 +241,15 @@ func (st *STimer) CycleTimerDetails() api.CycleTimerDetails {
 	return out
 }
 
+func (st *STimer) CronTimerDetails() api.CronTimerDetails {
+	return api.CronTimerDetails{
+		Expr:      st.CronExpr,
+		TimeZone:  st.TimeZone,
+		StartTime: st.StartTime,
+		EndTime:   st.EndTime,
+	}
+}
+
 func checkTimerCreateInput(in api.TimerCreateInput) (api.TimerCreateInput, error) {
 	now := time.Now()
 	if now.After(in.ExecTime) {
@@ -194,6 +289,12 @@ func (st *STimer) descChinese(zone *time.Location) string {
 	switch st.Type {
 	case api.TIMER_TYPE_ONCE:
 		return fmt.Sprintf("单次 %s触发", st.StartTime.In(zone).Format(format))
+	case api.TIMER_TYPE_CRON:
+		desc := fmt.Sprintf("按cron表达式【%s】(时区%s)触发", st.CronExpr, st.timeZoneDesc())
+		if st.EndTime.IsZero() {
+			return desc
+		}
+		return fmt.Sprintf("%s 有效时间为%s至%s", desc, st.StartTime.In(zone).Format(format), st.EndTime.In(zone).Format(format))
 	case api.TIMER_TYPE_DAY:
 		prefix = "每天"
 	case api.TIMER_TYPE_WEEK:
@@ -214,6 +315,13 @@ func (st *STimer) descChinese(zone *time.Location) string {
 	return fmt.Sprintf("%s %s触发 有效时间为%s至%s", prefix, st.hourMinutesDesc(zone), st.StartTime.In(zone).Format(format), st.EndTime.In(zone).Format(format))
 }
 
+func (st *STimer) timeZoneDesc() string {
+	if len(st.TimeZone) == 0 {
+		return "UTC"
+	}
+	return st.TimeZone
+}
+
 func (st *STimer) hourMinutesDesc(zone *time.Location) string {
 	now := time.Now()
 	t := time.Date(now.Year(), now.Month(), now.Day(), st.Hour, st.Minute, 0, 0, time.UTC).In(zone)
@@ -232,6 +340,8 @@ func (st *STimer) descEnglish(zone *time.Location) string {
 		detail = st.weekDaysDesc(zone)
 	case api.TIMER_TYPE_MONTH:
 		detail = st.monthDaysDesc(zone)
+	case api.TIMER_TYPE_CRON:
+		detail = fmt.Sprintf("cron %q in %s", st.CronExpr, st.timeZoneDesc())
 	}
 	if st.EndTime.IsZero() {
 		return detail
@@ -325,3 +435,44 @@ func checkCycleTimerCreateInput(in api.CycleTimerCreateInput) (api.CycleTimerCre
 	}
 	return in, nil
 }
+
+func checkCronTimerCreateInput(in api.CronTimerCreateInput) (api.CronTimerCreateInput, error) {
+	now := time.Now()
+	in.Expr = strings.TrimSpace(in.Expr)
+	if len(in.Expr) == 0 {
+		return in, fmt.Errorf("expr of cron timer should not be empty")
+	}
+	loc := time.UTC
+	if len(in.TimeZone) > 0 {
+		var err error
+		loc, err = time.LoadLocation(in.TimeZone)
+		if err != nil {
+			return in, fmt.Errorf("unknown time zone %s", in.TimeZone)
+		}
+	}
+	sched, err := cronman.ParseCronExpr(in.Expr, loc)
+	if err != nil {
+		return in, err
+	}
+	// scheduled tasks are checked every minute
+	if sched.FireTimesPerMinute() > 1 {
+		return in, fmt.Errorf("cron timer firing more than once per minute is not supported")
+	}
+	if !in.EndTime.IsZero() {
+		if now.After(in.EndTime) {
+			return in, fmt.Errorf("end_time is earlier than now")
+		}
+		if in.StartTime.After(in.EndTime) {
+			return in, fmt.Errorf("start_time is later than end_time")
+		}
+	}
+	from := now
+	if from.Before(in.StartTime) {
+		from = in.StartTime
+	}
+	next := sched.Next(from)
+	if next.IsZero() || (!in.EndTime.IsZero() && next.After(in.EndTime)) {
+		return in, fmt.Errorf("cron timer %q never fires in the valid time", in.Expr)
+	}
+	return in, nil
+}
@@ -0,0 +1,67 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guest
+
+import (
+	"context"
+
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/scheduler/algorithm/predicates"
+	"yunion.io/x/onecloud/pkg/scheduler/core"
+)
+
+// TopologySpreadPredicate filters out the candidates whose topology domain
+// would violate the hard topology spread constraints of the request and its
+// instance groups.
+type TopologySpreadPredicate struct {
+	predicates.BasePredicate
+}
+
+func (p *TopologySpreadPredicate) Name() string {
+	return "topology_spread"
+}
+
+func (p *TopologySpreadPredicate) Clone() core.FitPredicate {
+	return &TopologySpreadPredicate{}
+}
+
+func (p *TopologySpreadPredicate) PreExecute(ctx context.Context, u *core.Unit, cs []core.Candidater) (bool, error) {
+	spreads, err := core.NewTopologySpreads(u.SchedData(), cs)
+	if err != nil {
+		return false, errors.Wrap(err, "NewTopologySpreads")
+	}
+	if len(spreads) == 0 {
+		return false, nil
+	}
+	u.TopologySpreads = spreads
+	return true, nil
+}
+
+func (p *TopologySpreadPredicate) Execute(ctx context.Context, u *core.Unit, c core.Candidater) (bool, []core.PredicateFailureReason, error) {
+	h := predicates.NewPredicateHelper(p, u, c)
+
+	for _, ts := range u.TopologySpreads {
+		if !ts.Constraint.IsHard() {
+			continue
+		}
+		if ok, reason := ts.Fits(c.IndexKey()); !ok {
+			h.Exclude(reason)
+			break
+		}
+	}
+
+	return h.GetResult()
+}
@@ -0,0 +1,151 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guest
+
+import (
+	"math"
+
+	"yunion.io/x/pkg/utils"
+
+	computeapi "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/scheduler/algorithm/priorities"
+	"yunion.io/x/onecloud/pkg/scheduler/api"
+	"yunion.io/x/onecloud/pkg/scheduler/core"
+	"yunion.io/x/onecloud/pkg/scheduler/core/score"
+	o "yunion.io/x/onecloud/pkg/scheduler/options"
+)
+
+// the cheapest candidate gets COST_MAX_SCORE times the weight
+const COST_MAX_SCORE = 10
+
+// CostPriority favours the candidates of the lowest estimated monthly cost,
+// which is priced by the server sku and the storages of the candidate.
+type CostPriority struct {
+	priorities.BasePriority
+}
+
+func (p *CostPriority) Name() string {
+	return "host_cost"
+}
+
+func (p *CostPriority) Clone() core.Priority {
+	return &CostPriority{}
+}
+
+func (p *CostPriority) Map(u *core.Unit, c core.Candidater) (core.HostPriority, error) {
+	h := priorities.NewPriorityHelper(p, u, c)
+
+	if cost, ok := estimateMonthlyCost(u.SchedData(), c.Getter()); ok {
+		u.SetEstimatedCost(c.IndexKey(), cost)
+	}
+	return h.GetResult()
+}
+
+func (p *CostPriority) Reduce(u *core.Unit, cs []core.Candidater, _ core.HostPriorityList) error {
+	weight := getCostWeight(u.SchedData())
+	if weight <= 0 {
+		return nil
+	}
+	costs := make(map[string]float64)
+	for _, c := range cs {
+		if cost, ok := u.GetEstimatedCost(c.IndexKey()); ok {
+			costs[c.IndexKey()] = cost
+		}
+	}
+	for id, val := range costScores(costs, weight) {
+		u.SetScore(id, score.NewScore(score.TScore(val), p.Name()))
+	}
+	return nil
+}
+
+func getCostWeight(d *api.SchedInfo) int {
+	if d.CostWeight != nil {
+		return *d.CostWeight
+	}
+	return o.GetOptions().CostPriorityWeight
+}
+
+// costScores scores the candidates linearly from the cheapest to the most
+// expensive, nothing is scored if all the costs are the same.
+func costScores(costs map[string]float64, weight int) map[string]int {
+	ret := make(map[string]int)
+	if len(costs) == 0 {
+		return ret
+	}
+	minCost, maxCost := math.MaxFloat64, 0.0
+	for _, cost := range costs {
+		minCost = math.Min(minCost, cost)
+		maxCost = math.Max(maxCost, cost)
+	}
+	if maxCost-minCost < 0.01 {
+		return ret
+	}
+	for id, cost := range costs {
+		ret[id] = int(math.Round(float64(weight*COST_MAX_SCORE) * (maxCost - cost) / (maxCost - minCost)))
+	}
+	return ret
+}
+
+func isPrivateCandidate(getter core.CandidatePropertyGetter) bool {
+	region := getter.Region()
+	if region == nil {
+		return true
+	}
+	switch region.Provider {
+	case computeapi.CLOUD_PROVIDER_ONECLOUD, computeapi.CLOUD_PROVIDER_CLOUDPODS, computeapi.CLOUD_PROVIDER_VMWARE:
+		return true
+	}
+	return utils.IsInStringArray(region.Provider, computeapi.PRIVATE_CLOUD_PROVIDERS)
+}
+
+// estimateMonthlyCost returns false if the price of the instance itself is
+// unknown, disks without price are counted free.
+func estimateMonthlyCost(d *api.SchedInfo, getter core.CandidatePropertyGetter) (float64, bool) {
+	opts := o.GetOptions().CostOptions
+	isPrivate := isPrivateCandidate(getter)
+
+	cost := 0.0
+	if len(d.InstanceType) > 0 {
+		if sku := getter.Sku(d.InstanceType); sku != nil {
+			cost = sku.MonthlyPrice()
+		}
+	}
+	if cost <= 0 && isPrivate {
+		cost = float64(d.Ncpu)*opts.PrivateCpuCoreMonthlyPrice + float64(d.Memory)/1024*opts.PrivateMemoryGbMonthlyPrice
+	}
+	if cost <= 0 {
+		return 0, false
+	}
+
+	for _, disk := range d.Disks {
+		price := 0.0
+		for _, storage := range getter.Storages() {
+			if storage.SStorage == nil || storage.MonthlyPricePerGb <= 0 {
+				continue
+			}
+			if len(disk.Backend) > 0 && storage.StorageType != disk.Backend {
+				continue
+			}
+			if price <= 0 || storage.MonthlyPricePerGb < price {
+				price = storage.MonthlyPricePerGb
+			}
+		}
+		if price <= 0 && isPrivate {
+			price = opts.PrivateStorageGbMonthlyPrice
+		}
+		cost += float64(disk.SizeMb) / 1024 * price
+	}
+	return cost, true
+}
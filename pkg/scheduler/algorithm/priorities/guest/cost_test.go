@@ -0,0 +1,62 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guest
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCostScores(t *testing.T) {
+	cases := []struct {
+		name   string
+		costs  map[string]float64
+		weight int
+		want   map[string]int
+	}{
+		{
+			name:   "empty",
+			costs:  map[string]float64{},
+			weight: 1,
+			want:   map[string]int{},
+		},
+		{
+			name:   "same cost",
+			costs:  map[string]float64{"a": 100, "b": 100},
+			weight: 1,
+			want:   map[string]int{},
+		},
+		{
+			name:   "linear",
+			costs:  map[string]float64{"a": 100, "b": 150, "c": 200},
+			weight: 1,
+			want:   map[string]int{"a": 10, "b": 5, "c": 0},
+		},
+		{
+			name:   "weighted",
+			costs:  map[string]float64{"a": 100, "b": 175, "c": 200},
+			weight: 3,
+			want:   map[string]int{"a": 30, "b": 8, "c": 0},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := costScores(c.costs, c.weight)
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("want %v, got %v", c.want, got)
+			}
+		})
+	}
+}
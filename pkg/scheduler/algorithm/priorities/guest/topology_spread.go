@@ -0,0 +1,63 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guest
+
+import (
+	"yunion.io/x/onecloud/pkg/scheduler/algorithm/priorities"
+	"yunion.io/x/onecloud/pkg/scheduler/core"
+)
+
+// TopologySpreadPriority favours the candidates in the least crowded topology
+// domains of the soft topology spread constraints, the hard ones are enforced
+// by TopologySpreadPredicate.
+type TopologySpreadPriority struct {
+	priorities.BasePriority
+}
+
+func (p *TopologySpreadPriority) Name() string {
+	return "topology_spread"
+}
+
+func (p *TopologySpreadPriority) Clone() core.Priority {
+	return &TopologySpreadPriority{}
+}
+
+func (p *TopologySpreadPriority) PreExecute(u *core.Unit, cs []core.Candidater) (bool, []core.PredicateFailureReason, error) {
+	for _, ts := range u.TopologySpreads {
+		if !ts.Constraint.IsHard() {
+			return true, nil, nil
+		}
+	}
+	return false, nil, nil
+}
+
+func (p *TopologySpreadPriority) Map(u *core.Unit, c core.Candidater) (core.HostPriority, error) {
+	h := priorities.NewPriorityHelper(p, u, c)
+
+	var skew int64
+	for _, ts := range u.TopologySpreads {
+		if ts.Constraint.IsHard() {
+			continue
+		}
+		if s := ts.Skew(c.IndexKey()); s > 0 {
+			skew += s
+		}
+	}
+	if skew > 0 {
+		h.SetScore(-int(skew) * core.PriorityStep)
+	}
+
+	return h.GetResult()
+}
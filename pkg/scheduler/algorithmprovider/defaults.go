@@ -49,6 +49,7 @@ func defaultPredicates() sets.String {
 		factory.RegisterFitPredicate("p-CloudproviderschedtagFilter", predicates.NewCloudproviderSchedtagPredicate()),
 		factory.RegisterFitPredicate("q-CloudregionschedtagFilter", predicates.NewCloudregionSchedtagPredicate()),
 		factory.RegisterFitPredicate("r-ZoneschedtagFilter", predicates.NewZoneSchedtagPredicate()),
+		factory.RegisterFitPredicate("s-GuestTopologySpreadFilter", &predicateguest.TopologySpreadPredicate{}),
 		factory.RegisterFitPredicate("z-QuotaFilter", &predicates.SQuotaPredicate{}),
 	)
 }
@@ -59,5 +60,7 @@ func defaultPriorities() sets.String {
 		factory.RegisterPriority("guest-lowload", &priorityguest.LowLoadPriority{}, 1),
 		factory.RegisterPriority("guest-creating", &priorityguest.CreatingPriority{}, 1),
 		factory.RegisterPriority("guest-capacity", &priorityguest.CapacityPriority{}, 1),
+		factory.RegisterPriority("guest-cost", &priorityguest.CostPriority{}, 1),
+		factory.RegisterPriority("guest-topology-spread", &priorityguest.TopologySpreadPriority{}, 1),
 	)
 }
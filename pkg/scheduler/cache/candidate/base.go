@@ -15,6 +15,7 @@
 package candidate
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
@@ -58,6 +59,7 @@ type BaseHostDesc struct {
 	PendingUsage  map[string]interface{} `json:"pending_usage"`
 
 	ClassMetadata map[string]string `json:"class_metadata"`
+	Metadata      map[string]string `json:"metadata"`
 }
 
 type baseHostGetter struct {
@@ -154,6 +156,10 @@ func (b baseHostGetter) GetAllClassMetadata() (map[string]string, error) {
 	return b.h.ClassMetadata, nil
 }
 
+func (b baseHostGetter) HostMetadata() map[string]string {
+	return b.h.Metadata
+}
+
 func (b baseHostGetter) GetFreeGroupCount(groupId string) (int, error) {
 	// Must Be
 	scg, ok := b.h.InstanceGroups[groupId]
@@ -343,6 +349,10 @@ func newBaseHostDesc(b *baseBuilder, host *computemodels.SHost) (*BaseHostDesc,
 		return nil, fmt.Errorf("Fill class metadata error: %v", err)
 	}
 
+	if err := desc.fillMetadata(host); err != nil {
+		return nil, fmt.Errorf("Fill metadata error: %v", err)
+	}
+
 	if err := desc.fillIpmiInfo(host); err != nil {
 		return nil, fmt.Errorf("Fill ipmi info error: %v", err)
 	}
@@ -693,6 +703,15 @@ func (b *BaseHostDesc) fillClassMetadata(host *computemodels.SHost) error {
 	return nil
 }
 
+func (b *BaseHostDesc) fillMetadata(host *computemodels.SHost) error {
+	meta, err := host.GetAllMetadata(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+	b.Metadata = meta
+	return nil
+}
+
 func (b *BaseHostDesc) fillIpmiInfo(host *computemodels.SHost) error {
 	info, err := host.GetIpmiInfo()
 	if err != nil {
@@ -407,6 +407,13 @@ type Unit struct {
 	SelectPriorityMap        map[string]SSelectPriority
 	SelectPriorityUpdaterMap map[string]SSelectPriorityUpdater
 	SelectPriorityLock       sync.Mutex
+
+	// estimated monthly cost of the candidates with known prices
+	EstimatedCostMap map[string]float64
+	costLock         sync.Mutex
+
+	// topology spread constraints of the request and its instance groups
+	TopologySpreads []*TopologySpread
 }
 
 func NewScheduleUnit(info *api.SchedInfo, schedManager interface{}) *Unit {
@@ -431,6 +438,8 @@ func NewScheduleUnit(info *api.SchedInfo, schedManager interface{}) *Unit {
 
 		SelectPriorityMap:        spmap,
 		SelectPriorityUpdaterMap: spumap,
+
+		EstimatedCostMap: make(map[string]float64),
 	}
 	return unit
 }
@@ -663,6 +672,23 @@ func (u *Unit) GetScore(id string) Score {
 	return scoreObj
 }
 
+func (u *Unit) SetEstimatedCost(id string, cost float64) {
+	u.costLock.Lock()
+	defer u.costLock.Unlock()
+
+	u.EstimatedCostMap[id] = cost
+}
+
+// GetEstimatedCost returns the estimated monthly cost of the candidate,
+// false if the cost is unknown
+func (u *Unit) GetEstimatedCost(id string) (float64, bool) {
+	u.costLock.Lock()
+	defer u.costLock.Unlock()
+
+	cost, ok := u.EstimatedCostMap[id]
+	return cost, ok
+}
+
 func (u *Unit) GetScoreDetails(id string) string {
 	if score, ok := u.ScoreMap[id]; ok {
 		return score.String()
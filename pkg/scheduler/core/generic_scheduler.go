@@ -186,6 +186,9 @@ func newSchedResultByCtx(u *Unit, count int64, c Candidater) *SchedResultItem {
 		AllocatedResource: u.GetAllocatedResource(id),
 		SchedData:         u.SchedData(),
 	}
+	if cost, ok := u.GetEstimatedCost(id); ok {
+		r.EstimatedMonthlyCost = cost
+	}
 
 	if showDetails {
 		r.CapacityDetails = GetCapacities(u, id)
@@ -33,6 +33,11 @@ func transToInstanceGroupSchedResult(result *SchedResultItemList, schedInfo *api
 		item.Count = 0
 	}
 	guestInfos, backGuestInfos, groups := generateGuestInfo(schedInfo)
+	if result.Unit != nil {
+		for i := range guestInfos {
+			guestInfos[i].topologySpreads = result.Unit.TopologySpreads
+		}
+	}
 	hosts := buildHosts(result, groups)
 	if len(backGuestInfos) > 0 {
 		return getBackupSchedResult(hosts, guestInfos, backGuestInfos, schedInfo.SessionId)
@@ -44,6 +49,8 @@ type sGuestInfo struct {
 	schedInfo            *api.SchedInfo
 	instanceGroupsDetail map[string]*models.SGroup
 	preferHost           string
+	// the backup guests are not counted in topology spread
+	topologySpreads []*TopologySpread
 }
 
 type sSchedResultItem struct {
@@ -51,6 +58,35 @@ type sSchedResultItem struct {
 	instanceGroupCapacity map[string]int64
 	masterCount           int64
 	backupCount           int64
+	// topology domains counted by the last markHostUsed
+	spreadDomains []string
+}
+
+// topologySkew sums the skews of the soft topology spread constraints
+func (item *sSchedResultItem) topologySkew(spreads []*TopologySpread) int64 {
+	var skew int64
+	for _, ts := range spreads {
+		if ts.Constraint.IsHard() {
+			continue
+		}
+		if s := ts.Skew(item.ID); s > 0 {
+			skew += s
+		}
+	}
+	return skew
+}
+
+// fitTopology checks the hard topology spread constraints
+func (item *sSchedResultItem) fitTopology(spreads []*TopologySpread) bool {
+	for _, ts := range spreads {
+		if !ts.Constraint.IsHard() {
+			continue
+		}
+		if ok, _ := ts.Fits(item.ID); !ok {
+			return false
+		}
+	}
+	return true
 }
 
 func (item *sSchedResultItem) minInstanceGroupCapacity(groupSet map[string]*models.SGroup) int64 {
@@ -95,7 +131,7 @@ func buildHosts(result *SchedResultItemList, groups map[string]*models.SGroup) [
 // sortHost sorts the host for guest that is the backup one of the high-availability guest
 // if isBackup is true and the master one if isBackup is false.
 func sortHosts(hosts []*sSchedResultItem, guestInfo *sGuestInfo, isBackup *bool) {
-	sortIndexi, sortIndexj := make([]int64, 5), make([]int64, 5)
+	sortIndexi, sortIndexj := make([]int64, 6), make([]int64, 6)
 	sort.Slice(hosts, func(i, j int) bool {
 		switch {
 		case isBackup == nil:
@@ -105,11 +141,12 @@ func sortHosts(hosts []*sSchedResultItem, guestInfo *sGuestInfo, isBackup *bool)
 		default:
 			sortIndexi[0], sortIndexj[0] = hosts[i].masterCount, hosts[j].masterCount
 		}
-		sortIndexi[1], sortIndexj[1] = hosts[i].Count, hosts[j].Count
-		sortIndexi[2], sortIndexj[2] = -(hosts[i].minInstanceGroupCapacity(guestInfo.instanceGroupsDetail)), -(hosts[j].minInstanceGroupCapacity(guestInfo.instanceGroupsDetail))
-		sortIndexi[3], sortIndexj[3] = scoreNormalization(hosts[i].Score, hosts[j].Score)
-		sortIndexi[4], sortIndexj[4] = -(hosts[i].Capacity), -(hosts[j].Capacity)
-		for i := 0; i < 5; i++ {
+		sortIndexi[1], sortIndexj[1] = hosts[i].topologySkew(guestInfo.topologySpreads), hosts[j].topologySkew(guestInfo.topologySpreads)
+		sortIndexi[2], sortIndexj[2] = hosts[i].Count, hosts[j].Count
+		sortIndexi[3], sortIndexj[3] = -(hosts[i].minInstanceGroupCapacity(guestInfo.instanceGroupsDetail)), -(hosts[j].minInstanceGroupCapacity(guestInfo.instanceGroupsDetail))
+		sortIndexi[4], sortIndexj[4] = scoreNormalization(hosts[i].Score, hosts[j].Score)
+		sortIndexi[5], sortIndexj[5] = -(hosts[i].Capacity), -(hosts[j].Capacity)
+		for i := 0; i < 6; i++ {
 			if sortIndexi[i] == sortIndexj[i] {
 				continue
 			}
@@ -293,6 +330,10 @@ func markHostUsed(host *sSchedResultItem, guestInfo sGuestInfo, isBackup *bool)
 	for gid := range guestInfo.instanceGroupsDetail {
 		host.instanceGroupCapacity[gid] = host.instanceGroupCapacity[gid] - 1
 	}
+	host.spreadDomains = make([]string, len(guestInfo.topologySpreads))
+	for i, ts := range guestInfo.topologySpreads {
+		host.spreadDomains[i] = ts.Add(host.ID)
+	}
 	host.Capacity--
 	host.Count++
 	if isBackup == nil {
@@ -310,6 +351,12 @@ func unMarkHostUsed(host *sSchedResultItem, guestInfo sGuestInfo, isBackup *bool
 	for gid := range guestInfo.instanceGroupsDetail {
 		host.instanceGroupCapacity[gid] = host.instanceGroupCapacity[gid] + 1
 	}
+	for i, ts := range guestInfo.topologySpreads {
+		if i < len(host.spreadDomains) {
+			ts.Remove(host.spreadDomains[i])
+		}
+	}
+	host.spreadDomains = nil
 	host.Capacity++
 	host.Count--
 	if isBackup == nil {
@@ -342,6 +389,9 @@ Loop:
 		if host.Capacity <= 0 {
 			continue
 		}
+		if !host.fitTopology(guestInfo.topologySpreads) {
+			continue
+		}
 		// check forced instanceGroup
 		for id, group := range guestInfo.instanceGroupsDetail {
 			capacity := host.instanceGroupCapacity[id]
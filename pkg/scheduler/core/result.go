@@ -43,6 +43,9 @@ type SchedResultItem struct {
 	CapacityDetails map[string]int64 `json:"capacity_details"`
 	ScoreDetails    string           `json:"score_details"`
 
+	// estimated monthly cost, 0 if the prices are unknown
+	EstimatedMonthlyCost float64 `json:"estimated_monthly_cost"`
+
 	Candidater Candidater `json:"-"`
 
 	*AllocatedResource
@@ -77,10 +80,11 @@ func (its SchedResultItems) Less(i, j int) bool {
 
 func (item *SchedResultItem) ToCandidateResource(storageUsed *StorageUsed) *schedapi.CandidateResource {
 	return &schedapi.CandidateResource{
-		HostId: item.ID,
-		Name:   item.Name,
-		Disks:  item.getDisks(storageUsed),
-		Nets:   item.Nets,
+		HostId:               item.ID,
+		Name:                 item.Name,
+		Disks:                item.getDisks(storageUsed),
+		Nets:                 item.Nets,
+		EstimatedMonthlyCost: item.EstimatedMonthlyCost,
 	}
 }
 
@@ -54,7 +54,7 @@ func (r SResultHelperFunc) ResultHelp(result *SchedResultItemList, schedInfo *ap
 }
 
 func transToSchedResult(result *SchedResultItemList, schedInfo *api.SchedInfo) *schedapi.ScheduleOutput {
-	if schedInfo.Backup || len(schedInfo.InstanceGroupsDetail) > 0 {
+	if schedInfo.Backup || len(schedInfo.InstanceGroupsDetail) > 0 || (result.Unit != nil && len(result.Unit.TopologySpreads) > 0) {
 		return transToInstanceGroupSchedResult(result, schedInfo)
 	} else {
 		return transToRegionSchedResult(result.Data, int64(schedInfo.Count), schedInfo.SessionId)
@@ -0,0 +1,251 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/util/sets"
+
+	computeapi "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/scheduler/api"
+)
+
+// TopologySpread keeps the number of instances in each topology domain of a
+// spread constraint while scheduling. The instances are the members of the
+// instance groups the constraint applies to plus the guests already selected
+// by the current request.
+type TopologySpread struct {
+	Constraint computeapi.TopologySpreadConstraint
+
+	groupIds []string
+	// candidate id => topology domains of the candidate
+	domains map[string][]string
+	// topology domain => count of instances
+	counts map[string]int64
+}
+
+// NewTopologySpreads builds the spread constraints of the request and of its
+// instance groups over the candidates.
+func NewTopologySpreads(info *api.SchedInfo, cs []Candidater) ([]*TopologySpread, error) {
+	ret := make([]*TopologySpread, 0)
+	groupIds := make([]string, 0, len(info.InstanceGroupsDetail))
+	for id := range info.InstanceGroupsDetail {
+		groupIds = append(groupIds, id)
+	}
+	sort.Strings(groupIds)
+	for _, id := range groupIds {
+		for _, c := range info.InstanceGroupsDetail[id].GetTopologySpreadConstraints() {
+			ret = append(ret, newTopologySpread(info, c, []string{id}, cs))
+		}
+	}
+	if info.ServerConfigs != nil {
+		for _, c := range info.TopologySpreadConstraints {
+			ret = append(ret, newTopologySpread(info, c, groupIds, cs))
+		}
+	}
+	for _, ts := range ret {
+		if err := ts.countInstances(cs); err != nil {
+			return nil, errors.Wrapf(err, "count instances of topology %q", ts.Constraint.TopologyKey)
+		}
+	}
+	return ret, nil
+}
+
+func newTopologySpread(info *api.SchedInfo, c computeapi.TopologySpreadConstraint, groupIds []string, cs []Candidater) *TopologySpread {
+	ts := &TopologySpread{
+		Constraint: c,
+		groupIds:   groupIds,
+		domains:    make(map[string][]string),
+		counts:     make(map[string]int64),
+	}
+	for _, candidate := range cs {
+		domains := getTopologyDomains(info, c, candidate.Getter())
+		ts.domains[candidate.IndexKey()] = domains
+		for _, d := range domains {
+			ts.counts[d] = 0
+		}
+	}
+	return ts
+}
+
+// getTopologyDomains returns the topology domains the candidate belongs to
+func getTopologyDomains(info *api.SchedInfo, c computeapi.TopologySpreadConstraint, getter CandidatePropertyGetter) []string {
+	kind, arg := c.ParseTopologyKey()
+	switch kind {
+	case computeapi.TOPOLOGY_KEY_HOST:
+		return []string{getter.Id()}
+	case computeapi.TOPOLOGY_KEY_ZONE:
+		if zone := getter.Zone(); zone != nil {
+			return []string{zone.GetId()}
+		}
+	case computeapi.TOPOLOGY_KEY_STORAGE:
+		backend := ""
+		if info.ServerConfigs != nil && len(info.Disks) > 0 {
+			backend = info.Disks[0].Backend
+		}
+		types := sets.NewString()
+		for _, s := range getter.Storages() {
+			if len(backend) == 0 || s.StorageType == backend {
+				types.Insert(s.StorageType)
+			}
+		}
+		return types.List()
+	case computeapi.TOPOLOGY_KEY_METADATA_PREFIX:
+		if val, ok := getter.HostMetadata()[arg]; ok && len(val) > 0 {
+			return []string{val}
+		}
+	case computeapi.TOPOLOGY_KEY_SCHEDTAG_PREFIX:
+		tags := sets.NewString()
+		for _, tag := range getter.HostSchedtags() {
+			if strings.HasPrefix(tag.Name, arg) {
+				tags.Insert(tag.Name)
+			}
+		}
+		return tags.List()
+	}
+	return nil
+}
+
+func (ts *TopologySpread) countInstances(cs []Candidater) error {
+	if len(ts.groupIds) == 0 {
+		return nil
+	}
+	kind, _ := ts.Constraint.ParseTopologyKey()
+	if kind == computeapi.TOPOLOGY_KEY_STORAGE {
+		counts, err := models.GroupManager.GetGuestCountByStorageType(ts.groupIds)
+		if err != nil {
+			return err
+		}
+		for storageType, cnt := range counts {
+			if _, ok := ts.counts[storageType]; ok {
+				ts.counts[storageType] += int64(cnt)
+			}
+		}
+		return nil
+	}
+	for _, c := range cs {
+		getter := c.Getter()
+		var cnt int64
+		for _, id := range ts.groupIds {
+			if g, ok := getter.InstanceGroups()[id]; ok {
+				cnt += int64(g.ReferCount)
+			}
+			if usage := getter.GetPendingUsage(); usage != nil {
+				if g, ok := usage.InstanceGroupUsage[id]; ok {
+					cnt += int64(g.ReferCount)
+				}
+			}
+		}
+		if cnt == 0 {
+			continue
+		}
+		for _, d := range ts.domains[c.IndexKey()] {
+			ts.counts[d] += cnt
+		}
+	}
+	return nil
+}
+
+func (ts *TopologySpread) minCount() int64 {
+	var min int64 = -1
+	for _, cnt := range ts.counts {
+		if min < 0 || cnt < min {
+			min = cnt
+		}
+	}
+	if min < 0 {
+		return 0
+	}
+	return min
+}
+
+func (ts *TopologySpread) fitDomain(domain string, min int64) bool {
+	cnt := ts.counts[domain] + 1
+	if ts.Constraint.MaxPerDomain > 0 && cnt > int64(ts.Constraint.MaxPerDomain) {
+		return false
+	}
+	if ts.Constraint.MaxSkew > 0 && cnt-min > int64(ts.Constraint.MaxSkew) {
+		return false
+	}
+	return true
+}
+
+// Domains returns the topology domains of the candidate
+func (ts *TopologySpread) Domains(id string) []string {
+	return ts.domains[id]
+}
+
+// bestDomain returns the least crowded domain of the candidate
+func (ts *TopologySpread) bestDomain(id string) (string, bool) {
+	best, found := "", false
+	for _, d := range ts.domains[id] {
+		if !found || ts.counts[d] < ts.counts[best] {
+			best, found = d, true
+		}
+	}
+	return best, found
+}
+
+// Fits reports whether placing one more instance on the candidate keeps the
+// constraint satisfied.
+func (ts *TopologySpread) Fits(id string) (bool, string) {
+	domains := ts.domains[id]
+	if len(domains) == 0 {
+		return false, fmt.Sprintf("no topology domain of %q", ts.Constraint.TopologyKey)
+	}
+	min := ts.minCount()
+	for _, d := range domains {
+		if ts.fitDomain(d, min) {
+			return true, ""
+		}
+	}
+	return false, fmt.Sprintf("topology %q domain %s exceeds max_skew %d or max_per_domain %d",
+		ts.Constraint.TopologyKey, strings.Join(domains, ","), ts.Constraint.MaxSkew, ts.Constraint.MaxPerDomain)
+}
+
+// Skew returns how many more instances the least crowded domain of the
+// candidate has than the least crowded domain overall, -1 if the candidate
+// is outside of the topology.
+func (ts *TopologySpread) Skew(id string) int64 {
+	d, ok := ts.bestDomain(id)
+	if !ok {
+		return -1
+	}
+	return ts.counts[d] - ts.minCount()
+}
+
+// Add counts an instance placed on the candidate and returns the domain it's
+// counted in, which should be passed to Remove to revert it.
+func (ts *TopologySpread) Add(id string) string {
+	d, ok := ts.bestDomain(id)
+	if !ok {
+		return ""
+	}
+	ts.counts[d]++
+	return d
+}
+
+// Remove reverts an Add
+func (ts *TopologySpread) Remove(domain string) {
+	if len(domain) == 0 {
+		return
+	}
+	ts.counts[domain]--
+}
@@ -0,0 +1,114 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"testing"
+
+	computeapi "yunion.io/x/onecloud/pkg/apis/compute"
+)
+
+func newTestTopologySpread(c computeapi.TopologySpreadConstraint, domains map[string][]string, counts map[string]int64) *TopologySpread {
+	ts := &TopologySpread{
+		Constraint: c,
+		domains:    domains,
+		counts:     make(map[string]int64),
+	}
+	for _, ds := range domains {
+		for _, d := range ds {
+			ts.counts[d] = counts[d]
+		}
+	}
+	return ts
+}
+
+func TestTopologySpreadMaxSkew(t *testing.T) {
+	ts := newTestTopologySpread(
+		computeapi.TopologySpreadConstraint{TopologyKey: "zone", MaxSkew: 1},
+		map[string][]string{
+			"host1": {"zone1"},
+			"host2": {"zone1"},
+			"host3": {"zone2"},
+			"host4": nil,
+		},
+		map[string]int64{"zone1": 1},
+	)
+	if ok, _ := ts.Fits("host1"); ok {
+		t.Errorf("host1 should not fit: zone1 already has one more instance than zone2")
+	}
+	if ok, _ := ts.Fits("host3"); !ok {
+		t.Errorf("host3 should fit")
+	}
+	if ok, _ := ts.Fits("host4"); ok {
+		t.Errorf("host4 is out of the topology and should not fit")
+	}
+	if skew := ts.Skew("host2"); skew != 1 {
+		t.Errorf("skew of host2 want 1, got %d", skew)
+	}
+
+	// batch creation: the second instance goes to zone2 and zone1 is open again
+	d := ts.Add("host3")
+	if d != "zone2" {
+		t.Errorf("want domain zone2, got %s", d)
+	}
+	if ok, _ := ts.Fits("host1"); !ok {
+		t.Errorf("host1 should fit after zone2 is filled")
+	}
+	ts.Remove(d)
+	if ok, _ := ts.Fits("host1"); ok {
+		t.Errorf("host1 should not fit after reverting")
+	}
+}
+
+func TestTopologySpreadMaxPerDomain(t *testing.T) {
+	ts := newTestTopologySpread(
+		computeapi.TopologySpreadConstraint{TopologyKey: "metadata:rack", MaxPerDomain: 2},
+		map[string][]string{
+			"host1": {"rack1"},
+			"host2": {"rack1"},
+			"host3": {"rack2"},
+		},
+		map[string]int64{"rack1": 1},
+	)
+	ts.Add("host1")
+	for _, id := range []string{"host1", "host2"} {
+		if ok, _ := ts.Fits(id); ok {
+			t.Errorf("%s should not fit: rack1 is full", id)
+		}
+	}
+	if ok, _ := ts.Fits("host3"); !ok {
+		t.Errorf("host3 should fit")
+	}
+}
+
+func TestTopologySpreadMultipleDomains(t *testing.T) {
+	ts := newTestTopologySpread(
+		computeapi.TopologySpreadConstraint{TopologyKey: "storage", MaxSkew: 1},
+		map[string][]string{
+			"host1": {"local", "rbd"},
+			"host2": {"local"},
+		},
+		map[string]int64{"local": 1},
+	)
+	if ok, _ := ts.Fits("host1"); !ok {
+		t.Errorf("host1 should fit with rbd")
+	}
+	if ok, _ := ts.Fits("host2"); ok {
+		t.Errorf("host2 should not fit")
+	}
+	if d := ts.Add("host1"); d != "rbd" {
+		t.Errorf("want domain rbd, got %s", d)
+	}
+}
@@ -108,6 +108,7 @@ type CandidatePropertyGetter interface {
 	GetFreeGroupCount(groupId string) (int, error)
 
 	GetAllClassMetadata() (map[string]string, error)
+	HostMetadata() map[string]string
 
 	GetIpmiInfo() types.SIPMIInfo
 
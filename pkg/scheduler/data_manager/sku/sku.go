@@ -69,6 +69,20 @@ type ServerSku struct {
 	Name     string `json:"name"`
 	RegionId string `json:"cloudregion_id"`
 	ZoneId   string `json:"zone_id"`
+
+	PostpaidHourlyPrice float64 `json:"postpaid_hourly_price"`
+	PrepaidMonthlyPrice float64 `json:"prepaid_monthly_price"`
+}
+
+const HOURS_PER_MONTH = 730
+
+// MonthlyPrice returns the monthly price of the sku in pay-as-you-go
+// billing, or the prepaid price if there is no postpaid one, 0 if unknown
+func (s *ServerSku) MonthlyPrice() float64 {
+	if s.PostpaidHourlyPrice > 0 {
+		return s.PostpaidHourlyPrice * HOURS_PER_MONTH
+	}
+	return s.PrepaidMonthlyPrice
 }
 
 type skuList []*ServerSku
@@ -140,7 +154,7 @@ func (m *SSkuManager) syncOnce() {
 	startTime := time.Now()
 
 	skus := make([]ServerSku, 0)
-	q := models.ServerSkuManager.Query("id", "name", "cloudregion_id", "zone_id").IsTrue("enabled")
+	q := models.ServerSkuManager.Query("id", "name", "cloudregion_id", "zone_id", "postpaid_hourly_price", "prepaid_monthly_price").IsTrue("enabled")
 	q = q.Filter(
 		sqlchemy.OR(
 			sqlchemy.Equals(q.Field("prepaid_status"), computeapi.SkuStatusAvailable),
@@ -90,9 +90,21 @@ type SchedOptions struct {
 
 	SkuRefreshInterval string `help:"Server SKU refresh interval" default:"12h"`
 
+	CostOptions
+
 	OpenstackOptions
 }
 
+// CostOptions estimate the monthly cost of candidates without sku or
+// storage prices, e.g. the private host pools
+type CostOptions struct {
+	CostPriorityWeight int `help:"Default weight of the cost priority, cost aware scheduling is disabled unless set above 0" default:"0"`
+
+	PrivateCpuCoreMonthlyPrice   float64 `help:"Monthly price of a cpu core of the private hosts without sku prices" default:"0"`
+	PrivateMemoryGbMonthlyPrice  float64 `help:"Monthly price of 1GB memory of the private hosts without sku prices" default:"0"`
+	PrivateStorageGbMonthlyPrice float64 `help:"Monthly price of 1GB disk of the private storages without prices" default:"0"`
+}
+
 type OpenstackOptions struct {
 	OpenstackSchedulerCPUFilter     bool `help:"Scheduler OpenStack usable host by cpu" default:"true"`
 	OpenstackSchedulerMemoryFilter  bool `help:"Scheduler OpenStack usable host by memory" default:"true"`
@@ -0,0 +1,430 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package acmeutils
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/util/httputils"
+)
+
+const (
+	contentTypeJOSE    = "application/jose+json"
+	contentTypeProblem = "application/problem+json"
+	contentTypePEM     = "application/pem-certificate-chain"
+
+	defaultPollInterval = 2 * time.Second
+)
+
+// SAcmeClient is a minimal ACME (RFC 8555) client supporting the flow of
+// account registration, order, http-01/dns-01 authorization, finalization
+// and certificate download.  Requests other than directory and nonce are
+// signed with ES256
+type SAcmeClient struct {
+	directoryUrl string
+	key          *ecdsa.PrivateKey
+	accountUrl   string
+
+	httpclient *http.Client
+	dir        *SDirectory
+
+	nonceLock sync.Mutex
+	nonces    []string
+}
+
+func NewAcmeClient(directoryUrl string, key *ecdsa.PrivateKey, insecure bool, timeout time.Duration) *SAcmeClient {
+	return &SAcmeClient{
+		directoryUrl: directoryUrl,
+		key:          key,
+		httpclient:   httputils.GetClient(insecure, timeout),
+	}
+}
+
+func (cli *SAcmeClient) SetAccountUrl(accountUrl string) {
+	cli.accountUrl = accountUrl
+}
+
+func (cli *SAcmeClient) GetAccountUrl() string {
+	return cli.accountUrl
+}
+
+func (cli *SAcmeClient) KeyAuthorization(token string) string {
+	return KeyAuthorization(cli.key, token)
+}
+
+func (cli *SAcmeClient) DNS01Value(token string) string {
+	return DNS01Value(cli.key, token)
+}
+
+func (cli *SAcmeClient) Directory(ctx context.Context) (*SDirectory, error) {
+	if cli.dir != nil {
+		return cli.dir, nil
+	}
+	req, err := http.NewRequest(http.MethodGet, cli.directoryUrl, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "NewRequest")
+	}
+	resp, err := cli.httpclient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, errors.Wrapf(err, "get directory %s", cli.directoryUrl)
+	}
+	defer resp.Body.Close()
+	dir := &SDirectory{}
+	if err := cli.parseResponse(resp, dir); err != nil {
+		return nil, errors.Wrap(err, "parse directory")
+	}
+	if dir.NewNonce == "" || dir.NewAccount == "" || dir.NewOrder == "" {
+		return nil, errors.Wrapf(errors.ErrInvalidStatus, "incomplete directory %s", cli.directoryUrl)
+	}
+	cli.dir = dir
+	return dir, nil
+}
+
+func (cli *SAcmeClient) saveNonce(resp *http.Response) {
+	nonce := resp.Header.Get("Replay-Nonce")
+	if nonce == "" {
+		return
+	}
+	cli.nonceLock.Lock()
+	defer cli.nonceLock.Unlock()
+	cli.nonces = append(cli.nonces, nonce)
+}
+
+func (cli *SAcmeClient) popNonce(ctx context.Context) (string, error) {
+	cli.nonceLock.Lock()
+	if n := len(cli.nonces); n > 0 {
+		nonce := cli.nonces[n-1]
+		cli.nonces = cli.nonces[:n-1]
+		cli.nonceLock.Unlock()
+		return nonce, nil
+	}
+	cli.nonceLock.Unlock()
+
+	dir, err := cli.Directory(ctx)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest(http.MethodHead, dir.NewNonce, nil)
+	if err != nil {
+		return "", errors.Wrap(err, "NewRequest")
+	}
+	resp, err := cli.httpclient.Do(req.WithContext(ctx))
+	if err != nil {
+		return "", errors.Wrap(err, "request new nonce")
+	}
+	resp.Body.Close()
+	nonce := resp.Header.Get("Replay-Nonce")
+	if nonce == "" {
+		return "", errors.Wrapf(errors.ErrInvalidStatus, "no nonce returned by %s", dir.NewNonce)
+	}
+	return nonce, nil
+}
+
+// signJWS builds the flattened JWS JSON serialization of payload.  A nil
+// payload means POST-as-GET, whose payload is an empty string
+func (cli *SAcmeClient) signJWS(url, nonce string, payload interface{}, useJWK bool) ([]byte, error) {
+	protected := fmt.Sprintf(`{"alg":"ES256","nonce":%q,"url":%q`, nonce, url)
+	if useJWK {
+		protected += fmt.Sprintf(`,"jwk":%s}`, jwkJSON(&cli.key.PublicKey))
+	} else {
+		protected += fmt.Sprintf(`,"kid":%q}`, cli.accountUrl)
+	}
+	payloadB64 := ""
+	if payload != nil {
+		d, err := json.Marshal(payload)
+		if err != nil {
+			return nil, errors.Wrap(err, "marshal payload")
+		}
+		payloadB64 = base64url(d)
+	}
+	protectedB64 := base64url([]byte(protected))
+	sig, err := signES256(cli.key, []byte(protectedB64+"."+payloadB64))
+	if err != nil {
+		return nil, errors.Wrap(err, "sign")
+	}
+	return json.Marshal(map[string]string{
+		"protected": protectedB64,
+		"payload":   payloadB64,
+		"signature": base64url(sig),
+	})
+}
+
+// post sends a signed request and retries once on badNonce error.  The
+// response body is closed by caller
+func (cli *SAcmeClient) post(ctx context.Context, url string, payload interface{}, useJWK bool) (*http.Response, error) {
+	if !useJWK && cli.accountUrl == "" {
+		return nil, errors.Wrap(errors.ErrInvalidStatus, "account not registered")
+	}
+	for i := 0; ; i++ {
+		nonce, err := cli.popNonce(ctx)
+		if err != nil {
+			return nil, errors.Wrap(err, "get nonce")
+		}
+		body, err := cli.signJWS(url, nonce, payload, useJWK)
+		if err != nil {
+			return nil, err
+		}
+		req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return nil, errors.Wrap(err, "NewRequest")
+		}
+		req.Header.Set("Content-Type", contentTypeJOSE)
+		resp, err := cli.httpclient.Do(req.WithContext(ctx))
+		if err != nil {
+			return nil, errors.Wrapf(err, "post %s", url)
+		}
+		cli.saveNonce(resp)
+		if resp.StatusCode < 400 {
+			return resp, nil
+		}
+		problem := cli.readProblem(resp)
+		resp.Body.Close()
+		if problem.Type == ERROR_BAD_NONCE && i == 0 {
+			log.Debugf("acme: bad nonce for %s, retry", url)
+			continue
+		}
+		return nil, problem
+	}
+}
+
+func (cli *SAcmeClient) readProblem(resp *http.Response) *SProblem {
+	problem := &SProblem{}
+	d, _ := ioutil.ReadAll(resp.Body)
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), contentTypeProblem) {
+		json.Unmarshal(d, problem)
+	}
+	if problem.Status == 0 {
+		problem.Status = resp.StatusCode
+	}
+	if problem.Detail == "" {
+		problem.Detail = string(d)
+	}
+	return problem
+}
+
+func (cli *SAcmeClient) parseResponse(resp *http.Response, v interface{}) error {
+	if resp.StatusCode >= 400 {
+		return cli.readProblem(resp)
+	}
+	d, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrap(err, "read body")
+	}
+	return json.Unmarshal(d, v)
+}
+
+func (cli *SAcmeClient) postJSON(ctx context.Context, url string, payload interface{}, useJWK bool, v interface{}) (*http.Response, error) {
+	resp, err := cli.post(ctx, url, payload, useJWK)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if err := cli.parseResponse(resp, v); err != nil {
+		return nil, errors.Wrapf(err, "parse response of %s", url)
+	}
+	return resp, nil
+}
+
+// Register creates the account of the client key, or returns the url of the
+// existing one.  Terms of service are agreed implicitly
+func (cli *SAcmeClient) Register(ctx context.Context, email string) (string, error) {
+	dir, err := cli.Directory(ctx)
+	if err != nil {
+		return "", err
+	}
+	account := SAccount{
+		TermsOfServiceAgreed: true,
+	}
+	if email != "" {
+		account.Contact = []string{"mailto:" + email}
+	}
+	resp, err := cli.postJSON(ctx, dir.NewAccount, account, true, &account)
+	if err != nil {
+		return "", errors.Wrap(err, "new account")
+	}
+	cli.accountUrl = resp.Header.Get("Location")
+	if cli.accountUrl == "" {
+		return "", errors.Wrap(errors.ErrInvalidStatus, "no account url returned")
+	}
+	return cli.accountUrl, nil
+}
+
+func (cli *SAcmeClient) NewOrder(ctx context.Context, domains []string) (*SOrder, error) {
+	dir, err := cli.Directory(ctx)
+	if err != nil {
+		return nil, err
+	}
+	input := struct {
+		Identifiers []SIdentifier `json:"identifiers"`
+	}{}
+	for _, domain := range domains {
+		input.Identifiers = append(input.Identifiers, SIdentifier{Type: IDENTIFIER_TYPE_DNS, Value: domain})
+	}
+	order := &SOrder{}
+	resp, err := cli.postJSON(ctx, dir.NewOrder, input, false, order)
+	if err != nil {
+		return nil, errors.Wrap(err, "new order")
+	}
+	order.Url = resp.Header.Get("Location")
+	return order, nil
+}
+
+func (cli *SAcmeClient) GetOrder(ctx context.Context, url string) (*SOrder, error) {
+	order := &SOrder{}
+	if _, err := cli.postJSON(ctx, url, nil, false, order); err != nil {
+		return nil, errors.Wrap(err, "get order")
+	}
+	order.Url = url
+	return order, nil
+}
+
+func (cli *SAcmeClient) GetAuthorization(ctx context.Context, url string) (*SAuthorization, error) {
+	authz := &SAuthorization{}
+	if _, err := cli.postJSON(ctx, url, nil, false, authz); err != nil {
+		return nil, errors.Wrap(err, "get authorization")
+	}
+	authz.Url = url
+	return authz, nil
+}
+
+// AcceptChallenge tells the server the challenge is ready for validation
+func (cli *SAcmeClient) AcceptChallenge(ctx context.Context, chal *SChallenge) error {
+	if _, err := cli.postJSON(ctx, chal.Url, struct{}{}, false, chal); err != nil {
+		return errors.Wrapf(err, "accept challenge %s", chal.Type)
+	}
+	return nil
+}
+
+func retryAfter(resp *http.Response) time.Duration {
+	if resp == nil {
+		return defaultPollInterval
+	}
+	if sec, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && sec > 0 {
+		return time.Duration(sec) * time.Second
+	}
+	return defaultPollInterval
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// WaitAuthorization polls authorization until it is no longer pending.  An
+// error is returned if it ends up not valid
+func (cli *SAcmeClient) WaitAuthorization(ctx context.Context, url string) (*SAuthorization, error) {
+	for {
+		authz := &SAuthorization{}
+		resp, err := cli.postJSON(ctx, url, nil, false, authz)
+		if err != nil {
+			return nil, errors.Wrap(err, "get authorization")
+		}
+		authz.Url = url
+		switch authz.Status {
+		case STATUS_VALID:
+			return authz, nil
+		case STATUS_PENDING, STATUS_PROCESSING:
+		default:
+			for i := range authz.Challenges {
+				if authz.Challenges[i].Error != nil {
+					return authz, errors.Wrapf(authz.Challenges[i].Error, "authorization of %s %s", authz.Identifier.Value, authz.Status)
+				}
+			}
+			return authz, errors.Wrapf(errors.ErrInvalidStatus, "authorization of %s %s", authz.Identifier.Value, authz.Status)
+		}
+		if err := sleepContext(ctx, retryAfter(resp)); err != nil {
+			return nil, errors.Wrap(err, "wait authorization")
+		}
+	}
+}
+
+// WaitOrder polls order until it is valid or invalid
+func (cli *SAcmeClient) WaitOrder(ctx context.Context, url string) (*SOrder, error) {
+	for {
+		order := &SOrder{}
+		resp, err := cli.postJSON(ctx, url, nil, false, order)
+		if err != nil {
+			return nil, errors.Wrap(err, "get order")
+		}
+		order.Url = url
+		switch order.Status {
+		case STATUS_VALID:
+			return order, nil
+		case STATUS_INVALID:
+			if order.Error != nil {
+				return order, errors.Wrap(order.Error, "order invalid")
+			}
+			return order, errors.Wrap(errors.ErrInvalidStatus, "order invalid")
+		}
+		if err := sleepContext(ctx, retryAfter(resp)); err != nil {
+			return nil, errors.Wrap(err, "wait order")
+		}
+	}
+}
+
+// FinalizeOrder submits the DER encoded csr and waits for the certificate to
+// be issued
+func (cli *SAcmeClient) FinalizeOrder(ctx context.Context, order *SOrder, csr []byte) (*SOrder, error) {
+	input := struct {
+		Csr string `json:"csr"`
+	}{
+		Csr: base64url(csr),
+	}
+	ret := &SOrder{}
+	if _, err := cli.postJSON(ctx, order.Finalize, input, false, ret); err != nil {
+		return nil, errors.Wrap(err, "finalize order")
+	}
+	ret.Url = order.Url
+	if ret.Status == STATUS_VALID {
+		return ret, nil
+	}
+	return cli.WaitOrder(ctx, order.Url)
+}
+
+// FetchCertificate downloads the PEM encoded certificate chain
+func (cli *SAcmeClient) FetchCertificate(ctx context.Context, url string) (string, error) {
+	resp, err := cli.post(ctx, url, nil, false)
+	if err != nil {
+		return "", errors.Wrap(err, "fetch certificate")
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "" && !strings.HasPrefix(ct, contentTypePEM) {
+		return "", errors.Wrapf(errors.ErrInvalidStatus, "unexpected certificate content type %s", ct)
+	}
+	d, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", errors.Wrap(err, "read certificate")
+	}
+	return string(d), nil
+}
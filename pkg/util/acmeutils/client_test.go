@@ -0,0 +1,309 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package acmeutils
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// sFakeAcmeServer implements just enough of RFC 8555 to drive the client
+// through a full issuance, verifying every JWS it receives
+type sFakeAcmeServer struct {
+	t   *testing.T
+	srv *httptest.Server
+
+	lock      sync.Mutex
+	nonce     int
+	accountPK *ecdsa.PublicKey
+	domain    string
+	authzDone bool
+	certPEM   string
+}
+
+func newFakeAcmeServer(t *testing.T) *sFakeAcmeServer {
+	s := &sFakeAcmeServer{t: t}
+	s.srv = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+func (s *sFakeAcmeServer) url(p string) string {
+	return s.srv.URL + p
+}
+
+func (s *sFakeAcmeServer) newNonce(w http.ResponseWriter) {
+	s.nonce++
+	w.Header().Set("Replay-Nonce", fmt.Sprintf("nonce-%d", s.nonce))
+}
+
+func b64decode(t *testing.T, s string) []byte {
+	d, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		t.Fatalf("decode %q: %s", s, err)
+	}
+	return d
+}
+
+func (s *sFakeAcmeServer) verify(r *http.Request) (map[string]interface{}, []byte) {
+	if ct := r.Header.Get("Content-Type"); ct != contentTypeJOSE {
+		s.t.Errorf("content type %s", ct)
+	}
+	body, _ := ioutil.ReadAll(r.Body)
+	jws := map[string]string{}
+	if err := json.Unmarshal(body, &jws); err != nil {
+		s.t.Fatalf("invalid jws %s", body)
+	}
+	protected := map[string]interface{}{}
+	json.Unmarshal(b64decode(s.t, jws["protected"]), &protected)
+	if protected["url"] != s.url(r.URL.Path) {
+		s.t.Errorf("url in jws %v, want %s", protected["url"], s.url(r.URL.Path))
+	}
+	var pub *ecdsa.PublicKey
+	if jwk, ok := protected["jwk"].(map[string]interface{}); ok {
+		pub = &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(b64decode(s.t, jwk["x"].(string))),
+			Y:     new(big.Int).SetBytes(b64decode(s.t, jwk["y"].(string))),
+		}
+		s.accountPK = pub
+	} else {
+		if protected["kid"] != s.url("/acct/1") {
+			s.t.Errorf("kid %v", protected["kid"])
+		}
+		pub = s.accountPK
+	}
+	sig := b64decode(s.t, jws["signature"])
+	d := sha256.Sum256([]byte(jws["protected"] + "." + jws["payload"]))
+	r1 := new(big.Int).SetBytes(sig[:32])
+	s1 := new(big.Int).SetBytes(sig[32:])
+	if !ecdsa.Verify(pub, d[:], r1, s1) {
+		s.t.Errorf("bad signature for %s", r.URL.Path)
+	}
+	if jws["payload"] == "" {
+		return protected, nil
+	}
+	return protected, b64decode(s.t, jws["payload"])
+}
+
+func (s *sFakeAcmeServer) writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func (s *sFakeAcmeServer) serve(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	switch r.URL.Path {
+	case "/directory":
+		s.writeJSON(w, 200, SDirectory{
+			NewNonce:   s.url("/nonce"),
+			NewAccount: s.url("/account"),
+			NewOrder:   s.url("/order"),
+		})
+		return
+	case "/nonce":
+		s.newNonce(w)
+		return
+	}
+
+	s.newNonce(w)
+	_, payload := s.verify(r)
+	authz := SAuthorization{
+		Status:     STATUS_PENDING,
+		Identifier: SIdentifier{Type: IDENTIFIER_TYPE_DNS, Value: s.domain},
+		Challenges: []SChallenge{
+			{Type: CHALLENGE_TYPE_HTTP01, Url: s.url("/chal/1"), Token: "token1", Status: STATUS_PENDING},
+		},
+	}
+	if s.authzDone {
+		authz.Status = STATUS_VALID
+		authz.Challenges[0].Status = STATUS_VALID
+	}
+	order := SOrder{
+		Status:         STATUS_PENDING,
+		Identifiers:    []SIdentifier{{Type: IDENTIFIER_TYPE_DNS, Value: s.domain}},
+		Authorizations: []string{s.url("/authz/1")},
+		Finalize:       s.url("/finalize/1"),
+	}
+	switch r.URL.Path {
+	case "/account":
+		w.Header().Set("Location", s.url("/acct/1"))
+		s.writeJSON(w, 201, SAccount{Status: STATUS_VALID})
+	case "/order":
+		input := struct {
+			Identifiers []SIdentifier `json:"identifiers"`
+		}{}
+		json.Unmarshal(payload, &input)
+		s.domain = input.Identifiers[0].Value
+		order.Identifiers[0].Value = s.domain
+		w.Header().Set("Location", s.url("/orders/1"))
+		s.writeJSON(w, 201, order)
+	case "/authz/1":
+		s.writeJSON(w, 200, authz)
+	case "/chal/1":
+		s.authzDone = true
+		s.writeJSON(w, 200, SChallenge{Type: CHALLENGE_TYPE_HTTP01, Url: s.url("/chal/1"), Token: "token1", Status: STATUS_PROCESSING})
+	case "/finalize/1":
+		input := struct {
+			Csr string `json:"csr"`
+		}{}
+		json.Unmarshal(payload, &input)
+		csr, err := x509.ParseCertificateRequest(b64decode(s.t, input.Csr))
+		if err != nil {
+			s.t.Fatalf("parse csr: %s", err)
+		}
+		s.certPEM = s.issue(csr)
+		order.Status = STATUS_PROCESSING
+		w.Header().Set("Retry-After", "1")
+		s.writeJSON(w, 200, order)
+	case "/orders/1":
+		if s.certPEM != "" {
+			order.Status = STATUS_VALID
+			order.Certificate = s.url("/cert/1")
+		}
+		s.writeJSON(w, 200, order)
+	case "/cert/1":
+		w.Header().Set("Content-Type", contentTypePEM)
+		w.Write([]byte(s.certPEM))
+	default:
+		w.WriteHeader(404)
+	}
+}
+
+func (s *sFakeAcmeServer) issue(csr *x509.CertificateRequest) string {
+	caKey, _ := GenerateKey()
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: csr.Subject.CommonName},
+		DNSNames:     csr.DNSNames,
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(90 * 24 * time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, csr.PublicKey, caKey)
+	if err != nil {
+		s.t.Fatalf("create certificate: %s", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+func TestAcmeClientIssue(t *testing.T) {
+	srv := newFakeAcmeServer(t)
+	defer srv.srv.Close()
+
+	accountKey, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey: %s", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cli := NewAcmeClient(srv.url("/directory"), accountKey, false, 10*time.Second)
+	accountUrl, err := cli.Register(ctx, "admin@example.com")
+	if err != nil {
+		t.Fatalf("Register: %s", err)
+	}
+	if accountUrl != srv.url("/acct/1") {
+		t.Errorf("account url %s", accountUrl)
+	}
+	order, err := cli.NewOrder(ctx, []string{"www.example.com"})
+	if err != nil {
+		t.Fatalf("NewOrder: %s", err)
+	}
+	authz, err := cli.GetAuthorization(ctx, order.Authorizations[0])
+	if err != nil {
+		t.Fatalf("GetAuthorization: %s", err)
+	}
+	chal := authz.GetChallenge(CHALLENGE_TYPE_HTTP01)
+	if chal == nil {
+		t.Fatalf("no http-01 challenge")
+	}
+	if keyAuth := cli.KeyAuthorization(chal.Token); !strings.HasPrefix(keyAuth, "token1.") {
+		t.Errorf("key authorization %s", keyAuth)
+	}
+	if err := cli.AcceptChallenge(ctx, chal); err != nil {
+		t.Fatalf("AcceptChallenge: %s", err)
+	}
+	if _, err := cli.WaitAuthorization(ctx, authz.Url); err != nil {
+		t.Fatalf("WaitAuthorization: %s", err)
+	}
+	certKey, _ := GenerateKey()
+	csr, err := CreateCertificateRequest(certKey, []string{"www.example.com"})
+	if err != nil {
+		t.Fatalf("CreateCertificateRequest: %s", err)
+	}
+	order, err = cli.FinalizeOrder(ctx, order, csr)
+	if err != nil {
+		t.Fatalf("FinalizeOrder: %s", err)
+	}
+	certPEM, err := cli.FetchCertificate(ctx, order.Certificate)
+	if err != nil {
+		t.Fatalf("FetchCertificate: %s", err)
+	}
+	block, _ := pem.Decode([]byte(certPEM))
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatalf("ParseCertificate: %s", err)
+	}
+	if cert.Subject.CommonName != "www.example.com" {
+		t.Errorf("common name %s", cert.Subject.CommonName)
+	}
+}
+
+func TestKeyEncoding(t *testing.T) {
+	key, _ := GenerateKey()
+	s, err := EncodeKey(key)
+	if err != nil {
+		t.Fatalf("EncodeKey: %s", err)
+	}
+	key2, err := DecodeKey(s)
+	if err != nil {
+		t.Fatalf("DecodeKey: %s", err)
+	}
+	if JWKThumbprint(&key.PublicKey) != JWKThumbprint(&key2.PublicKey) {
+		t.Errorf("thumbprint mismatch after decode")
+	}
+	if v := DNS01Value(key, "token"); len(v) != 43 {
+		t.Errorf("dns-01 value %q", v)
+	}
+	cases := map[string]string{
+		"example.com":      "_acme-challenge.example.com",
+		"*.example.com":    "_acme-challenge.example.com",
+		"www.example.com.": "_acme-challenge.www.example.com",
+	}
+	for domain, want := range cases {
+		if got := DNS01RecordName(domain); got != want {
+			t.Errorf("DNS01RecordName(%s) = %s, want %s", domain, got, want)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package acmeutils
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"yunion.io/x/pkg/errors"
+)
+
+// GenerateKey generates an ECDSA P-256 key, used both as ACME account key and
+// certificate key
+func GenerateKey() (*ecdsa.PrivateKey, error) {
+	return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+}
+
+func EncodeKey(key *ecdsa.PrivateKey) (string, error) {
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return "", errors.Wrap(err, "MarshalECPrivateKey")
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})), nil
+}
+
+func DecodeKey(s string) (*ecdsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(s))
+	if block == nil {
+		return nil, errors.Wrap(errors.ErrInvalidStatus, "no pem block found")
+	}
+	key, err := x509.ParseECPrivateKey(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "ParseECPrivateKey")
+	}
+	return key, nil
+}
+
+// CreateCertificateRequest creates a DER encoded CSR for domains.  The first
+// domain is used as the common name
+func CreateCertificateRequest(key crypto.Signer, domains []string) ([]byte, error) {
+	if len(domains) == 0 {
+		return nil, errors.Wrap(errors.ErrInvalidStatus, "no domains")
+	}
+	tmpl := &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: domains[0]},
+		DNSNames: domains,
+	}
+	return x509.CreateCertificateRequest(rand.Reader, tmpl, key)
+}
+
+func base64url(d []byte) string {
+	return base64.RawURLEncoding.EncodeToString(d)
+}
+
+func padBytes(b *big.Int, size int) []byte {
+	d := b.Bytes()
+	if len(d) >= size {
+		return d
+	}
+	ret := make([]byte, size)
+	copy(ret[size-len(d):], d)
+	return ret
+}
+
+// jwkJSON returns the JWK of the public key with members in lexicographic
+// order, as required for computing thumbprint by RFC 7638
+func jwkJSON(pub *ecdsa.PublicKey) string {
+	size := (pub.Curve.Params().BitSize + 7) / 8
+	return fmt.Sprintf(`{"crv":%q,"kty":"EC","x":%q,"y":%q}`,
+		pub.Curve.Params().Name,
+		base64url(padBytes(pub.X, size)),
+		base64url(padBytes(pub.Y, size)),
+	)
+}
+
+func JWKThumbprint(pub *ecdsa.PublicKey) string {
+	d := sha256.Sum256([]byte(jwkJSON(pub)))
+	return base64url(d[:])
+}
+
+// KeyAuthorization returns the key authorization of a challenge token, which
+// is the content to serve for http-01 challenge
+func KeyAuthorization(key *ecdsa.PrivateKey, token string) string {
+	return token + "." + JWKThumbprint(&key.PublicKey)
+}
+
+// DNS01Value returns the TXT record value for dns-01 challenge
+func DNS01Value(key *ecdsa.PrivateKey, token string) string {
+	d := sha256.Sum256([]byte(KeyAuthorization(key, token)))
+	return base64url(d[:])
+}
+
+// DNS01RecordName returns the TXT record name for domain.  Wildcard domain
+// shares the same record name with its base domain
+func DNS01RecordName(domain string) string {
+	domain = strings.TrimPrefix(domain, "*.")
+	return DNS01_RECORD_PREFIX + "." + strings.TrimSuffix(domain, ".")
+}
+
+func signES256(key *ecdsa.PrivateKey, data []byte) ([]byte, error) {
+	d := sha256.Sum256(data)
+	r, s, err := ecdsa.Sign(rand.Reader, key, d[:])
+	if err != nil {
+		return nil, err
+	}
+	size := (key.Curve.Params().BitSize + 7) / 8
+	sig := append(padBytes(r, size), padBytes(s, size)...)
+	return sig, nil
+}
@@ -0,0 +1,116 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package acmeutils
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	IDENTIFIER_TYPE_DNS = "dns"
+
+	CHALLENGE_TYPE_HTTP01 = "http-01"
+	CHALLENGE_TYPE_DNS01  = "dns-01"
+
+	STATUS_PENDING     = "pending"
+	STATUS_READY       = "ready"
+	STATUS_PROCESSING  = "processing"
+	STATUS_VALID       = "valid"
+	STATUS_INVALID     = "invalid"
+	STATUS_DEACTIVATED = "deactivated"
+	STATUS_EXPIRED     = "expired"
+	STATUS_REVOKED     = "revoked"
+
+	// prefix of the path lbagent serves http-01 key authorizations under
+	HTTP01_PATH_PREFIX = "/.well-known/acme-challenge/"
+	// prefix of the TXT record name holding dns-01 digests
+	DNS01_RECORD_PREFIX = "_acme-challenge"
+
+	ERROR_BAD_NONCE = "urn:ietf:params:acme:error:badNonce"
+)
+
+// SDirectory is the directory object of an ACME server, RFC 8555 section 7.1.1
+type SDirectory struct {
+	NewNonce   string `json:"newNonce"`
+	NewAccount string `json:"newAccount"`
+	NewOrder   string `json:"newOrder"`
+	RevokeCert string `json:"revokeCert"`
+	KeyChange  string `json:"keyChange"`
+}
+
+type SIdentifier struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// SProblem is the problem document returned by ACME server on error, RFC 7807
+type SProblem struct {
+	Type   string `json:"type"`
+	Detail string `json:"detail"`
+	Status int    `json:"status"`
+}
+
+func (p *SProblem) Error() string {
+	return fmt.Sprintf("acme: %d %s: %s", p.Status, p.Type, p.Detail)
+}
+
+type SAccount struct {
+	Status               string   `json:"status"`
+	Contact              []string `json:"contact"`
+	TermsOfServiceAgreed bool     `json:"termsOfServiceAgreed"`
+	OnlyReturnExisting   bool     `json:"onlyReturnExisting,omitempty"`
+}
+
+type SOrder struct {
+	// url of the order, from the Location header
+	Url string `json:"-"`
+
+	Status         string        `json:"status"`
+	Expires        time.Time     `json:"expires"`
+	Identifiers    []SIdentifier `json:"identifiers"`
+	Authorizations []string      `json:"authorizations"`
+	Finalize       string        `json:"finalize"`
+	Certificate    string        `json:"certificate"`
+	Error          *SProblem     `json:"error"`
+}
+
+type SChallenge struct {
+	Type   string    `json:"type"`
+	Url    string    `json:"url"`
+	Status string    `json:"status"`
+	Token  string    `json:"token"`
+	Error  *SProblem `json:"error"`
+}
+
+type SAuthorization struct {
+	// url of the authorization
+	Url string `json:"-"`
+
+	Status     string       `json:"status"`
+	Expires    time.Time    `json:"expires"`
+	Identifier SIdentifier  `json:"identifier"`
+	Challenges []SChallenge `json:"challenges"`
+	Wildcard   bool         `json:"wildcard"`
+}
+
+func (authz *SAuthorization) GetChallenge(challengeType string) *SChallenge {
+	for i := range authz.Challenges {
+		if authz.Challenges[i].Type == challengeType {
+			return &authz.Challenges[i]
+		}
+	}
+	return nil
+}
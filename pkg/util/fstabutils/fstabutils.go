@@ -98,6 +98,16 @@ func (ft *FsTab) RemoveDevices(devCnt int) *FsTab {
 	return &newList
 }
 
+func (ft *FsTab) RemoveFsType(fs string) *FsTab {
+	var newList = make(FsTab, 0)
+	for _, f := range *ft {
+		if f.Fs != fs {
+			newList = append(newList, f)
+		}
+	}
+	return &newList
+}
+
 func (ft *FsTab) ToConf() string {
 	var res string
 	for _, f := range *ft {
@@ -43,6 +43,7 @@ import (
 	"yunion.io/x/pkg/utils"
 
 	"yunion.io/x/onecloud/pkg/appctx"
+	"yunion.io/x/onecloud/pkg/util/tracing"
 )
 
 type THttpMethod string
@@ -509,6 +510,7 @@ func requestInternal(client sClient, ctx context.Context, method THttpMethod, ur
 	if len(ctxData.RequestId) > 0 {
 		header.Set("X-Request-Id", ctxData.RequestId)
 	}
+	tracing.Inject(ctx, header)
 	req, err := http.NewRequest(string(method), urlStr, body)
 	if err != nil {
 		return nil, nil, err
@@ -227,4 +227,6 @@ const (
 	ACT_SYNC_CLASS_METADATA = "sync_class_metadata"
 
 	ACT_ENCRYPTION = "encrypt"
+
+	ACT_WEBCONSOLE_RECORD = "webconsole_record"
 )
@@ -0,0 +1,115 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovfutils
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"path"
+	"strings"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+)
+
+const (
+	ErrInvalidPackage = errors.Error("invalid ova package")
+)
+
+// OpenDisk returns the reader of disk content from the reader of disk file, which may be compressed
+func (disk *SOvfDisk) OpenDisk(reader io.Reader) (io.ReadCloser, error) {
+	if disk.Compression == COMPRESSION_GZIP {
+		gz, err := gzip.NewReader(reader)
+		if err != nil {
+			return nil, errors.Wrapf(err, "gzip reader of %s", disk.Href)
+		}
+		return gz, nil
+	}
+	return ioutil.NopCloser(reader), nil
+}
+
+// ReadOva reads ova package from stream. The ovf descriptor must be the first file of
+// the package by the specification, so disks are handled one by one without extracting
+// the whole package. onDisk is called with the uncompressed content of each disk.
+func ReadOva(
+	reader io.Reader,
+	onDescriptor func(info *SOvfInfo) error,
+	onDisk func(disk *SOvfDisk, reader io.Reader) error,
+) error {
+	var info *SOvfInfo
+	read := make(map[int]bool)
+	tr := tar.NewReader(reader)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return errors.Wrap(ErrInvalidPackage, err.Error())
+		}
+		if hdr.Typeflag != tar.TypeReg && hdr.Typeflag != tar.TypeRegA {
+			continue
+		}
+		name := path.Clean(hdr.Name)
+		switch strings.ToLower(path.Ext(name)) {
+		case ".ovf":
+			if info != nil {
+				return errors.Wrapf(ErrInvalidPackage, "more than one ovf descriptor %s", name)
+			}
+			info, err = ParseStream(tr)
+			if err != nil {
+				return errors.Wrapf(err, "parse %s", name)
+			}
+			err = onDescriptor(info)
+			if err != nil {
+				return err
+			}
+			continue
+		case ".mf", ".cert":
+			continue
+		}
+		if info == nil {
+			return errors.Wrapf(ErrInvalidPackage, "%s precedes the ovf descriptor", name)
+		}
+		disk := info.GetDisk(name)
+		if disk == nil {
+			log.Infof("skip file %s not referenced by disks", name)
+			continue
+		}
+		err = func() error {
+			diskReader, err := disk.OpenDisk(tr)
+			if err != nil {
+				return err
+			}
+			defer diskReader.Close()
+			return onDisk(disk, diskReader)
+		}()
+		if err != nil {
+			return errors.Wrapf(err, "disk %s", disk.Href)
+		}
+		read[disk.Index] = true
+	}
+	if info == nil {
+		return errors.Wrap(ErrInvalidPackage, "no ovf descriptor")
+	}
+	for i := range info.Disks {
+		if !read[info.Disks[i].Index] {
+			return errors.Wrapf(ErrInvalidPackage, "missing disk file %s", info.Disks[i].Href)
+		}
+	}
+	return nil
+}
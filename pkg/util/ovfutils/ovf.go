@@ -0,0 +1,360 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovfutils
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+
+	"yunion.io/x/pkg/errors"
+)
+
+const (
+	FIRMWARE_BIOS = "bios"
+	FIRMWARE_UEFI = "uefi"
+
+	OS_TYPE_LINUX   = "Linux"
+	OS_TYPE_WINDOWS = "Windows"
+
+	COMPRESSION_GZIP = "gzip"
+
+	ErrInvalidDescriptor = errors.Error("invalid ovf descriptor")
+)
+
+// resource types of CIM_ResourceAllocationSettingData
+const (
+	resourceTypeProcessor = 3
+	resourceTypeMemory    = 4
+	resourceTypeEthernet  = 10
+	resourceTypeDisk      = 17
+)
+
+type sFile struct {
+	Id          string `xml:"id,attr"`
+	Href        string `xml:"href,attr"`
+	Size        int64  `xml:"size,attr"`
+	Compression string `xml:"compression,attr"`
+	ChunkSize   int64  `xml:"chunkSize,attr"`
+}
+
+type sDisk struct {
+	DiskId                  string `xml:"diskId,attr"`
+	FileRef                 string `xml:"fileRef,attr"`
+	Capacity                string `xml:"capacity,attr"`
+	CapacityAllocationUnits string `xml:"capacityAllocationUnits,attr"`
+	Format                  string `xml:"format,attr"`
+}
+
+// sItem is Item of ovf 1.x and StorageItem or EthernetPortItem of ovf 2.x
+type sItem struct {
+	ResourceType    int      `xml:"ResourceType"`
+	VirtualQuantity int64    `xml:"VirtualQuantity"`
+	AllocationUnits string   `xml:"AllocationUnits"`
+	HostResource    []string `xml:"HostResource"`
+}
+
+type sConfig struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type sVirtualSystem struct {
+	Id   string `xml:"id,attr"`
+	Name string `xml:"Name"`
+
+	OperatingSystem struct {
+		// vmware guest id, such as centos7_64Guest
+		OsType      string `xml:"osType,attr"`
+		Description string `xml:"Description"`
+		// virtualbox os type, such as Ubuntu_64
+		VBoxOsType string `xml:"OSType"`
+	} `xml:"OperatingSystemSection"`
+
+	Hardware struct {
+		Items             []sItem   `xml:"Item"`
+		StorageItems      []sItem   `xml:"StorageItem"`
+		EthernetPortItems []sItem   `xml:"EthernetPortItem"`
+		Configs           []sConfig `xml:"Config"`
+	} `xml:"VirtualHardwareSection"`
+
+	// vbox:Machine of virtualbox exported appliance
+	Machine struct {
+		Firmware struct {
+			Type string `xml:"type,attr"`
+		} `xml:"Hardware>Firmware"`
+	} `xml:"Machine"`
+}
+
+type sEnvelope struct {
+	XMLName xml.Name `xml:"Envelope"`
+
+	Files         []sFile         `xml:"References>File"`
+	Disks         []sDisk         `xml:"DiskSection>Disk"`
+	VirtualSystem *sVirtualSystem `xml:"VirtualSystem"`
+
+	VirtualSystemCollection *struct{} `xml:"VirtualSystemCollection"`
+}
+
+type SOvfDisk struct {
+	// Index is the order of disk attached to the virtual machine, disk 0 is the system disk
+	Index  int
+	DiskId string
+	// Href is the path of disk file relative to the descriptor
+	Href string
+	// Size is the size of disk file in bytes, 0 if unknown
+	Size int64
+	// Capacity is the virtual size of disk in bytes, 0 if unknown
+	Capacity    int64
+	Format      string
+	Compression string
+}
+
+type SOvfInfo struct {
+	Name          string
+	OsType        string
+	OsDescription string
+	CpuCount      int
+	MemoryMB      int64
+	NicCount      int
+	Firmware      string
+
+	Disks []SOvfDisk
+}
+
+func Parse(content []byte) (*SOvfInfo, error) {
+	return ParseStream(bytes.NewReader(content))
+}
+
+func ParseStream(stream io.Reader) (*SOvfInfo, error) {
+	envelope := sEnvelope{}
+	err := xml.NewDecoder(stream).Decode(&envelope)
+	if err != nil {
+		return nil, errors.Wrap(ErrInvalidDescriptor, err.Error())
+	}
+	if envelope.VirtualSystemCollection != nil {
+		return nil, errors.Wrap(errors.ErrNotSupported, "virtual system collection")
+	}
+	if envelope.VirtualSystem == nil {
+		return nil, errors.Wrap(ErrInvalidDescriptor, "no virtual system")
+	}
+	vs := envelope.VirtualSystem
+	info := &SOvfInfo{
+		Name:          vs.Name,
+		OsDescription: vs.OperatingSystem.Description,
+		Firmware:      FIRMWARE_BIOS,
+	}
+	if len(info.Name) == 0 {
+		info.Name = vs.Id
+	}
+	info.OsType = guessOsType(vs.OperatingSystem.OsType, vs.OperatingSystem.VBoxOsType, vs.OperatingSystem.Description)
+
+	for _, config := range vs.Hardware.Configs {
+		if config.Key == "firmware" && strings.ToLower(config.Value) == "efi" {
+			info.Firmware = FIRMWARE_UEFI
+		}
+	}
+	if strings.ToLower(vs.Machine.Firmware.Type) == "efi" {
+		info.Firmware = FIRMWARE_UEFI
+	}
+
+	items := make([]sItem, 0, len(vs.Hardware.Items)+len(vs.Hardware.StorageItems)+len(vs.Hardware.EthernetPortItems))
+	items = append(items, vs.Hardware.Items...)
+	items = append(items, vs.Hardware.StorageItems...)
+	items = append(items, vs.Hardware.EthernetPortItems...)
+
+	files := make(map[string]sFile)
+	for _, file := range envelope.Files {
+		files[file.Id] = file
+	}
+	disks := make(map[string]sDisk)
+	for _, disk := range envelope.Disks {
+		disks[disk.DiskId] = disk
+	}
+	attached := make([]string, 0)
+	for _, item := range items {
+		switch item.ResourceType {
+		case resourceTypeProcessor:
+			info.CpuCount = int(item.VirtualQuantity)
+		case resourceTypeMemory:
+			unit, err := parseAllocationUnits(item.AllocationUnits)
+			if err != nil {
+				return nil, errors.Wrapf(err, "memory")
+			}
+			info.MemoryMB = item.VirtualQuantity * unit / (1 << 20)
+		case resourceTypeEthernet:
+			info.NicCount += 1
+		case resourceTypeDisk:
+			for _, res := range item.HostResource {
+				// ovf:/disk/vmdisk1 or ovf:/file/file1
+				parts := strings.Split(strings.TrimPrefix(res, "ovf:"), "/")
+				if len(parts) == 3 && parts[1] == "disk" {
+					attached = append(attached, parts[2])
+				}
+			}
+		}
+	}
+	// disks not attached to any item follow the attached ones
+	for _, disk := range envelope.Disks {
+		found := false
+		for _, id := range attached {
+			if id == disk.DiskId {
+				found = true
+				break
+			}
+		}
+		if !found {
+			attached = append(attached, disk.DiskId)
+		}
+	}
+
+	for _, diskId := range attached {
+		disk, ok := disks[diskId]
+		if !ok {
+			return nil, errors.Wrapf(ErrInvalidDescriptor, "disk %s not found in disk section", diskId)
+		}
+		if len(disk.FileRef) == 0 {
+			// blank disk without file
+			continue
+		}
+		file, ok := files[disk.FileRef]
+		if !ok {
+			return nil, errors.Wrapf(ErrInvalidDescriptor, "file %s of disk %s not found in references", disk.FileRef, diskId)
+		}
+		if file.ChunkSize > 0 {
+			return nil, errors.Wrapf(errors.ErrNotSupported, "chunked file %s", file.Href)
+		}
+		if len(file.Compression) > 0 && file.Compression != COMPRESSION_GZIP {
+			return nil, errors.Wrapf(errors.ErrNotSupported, "compression %s of file %s", file.Compression, file.Href)
+		}
+		ovfDisk := SOvfDisk{
+			Index:       len(info.Disks),
+			DiskId:      diskId,
+			Href:        file.Href,
+			Size:        file.Size,
+			Format:      diskFormat(disk.Format, file.Href),
+			Compression: file.Compression,
+		}
+		if capacity, err := strconv.ParseInt(disk.Capacity, 10, 64); err == nil {
+			unit, err := parseAllocationUnits(disk.CapacityAllocationUnits)
+			if err != nil {
+				return nil, errors.Wrapf(err, "capacity of disk %s", diskId)
+			}
+			ovfDisk.Capacity = capacity * unit
+		}
+		info.Disks = append(info.Disks, ovfDisk)
+	}
+	if len(info.Disks) == 0 {
+		return nil, errors.Wrap(ErrInvalidDescriptor, "no disk file")
+	}
+	return info, nil
+}
+
+// GetDisk returns the disk whose file is name, which is the path of file in ova package or bundle
+func (info *SOvfInfo) GetDisk(name string) *SOvfDisk {
+	name = path.Clean(name)
+	for i := range info.Disks {
+		if path.Clean(info.Disks[i].Href) == name {
+			return &info.Disks[i]
+		}
+	}
+	return nil
+}
+
+// parseAllocationUnits parses programmatic units defined by DSP0004, such as "byte * 2^20" and "MegaBytes"
+func parseAllocationUnits(units string) (int64, error) {
+	u := strings.ToLower(strings.ReplaceAll(units, " ", ""))
+	switch u {
+	case "", "byte", "bytes":
+		return 1, nil
+	case "kb", "kilobyte", "kilobytes":
+		return 1 << 10, nil
+	case "mb", "megabyte", "megabytes":
+		return 1 << 20, nil
+	case "gb", "gigabyte", "gigabytes":
+		return 1 << 30, nil
+	case "tb", "terabyte", "terabytes":
+		return 1 << 40, nil
+	}
+	if !strings.HasPrefix(u, "byte*") {
+		return 0, errors.Wrapf(ErrInvalidDescriptor, "unknown allocation units %q", units)
+	}
+	parts := strings.SplitN(strings.TrimPrefix(u, "byte*"), "^", 2)
+	base, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, errors.Wrapf(ErrInvalidDescriptor, "unknown allocation units %q", units)
+	}
+	if len(parts) == 1 {
+		return base, nil
+	}
+	exp, err := strconv.Atoi(parts[1])
+	if err != nil || exp < 0 || exp > 62 {
+		return 0, errors.Wrapf(ErrInvalidDescriptor, "unknown allocation units %q", units)
+	}
+	ret := int64(1)
+	for i := 0; i < exp; i++ {
+		ret *= base
+	}
+	return ret, nil
+}
+
+func diskFormat(format, href string) string {
+	format = strings.ToLower(format)
+	switch {
+	case strings.Contains(format, "vmdk"):
+		return "vmdk"
+	case strings.Contains(format, "qcow"):
+		return "qcow2"
+	case strings.Contains(format, "vhd"):
+		return "vhd"
+	}
+	switch strings.ToLower(path.Ext(href)) {
+	case ".vmdk":
+		return "vmdk"
+	case ".qcow2":
+		return "qcow2"
+	case ".vhd":
+		return "vhd"
+	case ".raw", ".img":
+		return "raw"
+	}
+	return ""
+}
+
+var linuxOsNames = []string{
+	"linux", "centos", "rhel", "redhat", "ubuntu", "debian", "suse", "sles", "fedora",
+	"oracle", "rocky", "alma", "coreos", "photon", "openeuler", "kylin", "uos",
+}
+
+func guessOsType(names ...string) string {
+	for _, name := range names {
+		name = strings.ToLower(name)
+		if len(name) == 0 {
+			continue
+		}
+		if strings.HasPrefix(name, "win") || strings.Contains(name, "windows") {
+			return OS_TYPE_WINDOWS
+		}
+		for _, n := range linuxOsNames {
+			if strings.Contains(name, n) {
+				return OS_TYPE_LINUX
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,246 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovfutils
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"yunion.io/x/pkg/errors"
+)
+
+const vmwareOvf = `<?xml version="1.0" encoding="UTF-8"?>
+<Envelope vmw:buildId="build-13010631" xmlns="http://schemas.dmtf.org/ovf/envelope/1" xmlns:ovf="http://schemas.dmtf.org/ovf/envelope/1" xmlns:rasd="http://schemas.dmtf.org/wbem/wscim/1/cim-schema/2/CIM_ResourceAllocationSettingData" xmlns:vmw="http://www.vmware.com/schema/ovf">
+  <References>
+    <File ovf:href="centos-disk1.vmdk" ovf:id="file1" ovf:size="1073741824"/>
+    <File ovf:href="centos-disk2.vmdk.gz" ovf:id="file2" ovf:size="1024" ovf:compression="gzip"/>
+  </References>
+  <DiskSection>
+    <Info>Virtual disk information</Info>
+    <Disk ovf:capacity="20" ovf:capacityAllocationUnits="byte * 2^30" ovf:diskId="vmdisk2" ovf:fileRef="file2" ovf:format="http://www.vmware.com/interfaces/specifications/vmdk.html#streamOptimized"/>
+    <Disk ovf:capacity="40" ovf:capacityAllocationUnits="byte * 2^30" ovf:diskId="vmdisk1" ovf:fileRef="file1" ovf:format="http://www.vmware.com/interfaces/specifications/vmdk.html#streamOptimized"/>
+  </DiskSection>
+  <VirtualSystem ovf:id="centos7">
+    <Name>centos7</Name>
+    <OperatingSystemSection ovf:id="107" vmw:osType="centos7_64Guest">
+      <Description>CentOS 7 (64-bit)</Description>
+    </OperatingSystemSection>
+    <VirtualHardwareSection>
+      <Item>
+        <rasd:AllocationUnits>hertz * 10^6</rasd:AllocationUnits>
+        <rasd:InstanceID>1</rasd:InstanceID>
+        <rasd:ResourceType>3</rasd:ResourceType>
+        <rasd:VirtualQuantity>4</rasd:VirtualQuantity>
+      </Item>
+      <Item>
+        <rasd:AllocationUnits>byte * 2^20</rasd:AllocationUnits>
+        <rasd:InstanceID>2</rasd:InstanceID>
+        <rasd:ResourceType>4</rasd:ResourceType>
+        <rasd:VirtualQuantity>8192</rasd:VirtualQuantity>
+      </Item>
+      <Item>
+        <rasd:AddressOnParent>0</rasd:AddressOnParent>
+        <rasd:HostResource>ovf:/disk/vmdisk1</rasd:HostResource>
+        <rasd:InstanceID>6</rasd:InstanceID>
+        <rasd:ResourceType>17</rasd:ResourceType>
+      </Item>
+      <Item>
+        <rasd:AddressOnParent>1</rasd:AddressOnParent>
+        <rasd:HostResource>ovf:/disk/vmdisk2</rasd:HostResource>
+        <rasd:InstanceID>7</rasd:InstanceID>
+        <rasd:ResourceType>17</rasd:ResourceType>
+      </Item>
+      <Item>
+        <rasd:InstanceID>8</rasd:InstanceID>
+        <rasd:ResourceType>10</rasd:ResourceType>
+      </Item>
+      <Item>
+        <rasd:InstanceID>9</rasd:InstanceID>
+        <rasd:ResourceType>10</rasd:ResourceType>
+      </Item>
+      <vmw:Config ovf:required="false" vmw:key="firmware" vmw:value="efi"/>
+    </VirtualHardwareSection>
+  </VirtualSystem>
+</Envelope>`
+
+const vboxOvf = `<?xml version="1.0"?>
+<Envelope ovf:version="2.0" xmlns="http://schemas.dmtf.org/ovf/envelope/2" xmlns:ovf="http://schemas.dmtf.org/ovf/envelope/2" xmlns:rasd="http://schemas.dmtf.org/wbem/wscim/1/cim-schema/2/CIM_ResourceAllocationSettingData" xmlns:vbox="http://www.virtualbox.org/ovf/machine">
+  <References>
+    <File ovf:id="file1" ovf:href="win10-disk001.vmdk"/>
+  </References>
+  <DiskSection>
+    <Disk ovf:capacity="53687091200" ovf:diskId="vmdisk1" ovf:fileRef="file1" ovf:format="http://www.vmware.com/interfaces/specifications/vmdk.html#streamOptimized"/>
+  </DiskSection>
+  <VirtualSystem ovf:id="win10">
+    <OperatingSystemSection ovf:id="105">
+      <Description>Microsoft Windows</Description>
+      <vbox:OSType ovf:required="false">Windows10_64</vbox:OSType>
+    </OperatingSystemSection>
+    <VirtualHardwareSection>
+      <Item>
+        <rasd:ResourceType>3</rasd:ResourceType>
+        <rasd:VirtualQuantity>2</rasd:VirtualQuantity>
+      </Item>
+      <Item>
+        <rasd:AllocationUnits>MegaBytes</rasd:AllocationUnits>
+        <rasd:ResourceType>4</rasd:ResourceType>
+        <rasd:VirtualQuantity>4096</rasd:VirtualQuantity>
+      </Item>
+      <StorageItem>
+        <sasd:HostResource xmlns:sasd="http://schemas.dmtf.org/wbem/wscim/1/cim-schema/2/CIM_StorageAllocationSettingData">/disk/vmdisk1</sasd:HostResource>
+        <sasd:ResourceType xmlns:sasd="http://schemas.dmtf.org/wbem/wscim/1/cim-schema/2/CIM_StorageAllocationSettingData">17</sasd:ResourceType>
+      </StorageItem>
+      <EthernetPortItem>
+        <epasd:ResourceType xmlns:epasd="http://schemas.dmtf.org/wbem/wscim/1/cim-schema/2/CIM_EthernetPortAllocationSettingData">10</epasd:ResourceType>
+      </EthernetPortItem>
+    </VirtualHardwareSection>
+    <vbox:Machine ovf:required="false" name="win10">
+      <Hardware>
+        <Firmware type="EFI"/>
+      </Hardware>
+    </vbox:Machine>
+  </VirtualSystem>
+</Envelope>`
+
+func TestParse(t *testing.T) {
+	info, err := Parse([]byte(vmwareOvf))
+	if err != nil {
+		t.Fatalf("parse vmware ovf: %v", err)
+	}
+	if info.Name != "centos7" || info.OsType != OS_TYPE_LINUX || info.CpuCount != 4 || info.MemoryMB != 8192 || info.NicCount != 2 || info.Firmware != FIRMWARE_UEFI {
+		t.Errorf("unexpected vmware info %#v", info)
+	}
+	if len(info.Disks) != 2 {
+		t.Fatalf("expect 2 disks, got %d", len(info.Disks))
+	}
+	// disks are ordered by the hardware items instead of disk section
+	if info.Disks[0].DiskId != "vmdisk1" || info.Disks[0].Capacity != 40<<30 || info.Disks[0].Format != "vmdk" {
+		t.Errorf("unexpected system disk %#v", info.Disks[0])
+	}
+	if info.Disks[1].Index != 1 || info.Disks[1].Compression != COMPRESSION_GZIP {
+		t.Errorf("unexpected data disk %#v", info.Disks[1])
+	}
+
+	info, err = Parse([]byte(vboxOvf))
+	if err != nil {
+		t.Fatalf("parse virtualbox ovf: %v", err)
+	}
+	if info.Name != "win10" || info.OsType != OS_TYPE_WINDOWS || info.CpuCount != 2 || info.MemoryMB != 4096 || info.NicCount != 1 || info.Firmware != FIRMWARE_UEFI {
+		t.Errorf("unexpected virtualbox info %#v", info)
+	}
+	if len(info.Disks) != 1 || info.Disks[0].Capacity != 50<<30 {
+		t.Errorf("unexpected virtualbox disks %#v", info.Disks)
+	}
+
+	_, err = Parse([]byte("<Envelope></Envelope>"))
+	if errors.Cause(err) != ErrInvalidDescriptor {
+		t.Errorf("expect invalid descriptor, got %v", err)
+	}
+}
+
+func TestParseAllocationUnits(t *testing.T) {
+	cases := map[string]int64{
+		"":             1,
+		"byte":         1,
+		"byte * 2^20":  1 << 20,
+		"byte * 1024":  1024,
+		"GigaBytes":    1 << 30,
+		"byte*10^3":    1000,
+		"hertz * 10^6": 0,
+	}
+	for units, want := range cases {
+		got, err := parseAllocationUnits(units)
+		if want == 0 {
+			if err == nil {
+				t.Errorf("%q should fail", units)
+			}
+			continue
+		}
+		if err != nil || got != want {
+			t.Errorf("%q: want %d got %d, %v", units, want, got, err)
+		}
+	}
+}
+
+type tarFile struct {
+	name    string
+	content []byte
+}
+
+func makeOva(t *testing.T, files []tarFile) io.Reader {
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	for _, f := range files {
+		err := tw.WriteHeader(&tar.Header{Name: f.name, Mode: 0644, Size: int64(len(f.content))})
+		if err != nil {
+			t.Fatalf("write header: %v", err)
+		}
+		tw.Write(f.content)
+	}
+	tw.Close()
+	return buf
+}
+
+func TestReadOva(t *testing.T) {
+	gzBuf := &bytes.Buffer{}
+	gw := gzip.NewWriter(gzBuf)
+	gw.Write([]byte("data disk"))
+	gw.Close()
+
+	ova := makeOva(t, []tarFile{
+		{"centos.ovf", []byte(vmwareOvf)},
+		{"centos.mf", []byte("SHA256(centos.ovf)= 00")},
+		{"./centos-disk1.vmdk", []byte("system disk")},
+		{"centos-disk2.vmdk.gz", gzBuf.Bytes()},
+	})
+	disks := make(map[string]string)
+	var name string
+	err := ReadOva(ova, func(info *SOvfInfo) error {
+		name = info.Name
+		return nil
+	}, func(disk *SOvfDisk, reader io.Reader) error {
+		content, err := ioutil.ReadAll(reader)
+		disks[disk.DiskId] = string(content)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("read ova: %v", err)
+	}
+	if name != "centos7" || disks["vmdisk1"] != "system disk" || disks["vmdisk2"] != "data disk" {
+		t.Errorf("unexpected ova content %s %#v", name, disks)
+	}
+
+	ova = makeOva(t, []tarFile{
+		{"centos-disk1.vmdk", []byte("system disk")},
+		{"centos.ovf", []byte(vmwareOvf)},
+	})
+	err = ReadOva(ova, func(*SOvfInfo) error { return nil }, func(*SOvfDisk, io.Reader) error { return nil })
+	if errors.Cause(err) != ErrInvalidPackage {
+		t.Errorf("expect invalid package if descriptor is not the first, got %v", err)
+	}
+
+	ova = makeOva(t, []tarFile{
+		{"centos.ovf", []byte(vmwareOvf)},
+		{"centos-disk1.vmdk", []byte("system disk")},
+	})
+	err = ReadOva(ova, func(*SOvfInfo) error { return nil }, func(*SOvfDisk, io.Reader) error { return nil })
+	if errors.Cause(err) != ErrInvalidPackage {
+		t.Errorf("expect invalid package if disk is missing, got %v", err)
+	}
+}
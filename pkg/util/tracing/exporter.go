@@ -0,0 +1,354 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+)
+
+const (
+	OTLP_TRACES_PATH = "/v1/traces"
+
+	DEFAULT_BATCH_SIZE     = 512
+	DEFAULT_QUEUE_SIZE     = 2048
+	DEFAULT_FLUSH_INTERVAL = 5 * time.Second
+	DEFAULT_EXPORT_TIMEOUT = 10 * time.Second
+
+	instrumentationScope = "yunion.io/x/onecloud"
+)
+
+type SOptions struct {
+	ServiceName string
+	// OTLP/HTTP endpoint of the collector, e.g. http://otel-collector:4318
+	Endpoint string
+	// extra headers sent to the collector, e.g. for authentication
+	Headers map[string]string
+	// ratio of the traces started by this service to be sampled, the
+	// decision of the parent is respected for propagated traces
+	SampleRatio float64
+}
+
+type sTracer struct {
+	opts     SOptions
+	url      string
+	client   *http.Client
+	spans    chan *Span
+	stop     chan struct{}
+	done     chan struct{}
+	dropped  int64
+	stopOnce sync.Once
+}
+
+var (
+	tracerLock   sync.RWMutex
+	globalTracer *sTracer
+)
+
+func getTracer() *sTracer {
+	tracerLock.RLock()
+	defer tracerLock.RUnlock()
+
+	return globalTracer
+}
+
+func IsEnabled() bool {
+	return getTracer() != nil
+}
+
+// Init starts exporting spans of this process to the OTLP collector,
+// replacing the exporter started before.
+func Init(opts SOptions) error {
+	if len(opts.Endpoint) == 0 {
+		return errors.Error("empty otlp endpoint")
+	}
+	u, err := url.Parse(opts.Endpoint)
+	if err != nil || len(u.Host) == 0 {
+		return errors.Errorf("invalid otlp endpoint %s", opts.Endpoint)
+	}
+	if !strings.HasSuffix(u.Path, OTLP_TRACES_PATH) {
+		u.Path = strings.TrimSuffix(u.Path, "/") + OTLP_TRACES_PATH
+	}
+	tracer := &sTracer{
+		opts:   opts,
+		url:    u.String(),
+		client: &http.Client{Timeout: DEFAULT_EXPORT_TIMEOUT},
+		spans:  make(chan *Span, DEFAULT_QUEUE_SIZE),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go tracer.run()
+
+	tracerLock.Lock()
+	old := globalTracer
+	globalTracer = tracer
+	tracerLock.Unlock()
+
+	if old != nil {
+		old.shutdown(context.Background())
+	}
+	log.Infof("tracing enabled, export spans of %s to %s with sample ratio %v", opts.ServiceName, tracer.url, opts.SampleRatio)
+	return nil
+}
+
+// Shutdown stops the exporter after the pending spans are flushed.
+func Shutdown(ctx context.Context) {
+	tracerLock.Lock()
+	tracer := globalTracer
+	globalTracer = nil
+	tracerLock.Unlock()
+
+	if tracer != nil {
+		tracer.shutdown(ctx)
+	}
+}
+
+func (t *sTracer) shouldSample(traceId TraceID) bool {
+	return sampleTraceId(traceId, t.opts.SampleRatio)
+}
+
+func (t *sTracer) export(s *Span) {
+	select {
+	case t.spans <- s:
+	default:
+		if n := atomic.AddInt64(&t.dropped, 1); n%1000 == 1 {
+			log.Warningf("tracing queue full, %d spans dropped", n)
+		}
+	}
+}
+
+func (t *sTracer) shutdown(ctx context.Context) {
+	t.stopOnce.Do(func() {
+		close(t.stop)
+	})
+	select {
+	case <-t.done:
+	case <-ctx.Done():
+	}
+}
+
+func (t *sTracer) run() {
+	defer close(t.done)
+
+	ticker := time.NewTicker(DEFAULT_FLUSH_INTERVAL)
+	defer ticker.Stop()
+
+	batch := make([]*Span, 0, DEFAULT_BATCH_SIZE)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := t.post(batch); err != nil {
+			log.Warningf("export %d spans: %v", len(batch), err)
+		}
+		batch = batch[:0]
+	}
+	for {
+		select {
+		case s := <-t.spans:
+			batch = append(batch, s)
+			if len(batch) >= DEFAULT_BATCH_SIZE {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-t.stop:
+			for {
+				select {
+				case s := <-t.spans:
+					batch = append(batch, s)
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+func (t *sTracer) post(spans []*Span) error {
+	body, err := json.Marshal(t.exportRequest(spans))
+	if err != nil {
+		return errors.Wrap(err, "json.Marshal")
+	}
+	req, err := http.NewRequest(http.MethodPost, t.url, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "http.NewRequest")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range t.opts.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "post")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return errors.Errorf("collector responds %d: %s", resp.StatusCode, msg)
+	}
+	return nil
+}
+
+// OTLP/JSON encoding of ExportTraceServiceRequest, ids are hex strings and
+// 64 bit integers are decimal strings.
+type otlpAnyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpStatus struct {
+	Code    StatusCode `json:"code,omitempty"`
+	Message string     `json:"message,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceId           string         `json:"traceId"`
+	SpanId            string         `json:"spanId"`
+	TraceState        string         `json:"traceState,omitempty"`
+	ParentSpanId      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              SpanKind       `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpExportRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+func otlpValue(v interface{}) otlpAnyValue {
+	switch val := v.(type) {
+	case string:
+		return otlpAnyValue{StringValue: &val}
+	case bool:
+		return otlpAnyValue{BoolValue: &val}
+	case int:
+		s := strconv.FormatInt(int64(val), 10)
+		return otlpAnyValue{IntValue: &s}
+	case int64:
+		s := strconv.FormatInt(val, 10)
+		return otlpAnyValue{IntValue: &s}
+	case float64:
+		return otlpAnyValue{DoubleValue: &val}
+	default:
+		s := fmt.Sprintf("%v", val)
+		return otlpAnyValue{StringValue: &s}
+	}
+}
+
+func otlpAttributes(attrs map[string]interface{}) []otlpKeyValue {
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	ret := make([]otlpKeyValue, len(keys))
+	for i, k := range keys {
+		ret[i] = otlpKeyValue{Key: k, Value: otlpValue(attrs[k])}
+	}
+	return ret
+}
+
+func (s *Span) otlp() otlpSpan {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	span := otlpSpan{
+		TraceId:           s.spanContext.TraceId.String(),
+		SpanId:            s.spanContext.SpanId.String(),
+		TraceState:        s.spanContext.TraceState,
+		Name:              s.name,
+		Kind:              s.kind,
+		StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+		Attributes:        otlpAttributes(s.attributes),
+		Status: otlpStatus{
+			Code:    s.status,
+			Message: s.statusMsg,
+		},
+	}
+	if s.parentSpanId.IsValid() {
+		span.ParentSpanId = s.parentSpanId.String()
+	}
+	return span
+}
+
+func (t *sTracer) exportRequest(spans []*Span) otlpExportRequest {
+	otlpSpans := make([]otlpSpan, len(spans))
+	for i := range spans {
+		otlpSpans[i] = spans[i].otlp()
+	}
+	return otlpExportRequest{
+		ResourceSpans: []otlpResourceSpans{
+			{
+				Resource: otlpResource{
+					Attributes: otlpAttributes(map[string]interface{}{
+						"service.name": t.opts.ServiceName,
+					}),
+				},
+				ScopeSpans: []otlpScopeSpans{
+					{
+						Scope: otlpScope{Name: instrumentationScope},
+						Spans: otlpSpans,
+					},
+				},
+			},
+		},
+	}
+}
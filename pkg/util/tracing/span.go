@@ -0,0 +1,352 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"yunion.io/x/pkg/errors"
+)
+
+const (
+	// W3C trace context headers, https://www.w3.org/TR/trace-context/
+	TRACEPARENT_HEADER = "traceparent"
+	TRACESTATE_HEADER  = "tracestate"
+
+	traceparentVersion = "00"
+	flagSampled        = 0x01
+
+	ErrInvalidTraceParent = errors.Error("invalid traceparent")
+)
+
+type TraceID [16]byte
+
+type SpanID [8]byte
+
+func (t TraceID) IsValid() bool {
+	return t != TraceID{}
+}
+
+func (t TraceID) String() string {
+	return hex.EncodeToString(t[:])
+}
+
+func (s SpanID) IsValid() bool {
+	return s != SpanID{}
+}
+
+func (s SpanID) String() string {
+	return hex.EncodeToString(s[:])
+}
+
+// SpanContext is the part of a span propagated across process boundaries.
+type SpanContext struct {
+	TraceId    TraceID
+	SpanId     SpanID
+	Sampled    bool
+	TraceState string
+}
+
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceId.IsValid() && sc.SpanId.IsValid()
+}
+
+func (sc SpanContext) TraceParent() string {
+	flags := 0
+	if sc.Sampled {
+		flags |= flagSampled
+	}
+	return fmt.Sprintf("%s-%s-%s-%02x", traceparentVersion, sc.TraceId, sc.SpanId, flags)
+}
+
+func ParseTraceParent(traceparent string) (SpanContext, error) {
+	sc := SpanContext{}
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(parts) < 4 {
+		return sc, errors.Wrap(ErrInvalidTraceParent, traceparent)
+	}
+	version, traceId, spanId, flags := parts[0], parts[1], parts[2], parts[3]
+	// future versions may append fields, version ff is forbidden
+	if len(version) != 2 || version == "ff" || (version == traceparentVersion && len(parts) != 4) {
+		return sc, errors.Wrapf(ErrInvalidTraceParent, "version of %s", traceparent)
+	}
+	if len(traceId) != 32 || len(spanId) != 16 || len(flags) != 2 {
+		return sc, errors.Wrap(ErrInvalidTraceParent, traceparent)
+	}
+	if _, err := hex.Decode(sc.TraceId[:], []byte(traceId)); err != nil {
+		return sc, errors.Wrapf(ErrInvalidTraceParent, "trace id of %s", traceparent)
+	}
+	if _, err := hex.Decode(sc.SpanId[:], []byte(spanId)); err != nil {
+		return sc, errors.Wrapf(ErrInvalidTraceParent, "span id of %s", traceparent)
+	}
+	flagBytes, err := hex.DecodeString(flags)
+	if err != nil {
+		return sc, errors.Wrapf(ErrInvalidTraceParent, "flags of %s", traceparent)
+	}
+	if !sc.IsValid() {
+		return sc, errors.Wrapf(ErrInvalidTraceParent, "all zero id of %s", traceparent)
+	}
+	sc.Sampled = flagBytes[0]&flagSampled != 0
+	return sc, nil
+}
+
+type SpanKind int
+
+// values of the span kind as defined by OTLP
+const (
+	SPAN_KIND_INTERNAL = SpanKind(1)
+	SPAN_KIND_SERVER   = SpanKind(2)
+	SPAN_KIND_CLIENT   = SpanKind(3)
+)
+
+type StatusCode int
+
+const (
+	STATUS_UNSET = StatusCode(0)
+	STATUS_OK    = StatusCode(1)
+	STATUS_ERROR = StatusCode(2)
+)
+
+// Span is an operation being traced, all the methods are safe on a nil
+// span, which is what StartSpan returns when tracing is disabled.
+type Span struct {
+	lock sync.Mutex
+
+	name         string
+	kind         SpanKind
+	spanContext  SpanContext
+	parentSpanId SpanID
+	start        time.Time
+	end          time.Time
+	attributes   map[string]interface{}
+	status       StatusCode
+	statusMsg    string
+	ended        bool
+
+	tracer *sTracer
+}
+
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.spanContext
+}
+
+func (s *Span) SetName(name string) {
+	if s == nil {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.name = name
+}
+
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.attributes == nil {
+		s.attributes = make(map[string]interface{})
+	}
+	s.attributes[key] = value
+}
+
+func (s *Span) SetStatus(code StatusCode, msg string) {
+	if s == nil {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.status = code
+	s.statusMsg = msg
+}
+
+// RecordError marks the span failed by err, a nil err is ignored.
+func (s *Span) RecordError(err error) {
+	if err == nil {
+		return
+	}
+	s.SetStatus(STATUS_ERROR, err.Error())
+}
+
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.lock.Lock()
+	if s.ended {
+		s.lock.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	s.lock.Unlock()
+
+	if s.spanContext.Sampled && s.tracer != nil {
+		s.tracer.export(s)
+	}
+}
+
+type spanContextKey struct{}
+
+type remoteSpanContextKey struct{}
+
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, span)
+}
+
+func SpanFromContext(ctx context.Context) *Span {
+	if ctx == nil {
+		return nil
+	}
+	if span, ok := ctx.Value(spanContextKey{}).(*Span); ok {
+		return span
+	}
+	return nil
+}
+
+// ContextWithRemoteSpanContext makes sc, which comes from another process,
+// the parent of the spans started from the returned context.
+func ContextWithRemoteSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, remoteSpanContextKey{}, sc)
+}
+
+// SpanContextFromContext returns the span context of the current span or
+// the remote parent stored in ctx.
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	if ctx == nil {
+		return SpanContext{}
+	}
+	if span := SpanFromContext(ctx); span != nil {
+		return span.SpanContext()
+	}
+	if sc, ok := ctx.Value(remoteSpanContextKey{}).(SpanContext); ok {
+		return sc
+	}
+	return SpanContext{}
+}
+
+// TraceParentFromContext returns the traceparent to be propagated from ctx,
+// or an empty string if there is no valid span context.
+func TraceParentFromContext(ctx context.Context) string {
+	sc := SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return ""
+	}
+	return sc.TraceParent()
+}
+
+// Extract returns a context carrying the remote span context of the
+// trace context headers in header.
+func Extract(ctx context.Context, header http.Header) context.Context {
+	traceparent := header.Get(TRACEPARENT_HEADER)
+	if len(traceparent) == 0 {
+		return ctx
+	}
+	sc, err := ParseTraceParent(traceparent)
+	if err != nil {
+		return ctx
+	}
+	sc.TraceState = header.Get(TRACESTATE_HEADER)
+	return ContextWithRemoteSpanContext(ctx, sc)
+}
+
+// Inject writes the span context of ctx into header as trace context headers.
+func Inject(ctx context.Context, header http.Header) {
+	sc := SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return
+	}
+	header.Set(TRACEPARENT_HEADER, sc.TraceParent())
+	if len(sc.TraceState) > 0 {
+		header.Set(TRACESTATE_HEADER, sc.TraceState)
+	}
+}
+
+func newTraceId() TraceID {
+	id := TraceID{}
+	rand.Read(id[:])
+	return id
+}
+
+func newSpanId() SpanID {
+	id := SpanID{}
+	for !id.IsValid() {
+		rand.Read(id[:])
+	}
+	return id
+}
+
+// StartSpan starts a span as a child of the span or remote span context in
+// ctx, a new trace is started if there is neither.
+func StartSpan(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	tracer := getTracer()
+	if tracer == nil {
+		return ctx, nil
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	parent := SpanContextFromContext(ctx)
+	span := &Span{
+		name:   name,
+		kind:   kind,
+		start:  time.Now(),
+		tracer: tracer,
+	}
+	if parent.IsValid() {
+		span.spanContext = SpanContext{
+			TraceId:    parent.TraceId,
+			Sampled:    parent.Sampled,
+			TraceState: parent.TraceState,
+		}
+		span.parentSpanId = parent.SpanId
+	} else {
+		traceId := newTraceId()
+		span.spanContext = SpanContext{
+			TraceId: traceId,
+			Sampled: tracer.shouldSample(traceId),
+		}
+	}
+	span.spanContext.SpanId = newSpanId()
+	return ContextWithSpan(ctx, span), span
+}
+
+// sampleTraceId decides by the lower 8 bytes of the trace id, so that all
+// the services sample a trace started without a sampled parent consistently.
+func sampleTraceId(traceId TraceID, ratio float64) bool {
+	if ratio >= 1 {
+		return true
+	}
+	if ratio <= 0 {
+		return false
+	}
+	bound := uint64(ratio * (1 << 63))
+	return binary.BigEndian.Uint64(traceId[8:16])>>1 < bound
+}
@@ -0,0 +1,180 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseTraceParent(t *testing.T) {
+	cases := []struct {
+		in      string
+		sampled bool
+		valid   bool
+	}{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true, true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", false, true},
+		{"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", true, true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", false, false},
+		{"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false, false},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", false, false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", false, false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e47zz-00f067aa0ba902b7-01", false, false},
+		{"00-4bf92f3577b34da6-00f067aa0ba902b7-01", false, false},
+		{"", false, false},
+	}
+	for _, c := range cases {
+		sc, err := ParseTraceParent(c.in)
+		if c.valid != (err == nil) {
+			t.Errorf("%q: want valid %v, got error %v", c.in, c.valid, err)
+			continue
+		}
+		if !c.valid {
+			continue
+		}
+		if sc.Sampled != c.sampled {
+			t.Errorf("%q: want sampled %v", c.in, c.sampled)
+		}
+		if sc.TraceId.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || sc.SpanId.String() != "00f067aa0ba902b7" {
+			t.Errorf("%q: got ids %s %s", c.in, sc.TraceId, sc.SpanId)
+		}
+	}
+
+	in := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	sc, _ := ParseTraceParent(in)
+	if got := sc.TraceParent(); got != in {
+		t.Errorf("want %s, got %s", in, got)
+	}
+}
+
+func TestStartSpanDisabled(t *testing.T) {
+	Shutdown(context.Background())
+
+	ctx, span := StartSpan(context.Background(), "noop", SPAN_KIND_INTERNAL)
+	if span != nil {
+		t.Fatalf("span started when tracing is disabled")
+	}
+	// nil spans are no-ops
+	span.SetAttribute("k", "v")
+	span.RecordError(ErrInvalidTraceParent)
+	span.End()
+
+	header := http.Header{}
+	Inject(ctx, header)
+	if len(header) != 0 {
+		t.Errorf("unexpected headers %v", header)
+	}
+
+	// propagation still works without an exporter
+	header.Set(TRACEPARENT_HEADER, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	header.Set(TRACESTATE_HEADER, "vendor=value")
+	ctx = Extract(context.Background(), header)
+	out := http.Header{}
+	Inject(ctx, out)
+	if out.Get(TRACEPARENT_HEADER) != header.Get(TRACEPARENT_HEADER) || out.Get(TRACESTATE_HEADER) != "vendor=value" {
+		t.Errorf("want %v, got %v", header, out)
+	}
+}
+
+func TestSampleTraceId(t *testing.T) {
+	if !sampleTraceId(newTraceId(), 1) {
+		t.Errorf("ratio 1 should always sample")
+	}
+	if sampleTraceId(newTraceId(), 0) {
+		t.Errorf("ratio 0 should never sample")
+	}
+	sampled := 0
+	for i := 0; i < 10000; i++ {
+		if sampleTraceId(newTraceId(), 0.25) {
+			sampled++
+		}
+	}
+	if sampled < 2000 || sampled > 3000 {
+		t.Errorf("ratio 0.25 sampled %d of 10000", sampled)
+	}
+}
+
+func TestExport(t *testing.T) {
+	received := make(chan otlpExportRequest, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != OTLP_TRACES_PATH {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("missing header")
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		req := otlpExportRequest{}
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Errorf("unmarshal %s: %v", body, err)
+		}
+		received <- req
+	}))
+	defer srv.Close()
+
+	err := Init(SOptions{
+		ServiceName: "test",
+		Endpoint:    srv.URL,
+		Headers:     map[string]string{"Authorization": "Bearer token"},
+		SampleRatio: 1,
+	})
+	if err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+
+	ctx, parent := StartSpan(context.Background(), "parent", SPAN_KIND_SERVER)
+	_, child := StartSpan(ctx, "child", SPAN_KIND_CLIENT)
+	child.SetAttribute("http.status_code", 500)
+	child.RecordError(ErrInvalidTraceParent)
+	child.End()
+	parent.End()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	Shutdown(shutdownCtx)
+
+	var req otlpExportRequest
+	select {
+	case req = <-received:
+	default:
+		t.Fatalf("no spans exported")
+	}
+	if len(req.ResourceSpans) != 1 || len(req.ResourceSpans[0].ScopeSpans) != 1 {
+		t.Fatalf("unexpected request %#v", req)
+	}
+	if v := req.ResourceSpans[0].Resource.Attributes[0]; v.Key != "service.name" || *v.Value.StringValue != "test" {
+		t.Errorf("unexpected resource %#v", v)
+	}
+	spans := req.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("want 2 spans, got %d", len(spans))
+	}
+	c, p := spans[0], spans[1]
+	if c.TraceId != p.TraceId || c.ParentSpanId != p.SpanId || len(p.ParentSpanId) != 0 {
+		t.Errorf("broken parent relation %#v %#v", c, p)
+	}
+	if c.Status.Code != STATUS_ERROR || c.Kind != SPAN_KIND_CLIENT {
+		t.Errorf("unexpected child %#v", c)
+	}
+	if len(c.Attributes) != 1 || *c.Attributes[0].Value.IntValue != "500" {
+		t.Errorf("unexpected attributes %#v", c.Attributes)
+	}
+}
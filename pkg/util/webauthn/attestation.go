@@ -0,0 +1,152 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webauthn
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/x509"
+
+	"yunion.io/x/pkg/errors"
+)
+
+const (
+	AttestationFormatNone    = "none"
+	AttestationFormatPacked  = "packed"
+	AttestationFormatFidoU2F = "fido-u2f"
+)
+
+type sAttestationObject struct {
+	format   string
+	authData []byte
+	attStmt  map[interface{}]interface{}
+}
+
+func parseAttestationObject(data []byte) (*sAttestationObject, error) {
+	obj, _, err := decodeCBOR(data)
+	if err != nil {
+		return nil, errors.Wrap(err, "decode attestationObject")
+	}
+	m, ok := obj.(map[interface{}]interface{})
+	if !ok {
+		return nil, errors.Wrap(ErrInvalidAttestation, "attestationObject is not a map")
+	}
+	att := &sAttestationObject{}
+	if att.format, ok = m["fmt"].(string); !ok {
+		return nil, errors.Wrap(ErrInvalidAttestation, "missing fmt")
+	}
+	if att.authData, ok = m["authData"].([]byte); !ok {
+		return nil, errors.Wrap(ErrInvalidAttestation, "missing authData")
+	}
+	if stmt, ok := m["attStmt"]; ok {
+		if att.attStmt, ok = stmt.(map[interface{}]interface{}); !ok {
+			return nil, errors.Wrap(ErrInvalidAttestation, "attStmt is not a map")
+		}
+	}
+	return att, nil
+}
+
+// x5c returns the attestation certificate chain, leaf first
+func (att *sAttestationObject) x5c() ([]*x509.Certificate, error) {
+	raw, ok := att.attStmt["x5c"]
+	if !ok {
+		return nil, nil
+	}
+	list, ok := raw.([]interface{})
+	if !ok || len(list) == 0 {
+		return nil, errors.Wrap(ErrInvalidAttestation, "malformed x5c")
+	}
+	certs := make([]*x509.Certificate, 0, len(list))
+	for _, item := range list {
+		der, ok := item.([]byte)
+		if !ok {
+			return nil, errors.Wrap(ErrInvalidAttestation, "malformed x5c")
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, errors.Wrap(ErrInvalidAttestation, err.Error())
+		}
+		certs = append(certs, cert)
+	}
+	return certs, nil
+}
+
+// verify checks the attestation statement. The certificate chain is not
+// validated against a trust store, the relying party only asks for
+// attestation "none" and keeps the format for auditing.
+func (att *sAttestationObject) verify(ad *sAuthenticatorData, pub *SPublicKey, clientDataHash []byte) error {
+	switch att.format {
+	case AttestationFormatNone:
+		if len(att.attStmt) > 0 {
+			return errors.Wrap(ErrInvalidAttestation, "none attestation with statement")
+		}
+		return nil
+	case AttestationFormatPacked:
+		return att.verifyPacked(ad, pub, clientDataHash)
+	case AttestationFormatFidoU2F:
+		return att.verifyFidoU2F(ad, pub, clientDataHash)
+	}
+	return errors.Wrapf(ErrInvalidAttestation, "unsupported format %s", att.format)
+}
+
+func (att *sAttestationObject) verifyPacked(ad *sAuthenticatorData, pub *SPublicKey, clientDataHash []byte) error {
+	alg, ok := att.attStmt["alg"].(int64)
+	if !ok {
+		return errors.Wrap(ErrInvalidAttestation, "packed: missing alg")
+	}
+	sig, ok := att.attStmt["sig"].([]byte)
+	if !ok {
+		return errors.Wrap(ErrInvalidAttestation, "packed: missing sig")
+	}
+	signed := append(append([]byte{}, ad.raw...), clientDataHash...)
+	certs, err := att.x5c()
+	if err != nil {
+		return err
+	}
+	if len(certs) == 0 {
+		// self attestation
+		if alg != pub.Algorithm {
+			return errors.Wrap(ErrInvalidAttestation, "packed: alg does not match credential key")
+		}
+		return pub.Verify(signed, sig)
+	}
+	return verifySignature(alg, certs[0].PublicKey, signed, sig)
+}
+
+func (att *sAttestationObject) verifyFidoU2F(ad *sAuthenticatorData, pub *SPublicKey, clientDataHash []byte) error {
+	sig, ok := att.attStmt["sig"].([]byte)
+	if !ok {
+		return errors.Wrap(ErrInvalidAttestation, "fido-u2f: missing sig")
+	}
+	certs, err := att.x5c()
+	if err != nil {
+		return err
+	}
+	if len(certs) != 1 {
+		return errors.Wrap(ErrInvalidAttestation, "fido-u2f: x5c must contain exactly one certificate")
+	}
+	key, ok := pub.Key.(*ecdsa.PublicKey)
+	if !ok || key.Curve != elliptic.P256() {
+		return errors.Wrap(ErrInvalidAttestation, "fido-u2f: credential key is not P-256")
+	}
+	var buf bytes.Buffer
+	buf.WriteByte(0)
+	buf.Write(ad.rpIdHash)
+	buf.Write(clientDataHash)
+	buf.Write(ad.credentialId)
+	buf.Write(elliptic.Marshal(key.Curve, key.X, key.Y))
+	return verifySignature(COSEAlgES256, certs[0].PublicKey, buf.Bytes(), sig)
+}
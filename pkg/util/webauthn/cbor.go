@@ -0,0 +1,185 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webauthn
+
+import (
+	"encoding/binary"
+	"math"
+
+	"yunion.io/x/pkg/errors"
+)
+
+const (
+	cborMaxDepth = 16
+)
+
+// decodeCBOR decodes the first CBOR data item of data and returns the
+// remaining bytes.  Only the definite length items produced by
+// authenticators are supported, integers are returned as int64 and maps as
+// map[interface{}]interface{}
+func decodeCBOR(data []byte) (interface{}, []byte, error) {
+	return decodeCBORItem(data, 0)
+}
+
+func cborArgument(data []byte) (byte, uint64, []byte, error) {
+	if len(data) == 0 {
+		return 0, 0, nil, errors.Wrap(ErrInvalidCBOR, "unexpected end of data")
+	}
+	major := data[0] >> 5
+	info := data[0] & 0x1f
+	data = data[1:]
+	switch {
+	case info < 24:
+		return major, uint64(info), data, nil
+	case info <= 27:
+		size := 1 << (info - 24)
+		if len(data) < size {
+			return 0, 0, nil, errors.Wrap(ErrInvalidCBOR, "unexpected end of data")
+		}
+		var val uint64
+		switch size {
+		case 1:
+			val = uint64(data[0])
+		case 2:
+			val = uint64(binary.BigEndian.Uint16(data))
+		case 4:
+			val = uint64(binary.BigEndian.Uint32(data))
+		case 8:
+			val = binary.BigEndian.Uint64(data)
+		}
+		return major, val, data[size:], nil
+	}
+	return 0, 0, nil, errors.Wrapf(ErrInvalidCBOR, "unsupported additional information %d", info)
+}
+
+func decodeCBORItem(data []byte, depth int) (interface{}, []byte, error) {
+	if depth > cborMaxDepth {
+		return nil, nil, errors.Wrap(ErrInvalidCBOR, "nested too deep")
+	}
+	if len(data) > 0 && data[0]>>5 == 7 {
+		return decodeCBORSimple(data)
+	}
+	major, arg, rest, err := cborArgument(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch major {
+	case 0:
+		if arg > math.MaxInt64 {
+			return nil, nil, errors.Wrap(ErrInvalidCBOR, "integer overflow")
+		}
+		return int64(arg), rest, nil
+	case 1:
+		if arg > math.MaxInt64 {
+			return nil, nil, errors.Wrap(ErrInvalidCBOR, "integer overflow")
+		}
+		return -1 - int64(arg), rest, nil
+	case 2, 3:
+		if uint64(len(rest)) < arg {
+			return nil, nil, errors.Wrap(ErrInvalidCBOR, "unexpected end of data")
+		}
+		if major == 2 {
+			return rest[:arg], rest[arg:], nil
+		}
+		return string(rest[:arg]), rest[arg:], nil
+	case 4:
+		if arg > uint64(len(rest)) {
+			return nil, nil, errors.Wrap(ErrInvalidCBOR, "array too long")
+		}
+		arr := make([]interface{}, 0, arg)
+		for i := uint64(0); i < arg; i++ {
+			var item interface{}
+			item, rest, err = decodeCBORItem(rest, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			arr = append(arr, item)
+		}
+		return arr, rest, nil
+	case 5:
+		if arg > uint64(len(rest)) {
+			return nil, nil, errors.Wrap(ErrInvalidCBOR, "map too long")
+		}
+		m := make(map[interface{}]interface{}, arg)
+		for i := uint64(0); i < arg; i++ {
+			var key, val interface{}
+			key, rest, err = decodeCBORItem(rest, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			switch key.(type) {
+			case int64, string:
+			default:
+				return nil, nil, errors.Wrap(ErrInvalidCBOR, "unsupported map key type")
+			}
+			val, rest, err = decodeCBORItem(rest, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			m[key] = val
+		}
+		return m, rest, nil
+	case 6:
+		// the semantic of tags is not needed
+		return decodeCBORItem(rest, depth+1)
+	}
+	return nil, nil, errors.Wrapf(ErrInvalidCBOR, "unsupported major type %d", major)
+}
+
+func decodeCBORSimple(data []byte) (interface{}, []byte, error) {
+	info := data[0] & 0x1f
+	rest := data[1:]
+	switch info {
+	case 20:
+		return false, rest, nil
+	case 21:
+		return true, rest, nil
+	case 22, 23:
+		return nil, rest, nil
+	case 25:
+		if len(rest) < 2 {
+			return nil, nil, errors.Wrap(ErrInvalidCBOR, "unexpected end of data")
+		}
+		return float64(halfToFloat(binary.BigEndian.Uint16(rest))), rest[2:], nil
+	case 26:
+		if len(rest) < 4 {
+			return nil, nil, errors.Wrap(ErrInvalidCBOR, "unexpected end of data")
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(rest))), rest[4:], nil
+	case 27:
+		if len(rest) < 8 {
+			return nil, nil, errors.Wrap(ErrInvalidCBOR, "unexpected end of data")
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(rest)), rest[8:], nil
+	}
+	return nil, nil, errors.Wrapf(ErrInvalidCBOR, "unsupported simple value %d", info)
+}
+
+func halfToFloat(h uint16) float32 {
+	sign := uint32(h>>15) << 31
+	exp := uint32(h>>10) & 0x1f
+	frac := uint32(h) & 0x3ff
+	switch exp {
+	case 0:
+		f := float32(frac) / 1024 / 16384
+		if sign != 0 {
+			return -f
+		}
+		return f
+	case 0x1f:
+		return math.Float32frombits(sign | 0x7f800000 | frac<<13)
+	}
+	return math.Float32frombits(sign | (exp+112)<<23 | frac<<13)
+}
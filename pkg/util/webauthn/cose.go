@@ -0,0 +1,211 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/asn1"
+	"hash"
+	"math/big"
+
+	"yunion.io/x/pkg/errors"
+)
+
+// COSE algorithm identifiers, https://www.iana.org/assignments/cose/cose.xhtml
+const (
+	COSEAlgES256 = -7
+	COSEAlgES384 = -35
+	COSEAlgES512 = -36
+	COSEAlgEdDSA = -8
+	COSEAlgRS256 = -257
+	COSEAlgRS384 = -258
+	COSEAlgRS512 = -259
+	COSEAlgPS256 = -37
+)
+
+const (
+	coseKeyTypeOKP = 1
+	coseKeyTypeEC2 = 2
+	coseKeyTypeRSA = 3
+
+	coseCurveP256    = 1
+	coseCurveP384    = 2
+	coseCurveP521    = 3
+	coseCurveEd25519 = 6
+
+	coseLabelKty = 1
+	coseLabelAlg = 3
+	// crv of EC2 and OKP, n of RSA
+	coseLabelCrvOrN = -1
+	// x of EC2 and OKP, e of RSA
+	coseLabelXOrE = -2
+	coseLabelY    = -3
+)
+
+// SupportedAlgorithms are the algorithms offered in pubKeyCredParams, in
+// the order of preference
+var SupportedAlgorithms = []int64{COSEAlgES256, COSEAlgEdDSA, COSEAlgRS256, COSEAlgES384, COSEAlgES512, COSEAlgPS256}
+
+// SPublicKey is a credential public key decoded from its COSE_Key form
+type SPublicKey struct {
+	Algorithm int64
+	Key       crypto.PublicKey
+}
+
+func cborInt(m map[interface{}]interface{}, key int64) (int64, bool) {
+	v, ok := m[key].(int64)
+	return v, ok
+}
+
+func cborBytes(m map[interface{}]interface{}, key int64) ([]byte, bool) {
+	v, ok := m[key].([]byte)
+	return v, ok
+}
+
+// ParsePublicKey decodes a COSE_Key, the key may be followed by other CBOR
+// items which are returned as rest
+func ParsePublicKey(data []byte) (*SPublicKey, []byte, error) {
+	item, rest, err := decodeCBOR(data)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "decode COSE key")
+	}
+	m, ok := item.(map[interface{}]interface{})
+	if !ok {
+		return nil, nil, errors.Wrap(ErrInvalidPublicKey, "COSE key is not a map")
+	}
+	kty, _ := cborInt(m, coseLabelKty)
+	alg, ok := cborInt(m, coseLabelAlg)
+	if !ok {
+		return nil, nil, errors.Wrap(ErrInvalidPublicKey, "missing alg")
+	}
+	pub := &SPublicKey{Algorithm: alg}
+	switch kty {
+	case coseKeyTypeEC2:
+		crv, _ := cborInt(m, coseLabelCrvOrN)
+		x, okx := cborBytes(m, coseLabelXOrE)
+		y, oky := cborBytes(m, coseLabelY)
+		if !okx || !oky {
+			return nil, nil, errors.Wrap(ErrInvalidPublicKey, "missing EC2 coordinates")
+		}
+		var curve elliptic.Curve
+		switch crv {
+		case coseCurveP256:
+			curve = elliptic.P256()
+		case coseCurveP384:
+			curve = elliptic.P384()
+		case coseCurveP521:
+			curve = elliptic.P521()
+		default:
+			return nil, nil, errors.Wrapf(ErrUnsupportedAlgorithm, "EC2 curve %d", crv)
+		}
+		key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(key.X, key.Y) {
+			return nil, nil, errors.Wrap(ErrInvalidPublicKey, "point is not on curve")
+		}
+		pub.Key = key
+	case coseKeyTypeOKP:
+		crv, _ := cborInt(m, coseLabelCrvOrN)
+		x, ok := cborBytes(m, coseLabelXOrE)
+		if crv != coseCurveEd25519 {
+			return nil, nil, errors.Wrapf(ErrUnsupportedAlgorithm, "OKP curve %d", crv)
+		}
+		if !ok || len(x) != ed25519.PublicKeySize {
+			return nil, nil, errors.Wrap(ErrInvalidPublicKey, "invalid Ed25519 key")
+		}
+		pub.Key = ed25519.PublicKey(x)
+	case coseKeyTypeRSA:
+		n, okn := cborBytes(m, coseLabelCrvOrN)
+		e, oke := cborBytes(m, coseLabelXOrE)
+		if !okn || !oke || len(e) > 4 {
+			return nil, nil, errors.Wrap(ErrInvalidPublicKey, "invalid RSA key")
+		}
+		pub.Key = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	default:
+		return nil, nil, errors.Wrapf(ErrUnsupportedAlgorithm, "key type %d", kty)
+	}
+	return pub, rest, nil
+}
+
+func algHash(alg int64) (crypto.Hash, func() hash.Hash) {
+	switch alg {
+	case COSEAlgES384, COSEAlgRS384:
+		return crypto.SHA384, sha512.New384
+	case COSEAlgES512, COSEAlgRS512:
+		return crypto.SHA512, sha512.New
+	}
+	return crypto.SHA256, sha256.New
+}
+
+type ecdsaSignature struct {
+	R, S *big.Int
+}
+
+// Verify checks sig of data made by the private part of the key
+func (pub *SPublicKey) Verify(data, sig []byte) error {
+	return verifySignature(pub.Algorithm, pub.Key, data, sig)
+}
+
+func verifySignature(alg int64, key crypto.PublicKey, data, sig []byte) error {
+	h, newHash := algHash(alg)
+	switch alg {
+	case COSEAlgES256, COSEAlgES384, COSEAlgES512:
+		k, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return errors.Wrap(ErrInvalidPublicKey, "not an ECDSA key")
+		}
+		esig := ecdsaSignature{}
+		if rest, err := asn1.Unmarshal(sig, &esig); err != nil || len(rest) > 0 {
+			return errors.Wrap(ErrInvalidSignature, "malformed ECDSA signature")
+		}
+		hasher := newHash()
+		hasher.Write(data)
+		if !ecdsa.Verify(k, hasher.Sum(nil), esig.R, esig.S) {
+			return ErrInvalidSignature
+		}
+	case COSEAlgEdDSA:
+		k, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return errors.Wrap(ErrInvalidPublicKey, "not an Ed25519 key")
+		}
+		if !ed25519.Verify(k, data, sig) {
+			return ErrInvalidSignature
+		}
+	case COSEAlgRS256, COSEAlgRS384, COSEAlgRS512, COSEAlgPS256:
+		k, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errors.Wrap(ErrInvalidPublicKey, "not an RSA key")
+		}
+		hasher := newHash()
+		hasher.Write(data)
+		var err error
+		if alg == COSEAlgPS256 {
+			err = rsa.VerifyPSS(k, h, hasher.Sum(nil), sig, nil)
+		} else {
+			err = rsa.VerifyPKCS1v15(k, h, hasher.Sum(nil), sig)
+		}
+		if err != nil {
+			return errors.Wrap(ErrInvalidSignature, err.Error())
+		}
+	default:
+		return errors.Wrapf(ErrUnsupportedAlgorithm, "alg %d", alg)
+	}
+	return nil
+}
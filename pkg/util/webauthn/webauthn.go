@@ -0,0 +1,523 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"time"
+
+	"yunion.io/x/pkg/errors"
+)
+
+const (
+	ErrInvalidCBOR          = errors.Error("invalid CBOR data")
+	ErrInvalidPublicKey     = errors.Error("invalid public key")
+	ErrInvalidSignature     = errors.Error("invalid signature")
+	ErrUnsupportedAlgorithm = errors.Error("unsupported algorithm")
+	ErrInvalidAttestation   = errors.Error("invalid attestation")
+	ErrInvalidClientData    = errors.Error("invalid client data")
+	ErrChallengeMismatch    = errors.Error("challenge mismatch")
+	ErrOriginMismatch       = errors.Error("origin mismatch")
+	ErrRPIDMismatch         = errors.Error("rp id hash mismatch")
+	ErrUserNotPresent       = errors.Error("user not present")
+	ErrUserNotVerified      = errors.Error("user not verified")
+	ErrCredentialMismatch   = errors.Error("credential mismatch")
+	ErrSignCountRegression  = errors.Error("signature counter did not increase, the authenticator may be cloned")
+)
+
+const (
+	ClientDataTypeCreate = "webauthn.create"
+	ClientDataTypeGet    = "webauthn.get"
+
+	UserVerificationRequired    = "required"
+	UserVerificationPreferred   = "preferred"
+	UserVerificationDiscouraged = "discouraged"
+
+	PublicKeyCredentialType = "public-key"
+
+	// challenges are at least 16 bytes as required by the spec
+	challengeLength = 32
+
+	DefaultTimeout = 5 * time.Minute
+)
+
+const (
+	flagUserPresent      = 0x01
+	flagUserVerified     = 0x04
+	flagBackupEligible   = 0x08
+	flagAttestedCredData = 0x40
+	flagExtensionData    = 0x80
+)
+
+// EncodeBase64URL encodes binary fields the way browsers serialize them
+func EncodeBase64URL(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeBase64URL accepts base64url with or without padding, as well as
+// standard base64 sent by some clients
+func DecodeBase64URL(s string) ([]byte, error) {
+	s = strings.TrimRight(s, "=")
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err == nil {
+		return b, nil
+	}
+	return base64.RawStdEncoding.DecodeString(s)
+}
+
+// NewChallenge returns a random challenge encoded in base64url
+func NewChallenge() (string, error) {
+	b := make([]byte, challengeLength)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", errors.Wrap(err, "rand.Read")
+	}
+	return EncodeBase64URL(b), nil
+}
+
+type SRelyingPartyEntity struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type SUserEntity struct {
+	// base64url of the user handle
+	Id          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+type SCredentialParameter struct {
+	Type string `json:"type"`
+	Alg  int64  `json:"alg"`
+}
+
+type SCredentialDescriptor struct {
+	Type       string   `json:"type"`
+	Id         string   `json:"id"`
+	Transports []string `json:"transports,omitempty"`
+}
+
+type SAuthenticatorSelection struct {
+	ResidentKey      string `json:"residentKey,omitempty"`
+	UserVerification string `json:"userVerification,omitempty"`
+}
+
+// SCredentialCreationOptions is the publicKey argument of
+// navigator.credentials.create(), binary fields are base64url encoded
+type SCredentialCreationOptions struct {
+	Challenge              string                  `json:"challenge"`
+	RP                     SRelyingPartyEntity     `json:"rp"`
+	User                   SUserEntity             `json:"user"`
+	PubKeyCredParams       []SCredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                   `json:"timeout"`
+	ExcludeCredentials     []SCredentialDescriptor `json:"excludeCredentials,omitempty"`
+	AuthenticatorSelection SAuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                  `json:"attestation"`
+}
+
+// SCredentialRequestOptions is the publicKey argument of
+// navigator.credentials.get()
+type SCredentialRequestOptions struct {
+	Challenge        string                  `json:"challenge"`
+	RPId             string                  `json:"rpId"`
+	Timeout          int64                   `json:"timeout"`
+	AllowCredentials []SCredentialDescriptor `json:"allowCredentials,omitempty"`
+	UserVerification string                  `json:"userVerification"`
+}
+
+// SAttestationResponse is the PublicKeyCredential returned by
+// navigator.credentials.create(), as serialized by the client
+type SAttestationResponse struct {
+	Id       string `json:"id"`
+	RawId    string `json:"rawId"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string   `json:"clientDataJSON"`
+		AttestationObject string   `json:"attestationObject"`
+		Transports        []string `json:"transports,omitempty"`
+	} `json:"response"`
+}
+
+// SAssertionResponse is the PublicKeyCredential returned by
+// navigator.credentials.get()
+type SAssertionResponse struct {
+	Id       string `json:"id"`
+	RawId    string `json:"rawId"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string `json:"clientDataJSON"`
+		AuthenticatorData string `json:"authenticatorData"`
+		Signature         string `json:"signature"`
+		UserHandle        string `json:"userHandle,omitempty"`
+	} `json:"response"`
+}
+
+// CredentialId returns the base64url credential id of the response
+func (resp *SAssertionResponse) CredentialId() string {
+	if len(resp.RawId) > 0 {
+		return resp.RawId
+	}
+	return resp.Id
+}
+
+// UserHandle returns the user handle which is only present for
+// discoverable credentials
+func (resp *SAssertionResponse) UserHandle() (string, error) {
+	b, err := DecodeBase64URL(resp.Response.UserHandle)
+	if err != nil {
+		return "", errors.Wrap(err, "decode userHandle")
+	}
+	return string(b), nil
+}
+
+// SCredential is a registered credential kept by the relying party
+type SCredential struct {
+	// base64url of the credential id
+	CredentialId string `json:"credential_id"`
+	// base64url of the COSE_Key
+	PublicKey  string   `json:"public_key"`
+	Algorithm  int64    `json:"algorithm"`
+	RPId       string   `json:"rp_id"`
+	AAGUID     string   `json:"aaguid"`
+	SignCount  uint32   `json:"sign_count"`
+	Transports []string `json:"transports"`
+
+	AttestationFormat string `json:"attestation_format"`
+	UserVerified      bool   `json:"user_verified"`
+	BackupEligible    bool   `json:"backup_eligible"`
+}
+
+func (cred SCredential) Descriptor() SCredentialDescriptor {
+	return SCredentialDescriptor{
+		Type:       PublicKeyCredentialType,
+		Id:         cred.CredentialId,
+		Transports: cred.Transports,
+	}
+}
+
+// SCollectedClientData is the decoded clientDataJSON
+type SCollectedClientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+// ParseClientData decodes the base64url clientDataJSON, returned with the
+// hash signed by the authenticator
+func ParseClientData(b64 string) (*SCollectedClientData, []byte, error) {
+	raw, err := DecodeBase64URL(b64)
+	if err != nil {
+		return nil, nil, errors.Wrap(ErrInvalidClientData, err.Error())
+	}
+	cd := SCollectedClientData{}
+	err = json.Unmarshal(raw, &cd)
+	if err != nil {
+		return nil, nil, errors.Wrap(ErrInvalidClientData, err.Error())
+	}
+	hash := sha256.Sum256(raw)
+	return &cd, hash[:], nil
+}
+
+type sAuthenticatorData struct {
+	raw       []byte
+	rpIdHash  []byte
+	flags     byte
+	signCount uint32
+
+	aaguid       []byte
+	credentialId []byte
+	publicKey    []byte
+}
+
+func parseAuthenticatorData(raw []byte) (*sAuthenticatorData, error) {
+	if len(raw) < 37 {
+		return nil, errors.Wrap(ErrInvalidAttestation, "authenticator data too short")
+	}
+	ad := &sAuthenticatorData{
+		raw:       raw,
+		rpIdHash:  raw[:32],
+		flags:     raw[32],
+		signCount: binary.BigEndian.Uint32(raw[33:37]),
+	}
+	if ad.flags&flagAttestedCredData != 0 {
+		rest := raw[37:]
+		if len(rest) < 18 {
+			return nil, errors.Wrap(ErrInvalidAttestation, "attested credential data too short")
+		}
+		ad.aaguid = rest[:16]
+		idLen := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if len(rest) < idLen {
+			return nil, errors.Wrap(ErrInvalidAttestation, "credential id too short")
+		}
+		ad.credentialId = rest[:idLen]
+		rest = rest[idLen:]
+		_, after, err := ParsePublicKey(rest)
+		if err != nil {
+			return nil, errors.Wrap(err, "credential public key")
+		}
+		ad.publicKey = rest[:len(rest)-len(after)]
+		if len(after) > 0 && ad.flags&flagExtensionData == 0 {
+			return nil, errors.Wrap(ErrInvalidAttestation, "trailing authenticator data")
+		}
+	}
+	return ad, nil
+}
+
+// SRelyingParty verifies the ceremonies of one relying party
+type SRelyingParty struct {
+	Id   string
+	Name string
+	// allowed origins, https://<Id> and its subdomains are allowed when
+	// empty
+	Origins []string
+	// required, preferred or discouraged
+	UserVerification string
+	Timeout          time.Duration
+}
+
+func (rp SRelyingParty) timeoutMs() int64 {
+	if rp.Timeout <= 0 {
+		return int64(DefaultTimeout / time.Millisecond)
+	}
+	return int64(rp.Timeout / time.Millisecond)
+}
+
+func (rp SRelyingParty) userVerification() string {
+	if len(rp.UserVerification) == 0 {
+		return UserVerificationPreferred
+	}
+	return rp.UserVerification
+}
+
+// CreationOptions returns the options to register a new credential for
+// user, credentials in exclude are not registered again
+func (rp SRelyingParty) CreationOptions(challenge string, user SUserEntity, exclude []SCredential) SCredentialCreationOptions {
+	opts := SCredentialCreationOptions{
+		Challenge: challenge,
+		RP: SRelyingPartyEntity{
+			Id:   rp.Id,
+			Name: rp.Name,
+		},
+		User:    user,
+		Timeout: rp.timeoutMs(),
+		AuthenticatorSelection: SAuthenticatorSelection{
+			ResidentKey:      "preferred",
+			UserVerification: rp.userVerification(),
+		},
+		Attestation: "none",
+	}
+	for _, alg := range SupportedAlgorithms {
+		opts.PubKeyCredParams = append(opts.PubKeyCredParams, SCredentialParameter{Type: PublicKeyCredentialType, Alg: alg})
+	}
+	for _, cred := range exclude {
+		opts.ExcludeCredentials = append(opts.ExcludeCredentials, cred.Descriptor())
+	}
+	return opts
+}
+
+// RequestOptions returns the options to get an assertion, an empty allow
+// list asks for discoverable credentials as in passwordless login
+func (rp SRelyingParty) RequestOptions(challenge string, allow []SCredential) SCredentialRequestOptions {
+	opts := SCredentialRequestOptions{
+		Challenge:        challenge,
+		RPId:             rp.Id,
+		Timeout:          rp.timeoutMs(),
+		UserVerification: rp.userVerification(),
+	}
+	for _, cred := range allow {
+		opts.AllowCredentials = append(opts.AllowCredentials, cred.Descriptor())
+	}
+	return opts
+}
+
+// checkOrigin follows the rule that the rp id must be the effective domain
+// of the origin or a registrable suffix of it
+func (rp SRelyingParty) checkOrigin(origin string) error {
+	if len(rp.Origins) > 0 {
+		for _, o := range rp.Origins {
+			if strings.TrimRight(o, "/") == origin {
+				return nil
+			}
+		}
+		return errors.Wrapf(ErrOriginMismatch, "origin %s", origin)
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return errors.Wrapf(ErrOriginMismatch, "origin %s", origin)
+	}
+	host := u.Hostname()
+	if u.Scheme != "https" && !(u.Scheme == "http" && host == "localhost") {
+		return errors.Wrapf(ErrOriginMismatch, "insecure origin %s", origin)
+	}
+	if host != rp.Id && !strings.HasSuffix(host, "."+rp.Id) {
+		return errors.Wrapf(ErrOriginMismatch, "origin %s", origin)
+	}
+	return nil
+}
+
+func (rp SRelyingParty) verifyClientData(b64 string, typ string, challenge string) ([]byte, error) {
+	cd, hash, err := ParseClientData(b64)
+	if err != nil {
+		return nil, err
+	}
+	if cd.Type != typ {
+		return nil, errors.Wrapf(ErrInvalidClientData, "type %s", cd.Type)
+	}
+	if len(challenge) == 0 || strings.TrimRight(cd.Challenge, "=") != strings.TrimRight(challenge, "=") {
+		return nil, ErrChallengeMismatch
+	}
+	if err := rp.checkOrigin(cd.Origin); err != nil {
+		return nil, err
+	}
+	return hash, nil
+}
+
+func (rp SRelyingParty) verifyAuthenticatorData(ad *sAuthenticatorData) error {
+	rpIdHash := sha256.Sum256([]byte(rp.Id))
+	if !bytes.Equal(ad.rpIdHash, rpIdHash[:]) {
+		return ErrRPIDMismatch
+	}
+	if ad.flags&flagUserPresent == 0 {
+		return ErrUserNotPresent
+	}
+	if rp.userVerification() == UserVerificationRequired && ad.flags&flagUserVerified == 0 {
+		return ErrUserNotVerified
+	}
+	return nil
+}
+
+// VerifyCreation verifies the registration response to the challenge and
+// returns the new credential
+func (rp SRelyingParty) VerifyCreation(challenge string, resp *SAttestationResponse) (*SCredential, error) {
+	if resp.Type != PublicKeyCredentialType {
+		return nil, errors.Wrapf(ErrInvalidAttestation, "credential type %s", resp.Type)
+	}
+	clientDataHash, err := rp.verifyClientData(resp.Response.ClientDataJSON, ClientDataTypeCreate, challenge)
+	if err != nil {
+		return nil, err
+	}
+	attObj, err := DecodeBase64URL(resp.Response.AttestationObject)
+	if err != nil {
+		return nil, errors.Wrap(ErrInvalidAttestation, "decode attestationObject")
+	}
+	att, err := parseAttestationObject(attObj)
+	if err != nil {
+		return nil, err
+	}
+	ad, err := parseAuthenticatorData(att.authData)
+	if err != nil {
+		return nil, err
+	}
+	if err := rp.verifyAuthenticatorData(ad); err != nil {
+		return nil, err
+	}
+	if ad.flags&flagAttestedCredData == 0 {
+		return nil, errors.Wrap(ErrInvalidAttestation, "missing attested credential data")
+	}
+	pub, _, err := ParsePublicKey(ad.publicKey)
+	if err != nil {
+		return nil, err
+	}
+	supported := false
+	for _, alg := range SupportedAlgorithms {
+		if alg == pub.Algorithm {
+			supported = true
+			break
+		}
+	}
+	if !supported {
+		return nil, errors.Wrapf(ErrUnsupportedAlgorithm, "alg %d", pub.Algorithm)
+	}
+	err = att.verify(ad, pub, clientDataHash)
+	if err != nil {
+		return nil, err
+	}
+	return &SCredential{
+		CredentialId:      EncodeBase64URL(ad.credentialId),
+		PublicKey:         EncodeBase64URL(ad.publicKey),
+		Algorithm:         pub.Algorithm,
+		RPId:              rp.Id,
+		AAGUID:            hex.EncodeToString(ad.aaguid),
+		SignCount:         ad.signCount,
+		Transports:        resp.Response.Transports,
+		AttestationFormat: att.format,
+		UserVerified:      ad.flags&flagUserVerified != 0,
+		BackupEligible:    ad.flags&flagBackupEligible != 0,
+	}, nil
+}
+
+// VerifyAssertion verifies the authentication response to the challenge
+// made with cred, the signature counter of cred is updated on success
+func (rp SRelyingParty) VerifyAssertion(challenge string, resp *SAssertionResponse, cred *SCredential) error {
+	if resp.Type != PublicKeyCredentialType {
+		return errors.Wrapf(ErrInvalidClientData, "credential type %s", resp.Type)
+	}
+	if strings.TrimRight(resp.CredentialId(), "=") != cred.CredentialId {
+		return ErrCredentialMismatch
+	}
+	clientDataHash, err := rp.verifyClientData(resp.Response.ClientDataJSON, ClientDataTypeGet, challenge)
+	if err != nil {
+		return err
+	}
+	raw, err := DecodeBase64URL(resp.Response.AuthenticatorData)
+	if err != nil {
+		return errors.Wrap(ErrInvalidClientData, "decode authenticatorData")
+	}
+	ad, err := parseAuthenticatorData(raw)
+	if err != nil {
+		return err
+	}
+	if err := rp.verifyAuthenticatorData(ad); err != nil {
+		return err
+	}
+	sig, err := DecodeBase64URL(resp.Response.Signature)
+	if err != nil {
+		return errors.Wrap(ErrInvalidSignature, "decode signature")
+	}
+	keyData, err := DecodeBase64URL(cred.PublicKey)
+	if err != nil {
+		return errors.Wrap(ErrInvalidPublicKey, "decode stored public key")
+	}
+	pub, _, err := ParsePublicKey(keyData)
+	if err != nil {
+		return err
+	}
+	signed := append(append([]byte{}, raw...), clientDataHash...)
+	if err := pub.Verify(signed, sig); err != nil {
+		return err
+	}
+	// authenticators without a counter always report 0
+	if ad.signCount != 0 || cred.SignCount != 0 {
+		if ad.signCount <= cred.SignCount {
+			return ErrSignCountRegression
+		}
+	}
+	cred.SignCount = ad.signCount
+	if ad.flags&flagUserVerified != 0 {
+		cred.UserVerified = true
+	}
+	return nil
+}
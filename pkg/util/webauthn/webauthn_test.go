@@ -0,0 +1,402 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webauthn
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"math/big"
+	"sort"
+	"testing"
+
+	"yunion.io/x/pkg/errors"
+)
+
+// cborEncode supports the subset needed to build attestation objects
+func cborEncode(v interface{}) []byte {
+	var buf bytes.Buffer
+	cborWrite(&buf, v)
+	return buf.Bytes()
+}
+
+func cborHead(buf *bytes.Buffer, major byte, n uint64) {
+	switch {
+	case n < 24:
+		buf.WriteByte(major<<5 | byte(n))
+	case n <= 0xff:
+		buf.WriteByte(major<<5 | 24)
+		buf.WriteByte(byte(n))
+	case n <= 0xffff:
+		buf.WriteByte(major<<5 | 25)
+		binary.Write(buf, binary.BigEndian, uint16(n))
+	case n <= 0xffffffff:
+		buf.WriteByte(major<<5 | 26)
+		binary.Write(buf, binary.BigEndian, uint32(n))
+	default:
+		buf.WriteByte(major<<5 | 27)
+		binary.Write(buf, binary.BigEndian, n)
+	}
+}
+
+func cborWrite(buf *bytes.Buffer, v interface{}) {
+	switch x := v.(type) {
+	case int:
+		cborWrite(buf, int64(x))
+	case int64:
+		if x >= 0 {
+			cborHead(buf, 0, uint64(x))
+		} else {
+			cborHead(buf, 1, uint64(-1-x))
+		}
+	case []byte:
+		cborHead(buf, 2, uint64(len(x)))
+		buf.Write(x)
+	case string:
+		cborHead(buf, 3, uint64(len(x)))
+		buf.WriteString(x)
+	case []interface{}:
+		cborHead(buf, 4, uint64(len(x)))
+		for _, item := range x {
+			cborWrite(buf, item)
+		}
+	case map[interface{}]interface{}:
+		keys := make([]interface{}, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			return bytes.Compare(cborEncode(keys[i]), cborEncode(keys[j])) < 0
+		})
+		cborHead(buf, 5, uint64(len(x)))
+		for _, k := range keys {
+			cborWrite(buf, k)
+			cborWrite(buf, x[k])
+		}
+	default:
+		panic("unsupported cbor value")
+	}
+}
+
+// sSoftAuthenticator stands in for a hardware security key
+type sSoftAuthenticator struct {
+	alg    int64
+	signer crypto.Signer
+	credId []byte
+	count  uint32
+	flags  byte
+	// when true the counter is not advanced
+	noCounter bool
+}
+
+func newSoftAuthenticator(t *testing.T, alg int64) *sSoftAuthenticator {
+	a := &sSoftAuthenticator{alg: alg, flags: flagUserPresent | flagUserVerified}
+	switch alg {
+	case COSEAlgES256:
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatalf("generate key: %v", err)
+		}
+		a.signer = key
+	case COSEAlgEdDSA:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatalf("generate key: %v", err)
+		}
+		a.signer = key
+	default:
+		t.Fatalf("unsupported alg %d", alg)
+	}
+	a.credId = make([]byte, 16)
+	rand.Read(a.credId)
+	return a
+}
+
+func (a *sSoftAuthenticator) coseKey() []byte {
+	switch k := a.signer.Public().(type) {
+	case *ecdsa.PublicKey:
+		return cborEncode(map[interface{}]interface{}{
+			1: 2, 3: a.alg, -1: 1,
+			-2: padBytes(k.X, 32),
+			-3: padBytes(k.Y, 32),
+		})
+	case ed25519.PublicKey:
+		return cborEncode(map[interface{}]interface{}{
+			1: 1, 3: a.alg, -1: 6, -2: []byte(k),
+		})
+	}
+	return nil
+}
+
+func padBytes(n *big.Int, size int) []byte {
+	b := n.Bytes()
+	return append(make([]byte, size-len(b)), b...)
+}
+
+func (a *sSoftAuthenticator) sign(data []byte) []byte {
+	var sig []byte
+	var err error
+	if a.alg == COSEAlgEdDSA {
+		sig, err = a.signer.Sign(rand.Reader, data, crypto.Hash(0))
+	} else {
+		digest := sha256.Sum256(data)
+		sig, err = a.signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	}
+	if err != nil {
+		panic(err)
+	}
+	return sig
+}
+
+func (a *sSoftAuthenticator) authData(rpId string, attested bool) []byte {
+	if !a.noCounter {
+		a.count++
+	}
+	var buf bytes.Buffer
+	h := sha256.Sum256([]byte(rpId))
+	buf.Write(h[:])
+	flags := a.flags
+	if attested {
+		flags |= flagAttestedCredData
+	}
+	buf.WriteByte(flags)
+	binary.Write(&buf, binary.BigEndian, a.count)
+	if attested {
+		buf.Write(make([]byte, 16))
+		binary.Write(&buf, binary.BigEndian, uint16(len(a.credId)))
+		buf.Write(a.credId)
+		buf.Write(a.coseKey())
+	}
+	return buf.Bytes()
+}
+
+func clientDataJSON(typ, challenge, origin string) []byte {
+	b, _ := json.Marshal(SCollectedClientData{Type: typ, Challenge: challenge, Origin: origin})
+	return b
+}
+
+func (a *sSoftAuthenticator) create(rpId, origin, challenge, format string) *SAttestationResponse {
+	cdj := clientDataJSON(ClientDataTypeCreate, challenge, origin)
+	authData := a.authData(rpId, true)
+	stmt := map[interface{}]interface{}{}
+	if format == AttestationFormatPacked {
+		cdh := sha256.Sum256(cdj)
+		stmt["alg"] = a.alg
+		stmt["sig"] = a.sign(append(append([]byte{}, authData...), cdh[:]...))
+	}
+	att := cborEncode(map[interface{}]interface{}{
+		"fmt":      format,
+		"authData": authData,
+		"attStmt":  stmt,
+	})
+	resp := &SAttestationResponse{
+		Id:    EncodeBase64URL(a.credId),
+		RawId: EncodeBase64URL(a.credId),
+		Type:  PublicKeyCredentialType,
+	}
+	resp.Response.ClientDataJSON = EncodeBase64URL(cdj)
+	resp.Response.AttestationObject = EncodeBase64URL(att)
+	resp.Response.Transports = []string{"usb"}
+	return resp
+}
+
+func (a *sSoftAuthenticator) get(rpId, origin, challenge string) *SAssertionResponse {
+	cdj := clientDataJSON(ClientDataTypeGet, challenge, origin)
+	authData := a.authData(rpId, false)
+	cdh := sha256.Sum256(cdj)
+	resp := &SAssertionResponse{
+		Id:    EncodeBase64URL(a.credId),
+		RawId: EncodeBase64URL(a.credId),
+		Type:  PublicKeyCredentialType,
+	}
+	resp.Response.ClientDataJSON = EncodeBase64URL(cdj)
+	resp.Response.AuthenticatorData = EncodeBase64URL(authData)
+	resp.Response.Signature = EncodeBase64URL(a.sign(append(append([]byte{}, authData...), cdh[:]...)))
+	resp.Response.UserHandle = EncodeBase64URL([]byte("user-id"))
+	return resp
+}
+
+func TestCeremonies(t *testing.T) {
+	rp := SRelyingParty{
+		Id:               "example.com",
+		Name:             "Example",
+		UserVerification: UserVerificationRequired,
+	}
+	origin := "https://console.example.com"
+	for _, c := range []struct {
+		alg    int64
+		format string
+	}{
+		{COSEAlgES256, AttestationFormatNone},
+		{COSEAlgES256, AttestationFormatPacked},
+		{COSEAlgEdDSA, AttestationFormatNone},
+		{COSEAlgEdDSA, AttestationFormatPacked},
+	} {
+		a := newSoftAuthenticator(t, c.alg)
+		challenge, err := NewChallenge()
+		if err != nil {
+			t.Fatalf("NewChallenge: %v", err)
+		}
+		cred, err := rp.VerifyCreation(challenge, a.create(rp.Id, origin, challenge, c.format))
+		if err != nil {
+			t.Fatalf("alg %d %s: VerifyCreation: %v", c.alg, c.format, err)
+		}
+		if cred.CredentialId != EncodeBase64URL(a.credId) || cred.Algorithm != c.alg || cred.SignCount != 1 {
+			t.Fatalf("alg %d %s: unexpected credential %#v", c.alg, c.format, cred)
+		}
+
+		challenge, _ = NewChallenge()
+		resp := a.get(rp.Id, origin, challenge)
+		if uh, err := resp.UserHandle(); err != nil || uh != "user-id" {
+			t.Errorf("alg %d: UserHandle %q %v", c.alg, uh, err)
+		}
+		err = rp.VerifyAssertion(challenge, resp, cred)
+		if err != nil {
+			t.Fatalf("alg %d: VerifyAssertion: %v", c.alg, err)
+		}
+		if cred.SignCount != 2 {
+			t.Errorf("alg %d: sign count %d, want 2", c.alg, cred.SignCount)
+		}
+		// replaying the same response must fail on the counter
+		if err := rp.VerifyAssertion(challenge, resp, cred); errors.Cause(err) != ErrSignCountRegression {
+			t.Errorf("alg %d: replay got %v", c.alg, err)
+		}
+	}
+}
+
+func TestCeremonyFailures(t *testing.T) {
+	rp := SRelyingParty{Id: "example.com", UserVerification: UserVerificationRequired}
+	origin := "https://example.com"
+	a := newSoftAuthenticator(t, COSEAlgES256)
+	challenge, _ := NewChallenge()
+	cred, err := rp.VerifyCreation(challenge, a.create(rp.Id, origin, challenge, AttestationFormatNone))
+	if err != nil {
+		t.Fatalf("VerifyCreation: %v", err)
+	}
+	other, _ := NewChallenge()
+
+	cases := []struct {
+		name string
+		resp func() *SAssertionResponse
+		want error
+	}{
+		{
+			name: "challenge",
+			resp: func() *SAssertionResponse { return a.get(rp.Id, origin, other) },
+			want: ErrChallengeMismatch,
+		},
+		{
+			name: "origin",
+			resp: func() *SAssertionResponse { return a.get(rp.Id, "https://evil.com", challenge) },
+			want: ErrOriginMismatch,
+		},
+		{
+			name: "insecure origin",
+			resp: func() *SAssertionResponse { return a.get(rp.Id, "http://example.com", challenge) },
+			want: ErrOriginMismatch,
+		},
+		{
+			name: "suffix origin",
+			resp: func() *SAssertionResponse { return a.get(rp.Id, "https://notexample.com", challenge) },
+			want: ErrOriginMismatch,
+		},
+		{
+			name: "rp id",
+			resp: func() *SAssertionResponse { return a.get("evil.com", origin, challenge) },
+			want: ErrRPIDMismatch,
+		},
+		{
+			name: "user verification",
+			resp: func() *SAssertionResponse {
+				a.flags = flagUserPresent
+				defer func() { a.flags = flagUserPresent | flagUserVerified }()
+				return a.get(rp.Id, origin, challenge)
+			},
+			want: ErrUserNotVerified,
+		},
+		{
+			name: "tampered client data",
+			resp: func() *SAssertionResponse {
+				resp := a.get(rp.Id, origin, challenge)
+				resp.Response.ClientDataJSON = EncodeBase64URL(clientDataJSON(ClientDataTypeGet, challenge, "https://a.example.com"))
+				return resp
+			},
+			want: ErrInvalidSignature,
+		},
+		{
+			name: "other credential",
+			resp: func() *SAssertionResponse {
+				return newSoftAuthenticator(t, COSEAlgES256).get(rp.Id, origin, challenge)
+			},
+			want: ErrCredentialMismatch,
+		},
+		{
+			name: "forged signature",
+			resp: func() *SAssertionResponse {
+				b := newSoftAuthenticator(t, COSEAlgES256)
+				b.credId = a.credId
+				b.count = a.count
+				return b.get(rp.Id, origin, challenge)
+			},
+			want: ErrInvalidSignature,
+		},
+	}
+	for _, c := range cases {
+		err := rp.VerifyAssertion(challenge, c.resp(), cred)
+		if errors.Cause(err) != c.want {
+			t.Errorf("%s: got %v, want %v", c.name, err, c.want)
+		}
+	}
+
+	// authenticators without a counter keep reporting 0
+	b := newSoftAuthenticator(t, COSEAlgEdDSA)
+	b.noCounter = true
+	cred, err = rp.VerifyCreation(challenge, b.create(rp.Id, origin, challenge, AttestationFormatNone))
+	if err != nil {
+		t.Fatalf("VerifyCreation without counter: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := rp.VerifyAssertion(challenge, b.get(rp.Id, origin, challenge), cred); err != nil {
+			t.Fatalf("VerifyAssertion without counter: %v", err)
+		}
+	}
+
+	// registration on behalf of another rp
+	if _, err := rp.VerifyCreation(challenge, a.create("evil.com", origin, challenge, AttestationFormatNone)); errors.Cause(err) != ErrRPIDMismatch {
+		t.Errorf("create for other rp: %v", err)
+	}
+}
+
+func TestOrigins(t *testing.T) {
+	rp := SRelyingParty{Id: "localhost"}
+	for _, o := range []string{"http://localhost:8080", "https://localhost"} {
+		if err := rp.checkOrigin(o); err != nil {
+			t.Errorf("%s: %v", o, err)
+		}
+	}
+	rp = SRelyingParty{Id: "example.com", Origins: []string{"https://console.example.com/"}}
+	if err := rp.checkOrigin("https://console.example.com"); err != nil {
+		t.Errorf("explicit origin: %v", err)
+	}
+	if err := rp.checkOrigin("https://other.example.com"); err == nil {
+		t.Errorf("origin outside the allowed list is accepted")
+	}
+}
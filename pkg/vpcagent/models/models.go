@@ -18,7 +18,10 @@ import (
 	"fmt"
 
 	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/util/netutils"
 
+	computeapis "yunion.io/x/onecloud/pkg/apis/compute"
 	compute_models "yunion.io/x/onecloud/pkg/compute/models"
 )
 
@@ -27,8 +30,10 @@ type Vpc struct {
 
 	RouteTable *RouteTable `json:"-"`
 
-	Wire     *Wire    `json:"-"`
-	Networks Networks `json:"-"`
+	Wire                  *Wire                 `json:"-"`
+	Networks              Networks              `json:"-"`
+	NatGateways           NatGateways           `json:"-"`
+	VpcPeeringConnections VpcPeeringConnections `json:"-"`
 }
 
 func (el *Vpc) Copy() *Vpc {
@@ -69,6 +74,7 @@ type Network struct {
 	Guestnetworks Guestnetworks `json:"-"`
 	Groupnetworks Groupnetworks `json:"-"`
 	Elasticips    Elasticips    `json:"-"`
+	Loadbalancers Loadbalancers `json:"-"`
 }
 
 func (el *Network) Copy() *Network {
@@ -270,3 +276,132 @@ func (el *Group) Copy() *Group {
 		SGroup: el.SGroup,
 	}
 }
+
+type Loadbalancer struct {
+	compute_models.SLoadbalancer
+
+	Network                   *Network                  `json:"-"`
+	LoadbalancerListeners     LoadbalancerListeners     `json:"-"`
+	LoadbalancerBackendGroups LoadbalancerBackendGroups `json:"-"`
+}
+
+func (el *Loadbalancer) Copy() *Loadbalancer {
+	return &Loadbalancer{
+		SLoadbalancer: el.SLoadbalancer,
+	}
+}
+
+type LoadbalancerListener struct {
+	compute_models.SLoadbalancerListener
+
+	Loadbalancer             *Loadbalancer             `json:"-"`
+	LoadbalancerBackendGroup *LoadbalancerBackendGroup `json:"-"`
+}
+
+func (el *LoadbalancerListener) Copy() *LoadbalancerListener {
+	return &LoadbalancerListener{
+		SLoadbalancerListener: el.SLoadbalancerListener,
+	}
+}
+
+type LoadbalancerBackendGroup struct {
+	compute_models.SLoadbalancerBackendGroup
+
+	Loadbalancer         *Loadbalancer        `json:"-"`
+	LoadbalancerBackends LoadbalancerBackends `json:"-"`
+}
+
+func (el *LoadbalancerBackendGroup) Copy() *LoadbalancerBackendGroup {
+	return &LoadbalancerBackendGroup{
+		SLoadbalancerBackendGroup: el.SLoadbalancerBackendGroup,
+	}
+}
+
+type LoadbalancerBackend struct {
+	compute_models.SLoadbalancerBackend
+
+	LoadbalancerBackendGroup *LoadbalancerBackendGroup `json:"-"`
+}
+
+func (el *LoadbalancerBackend) Copy() *LoadbalancerBackend {
+	return &LoadbalancerBackend{
+		SLoadbalancerBackend: el.SLoadbalancerBackend,
+	}
+}
+
+type NatGateway struct {
+	compute_models.SNatGateway
+
+	Vpc         *Vpc        `json:"-"`
+	NatSEntries NatSEntries `json:"-"`
+	NatDEntries NatDEntries `json:"-"`
+}
+
+func (el *NatGateway) Copy() *NatGateway {
+	return &NatGateway{
+		SNatGateway: el.SNatGateway,
+	}
+}
+
+type NatSEntry struct {
+	compute_models.SNatSEntry
+
+	NatGateway *NatGateway `json:"-"`
+	Network    *Network    `json:"-"`
+}
+
+func (el *NatSEntry) Copy() *NatSEntry {
+	return &NatSEntry{
+		SNatSEntry: el.SNatSEntry,
+	}
+}
+
+type NatDEntry struct {
+	compute_models.SNatDEntry
+
+	NatGateway *NatGateway `json:"-"`
+}
+
+func (el *NatDEntry) Copy() *NatDEntry {
+	return &NatDEntry{
+		SNatDEntry: el.SNatDEntry,
+	}
+}
+
+type VpcPeeringConnection struct {
+	compute_models.SVpcPeeringConnection
+
+	Vpc     *Vpc `json:"-"`
+	PeerVpc *Vpc `json:"-"`
+}
+
+func (el *VpcPeeringConnection) Copy() *VpcPeeringConnection {
+	return &VpcPeeringConnection{
+		SVpcPeeringConnection: el.SVpcPeeringConnection,
+	}
+}
+
+// LinkAddrs returns addresses of the link on the side of vpcId and on the
+// other side
+func (el *VpcPeeringConnection) LinkAddrs(vpcId string) (netutils.IPV4Addr, netutils.IPV4Addr, error) {
+	prefix, err := netutils.NewIPV4Prefix(el.LinkCidr)
+	if err != nil {
+		return 0, 0, errors.Wrapf(err, "vpc peering %s link cidr %q", el.Id, el.LinkCidr)
+	}
+	var (
+		addr1 = prefix.Address.NetAddr(prefix.MaskLen).StepUp()
+		addr2 = addr1.StepUp()
+	)
+	if vpcId == el.VpcId {
+		return addr1, addr2, nil
+	}
+	return addr2, addr1, nil
+}
+
+// IsLinkUp tells whether routers of both vpcs should be linked
+func (el *VpcPeeringConnection) IsLinkUp() bool {
+	return el.Status == computeapis.VPC_PEERING_CONNECTION_STATUS_ACTIVE &&
+		el.Enabled.IsTrue() &&
+		el.LinkCidr != "" &&
+		el.Vpc != nil && el.PeerVpc != nil
+}
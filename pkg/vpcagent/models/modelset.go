@@ -47,6 +47,17 @@ type (
 	Groupguests   map[string]*Groupguest
 	Groupnetworks map[string]*Groupnetwork
 	Groups        map[string]*Group
+
+	Loadbalancers             map[string]*Loadbalancer
+	LoadbalancerListeners     map[string]*LoadbalancerListener
+	LoadbalancerBackendGroups map[string]*LoadbalancerBackendGroup
+	LoadbalancerBackends      map[string]*LoadbalancerBackend
+
+	NatGateways map[string]*NatGateway
+	NatSEntries map[string]*NatSEntry
+	NatDEntries map[string]*NatDEntry
+
+	VpcPeeringConnections map[string]*VpcPeeringConnection
 )
 
 func (set Vpcs) ModelManager() mcclient_modulebase.IBaseManager {
@@ -145,6 +156,44 @@ func (ms Vpcs) joinNetworks(subEntries Networks) bool {
 	return correct
 }
 
+func (ms Vpcs) joinNatGateways(subEntries NatGateways) bool {
+	for _, m := range ms {
+		m.NatGateways = NatGateways{}
+	}
+	for _, subEntry := range subEntries {
+		vpcId := subEntry.VpcId
+		m, ok := ms[vpcId]
+		if !ok {
+			// nat gateways of managed vpcs
+			continue
+		}
+		subEntry.Vpc = m
+		m.NatGateways[subEntry.Id] = subEntry
+	}
+	return true
+}
+
+func (ms Vpcs) joinVpcPeeringConnections(subEntries VpcPeeringConnections) bool {
+	for _, m := range ms {
+		m.VpcPeeringConnections = VpcPeeringConnections{}
+	}
+	for _, subEntry := range subEntries {
+		vpc, ok := ms[subEntry.VpcId]
+		if !ok {
+			continue
+		}
+		peerVpc, ok := ms[subEntry.PeerVpcId]
+		if !ok {
+			continue
+		}
+		subEntry.Vpc = vpc
+		subEntry.PeerVpc = peerVpc
+		vpc.VpcPeeringConnections[subEntry.Id] = subEntry
+		peerVpc.VpcPeeringConnections[subEntry.Id] = subEntry
+	}
+	return true
+}
+
 func (set Wires) ModelManager() mcclient_modulebase.IBaseManager {
 	return &mcclient_modules.Wires
 }
@@ -396,6 +445,23 @@ func (ms Networks) joinElasticips(subEntries Elasticips) bool {
 	return correct
 }
 
+func (ms Networks) joinLoadbalancers(subEntries Loadbalancers) bool {
+	for _, m := range ms {
+		m.Loadbalancers = Loadbalancers{}
+	}
+	for _, subEntry := range subEntries {
+		netId := subEntry.NetworkId
+		m, ok := ms[netId]
+		if !ok {
+			// the network can be filtered out, e.g. not in a vpc
+			continue
+		}
+		subEntry.Network = m
+		m.Loadbalancers[subEntry.Id] = subEntry
+	}
+	return true
+}
+
 func (set Guestnetworks) ModelManager() mcclient_modulebase.IBaseManager {
 	return &mcclient_modules.Servernetworks
 }
@@ -822,3 +888,279 @@ func (set Groups) joinGroupnetworks(subEntries Groupnetworks, networks Networks)
 	}
 	return true
 }
+
+func (set Loadbalancers) ModelManager() mcclient_modulebase.IBaseManager {
+	return &mcclient_modules.Loadbalancers
+}
+
+func (set Loadbalancers) ModelFilter() []string {
+	return []string{
+		"network_type.equals(" + computeapis.LB_NETWORK_TYPE_VPC + ")",
+	}
+}
+
+func (set Loadbalancers) NewModel() db.IModel {
+	return &Loadbalancer{}
+}
+
+func (set Loadbalancers) AddModel(i db.IModel) {
+	m := i.(*Loadbalancer)
+	set[m.Id] = m
+}
+
+func (set Loadbalancers) Copy() apihelper.IModelSet {
+	setCopy := Loadbalancers{}
+	for id, el := range set {
+		setCopy[id] = el.Copy()
+	}
+	return setCopy
+}
+
+func (ms Loadbalancers) joinLoadbalancerBackendGroups(subEntries LoadbalancerBackendGroups) bool {
+	for _, m := range ms {
+		m.LoadbalancerBackendGroups = LoadbalancerBackendGroups{}
+	}
+	for _, subEntry := range subEntries {
+		lbId := subEntry.LoadbalancerId
+		m, ok := ms[lbId]
+		if !ok {
+			// backend groups of non-vpc loadbalancers
+			continue
+		}
+		subEntry.Loadbalancer = m
+		m.LoadbalancerBackendGroups[subEntry.Id] = subEntry
+	}
+	return true
+}
+
+func (ms Loadbalancers) joinLoadbalancerListeners(subEntries LoadbalancerListeners, backendGroups LoadbalancerBackendGroups) bool {
+	for _, m := range ms {
+		m.LoadbalancerListeners = LoadbalancerListeners{}
+	}
+	correct := true
+	for _, subEntry := range subEntries {
+		lbId := subEntry.LoadbalancerId
+		m, ok := ms[lbId]
+		if !ok {
+			// listeners of non-vpc loadbalancers
+			continue
+		}
+		subEntry.Loadbalancer = m
+		m.LoadbalancerListeners[subEntry.Id] = subEntry
+
+		lbbgId := subEntry.BackendGroupId
+		if lbbgId == "" {
+			lbbgId = m.BackendGroupId
+		}
+		if lbbgId == "" {
+			continue
+		}
+		lbbg, ok := backendGroups[lbbgId]
+		if !ok {
+			log.Warningf("loadbalancer listener %s(%s): backend group %s not found",
+				subEntry.Name, subEntry.Id, lbbgId)
+			correct = false
+			continue
+		}
+		subEntry.LoadbalancerBackendGroup = lbbg
+	}
+	return correct
+}
+
+func (set LoadbalancerListeners) ModelManager() mcclient_modulebase.IBaseManager {
+	return &mcclient_modules.LoadbalancerListeners
+}
+
+func (set LoadbalancerListeners) NewModel() db.IModel {
+	return &LoadbalancerListener{}
+}
+
+func (set LoadbalancerListeners) AddModel(i db.IModel) {
+	m := i.(*LoadbalancerListener)
+	set[m.Id] = m
+}
+
+func (set LoadbalancerListeners) Copy() apihelper.IModelSet {
+	setCopy := LoadbalancerListeners{}
+	for id, el := range set {
+		setCopy[id] = el.Copy()
+	}
+	return setCopy
+}
+
+func (set LoadbalancerBackendGroups) ModelManager() mcclient_modulebase.IBaseManager {
+	return &mcclient_modules.LoadbalancerBackendGroups
+}
+
+func (set LoadbalancerBackendGroups) NewModel() db.IModel {
+	return &LoadbalancerBackendGroup{}
+}
+
+func (set LoadbalancerBackendGroups) AddModel(i db.IModel) {
+	m := i.(*LoadbalancerBackendGroup)
+	set[m.Id] = m
+}
+
+func (set LoadbalancerBackendGroups) Copy() apihelper.IModelSet {
+	setCopy := LoadbalancerBackendGroups{}
+	for id, el := range set {
+		setCopy[id] = el.Copy()
+	}
+	return setCopy
+}
+
+func (ms LoadbalancerBackendGroups) joinLoadbalancerBackends(subEntries LoadbalancerBackends) bool {
+	for _, m := range ms {
+		m.LoadbalancerBackends = LoadbalancerBackends{}
+	}
+	for _, subEntry := range subEntries {
+		lbbgId := subEntry.BackendGroupId
+		m, ok := ms[lbbgId]
+		if !ok {
+			continue
+		}
+		subEntry.LoadbalancerBackendGroup = m
+		m.LoadbalancerBackends[subEntry.Id] = subEntry
+	}
+	return true
+}
+
+func (set LoadbalancerBackends) ModelManager() mcclient_modulebase.IBaseManager {
+	return &mcclient_modules.LoadbalancerBackends
+}
+
+func (set LoadbalancerBackends) NewModel() db.IModel {
+	return &LoadbalancerBackend{}
+}
+
+func (set LoadbalancerBackends) AddModel(i db.IModel) {
+	m := i.(*LoadbalancerBackend)
+	set[m.Id] = m
+}
+
+func (set LoadbalancerBackends) Copy() apihelper.IModelSet {
+	setCopy := LoadbalancerBackends{}
+	for id, el := range set {
+		setCopy[id] = el.Copy()
+	}
+	return setCopy
+}
+
+func (set NatGateways) ModelManager() mcclient_modulebase.IBaseManager {
+	return &mcclient_modules.NatGateways
+}
+
+func (set NatGateways) NewModel() db.IModel {
+	return &NatGateway{}
+}
+
+func (set NatGateways) AddModel(i db.IModel) {
+	m := i.(*NatGateway)
+	set[m.Id] = m
+}
+
+func (set NatGateways) Copy() apihelper.IModelSet {
+	setCopy := NatGateways{}
+	for id, el := range set {
+		setCopy[id] = el.Copy()
+	}
+	return setCopy
+}
+
+func (ms NatGateways) joinNatSEntries(subEntries NatSEntries, networks Networks) bool {
+	for _, m := range ms {
+		m.NatSEntries = NatSEntries{}
+	}
+	for _, subEntry := range subEntries {
+		natId := subEntry.NatgatewayId
+		m, ok := ms[natId]
+		if !ok {
+			continue
+		}
+		subEntry.NatGateway = m
+		if subEntry.NetworkId != "" {
+			subEntry.Network = networks[subEntry.NetworkId]
+		}
+		m.NatSEntries[subEntry.Id] = subEntry
+	}
+	return true
+}
+
+func (ms NatGateways) joinNatDEntries(subEntries NatDEntries) bool {
+	for _, m := range ms {
+		m.NatDEntries = NatDEntries{}
+	}
+	for _, subEntry := range subEntries {
+		natId := subEntry.NatgatewayId
+		m, ok := ms[natId]
+		if !ok {
+			continue
+		}
+		subEntry.NatGateway = m
+		m.NatDEntries[subEntry.Id] = subEntry
+	}
+	return true
+}
+
+func (set NatSEntries) ModelManager() mcclient_modulebase.IBaseManager {
+	return &mcclient_modules.NatSTable
+}
+
+func (set NatSEntries) NewModel() db.IModel {
+	return &NatSEntry{}
+}
+
+func (set NatSEntries) AddModel(i db.IModel) {
+	m := i.(*NatSEntry)
+	set[m.Id] = m
+}
+
+func (set NatSEntries) Copy() apihelper.IModelSet {
+	setCopy := NatSEntries{}
+	for id, el := range set {
+		setCopy[id] = el.Copy()
+	}
+	return setCopy
+}
+
+func (set NatDEntries) ModelManager() mcclient_modulebase.IBaseManager {
+	return &mcclient_modules.NatDTable
+}
+
+func (set NatDEntries) NewModel() db.IModel {
+	return &NatDEntry{}
+}
+
+func (set NatDEntries) AddModel(i db.IModel) {
+	m := i.(*NatDEntry)
+	set[m.Id] = m
+}
+
+func (set NatDEntries) Copy() apihelper.IModelSet {
+	setCopy := NatDEntries{}
+	for id, el := range set {
+		setCopy[id] = el.Copy()
+	}
+	return setCopy
+}
+
+func (set VpcPeeringConnections) ModelManager() mcclient_modulebase.IBaseManager {
+	return &mcclient_modules.VpcPeeringConnections
+}
+
+func (set VpcPeeringConnections) NewModel() db.IModel {
+	return &VpcPeeringConnection{}
+}
+
+func (set VpcPeeringConnections) AddModel(i db.IModel) {
+	m := i.(*VpcPeeringConnection)
+	set[m.Id] = m
+}
+
+func (set VpcPeeringConnections) Copy() apihelper.IModelSet {
+	setCopy := VpcPeeringConnections{}
+	for id, el := range set {
+		setCopy[id] = el.Copy()
+	}
+	return setCopy
+}
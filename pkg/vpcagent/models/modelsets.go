@@ -39,6 +39,17 @@ type ModelSetsMaxUpdatedAt struct {
 
 	Groupguests   time.Time
 	Groupnetworks time.Time
+
+	Loadbalancers             time.Time
+	LoadbalancerListeners     time.Time
+	LoadbalancerBackendGroups time.Time
+	LoadbalancerBackends      time.Time
+
+	NatGateways time.Time
+	NatSEntries time.Time
+	NatDEntries time.Time
+
+	VpcPeeringConnections time.Time
 }
 
 func NewModelSetsMaxUpdatedAt() *ModelSetsMaxUpdatedAt {
@@ -61,6 +72,17 @@ func NewModelSetsMaxUpdatedAt() *ModelSetsMaxUpdatedAt {
 
 		Groupguests:   apihelper.PseudoZeroTime,
 		Groupnetworks: apihelper.PseudoZeroTime,
+
+		Loadbalancers:             apihelper.PseudoZeroTime,
+		LoadbalancerListeners:     apihelper.PseudoZeroTime,
+		LoadbalancerBackendGroups: apihelper.PseudoZeroTime,
+		LoadbalancerBackends:      apihelper.PseudoZeroTime,
+
+		NatGateways: apihelper.PseudoZeroTime,
+		NatSEntries: apihelper.PseudoZeroTime,
+		NatDEntries: apihelper.PseudoZeroTime,
+
+		VpcPeeringConnections: apihelper.PseudoZeroTime,
 	}
 }
 
@@ -84,6 +106,17 @@ type ModelSets struct {
 	Groupguests   Groupguests
 	Groupnetworks Groupnetworks
 	Groups        Groups
+
+	Loadbalancers             Loadbalancers
+	LoadbalancerListeners     LoadbalancerListeners
+	LoadbalancerBackendGroups LoadbalancerBackendGroups
+	LoadbalancerBackends      LoadbalancerBackends
+
+	NatGateways NatGateways
+	NatSEntries NatSEntries
+	NatDEntries NatDEntries
+
+	VpcPeeringConnections VpcPeeringConnections
 }
 
 func NewModelSets() *ModelSets {
@@ -107,6 +140,17 @@ func NewModelSets() *ModelSets {
 		Groupguests:   Groupguests{},
 		Groupnetworks: Groupnetworks{},
 		Groups:        Groups{},
+
+		Loadbalancers:             Loadbalancers{},
+		LoadbalancerListeners:     LoadbalancerListeners{},
+		LoadbalancerBackendGroups: LoadbalancerBackendGroups{},
+		LoadbalancerBackends:      LoadbalancerBackends{},
+
+		NatGateways: NatGateways{},
+		NatSEntries: NatSEntries{},
+		NatDEntries: NatDEntries{},
+
+		VpcPeeringConnections: VpcPeeringConnections{},
 	}
 }
 
@@ -131,6 +175,15 @@ func (mss *ModelSets) ModelSetList() []apihelper.IModelSet {
 
 		mss.Groupguests,
 		mss.Groupnetworks,
+
+		mss.Loadbalancers,
+		mss.LoadbalancerListeners,
+		mss.LoadbalancerBackendGroups,
+		mss.LoadbalancerBackends,
+		mss.NatGateways,
+		mss.NatSEntries,
+		mss.NatDEntries,
+		mss.VpcPeeringConnections,
 	}
 }
 
@@ -158,6 +211,17 @@ func (mss *ModelSets) copy_() *ModelSets {
 
 		Groupguests:   mss.Groupguests.Copy().(Groupguests),
 		Groupnetworks: mss.Groupnetworks.Copy().(Groupnetworks),
+
+		Loadbalancers:             mss.Loadbalancers.Copy().(Loadbalancers),
+		LoadbalancerListeners:     mss.LoadbalancerListeners.Copy().(LoadbalancerListeners),
+		LoadbalancerBackendGroups: mss.LoadbalancerBackendGroups.Copy().(LoadbalancerBackendGroups),
+		LoadbalancerBackends:      mss.LoadbalancerBackends.Copy().(LoadbalancerBackends),
+
+		NatGateways: mss.NatGateways.Copy().(NatGateways),
+		NatSEntries: mss.NatSEntries.Copy().(NatSEntries),
+		NatDEntries: mss.NatDEntries.Copy().(NatDEntries),
+
+		VpcPeeringConnections: mss.VpcPeeringConnections.Copy().(VpcPeeringConnections),
 	}
 	return mssCopy
 }
@@ -213,6 +277,14 @@ func (mss *ModelSets) join() bool {
 	p = append(p, mss.Guestnetworks.joinNetworkAddresses(mss.NetworkAddresses))
 	p = append(p, mss.Groups.joinGroupnetworks(mss.Groupnetworks, mss.Networks))
 	p = append(p, mss.Groupnetworks.joinElasticips(mss.Elasticips))
+	p = append(p, mss.Networks.joinLoadbalancers(mss.Loadbalancers))
+	p = append(p, mss.Loadbalancers.joinLoadbalancerBackendGroups(mss.LoadbalancerBackendGroups))
+	p = append(p, mss.Loadbalancers.joinLoadbalancerListeners(mss.LoadbalancerListeners, mss.LoadbalancerBackendGroups))
+	p = append(p, mss.LoadbalancerBackendGroups.joinLoadbalancerBackends(mss.LoadbalancerBackends))
+	p = append(p, mss.Vpcs.joinNatGateways(mss.NatGateways))
+	p = append(p, mss.NatGateways.joinNatSEntries(mss.NatSEntries, mss.Networks))
+	p = append(p, mss.NatGateways.joinNatDEntries(mss.NatDEntries))
+	p = append(p, mss.Vpcs.joinVpcPeeringConnections(mss.VpcPeeringConnections))
 	for _, b := range p {
 		if !b {
 			return false
@@ -25,6 +25,7 @@ import (
 	"yunion.io/x/ovsdb/schema/ovn_nb"
 	"yunion.io/x/ovsdb/types"
 	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
 
 	apis "yunion.io/x/onecloud/pkg/apis/compute"
 	"yunion.io/x/onecloud/pkg/mcclient/auth"
@@ -37,11 +38,20 @@ import (
 const (
 	externalKeyOcVersion = "oc-version"
 	externalKeyOcRef     = "oc-ref"
+
+	// health check settings of ovn load balancer.  Load_Balancer_Health_Check
+	// rows are not dumped, the settings are kept here for comparison
+	externalKeyOcLbHealthCheck = "oc-lb-health-check"
 )
 
+// iOvnNbCtl runs ovn-nbctl commands.  It's implemented by *ovnutil.OvnNbCtl
+type iOvnNbCtl interface {
+	Must(ctx context.Context, msg string, args []string) *ovnutil.CmdResult
+}
+
 type OVNNorthboundKeeper struct {
 	DB  ovn_nb.OVNNorthbound
-	cli *ovnutil.OvnNbCtl
+	cli iOvnNbCtl
 }
 
 func DumpOVNNorthbound(ctx context.Context, cli *ovnutil.OvnNbCtl) (*OVNNorthboundKeeper, error) {
@@ -56,6 +66,8 @@ func DumpOVNNorthbound(ctx context.Context, cli *ovnutil.OvnNbCtl) (*OVNNorthbou
 		&db.DHCPOptions,
 		&db.QoS,
 		&db.DNS,
+		&db.LoadBalancer,
+		&db.NAT,
 	}
 	args := []string{"--format=json", "list", "<tbl>"}
 	for _, itbl := range itbls {
@@ -939,6 +951,328 @@ func (keeper *OVNNorthboundKeeper) ClaimGroupnetwork(ctx context.Context, groupn
 	return keeper.cli.Must(ctx, "ClaimGroupnetworks", args)
 }
 
+func (keeper *OVNNorthboundKeeper) ClaimLoadbalancer(ctx context.Context, lb *agentmodels.Loadbalancer) error {
+	if lb.Status != apis.LB_STATUS_ENABLED || lb.Address == "" {
+		return nil
+	}
+	var (
+		network = lb.Network
+		vpc     = network.Vpc
+		// backend address => logical switch port, for health check
+		lports = map[string]string{}
+	)
+	for _, network := range vpc.Networks {
+		for _, guestnetwork := range network.Guestnetworks {
+			lports[guestnetwork.IpAddr] = gnpName(guestnetwork.NetworkId, guestnetwork.Ifname)
+		}
+	}
+
+	var (
+		args []string
+		nref int
+	)
+	for _, listener := range lb.LoadbalancerListeners {
+		if listener.Status != apis.LB_STATUS_ENABLED {
+			continue
+		}
+		switch listener.ListenerType {
+		case apis.LB_LISTENER_TYPE_TCP, apis.LB_LISTENER_TYPE_UDP:
+		default:
+			// only l4 listeners can be offloaded to ovn
+			continue
+		}
+		lbbg := listener.LoadbalancerBackendGroup
+		if lbbg == nil {
+			continue
+		}
+		var (
+			backends []string
+			mappings = map[string]string{}
+		)
+		for _, backend := range lbbg.LoadbalancerBackends {
+			if backend.Address == "" || backend.Port <= 0 {
+				continue
+			}
+			backends = append(backends, fmt.Sprintf("%s:%d", backend.Address, backend.Port))
+			if lport, ok := lports[backend.Address]; ok {
+				// probes are sent from the loadbalancer address
+				mappings[backend.Address] = fmt.Sprintf("%s:%s", lport, lb.Address)
+			}
+		}
+		if len(backends) == 0 {
+			continue
+		}
+		sort.Strings(backends)
+
+		var (
+			ref       = fmt.Sprintf("lb%d", nref)
+			ocVersion = fmt.Sprintf("%s.%d", listener.UpdatedAt, listener.UpdateVersion)
+			vip       = fmt.Sprintf("%s:%d", lb.Address, listener.ListenerPort)
+		)
+		ovnLb := &ovn_nb.LoadBalancer{
+			Name:     lbName(listener.Id),
+			Protocol: ptr(listener.ListenerType),
+			Vips: map[string]string{
+				vip: strings.Join(backends, ","),
+			},
+			ExternalIds: map[string]string{
+				externalKeyOcRef: listener.Id,
+			},
+		}
+		// ovn health check only probes the backend port, http
+		// checks are done at the connection level
+		var hcOpts map[string]string
+		if listener.HealthCheck == apis.LB_BOOL_ON && len(mappings) > 0 {
+			hcOpts = map[string]string{
+				"interval":      fmt.Sprintf("%d", listener.HealthCheckInterval),
+				"timeout":       fmt.Sprintf("%d", listener.HealthCheckTimeout),
+				"success_count": fmt.Sprintf("%d", listener.HealthCheckRise),
+				"failure_count": fmt.Sprintf("%d", listener.HealthCheckFall),
+			}
+			ovnLb.ExternalIds[externalKeyOcLbHealthCheck] = fmt.Sprintf("%s/%s/%s/%s",
+				hcOpts["interval"], hcOpts["timeout"], hcOpts["success_count"], hcOpts["failure_count"])
+		}
+
+		allFound, cleanupArgs := cmp(&keeper.DB, ocVersion, ovnLb)
+		if allFound {
+			// logical switches could have been recreated without
+			// reference to the load balancer
+			m := keeper.DB.LoadBalancer.FindOneMatchNonZeros(ovnLb)
+			for _, network := range vpc.Networks {
+				lsName := netLsName(network.Id)
+				if ls := keeper.DB.LogicalSwitch.FindOneMatchNonZeros(&ovn_nb.LogicalSwitch{Name: lsName}); ls != nil {
+					if !utils.IsInStringArray(m.Uuid, ls.LoadBalancer) {
+						args = append(args, "--", "--if-exists", "add", "Logical_Switch", lsName, "load_balancer", m.Uuid)
+					}
+				}
+			}
+			continue
+		}
+		nref++
+		args = append(args, cleanupArgs...)
+		createArgs := ovnCreateArgs(ovnLb, ref)
+		if hcOpts != nil {
+			hcRef := ref + "hc"
+			args = append(args, "--", "--id=@"+hcRef, "create", "Load_Balancer_Health_Check")
+			args = append(args, types.OvsdbCmdArgsString("vip", vip)...)
+			args = append(args, types.OvsdbCmdArgsMapStringString("options", hcOpts)...)
+			createArgs = append(createArgs, "health_check=@"+hcRef)
+			createArgs = append(createArgs, types.OvsdbCmdArgsMapStringString("ip_port_mappings", mappings)...)
+		}
+		args = append(args, createArgs...)
+		args = append(args, "--", "--if-exists", "add", "Logical_Router", vpcLrName(vpc.Id), "load_balancer", "@"+ref)
+		for _, network := range vpc.Networks {
+			args = append(args, "--", "--if-exists", "add", "Logical_Switch", netLsName(network.Id), "load_balancer", "@"+ref)
+		}
+	}
+	if len(args) > 0 {
+		return keeper.cli.Must(ctx, "ClaimLoadbalancer", args)
+	}
+	return nil
+}
+
+// ClaimVpcPeeringConnection links routers of both vpcs with a transit
+// logical switch.  Traffic is routed over the link only by route table
+// entries of next hop type VpcPeering, see resolveRoutes
+func (keeper *OVNNorthboundKeeper) ClaimVpcPeeringConnection(ctx context.Context, peer *agentmodels.VpcPeeringConnection) error {
+	if !peer.IsLinkUp() {
+		return nil
+	}
+	var (
+		ocVersion = fmt.Sprintf("%s.%d", peer.UpdatedAt, peer.UpdateVersion)
+		peerLs    = &ovn_nb.LogicalSwitch{
+			Name: vpcPeerLsName(peer.Id),
+		}
+		irows = []types.IRow{peerLs}
+	)
+	type peerSide struct {
+		vpc *agentmodels.Vpc
+		rpp *ovn_nb.LogicalRouterPort
+		prp *ovn_nb.LogicalSwitchPort
+	}
+	var sides []*peerSide
+	for _, vpc := range []*agentmodels.Vpc{peer.Vpc, peer.PeerVpc} {
+		localAddr, _, err := peer.LinkAddrs(vpc.Id)
+		if err != nil {
+			return err
+		}
+		side := &peerSide{
+			vpc: vpc,
+			rpp: &ovn_nb.LogicalRouterPort{
+				Name:     vpcRppName(peer.Id, vpc.Id),
+				Mac:      mac.HashVpcPeeringRouterPortMac(peer.Id, vpc.Id),
+				Networks: []string{fmt.Sprintf("%s/%d", localAddr, apis.VpcPeeringLinkMask)},
+			},
+			prp: &ovn_nb.LogicalSwitchPort{
+				Name:      vpcPrpName(peer.Id, vpc.Id),
+				Type:      "router",
+				Addresses: []string{"router"},
+				Options: map[string]string{
+					"router-port": vpcRppName(peer.Id, vpc.Id),
+				},
+			},
+		}
+		irows = append(irows, side.rpp, side.prp)
+		sides = append(sides, side)
+	}
+
+	allFound, args := cmp(&keeper.DB, ocVersion, irows...)
+	if allFound {
+		return nil
+	}
+	args = append(args, ovnCreateArgs(peerLs, peerLs.Name)...)
+	for _, side := range sides {
+		args = append(args, ovnCreateArgs(side.rpp, side.rpp.Name)...)
+		args = append(args, ovnCreateArgs(side.prp, side.prp.Name)...)
+		args = append(args, "--", "add", "Logical_Switch", peerLs.Name, "ports", "@"+side.prp.Name)
+		args = append(args, "--", "add", "Logical_Router", vpcLrName(side.vpc.Id), "ports", "@"+side.rpp.Name)
+	}
+	return keeper.cli.Must(ctx, "ClaimVpcPeeringConnection", args)
+}
+
+func natEntryClaimable(status string) bool {
+	switch status {
+	case apis.NAT_STAUTS_AVAILABLE, apis.NAT_STATUS_DEPLOYING:
+		return true
+	default:
+		return false
+	}
+}
+
+// ClaimNatGateway programs snat rules and dnat load balancers of the nat
+// gateway on the vpc external router.  Translated traffic leaves through
+// the eipgw path in the same way as traffic of guests with eip.  It tells
+// whether the nat gateway is programmed, false when it's skipped
+func (keeper *OVNNorthboundKeeper) ClaimNatGateway(ctx context.Context, nat *agentmodels.NatGateway) (bool, error) {
+	if !natEntryClaimable(nat.Status) {
+		return false, nil
+	}
+	var (
+		vpc       = nat.Vpc
+		ocVersion = fmt.Sprintf("%s.%d", nat.UpdatedAt, nat.UpdateVersion)
+		eipgwVip  = apis.VpcEipGatewayIP3().String()
+
+		nats   []*ovn_nb.NAT
+		routes []*ovn_nb.LogicalRouterStaticRoute
+	)
+	if !vpcHasEipgw(vpc) {
+		return false, nil
+	}
+	newRoute := func(ipPrefix, ocRef string) *ovn_nb.LogicalRouterStaticRoute {
+		return &ovn_nb.LogicalRouterStaticRoute{
+			Policy:     ptr("src-ip"),
+			IpPrefix:   ipPrefix,
+			Nexthop:    eipgwVip,
+			OutputPort: ptr(vpcRepName(vpc.Id)),
+			ExternalIds: map[string]string{
+				externalKeyOcRef: ocRef,
+			},
+		}
+	}
+	for _, snat := range nat.NatSEntries {
+		if !natEntryClaimable(snat.Status) || snat.IP == "" {
+			continue
+		}
+		logicalIp := snat.SourceCIDR
+		if logicalIp == "" {
+			if snat.Network == nil {
+				log.Warningf("snat entry %s(%s): network %s not found", snat.Name, snat.Id, snat.NetworkId)
+				continue
+			}
+			prefix, err := snat.Network.GetPrefix()
+			if err != nil {
+				log.Errorf("snat entry %s(%s): network prefix: %v", snat.Name, snat.Id, err)
+				continue
+			}
+			logicalIp = prefix.String()
+		}
+		ocRef := fmt.Sprintf("snat/%s", snat.Id)
+		nats = append(nats, &ovn_nb.NAT{
+			Type:       "snat",
+			ExternalIp: snat.IP,
+			LogicalIp:  logicalIp,
+			ExternalIds: map[string]string{
+				externalKeyOcRef: ocRef,
+			},
+		})
+		routes = append(routes, newRoute(logicalIp, ocRef))
+	}
+	// dnat rules are load balancer vips of the form eip:port => ip:port, so
+	// that only the specified port is exposed and the source address of
+	// the internal ip is left untouched.  Replies are routed back through
+	// the eipgw where they are un-dnated
+	var lbs []*ovn_nb.LoadBalancer
+	dnatRoutes := map[string]bool{}
+	for _, dnat := range nat.NatDEntries {
+		if !natEntryClaimable(dnat.Status) || dnat.ExternalIP == "" || dnat.InternalIP == "" {
+			continue
+		}
+		proto := strings.ToLower(dnat.IpProtocol)
+		switch proto {
+		case "tcp", "udp":
+		default:
+			log.Warningf("dnat entry %s(%s): unsupported protocol %q", dnat.Name, dnat.Id, dnat.IpProtocol)
+			continue
+		}
+		if dnat.ExternalPort <= 0 || dnat.InternalPort <= 0 {
+			log.Warningf("dnat entry %s(%s): invalid port %d => %d", dnat.Name, dnat.Id, dnat.ExternalPort, dnat.InternalPort)
+			continue
+		}
+		ocRef := fmt.Sprintf("dnat/%s", dnat.Id)
+		lbs = append(lbs, &ovn_nb.LoadBalancer{
+			Name:     natDnatLbName(dnat.Id),
+			Protocol: ptr(proto),
+			Vips: map[string]string{
+				fmt.Sprintf("%s:%d", dnat.ExternalIP, dnat.ExternalPort): fmt.Sprintf("%s:%d", dnat.InternalIP, dnat.InternalPort),
+			},
+			ExternalIds: map[string]string{
+				externalKeyOcRef: ocRef,
+			},
+		})
+		if !dnatRoutes[dnat.InternalIP] {
+			dnatRoutes[dnat.InternalIP] = true
+			routes = append(routes, newRoute(dnat.InternalIP+"/32", fmt.Sprintf("dnat/%s/%s", nat.Id, dnat.InternalIP)))
+		}
+	}
+	if len(nats) == 0 && len(lbs) == 0 {
+		return true, nil
+	}
+
+	irows := make([]types.IRow, 0, len(nats)+len(lbs)+len(routes))
+	for _, irow := range nats {
+		irows = append(irows, irow)
+	}
+	for _, irow := range lbs {
+		irows = append(irows, irow)
+	}
+	for _, irow := range routes {
+		irows = append(irows, irow)
+	}
+	allFound, args := cmp(&keeper.DB, ocVersion, irows...)
+	if allFound {
+		return true, nil
+	}
+	for i, irow := range nats {
+		ref := fmt.Sprintf("nat%d", i)
+		args = append(args, ovnCreateArgs(irow, ref)...)
+		args = append(args, "--", "add", "Logical_Router", vpcExtLrName(vpc.Id), "nat", "@"+ref)
+	}
+	for i, irow := range lbs {
+		ref := fmt.Sprintf("natLb%d", i)
+		args = append(args, ovnCreateArgs(irow, ref)...)
+		args = append(args, "--", "add", "Logical_Router", vpcExtLrName(vpc.Id), "load_balancer", "@"+ref)
+	}
+	for i, irow := range routes {
+		ref := fmt.Sprintf("natRoute%d", i)
+		args = append(args, ovnCreateArgs(irow, ref)...)
+		args = append(args, "--", "add", "Logical_Router", vpcExtLrName(vpc.Id), "static_routes", "@"+ref)
+	}
+	if err := keeper.cli.Must(ctx, "ClaimNatGateway", args); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 func (keeper *OVNNorthboundKeeper) Mark(ctx context.Context) {
 	db := &keeper.DB
 	itbls := []types.ITable{
@@ -951,6 +1285,8 @@ func (keeper *OVNNorthboundKeeper) Mark(ctx context.Context) {
 		&db.DHCPOptions,
 		&db.QoS,
 		&db.DNS,
+		&db.LoadBalancer,
+		&db.NAT,
 	}
 	for _, itbl := range itbls {
 		for _, irow := range itbl.Rows() {
@@ -969,6 +1305,7 @@ func (keeper *OVNNorthboundKeeper) Sweep(ctx context.Context) error {
 		&db.LogicalRouter,
 		&db.DHCPOptions,
 		&db.DNS,
+		&db.LoadBalancer,
 	}
 	var irows []types.IRow
 	for _, itbl := range itbls {
@@ -1012,6 +1349,20 @@ func (keeper *OVNNorthboundKeeper) Sweep(ctx context.Context) error {
 			keeper.cli.Must(ctx, "Sweep acls", args)
 		}
 	}
+	{
+		var args []string
+		for _, irow := range db.NAT.Rows() {
+			_, ok := irow.GetExternalId(externalKeyOcVersion)
+			if !ok {
+				for _, lr := range db.LogicalRouter.FindNATReferrer_nat(irow.OvsdbUuid()) {
+					args = append(args, "--", "--if-exists", "remove", "Logical_Router", lr.Name, "nat", irow.OvsdbUuid())
+				}
+			}
+		}
+		if len(args) > 0 {
+			keeper.cli.Must(ctx, "Sweep nat", args)
+		}
+	}
 	{ //  remove unused QoS rows
 		var args []string
 		for _, irow := range db.QoS.Rows() {
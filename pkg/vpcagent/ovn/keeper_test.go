@@ -0,0 +1,493 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovn
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"yunion.io/x/ovsdb/types"
+	"yunion.io/x/pkg/tristate"
+
+	apis "yunion.io/x/onecloud/pkg/apis/compute"
+	agentmodels "yunion.io/x/onecloud/pkg/vpcagent/models"
+	"yunion.io/x/onecloud/pkg/vpcagent/ovnutil"
+)
+
+// fakeOvnNbCtl records ovn-nbctl commands instead of running them
+type fakeOvnNbCtl struct {
+	cmds [][]string
+}
+
+func (cli *fakeOvnNbCtl) Must(ctx context.Context, msg string, args []string) *ovnutil.CmdResult {
+	cli.cmds = append(cli.cmds, args)
+	return nil
+}
+
+type fakeOvnRow struct {
+	table string
+	ref   string
+	cols  []string
+}
+
+func (row *fakeOvnRow) has(col string) bool {
+	for _, c := range row.cols {
+		if c == col {
+			return true
+		}
+	}
+	return false
+}
+
+// subCmds splits args of one ovn-nbctl invocation by "--"
+func subCmds(args []string) [][]string {
+	var (
+		r   [][]string
+		cur []string
+	)
+	for _, arg := range args {
+		if arg == "--" {
+			if len(cur) > 0 {
+				r = append(r, cur)
+			}
+			cur = nil
+			continue
+		}
+		cur = append(cur, arg)
+	}
+	if len(cur) > 0 {
+		r = append(r, cur)
+	}
+	return r
+}
+
+func (cli *fakeOvnNbCtl) creates(table string) []*fakeOvnRow {
+	var rows []*fakeOvnRow
+	for _, args := range cli.cmds {
+		for _, sub := range subCmds(args) {
+			if len(sub) < 3 || !strings.HasPrefix(sub[0], "--id=@") || sub[1] != "create" || sub[2] != table {
+				continue
+			}
+			rows = append(rows, &fakeOvnRow{
+				table: table,
+				ref:   strings.TrimPrefix(sub[0], "--id="),
+				cols:  sub[3:],
+			})
+		}
+	}
+	return rows
+}
+
+// adds returns "<record> <column>" of rows referenced by ref
+func (cli *fakeOvnNbCtl) adds(ref string) []string {
+	var r []string
+	for _, args := range cli.cmds {
+		for _, sub := range subCmds(args) {
+			if len(sub) > 0 && sub[0] == "--if-exists" {
+				sub = sub[1:]
+			}
+			if len(sub) == 5 && sub[0] == "add" && sub[4] == ref {
+				r = append(r, sub[2]+" "+sub[3])
+			}
+		}
+	}
+	return r
+}
+
+func newTestKeeper() (*OVNNorthboundKeeper, *fakeOvnNbCtl) {
+	cli := &fakeOvnNbCtl{}
+	return &OVNNorthboundKeeper{cli: cli}, cli
+}
+
+func strCol(name, val string) string {
+	return name + "=" + types.OvsdbCmdArgString(val)
+}
+
+func vipsCol(vip, backends string) string {
+	return types.OvsdbCmdArgsMapStringString("vips", map[string]string{vip: backends})[0]
+}
+
+func newTestNatGateway() *agentmodels.NatGateway {
+	vpc := &agentmodels.Vpc{}
+	vpc.Id = "vpc0"
+	vpc.ExternalAccessMode = apis.VPC_EXTERNAL_ACCESS_MODE_EIP
+	nat := &agentmodels.NatGateway{
+		Vpc:         vpc,
+		NatSEntries: agentmodels.NatSEntries{},
+		NatDEntries: agentmodels.NatDEntries{},
+	}
+	nat.Id = "nat0"
+	nat.Status = apis.NAT_STAUTS_AVAILABLE
+	return nat
+}
+
+func addTestNatDEntry(nat *agentmodels.NatGateway, id, proto, eip string, eport int, ip string, iport int) {
+	dnat := &agentmodels.NatDEntry{}
+	dnat.Id = id
+	dnat.Status = apis.NAT_STAUTS_AVAILABLE
+	dnat.IpProtocol = proto
+	dnat.ExternalIP = eip
+	dnat.ExternalPort = eport
+	dnat.InternalIP = ip
+	dnat.InternalPort = iport
+	nat.NatDEntries[id] = dnat
+}
+
+func TestClaimNatGatewayDnat(t *testing.T) {
+	ctx := context.Background()
+	nat := newTestNatGateway()
+	addTestNatDEntry(nat, "dnat0", "tcp", "1.2.3.4", 22, "10.0.0.2", 2222)
+	addTestNatDEntry(nat, "dnat1", "UDP", "1.2.3.4", 53, "10.0.0.2", 53)
+	addTestNatDEntry(nat, "dnat2", "tcp", "1.2.3.4", 80, "10.0.0.3", 8080)
+	// unsupported protocol is skipped
+	addTestNatDEntry(nat, "dnat3", "icmp", "1.2.3.4", 1, "10.0.0.4", 1)
+
+	keeper, cli := newTestKeeper()
+	if claimed, err := keeper.ClaimNatGateway(ctx, nat); err != nil || !claimed {
+		t.Fatalf("expect nat gateway claimed, got %v, %v", claimed, err)
+	}
+
+	// ports must never be exposed as a whole by dnat_and_snat
+	for _, row := range cli.creates("NAT") {
+		if row.has(strCol("type", "dnat_and_snat")) {
+			t.Errorf("unexpected dnat_and_snat rule: %v", row.cols)
+		}
+	}
+
+	lbs := cli.creates("Load_Balancer")
+	if len(lbs) != 3 {
+		t.Fatalf("expect 3 load balancers, got %d", len(lbs))
+	}
+	wants := map[string]struct {
+		proto string
+		vips  string
+	}{
+		strCol("name", natDnatLbName("dnat0")): {strCol("protocol", "tcp"), vipsCol("1.2.3.4:22", "10.0.0.2:2222")},
+		strCol("name", natDnatLbName("dnat1")): {strCol("protocol", "udp"), vipsCol("1.2.3.4:53", "10.0.0.2:53")},
+		strCol("name", natDnatLbName("dnat2")): {strCol("protocol", "tcp"), vipsCol("1.2.3.4:80", "10.0.0.3:8080")},
+	}
+	for _, lb := range lbs {
+		found := false
+		for name, want := range wants {
+			if !lb.has(name) {
+				continue
+			}
+			found = true
+			if !lb.has(want.proto) || !lb.has(want.vips) {
+				t.Errorf("%s: want %s %s, got %v", name, want.proto, want.vips, lb.cols)
+			}
+		}
+		if !found {
+			t.Errorf("unexpected load balancer %v", lb.cols)
+		}
+		adds := cli.adds(lb.ref)
+		if len(adds) != 1 || adds[0] != vpcExtLrName("vpc0")+" load_balancer" {
+			t.Errorf("load balancer %s should be attached to external router, got %v", lb.ref, adds)
+		}
+	}
+
+	// replies of each internal ip are routed back through eipgw once
+	routes := cli.creates("Logical_Router_Static_Route")
+	if len(routes) != 2 {
+		t.Fatalf("expect 2 routes, got %d", len(routes))
+	}
+	for _, ip := range []string{"10.0.0.2/32", "10.0.0.3/32"} {
+		found := false
+		for _, route := range routes {
+			if route.has(strCol("ip_prefix", ip)) {
+				found = true
+				if !route.has(strCol("policy", "src-ip")) {
+					t.Errorf("route of %s should be src-ip policy: %v", ip, route.cols)
+				}
+			}
+		}
+		if !found {
+			t.Errorf("missing route of %s", ip)
+		}
+	}
+}
+
+func TestClaimNatGatewayNoEipgw(t *testing.T) {
+	nat := newTestNatGateway()
+	nat.Vpc.ExternalAccessMode = apis.VPC_EXTERNAL_ACCESS_MODE_NONE
+	addTestNatDEntry(nat, "dnat0", "tcp", "1.2.3.4", 22, "10.0.0.2", 22)
+
+	keeper, cli := newTestKeeper()
+	if claimed, _ := keeper.ClaimNatGateway(context.Background(), nat); claimed {
+		t.Errorf("expect nat gateway not claimed without eipgw")
+	}
+	if len(cli.cmds) > 0 {
+		t.Errorf("expect no commands without eipgw, got %v", cli.cmds)
+	}
+}
+
+func TestClaimNatGatewayNotClaimable(t *testing.T) {
+	nat := newTestNatGateway()
+	nat.Status = apis.NAT_STATUS_DELETING
+	addTestNatDEntry(nat, "dnat0", "tcp", "1.2.3.4", 22, "10.0.0.2", 22)
+
+	keeper, cli := newTestKeeper()
+	if claimed, _ := keeper.ClaimNatGateway(context.Background(), nat); claimed {
+		t.Errorf("expect nat gateway of status %s not claimed", nat.Status)
+	}
+	if len(cli.cmds) > 0 {
+		t.Errorf("expect no commands, got %v", cli.cmds)
+	}
+}
+
+func newTestVpcPeeringConnection() *agentmodels.VpcPeeringConnection {
+	vpc := &agentmodels.Vpc{VpcPeeringConnections: agentmodels.VpcPeeringConnections{}}
+	vpc.Id = "vpc0"
+	vpc.CidrBlock = "10.0.0.0/16"
+	peerVpc := &agentmodels.Vpc{VpcPeeringConnections: agentmodels.VpcPeeringConnections{}}
+	peerVpc.Id = "vpc1"
+	peerVpc.CidrBlock = "10.1.0.0/16"
+	peer := &agentmodels.VpcPeeringConnection{
+		Vpc:     vpc,
+		PeerVpc: peerVpc,
+	}
+	peer.Id = "peer0"
+	peer.VpcId = vpc.Id
+	peer.PeerVpcId = peerVpc.Id
+	peer.Status = apis.VPC_PEERING_CONNECTION_STATUS_ACTIVE
+	peer.Enabled = tristate.True
+	peer.LinkCidr = "100.64.0.0/30"
+	vpc.VpcPeeringConnections[peer.Id] = peer
+	peerVpc.VpcPeeringConnections[peer.Id] = peer
+	return peer
+}
+
+func TestClaimVpcPeeringConnection(t *testing.T) {
+	peer := newTestVpcPeeringConnection()
+
+	keeper, cli := newTestKeeper()
+	keeper.ClaimVpcPeeringConnection(context.Background(), peer)
+
+	// routes over the link come from route tables only
+	if routes := cli.creates("Logical_Router_Static_Route"); len(routes) > 0 {
+		t.Errorf("unexpected static routes: %v", routes[0].cols)
+	}
+	if lss := cli.creates("Logical_Switch"); len(lss) != 1 || !lss[0].has(strCol("name", vpcPeerLsName(peer.Id))) {
+		t.Errorf("expect transit switch %s", vpcPeerLsName(peer.Id))
+	}
+	rpps := cli.creates("Logical_Router_Port")
+	if len(rpps) != 2 {
+		t.Fatalf("expect 2 router ports, got %d", len(rpps))
+	}
+	for _, side := range []struct {
+		vpcId string
+		addr  string
+	}{
+		{"vpc0", "100.64.0.1/30"},
+		{"vpc1", "100.64.0.2/30"},
+	} {
+		name := vpcRppName(peer.Id, side.vpcId)
+		adds := cli.adds("@" + name)
+		if len(adds) != 1 || adds[0] != vpcLrName(side.vpcId)+" ports" {
+			t.Errorf("router port %s should be attached to router of %s, got %v", name, side.vpcId, adds)
+		}
+		found := false
+		for _, rpp := range rpps {
+			if rpp.ref == "@"+name {
+				found = true
+				networks := types.OvsdbCmdArgsStringMultiples("networks", []string{side.addr})[0]
+				if !rpp.has(networks) {
+					t.Errorf("router port %s: want networks %s, got %v", name, side.addr, rpp.cols)
+				}
+			}
+		}
+		if !found {
+			t.Errorf("missing router port %s", name)
+		}
+	}
+}
+
+func TestClaimVpcPeeringConnectionLinkDown(t *testing.T) {
+	peer := newTestVpcPeeringConnection()
+	peer.Enabled = tristate.False
+
+	keeper, cli := newTestKeeper()
+	keeper.ClaimVpcPeeringConnection(context.Background(), peer)
+	if len(cli.cmds) > 0 {
+		t.Errorf("expect no commands when link is down, got %v", cli.cmds)
+	}
+}
+
+func newTestLoadbalancer() *agentmodels.Loadbalancer {
+	vpc := &agentmodels.Vpc{Networks: agentmodels.Networks{}}
+	vpc.Id = "vpc0"
+	for _, netId := range []string{"net0", "net1"} {
+		network := &agentmodels.Network{
+			Vpc:           vpc,
+			Guestnetworks: agentmodels.Guestnetworks{},
+		}
+		network.Id = netId
+		vpc.Networks[netId] = network
+	}
+	gn := &agentmodels.Guestnetwork{}
+	gn.NetworkId = "net0"
+	gn.Ifname = "eth0"
+	gn.IpAddr = "10.0.0.2"
+	vpc.Networks["net0"].Guestnetworks["1"] = gn
+
+	lb := &agentmodels.Loadbalancer{
+		Network:                   vpc.Networks["net0"],
+		LoadbalancerListeners:     agentmodels.LoadbalancerListeners{},
+		LoadbalancerBackendGroups: agentmodels.LoadbalancerBackendGroups{},
+	}
+	lb.Id = "lb0"
+	lb.Status = apis.LB_STATUS_ENABLED
+	lb.Address = "10.0.0.100"
+	return lb
+}
+
+// addTestLbListener adds a listener forwarding to backends of "address:port"
+func addTestLbListener(lb *agentmodels.Loadbalancer, id, listenerType string, port int, backends ...string) *agentmodels.LoadbalancerListener {
+	lbbg := &agentmodels.LoadbalancerBackendGroup{
+		Loadbalancer:         lb,
+		LoadbalancerBackends: agentmodels.LoadbalancerBackends{},
+	}
+	lbbg.Id = id + "-bg"
+	for i, backend := range backends {
+		lbb := &agentmodels.LoadbalancerBackend{LoadbalancerBackendGroup: lbbg}
+		lbb.Id = fmt.Sprintf("%s-%d", lbbg.Id, i)
+		parts := strings.Split(backend, ":")
+		lbb.Address = parts[0]
+		lbb.Port, _ = strconv.Atoi(parts[1])
+		lbbg.LoadbalancerBackends[lbb.Id] = lbb
+	}
+	lb.LoadbalancerBackendGroups[lbbg.Id] = lbbg
+
+	listener := &agentmodels.LoadbalancerListener{
+		Loadbalancer:             lb,
+		LoadbalancerBackendGroup: lbbg,
+	}
+	listener.Id = id
+	listener.Status = apis.LB_STATUS_ENABLED
+	listener.ListenerType = listenerType
+	listener.ListenerPort = port
+	listener.BackendGroupId = lbbg.Id
+	lb.LoadbalancerListeners[id] = listener
+	return listener
+}
+
+func TestClaimLoadbalancer(t *testing.T) {
+	lb := newTestLoadbalancer()
+	lis0 := addTestLbListener(lb, "lis0", apis.LB_LISTENER_TYPE_TCP, 80, "10.0.0.3:8080", "10.0.0.2:8080")
+	lis0.HealthCheck = apis.LB_BOOL_ON
+	lis0.HealthCheckInterval = 5
+	lis0.HealthCheckTimeout = 3
+	lis0.HealthCheckRise = 2
+	lis0.HealthCheckFall = 4
+	addTestLbListener(lb, "lis1", apis.LB_LISTENER_TYPE_UDP, 53, "10.0.0.2:53")
+	// l7 listeners are not offloaded
+	addTestLbListener(lb, "lis2", apis.LB_LISTENER_TYPE_HTTP, 443, "10.0.0.2:443")
+	// disabled listeners and listeners without valid backends are skipped
+	lis3 := addTestLbListener(lb, "lis3", apis.LB_LISTENER_TYPE_TCP, 22, "10.0.0.2:22")
+	lis3.Status = apis.LB_STATUS_DISABLED
+	addTestLbListener(lb, "lis4", apis.LB_LISTENER_TYPE_TCP, 8000, "10.0.0.2:0")
+
+	keeper, cli := newTestKeeper()
+	keeper.ClaimLoadbalancer(context.Background(), lb)
+
+	lbs := cli.creates("Load_Balancer")
+	if len(lbs) != 2 {
+		t.Fatalf("expect 2 load balancers, got %d", len(lbs))
+	}
+	wants := map[string]struct {
+		proto string
+		vips  string
+	}{
+		strCol("name", lbName("lis0")): {strCol("protocol", "tcp"), vipsCol("10.0.0.100:80", "10.0.0.2:8080,10.0.0.3:8080")},
+		strCol("name", lbName("lis1")): {strCol("protocol", "udp"), vipsCol("10.0.0.100:53", "10.0.0.2:53")},
+	}
+	wantAdds := []string{
+		vpcLrName("vpc0") + " load_balancer",
+		netLsName("net0") + " load_balancer",
+		netLsName("net1") + " load_balancer",
+	}
+	sort.Strings(wantAdds)
+	var lis0Lb *fakeOvnRow
+	for _, row := range lbs {
+		found := false
+		for name, want := range wants {
+			if !row.has(name) {
+				continue
+			}
+			found = true
+			if !row.has(want.proto) || !row.has(want.vips) {
+				t.Errorf("%s: want %s %s, got %v", name, want.proto, want.vips, row.cols)
+			}
+			if name == strCol("name", lbName("lis0")) {
+				lis0Lb = row
+			}
+		}
+		if !found {
+			t.Errorf("unexpected load balancer %v", row.cols)
+		}
+		// the vip is served on the router and every switch of vpc
+		adds := cli.adds(row.ref)
+		sort.Strings(adds)
+		if strings.Join(adds, ";") != strings.Join(wantAdds, ";") {
+			t.Errorf("load balancer %s: want attached to %v, got %v", row.ref, wantAdds, adds)
+		}
+	}
+	if lis0Lb == nil {
+		t.Fatalf("missing load balancer of lis0")
+	}
+
+	// only backends with known logical switch ports are probed
+	hcs := cli.creates("Load_Balancer_Health_Check")
+	if len(hcs) != 1 {
+		t.Fatalf("expect 1 health check, got %d", len(hcs))
+	}
+	if !hcs[0].has(types.OvsdbCmdArgsString("vip", "10.0.0.100:80")[0]) {
+		t.Errorf("health check: want vip 10.0.0.100:80, got %v", hcs[0].cols)
+	}
+	hcOpts := types.OvsdbCmdArgsMapStringString("options", map[string]string{
+		"interval":      "5",
+		"timeout":       "3",
+		"success_count": "2",
+		"failure_count": "4",
+	})[0]
+	if !hcs[0].has(hcOpts) {
+		t.Errorf("health check: want %s, got %v", hcOpts, hcs[0].cols)
+	}
+	if !lis0Lb.has("health_check=" + hcs[0].ref) {
+		t.Errorf("load balancer of lis0 should refer to health check %s, got %v", hcs[0].ref, lis0Lb.cols)
+	}
+	mappings := types.OvsdbCmdArgsMapStringString("ip_port_mappings", map[string]string{
+		"10.0.0.2": gnpName("net0", "eth0") + ":10.0.0.100",
+	})[0]
+	if !lis0Lb.has(mappings) {
+		t.Errorf("load balancer of lis0: want %s, got %v", mappings, lis0Lb.cols)
+	}
+}
+
+func TestClaimLoadbalancerDisabled(t *testing.T) {
+	lb := newTestLoadbalancer()
+	lb.Status = apis.LB_STATUS_DISABLED
+	addTestLbListener(lb, "lis0", apis.LB_LISTENER_TYPE_TCP, 80, "10.0.0.2:8080")
+
+	keeper, cli := newTestKeeper()
+	keeper.ClaimLoadbalancer(context.Background(), lb)
+	if len(cli.cmds) > 0 {
+		t.Errorf("expect no commands for disabled load balancer, got %v", cli.cmds)
+	}
+}
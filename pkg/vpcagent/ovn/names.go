@@ -81,6 +81,19 @@ func vpcEipLspName(vpcId string, eipgwId string) string {
 	return fmt.Sprintf("vpc-ep/%s/%s", vpcId, eipgwId)
 }
 
+// vpc peering
+func vpcPeerLsName(peerId string) string {
+	return fmt.Sprintf("vpc-p/%s", peerId)
+}
+
+func vpcRppName(peerId string, vpcId string) string {
+	return fmt.Sprintf("vpc-rp/%s/%s", peerId, vpcId)
+}
+
+func vpcPrpName(peerId string, vpcId string) string {
+	return fmt.Sprintf("vpc-pr/%s/%s", peerId, vpcId)
+}
+
 func netLsName(netId string) string {
 	return fmt.Sprintf("subnet/%s", netId)
 }
@@ -107,3 +120,11 @@ func gnpName(netId string, ifname string) string {
 func vipName(netId string, groupId string, ipaddr string) string {
 	return fmt.Sprintf("vip-%s-%s-%s", netId, groupId, ipaddr)
 }
+
+func lbName(listenerId string) string {
+	return fmt.Sprintf("lb/%s", listenerId)
+}
+
+func natDnatLbName(dnatId string) string {
+	return fmt.Sprintf("dnat/%s", dnatId)
+}
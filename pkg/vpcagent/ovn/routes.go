@@ -61,6 +61,19 @@ func resolveRoutes(vpc *agentmodels.Vpc, mss *agentmodels.ModelSets) resolvedRou
 					Guestnetwork: gn,
 				})
 			}
+		case computeapis.NEXT_HOP_TYPE_VPCPEERING:
+			peer, ok := vpc.VpcPeeringConnections[routeModel.NextHopId]
+			if !ok || !peer.IsLinkUp() {
+				break
+			}
+			_, remoteAddr, err := peer.LinkAddrs(vpc.Id)
+			if err != nil {
+				break
+			}
+			r = append(r, resolvedRoute{
+				Cidr:    routeModel.Cidr,
+				NextHop: remoteAddr.String(),
+			})
 		default:
 			return nil
 		}
@@ -0,0 +1,60 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovn
+
+import (
+	"testing"
+
+	"yunion.io/x/pkg/tristate"
+
+	apis "yunion.io/x/onecloud/pkg/apis/compute"
+	agentmodels "yunion.io/x/onecloud/pkg/vpcagent/models"
+)
+
+func TestResolveRoutesVpcPeering(t *testing.T) {
+	peer := newTestVpcPeeringConnection()
+	for _, c := range []struct {
+		vpc     *agentmodels.Vpc
+		cidr    string
+		nextHop string
+	}{
+		{peer.Vpc, "10.1.0.0/16", "100.64.0.2"},
+		{peer.PeerVpc, "10.0.0.0/16", "100.64.0.1"},
+	} {
+		rt := &agentmodels.RouteTable{}
+		rt.Routes = &apis.SRoutes{
+			{
+				Cidr:        c.cidr,
+				NextHopType: apis.NEXT_HOP_TYPE_VPCPEERING,
+				NextHopId:   peer.Id,
+			},
+		}
+		c.vpc.RouteTable = rt
+
+		routes := resolveRoutes(c.vpc, nil)
+		if len(routes) != 1 {
+			t.Fatalf("vpc %s: expect 1 route, got %d", c.vpc.Id, len(routes))
+		}
+		if routes[0].Cidr != c.cidr || routes[0].NextHop != c.nextHop {
+			t.Errorf("vpc %s: want %s via %s, got %s via %s", c.vpc.Id, c.cidr, c.nextHop, routes[0].Cidr, routes[0].NextHop)
+		}
+	}
+
+	// no route over the link when it is down
+	peer.Enabled = tristate.False
+	if routes := resolveRoutes(peer.Vpc, nil); len(routes) > 0 {
+		t.Errorf("expect no routes when link is down, got %v", routes)
+	}
+}
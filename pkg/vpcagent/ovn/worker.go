@@ -21,12 +21,14 @@ import (
 	"sync"
 	"time"
 
+	"yunion.io/x/jsonutils"
 	"yunion.io/x/log"
 	"yunion.io/x/pkg/errors"
 
 	"yunion.io/x/onecloud/pkg/apihelper"
 	apis "yunion.io/x/onecloud/pkg/apis/compute"
 	"yunion.io/x/onecloud/pkg/mcclient/auth"
+	"yunion.io/x/onecloud/pkg/mcclient/modulebase"
 	mcclient_modules "yunion.io/x/onecloud/pkg/mcclient/modules/compute"
 	agentmodels "yunion.io/x/onecloud/pkg/vpcagent/models"
 	"yunion.io/x/onecloud/pkg/vpcagent/options"
@@ -170,9 +172,32 @@ func (w *Worker) run(ctx context.Context, mss *agentmodels.ModelSets) (err error
 				ovndb.ClaimGroupnetwork(ctx, groupnetwork)
 			}
 		}
+		// after networks claimed, as load balancers are attached to
+		// all subnets of the vpc
+		for _, network := range vpc.Networks {
+			for _, lb := range network.Loadbalancers {
+				ovndb.ClaimLoadbalancer(ctx, lb)
+			}
+		}
+		if vpcHasEipgw(vpc) {
+			for _, nat := range vpc.NatGateways {
+				claimed, err := ovndb.ClaimNatGateway(ctx, nat)
+				if err != nil {
+					log.Errorf("claim nat gateway %s(%s): %v", nat.Name, nat.Id, err)
+					continue
+				}
+				if claimed {
+					w.reportNatGatewayStatus(ctx, nat)
+				}
+			}
+		}
 		routes := resolveRoutes(vpc, mss)
 		ovndb.ClaimRoutes(ctx, vpc, routes)
 	}
+	// after vpcs claimed, as both routers must be present
+	for _, peer := range mss.VpcPeeringConnections {
+		ovndb.ClaimVpcPeeringConnection(ctx, peer)
+	}
 	for _, vpc := range mss.Vpcs {
 		if vpc.Id == apis.DEFAULT_VPC_ID {
 			continue
@@ -183,3 +208,40 @@ func (w *Worker) run(ctx context.Context, mss *agentmodels.ModelSets) (err error
 	ovndb.Sweep(ctx)
 	return nil
 }
+
+// reportNatGatewayStatus marks the nat gateway and its entries available
+// after their ovn nat rules are in place
+func (w *Worker) reportNatGatewayStatus(ctx context.Context, nat *agentmodels.NatGateway) {
+	var (
+		apiVersion = "v2"
+		s          = auth.GetAdminSession(ctx, w.opts.Region, apiVersion)
+		params     = jsonutils.NewDict()
+	)
+	params.Set("status", jsonutils.NewString(apis.NAT_STAUTS_AVAILABLE))
+	params.Set("reason", jsonutils.NewString("ovn nat rules programmed"))
+
+	report := func(man modulebase.ResourceManager, id, name, status string) bool {
+		if status != apis.NAT_STATUS_DEPLOYING {
+			return true
+		}
+		if _, err := man.PerformAction(s, id, "status", params); err != nil {
+			log.Errorf("%s %s(%s) report status: %v", man.GetKeyword(), name, id, err)
+			return false
+		}
+		return true
+	}
+	if !report(mcclient_modules.NatGateways, nat.Id, nat.Name, nat.Status) {
+		return
+	}
+	nat.Status = apis.NAT_STAUTS_AVAILABLE // update local copy in place
+	for _, snat := range nat.NatSEntries {
+		if report(mcclient_modules.NatSTable, snat.Id, snat.Name, snat.Status) {
+			snat.Status = apis.NAT_STAUTS_AVAILABLE
+		}
+	}
+	for _, dnat := range nat.NatDEntries {
+		if report(mcclient_modules.NatDTable, dnat.Id, dnat.Name, dnat.Status) {
+			dnat.Status = apis.NAT_STAUTS_AVAILABLE
+		}
+	}
+}
@@ -51,6 +51,9 @@ func InitHandlers(app *appsrv.Application) {
 	app.AddHandler("POST", ApiPathPrefix+"baremetal/<id>", auth.Authenticate(handleBaremetalShell))
 	app.AddHandler("POST", ApiPathPrefix+"ssh/<ip>", auth.Authenticate(handleSshShell))
 	app.AddHandler("POST", ApiPathPrefix+"server/<id>", auth.Authenticate(handleServerRemoteConsole))
+	app.AddHandler("GET", ApiPathPrefix+"recordings", auth.Authenticate(handleListRecordings))
+	app.AddHandler("GET", ApiPathPrefix+"recordings/<id>", auth.Authenticate(handleGetRecording))
+	app.AddHandler("GET", ApiPathPrefix+"recordings/<id>/playback", auth.Authenticate(handlePlaybackRecording))
 }
 
 func fetchK8sEnv(ctx context.Context, w http.ResponseWriter, r *http.Request) (*command.K8sEnv, error) {
@@ -145,7 +148,8 @@ func handleK8sCommand(
 	}
 
 	cmd := cmdFactory(env)
-	handleCommandSession(ctx, cmd, w)
+	userCred := auth.FetchUserCredential(ctx, policy.FilterPolicyCredential)
+	handleCommandSession(ctx, cmd, w, newRecordingRequest(userCred, env.Pod, nil, env.Data))
 }
 
 func handleK8sShell(ctx context.Context, w http.ResponseWriter, r *http.Request) {
@@ -163,12 +167,13 @@ func handleSshShell(ctx context.Context, w http.ResponseWriter, r *http.Request)
 		httperrors.GeneralServerError(ctx, w, err)
 		return
 	}
-	cmd, err := command.NewSSHtoolSolCommand(ctx, userCred, env.Params["<ip>"], env.Body)
+	ip := env.Params["<ip>"]
+	cmd, err := command.NewSSHtoolSolCommand(ctx, userCred, ip, env.Body)
 	if err != nil {
 		httperrors.GeneralServerError(ctx, w, err)
 		return
 	}
-	handleCommandSession(ctx, cmd, w)
+	handleCommandSession(ctx, cmd, w, newRecordingRequest(userCred, ip, env.Query, env.Body))
 }
 
 func handleBaremetalShell(ctx context.Context, w http.ResponseWriter, r *http.Request) {
@@ -194,7 +199,7 @@ func handleBaremetalShell(ctx context.Context, w http.ResponseWriter, r *http.Re
 		httperrors.GeneralServerError(ctx, w, err)
 		return
 	}
-	handleCommandSession(ctx, cmd, w)
+	handleCommandSession(ctx, cmd, w, newRecordingRequest(env.ClientSessin.GetToken(), hostId, env.Query, env.Body))
 }
 
 func handleServerRemoteConsole(ctx context.Context, w http.ResponseWriter, r *http.Request) {
@@ -213,7 +218,8 @@ func handleServerRemoteConsole(ctx context.Context, w http.ResponseWriter, r *ht
 	case session.ALIYUN, session.QCLOUD, session.OPENSTACK, session.VMRC, session.ZSTACK, session.CTYUN, session.HUAWEI, session.APSARA, session.JDCLOUD, session.CLOUDPODS:
 		responsePublicCloudConsole(ctx, info, w)
 	case session.VNC, session.SPICE, session.WMKS:
-		handleDataSession(ctx, info, w, url.Values{"password": {info.GetPassword()}}, true)
+		// graphic consoles are not terminals, they can't be recorded as asciicast
+		handleDataSession(ctx, info, w, url.Values{"password": {info.GetPassword()}}, true, nil)
 	default:
 		httperrors.NotAcceptableError(ctx, w, "Unspported remote console protocol: %s", info.Protocol)
 	}
@@ -231,12 +237,17 @@ func responsePublicCloudConsole(ctx context.Context, info *session.RemoteConsole
 	sendJSON(w, resp.JSON(resp))
 }
 
-func handleDataSession(ctx context.Context, sData session.ISessionData, w http.ResponseWriter, connParams url.Values, b64Encode bool) {
+func handleDataSession(ctx context.Context, sData session.ISessionData, w http.ResponseWriter, connParams url.Values, b64Encode bool, rec *recordingRequest) {
 	s, err := session.Manager.Save(sData)
 	if err != nil {
 		httperrors.GeneralServerError(ctx, w, err)
 		return
 	}
+	if rec != nil {
+		if record, forced := session.RecordingPolicy(rec.userCred, rec.requested); record {
+			s.EnableRecording(rec.userCred, rec.target, forced)
+		}
+	}
 	params, err := s.GetConnectParams(connParams)
 	if err != nil {
 		httperrors.GeneralServerError(ctx, w, err)
@@ -252,8 +263,34 @@ func handleDataSession(ctx context.Context, sData session.ISessionData, w http.R
 	sendJSON(w, resp.JSON(resp))
 }
 
-func handleCommandSession(ctx context.Context, cmd command.ICommand, w http.ResponseWriter) {
-	handleDataSession(ctx, session.WrapCommandSession(cmd), w, nil, false)
+func handleCommandSession(ctx context.Context, cmd command.ICommand, w http.ResponseWriter, rec *recordingRequest) {
+	handleDataSession(ctx, session.WrapCommandSession(cmd), w, nil, false, rec)
+}
+
+type recordingRequest struct {
+	userCred  mcclient.TokenCredential
+	target    string
+	requested bool
+}
+
+func newRecordingRequest(userCred mcclient.TokenCredential, target string, query, body jsonutils.JSONObject) *recordingRequest {
+	requested := false
+	for _, obj := range []jsonutils.JSONObject{query, body} {
+		if obj == nil {
+			continue
+		}
+		if jsonutils.QueryBoolean(obj, "record", false) {
+			requested = true
+		}
+		if sub, _ := obj.Get("webconsole"); sub != nil && jsonutils.QueryBoolean(sub, "record", false) {
+			requested = true
+		}
+	}
+	return &recordingRequest{
+		userCred:  userCred,
+		target:    target,
+		requested: requested,
+	}
 }
 
 func sendJSON(w http.ResponseWriter, body jsonutils.JSONObject) {
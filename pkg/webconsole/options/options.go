@@ -32,6 +32,17 @@ type WebConsoleOptions struct {
 	EnableAutoLogin   bool   `help:"allow webconsole to log in directly with the cloudroot public key" default:"false"`
 	ApsaraConsoleAddr string `help:"Apsara console addr" default:"https://xxxx.com.cn/module/ecs/vnc/index.html"`
 	AliyunVncVersion  string `help:"Aliyun vnc version" default:"0.0.8"`
+
+	EnableSessionRecording bool   `help:"allow terminal sessions to be recorded in asciicast v2 format" default:"false"`
+	RecordingWithInput     bool   `help:"record user input as well as terminal output" default:"false"`
+	RecordingDir           string `help:"directory to keep recordings and their metadata" default:"/opt/cloud/workspace/webconsole/recordings"`
+	RecordingStorage       string `help:"where finished recordings are stored" default:"local" choices:"local|s3"`
+
+	RecordingS3Endpoint  string `help:"s3 endpoint used to store recordings"`
+	RecordingS3AccessKey string `help:"s3 access key used to store recordings"`
+	RecordingS3SecretKey string `help:"s3 secret key used to store recordings"`
+	RecordingS3Bucket    string `help:"s3 bucket used to store recordings" default:"webconsole-recordings"`
+	RecordingS3UseSSL    bool   `help:"use ssl to connect recording s3 endpoint" default:"false"`
 }
 
 func OnOptionsChange(oldO, newO interface{}) bool {
@@ -0,0 +1,162 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webconsole
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"yunion.io/x/pkg/errors"
+
+	webconsole_api "yunion.io/x/onecloud/pkg/apis/webconsole"
+	"yunion.io/x/onecloud/pkg/appsrv"
+	"yunion.io/x/onecloud/pkg/cloudcommon/policy"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/mcclient/auth"
+	"yunion.io/x/onecloud/pkg/util/rbacutils"
+	"yunion.io/x/onecloud/pkg/webconsole/session"
+)
+
+const (
+	RECORDING_RESOURCE = webconsole_api.RECORDING_RESOURCE
+
+	defaultRecordingListLimit = 20
+)
+
+func fetchRecordingScope(ctx context.Context, action string) (mcclient.TokenCredential, rbacutils.TRbacScope, error) {
+	userCred := auth.FetchUserCredential(ctx, policy.FilterPolicyCredential)
+	if userCred == nil {
+		return nil, rbacutils.ScopeNone, httperrors.NewUnauthorizedError("No token founded")
+	}
+	scope, _ := policy.PolicyManager.AllowScope(userCred, webconsole_api.SERVICE_TYPE, RECORDING_RESOURCE, action)
+	if scope == rbacutils.ScopeNone {
+		return nil, rbacutils.ScopeNone, httperrors.NewForbiddenError("%s not allow to %s %s", userCred.GetUserName(), action, RECORDING_RESOURCE)
+	}
+	return userCred, scope, nil
+}
+
+func fetchRecording(ctx context.Context, w http.ResponseWriter, r *http.Request) (*webconsole_api.SessionRecordingDetails, error) {
+	params, _, _ := appsrv.FetchEnv(ctx, w, r)
+	userCred, scope, err := fetchRecordingScope(ctx, policy.PolicyActionGet)
+	if err != nil {
+		return nil, err
+	}
+	rec, err := session.GetRecording(params["<id>"])
+	if err != nil {
+		if errors.Cause(err) == errors.ErrNotFound {
+			return nil, httperrors.NewResourceNotFoundError2(session.RECORDING_KEYWORD, params["<id>"])
+		}
+		return nil, err
+	}
+	if !session.IsRecordingVisible(rec, userCred, scope) {
+		return nil, httperrors.NewResourceNotFoundError2(session.RECORDING_KEYWORD, params["<id>"])
+	}
+	return rec, nil
+}
+
+func handleListRecordings(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	_, query, _ := appsrv.FetchEnv(ctx, w, r)
+	userCred, scope, err := fetchRecordingScope(ctx, policy.PolicyActionList)
+	if err != nil {
+		httperrors.GeneralServerError(ctx, w, err)
+		return
+	}
+	input := webconsole_api.SessionRecordingListInput{}
+	if query != nil {
+		if err := query.Unmarshal(&input); err != nil {
+			httperrors.InputParameterError(ctx, w, "unmarshal input: %v", err)
+			return
+		}
+	}
+	if input.Limit <= 0 {
+		input.Limit = defaultRecordingListLimit
+	}
+	if input.Offset < 0 {
+		input.Offset = 0
+	}
+	recordings, err := session.ListRecordings(func(rec *webconsole_api.SessionRecordingDetails) bool {
+		if !session.IsRecordingVisible(rec, userCred, scope) {
+			return false
+		}
+		if len(input.Project) > 0 && input.Project != rec.ProjectId && input.Project != rec.Project {
+			return false
+		}
+		if len(input.User) > 0 && input.User != rec.UserId && input.User != rec.User {
+			return false
+		}
+		if len(input.SessionId) > 0 && input.SessionId != rec.SessionId {
+			return false
+		}
+		if len(input.Protocol) > 0 && input.Protocol != rec.Protocol {
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		httperrors.GeneralServerError(ctx, w, err)
+		return
+	}
+	output := webconsole_api.SessionRecordingListOutput{
+		Recordings: []webconsole_api.SessionRecordingDetails{},
+		Total:      len(recordings),
+		Limit:      input.Limit,
+		Offset:     input.Offset,
+	}
+	if input.Offset < len(recordings) {
+		end := input.Offset + input.Limit
+		if end > len(recordings) {
+			end = len(recordings)
+		}
+		output.Recordings = recordings[input.Offset:end]
+	}
+	sendJSON(w, output.JSON(output))
+}
+
+func handleGetRecording(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	rec, err := fetchRecording(ctx, w, r)
+	if err != nil {
+		httperrors.GeneralServerError(ctx, w, err)
+		return
+	}
+	sendJSON(w, rec.JSON(rec))
+}
+
+// handlePlaybackRecording streams the asciicast file, which can be played
+// by asciinema-player in the browser or by `asciinema play` in the terminal.
+func handlePlaybackRecording(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	rec, err := fetchRecording(ctx, w, r)
+	if err != nil {
+		httperrors.GeneralServerError(ctx, w, err)
+		return
+	}
+	if rec.Status == webconsole_api.RECORDING_STATUS_RECORDING || rec.Status == webconsole_api.RECORDING_STATUS_FAILED {
+		httperrors.GeneralServerError(ctx, w, httperrors.NewInvalidStatusError("recording %s is %s", rec.Id, rec.Status))
+		return
+	}
+	size, reader, err := session.OpenRecording(ctx, rec)
+	if err != nil {
+		httperrors.GeneralServerError(ctx, w, err)
+		return
+	}
+	defer reader.Close()
+	w.Header().Set("Content-Type", "application/x-asciicast")
+	w.Header().Set("Content-Length", fmt.Sprintf("%d", size))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", rec.Id+".cast"))
+	w.WriteHeader(http.StatusOK)
+	io.Copy(w, reader)
+}
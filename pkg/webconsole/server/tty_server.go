@@ -90,6 +90,7 @@ func initSocketHandler(so socketio.Socket, p *session.Pty) {
 				info := p.Session.ShowInfo()
 				if len(info) > 0 {
 					so.Emit(OUTPUT_EVENT, info)
+					p.RecordOutput([]byte(info))
 				}
 			}
 		}
@@ -102,6 +103,7 @@ func initSocketHandler(so socketio.Socket, p *session.Pty) {
 				p.Session.Scan(d, func(msg string) {
 					if len(msg) > 0 {
 						so.Emit(OUTPUT_EVENT, msg)
+						p.RecordOutput([]byte(msg))
 					}
 				})
 			}
@@ -119,6 +121,7 @@ func initSocketHandler(so socketio.Socket, p *session.Pty) {
 				}
 			}
 		} else {
+			p.RecordInput([]byte(data))
 			p.Pty.Write([]byte(data))
 		}
 	})
@@ -35,6 +35,7 @@ import (
 	"yunion.io/x/onecloud/pkg/webconsole"
 	o "yunion.io/x/onecloud/pkg/webconsole/options"
 	"yunion.io/x/onecloud/pkg/webconsole/server"
+	"yunion.io/x/onecloud/pkg/webconsole/session"
 )
 
 func ensureBinExists(binPath string) {
@@ -67,6 +68,10 @@ func StartService() {
 
 	common_options.StartOptionManager(opts, opts.ConfigSyncPeriodSeconds, api.SERVICE_TYPE, api.SERVICE_VERSION, o.OnOptionsChange)
 
+	if err := session.InitRecording(); err != nil {
+		log.Fatalf("init session recording: %v", err)
+	}
+
 	registerSigTraps()
 	start()
 }
@@ -131,6 +136,7 @@ func addMiscHandlers(root *mux.Router) {
 	root.HandleFunc("/stats", adapterF(appsrv.StatisticHandler))
 	root.HandleFunc("/ping", adapterF(appsrv.PingHandler))
 	root.HandleFunc("/worker_stats", adapterF(appsrv.WorkerStatsHandler))
+	root.HandleFunc("/metrics", adapterF(appsrv.MetricsHandler))
 
 	// pprof handler
 	root.PathPrefix("/debug/pprof/").Handler(http.DefaultServeMux)
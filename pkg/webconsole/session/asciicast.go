@@ -0,0 +1,195 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"yunion.io/x/pkg/errors"
+)
+
+const (
+	ASCIICAST_VERSION = 2
+
+	ASCIICAST_EVENT_OUTPUT = "o"
+	ASCIICAST_EVENT_INPUT  = "i"
+	ASCIICAST_EVENT_RESIZE = "r"
+
+	ASCIICAST_DEFAULT_WIDTH  = 80
+	ASCIICAST_DEFAULT_HEIGHT = 24
+)
+
+type sAsciicastHeader struct {
+	Version   int               `json:"version"`
+	Width     uint16            `json:"width"`
+	Height    uint16            `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// SAsciicastWriter writes terminal events as asciicast v2 lines, the header
+// is deferred until the first event so that an initial resize sets the size.
+type SAsciicastWriter struct {
+	lock sync.Mutex
+
+	w      io.Writer
+	title  string
+	start  time.Time
+	width  uint16
+	height uint16
+
+	headerWritten bool
+	// incomplete utf8 sequence of every event type
+	pending map[string][]byte
+	size    int64
+}
+
+func NewAsciicastWriter(w io.Writer, title string, start time.Time) *SAsciicastWriter {
+	return &SAsciicastWriter{
+		w:       w,
+		title:   title,
+		start:   start,
+		width:   ASCIICAST_DEFAULT_WIDTH,
+		height:  ASCIICAST_DEFAULT_HEIGHT,
+		pending: map[string][]byte{},
+	}
+}
+
+func (aw *SAsciicastWriter) writeLine(v interface{}) error {
+	line, err := json.Marshal(v)
+	if err != nil {
+		return errors.Wrap(err, "json.Marshal")
+	}
+	line = append(line, '\n')
+	n, err := aw.w.Write(line)
+	aw.size += int64(n)
+	if err != nil {
+		return errors.Wrap(err, "write")
+	}
+	return nil
+}
+
+func (aw *SAsciicastWriter) ensureHeader() error {
+	if aw.headerWritten {
+		return nil
+	}
+	header := sAsciicastHeader{
+		Version:   ASCIICAST_VERSION,
+		Width:     aw.width,
+		Height:    aw.height,
+		Timestamp: aw.start.Unix(),
+		Title:     aw.title,
+		Env: map[string]string{
+			"TERM": "xterm-256color",
+		},
+	}
+	if err := aw.writeLine(header); err != nil {
+		return errors.Wrap(err, "write header")
+	}
+	aw.headerWritten = true
+	return nil
+}
+
+func (aw *SAsciicastWriter) elapsed(at time.Time) float64 {
+	d := at.Sub(aw.start)
+	if d < 0 {
+		d = 0
+	}
+	return float64(d.Microseconds()) / 1e6
+}
+
+// splitUTF8 returns the longest prefix of data that does not end in the
+// middle of a multi-byte character and the remaining bytes.
+func splitUTF8(data []byte) ([]byte, []byte) {
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if !utf8.RuneStart(data[i]) {
+			continue
+		}
+		if !utf8.FullRune(data[i:]) {
+			return data[:i], data[i:]
+		}
+		break
+	}
+	return data, nil
+}
+
+func (aw *SAsciicastWriter) WriteEvent(at time.Time, code string, data []byte) error {
+	aw.lock.Lock()
+	defer aw.lock.Unlock()
+
+	if pending := aw.pending[code]; len(pending) > 0 {
+		data = append(pending, data...)
+	}
+	data, aw.pending[code] = splitUTF8(data)
+	if len(data) == 0 {
+		return nil
+	}
+	if err := aw.ensureHeader(); err != nil {
+		return err
+	}
+	return aw.writeLine([]interface{}{aw.elapsed(at), code, string(data)})
+}
+
+func (aw *SAsciicastWriter) Resize(at time.Time, cols, rows uint16) error {
+	aw.lock.Lock()
+	defer aw.lock.Unlock()
+
+	if cols == 0 || rows == 0 {
+		return nil
+	}
+	if !aw.headerWritten {
+		aw.width, aw.height = cols, rows
+		return nil
+	}
+	if cols == aw.width && rows == aw.height {
+		return nil
+	}
+	aw.width, aw.height = cols, rows
+	return aw.writeLine([]interface{}{aw.elapsed(at), ASCIICAST_EVENT_RESIZE, fmt.Sprintf("%dx%d", cols, rows)})
+}
+
+// Flush writes out the pending bytes and the header of an empty recording.
+func (aw *SAsciicastWriter) Flush(at time.Time) error {
+	aw.lock.Lock()
+	defer aw.lock.Unlock()
+
+	if err := aw.ensureHeader(); err != nil {
+		return err
+	}
+	for _, code := range []string{ASCIICAST_EVENT_OUTPUT, ASCIICAST_EVENT_INPUT} {
+		pending := aw.pending[code]
+		if len(pending) == 0 {
+			continue
+		}
+		delete(aw.pending, code)
+		if err := aw.writeLine([]interface{}{aw.elapsed(at), code, string(pending)}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (aw *SAsciicastWriter) Size() int64 {
+	aw.lock.Lock()
+	defer aw.lock.Unlock()
+
+	return aw.size
+}
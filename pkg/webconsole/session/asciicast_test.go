@@ -0,0 +1,79 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package session
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSplitUTF8(t *testing.T) {
+	zh := []byte("中")
+	cases := []struct {
+		in   []byte
+		head string
+		tail []byte
+	}{
+		{[]byte("abc"), "abc", nil},
+		{append([]byte("a"), zh[:1]...), "a", zh[:1]},
+		{append([]byte("a"), zh[:2]...), "a", zh[:2]},
+		{append([]byte("a"), zh...), "a中", nil},
+		{[]byte{}, "", nil},
+	}
+	for _, c := range cases {
+		head, tail := splitUTF8(c.in)
+		if string(head) != c.head || !bytes.Equal(tail, c.tail) {
+			t.Errorf("splitUTF8(%q) = %q, %q, want %q, %q", c.in, head, tail, c.head, c.tail)
+		}
+	}
+}
+
+func TestAsciicastWriter(t *testing.T) {
+	var buf bytes.Buffer
+	start := time.Unix(1600000000, 0)
+	aw := NewAsciicastWriter(&buf, "tty test", start)
+	zh := []byte("中")
+
+	aw.Resize(start, 120, 40)
+	aw.WriteEvent(start.Add(time.Second), ASCIICAST_EVENT_OUTPUT, append([]byte("hi "), zh[:1]...))
+	aw.WriteEvent(start.Add(2*time.Second), ASCIICAST_EVENT_OUTPUT, zh[1:])
+	aw.Resize(start.Add(3*time.Second), 100, 30)
+	aw.WriteEvent(start.Add(4*time.Second), ASCIICAST_EVENT_INPUT, []byte("ls\r"))
+	if err := aw.Flush(start.Add(5 * time.Second)); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+
+	want := []string{
+		`{"version":2,"width":120,"height":40,"timestamp":1600000000,"title":"tty test","env":{"TERM":"xterm-256color"}}`,
+		`[1,"o","hi "]`,
+		`[2,"o","中"]`,
+		`[3,"r","100x30"]`,
+		`[4,"i","ls\r"]`,
+	}
+	got := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(got) != len(want) {
+		t.Fatalf("got %d lines, want %d:\n%s", len(got), len(want), buf.String())
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("line %d: got %s, want %s", i, got[i], want[i])
+		}
+	}
+	if aw.Size() != int64(buf.Len()) {
+		t.Errorf("size %d, want %d", aw.Size(), buf.Len())
+	}
+}
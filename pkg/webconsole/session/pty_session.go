@@ -34,6 +34,7 @@ type Pty struct {
 	size       *pty.Winsize
 	OriginSize *pty.Winsize
 	Exit       bool
+	Recorder   *SRecorder
 }
 
 func NewPty(session *SSession) (p *Pty, err error) {
@@ -44,6 +45,16 @@ func NewPty(session *SSession) (p *Pty, err error) {
 		Exit:    false,
 		Pty:     nil,
 	}
+	if session.IsRecordingEnabled() {
+		p.Recorder, err = NewRecorder(session)
+		if err != nil {
+			if session.recordingForced {
+				return nil, errors.Wrap(err, "recording is required for this session")
+			}
+			log.Errorf("[session %s] start recording error: %v", session.Id, err)
+			p.Recorder, err = nil, nil
+		}
+	}
 	log.Debugf("[session %s] Start command: %#v", session.Id, cmd)
 	if cmd != nil {
 		p.Pty, err = pty.Start(p.Cmd)
@@ -76,9 +87,22 @@ func (p *Pty) Read() ([]byte, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, "Pty.Read")
 	}
+	p.RecordOutput(buf[0:n])
 	return buf[0:n], nil
 }
 
+func (p *Pty) RecordOutput(data []byte) {
+	if p.Recorder != nil {
+		p.Recorder.Output(data)
+	}
+}
+
+func (p *Pty) RecordInput(data []byte) {
+	if p.Recorder != nil {
+		p.Recorder.Input(data)
+	}
+}
+
 func (p *Pty) startResizeMonitor() {
 	go func() {
 		for range p.sizeCh {
@@ -96,6 +120,9 @@ func (p *Pty) startResizeMonitor() {
 
 func (p *Pty) Resize(size *pty.Winsize) {
 	p.size = size
+	if p.Recorder != nil {
+		p.Recorder.Resize(size.Cols, size.Rows)
+	}
 	p.sizeCh <- syscall.SIGWINCH
 }
 
@@ -110,6 +137,13 @@ func (p *Pty) Stop() (err error) {
 		err = errors.NewAggregate(errs)
 	}()
 	// LOCK required
+	defer func() {
+		if p.Recorder != nil {
+			if err := p.Recorder.Close(); err != nil {
+				log.Errorf("[%s] close recorder error: %v", p.Session.Id, err)
+			}
+		}
+	}()
 	defer func() {
 		if err := p.Session.Close(); err != nil {
 			errs = append(errs, err)
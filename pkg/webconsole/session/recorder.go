@@ -0,0 +1,388 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package session
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/util/stringutils"
+
+	api "yunion.io/x/onecloud/pkg/apis/webconsole"
+	"yunion.io/x/onecloud/pkg/cloudcommon/policy"
+	"yunion.io/x/onecloud/pkg/image/drivers/s3"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+	"yunion.io/x/onecloud/pkg/util/rbacutils"
+	o "yunion.io/x/onecloud/pkg/webconsole/options"
+)
+
+const (
+	RECORDING_KEYWORD = "webconsole_recording"
+
+	recordingCastSuffix = ".cast"
+	recordingMetaSuffix = ".json"
+	recordingS3Prefix   = "webconsole/recordings/"
+)
+
+var recordingLock sync.Mutex
+
+// InitRecording prepares the recording directory and storage, recordings
+// left unfinished by a previous run are marked as interrupted.
+func InitRecording() error {
+	if !o.Options.EnableSessionRecording {
+		return nil
+	}
+	if err := os.MkdirAll(o.Options.RecordingDir, 0700); err != nil {
+		return errors.Wrapf(err, "mkdir %s", o.Options.RecordingDir)
+	}
+	if o.Options.RecordingStorage == api.RECORDING_STORAGE_S3 {
+		endpoint := o.Options.RecordingS3Endpoint
+		if !strings.HasPrefix(endpoint, "http://") && !strings.HasPrefix(endpoint, "https://") {
+			prefix := "http://"
+			if o.Options.RecordingS3UseSSL {
+				prefix = "https://"
+			}
+			endpoint = prefix + endpoint
+		}
+		err := s3.Init(
+			endpoint,
+			o.Options.RecordingS3AccessKey,
+			o.Options.RecordingS3SecretKey,
+			o.Options.RecordingS3Bucket,
+			o.Options.RecordingS3UseSSL,
+		)
+		if err != nil {
+			return errors.Wrap(err, "init s3 client")
+		}
+	}
+	recordings, err := ListRecordings(nil)
+	if err != nil {
+		return errors.Wrap(err, "ListRecordings")
+	}
+	for i := range recordings {
+		rec := &recordings[i]
+		if rec.Status != api.RECORDING_STATUS_RECORDING {
+			continue
+		}
+		rec.Status = api.RECORDING_STATUS_INTERRUPTED
+		rec.Reason = "As the service restarts, the recording is interrupted"
+		if fi, err := os.Stat(recordingCastPath(rec.Id)); err == nil {
+			rec.Size = fi.Size()
+			rec.EndedAt = fi.ModTime()
+			rec.Duration = rec.EndedAt.Sub(rec.StartedAt).Seconds()
+		}
+		if err := saveRecordingMeta(rec); err != nil {
+			return errors.Wrapf(err, "save recording %s", rec.Id)
+		}
+	}
+	return nil
+}
+
+// RecordingPolicy decides whether a session of userCred is recorded. The
+// session is always recorded unless the policies of the user allow to
+// perform skip-recording on webconsole recordings, policies are evaluated
+// on every session so that changes take effect without restarting.
+func RecordingPolicy(userCred mcclient.TokenCredential, requested bool) (record bool, forced bool) {
+	if !o.Options.EnableSessionRecording || userCred == nil {
+		return false, false
+	}
+	result := policy.PolicyManager.Allow(rbacutils.ScopeUser, userCred, api.SERVICE_TYPE, api.RECORDING_RESOURCE, rbacutils.ActionPerform, api.RECORDING_ACTION_SKIP)
+	if result.Result.IsDeny() {
+		return true, true
+	}
+	return requested, false
+}
+
+func recordingCastPath(id string) string {
+	return filepath.Join(o.Options.RecordingDir, id+recordingCastSuffix)
+}
+
+func recordingMetaPath(id string) string {
+	return filepath.Join(o.Options.RecordingDir, id+recordingMetaSuffix)
+}
+
+func recordingS3Object(id string) string {
+	return recordingS3Prefix + id + recordingCastSuffix
+}
+
+func saveRecordingMeta(rec *api.SessionRecordingDetails) error {
+	recordingLock.Lock()
+	defer recordingLock.Unlock()
+
+	tmp := recordingMetaPath(rec.Id) + ".tmp"
+	if err := ioutil.WriteFile(tmp, []byte(jsonutils.Marshal(rec).PrettyString()), 0600); err != nil {
+		return errors.Wrap(err, "write meta")
+	}
+	return os.Rename(tmp, recordingMetaPath(rec.Id))
+}
+
+func GetRecording(id string) (*api.SessionRecordingDetails, error) {
+	if strings.ContainsAny(id, "/\\") {
+		return nil, errors.Wrapf(errors.ErrNotFound, "recording %s", id)
+	}
+	data, err := ioutil.ReadFile(recordingMetaPath(id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.Wrapf(errors.ErrNotFound, "recording %s", id)
+		}
+		return nil, errors.Wrap(err, "read meta")
+	}
+	obj, err := jsonutils.Parse(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parse recording %s", id)
+	}
+	rec := &api.SessionRecordingDetails{}
+	if err := obj.Unmarshal(rec); err != nil {
+		return nil, errors.Wrapf(err, "unmarshal recording %s", id)
+	}
+	return rec, nil
+}
+
+// ListRecordings returns the recordings accepted by filter, newest first.
+func ListRecordings(filter func(rec *api.SessionRecordingDetails) bool) ([]api.SessionRecordingDetails, error) {
+	files, err := ioutil.ReadDir(o.Options.RecordingDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "read recording dir")
+	}
+	ret := []api.SessionRecordingDetails{}
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), recordingMetaSuffix) {
+			continue
+		}
+		rec, err := GetRecording(strings.TrimSuffix(f.Name(), recordingMetaSuffix))
+		if err != nil {
+			log.Warningf("load recording %s: %v", f.Name(), err)
+			continue
+		}
+		if filter != nil && !filter(rec) {
+			continue
+		}
+		ret = append(ret, *rec)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].StartedAt.After(ret[j].StartedAt)
+	})
+	return ret, nil
+}
+
+// OpenRecording returns the asciicast content of a recording.
+func OpenRecording(ctx context.Context, rec *api.SessionRecordingDetails) (int64, io.ReadCloser, error) {
+	switch rec.Storage {
+	case api.RECORDING_STORAGE_S3:
+		return s3.Get(ctx, recordingS3Object(rec.Id))
+	default:
+		f, err := os.Open(recordingCastPath(rec.Id))
+		if err != nil {
+			if os.IsNotExist(err) {
+				return 0, nil, errors.Wrapf(errors.ErrNotFound, "recording %s", rec.Id)
+			}
+			return 0, nil, errors.Wrap(err, "open recording")
+		}
+		fi, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return 0, nil, errors.Wrap(err, "stat recording")
+		}
+		return fi.Size(), f, nil
+	}
+}
+
+type SRecorder struct {
+	Recording *api.SessionRecordingDetails
+
+	userCred  mcclient.TokenCredential
+	file      *os.File
+	buf       *bufio.Writer
+	writer    *SAsciicastWriter
+	closeOnce sync.Once
+}
+
+func NewRecorder(s *SSession) (*SRecorder, error) {
+	userCred := s.recordingUserCred
+	start := time.Now()
+	rec := &api.SessionRecordingDetails{
+		Id:              stringutils.UUID4(),
+		SessionId:       s.Id,
+		Protocol:        s.GetProtocol(),
+		Target:          s.recordingTarget,
+		Format:          api.RECORDING_FORMAT_ASCIICAST,
+		UserId:          userCred.GetUserId(),
+		User:            userCred.GetUserName(),
+		ProjectId:       userCred.GetProjectId(),
+		Project:         userCred.GetProjectName(),
+		ProjectDomainId: userCred.GetProjectDomainId(),
+		ProjectDomain:   userCred.GetProjectDomain(),
+		Forced:          s.recordingForced,
+		WithInput:       o.Options.RecordingWithInput,
+		StartedAt:       start,
+		Storage:         api.RECORDING_STORAGE_LOCAL,
+		Status:          api.RECORDING_STATUS_RECORDING,
+	}
+	f, err := os.OpenFile(recordingCastPath(rec.Id), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, errors.Wrap(err, "create recording file")
+	}
+	rec.Location = f.Name()
+	if err := saveRecordingMeta(rec); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, errors.Wrap(err, "save recording meta")
+	}
+	r := &SRecorder{
+		Recording: rec,
+		userCred:  userCred,
+		file:      f,
+		buf:       bufio.NewWriter(f),
+	}
+	r.writer = NewAsciicastWriter(r.buf, fmt.Sprintf("%s %s@%s", rec.Protocol, rec.User, rec.Target), start)
+	log.Infof("[session %s] start recording %s", s.Id, rec.Id)
+	return r, nil
+}
+
+func (r *SRecorder) Output(data []byte) {
+	if err := r.writer.WriteEvent(time.Now(), ASCIICAST_EVENT_OUTPUT, data); err != nil {
+		log.Errorf("[recording %s] write output: %v", r.Recording.Id, err)
+	}
+}
+
+// Input records user input only when --recording-with-input is set, as
+// input may contain passwords typed at the prompts of the remote host.
+func (r *SRecorder) Input(data []byte) {
+	if !r.Recording.WithInput {
+		return
+	}
+	if err := r.writer.WriteEvent(time.Now(), ASCIICAST_EVENT_INPUT, data); err != nil {
+		log.Errorf("[recording %s] write input: %v", r.Recording.Id, err)
+	}
+}
+
+func (r *SRecorder) Resize(cols, rows uint16) {
+	if err := r.writer.Resize(time.Now(), cols, rows); err != nil {
+		log.Errorf("[recording %s] write resize: %v", r.Recording.Id, err)
+	}
+}
+
+// Close finishes the recording, moves it to the configured storage and
+// links it to the action log of the logger service.
+func (r *SRecorder) Close() error {
+	var err error
+	r.closeOnce.Do(func() {
+		err = r.close()
+		rec := r.Recording
+		if err != nil {
+			rec.Status = api.RECORDING_STATUS_FAILED
+			rec.Reason = err.Error()
+		}
+		if e := saveRecordingMeta(rec); e != nil {
+			log.Errorf("[recording %s] save meta: %v", rec.Id, e)
+		}
+		notes := jsonutils.NewDict()
+		notes.Add(jsonutils.NewString(rec.SessionId), "session_id")
+		notes.Add(jsonutils.NewString(rec.Protocol), "protocol")
+		notes.Add(jsonutils.NewString(rec.Target), "target")
+		notes.Add(jsonutils.NewString(rec.Storage), "storage")
+		notes.Add(jsonutils.NewString(rec.Location), "location")
+		notes.Add(jsonutils.NewFloat64(rec.Duration), "duration")
+		if len(rec.Reason) > 0 {
+			notes.Add(jsonutils.NewString(rec.Reason), "reason")
+		}
+		logclient.AddSimpleActionLog(r, logclient.ACT_WEBCONSOLE_RECORD, notes, r.userCred, err == nil)
+	})
+	return err
+}
+
+func (r *SRecorder) close() error {
+	rec := r.Recording
+	rec.EndedAt = time.Now()
+	rec.Duration = rec.EndedAt.Sub(rec.StartedAt).Seconds()
+	if err := r.writer.Flush(rec.EndedAt); err != nil {
+		r.file.Close()
+		return errors.Wrap(err, "flush events")
+	}
+	if err := r.buf.Flush(); err != nil {
+		r.file.Close()
+		return errors.Wrap(err, "flush file")
+	}
+	if err := r.file.Close(); err != nil {
+		return errors.Wrap(err, "close file")
+	}
+	rec.Size = r.writer.Size()
+	if o.Options.RecordingStorage == api.RECORDING_STORAGE_S3 {
+		castPath := recordingCastPath(rec.Id)
+		location, err := s3.Put(context.Background(), castPath, recordingS3Object(rec.Id))
+		if err != nil {
+			return errors.Wrap(err, "upload to s3")
+		}
+		rec.Storage = api.RECORDING_STORAGE_S3
+		rec.Location = location
+		if err := os.Remove(castPath); err != nil {
+			log.Warningf("[recording %s] remove local file: %v", rec.Id, err)
+		}
+	}
+	rec.Status = api.RECORDING_STATUS_READY
+	log.Infof("[recording %s] finished, %d bytes saved to %s", rec.Id, rec.Size, rec.Location)
+	return nil
+}
+
+func (r *SRecorder) GetId() string {
+	return r.Recording.Id
+}
+
+func (r *SRecorder) GetName() string {
+	name := r.Recording.Protocol
+	if len(r.Recording.Target) > 0 {
+		name = fmt.Sprintf("%s-%s", name, r.Recording.Target)
+	}
+	return name
+}
+
+func (r *SRecorder) Keyword() string {
+	return RECORDING_KEYWORD
+}
+
+func (r *SRecorder) GetOwnerId() mcclient.IIdentityProvider {
+	return r.userCred
+}
+
+// IsRecordingVisible tells whether a recording is inside the given scope of userCred.
+func IsRecordingVisible(rec *api.SessionRecordingDetails, userCred mcclient.TokenCredential, scope rbacutils.TRbacScope) bool {
+	switch scope {
+	case rbacutils.ScopeSystem:
+		return true
+	case rbacutils.ScopeDomain:
+		return rec.ProjectDomainId == userCred.GetProjectDomainId()
+	case rbacutils.ScopeProject:
+		return rec.ProjectId == userCred.GetProjectId()
+	case rbacutils.ScopeUser:
+		return rec.UserId == userCred.GetUserId()
+	}
+	return false
+}
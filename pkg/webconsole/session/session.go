@@ -29,6 +29,7 @@ import (
 	"yunion.io/x/pkg/util/stringutils"
 	"yunion.io/x/pkg/utils"
 
+	"yunion.io/x/onecloud/pkg/mcclient"
 	"yunion.io/x/onecloud/pkg/webconsole/command"
 	o "yunion.io/x/onecloud/pkg/webconsole/options"
 )
@@ -124,6 +125,10 @@ type SSession struct {
 	AccessToken   string
 	AccessedAt    time.Time
 	duplicateHook func()
+
+	recordingUserCred mcclient.TokenCredential
+	recordingTarget   string
+	recordingForced   bool
 }
 
 func (s SSession) GetConnectParams(params url.Values) (string, error) {
@@ -165,3 +170,14 @@ func (s *SSession) Close() error {
 func (s *SSession) RegisterDuplicateHook(f func()) {
 	s.duplicateHook = f
 }
+
+// EnableRecording makes the terminal of this session recorded on behalf of userCred.
+func (s *SSession) EnableRecording(userCred mcclient.TokenCredential, target string, forced bool) {
+	s.recordingUserCred = userCred
+	s.recordingTarget = target
+	s.recordingForced = forced
+}
+
+func (s *SSession) IsRecordingEnabled() bool {
+	return s.recordingUserCred != nil
+}